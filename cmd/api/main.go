@@ -3,25 +3,45 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/interceptor"
+	temporallog "go.temporal.io/sdk/log"
 
 	"dev/bravebird/browser-automation-go/pkg/api"
+	"dev/bravebird/browser-automation-go/pkg/artifacts"
+	"dev/bravebird/browser-automation-go/pkg/audit"
 	"dev/bravebird/browser-automation-go/pkg/database"
+	"dev/bravebird/browser-automation-go/pkg/health"
 	"dev/bravebird/browser-automation-go/pkg/llm"
+	"dev/bravebird/browser-automation-go/pkg/logging"
+	"dev/bravebird/browser-automation-go/pkg/retention"
 	"dev/bravebird/browser-automation-go/pkg/semantic"
+	"dev/bravebird/browser-automation-go/pkg/telemetry"
 )
 
 func main() {
-	log.Println("Starting Browser Automation API Server")
+	migrate := flag.Bool("migrate", false, "apply the database migrations it lacks on startup")
+	flag.Parse()
+
+	// Log structured lines, tagged with the request they belong to
+	logger, err := logging.Setup("api")
+	if err != nil {
+		log.Fatalf("Failed to set up logging: %v", err)
+	}
+	logger.Info("Starting Browser Automation API Server")
 
 	// Get configuration from environment
 	port := getEnvOrDefault("PORT", "8080")
@@ -29,23 +49,43 @@ func main() {
 	temporalHost := getEnvOrDefault("TEMPORAL_HOST", "localhost:7233")
 	ollamaHost := getEnvOrDefault("OLLAMA_HOST", "http://localhost:11434")
 
-	// Initialize database
-	db, err := database.New(mysqlDSN)
+	// Initialize database, an embedded SQLite file when SQLITE_PATH is set
+	var db *database.DB
+	if sqlitePath := os.Getenv("SQLITE_PATH"); sqlitePath != "" {
+		db, err = database.NewSQLite(sqlitePath)
+	} else {
+		db, err = database.New(mysqlDSN)
+	}
 	if err != nil {
-		log.Printf("Warning: Failed to connect to database: %v", err)
-		log.Println("Running without database persistence")
+		slog.Warn("Failed to connect to database, running without persistence", "error", err)
 		db = nil
 	}
 	if db != nil {
 		defer db.Close()
 	}
+	if *migrate {
+		if db == nil {
+			logging.Fatal("No database to migrate")
+		}
+		if err := db.Migrate(context.Background()); err != nil {
+			logging.Fatal("Failed to migrate database", "error", err)
+		}
+	}
+
+	// Trace requests into the runs they start when OTEL_EXPORTER_OTLP_ENDPOINT is set
+	shutdownTracing, err := telemetry.Setup(context.Background(), "browser-automation-api")
+	if err != nil {
+		logging.Fatal("Failed to set up tracing", "error", err)
+	}
 
 	// Initialize Temporal client
 	temporalClient, err := client.Dial(client.Options{
-		HostPort: temporalHost,
+		HostPort:     temporalHost,
+		Interceptors: []interceptor.ClientInterceptor{telemetry.TemporalInterceptor()},
+		Logger:       temporallog.NewStructuredLogger(logger),
 	})
 	if err != nil {
-		log.Fatalf("Failed to create Temporal client: %v", err)
+		logging.Fatal("Failed to create Temporal client", "error", err)
 	}
 	defer temporalClient.Close()
 
@@ -69,8 +109,28 @@ func main() {
 	// Initialize embedding service
 	embeddingService := semantic.NewEmbeddingService(ollamaHost, "nomic-embed-text")
 
+	// Screenshots, downloads and generated code are read from the workers'
+	// directories unless ARTIFACT_STORE names a bucket they share
+	artifactStore, err := artifacts.FromEnv(artifacts.NewLocal(
+		getEnvOrDefault("SCREENSHOT_DIR", "/tmp/screenshots"),
+		getEnvOrDefault("DOWNLOAD_DIR", "/tmp/downloads"),
+		getEnvOrDefault("GENERATED_CODE_DIR", "generated_code"),
+	))
+	if err != nil {
+		logging.Fatal("Invalid artifact store", "error", err)
+	}
+
 	// Create API handlers
-	handlers := api.NewHandlers(db, temporalClient, llmConfigs, embeddingService)
+	handlers := api.NewHandlers(db, temporalClient, llmConfigs, embeddingService, artifactStore)
+	// Workers push run progress with the PROGRESS_TOKEN they share with the API
+	handlers.ProgressToken = os.Getenv("PROGRESS_TOKEN")
+	// The admin API lists and closes the browser sessions open on the
+	// workers at WORKER_URLS, a comma-separated list of their HTTP servers
+	for _, workerURL := range strings.Split(os.Getenv("WORKER_URLS"), ",") {
+		if workerURL = strings.TrimSpace(workerURL); workerURL != "" {
+			handlers.WorkerURLs = append(handlers.WorkerURLs, workerURL)
+		}
+	}
 
 	// Setup router
 	router := mux.NewRouter()
@@ -80,43 +140,146 @@ func main() {
 		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 	}).Methods("GET")
 
+	// Readiness, failing while MySQL or Temporal is down. The API still
+	// serves recorded workflows without Ollama.
+	router.Handle("/ready", health.Handler(health.DefaultTimeout,
+		health.Check{Name: "mysql", Check: func(ctx context.Context) error {
+			if db == nil {
+				return errors.New("not connected")
+			}
+			return db.Ping(ctx)
+		}},
+		health.Check{Name: "temporal", Check: func(ctx context.Context) error {
+			_, err := temporalClient.CheckHealth(ctx, &client.CheckHealthRequest{})
+			return err
+		}},
+		health.Check{Name: "ollama", Optional: true, Check: health.HTTP(nil, ollamaHost+"/api/tags")},
+	)).Methods("GET")
+
 	// API routes
 	apiRouter := router.PathPrefix("/api").Subrouter()
+	apiRouter.Use(telemetry.NameRoute)
+	if db != nil {
+		// Workers' progress pushes change nothing anyone asked for
+		apiRouter.Use(audit.Middleware(db, "/api/runs/{id}/progress"))
+	}
 
 	// Workflows
 	apiRouter.HandleFunc("/workflows", handlers.ListWorkflows).Methods("GET")
 	apiRouter.HandleFunc("/workflows", handlers.CreateWorkflow).Methods("POST")
+	apiRouter.HandleFunc("/workflows/import", handlers.ImportWorkflow).Methods("POST")
+	apiRouter.HandleFunc("/workflows/merge", handlers.MergeWorkflows).Methods("POST")
+	apiRouter.HandleFunc("/workflows/search", handlers.SearchWorkflows).Methods("GET")
 	apiRouter.HandleFunc("/workflows/{id}", handlers.GetWorkflow).Methods("GET")
+	apiRouter.HandleFunc("/workflows/{id}", handlers.UpdateWorkflow).Methods("PUT")
 	apiRouter.HandleFunc("/workflows/{id}", handlers.DeleteWorkflow).Methods("DELETE")
+	apiRouter.HandleFunc("/workflows/{id}/restore", handlers.RestoreWorkflow).Methods("POST")
+	apiRouter.HandleFunc("/workflows/{id}/clone", handlers.CloneWorkflow).Methods("POST")
 	apiRouter.HandleFunc("/workflows/{id}/generate", handlers.GenerateWorkflow).Methods("POST")
 	apiRouter.HandleFunc("/workflows/{id}/actions", handlers.GetWorkflowActions).Methods("GET")
+	apiRouter.HandleFunc("/workflows/{id}/actions", handlers.UpdateWorkflowActions).Methods("PUT")
+	apiRouter.HandleFunc("/workflows/{id}/actions", handlers.InsertWorkflowAction).Methods("POST")
+	apiRouter.HandleFunc("/workflows/{id}/actions/order", handlers.ReorderWorkflowActions).Methods("PUT")
+	apiRouter.HandleFunc("/workflows/{id}/actions/{seq:[0-9]+}", handlers.UpdateWorkflowAction).Methods("PATCH")
+	apiRouter.HandleFunc("/workflows/{id}/actions/{seq:[0-9]+}", handlers.DeleteWorkflowAction).Methods("DELETE")
+	apiRouter.HandleFunc("/workflows/{id}/parameters", handlers.UpdateWorkflowParameters).Methods("PUT")
+	apiRouter.HandleFunc("/workflows/{id}/settings", handlers.UpdateWorkflowSettings).Methods("PUT")
+	apiRouter.HandleFunc("/workflows/{id}/tags", handlers.AddWorkflowTags).Methods("POST")
+	apiRouter.HandleFunc("/workflows/{id}/tags/{tag}", handlers.RemoveWorkflowTag).Methods("DELETE")
+	apiRouter.HandleFunc("/tags", handlers.ListTags).Methods("GET")
+	apiRouter.HandleFunc("/workflows/{id}/versions", handlers.ListWorkflowVersions).Methods("GET")
+	apiRouter.HandleFunc("/workflows/{id}/versions/{version}", handlers.GetWorkflowVersion).Methods("GET")
+	apiRouter.HandleFunc("/workflows/{id}/generations", handlers.ListGenerations).Methods("GET")
+	apiRouter.HandleFunc("/workflows/{id}/generations/diff", handlers.DiffGenerations).Methods("GET")
+	apiRouter.HandleFunc("/workflows/{id}/analytics", handlers.GetWorkflowAnalytics).Methods("GET")
+	apiRouter.HandleFunc("/workflows/{id}/selectors", handlers.GetSelectorHealth).Methods("GET")
+	apiRouter.HandleFunc("/workflows/{id}/artifacts", handlers.DownloadArtifacts).Methods("GET")
+	apiRouter.HandleFunc("/workflows/{id}/export", handlers.ExportWorkflow).Methods("GET")
 
 	// Runs
 	apiRouter.HandleFunc("/workflows/{id}/run", handlers.ExecuteWorkflow).Methods("POST")
+	apiRouter.HandleFunc("/workflows/{id}/simulate", handlers.SimulateWorkflow).Methods("POST")
+	apiRouter.HandleFunc("/workflows/{id}/run-batch", handlers.ExecuteBatch).Methods("POST")
+	apiRouter.HandleFunc("/batches/{id}", handlers.GetBatch).Methods("GET")
 	apiRouter.HandleFunc("/runs", handlers.ListRuns).Methods("GET")
 	apiRouter.HandleFunc("/runs/{id}", handlers.GetRun).Methods("GET")
+	apiRouter.HandleFunc("/runs/{id}", handlers.DeleteRun).Methods("DELETE")
+	apiRouter.HandleFunc("/runs/{id}/restore", handlers.RestoreRun).Methods("POST")
+	apiRouter.HandleFunc("/runs/{id}/output.{format:csv|json}", handlers.GetRunOutput).Methods("GET")
+	apiRouter.HandleFunc("/runs/{id}/report.xml", handlers.GetRunReport).Methods("GET")
+	apiRouter.HandleFunc("/runs/{id}/report.html", handlers.GetRunHTMLReport).Methods("GET")
+	apiRouter.HandleFunc("/runs/{id}/allure.zip", handlers.GetRunAllureResults).Methods("GET")
 	apiRouter.HandleFunc("/runs/{id}/cancel", handlers.CancelRun).Methods("POST")
+	apiRouter.HandleFunc("/runs/{id}/pause", handlers.PauseRun).Methods("POST")
+	apiRouter.HandleFunc("/runs/{id}/resume", handlers.ResumeRun).Methods("POST")
+	apiRouter.HandleFunc("/runs/{id}/next", handlers.NextStep).Methods("POST")
+	apiRouter.HandleFunc("/runs/{id}/steps/{step}/{action}", handlers.DecideStep).Methods("POST")
+	apiRouter.HandleFunc("/runs/{id}/parameters", handlers.UpdateRunParameters).Methods("POST")
+	apiRouter.HandleFunc("/runs/{id}/downloads/{filename}", handlers.ServeDownload).Methods("GET")
 
 	// WebSocket for real-time updates
 	apiRouter.HandleFunc("/runs/{id}/stream", handlers.StreamRunUpdates).Methods("GET")
+	apiRouter.HandleFunc("/runs/{id}/events", handlers.StreamRunEvents).Methods("GET")
+	apiRouter.HandleFunc("/runs/{id}/progress", handlers.PublishRunProgress).Methods("POST")
+	apiRouter.HandleFunc("/runs/{id}/screencast", handlers.StreamScreencast).Methods("GET")
 
 	// LLM providers
 	apiRouter.HandleFunc("/llm/providers", handlers.ListLLMProviders).Methods("GET")
 	apiRouter.HandleFunc("/llm/providers/{name}/key", handlers.SetAPIKey).Methods("POST")
 	apiRouter.HandleFunc("/llm/providers/{name}/key", handlers.DeleteAPIKey).Methods("DELETE")
 
+	// Workflow templates, which workflows are created from without a recording
+	apiRouter.HandleFunc("/workflow-templates", handlers.ListWorkflowTemplates).Methods("GET")
+	apiRouter.HandleFunc("/workflow-templates", handlers.CreateWorkflowTemplate).Methods("POST")
+	apiRouter.HandleFunc("/workflow-templates/{id}", handlers.GetWorkflowTemplate).Methods("GET")
+	apiRouter.HandleFunc("/workflow-templates/{id}", handlers.UpdateWorkflowTemplate).Methods("PUT")
+	apiRouter.HandleFunc("/workflow-templates/{id}", handlers.DeleteWorkflowTemplate).Methods("DELETE")
+	apiRouter.HandleFunc("/workflow-templates/{id}/workflows", handlers.InstantiateWorkflowTemplate).Methods("POST")
+
+	// Code templates for the template-based generator
+	apiRouter.HandleFunc("/templates", handlers.ListCodeTemplates).Methods("GET")
+	apiRouter.HandleFunc("/templates/{action_type}", handlers.SaveCodeTemplate).Methods("PUT")
+	apiRouter.HandleFunc("/templates/{action_type}", handlers.DeleteCodeTemplate).Methods("DELETE")
+
+	// Saved browser sessions
+	apiRouter.HandleFunc("/sessions", handlers.ListBrowserSessions).Methods("GET")
+	apiRouter.HandleFunc("/sessions/{name}", handlers.DeleteBrowserSession).Methods("DELETE")
+
+	// Browser sessions open on the workers
+	apiRouter.HandleFunc("/admin/sessions", handlers.ListOpenBrowserSessions).Methods("GET")
+	apiRouter.HandleFunc("/admin/sessions/{id}", handlers.CloseOpenBrowserSession).Methods("DELETE")
+
+	// Pipelines
+	apiRouter.HandleFunc("/pipelines", handlers.CreatePipeline).Methods("POST")
+	apiRouter.HandleFunc("/pipelines", handlers.ListPipelines).Methods("GET")
+	apiRouter.HandleFunc("/pipelines/{id}", handlers.GetPipeline).Methods("GET")
+	apiRouter.HandleFunc("/pipelines/{id}", handlers.DeletePipeline).Methods("DELETE")
+	apiRouter.HandleFunc("/pipelines/{id}/run", handlers.RunPipeline).Methods("POST")
+	apiRouter.HandleFunc("/pipeline-runs/{id}", handlers.GetPipelineRun).Methods("GET")
+
+	// Schedules
+	apiRouter.HandleFunc("/schedules", handlers.CreateSchedule).Methods("POST")
+	apiRouter.HandleFunc("/schedules", handlers.ListSchedules).Methods("GET")
+	apiRouter.HandleFunc("/schedules/{id}", handlers.GetSchedule).Methods("GET")
+	apiRouter.HandleFunc("/schedules/{id}", handlers.UpdateSchedule).Methods("PUT")
+	apiRouter.HandleFunc("/schedules/{id}", handlers.DeleteSchedule).Methods("DELETE")
+
 	// Screenshots
 	apiRouter.HandleFunc("/screenshots/{filename}", handlers.ServeScreenshot).Methods("GET")
 
+	// Audit log of the requests that changed something
+	apiRouter.HandleFunc("/audit", handlers.ListAuditEvents).Methods("GET")
+
 	// Setup CORS
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"*"},
+		ExposedHeaders:   []string{"X-Total-Count", "ETag"}, // of paged lists and workflows
 		AllowCredentials: true,
 	})
 
-	handler := c.Handler(router)
+	handler := c.Handler(telemetry.Handler(logging.Requests(router), "api"))
 
 	// Create server
 	server := &http.Server{
@@ -127,11 +290,33 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Prune old runs and artifacts, and purge the trash, when retention is
+	// configured
+	policy, err := retention.ParsePolicy(os.Getenv("RUN_RETENTION_DAYS"), os.Getenv("ARTIFACT_RETENTION_DAYS"), os.Getenv("DELETED_RETENTION_DAYS"), os.Getenv("RETENTION_INTERVAL"))
+	if err != nil {
+		logging.Fatal("Invalid retention policy", "error", err)
+	}
+	cleanerCtx, stopCleaner := context.WithCancel(context.Background())
+	if policy.Enabled() && db != nil {
+		cleaner := &retention.Cleaner{DB: db, Artifacts: artifactStore, Policy: policy}
+		go cleaner.Run(cleanerCtx, func(stats retention.Stats, err error) {
+			if err != nil {
+				slog.Warn("Retention prune failed", "error", err)
+			}
+			if stats.RunsDeleted > 0 || stats.ArtifactsPruned > 0 {
+				slog.Info("Retention pruned runs", "runsDeleted", stats.RunsDeleted, "artifactsPruned", stats.ArtifactsPruned)
+			}
+			if stats.RunsPurged > 0 || stats.WorkflowsPurged > 0 {
+				slog.Info("Retention purged the trash", "runsPurged", stats.RunsPurged, "workflowsPurged", stats.WorkflowsPurged)
+			}
+		})
+	}
+
 	// Start server in goroutine
 	go func() {
-		log.Printf("API server listening on port %s", port)
+		slog.Info("API server listening", "port", port)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed: %v", err)
+			logging.Fatal("Server failed", "error", err)
 		}
 	}()
 
@@ -140,17 +325,21 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
+	slog.Info("Shutting down server")
+	stopCleaner()
 
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		logging.Fatal("Server forced to shutdown", "error", err)
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Warn("Failed to flush traces", "error", err)
 	}
 
-	log.Println("Server stopped")
+	slog.Info("Server stopped")
 }
 
 func getEnvOrDefault(key, defaultVal string) string {
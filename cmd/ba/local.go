@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"dev/bravebird/browser-automation-go/pkg/codegen"
+	"dev/bravebird/browser-automation-go/pkg/ingestion"
+	"dev/bravebird/browser-automation-go/pkg/llm"
+	"dev/bravebird/browser-automation-go/pkg/models"
+	"dev/bravebird/browser-automation-go/pkg/semantic"
+)
+
+// ==================== Commands ====================
+
+func runParse(args []string) error {
+	fs := newFlagSet("parse")
+	fs.Parse(args)
+	path, err := oneArg(fs)
+	if err != nil {
+		return err
+	}
+
+	parser, err := loadRecording(path)
+	if err != nil {
+		return err
+	}
+
+	counts := make(map[string]int)
+	for _, e := range parser.GetCustomEvents() {
+		counts[fmt.Sprint(e.Type)]++
+	}
+
+	fmt.Println("Start URL:", parser.GetStartURL())
+	fmt.Println("Total events:", len(parser.GetEvents()))
+	fmt.Println("RRWeb events:", len(parser.GetRRWebEvents()))
+	fmt.Println("Custom events:", len(parser.GetCustomEvents()))
+	eventTypes := make([]string, 0, len(counts))
+	for eventType := range counts {
+		eventTypes = append(eventTypes, eventType)
+	}
+	sort.Strings(eventTypes)
+	for _, eventType := range eventTypes {
+		fmt.Printf("  %-12s %d\n", eventType, counts[eventType])
+	}
+	return nil
+}
+
+func runExtract(args []string) error {
+	fs := newFlagSet("extract")
+	tolerance := fs.String("tolerance", "medium", "action filtering: low, medium or high")
+	provider := fs.String("provider", "", "LLM provider classifying variable tokens (heuristics when unset)")
+	output := fs.String("o", "", "write JSON to this file instead of stdout")
+	fs.Parse(args)
+	path, err := oneArg(fs)
+	if err != nil {
+		return err
+	}
+
+	actions, params, err := extractRecording(path, *tolerance, *provider)
+	if err != nil {
+		return err
+	}
+
+	data, _ := json.MarshalIndent(map[string]interface{}{
+		"actions":    actions,
+		"parameters": params,
+	}, "", "  ")
+	return writeOutput(*output, append(data, '\n'))
+}
+
+func runGenerate(args []string) error {
+	fs := newFlagSet("generate")
+	api := apiFlag(fs)
+	format := fs.String("format", "script", "output format: llm, script or test")
+	provider := fs.String("provider", "", "LLM provider for the llm format (default ollama)")
+	tolerance := fs.String("tolerance", "medium", "action filtering for recordings: low, medium or high")
+	headless := fs.Bool("headless", false, "generated code launches a headless browser")
+	pkg := fs.String("package", "", "package clause for the test format")
+	pageObjects := fs.Bool("page-objects", false, "group selectors into page object structs")
+	humanLike := fs.Bool("human-like", false, "mouse trajectories and typing jitter")
+	output := fs.String("o", "", "write code to this file instead of stdout (a directory when watching one)")
+	watch := fs.Bool("watch", false, "regenerate whenever the recording, or a recording in the directory, changes")
+	interval := fs.Duration("interval", time.Second, "how often -watch checks for changes")
+	fs.Parse(args)
+	arg, err := oneArg(fs)
+	if err != nil {
+		return err
+	}
+
+	opts := generateOptions{
+		format:    *format,
+		provider:  *provider,
+		tolerance: *tolerance,
+		script: codegen.ScriptOptions{
+			Headless:    *headless,
+			Package:     *pkg,
+			PageObjects: *pageObjects,
+			HumanLike:   *humanLike,
+		},
+	}
+
+	if *watch {
+		return watchRecordings(arg, *output, *interval, opts)
+	}
+
+	if !isLocalFile(arg) {
+		code, err := newClient(*api).generate(arg, map[string]interface{}{
+			"format":       *format,
+			"llm_provider": *provider,
+			"headless":     *headless,
+			"package":      *pkg,
+			"page_objects": *pageObjects,
+			"human_like":   *humanLike,
+		})
+		if err != nil {
+			return err
+		}
+		return writeOutput(*output, []byte(code))
+	}
+
+	code, _, err := generateLocal(arg, opts)
+	if err != nil {
+		return err
+	}
+	return writeOutput(*output, []byte(code))
+}
+
+// generateOptions are the generate flags used for local recordings
+type generateOptions struct {
+	format    string
+	provider  string
+	tolerance string
+	script    codegen.ScriptOptions
+}
+
+// generateLocal extracts a recording and generates code for it, returning
+// the actions the code was generated from
+func generateLocal(path string, opts generateOptions) (string, []models.SemanticAction, error) {
+	actions, params, err := extractRecording(path, opts.tolerance, opts.provider)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var code string
+	switch opts.format {
+	case "llm":
+		p, err := llm.NewProvider(llmConfig(opts.provider))
+		if err != nil {
+			return "", nil, err
+		}
+		code, err = p.GenerateCompleteWorkflow(context.Background(), actions, params)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to generate workflow: %w", err)
+		}
+	case "script":
+		code = codegen.GenerateGoRodScript(actions, params, opts.script)
+	case "test":
+		code = codegen.GenerateGoRodTest(actions, params, opts.script)
+	default:
+		return "", nil, fmt.Errorf("unknown format: %s", opts.format)
+	}
+
+	return code, actions, nil
+}
+
+// ==================== Helpers ====================
+
+// loadRecording parses a hybrid recording, JSON or protobuf by extension
+func loadRecording(path string) (*ingestion.HybridParser, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.ToLower(filepath.Ext(path)) == ".bin" {
+		p := ingestion.NewProtoParser()
+		if err := p.Parse(content); err != nil {
+			return nil, fmt.Errorf("failed to parse proto events: %w", err)
+		}
+		return p.HybridParser, nil
+	}
+
+	p := ingestion.NewHybridParser()
+	if err := p.Parse(content); err != nil {
+		return nil, fmt.Errorf("failed to parse events: %w", err)
+	}
+	return p, nil
+}
+
+// extractRecording returns the semantic actions and variable tokens of a
+// recording, as the API does on upload
+func extractRecording(path, tolerance, provider string) ([]models.SemanticAction, []models.WorkflowParameter, error) {
+	level, err := parseTolerance(tolerance)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parser, err := loadRecording(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	extractor := semantic.NewExtractor(parser, level)
+	actions := extractor.ExtractActions()
+
+	var classifier semantic.ValueClassifier
+	if provider != "" {
+		if p, err := llm.NewProvider(llmConfig(provider)); err == nil {
+			classifier = p
+		}
+	}
+
+	params := extractor.IdentifyVariableTokens(context.Background(), actions, classifier)
+	return actions, params, nil
+}
+
+func parseTolerance(s string) (semantic.ToleranceLevel, error) {
+	switch strings.ToLower(s) {
+	case "low":
+		return semantic.ToleranceLow, nil
+	case "", "medium":
+		return semantic.ToleranceMedium, nil
+	case "high":
+		return semantic.ToleranceHigh, nil
+	}
+	return 0, fmt.Errorf("unknown tolerance %q: want low, medium or high", s)
+}
+
+// llmConfig returns the default config of a provider with its API key, model
+// and host taken from the same environment variables as the worker
+func llmConfig(provider string) llm.Config {
+	if provider == "" {
+		provider = string(llm.ProviderOllama)
+	}
+	config := llm.DefaultConfigs()[llm.ProviderName(provider)]
+	config.Provider = provider
+
+	env := strings.ToUpper(provider)
+	if apiKey := os.Getenv(env + "_API_KEY"); apiKey != "" {
+		config.APIKey = apiKey
+	}
+	if model := os.Getenv(env + "_MODEL"); model != "" {
+		config.Model = model
+	}
+	if host := os.Getenv("OLLAMA_HOST"); host != "" && provider == string(llm.ProviderOllama) {
+		config.BaseURL = host
+	}
+	return config
+}
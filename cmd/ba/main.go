@@ -0,0 +1,140 @@
+// Command ba is the command line interface to the browser automation
+// pipeline. It parses and extracts recordings locally and generates code
+// either locally or through the API server, which it also uses to upload
+// and run workflows.
+//
+//	ba parse recording.json
+//	ba extract -tolerance high recording.json
+//	ba generate -format script recording.json
+//	ba generate -api http://localhost:8080 <workflow-id>
+//	ba upload -name "Search" recording.json
+//	ba run -param query=dogs -wait <workflow-id>
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// defaultAPIURL is used by commands that need the API server when neither
+// -api nor BA_API_URL is set
+const defaultAPIURL = "http://localhost:8080"
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(args []string) error
+}
+
+// commands is set in init as the commands refer back to it for their usage
+var commands []command
+
+func init() {
+	commands = []command{
+		{"parse", "parse [flags] <recording>", "Summarize the events of a recording", runParse},
+		{"extract", "extract [flags] <recording>", "Extract semantic actions and parameters as JSON", runExtract},
+		{"generate", "generate [flags] <recording | workflow-id>", "Generate Go Rod code from a recording or stored workflow", runGenerate},
+		{"upload", "upload [flags] <recording>", "Create a workflow on the API server", runUpload},
+		{"run", "run [flags] <workflow-id | recording>", "Execute a workflow on the API server", runRun},
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		usage()
+		return
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "ba %s: %v\n", cmd.name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "ba: unknown command %q\n\n", os.Args[1])
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: ba <command> [flags] [args]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun 'ba <command> -h' for the flags of a command.")
+}
+
+// newFlagSet returns the flag set of the named command with a usage line
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		for _, cmd := range commands {
+			if cmd.name == name {
+				fmt.Fprintf(fs.Output(), "Usage: ba %s\n\n%s\n\nFlags:\n", cmd.usage, cmd.summary)
+			}
+		}
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// apiFlag registers the -api flag, defaulting to BA_API_URL
+func apiFlag(fs *flag.FlagSet) *string {
+	return fs.String("api", os.Getenv("BA_API_URL"), "API server URL, e.g. "+defaultAPIURL+" (env BA_API_URL)")
+}
+
+// oneArg returns the single positional argument of fs
+func oneArg(fs *flag.FlagSet) (string, error) {
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return "", fmt.Errorf("expected 1 argument, got %d", fs.NArg())
+	}
+	return fs.Arg(0), nil
+}
+
+// isLocalFile reports whether arg names an existing file rather than a workflow ID
+func isLocalFile(arg string) bool {
+	info, err := os.Stat(arg)
+	return err == nil && !info.IsDir()
+}
+
+// writeOutput writes data to path, or to stdout when path is empty
+func writeOutput(path string, data []byte) error {
+	if path == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", path)
+	return nil
+}
+
+// paramFlags collects repeated -param name=value flags
+type paramFlags map[string]string
+
+func (p paramFlags) String() string {
+	pairs := make([]string, 0, len(p))
+	for name, value := range p {
+		pairs = append(pairs, name+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (p paramFlags) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected name=value, got %q", s)
+	}
+	p[name] = value
+	return nil
+}
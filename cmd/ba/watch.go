@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dev/bravebird/browser-automation-go/pkg/codegen"
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// recordingExts are the files -watch picks up in a directory
+var recordingExts = map[string]bool{".json": true, ".bin": true}
+
+// fileState identifies a version of a watched file
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// watchedRecording tracks one recording between polls
+type watchedRecording struct {
+	seen      fileState // state at the last poll
+	processed fileState // state last generated from
+	actions   []models.SemanticAction
+}
+
+// watchRecordings regenerates code for path, a recording or a directory of
+// recordings, whenever it changes, printing how the extracted actions changed.
+// Files are polled and only processed once they stop changing between two
+// polls, so a recorder still writing the file is not read half way.
+func watchRecordings(path, output string, interval time.Duration, opts generateOptions) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	dir := info.IsDir()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "Watching %s, press Ctrl+C to stop\n", path)
+
+	watched := make(map[string]*watchedRecording)
+	first := true
+	for {
+		files, err := recordingFiles(path, dir)
+		if err != nil {
+			return err
+		}
+
+		current := make(map[string]bool, len(files))
+		for _, file := range files {
+			current[file] = true
+			info, err := os.Stat(file)
+			if err != nil {
+				continue
+			}
+			state := fileState{modTime: info.ModTime(), size: info.Size()}
+
+			w, ok := watched[file]
+			if !ok {
+				w = &watchedRecording{}
+				watched[file] = w
+			}
+			stable := state == w.seen || first
+			w.seen = state
+			if !stable || state == w.processed {
+				continue
+			}
+
+			w.processed = state
+			w.actions = regenerate(file, watchOutput(file, output, dir), w.actions, opts)
+		}
+		for file := range watched {
+			if !current[file] {
+				fmt.Fprintf(os.Stderr, "%s %s: removed\n", time.Now().Format("15:04:05"), file)
+				delete(watched, file)
+			}
+		}
+		first = false
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// recordingFiles lists the recordings to watch
+func recordingFiles(path string, dir bool) ([]string, error) {
+	if !dir {
+		return []string{path}, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && recordingExts[strings.ToLower(filepath.Ext(entry.Name()))] {
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// watchOutput returns where the code for file is written. In a directory
+// watch -o names a directory receiving one .go file per recording; without
+// -o only the action summaries are printed.
+func watchOutput(file, output string, dir bool) string {
+	if !dir || output == "" {
+		return output
+	}
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	return filepath.Join(output, name+".go")
+}
+
+// regenerate generates code for file and prints the change in its actions
+// against previous, returning the new actions. Failures are reported and
+// keep the previous actions so the next good save diffs against them.
+func regenerate(file, output string, previous []models.SemanticAction, opts generateOptions) []models.SemanticAction {
+	now := time.Now().Format("15:04:05")
+
+	code, actions, err := generateLocal(file, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s %s: %v\n", now, file, err)
+		return previous
+	}
+
+	if output != "" {
+		if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "%s %s: %v\n", now, file, err)
+			return previous
+		}
+	}
+	if err := writeOutput(output, []byte(code)); err != nil {
+		fmt.Fprintf(os.Stderr, "%s %s: %v\n", now, file, err)
+		return previous
+	}
+
+	if previous == nil {
+		fmt.Fprintf(os.Stderr, "%s %s: %d actions\n", now, file, len(actions))
+		return actions
+	}
+
+	oldLines, newLines := actionSummary(previous), actionSummary(actions)
+	diff := codegen.UnifiedDiff(oldLines, newLines, "previous", "current")
+
+	added, removed := 0, 0
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "%s %s: %d actions (+%d -%d)\n", now, file, len(actions), added, removed)
+	if diff != "" {
+		// Skip the file header, the hunks are what changed
+		_, hunks, _ := strings.Cut(diff, "+++ current\n")
+		fmt.Fprint(os.Stderr, hunks)
+	}
+	return actions
+}
+
+// actionSummary renders one line per action, stable across regenerations
+// so the diff shows what the recorder settings changed
+func actionSummary(actions []models.SemanticAction) string {
+	var out strings.Builder
+	for _, a := range actions {
+		out.WriteString(string(a.ActionType))
+		if a.Target.Selector != "" && a.Target.Selector != "window" {
+			fmt.Fprintf(&out, " %s", a.Target.Selector)
+		}
+		if a.Target.Text != "" {
+			fmt.Fprintf(&out, " %q", shorten(a.Target.Text, 40))
+		}
+		if a.Value != "" {
+			fmt.Fprintf(&out, " = %q", shorten(a.Value, 60))
+		}
+		if a.InteractionRank != "" {
+			fmt.Fprintf(&out, " [%s]", a.InteractionRank)
+		}
+		out.WriteByte('\n')
+	}
+	return out.String()
+}
+
+func shorten(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "..."
+	}
+	return s
+}
@@ -1,32 +1,65 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"log"
+	"log/slog"
+	"math"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/interceptor"
+	temporallog "go.temporal.io/sdk/log"
 	"go.temporal.io/sdk/worker"
 
+	"dev/bravebird/browser-automation-go/pkg/artifacts"
+	"dev/bravebird/browser-automation-go/pkg/database"
+	"dev/bravebird/browser-automation-go/pkg/health"
 	"dev/bravebird/browser-automation-go/pkg/llm"
+	"dev/bravebird/browser-automation-go/pkg/logging"
+	"dev/bravebird/browser-automation-go/pkg/models"
+	"dev/bravebird/browser-automation-go/pkg/notify"
+	"dev/bravebird/browser-automation-go/pkg/secrets"
+	"dev/bravebird/browser-automation-go/pkg/telemetry"
 	"dev/bravebird/browser-automation-go/pkg/temporal/activities"
 	"dev/bravebird/browser-automation-go/pkg/temporal/workflows"
 )
 
-const TaskQueue = "browser-automation"
-
 func main() {
+	// Lines are written as LOG_FORMAT at LOG_LEVEL, tagged with the run
+	// and step of the activity logging them
+	logger, err := logging.Setup("worker")
+	if err != nil {
+		log.Fatalf("Failed to set up logging: %v", err)
+	}
+
 	// Get Temporal host from environment
 	temporalHost := os.Getenv("TEMPORAL_HOST")
 	if temporalHost == "" {
 		temporalHost = "localhost:7233"
 	}
 
-	// Create Temporal client
+	// Trace workflows and activities when OTEL_EXPORTER_OTLP_ENDPOINT is set
+	shutdownTracing, err := telemetry.Setup(context.Background(), "browser-automation-worker")
+	if err != nil {
+		logging.Fatal("Failed to set up tracing", "error", err)
+	}
+
+	// Create Temporal client. Its tracing interceptor also traces the
+	// workflows and activities of its workers.
 	c, err := client.Dial(client.Options{
-		HostPort: temporalHost,
+		HostPort:     temporalHost,
+		Interceptors: []interceptor.ClientInterceptor{telemetry.TemporalInterceptor()},
+		Logger:       temporallog.NewStructuredLogger(logger),
 	})
 	if err != nil {
-		log.Fatalf("Failed to create Temporal client: %v", err)
+		logging.Fatal("Failed to create Temporal client", "error", err)
 	}
 	defer c.Close()
 
@@ -88,16 +121,264 @@ func main() {
 
 	// Create activities
 	acts := activities.NewActivities(llmConfigs, screenshotDir)
+	acts.DownloadDir = getEnvOrDefault("DOWNLOAD_DIR", "/tmp/downloads")
+	acts.AxeCore = getEnvOrDefault("AXE_CORE", activities.DefaultAxeCore)
+
+	// Artifacts stay in the directories above unless ARTIFACT_STORE names a
+	// bucket shared with the API server
+	acts.Artifacts, err = artifacts.FromEnv(artifacts.NewLocal(screenshotDir, acts.DownloadDir, getEnvOrDefault("GENERATED_CODE_DIR", "generated_code")))
+	if err != nil {
+		logging.Fatal("Invalid artifact store", "error", err)
+	}
+
+	// Runs push their progress to the API at API_URL, authenticated with
+	// the PROGRESS_TOKEN they share, which otherwise polls them for the
+	// clients streaming them
+	acts.APIURL = strings.TrimSuffix(os.Getenv("API_URL"), "/")
+	acts.ProgressToken = os.Getenv("PROGRESS_TOKEN")
+	acts.Temporal = c
+
+	// Run notifications link to the API at API_PUBLIC_URL, post to Slack
+	// channels with SLACK_BOT_TOKEN where workflows have no webhook, and
+	// are emailed through the SMTP server
+	acts.PublicURL = strings.TrimSuffix(os.Getenv("API_PUBLIC_URL"), "/")
+	acts.Notify = notify.Config{
+		SlackToken: os.Getenv("SLACK_BOT_TOKEN"),
+		SMTP: notify.SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+		},
+	}
+	if port := os.Getenv("SMTP_PORT"); port != "" {
+		if acts.Notify.SMTP.Port, err = strconv.Atoi(port); err != nil {
+			logging.Fatal("Invalid SMTP_PORT", "error", err)
+		}
+	}
+
+	// Pre-generated code is stored in the database so any worker can execute
+	// a run's actions; without one it is passed inline in workflow history.
+	// SQLITE_PATH shares the API's SQLite file instead.
+	if sqlitePath, mysqlDSN := os.Getenv("SQLITE_PATH"), os.Getenv("MYSQL_DSN"); sqlitePath != "" || mysqlDSN != "" {
+		var db *database.DB
+		if sqlitePath != "" {
+			db, err = database.NewSQLite(sqlitePath)
+		} else {
+			db, err = database.New(mysqlDSN)
+		}
+		if err != nil {
+			slog.Warn("Failed to connect to database, passing generated code inline", "error", err)
+		} else {
+			defer db.Close()
+			acts.DB = db
+		}
+	}
+
+	// Custom templates for the fallback generator, <action_type>.tmpl files
+	if templateDir := os.Getenv("CODE_TEMPLATE_DIR"); templateDir != "" {
+		templates, err := llm.LoadTemplateDir(templateDir)
+		if err != nil {
+			logging.Fatal("Failed to load code templates", "error", err)
+		}
+		acts.Templates = templates
+	}
+
+	// Browser pool limits
+	poolConfig := activities.DefaultPoolConfig()
+	poolConfig.MaxSessions = getEnvInt("BROWSER_MAX_SESSIONS", poolConfig.MaxSessions)
+	poolConfig.QueueTimeout = getEnvDuration("BROWSER_QUEUE_TIMEOUT", poolConfig.QueueTimeout)
+	poolConfig.TTL = getEnvDuration("BROWSER_SESSION_TTL", poolConfig.TTL)
+	poolConfig.IdleTimeout = getEnvDuration("BROWSER_IDLE_TIMEOUT", poolConfig.IdleTimeout)
+	poolConfig.ProfileDir = os.Getenv("CHROME_PROFILE_DIR")
+	acts.Pool = activities.NewBrowserPool(poolConfig)
+
+	// Remote Chrome fleet, browsers run on this worker when unset
+	if endpoints := os.Getenv("BROWSER_ENDPOINTS"); endpoints != "" {
+		acts.Remote = activities.NewRemoteEndpoints(endpoints)
+		slog.Info("Using remote browser endpoints", "endpoints", acts.Remote.Len())
+	}
+
+	// Actions per minute by target domain, such as "example.com=30,*=120",
+	// shared by the workers through the database
+	if limits := os.Getenv("DOMAIN_RATE_LIMITS"); limits != "" {
+		domainLimits, err := activities.ParseDomainLimits(limits)
+		if err != nil {
+			logging.Fatal("Invalid DOMAIN_RATE_LIMITS", "error", err)
+		}
+		if acts.DB == nil {
+			slog.Warn("DOMAIN_RATE_LIMITS apply to this worker alone without MYSQL_DSN")
+		}
+		acts.DomainLimits = domainLimits
+	}
+
+	// Saving sessions after a run needs a key to encrypt them with
+	if key := os.Getenv("SESSION_ENCRYPTION_KEY"); key != "" {
+		sealer, err := secrets.NewSealer(key)
+		if err != nil {
+			logging.Fatal("Invalid SESSION_ENCRYPTION_KEY", "error", err)
+		}
+		acts.Sessions = sealer
+	}
 
-	// Create worker
-	w := worker.New(c, TaskQueue, worker.Options{
-		MaxConcurrentActivityExecutionSize:     5,
-		MaxConcurrentWorkflowTaskExecutionSize: 10,
+	// The worker's HTTP server reports its readiness, failing while Temporal
+	// is down or no browser can be had, and serves the API, which reaches it
+	// at WORKER_URL, its browser sessions and their live views
+	httpMux := http.NewServeMux()
+	httpMux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 	})
+	readiness := []health.Check{
+		{Name: "temporal", Check: func(ctx context.Context) error {
+			_, err := c.CheckHealth(ctx, &client.CheckHealthRequest{})
+			return err
+		}},
+		{Name: "chrome", Check: acts.CheckBrowser},
+		{Name: "ollama", Optional: true, Check: health.HTTP(nil, ollamaHost+"/api/tags")},
+	}
+	if acts.DB != nil {
+		readiness = append(readiness, health.Check{Name: "mysql", Optional: true, Check: acts.DB.Ping})
+	}
+	httpMux.Handle("GET /ready", health.Handler(health.DefaultTimeout, readiness...))
+	workerURL := os.Getenv("WORKER_URL")
+	if workerURL != "" {
+		acts.WorkerURL = workerURL
+		httpMux.Handle("/screencast/", acts.ScreencastHandler())
+	}
+	// The API lists and force-closes the worker's browser sessions
+	httpMux.Handle("/sessions/", acts.SessionsHandler())
+	httpServer := &http.Server{
+		Addr:              getEnvOrDefault("WORKER_HTTP_ADDR", ":8081"),
+		Handler:           httpMux,
+		ReadHeaderTimeout: 15 * time.Second,
+	}
+	go func() {
+		slog.Info("Worker HTTP server listening", "addr", httpServer.Addr, "workerURL", workerURL)
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Warn("Worker HTTP server failed, readiness and screencasts unavailable", "error", err)
+		}
+	}()
+
+	// Close browsers whose run never closed them, and all of them once the
+	// workers have stopped
+	reaperCtx, stopReaper := context.WithCancel(context.Background())
+	reaperDone := make(chan struct{})
+	go func() {
+		defer close(reaperDone)
+		acts.Pool.RunReaper(reaperCtx, func(sessions, profiles []string) {
+			if len(sessions) > 0 {
+				slog.Info("Closed expired browser sessions", "sessions", sessions)
+			}
+			if len(profiles) > 0 {
+				slog.Warn("Killed orphaned Chrome processes and removed their profiles", "profiles", profiles)
+			}
+		})
+	}()
+
+	// Labeled workers also serve the runs requiring their capabilities,
+	// such as a display for headful runs, from queues of their own
+	labels, err := workflows.ParseLabels(os.Getenv("WORKER_LABELS"))
+	if err != nil {
+		logging.Fatal("Invalid WORKER_LABELS", "error", err)
+	}
+	queues, err := workflows.WorkerTaskQueues(labels)
+	if err != nil {
+		logging.Fatal("Invalid WORKER_LABELS", "error", err)
+	}
+
+	// Create a worker per task queue. The worker's limits are split across
+	// them, so its queues together take no more activities and browser
+	// sessions than a worker with a single queue. Each priority has the
+	// queue's whole share, so runs of one don't wait on the tasks of
+	// another; the browser pool hands the browsers out by priority.
+	if poolConfig.MaxSessions > 0 && len(queues) > poolConfig.MaxSessions {
+		slog.Warn("More task queues than browser sessions, sessions beyond the limit wait for a free browser", "taskQueues", len(queues), "maxSessions", poolConfig.MaxSessions)
+	}
+	// On shutdown, running activities get WORKER_STOP_TIMEOUT to finish
+	stopTimeout := getEnvDuration("WORKER_STOP_TIMEOUT", 30*time.Second)
+	fatal := make(chan error, len(queues)*len(models.RunPriorities))
+	var workers []worker.Worker
+	var polled []string
+	for i, queue := range queues {
+		for _, priority := range models.RunPriorities {
+			queue := workflows.PriorityTaskQueue(queue, priority)
+			polled = append(polled, queue)
+			workers = append(workers, newWorker(c, queue, acts, worker.Options{
+				MaxConcurrentActivityExecutionSize:     splitLimit(maxConcurrentActivities, len(queues), i),
+				MaxConcurrentWorkflowTaskExecutionSize: splitLimit(maxConcurrentWorkflowTasks, len(queues), i),
+				MaxConcurrentSessionExecutionSize:      splitLimit(sessionLimit(poolConfig.MaxSessions), len(queues), i),
+				WorkerStopTimeout:                      stopTimeout,
+				OnFatalError:                           func(err error) { fatal <- err },
+			}))
+		}
+	}
+
+	slog.Info("Starting Temporal worker",
+		"taskQueues", polled,
+		"temporalHost", temporalHost,
+		"llmProviders", getProviderNames(llmConfigs),
+	)
+
+	// Run until SIGINT or SIGTERM, or until a worker fails
+	for _, w := range workers {
+		if err := w.Start(); err != nil {
+			logging.Fatal("Worker failed to start", "error", err)
+		}
+	}
+	select {
+	case sig := <-worker.InterruptCh():
+		slog.Info("Shutting down, letting running activities finish", "signal", sig, "stopTimeout", stopTimeout)
+	case err = <-fatal:
+		slog.Error("Worker failed, shutting down", "error", err)
+	}
+
+	// The workers stop polling together and wait for their activities, so
+	// draining takes at most the stop timeout. The browsers still open are
+	// closed after, and Chromes that don't exit killed, so none outlive the
+	// worker.
+	var stopping sync.WaitGroup
+	for _, w := range workers {
+		stopping.Add(1)
+		go func() {
+			defer stopping.Done()
+			w.Stop()
+		}()
+	}
+	stopping.Wait()
+	httpServer.Close()
+	slog.Info("Closing browser sessions", "open", acts.Pool.Len())
+	stopReaper()
+	<-reaperDone
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if flushErr := shutdownTracing(flushCtx); flushErr != nil {
+		slog.Warn("Failed to flush traces", "error", flushErr)
+	}
+	if err != nil {
+		logging.Fatal("Worker failed", "error", err)
+	}
+}
+
+// Concurrency limits of the worker process, across its task queues
+const (
+	maxConcurrentActivities    = 5
+	maxConcurrentWorkflowTasks = 10
+)
+
+// newWorker creates a worker polling a task queue with every workflow and
+// activity registered, within the given share of the process's limits
+func newWorker(c client.Client, queue string, acts *activities.Activities, limits worker.Options) worker.Worker {
+	// Runs hold a session for the lifetime of their browser, which also
+	// caps the browsers open on this worker
+	limits.EnableSessionWorker = true
+	w := worker.New(c, queue, limits)
 
 	// Register workflows
 	w.RegisterWorkflow(workflows.BrowserAutomationWorkflow)
 	w.RegisterWorkflow(workflows.ParallelBrowserAutomationWorkflow)
+	w.RegisterWorkflow(workflows.PipelineWorkflow)
+	w.RegisterWorkflow(workflows.ScheduledRunWorkflow)
 
 	// Register activities
 	w.RegisterActivity(acts.InitializeBrowserActivity)
@@ -105,16 +386,20 @@ func main() {
 	w.RegisterActivity(acts.PreGenerateCodeActivity)
 	w.RegisterActivity(acts.ExecuteBrowserActionActivity)
 	w.RegisterActivity(acts.TakeScreenshotActivity)
-
-	log.Printf("Starting Temporal worker on task queue: %s", TaskQueue)
-	log.Printf("Temporal host: %s", temporalHost)
-	log.Printf("Available LLM providers: %v", getProviderNames(llmConfigs))
-
-	// Start worker
-	err = w.Run(worker.InterruptCh())
-	if err != nil {
-		log.Fatalf("Worker failed: %v", err)
-	}
+	w.RegisterActivity(acts.DebugSnapshotActivity)
+	w.RegisterActivity(acts.DOMSnapshotActivity)
+	w.RegisterActivity(acts.SaveStorageStateActivity)
+	w.RegisterActivity(acts.DeleteStorageStateActivity)
+	w.RegisterActivity(acts.HealSelectorsActivity)
+	w.RegisterActivity(acts.RecordRunActivity)
+	w.RegisterActivity(acts.RecordRunStartedActivity)
+	w.RegisterActivity(acts.UpdateRunStatusActivity)
+	w.RegisterActivity(acts.RecordRunOutputsActivity)
+	w.RegisterActivity(acts.RecordActionResultsActivity)
+	w.RegisterActivity(acts.AcquireRunSlotActivity)
+	w.RegisterActivity(acts.NotifyRunActivity)
+	w.RegisterActivity(acts.PublishProgressActivity)
+	return w
 }
 
 func getEnvOrDefault(key, defaultVal string) string {
@@ -131,3 +416,50 @@ func getProviderNames(configs map[string]llm.Config) []string {
 	}
 	return names
 }
+
+func getEnvInt(key string, defaultVal int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		logging.Fatal("Invalid "+key, "error", err)
+	}
+	return n
+}
+
+func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		logging.Fatal("Invalid "+key, "error", err)
+	}
+	return d
+}
+
+// splitLimit is the i-th of n task queues' share of a limit. The first
+// queues, starting with the shared one most runs use, take the remainder,
+// and every queue gets at least one.
+func splitLimit(limit, n, i int) int {
+	share := limit / n
+	if i < limit%n {
+		share++
+	}
+	if share < 1 {
+		share = 1
+	}
+	return share
+}
+
+// sessionLimit converts the browser limit to Temporal's session limit,
+// where 0 selects the SDK default rather than unlimited
+func sessionLimit(maxSessions int) int {
+	if maxSessions <= 0 {
+		return math.MaxInt32
+	}
+	return maxSessions
+}
@@ -0,0 +1,41 @@
+// Package library holds the workflow templates shipped with the automator,
+// which new workflows can be created from without recording them
+package library
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+//go:embed *.json
+var files embed.FS
+
+// Templates returns the shipped templates, ordered by ID, which is the name
+// of their file. Their actions are numbered in their order.
+func Templates() ([]models.WorkflowTemplate, error) {
+	names, err := fs.Glob(files, "*.json")
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make([]models.WorkflowTemplate, 0, len(names))
+	for _, name := range names {
+		data, err := files.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		var t models.WorkflowTemplate
+		if err := json.Unmarshal(data, &t); err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+		}
+		t.ID = name[:len(name)-len(".json")]
+		t.BuiltIn = true
+		models.ResequenceActions(t.Actions)
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
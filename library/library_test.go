@@ -0,0 +1,20 @@
+package library
+
+import "testing"
+
+func TestTemplates(t *testing.T) {
+	templates, err := Templates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) == 0 {
+		t.Fatal("no templates are shipped")
+	}
+	for _, tmpl := range templates {
+		t.Run(tmpl.ID, func(t *testing.T) {
+			if err := tmpl.Validate(); err != nil {
+				t.Errorf("Validate() = %v", err)
+			}
+		})
+	}
+}
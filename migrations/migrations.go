@@ -0,0 +1,16 @@
+// Package migrations holds the versioned database schema, which the API
+// server applies on startup when run with -migrate
+package migrations
+
+import "embed"
+
+// MySQL holds the migrations of MySQL databases
+//
+//go:embed mysql/*.sql
+var MySQL embed.FS
+
+// SQLite holds the migrations of SQLite databases. The first one creates the
+// schema the MySQL migrations of the same version reach.
+//
+//go:embed sqlite/*.sql
+var SQLite embed.FS
@@ -1,3 +1,4 @@
+-- +goose Up
 -- Browser Automation Workflow System Schema
 -- MySQL 8.0+
 
@@ -1,3 +1,4 @@
+-- +goose Up
 -- Add interaction_rank and timestamp columns to semantic_actions table
 ALTER TABLE semantic_actions
 ADD COLUMN interaction_rank VARCHAR(20) DEFAULT 'Medium',
@@ -0,0 +1,5 @@
+-- +goose Up
+-- Add context column to semantic_actions table
+-- Holds the elements that appeared after the action, used for generated assertions
+ALTER TABLE semantic_actions
+ADD COLUMN context JSON;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Add generated code columns to workflow_definitions table
+-- Keeps the last generated workflow program so it can be downloaded as an artifact
+ALTER TABLE workflow_definitions
+ADD COLUMN generated_code LONGTEXT,
+ADD COLUMN generated_format VARCHAR(20);
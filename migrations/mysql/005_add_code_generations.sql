@@ -0,0 +1,15 @@
+-- +goose Up
+-- Code generation history
+-- Every run of GenerateWorkflow is kept so regenerations can be diffed
+CREATE TABLE IF NOT EXISTS code_generations (
+    id VARCHAR(36) PRIMARY KEY,
+    workflow_id VARCHAR(36) NOT NULL,
+    version INT NOT NULL,
+    format VARCHAR(20) NOT NULL,
+    llm_provider VARCHAR(50),
+    code LONGTEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE INDEX idx_workflow_version (workflow_id, version),
+    FOREIGN KEY (workflow_id) REFERENCES workflow_definitions(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,26 @@
+-- +goose Up
+-- Immutable workflow versions
+-- Creating, editing, importing or regenerating a workflow snapshots its
+-- actions and parameters so runs can be pinned to an exact version
+CREATE TABLE IF NOT EXISTS workflow_versions (
+    id VARCHAR(36) PRIMARY KEY,
+    workflow_id VARCHAR(36) NOT NULL,
+    version INT NOT NULL,
+    change_type VARCHAR(30) NOT NULL,
+    actions JSON NOT NULL,
+    parameters JSON,
+    code_version INT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE INDEX idx_workflow_version (workflow_id, version),
+    FOREIGN KEY (workflow_id) REFERENCES workflow_definitions(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Existing workflows start at version 1, built from the actions recorded at creation
+INSERT INTO workflow_versions (id, workflow_id, version, change_type, actions, parameters)
+SELECT UUID(), id, 1, 'created', COALESCE(semantic_context, JSON_ARRAY()), parameters
+FROM workflow_definitions;
+
+-- Version each run executed
+ALTER TABLE workflow_runs
+ADD COLUMN workflow_version INT;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- User-supplied code templates
+-- Overrides the template-based generator's code for one action type
+CREATE TABLE IF NOT EXISTS code_templates (
+    action_type VARCHAR(50) PRIMARY KEY,
+    template TEXT NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
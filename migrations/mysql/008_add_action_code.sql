@@ -0,0 +1,15 @@
+-- +goose Up
+-- Pre-generated action code
+-- Kept per run and sequence so any worker can execute an action, rather
+-- than only the worker whose disk the code was written to
+CREATE TABLE IF NOT EXISTS action_code (
+    run_id VARCHAR(36) NOT NULL,
+    sequence_id INT NOT NULL,
+    workflow_id VARCHAR(36) NOT NULL,
+    code LONGTEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (run_id, sequence_id),
+    INDEX idx_workflow (workflow_id, created_at),
+    FOREIGN KEY (run_id) REFERENCES workflow_runs(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
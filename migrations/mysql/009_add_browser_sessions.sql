@@ -0,0 +1,14 @@
+-- +goose Up
+-- Saved browser sessions
+-- Cookies and localStorage captured at the end of a run, encrypted with the
+-- SESSION_ENCRYPTION_KEY, so later runs can start logged in
+CREATE TABLE IF NOT EXISTS browser_sessions (
+    name VARCHAR(255) PRIMARY KEY,
+    state_encrypted LONGTEXT NOT NULL,
+    workflow_id VARCHAR(36),
+    run_id VARCHAR(36),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    INDEX idx_workflow (workflow_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,5 @@
+-- +goose Up
+-- Workflow settings
+-- Replay settings applied to every run of a workflow, such as how to answer
+-- JavaScript dialogs
+ALTER TABLE workflow_definitions ADD COLUMN settings JSON NULL;
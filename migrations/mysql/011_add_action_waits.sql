@@ -0,0 +1,5 @@
+-- +goose Up
+-- Add waits column to semantic_actions table
+-- Holds the conditions an action waits for after it runs
+ALTER TABLE semantic_actions
+ADD COLUMN waits JSON NULL;
@@ -0,0 +1,5 @@
+-- +goose Up
+-- Add metadata column to semantic_actions table
+-- Holds the recorded event details and per-action timeout and retry overrides
+ALTER TABLE semantic_actions
+ADD COLUMN metadata JSON NULL;
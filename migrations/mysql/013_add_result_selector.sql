@@ -0,0 +1,5 @@
+-- +goose Up
+-- Record which candidate of an action's selector chain found its element
+ALTER TABLE action_results
+ADD COLUMN selector TEXT NULL,
+ADD COLUMN selector_fallbacks INT DEFAULT 0;
@@ -0,0 +1,4 @@
+-- +goose Up
+-- Record which loop iteration an action result belongs to
+ALTER TABLE action_results
+ADD COLUMN iteration INT DEFAULT 0;
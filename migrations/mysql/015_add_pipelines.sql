@@ -0,0 +1,10 @@
+-- +goose Up
+-- Pipelines run workflows one after another, mapping each one's outputs to
+-- the next one's parameters
+CREATE TABLE IF NOT EXISTS pipelines (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    steps JSON NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- Schedules run workflows on a cron expression or at a fixed interval
+-- through Temporal schedules; the runs they start record the schedule
+CREATE TABLE IF NOT EXISTS schedules (
+    id VARCHAR(36) PRIMARY KEY,
+    workflow_id VARCHAR(36) NOT NULL,
+    cron VARCHAR(255),
+    interval_seconds INT DEFAULT 0,
+    timezone VARCHAR(64),
+    parameters JSON,
+    headless BOOLEAN DEFAULT TRUE,
+    llm_provider VARCHAR(50),
+    paused BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    INDEX idx_workflow (workflow_id),
+    FOREIGN KEY (workflow_id) REFERENCES workflow_definitions(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE workflow_runs
+ADD COLUMN schedule_id VARCHAR(36) NULL,
+ADD INDEX idx_schedule (schedule_id);
@@ -0,0 +1,10 @@
+-- +goose Up
+-- Actions taken on each target domain by minute, counted by all workers
+-- against the domain's actions-per-minute limit
+CREATE TABLE IF NOT EXISTS domain_action_windows (
+    domain VARCHAR(255) NOT NULL,
+    window_start DATETIME NOT NULL,
+    actions INT NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (domain, window_start)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,5 @@
+-- +goose Up
+-- Add criticality column to semantic_actions table
+-- Critical actions stop the run when they fail, optional ones don't
+ALTER TABLE semantic_actions
+ADD COLUMN criticality VARCHAR(16) NULL;
@@ -0,0 +1,5 @@
+-- +goose Up
+-- Add compensations column to semantic_actions table
+-- Holds the actions undoing an action when a later step aborts the run
+ALTER TABLE semantic_actions
+ADD COLUMN compensations JSON NULL;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Add screenshot columns to action_results table
+-- Hold the page before and after each action of runs taking screenshots of every action
+ALTER TABLE action_results
+ADD COLUMN before_screenshot_path TEXT NULL,
+ADD COLUMN after_screenshot_path TEXT NULL;
@@ -0,0 +1,5 @@
+-- +goose Up
+-- Add metrics column to action_results table
+-- Holds the page load timings of navigate actions
+ALTER TABLE action_results
+ADD COLUMN metrics JSON NULL;
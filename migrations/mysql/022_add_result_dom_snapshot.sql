@@ -0,0 +1,5 @@
+-- +goose Up
+-- Add dom_snapshot_path column to action_results table
+-- Holds the page's DOM when an action failed, for postmortems
+ALTER TABLE action_results
+ADD COLUMN dom_snapshot_path TEXT NULL;
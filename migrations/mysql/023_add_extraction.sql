@@ -0,0 +1,8 @@
+-- +goose Up
+-- Add extract column to semantic_actions table and outputs column to workflow_runs table
+-- Hold what extract actions read and the values a run produced by name
+ALTER TABLE semantic_actions
+ADD COLUMN extract JSON NULL;
+
+ALTER TABLE workflow_runs
+ADD COLUMN outputs JSON NULL;
@@ -0,0 +1,5 @@
+-- +goose Up
+-- Add dataset column to workflow_runs table
+-- Holds the rows of values a run's extract actions read, served as CSV or JSON
+ALTER TABLE workflow_runs
+ADD COLUMN dataset JSON NULL;
@@ -0,0 +1,5 @@
+-- +goose Up
+-- Add assertion column to semantic_actions table
+-- Holds what an assert action checks on the page
+ALTER TABLE semantic_actions
+ADD COLUMN assertion JSON NULL;
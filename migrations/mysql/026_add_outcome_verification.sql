@@ -0,0 +1,8 @@
+-- +goose Up
+-- Add warning status to workflow_runs table and missing_outcomes column to action_results table
+-- Hold runs whose actions lacked their recorded outcomes and the elements that didn't appear
+ALTER TABLE workflow_runs
+MODIFY COLUMN status ENUM('pending', 'running', 'success', 'warning', 'failed', 'canceled') DEFAULT 'pending';
+
+ALTER TABLE action_results
+ADD COLUMN missing_outcomes JSON NULL;
@@ -0,0 +1,5 @@
+-- +goose Up
+-- Add selector_source column to action_results table
+-- Record whether the primary, a fallback or a healed selector found the action's element
+ALTER TABLE action_results
+ADD COLUMN selector_source VARCHAR(16) NULL;
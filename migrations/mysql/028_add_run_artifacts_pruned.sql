@@ -0,0 +1,6 @@
+-- +goose Up
+-- Add artifacts_pruned_at column to workflow_runs table
+-- Record when retention deleted a run's screenshots, downloads and generated code
+ALTER TABLE workflow_runs
+ADD COLUMN artifacts_pruned_at TIMESTAMP NULL,
+ADD INDEX idx_completed_at (completed_at);
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Add search_text column to semantic_actions table and full-text indexes
+-- Hold the text, selector and value of each action, searched with the
+-- names and start URLs of the workflows
+ALTER TABLE semantic_actions
+ADD COLUMN search_text TEXT NULL;
+
+UPDATE semantic_actions
+SET search_text = CONCAT_WS(' ',
+    JSON_UNQUOTE(JSON_EXTRACT(target, '$.text')),
+    JSON_UNQUOTE(JSON_EXTRACT(target, '$.selector')),
+    NULLIF(value, ''));
+
+CREATE FULLTEXT INDEX ft_search_text ON semantic_actions(search_text);
+
+CREATE FULLTEXT INDEX ft_name_start_url ON workflow_definitions(name, start_url);
@@ -0,0 +1,12 @@
+-- +goose Up
+-- Tags group workflows, such as by team or system, so lists can be
+-- filtered by them
+CREATE TABLE IF NOT EXISTS workflow_tags (
+    workflow_id VARCHAR(36) NOT NULL,
+    tag VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (workflow_id, tag),
+    INDEX idx_tag (tag),
+    FOREIGN KEY (workflow_id) REFERENCES workflow_definitions(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- User-defined workflow templates, parameterized actions new workflows are
+-- created from without a recording
+CREATE TABLE IF NOT EXISTS workflow_templates (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    start_url TEXT,
+    actions JSON NOT NULL,
+    parameters JSON NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    INDEX idx_name (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
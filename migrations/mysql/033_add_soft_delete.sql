@@ -0,0 +1,9 @@
+-- +goose Up
+-- Deleted workflows and runs stay in the trash, restorable, until they are
+-- purged
+ALTER TABLE workflow_definitions
+    ADD COLUMN deleted_at TIMESTAMP NULL,
+    ADD INDEX idx_deleted_at (deleted_at);
+ALTER TABLE workflow_runs
+    ADD COLUMN deleted_at TIMESTAMP NULL,
+    ADD INDEX idx_deleted_at (deleted_at);
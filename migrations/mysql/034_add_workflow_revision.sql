@@ -0,0 +1,4 @@
+-- +goose Up
+-- The revision counts the changes to a workflow, so that an update made
+-- from an outdated copy of it is refused rather than undoing another
+ALTER TABLE workflow_definitions ADD COLUMN revision INT NOT NULL DEFAULT 1;
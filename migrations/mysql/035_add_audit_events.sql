@@ -0,0 +1,18 @@
+-- +goose Up
+-- The audit log records who changed what through the API and when
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    actor VARCHAR(255) NOT NULL DEFAULT '',
+    remote_addr VARCHAR(64) NOT NULL DEFAULT '',
+    method VARCHAR(10) NOT NULL,
+    route VARCHAR(255) NOT NULL,
+    path TEXT NOT NULL,
+    resource_id VARCHAR(255) NOT NULL DEFAULT '',
+    status INT NOT NULL,
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX idx_created_at (created_at),
+    INDEX idx_actor (actor),
+    INDEX idx_resource_id (resource_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Action results are recorded as their run ends, the canceled ones too. They
+-- outlive the actions they ran, which get new IDs whenever the workflow is
+-- edited, to keep the workflow's analytics.
+ALTER TABLE action_results
+DROP FOREIGN KEY action_results_ibfk_2;
+
+ALTER TABLE action_results
+MODIFY COLUMN status ENUM('pending', 'running', 'success', 'failed', 'canceled') DEFAULT 'pending';
@@ -0,0 +1,197 @@
+-- +goose Up
+-- Browser Automation Workflow System Schema
+-- SQLite, for running without a MySQL server; the MySQL schema as of
+-- version 028 with JSON and ENUM columns stored as TEXT
+
+-- Workflow definitions from recorded events
+CREATE TABLE IF NOT EXISTS workflow_definitions (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    events_file_path TEXT NOT NULL,
+    is_workflow_generated BOOLEAN DEFAULT FALSE,
+    start_url TEXT,
+    semantic_context TEXT,
+    parameters TEXT,
+    generated_code TEXT,
+    generated_format VARCHAR(20),
+    settings TEXT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_workflow_definitions_created_at ON workflow_definitions(created_at);
+CREATE INDEX IF NOT EXISTS idx_workflow_definitions_is_generated ON workflow_definitions(is_workflow_generated);
+
+-- Individual semantic actions within workflows
+CREATE TABLE IF NOT EXISTS semantic_actions (
+    id VARCHAR(36) PRIMARY KEY,
+    workflow_id VARCHAR(36) NOT NULL REFERENCES workflow_definitions(id) ON DELETE CASCADE,
+    sequence_id INT NOT NULL,
+    action_type VARCHAR(50) NOT NULL,
+    target TEXT,
+    value TEXT,
+    embeddings BLOB,
+    interaction_rank VARCHAR(20) DEFAULT 'Medium',
+    timestamp BIGINT DEFAULT 0,
+    context TEXT,
+    waits TEXT NULL,
+    metadata TEXT NULL,
+    criticality VARCHAR(16) NULL,
+    compensations TEXT NULL,
+    extract TEXT NULL,
+    assertion TEXT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_semantic_actions_workflow_sequence ON semantic_actions(workflow_id, sequence_id);
+CREATE INDEX IF NOT EXISTS idx_semantic_actions_action_type ON semantic_actions(action_type);
+CREATE INDEX IF NOT EXISTS idx_semantic_actions_interaction_rank ON semantic_actions(interaction_rank);
+
+-- Workflow execution runs
+CREATE TABLE IF NOT EXISTS workflow_runs (
+    id VARCHAR(36) PRIMARY KEY,
+    workflow_id VARCHAR(36) NOT NULL REFERENCES workflow_definitions(id) ON DELETE CASCADE,
+    temporal_run_id VARCHAR(255),
+    temporal_workflow_id VARCHAR(255),
+    status TEXT DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'success', 'warning', 'failed', 'canceled')),
+    parameters TEXT,
+    started_at TIMESTAMP NULL,
+    completed_at TIMESTAMP NULL,
+    error_message TEXT,
+    workflow_version INT,
+    schedule_id VARCHAR(36) NULL,
+    outputs TEXT NULL,
+    dataset TEXT NULL,
+    artifacts_pruned_at TIMESTAMP NULL
+);
+CREATE INDEX IF NOT EXISTS idx_workflow_runs_workflow_id ON workflow_runs(workflow_id);
+CREATE INDEX IF NOT EXISTS idx_workflow_runs_status ON workflow_runs(status);
+CREATE INDEX IF NOT EXISTS idx_workflow_runs_started_at ON workflow_runs(started_at);
+CREATE INDEX IF NOT EXISTS idx_workflow_runs_temporal_run ON workflow_runs(temporal_run_id);
+CREATE INDEX IF NOT EXISTS idx_workflow_runs_schedule ON workflow_runs(schedule_id);
+CREATE INDEX IF NOT EXISTS idx_workflow_runs_completed_at ON workflow_runs(completed_at);
+
+-- Individual action results within runs
+CREATE TABLE IF NOT EXISTS action_results (
+    id VARCHAR(36) PRIMARY KEY,
+    run_id VARCHAR(36) NOT NULL REFERENCES workflow_runs(id) ON DELETE CASCADE,
+    action_id VARCHAR(36) NOT NULL REFERENCES semantic_actions(id) ON DELETE CASCADE,
+    sequence_id INT NOT NULL,
+    status TEXT DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'success', 'failed')),
+    retry_count INT DEFAULT 0,
+    screenshot_path TEXT,
+    generated_code TEXT,
+    error_message TEXT,
+    executed_at TIMESTAMP NULL,
+    duration_ms BIGINT DEFAULT 0,
+    selector TEXT NULL,
+    selector_fallbacks INT DEFAULT 0,
+    iteration INT DEFAULT 0,
+    before_screenshot_path TEXT NULL,
+    after_screenshot_path TEXT NULL,
+    metrics TEXT NULL,
+    dom_snapshot_path TEXT NULL,
+    missing_outcomes TEXT NULL,
+    selector_source VARCHAR(16) NULL
+);
+CREATE INDEX IF NOT EXISTS idx_action_results_run_id ON action_results(run_id);
+CREATE INDEX IF NOT EXISTS idx_action_results_run_sequence ON action_results(run_id, sequence_id);
+CREATE INDEX IF NOT EXISTS idx_action_results_status ON action_results(status);
+
+-- Stored embeddings for similarity search (optional future feature)
+CREATE TABLE IF NOT EXISTS embedding_store (
+    id VARCHAR(36) PRIMARY KEY,
+    action_id VARCHAR(36) NOT NULL REFERENCES semantic_actions(id) ON DELETE CASCADE,
+    embedding_vector BLOB NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_embedding_store_action_id ON embedding_store(action_id);
+
+-- Code generation history
+CREATE TABLE IF NOT EXISTS code_generations (
+    id VARCHAR(36) PRIMARY KEY,
+    workflow_id VARCHAR(36) NOT NULL REFERENCES workflow_definitions(id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    format VARCHAR(20) NOT NULL,
+    llm_provider VARCHAR(50),
+    code TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE (workflow_id, version)
+);
+
+-- Immutable workflow versions
+CREATE TABLE IF NOT EXISTS workflow_versions (
+    id VARCHAR(36) PRIMARY KEY,
+    workflow_id VARCHAR(36) NOT NULL REFERENCES workflow_definitions(id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    change_type VARCHAR(30) NOT NULL,
+    actions TEXT NOT NULL,
+    parameters TEXT,
+    code_version INT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE (workflow_id, version)
+);
+
+-- User-supplied code templates
+CREATE TABLE IF NOT EXISTS code_templates (
+    action_type VARCHAR(50) PRIMARY KEY,
+    template TEXT NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Pre-generated action code
+CREATE TABLE IF NOT EXISTS action_code (
+    run_id VARCHAR(36) NOT NULL REFERENCES workflow_runs(id) ON DELETE CASCADE,
+    sequence_id INT NOT NULL,
+    workflow_id VARCHAR(36) NOT NULL,
+    code TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (run_id, sequence_id)
+);
+CREATE INDEX IF NOT EXISTS idx_action_code_workflow ON action_code(workflow_id, created_at);
+
+-- Saved browser sessions
+CREATE TABLE IF NOT EXISTS browser_sessions (
+    name VARCHAR(255) PRIMARY KEY,
+    state_encrypted TEXT NOT NULL,
+    workflow_id VARCHAR(36),
+    run_id VARCHAR(36),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_browser_sessions_workflow ON browser_sessions(workflow_id);
+
+-- Pipelines run workflows one after another
+CREATE TABLE IF NOT EXISTS pipelines (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    steps TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Schedules run workflows on a cron expression or at a fixed interval
+CREATE TABLE IF NOT EXISTS schedules (
+    id VARCHAR(36) PRIMARY KEY,
+    workflow_id VARCHAR(36) NOT NULL REFERENCES workflow_definitions(id) ON DELETE CASCADE,
+    cron VARCHAR(255),
+    interval_seconds INT DEFAULT 0,
+    timezone VARCHAR(64),
+    parameters TEXT,
+    headless BOOLEAN DEFAULT TRUE,
+    llm_provider VARCHAR(50),
+    paused BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_schedules_workflow ON schedules(workflow_id);
+
+-- Actions taken on each target domain by minute
+CREATE TABLE IF NOT EXISTS domain_action_windows (
+    domain VARCHAR(255) NOT NULL,
+    window_start DATETIME NOT NULL,
+    actions INT NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (domain, window_start)
+);
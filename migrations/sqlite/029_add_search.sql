@@ -0,0 +1,12 @@
+-- +goose Up
+-- Add search_text column to semantic_actions table
+-- Holds the text, selector and value of each action, searched with the
+-- names and start URLs of the workflows
+ALTER TABLE semantic_actions
+ADD COLUMN search_text TEXT NULL;
+
+UPDATE semantic_actions
+SET search_text = trim(
+    COALESCE(json_extract(target, '$.text') || ' ', '') ||
+    COALESCE(json_extract(target, '$.selector') || ' ', '') ||
+    COALESCE(NULLIF(value, ''), ''));
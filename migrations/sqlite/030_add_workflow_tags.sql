@@ -0,0 +1,11 @@
+-- +goose Up
+-- Tags group workflows, such as by team or system, so lists can be
+-- filtered by them
+CREATE TABLE IF NOT EXISTS workflow_tags (
+    workflow_id VARCHAR(36) NOT NULL REFERENCES workflow_definitions(id) ON DELETE CASCADE,
+    tag VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (workflow_id, tag)
+);
+CREATE INDEX IF NOT EXISTS idx_workflow_tags_tag ON workflow_tags(tag);
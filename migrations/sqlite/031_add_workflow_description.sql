@@ -0,0 +1,3 @@
+-- +goose Up
+-- A description says what a workflow does, set after upload
+ALTER TABLE workflow_definitions ADD COLUMN description TEXT NULL;
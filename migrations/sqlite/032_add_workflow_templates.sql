@@ -0,0 +1,14 @@
+-- +goose Up
+-- User-defined workflow templates, parameterized actions new workflows are
+-- created from without a recording
+CREATE TABLE IF NOT EXISTS workflow_templates (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    start_url TEXT,
+    actions TEXT NOT NULL,
+    parameters TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_workflow_templates_name ON workflow_templates(name);
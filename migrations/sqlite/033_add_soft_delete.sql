@@ -0,0 +1,7 @@
+-- +goose Up
+-- Deleted workflows and runs stay in the trash, restorable, until they are
+-- purged
+ALTER TABLE workflow_definitions ADD COLUMN deleted_at TIMESTAMP NULL;
+CREATE INDEX IF NOT EXISTS idx_workflow_definitions_deleted_at ON workflow_definitions(deleted_at);
+ALTER TABLE workflow_runs ADD COLUMN deleted_at TIMESTAMP NULL;
+CREATE INDEX IF NOT EXISTS idx_workflow_runs_deleted_at ON workflow_runs(deleted_at);
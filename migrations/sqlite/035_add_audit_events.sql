@@ -0,0 +1,17 @@
+-- +goose Up
+-- The audit log records who changed what through the API and when
+CREATE TABLE IF NOT EXISTS audit_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    actor VARCHAR(255) NOT NULL DEFAULT '',
+    remote_addr VARCHAR(64) NOT NULL DEFAULT '',
+    method VARCHAR(10) NOT NULL,
+    route VARCHAR(255) NOT NULL,
+    path TEXT NOT NULL,
+    resource_id VARCHAR(255) NOT NULL DEFAULT '',
+    status INTEGER NOT NULL,
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events(actor);
+CREATE INDEX IF NOT EXISTS idx_audit_events_resource_id ON audit_events(resource_id);
@@ -0,0 +1,39 @@
+-- +goose Up
+-- Action results are recorded as their run ends, the canceled ones too. They
+-- outlive the actions they ran, which get new IDs whenever the workflow is
+-- edited, to keep the workflow's analytics. SQLite can't alter constraints,
+-- so the table is rebuilt.
+CREATE TABLE action_results_new (
+    id VARCHAR(36) PRIMARY KEY,
+    run_id VARCHAR(36) NOT NULL REFERENCES workflow_runs(id) ON DELETE CASCADE,
+    action_id VARCHAR(36) NOT NULL,
+    sequence_id INT NOT NULL,
+    status TEXT DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'success', 'failed', 'canceled')),
+    retry_count INT DEFAULT 0,
+    screenshot_path TEXT,
+    generated_code TEXT,
+    error_message TEXT,
+    executed_at TIMESTAMP NULL,
+    duration_ms BIGINT DEFAULT 0,
+    selector TEXT NULL,
+    selector_fallbacks INT DEFAULT 0,
+    iteration INT DEFAULT 0,
+    before_screenshot_path TEXT NULL,
+    after_screenshot_path TEXT NULL,
+    metrics TEXT NULL,
+    dom_snapshot_path TEXT NULL,
+    missing_outcomes TEXT NULL,
+    selector_source VARCHAR(16) NULL
+);
+INSERT INTO action_results_new (id, run_id, action_id, sequence_id, status, retry_count, screenshot_path, generated_code,
+    error_message, executed_at, duration_ms, selector, selector_fallbacks, iteration, before_screenshot_path,
+    after_screenshot_path, metrics, dom_snapshot_path, missing_outcomes, selector_source)
+SELECT id, run_id, action_id, sequence_id, status, retry_count, screenshot_path, generated_code,
+    error_message, executed_at, duration_ms, selector, selector_fallbacks, iteration, before_screenshot_path,
+    after_screenshot_path, metrics, dom_snapshot_path, missing_outcomes, selector_source
+FROM action_results;
+DROP TABLE action_results;
+ALTER TABLE action_results_new RENAME TO action_results;
+CREATE INDEX IF NOT EXISTS idx_action_results_run_id ON action_results(run_id);
+CREATE INDEX IF NOT EXISTS idx_action_results_run_sequence ON action_results(run_id, sequence_id);
+CREATE INDEX IF NOT EXISTS idx_action_results_status ON action_results(status);
@@ -1,13 +1,30 @@
 package api
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/base64"
+	"encoding/csv"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"html/template"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,14 +32,24 @@ import (
 	"github.com/gorilla/websocket"
 	"go.temporal.io/sdk/client"
 
+	"dev/bravebird/browser-automation-go/library"
+	"dev/bravebird/browser-automation-go/pkg/artifacts"
+	"dev/bravebird/browser-automation-go/pkg/audit"
+	"dev/bravebird/browser-automation-go/pkg/codegen"
 	"dev/bravebird/browser-automation-go/pkg/database"
 	"dev/bravebird/browser-automation-go/pkg/ingestion"
 	"dev/bravebird/browser-automation-go/pkg/llm"
+	"dev/bravebird/browser-automation-go/pkg/logging"
 	"dev/bravebird/browser-automation-go/pkg/models"
+	"dev/bravebird/browser-automation-go/pkg/progress"
 	"dev/bravebird/browser-automation-go/pkg/semantic"
+	"dev/bravebird/browser-automation-go/pkg/simulation"
+	"dev/bravebird/browser-automation-go/pkg/telemetry"
+	"dev/bravebird/browser-automation-go/pkg/temporal/workflows"
 )
 
-const TaskQueue = "browser-automation"
+// TaskQueue is the task queue of runs without requirements
+const TaskQueue = workflows.TaskQueue
 
 // Handlers contains API handlers
 type Handlers struct {
@@ -31,7 +58,16 @@ type Handlers struct {
 	llmConfigs       map[string]llm.Config
 	runtimeAPIKeys   map[string]string // API keys submitted via UI
 	embeddingService *semantic.EmbeddingService
+	artifacts        artifacts.Store // screenshots, downloads and generated code of runs
+	progress         *progress.Hub   // run progress workers publish, for the clients streaming runs
 	upgrader         websocket.Upgrader
+
+	// ProgressToken is the secret workers present to push run progress,
+	// which is refused when it's empty
+	ProgressToken string
+	// WorkerURLs are the workers' HTTP servers, whose open browser sessions
+	// the admin API lists and closes
+	WorkerURLs []string
 }
 
 // NewHandlers creates new API handlers
@@ -40,6 +76,7 @@ func NewHandlers(
 	temporalClient client.Client,
 	llmConfigs map[string]llm.Config,
 	embeddingService *semantic.EmbeddingService,
+	artifactStore artifacts.Store,
 ) *Handlers {
 	return &Handlers{
 		db:               db,
@@ -47,6 +84,8 @@ func NewHandlers(
 		llmConfigs:       llmConfigs,
 		runtimeAPIKeys:   make(map[string]string),
 		embeddingService: embeddingService,
+		artifacts:        artifactStore,
+		progress:         progress.NewHub(),
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool { return true },
 		},
@@ -64,15 +103,239 @@ func (h *Handlers) ListWorkflows(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	workflows, err := h.db.ListWorkflowDefinitions(ctx)
+	opts, ok := queryList(w, r, models.WorkflowSorts)
+	if !ok {
+		return
+	}
+	tags, ok := queryTags(w, r)
+	if !ok {
+		return
+	}
+	workflows, total, err := h.db.ListWorkflowDefinitions(ctx, models.WorkflowFilter{
+		ListOptions: opts,
+		Name:        r.URL.Query().Get("name"),
+		Tags:        tags,
+	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
 	respondJSON(w, workflows)
 }
 
+// queryTags reads the ?tag= parameters of a list, responding with an error
+// when one is invalid
+func queryTags(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	var tags []string
+	for _, v := range r.URL.Query()["tag"] {
+		tag, err := models.NormalizeTag(v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+		tags = append(tags, tag)
+	}
+	return tags, true
+}
+
+// TagsRequest lists tags to add to a workflow
+type TagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// AddWorkflowTags tags a workflow, responding with all of its tags
+func (h *Handlers) AddWorkflowTags(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req TagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Tags) == 0 {
+		http.Error(w, "Missing tags", http.StatusBadRequest)
+		return
+	}
+	for i, tag := range req.Tags {
+		var err error
+		if req.Tags[i], err = models.NormalizeTag(tag); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	workflow, err := h.db.GetWorkflowDefinition(ctx, id)
+	if err != nil || workflow == nil {
+		http.Error(w, "Workflow not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.db.AddWorkflowTags(ctx, id, req.Tags); err != nil {
+		http.Error(w, "Failed to tag workflow: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	workflow, err = h.db.GetWorkflowDefinition(ctx, id)
+	if err != nil || workflow == nil {
+		http.Error(w, "Workflow not found", http.StatusNotFound)
+		return
+	}
+	respondJSON(w, TagsRequest{Tags: workflow.Tags})
+}
+
+// RemoveWorkflowTag removes a tag from a workflow
+func (h *Handlers) RemoveWorkflowTag(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	tag, err := models.NormalizeTag(vars["tag"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	removed, err := h.db.RemoveWorkflowTag(r.Context(), vars["id"], tag)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !removed {
+		http.Error(w, "Tag not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListTags lists the tags of workflows with how many have each
+func (h *Handlers) ListTags(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	tags, err := h.db.ListTags(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, tags)
+}
+
+// Search results returned without a ?limit=, and at most
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchWorkflows finds the workflows whose names or start URLs, or whose
+// actions' text, selectors or values, match ?q=
+func (h *Handlers) SearchWorkflows(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "Missing q", http.StatusBadRequest)
+		return
+	}
+	limit := defaultSearchLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxSearchLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	results, err := h.db.SearchWorkflows(ctx, query, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, results)
+}
+
+// maxListLimit is the most workflows or runs a list returns at once
+const maxListLimit = 1000
+
+// queryList reads the ?limit=, ?offset=, ?sort=, ?order= and ?since= and
+// ?until= dates of a list sortable by sorts, responding with an error when
+// one is invalid
+func queryList(w http.ResponseWriter, r *http.Request, sorts []string) (models.ListOptions, bool) {
+	query := r.URL.Query()
+	var opts models.ListOptions
+	for name, dst := range map[string]*int{"limit": &opts.Limit, "offset": &opts.Offset} {
+		if v := query.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				http.Error(w, name+" must be a non-negative number", http.StatusBadRequest)
+				return opts, false
+			}
+			*dst = n
+		}
+	}
+	if opts.Limit > maxListLimit {
+		http.Error(w, fmt.Sprintf("limit must be at most %d", maxListLimit), http.StatusBadRequest)
+		return opts, false
+	}
+
+	if opts.Sort = query.Get("sort"); opts.Sort != "" && !slices.Contains(sorts, opts.Sort) {
+		http.Error(w, "sort must be one of "+strings.Join(sorts, ", "), http.StatusBadRequest)
+		return opts, false
+	}
+	switch query.Get("order") {
+	case "", "desc":
+	case "asc":
+		opts.Asc = true
+	default:
+		http.Error(w, "order must be asc or desc", http.StatusBadRequest)
+		return opts, false
+	}
+
+	for name, dst := range map[string]*time.Time{"since": &opts.Since, "until": &opts.Until} {
+		if v := query.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				t, err = time.Parse(time.DateOnly, v)
+			}
+			if err != nil {
+				http.Error(w, name+" must be a date or an RFC 3339 time", http.StatusBadRequest)
+				return opts, false
+			}
+			*dst = t
+		}
+	}
+
+	if v := query.Get("deleted"); v != "" {
+		deleted, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "deleted must be true or false", http.StatusBadRequest)
+			return opts, false
+		}
+		opts.Deleted = deleted
+	}
+	return opts, true
+}
+
 // CreateWorkflow creates a new workflow from uploaded events file
 func (h *Handlers) CreateWorkflow(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -163,6 +426,7 @@ func (h *Handlers) CreateWorkflow(w http.ResponseWriter, r *http.Request) {
 	workflow := &models.WorkflowDefinition{
 		ID:              uuid.New().String(),
 		Name:            r.FormValue("name"),
+		Description:     r.FormValue("description"),
 		EventsFilePath:  filePath,
 		StartURL:        parser.GetStartURL(),
 		SemanticContext: string(actionsJSON),
@@ -188,12 +452,18 @@ func (h *Handlers) CreateWorkflow(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Failed to store actions: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+
+		if _, err := h.recordVersion(ctx, workflow.ID, actions, params, models.VersionCreated, 0); err != nil {
+			http.Error(w, "Failed to create version: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
 	}
 
 	// Return workflow with parsed data
 	workflow.Actions = actions
 	workflow.Parameters = params
 
+	audit.SetResource(ctx, workflow.ID)
 	respondJSON(w, workflow)
 }
 
@@ -227,10 +497,12 @@ func (h *Handlers) GetWorkflow(w http.ResponseWriter, r *http.Request) {
 		json.Unmarshal([]byte(workflow.ParametersJSON), &workflow.Parameters)
 	}
 
+	w.Header().Set("ETag", workflowETag(workflow))
 	respondJSON(w, workflow)
 }
 
-// DeleteWorkflow deletes a workflow definition
+// DeleteWorkflow moves a workflow to the trash, hiding it and its runs
+// until it is restored or purged
 func (h *Handlers) DeleteWorkflow(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	vars := mux.Vars(r)
@@ -241,14 +513,59 @@ func (h *Handlers) DeleteWorkflow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.db.DeleteWorkflowDefinition(ctx, id); err != nil {
+	// Workers look the workflow up as its runs go on, so it can't be
+	// hidden from them mid-run
+	active, err := h.db.CountActiveRuns(ctx, id, "", workflows.ActiveRunAge)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if active > 0 {
+		http.Error(w, fmt.Sprintf("Workflow has %d runs active", active), http.StatusConflict)
+		return
+	}
+
+	trashed, err := h.db.TrashWorkflowDefinition(ctx, id)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if !trashed {
+		http.Error(w, "Workflow not found", http.StatusNotFound)
+		return
+	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// RestoreWorkflow takes a workflow out of the trash, with its runs
+func (h *Handlers) RestoreWorkflow(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	restored, err := h.db.RestoreWorkflowDefinition(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !restored {
+		http.Error(w, "Workflow not in the trash", http.StatusNotFound)
+		return
+	}
+
+	workflow, err := h.db.GetWorkflowDefinition(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, workflow)
+}
+
 // GenerateWorkflow generates the Temporal workflow code
 func (h *Handlers) GenerateWorkflow(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -273,43 +590,113 @@ func (h *Handlers) GenerateWorkflow(w http.ResponseWriter, r *http.Request) {
 	var params []models.WorkflowParameter
 	json.Unmarshal([]byte(workflow.ParametersJSON), &params)
 
-	// Get LLM provider preference from request
+	// Get LLM provider preference and output format from request
 	var req struct {
-		LLMProvider string `json:"llm_provider"`
+		LLMProvider string             `json:"llm_provider"`
+		Format      string             `json:"format"` // "llm" (default), "script" or "test"
+		Headless    bool               `json:"headless"`
+		Package     string             `json:"package"`      // package clause for the "test" format
+		PageObjects bool               `json:"page_objects"` // group selectors into page object structs
+		HumanLike   bool               `json:"human_like"`   // mouse trajectories and typing jitter
+		Delay       models.DelayConfig `json:"delay"`
 	}
 	json.NewDecoder(r.Body).Decode(&req)
 
-	providerName := req.LLMProvider
-	if providerName == "" {
-		providerName = "ollama"
+	if err := req.Delay.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	// Generate workflow code using LLM
-	config, ok := h.llmConfigs[providerName]
-	if !ok {
-		config = h.llmConfigs["ollama"]
+	var code, providerName string
+	switch req.Format {
+	case "", "llm":
+		providerName = req.LLMProvider
+		if providerName == "" {
+			providerName = "ollama"
+		}
+
+		// Generate workflow code using LLM
+		config, ok := h.llmConfigs[providerName]
+		if !ok {
+			config = h.llmConfigs["ollama"]
+		}
+
+		provider, _ := llm.NewProvider(config)
+		code, err = provider.GenerateCompleteWorkflow(ctx, actions, params)
+		if err != nil {
+			http.Error(w, "Failed to generate workflow: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+	case "script":
+		// Template-based standalone program with a CLI flag per variable token
+		code = codegen.GenerateGoRodScript(actions, params, codegen.ScriptOptions{
+			Headless:    req.Headless,
+			PageObjects: req.PageObjects,
+			HumanLike:   req.HumanLike,
+			Delay:       req.Delay,
+		})
+
+	case "test":
+		// go test function with error checks and assertions from mutation context
+		code = codegen.GenerateGoRodTest(actions, params, codegen.ScriptOptions{
+			Headless:    req.Headless,
+			Package:     req.Package,
+			PageObjects: req.PageObjects,
+			HumanLike:   req.HumanLike,
+			Delay:       req.Delay,
+		})
+
+	default:
+		http.Error(w, "Unknown format: "+req.Format, http.StatusBadRequest)
+		return
 	}
 
-	provider, _ := llm.NewProvider(config)
-	code, err := provider.GenerateCompleteWorkflow(ctx, actions, params)
-	if err != nil {
-		http.Error(w, "Failed to generate workflow: "+err.Error(), http.StatusInternalServerError)
+	// Update workflow as generated, keeping the code for artifact downloads
+	format := req.Format
+	if format == "" {
+		format = "llm"
+	}
+	if err := h.db.SaveGeneratedCode(ctx, id, workflow.Revision, format, code); err != nil {
+		if errors.Is(err, database.ErrConflict) {
+			http.Error(w, "Workflow changed while its code was generated; generate it again", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to save generated code: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Update workflow as generated
-	workflow.IsWorkflowGenerated = true
-	h.db.UpdateWorkflowDefinition(ctx, workflow)
+	// Keep every generation so it can be diffed against the next one
+	generation := &models.CodeGeneration{
+		ID:          uuid.New().String(),
+		WorkflowID:  id,
+		Format:      format,
+		LLMProvider: providerName,
+		Code:        code,
+	}
+	if err := h.db.CreateCodeGeneration(ctx, generation); err != nil {
+		http.Error(w, "Failed to save generated code: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	version, err := h.recordVersion(ctx, id, actions, params, models.VersionRegenerated, generation.Version)
+	if err != nil {
+		http.Error(w, "Failed to create version: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	respondJSON(w, map[string]interface{}{
-		"workflow_id": id,
-		"code":        code,
-		"generated":   true,
+		"workflow_id":      id,
+		"format":           req.Format,
+		"code":             code,
+		"version":          generation.Version,
+		"workflow_version": version.Version,
+		"generated":        true,
 	})
 }
 
-// GetWorkflowActions returns the semantic actions for a workflow
-func (h *Handlers) GetWorkflowActions(w http.ResponseWriter, r *http.Request) {
+// ListGenerations lists the stored code generations of a workflow
+func (h *Handlers) ListGenerations(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -319,454 +706,4417 @@ func (h *Handlers) GetWorkflowActions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	actions, err := h.db.GetSemanticActions(ctx, id)
+	generations, err := h.db.ListCodeGenerations(ctx, id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	respondJSON(w, actions)
+	respondJSON(w, generations)
 }
 
-// ==================== Run Handlers ====================
-
-// ExecuteWorkflow executes a workflow
-func (h *Handlers) ExecuteWorkflow(w http.ResponseWriter, r *http.Request) {
+// DiffGenerations returns a unified diff between two code generations of a
+// workflow. "to" defaults to the latest version and "from" to the one before it.
+func (h *Handlers) DiffGenerations(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	vars := mux.Vars(r)
-	workflowID := vars["id"]
+	id := vars["id"]
 
-	var req models.ExecuteRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
 		return
 	}
-	req.WorkflowID = workflowID
 
-	// Get workflow
-	if h.db == nil {
-		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+	var from, to int
+	for name, dst := range map[string]*int{"from": &from, "to": &to} {
+		if v := r.URL.Query().Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				http.Error(w, "Invalid "+name+" version", http.StatusBadRequest)
+				return
+			}
+			*dst = n
+		}
+	}
+
+	newGen, err := h.db.GetCodeGeneration(ctx, id, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if newGen == nil {
+		http.Error(w, "Generation not found", http.StatusNotFound)
 		return
 	}
 
-	workflow, err := h.db.GetWorkflowDefinition(ctx, workflowID)
-	if err != nil || workflow == nil {
-		http.Error(w, "Workflow not found", http.StatusNotFound)
+	if from == 0 {
+		from = newGen.Version - 1
+	}
+	if from < 1 {
+		http.Error(w, "No earlier generation to compare with", http.StatusNotFound)
+		return
+	}
+	oldGen, err := h.db.GetCodeGeneration(ctx, id, from)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if oldGen == nil {
+		http.Error(w, "Generation not found", http.StatusNotFound)
 		return
 	}
 
-	actions, _ := h.db.GetSemanticActions(ctx, workflowID)
+	diff := codegen.UnifiedDiff(oldGen.Code, newGen.Code,
+		fmt.Sprintf("v%d (%s)", oldGen.Version, oldGen.Format),
+		fmt.Sprintf("v%d (%s)", newGen.Version, newGen.Format))
 
-	// Filter actions to remove noise (focus/blur and low-rank clicks)
-	filteredActions := make([]models.SemanticAction, 0, len(actions))
-	for _, action := range actions {
-		// Filter out Focus/Blur actions as they are unreliable/noisy
-		if action.ActionType == models.ActionFocus || action.ActionType == models.ActionBlur {
-			continue
-		}
-		// Filter out low-importance clicks (unless rank is not set)
-		if action.ActionType == models.ActionClick && action.InteractionRank == models.RankLow {
-			continue
-		}
-		filteredActions = append(filteredActions, action)
-	}
-	actions = filteredActions
+	// The diff carries the changes; leave the full code out of the response
+	oldGen.Code, newGen.Code = "", ""
+	respondJSON(w, map[string]interface{}{
+		"workflow_id": id,
+		"from":        oldGen,
+		"to":          newGen,
+		"diff":        diff,
+	})
+}
 
-	// Create run record
-	runID := uuid.New().String()
-	paramsJSON, _ := json.Marshal(req.Parameters)
+// GetWorkflowActions returns the semantic actions for a workflow
+func (h *Handlers) GetWorkflowActions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	id := vars["id"]
 
-	run := &models.WorkflowRun{
-		ID:             runID,
-		WorkflowID:     workflowID,
-		Status:         models.StatusPending,
-		ParametersJSON: string(paramsJSON),
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
 	}
 
-	if err := h.db.CreateWorkflowRun(ctx, run); err != nil {
-		http.Error(w, "Failed to create run: "+err.Error(), http.StatusInternalServerError)
+	actions, err := h.db.GetSemanticActions(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	respondJSON(w, actions)
+}
+
+// UpdateWorkflowActions replaces the actions of a workflow, numbering them in
+// their order, and records a new version
+func (h *Handlers) UpdateWorkflowActions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var actions []models.SemanticAction
+	if err := json.NewDecoder(r.Body).Decode(&actions); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	for _, action := range actions {
+		if err := action.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	workflow, err := h.db.GetWorkflowDefinition(ctx, id)
+	if err != nil || workflow == nil {
+		http.Error(w, "Workflow not found", http.StatusNotFound)
+		return
+	}
+	if !ifMatch(w, r, workflow) {
+		return
+	}
+
+	h.saveActions(w, r, workflow, actions)
+}
+
+// CloneRequest names the copy of a workflow
+type CloneRequest struct {
+	Name string `json:"name"`
+}
+
+// CloneWorkflow copies a workflow, its actions, parameters, settings and
+// tags to a new workflow, which can be changed without touching the
+// original. The copy is named "<name> (copy)" unless the body names it, and
+// its code is generated again.
+func (h *Handlers) CloneWorkflow(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req CloneRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	original, err := h.db.GetWorkflowDefinition(ctx, id)
+	if err != nil || original == nil {
+		http.Error(w, "Workflow not found", http.StatusNotFound)
+		return
+	}
+	actions, err := h.db.GetSemanticActions(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if req.Name == "" {
+		req.Name = original.Name + " (copy)"
+	}
+	if err := (models.WorkflowUpdate{Name: &req.Name}).Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The copy shares the uploaded events file, which is never changed
+	workflow := &models.WorkflowDefinition{
+		ID:              uuid.New().String(),
+		Name:            strings.TrimSpace(req.Name),
+		Description:     original.Description,
+		EventsFilePath:  original.EventsFilePath,
+		StartURL:        original.StartURL,
+		SemanticContext: original.SemanticContext,
+		ParametersJSON:  original.ParametersJSON,
+		Settings:        original.Settings,
+		Tags:            original.Tags,
+	}
+	if err := h.db.CreateWorkflowDefinition(ctx, workflow); err != nil {
+		http.Error(w, "Failed to create workflow: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.db.SaveWorkflowSettings(ctx, workflow.ID, workflow.Settings); err != nil {
+		http.Error(w, "Failed to save settings: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.db.AddWorkflowTags(ctx, workflow.ID, workflow.Tags); err != nil {
+		http.Error(w, "Failed to tag workflow: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	for i := range actions {
+		actions[i].ID = uuid.New().String()
+		actions[i].WorkflowID = workflow.ID
+	}
+	if err := h.db.CreateSemanticActions(ctx, workflow.ID, actions); err != nil {
+		http.Error(w, "Failed to store actions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var params []models.WorkflowParameter
+	json.Unmarshal([]byte(workflow.ParametersJSON), &params)
+
+	if _, err := h.recordVersion(ctx, workflow.ID, actions, params, models.VersionCloned, 0); err != nil {
+		http.Error(w, "Failed to create version: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	workflow.Actions = actions
+	workflow.Parameters = params
+
+	audit.SetResource(ctx, workflow.ID)
+	respondJSON(w, workflow)
+}
+
+// MergeRequest names the workflows to merge, first to last, and how
+type MergeRequest struct {
+	WorkflowIDs []string `json:"workflow_ids"`
+	Name        string   `json:"name"`
+	// Mode is "concat", running each workflow's actions after the previous
+	// one's (the default), or "interleave", running them in the order they
+	// were recorded in
+	Mode string `json:"mode"`
+}
+
+// MergeWorkflows creates a workflow from the actions and parameters of
+// others, such as a login recorded apart from the flow that follows it.
+// The new workflow starts at the first one's start URL, has its settings and
+// events file, and has the tags of all of them.
+func (h *Handlers) MergeWorkflows(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req MergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.WorkflowIDs) < 2 {
+		http.Error(w, "workflow_ids must name at least two workflows", http.StatusBadRequest)
+		return
+	}
+	if req.Mode != "" && req.Mode != "concat" && req.Mode != "interleave" {
+		http.Error(w, "mode must be concat or interleave", http.StatusBadRequest)
+		return
+	}
+
+	var originals []*models.WorkflowDefinition
+	var recordings []models.Recording
+	var names, tags []string
+	for _, id := range req.WorkflowIDs {
+		original, err := h.db.GetWorkflowDefinition(ctx, id)
+		if err != nil || original == nil {
+			http.Error(w, "Workflow not found: "+id, http.StatusNotFound)
+			return
+		}
+		actions, err := h.db.GetSemanticActions(ctx, id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var params []models.WorkflowParameter
+		json.Unmarshal([]byte(original.ParametersJSON), &params)
+
+		originals = append(originals, original)
+		recordings = append(recordings, models.Recording{Actions: actions, Parameters: params})
+		names = append(names, original.Name)
+		for _, tag := range original.Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	if req.Name == "" {
+		req.Name = strings.Join(names, " + ")
+	}
+	if err := (models.WorkflowUpdate{Name: &req.Name}).Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	merged := models.MergeRecordings(recordings, req.Mode == "interleave")
+	actionsJSON, _ := json.Marshal(merged.Actions)
+	paramsJSON, _ := json.Marshal(merged.Parameters)
+
+	first := originals[0]
+	workflow := &models.WorkflowDefinition{
+		ID:              uuid.New().String(),
+		Name:            strings.TrimSpace(req.Name),
+		EventsFilePath:  first.EventsFilePath,
+		StartURL:        first.StartURL,
+		SemanticContext: string(actionsJSON),
+		ParametersJSON:  string(paramsJSON),
+		Settings:        first.Settings,
+		Tags:            tags,
+	}
+	if err := h.db.CreateWorkflowDefinition(ctx, workflow); err != nil {
+		http.Error(w, "Failed to create workflow: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.db.SaveWorkflowSettings(ctx, workflow.ID, workflow.Settings); err != nil {
+		http.Error(w, "Failed to save settings: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.db.AddWorkflowTags(ctx, workflow.ID, workflow.Tags); err != nil {
+		http.Error(w, "Failed to tag workflow: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	for i := range merged.Actions {
+		merged.Actions[i].ID = uuid.New().String()
+		merged.Actions[i].WorkflowID = workflow.ID
+	}
+	if err := h.db.CreateSemanticActions(ctx, workflow.ID, merged.Actions); err != nil {
+		http.Error(w, "Failed to store actions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := h.recordVersion(ctx, workflow.ID, merged.Actions, merged.Parameters, models.VersionMerged, 0); err != nil {
+		http.Error(w, "Failed to create version: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	workflow.Actions = merged.Actions
+	workflow.Parameters = merged.Parameters
+
+	audit.SetResource(ctx, workflow.ID)
+	respondJSON(w, workflow)
+}
+
+// InsertWorkflowAction adds a step, such as one the recording missed, to a
+// workflow before the action whose sequence_id it has, or after the last one
+// when it has none, and records a new version
+func (h *Handlers) InsertWorkflowAction(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var action models.SemanticAction
+	if err := json.NewDecoder(r.Body).Decode(&action); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !action.ActionType.Known() {
+		http.Error(w, fmt.Sprintf("Unknown action type %q", action.ActionType), http.StatusBadRequest)
+		return
+	}
+
+	workflow, actions, ok := h.workflowActions(w, r)
+	if !ok {
+		return
+	}
+	if action.SequenceID == 0 {
+		action.SequenceID = len(actions) + 1
+	}
+	if action.SequenceID < 1 || action.SequenceID > len(actions)+1 {
+		http.Error(w, fmt.Sprintf("sequence_id must be between 1 and %d", len(actions)+1), http.StatusBadRequest)
+		return
+	}
+	if err := action.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The action is new, so it has no number to carry over
+	position := action.SequenceID - 1
+	action.SequenceID = 0
+	h.saveActions(w, r, workflow, slices.Insert(actions, position, action))
+}
+
+// UpdateWorkflowAction changes the fields of an action given in the body,
+// such as its target's selector or its value, and records a new version
+func (h *Handlers) UpdateWorkflowAction(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	workflow, actions, ok := h.workflowActions(w, r)
+	if !ok {
+		return
+	}
+	i, ok := actionIndex(w, r, actions)
+	if !ok {
+		return
+	}
+
+	// Decoding over the action keeps the fields the body leaves out
+	action := actions[i]
+	if err := json.NewDecoder(r.Body).Decode(&action); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	action.SequenceID = actions[i].SequenceID
+	if !action.ActionType.Known() {
+		http.Error(w, fmt.Sprintf("Unknown action type %q", action.ActionType), http.StatusBadRequest)
+		return
+	}
+	if err := action.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	actions[i] = action
+
+	h.saveActions(w, r, workflow, actions)
+}
+
+// DeleteWorkflowAction removes an action, such as noise the extractor kept,
+// from a workflow and records a new version
+func (h *Handlers) DeleteWorkflowAction(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	workflow, actions, ok := h.workflowActions(w, r)
+	if !ok {
+		return
+	}
+	i, ok := actionIndex(w, r, actions)
+	if !ok {
+		return
+	}
+
+	h.saveActions(w, r, workflow, slices.Delete(actions, i, i+1))
+}
+
+// ReorderRequest lists the sequence IDs of every action of a workflow in
+// the order they should run in
+type ReorderRequest struct {
+	Order []int `json:"order"`
+}
+
+// ReorderWorkflowActions changes the order a workflow's actions run in and
+// records a new version
+func (h *Handlers) ReorderWorkflowActions(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req ReorderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	workflow, actions, ok := h.workflowActions(w, r)
+	if !ok {
+		return
+	}
+	reordered, err := models.ReorderActions(actions, req.Order)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.saveActions(w, r, workflow, reordered)
+}
+
+// workflowActions returns the workflow the URL names and its actions,
+// writing the error when it can't or the request was made from an earlier
+// revision
+func (h *Handlers) workflowActions(w http.ResponseWriter, r *http.Request) (*models.WorkflowDefinition, []models.SemanticAction, bool) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+
+	workflow, err := h.db.GetWorkflowDefinition(ctx, id)
+	if err != nil || workflow == nil {
+		http.Error(w, "Workflow not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+	if !ifMatch(w, r, workflow) {
+		return nil, nil, false
+	}
+	actions, err := h.db.GetSemanticActions(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	return workflow, actions, true
+}
+
+// actionIndex returns the index of the action whose sequence ID the URL
+// names, writing a 404 when there is none
+func actionIndex(w http.ResponseWriter, r *http.Request, actions []models.SemanticAction) (int, bool) {
+	sequenceID, _ := strconv.Atoi(mux.Vars(r)["seq"])
+	i := slices.IndexFunc(actions, func(a models.SemanticAction) bool { return a.SequenceID == sequenceID })
+	if i < 0 {
+		http.Error(w, "Action not found", http.StatusNotFound)
+		return 0, false
+	}
+	return i, true
+}
+
+// saveActions stores edited actions, numbered in their order, as a new
+// version of a workflow and clears its generated program, which no longer
+// matches them
+func (h *Handlers) saveActions(w http.ResponseWriter, r *http.Request, workflow *models.WorkflowDefinition, actions []models.SemanticAction) {
+	ctx := r.Context()
+
+	var params []models.WorkflowParameter
+	if workflow.ParametersJSON != "" {
+		if err := json.Unmarshal([]byte(workflow.ParametersJSON), &params); err != nil {
+			http.Error(w, "Failed to read parameters: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Stored actions are immutable per version, so edited actions get new
+	// IDs. The parameters follow their actions to their new numbers.
+	params = models.RenumberParameters(params, models.ResequenceActions(actions))
+	for i := range actions {
+		actions[i].ID = uuid.New().String()
+		actions[i].WorkflowID = workflow.ID
+	}
+	paramsJSON, _ := json.Marshal(params)
+	if err := h.db.ReplaceSemanticActions(ctx, workflow.ID, workflow.Revision, actions, string(paramsJSON)); err != nil {
+		updateFailed(w, err)
+		return
+	}
+	workflow.Revision++
+	workflow.ParametersJSON = string(paramsJSON)
+	if err := h.db.ClearGeneratedCode(ctx, workflow.ID); err != nil {
+		http.Error(w, "Failed to update workflow: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	version, err := h.recordVersion(ctx, workflow.ID, actions, params, models.VersionActionsEdited, 0)
+	if err != nil {
+		http.Error(w, "Failed to create version: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", workflowETag(workflow))
+	respondJSON(w, version)
+}
+
+// UpdateWorkflow changes the name, description, start URL or parameter
+// defaults of a workflow, recording a new version when a default changes
+func (h *Handlers) UpdateWorkflow(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var update models.WorkflowUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := update.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	workflow, err := h.db.GetWorkflowDefinition(ctx, id)
+	if err != nil || workflow == nil {
+		http.Error(w, "Workflow not found", http.StatusNotFound)
+		return
+	}
+	if !ifMatch(w, r, workflow) {
+		return
+	}
+
+	var params []models.WorkflowParameter
+	json.Unmarshal([]byte(workflow.ParametersJSON), &params)
+	defaultsChanged, err := update.Apply(workflow, params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if defaultsChanged {
+		paramsJSON, _ := json.Marshal(params)
+		workflow.ParametersJSON = string(paramsJSON)
+	}
+	if err := h.db.UpdateWorkflowDefinition(ctx, workflow); err != nil {
+		updateFailed(w, err)
+		return
+	}
+
+	if defaultsChanged {
+		actions, err := h.db.GetSemanticActions(ctx, id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if _, err := h.recordVersion(ctx, id, actions, params, models.VersionParametersEdited, 0); err != nil {
+			http.Error(w, "Failed to create version: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	workflow.Parameters = params
+	w.Header().Set("ETag", workflowETag(workflow))
+	respondJSON(w, workflow)
+}
+
+// UpdateWorkflowParameters replaces the parameter definitions of a workflow
+// and records a new version
+func (h *Handlers) UpdateWorkflowParameters(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var params []models.WorkflowParameter
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	workflow, err := h.db.GetWorkflowDefinition(ctx, id)
+	if err != nil || workflow == nil {
+		http.Error(w, "Workflow not found", http.StatusNotFound)
+		return
+	}
+	if !ifMatch(w, r, workflow) {
+		return
+	}
+
+	paramsJSON, _ := json.Marshal(params)
+	workflow.ParametersJSON = string(paramsJSON)
+	if err := h.db.UpdateWorkflowDefinition(ctx, workflow); err != nil {
+		updateFailed(w, err)
+		return
+	}
+
+	actions, err := h.db.GetSemanticActions(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	version, err := h.recordVersion(ctx, id, actions, params, models.VersionParametersEdited, 0)
+	if err != nil {
+		http.Error(w, "Failed to create version: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", workflowETag(workflow))
+	respondJSON(w, version)
+}
+
+// UpdateWorkflowSettings replaces the replay settings of a workflow
+func (h *Handlers) UpdateWorkflowSettings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var settings models.WorkflowSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := settings.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	workflow, err := h.db.GetWorkflowDefinition(ctx, id)
+	if err != nil || workflow == nil {
+		http.Error(w, "Workflow not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.db.SaveWorkflowSettings(ctx, id, settings); err != nil {
+		http.Error(w, "Failed to update workflow: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, settings)
+}
+
+// ListWorkflowVersions lists the versions of a workflow
+func (h *Handlers) ListWorkflowVersions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	versions, err := h.db.ListWorkflowVersions(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, versions)
+}
+
+// GetWorkflowVersion returns one version of a workflow with its actions and parameters
+func (h *Handlers) GetWorkflowVersion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	number, err := strconv.Atoi(vars["version"])
+	if err != nil || number < 1 {
+		http.Error(w, "Invalid version", http.StatusBadRequest)
+		return
+	}
+
+	version, err := h.db.GetWorkflowVersion(ctx, id, number)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if version == nil {
+		http.Error(w, "Version not found", http.StatusNotFound)
+		return
+	}
+
+	respondJSON(w, version)
+}
+
+// recordVersion snapshots actions and parameters as the next version of a workflow
+func (h *Handlers) recordVersion(ctx context.Context, workflowID string, actions []models.SemanticAction, params []models.WorkflowParameter, change models.VersionChange, codeVersion int) (*models.WorkflowVersion, error) {
+	version := &models.WorkflowVersion{
+		ID:          uuid.New().String(),
+		WorkflowID:  workflowID,
+		ChangeType:  change,
+		Actions:     actions,
+		Parameters:  params,
+		CodeVersion: codeVersion,
+	}
+	if err := h.db.CreateWorkflowVersion(ctx, version); err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// ==================== Run Handlers ====================
+
+// ExecuteWorkflow executes a workflow
+func (h *Handlers) ExecuteWorkflow(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	workflowID := vars["id"]
+
+	var req models.ExecuteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.WorkflowID = workflowID
+
+	if err := req.Delay.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := req.Browser.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	device, err := req.Device.Resolve()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := models.ValidateLocale(req.Locale, req.Timezone); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	proxy, err := req.Proxy.Resolve()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := req.StorageState.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := req.HTTPCredentials.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := req.Retry.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	taskQueue, err := workflows.TaskQueueFor(req.Requirements, req.Priority)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Session != "" && req.StorageState != nil {
+		http.Error(w, "session and storage_state are mutually exclusive", http.StatusBadRequest)
+		return
+	}
+
+	// Get workflow
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	workflow, err := h.db.GetWorkflowDefinition(ctx, workflowID)
+	if err != nil || workflow == nil {
+		http.Error(w, "Workflow not found", http.StatusNotFound)
+		return
+	}
+
+	// At the workflow's concurrency limit the run is queued to wait for a
+	// free slot, or rejected when the run is created
+	status := "running"
+	limit := workflow.Settings.MaxConcurrentRuns
+	if limit > 0 && workflow.Settings.QueueRuns && !req.DryRun {
+		active, err := h.db.CountActiveRuns(ctx, workflowID, "", workflows.ActiveRunAge)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if active >= limit {
+			status = "queued"
+		}
+	}
+
+	// Parse workflow parameter definitions
+	var paramsDef []models.WorkflowParameter
+	if workflow.ParametersJSON != "" {
+		// Ignore error if parameters are malformed, treat as empty
+		_ = json.Unmarshal([]byte(workflow.ParametersJSON), &paramsDef)
+	}
+	actions, _ := h.db.GetSemanticActions(ctx, workflowID)
+
+	// Start from a saved session's cookies and localStorage, which the
+	// worker opens so they stay encrypted until the browser needs them
+	if req.Session != "" {
+		sealed, err := h.db.GetBrowserSessionState(ctx, req.Session)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if sealed == "" {
+			http.Error(w, fmt.Sprintf("Session %q not found", req.Session), http.StatusNotFound)
+			return
+		}
+	}
+
+	// Run a pinned version's snapshot instead of the live actions. Unpinned
+	// runs record the latest version for reference.
+	version, err := h.db.GetWorkflowVersion(ctx, workflowID, req.Version)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if req.Version > 0 {
+		if version == nil {
+			http.Error(w, fmt.Sprintf("Workflow version %d not found", req.Version), http.StatusNotFound)
+			return
+		}
+		actions = version.Actions
+		paramsDef = version.Parameters
+	}
+
+	actions = replayActions(actions)
+
+	// Custom code templates travel with the run so workers use the current set
+	codeTemplates, err := h.codeTemplateOverrides(ctx)
+	if err != nil {
+		http.Error(w, "Failed to load code templates: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	runID := uuid.New().String()
+	logging.Add(ctx, "workflowID", workflowID, "runID", runID)
+
 	// Start Temporal workflow
-	// Get API key from runtime keys if available
-	llmAPIKey := ""
-	if key, ok := h.runtimeAPIKeys[req.LLMProvider]; ok {
-		llmAPIKey = key
-	} else if config, ok := h.llmConfigs[req.LLMProvider]; ok {
-		llmAPIKey = config.APIKey
+	llmAPIKey := h.llmAPIKey(req.LLMProvider)
+
+	input := models.WorkflowInput{
+		WorkflowID:      workflowID,
+		RunID:           runID,
+		Parameters:      req.Parameters,
+		Params:          paramsDef,
+		Actions:         actions,
+		LLMProvider:     req.LLMProvider,
+		LLMAPIKey:       llmAPIKey,
+		Headless:        req.Headless,
+		ReuseBrowser:    req.ReuseBrowser,
+		BrowserEndpoint: req.BrowserEndpoint,
+		Browser:         req.Browser,
+		Device:          device,
+		UserAgent:       req.UserAgent,
+		Locale:          req.Locale,
+		Timezone:        req.Timezone,
+		Proxy:           proxy,
+		Stealth:         req.Stealth,
+		StorageState:    req.StorageState,
+		Session:         req.Session,
+		SaveSession:     req.SaveSession,
+		HTTPCredentials: req.HTTPCredentials,
+		Dialogs:         workflow.Settings.Dialogs,
+		Delay:           req.Delay,
+		CodeTemplates:   codeTemplates,
+		Timeout:         300,
+		RetryAttempts:   3,
+		Retry:           req.Retry,
+
+		SkipStabilityChecks: workflow.Settings.SkipStabilityChecks,
+		HealSelectors:       workflow.Settings.HealSelectors && req.Version == 0,
+		DecisionTimeout:     workflow.Settings.DecisionTimeout,
+
+		Loops: workflow.Settings.Loops,
+
+		MaxConcurrentRuns: workflow.Settings.MaxConcurrentRuns,
+		Notifications:     workflow.Settings.Notifications,
+
+		Debug:          req.Debug,
+		Screenshots:    req.Screenshots,
+		Accessibility:  req.Accessibility,
+		VerifyOutcomes: req.VerifyOutcomes,
+		Priority:       req.Priority,
+	}
+
+	if req.DryRun {
+		h.dryRun(w, r, taskQueue, input)
+		return
+	}
+
+	// Create run record
+	paramsJSON, _ := json.Marshal(req.Parameters)
+
+	run := &models.WorkflowRun{
+		ID:             runID,
+		WorkflowID:     workflowID,
+		Status:         models.StatusPending,
+		ParametersJSON: string(paramsJSON),
+	}
+	if version != nil {
+		run.WorkflowVersion = version.Version
+	}
+
+	// A rejecting workflow's run takes its slot as it is created, so runs
+	// requested at once can't all pass the limit
+	if limit > 0 && !workflow.Settings.QueueRuns {
+		reserved, active, err := h.db.ReserveWorkflowRun(ctx, run, limit, workflows.ActiveRunAge)
+		if err != nil {
+			http.Error(w, "Failed to create run: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !reserved {
+			http.Error(w, fmt.Sprintf("Workflow already has %d of at most %d runs active", active, limit), http.StatusTooManyRequests)
+			return
+		}
+	} else if err := h.db.CreateWorkflowRun(ctx, run); err != nil {
+		http.Error(w, "Failed to create run: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	workflowOptions := client.StartWorkflowOptions{
+		ID:        fmt.Sprintf("browser-automation-%s", runID),
+		TaskQueue: taskQueue,
+	}
+
+	we, err := h.temporalClient.ExecuteWorkflow(ctx, workflowOptions, "BrowserAutomationWorkflow", input)
+	if err != nil {
+		h.db.UpdateWorkflowRunStatus(ctx, runID, models.StatusFailed, err.Error())
+		http.Error(w, "Failed to start workflow: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Update run with Temporal IDs and mark as running
+	h.db.UpdateWorkflowRunStarted(ctx, runID, we.GetID(), we.GetRunID())
+
+	audit.SetResource(ctx, runID)
+	respondJSON(w, map[string]interface{}{
+		"run_id":               runID,
+		"temporal_workflow_id": we.GetID(),
+		"temporal_run_id":      we.GetRunID(),
+		"workflow_version":     run.WorkflowVersion,
+		"task_queue":           taskQueue,
+		"status":               status,
+	})
+}
+
+// dryRunTimeout bounds how long a dry run's response waits for its code to
+// be generated, which can take minutes with an LLM
+const dryRunTimeout = 20 * time.Minute
+
+// dryRun runs the workflow without a browser and responds with the plan,
+// the code generated for each step and the values it would use
+func (h *Handlers) dryRun(w http.ResponseWriter, r *http.Request, taskQueue string, input models.WorkflowInput) {
+	// The response outlives the server's write timeout
+	ctx, cancel := context.WithTimeout(r.Context(), dryRunTimeout)
+	defer cancel()
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(dryRunTimeout))
+
+	input.DryRun = true
+	input.MaxConcurrentRuns = 0
+	input.HealSelectors = false
+	input.Notifications = nil
+
+	workflowOptions := client.StartWorkflowOptions{
+		ID:        fmt.Sprintf("browser-automation-dry-run-%s", input.RunID),
+		TaskQueue: taskQueue,
+	}
+	we, err := h.temporalClient.ExecuteWorkflow(ctx, workflowOptions, "BrowserAutomationWorkflow", input)
+	if err != nil {
+		http.Error(w, "Failed to start dry run: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var result models.WorkflowResult
+	if err := we.Get(ctx, &result); err != nil {
+		http.Error(w, "Dry run failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, map[string]interface{}{
+		"dry_run":    true,
+		"parameters": input.Parameters,
+		"plan":       result.Plan,
+	})
+}
+
+// SimulateWorkflow checks the workflow's actions against the DOM its
+// recording captured, flagging selectors that don't resolve without
+// starting a browser
+func (h *Handlers) SimulateWorkflow(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	workflow, err := h.db.GetWorkflowDefinition(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if workflow == nil {
+		http.Error(w, "Workflow not found", http.StatusNotFound)
+		return
+	}
+
+	actions, err := h.db.GetSemanticActions(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if workflow.EventsFilePath == "" {
+		http.Error(w, "Workflow has no recording to simulate against", http.StatusBadRequest)
+		return
+	}
+
+	var events []models.HybridEvent
+	if strings.ToLower(filepath.Ext(workflow.EventsFilePath)) == ".bin" {
+		p := ingestion.NewProtoParser()
+		if err := p.ParseFile(workflow.EventsFilePath); err != nil {
+			http.Error(w, "Failed to read events file: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		events = p.GetEvents()
+	} else {
+		p := ingestion.NewHybridParser()
+		if err := p.ParseFile(workflow.EventsFilePath); err != nil {
+			http.Error(w, "Failed to read events file: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		events = p.GetEvents()
+	}
+
+	checks := simulation.Check(events, actions)
+	failed := 0
+	for _, check := range checks {
+		if check.Failed() {
+			failed++
+		}
+	}
+
+	respondJSON(w, map[string]interface{}{
+		"workflow_id": id,
+		"actions":     checks,
+		"failed":      failed,
+	})
+}
+
+// maxBatchRows caps the rows of a batch CSV
+const maxBatchRows = 1000
+
+// defaultBatchParallelism is how many runs of a batch execute at once
+// unless the request says otherwise
+const defaultBatchParallelism = 5
+
+// defaultBatchPriority is the priority of a batch's runs unless the request
+// says otherwise, so a large batch doesn't hold up runs started one by one
+const defaultBatchPriority = models.PriorityLow
+
+// ExecuteBatch runs a workflow once for each row of an uploaded CSV, whose
+// header names the workflow parameters its columns set
+func (h *Handlers) ExecuteBatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	workflowID := vars["id"]
+
+	if err := r.ParseMultipartForm(100 << 20); err != nil { // 100MB max
+		http.Error(w, "Failed to parse form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Missing file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		http.Error(w, "Invalid CSV: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(records) < 2 {
+		http.Error(w, "CSV needs a header row and at least one data row", http.StatusBadRequest)
+		return
+	}
+	if len(records)-1 > maxBatchRows {
+		http.Error(w, fmt.Sprintf("CSV has more than %d rows", maxBatchRows), http.StatusBadRequest)
+		return
+	}
+
+	parallelism := defaultBatchParallelism
+	if v := r.FormValue("parallelism"); v != "" {
+		parallelism, err = strconv.Atoi(v)
+		if err != nil || parallelism <= 0 {
+			http.Error(w, "parallelism must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+	llmProvider := r.FormValue("llm_provider")
+	headless := r.FormValue("headless") != "false"
+	requirements, err := workflows.ParseLabels(r.FormValue("requirements"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	priority := defaultBatchPriority
+	if v := r.FormValue("priority"); v != "" {
+		priority = models.RunPriority(v)
+	}
+	taskQueue, err := workflows.TaskQueueFor(requirements, priority)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	workflow, err := h.db.GetWorkflowDefinition(ctx, workflowID)
+	if err != nil || workflow == nil {
+		http.Error(w, "Workflow not found", http.StatusNotFound)
+		return
+	}
+
+	var paramsDef []models.WorkflowParameter
+	if workflow.ParametersJSON != "" {
+		_ = json.Unmarshal([]byte(workflow.ParametersJSON), &paramsDef)
+	}
+	known := make(map[string]bool, len(paramsDef))
+	for _, param := range paramsDef {
+		known[param.Name] = true
+	}
+
+	header := records[0]
+	for i, name := range header {
+		header[i] = strings.TrimSpace(name)
+		if !known[header[i]] {
+			http.Error(w, fmt.Sprintf("Column %q is not a parameter of the workflow", header[i]), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Runs beyond the workflow's concurrency limit would only wait for a slot
+	if limit := workflow.Settings.MaxConcurrentRuns; limit > 0 && parallelism > limit {
+		parallelism = limit
+	}
+
+	actions, _ := h.db.GetSemanticActions(ctx, workflowID)
+	actions = replayActions(actions)
+
+	codeTemplates, err := h.codeTemplateOverrides(ctx)
+	if err != nil {
+		http.Error(w, "Failed to load code templates: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	version, err := h.db.GetWorkflowVersion(ctx, workflowID, 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Create a run record per row, empty cells keeping the recorded value
+	batchID := uuid.New().String()
+	runConfigs := make([]workflows.RunConfig, 0, len(records)-1)
+	for _, record := range records[1:] {
+		params := make(map[string]string, len(header))
+		for i, value := range record {
+			if value != "" {
+				params[header[i]] = value
+			}
+		}
+
+		runID := uuid.New().String()
+		paramsJSON, _ := json.Marshal(params)
+		// Each run executes as a child workflow with a single run's ID, so it
+		// can be watched, paused and cancelled on its own. It stays pending
+		// until the batch starts it.
+		run := &models.WorkflowRun{
+			ID:                 runID,
+			WorkflowID:         workflowID,
+			TemporalWorkflowID: "browser-automation-" + runID,
+			Status:             models.StatusPending,
+			ParametersJSON:     string(paramsJSON),
+		}
+		if version != nil {
+			run.WorkflowVersion = version.Version
+		}
+		if err := h.db.CreateWorkflowRun(ctx, run); err != nil {
+			http.Error(w, "Failed to create run: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		runConfigs = append(runConfigs, workflows.RunConfig{RunID: runID, Parameters: params})
+	}
+
+	input := workflows.ParallelWorkflowInput{
+		WorkflowID:    workflowID,
+		Actions:       actions,
+		RunConfigs:    runConfigs,
+		LLMProvider:   llmProvider,
+		Headless:      headless,
+		Parallelism:   parallelism,
+		Priority:      priority,
+		Params:        paramsDef,
+		LLMAPIKey:     h.llmAPIKey(llmProvider),
+		Settings:      workflow.Settings,
+		CodeTemplates: codeTemplates,
+	}
+
+	// The batch's runs execute on its task queue
+	workflowOptions := client.StartWorkflowOptions{
+		ID:        batchWorkflowID(batchID),
+		TaskQueue: taskQueue,
+	}
+
+	if _, err := h.temporalClient.ExecuteWorkflow(ctx, workflowOptions, "ParallelBrowserAutomationWorkflow", input); err != nil {
+		for _, rc := range runConfigs {
+			h.db.UpdateWorkflowRunStatus(ctx, rc.RunID, models.StatusFailed, err.Error())
+		}
+		http.Error(w, "Failed to start batch: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	runIDs := make([]string, len(runConfigs))
+	for i, rc := range runConfigs {
+		runIDs[i] = rc.RunID
+	}
+
+	audit.SetResource(ctx, batchID)
+	respondJSON(w, map[string]interface{}{
+		"batch_id": batchID,
+		"run_ids":  runIDs,
+		"status":   "running",
+	})
+}
+
+// GetBatch returns the status of a batch's runs, counted by status
+func (h *Handlers) GetBatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	resp, err := h.temporalClient.QueryWorkflow(ctx, batchWorkflowID(id), "", "getProgress")
+	if err != nil {
+		http.Error(w, "Batch not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+	var progress workflows.ParallelWorkflowResult
+	if err := resp.Get(&progress); err != nil {
+		http.Error(w, "Failed to read batch progress: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	counts := make(map[models.RunStatus]int)
+	runs := make([]map[string]interface{}, len(progress.Results))
+	for i, result := range progress.Results {
+		counts[result.Status]++
+		runs[i] = map[string]interface{}{
+			"run_id":        result.RunID,
+			"status":        result.Status,
+			"error_message": result.ErrorMessage,
+		}
+	}
+
+	respondJSON(w, map[string]interface{}{
+		"batch_id": id,
+		"status":   batchStatus(counts, len(progress.Results)),
+		"total":    len(progress.Results),
+		"counts":   counts,
+		"runs":     runs,
+	})
+}
+
+// batchStatus sums up a batch of total runs by the number of them in each
+// status: running until every run ended, then failed when none of them
+// succeeded, partial when some did and succeeded when all did
+func batchStatus(counts map[models.RunStatus]int, total int) models.RunStatus {
+	if counts[models.StatusPending]+counts[models.StatusRunning]+counts[models.StatusPaused] > 0 {
+		return models.StatusRunning
+	}
+	unsuccessful := counts[models.StatusFailed] + counts[models.StatusCanceled]
+	switch {
+	case unsuccessful > 0 && unsuccessful == total:
+		return models.StatusFailed
+	case unsuccessful > 0:
+		return models.StatusPartial
+	default:
+		return models.StatusSuccess
+	}
+}
+
+// batchWorkflowID is the Temporal workflow ID of a batch
+func batchWorkflowID(batchID string) string {
+	return fmt.Sprintf("browser-automation-batch-%s", batchID)
+}
+
+// replayActions drops the recorded actions runs skip as noise: focus and
+// blur, and clicks ranked unimportant
+func replayActions(actions []models.SemanticAction) []models.SemanticAction {
+	filtered := make([]models.SemanticAction, 0, len(actions))
+	for _, action := range actions {
+		// Filter out Focus/Blur actions as they are unreliable/noisy
+		if action.ActionType == models.ActionFocus || action.ActionType == models.ActionBlur {
+			continue
+		}
+		// Filter out low-importance clicks (unless rank is not set)
+		if action.ActionType == models.ActionClick && action.InteractionRank == models.RankLow {
+			continue
+		}
+		filtered = append(filtered, action)
+	}
+	return filtered
+}
+
+// llmAPIKey returns the API key for an LLM provider, preferring the one
+// set at runtime from the UI
+func (h *Handlers) llmAPIKey(provider string) string {
+	if key, ok := h.runtimeAPIKeys[provider]; ok {
+		return key
+	}
+	if config, ok := h.llmConfigs[provider]; ok {
+		return config.APIKey
+	}
+	return ""
+}
+
+// GetWorkflowAnalytics reports each action's failure rate, retries and
+// duration spread across the workflow's runs, optionally over the last
+// days given in ?days=
+func (h *Handlers) GetWorkflowAnalytics(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	days, ok := queryDays(w, r)
+	if !ok {
+		return
+	}
+
+	analytics, err := h.db.GetWorkflowAnalytics(ctx, id, days)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	descriptions := h.actionDescriptions(ctx, id)
+	for i := range analytics.Actions {
+		analytics.Actions[i].Description = descriptions[analytics.Actions[i].ActionID]
+	}
+
+	respondJSON(w, analytics)
+}
+
+// GetSelectorHealth reports which kinds of selector found each action's
+// element across the workflow's runs, flagging the actions whose primary
+// selector consistently misses, optionally over the last days given in
+// ?days=
+func (h *Handlers) GetSelectorHealth(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	days, ok := queryDays(w, r)
+	if !ok {
+		return
+	}
+
+	health, err := h.db.GetSelectorHealth(ctx, id, days)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	descriptions := h.actionDescriptions(ctx, id)
+	for i := range health.Actions {
+		health.Actions[i].Description = descriptions[health.Actions[i].ActionID]
+	}
+
+	respondJSON(w, health)
+}
+
+// actionDescriptions describes the actions still in a workflow by ID
+func (h *Handlers) actionDescriptions(ctx context.Context, workflowID string) map[string]string {
+	actions, _ := h.db.GetSemanticActions(ctx, workflowID)
+	descriptions := make(map[string]string, len(actions))
+	for _, action := range actions {
+		descriptions[action.ID] = codegen.DescribeAction(action)
+	}
+	return descriptions
+}
+
+// queryDays reads the ?days= window of a history report, 0 for all of
+// history, responding with an error when it is invalid
+func queryDays(w http.ResponseWriter, r *http.Request) (int, bool) {
+	v := r.URL.Query().Get("days")
+	if v == "" {
+		return 0, true
+	}
+	days, err := strconv.Atoi(v)
+	if err != nil || days < 1 {
+		http.Error(w, "days must be a positive number", http.StatusBadRequest)
+		return 0, false
+	}
+	return days, true
+}
+
+// ListRuns lists workflow runs
+func (h *Handlers) ListRuns(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	opts, ok := queryList(w, r, models.RunSorts)
+	if !ok {
+		return
+	}
+	tags, ok := queryTags(w, r)
+	if !ok {
+		return
+	}
+	filter := models.RunFilter{
+		ListOptions:  opts,
+		WorkflowID:   r.URL.Query().Get("workflow_id"),
+		WorkflowName: r.URL.Query().Get("name"),
+		Tags:         tags,
+	}
+	// The runs of every workflow are only listed up to the latest ones
+	if filter.WorkflowID == "" && filter.Limit == 0 {
+		filter.Limit = defaultRunsLimit
+	}
+	if v := r.URL.Query().Get("status"); v != "" {
+		for _, status := range strings.Split(v, ",") {
+			filter.Statuses = append(filter.Statuses, models.RunStatus(strings.TrimSpace(status)))
+		}
+	}
+
+	runs, total, err := h.db.ListWorkflowRuns(ctx, filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	respondJSON(w, runs)
+}
+
+// defaultRunsLimit is how many runs of every workflow are listed without a
+// ?limit=
+const defaultRunsLimit = 50
+
+// GetRun retrieves a workflow run
+func (h *Handlers) GetRun(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	run, err := h.db.GetWorkflowRun(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if run == nil {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return
+	}
+
+	// Get action results
+	results, _ := h.db.GetActionResults(ctx, id)
+	run.ActionResults = results
+
+	respondJSON(w, run)
+}
+
+// DeleteRun moves a finished run to the trash
+func (h *Handlers) DeleteRun(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	run, err := h.db.GetWorkflowRun(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if run == nil {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return
+	}
+	if !run.Status.Finished() {
+		http.Error(w, "Run has not finished", http.StatusConflict)
+		return
+	}
+
+	if _, err := h.db.TrashWorkflowRun(ctx, id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreRun takes a run out of the trash
+func (h *Handlers) RestoreRun(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	restored, err := h.db.RestoreWorkflowRun(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !restored {
+		http.Error(w, "Run not in the trash", http.StatusNotFound)
+		return
+	}
+
+	run, err := h.db.GetWorkflowRun(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, run)
+}
+
+// GetRunOutput serves the dataset a run's extract actions read as CSV or
+// JSON, laid out by the workflow's output schema
+func (h *Handlers) GetRunOutput(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	run, err := h.db.GetWorkflowRun(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if run == nil {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return
+	}
+	workflow, err := h.db.GetWorkflowDefinition(ctx, run.WorkflowID)
+	if err != nil || workflow == nil {
+		http.Error(w, "Workflow not found", http.StatusNotFound)
+		return
+	}
+	columns := workflow.Settings.Output.ColumnsFor(run.Dataset)
+
+	filename := fmt.Sprintf("run-%s.%s", id, vars["format"])
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if vars["format"] == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		out := csv.NewWriter(w)
+		header := make([]string, len(columns))
+		for i, col := range columns {
+			header[i] = col.Name
+		}
+		out.Write(header)
+		for _, row := range run.Dataset {
+			record := make([]string, len(columns))
+			for i, col := range columns {
+				switch value := col.Parse(row[col.Name]).(type) {
+				case nil:
+				case float64:
+					record[i] = strconv.FormatFloat(value, 'f', -1, 64)
+				default:
+					record[i] = fmt.Sprint(value)
+				}
+			}
+			out.Write(record)
+		}
+		out.Flush()
+		return
+	}
+
+	records := make([]map[string]interface{}, 0, len(run.Dataset))
+	for _, row := range run.Dataset {
+		record := make(map[string]interface{}, len(columns))
+		for _, col := range columns {
+			record[col.Name] = col.Parse(row[col.Name])
+		}
+		records = append(records, record)
+	}
+	respondJSON(w, records)
+}
+
+// junitSuites is the root of a JUnit XML report
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+// junitSuite reports a run, its actions being the test cases
+type junitSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property"`
+	Cases      []junitCase     `xml:"testcase"`
+	SystemErr  string          `xml:"system-err,omitempty"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure"`
+	Skipped   *struct{}     `xml:"skipped"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// runReport is a run with its action results, the workflow's name and its
+// actions and their descriptions by ID, as reports show them
+type runReport struct {
+	Run          *models.WorkflowRun
+	Name         string
+	Actions      map[string]models.SemanticAction
+	Descriptions map[string]string
+}
+
+// loadRunReport loads the run a report request names, responding with an
+// error and returning nil when it can't
+func (h *Handlers) loadRunReport(w http.ResponseWriter, r *http.Request) *runReport {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return nil
+	}
+
+	run, err := h.db.GetWorkflowRun(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil
+	}
+	if run == nil {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return nil
+	}
+	if run.ActionResults, err = h.db.GetActionResults(ctx, id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil
+	}
+	// A run still going, or one whose results failed to be recorded, has
+	// them in its workflow
+	if len(run.ActionResults) == 0 && h.temporalClient != nil && run.TemporalWorkflowID != "" {
+		if resp, err := h.temporalClient.QueryWorkflow(ctx, run.TemporalWorkflowID, run.TemporalRunID, "getProgress"); err == nil {
+			var progress models.WorkflowResult
+			if resp.Get(&progress) == nil {
+				run.ActionResults = progress.ActionResults
+			}
+		}
+	}
+
+	report := &runReport{Run: run, Name: run.WorkflowID, Actions: map[string]models.SemanticAction{}, Descriptions: map[string]string{}}
+	if workflow, err := h.db.GetWorkflowDefinition(ctx, run.WorkflowID); err == nil && workflow != nil {
+		report.Name = workflow.Name
+	}
+	actions, _ := h.db.GetSemanticActions(ctx, run.WorkflowID)
+	for _, action := range actions {
+		report.Actions[action.ID] = action
+		report.Descriptions[action.ID] = codegen.DescribeAction(action)
+	}
+	return report
+}
+
+// stepName names an action result in reports, such as "Step 3: click Submit"
+func (rr *runReport) stepName(result models.ActionResult) string {
+	name := fmt.Sprintf("Step %d", result.SequenceID)
+	if result.Iteration > 0 {
+		name += fmt.Sprintf(" (iteration %d)", result.Iteration)
+	}
+	if desc := rr.Descriptions[result.ActionID]; desc != "" {
+		name += ": " + desc
+	}
+	return name
+}
+
+// GetRunReport serves a run's results as a JUnit XML report for CI test
+// reporting, with a test case per action linking its screenshots
+func (h *Handlers) GetRunReport(w http.ResponseWriter, r *http.Request) {
+	rr := h.loadRunReport(w, r)
+	if rr == nil {
+		return
+	}
+	run, suiteName := rr.Run, rr.Name
+
+	suite := junitSuite{
+		Name: suiteName,
+		Properties: []junitProperty{
+			{Name: "run_id", Value: run.ID},
+			{Name: "workflow_id", Value: run.WorkflowID},
+			{Name: "status", Value: string(run.Status)},
+		},
+	}
+	if run.StartedAt != nil {
+		suite.Timestamp = run.StartedAt.UTC().Format("2006-01-02T15:04:05")
+		if run.CompletedAt != nil {
+			suite.Time = junitSeconds(run.CompletedAt.Sub(*run.StartedAt).Milliseconds())
+		}
+	}
+	if run.WorkflowVersion > 0 {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "workflow_version", Value: strconv.Itoa(run.WorkflowVersion)})
+	}
+	// A run can fail outside its actions, such as when it times out
+	if !run.Status.Succeeded() && run.ErrorMessage != "" {
+		suite.SystemErr = run.ErrorMessage
+	}
+
+	base := requestBaseURL(r)
+	var totalMS int64
+	for _, result := range run.ActionResults {
+		tc := junitCase{Name: rr.stepName(result), Classname: suiteName, Time: junitSeconds(result.Duration)}
+		totalMS += result.Duration
+
+		var links []string
+		for _, p := range []string{result.ScreenshotPath, result.BeforeScreenshotPath, result.AfterScreenshotPath, result.DOMSnapshotPath} {
+			if p != "" {
+				links = append(links, base+"/api/screenshots/"+url.PathEscape(path.Base(p)))
+			}
+		}
+		switch result.Status {
+		case models.StatusFailed:
+			suite.Failures++
+			text := result.ErrorMessage
+			for _, link := range links {
+				text += "\n" + link
+			}
+			tc.Failure = &junitFailure{Message: result.ErrorMessage, Type: string(result.Status), Text: strings.TrimSpace(text)}
+		case models.StatusSuccess, models.StatusWarning:
+		default:
+			suite.Skipped++
+			tc.Skipped = &struct{}{}
+		}
+		// Jenkins and GitLab attach files named as [[ATTACHMENT|...]] in the
+		// test case's output
+		var out []string
+		for _, link := range links {
+			out = append(out, "[[ATTACHMENT|"+link+"]]")
+		}
+		tc.SystemOut = strings.Join(out, "\n")
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Tests = len(suite.Cases)
+	if suite.Time == "" {
+		suite.Time = junitSeconds(totalMS)
+	}
+
+	report := junitSuites{
+		Name:     suiteName,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitSuite{suite},
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "run-"+run.ID+".xml"))
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	enc.Encode(report)
+}
+
+//go:embed run_report.html
+var runReportHTML string
+
+// runReportTemplate renders a run as a single HTML file
+var runReportTemplate = template.Must(template.New("run_report").Parse(runReportHTML))
+
+// htmlReportStep is an action result as the HTML report shows it
+type htmlReportStep struct {
+	Index       int
+	Name        string
+	Status      models.RunStatus
+	Duration    string
+	Offset      float64 // start on the timeline, in percent of the run
+	Width       float64 // length on the timeline, in percent of the run
+	Error       string
+	Screenshots []htmlReportScreenshot
+	Code        string
+}
+
+type htmlReportScreenshot struct {
+	Caption string
+	Data    template.URL // data URI of the PNG
+}
+
+// GetRunHTMLReport serves a run as a single HTML file with its timeline,
+// steps, inlined screenshots, generated code and errors, for sharing with
+// people without access to the UI
+func (h *Handlers) GetRunHTMLReport(w http.ResponseWriter, r *http.Request) {
+	rr := h.loadRunReport(w, r)
+	if rr == nil {
+		return
+	}
+	run := rr.Run
+
+	var totalMS int64
+	for _, result := range run.ActionResults {
+		totalMS += result.Duration
+	}
+	steps := make([]htmlReportStep, 0, len(run.ActionResults))
+	var elapsedMS int64
+	for i, result := range run.ActionResults {
+		step := htmlReportStep{
+			Index:    i + 1,
+			Name:     rr.stepName(result),
+			Status:   result.Status,
+			Duration: formatMS(result.Duration),
+			Error:    result.ErrorMessage,
+			Code:     result.GeneratedCode,
+		}
+		if totalMS > 0 {
+			step.Offset = float64(elapsedMS) * 100 / float64(totalMS)
+			step.Width = float64(result.Duration) * 100 / float64(totalMS)
+		}
+		elapsedMS += result.Duration
+
+		for _, shot := range []struct{ caption, path string }{
+			{"Before", result.BeforeScreenshotPath},
+			{"After", result.AfterScreenshotPath},
+			{"On failure", result.ScreenshotPath},
+		} {
+			if shot.path == "" {
+				continue
+			}
+			data, err := h.readArtifact(r.Context(), artifacts.ScreenshotKey(path.Base(shot.path)))
+			if err != nil {
+				continue
+			}
+			step.Screenshots = append(step.Screenshots, htmlReportScreenshot{
+				Caption: shot.caption,
+				Data:    template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(data)),
+			})
+		}
+		steps = append(steps, step)
+	}
+
+	data := struct {
+		*runReport
+		Started  string
+		Duration string
+		Steps    []htmlReportStep
+		Outputs  map[string]string
+	}{runReport: rr, Steps: steps, Outputs: run.Outputs}
+	if run.StartedAt != nil {
+		data.Started = run.StartedAt.UTC().Format("2006-01-02 15:04:05 UTC")
+		if run.CompletedAt != nil {
+			data.Duration = formatMS(run.CompletedAt.Sub(*run.StartedAt).Milliseconds())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := runReportTemplate.Execute(&buf, data); err != nil {
+		http.Error(w, "Failed to render report: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "run-"+run.ID+".html"))
+	w.Write(buf.Bytes())
+}
+
+// allureResult is a test result of Allure's results format, a run with a
+// step per action
+type allureResult struct {
+	UUID          string              `json:"uuid"`
+	HistoryID     string              `json:"historyId"`
+	TestCaseID    string              `json:"testCaseId"`
+	Name          string              `json:"name"`
+	FullName      string              `json:"fullName"`
+	Status        string              `json:"status"`
+	StatusDetails *allureDetails      `json:"statusDetails,omitempty"`
+	Stage         string              `json:"stage"`
+	Start         int64               `json:"start,omitempty"`
+	Stop          int64               `json:"stop,omitempty"`
+	Labels        []allureLabel       `json:"labels"`
+	Parameters    []allureLabel       `json:"parameters"`
+	Steps         []allureStep        `json:"steps"`
+	Attachments   []allureAttachment  `json:"attachments"`
+	Links         []map[string]string `json:"links"`
+}
+
+type allureStep struct {
+	Name          string             `json:"name"`
+	Status        string             `json:"status"`
+	StatusDetails *allureDetails     `json:"statusDetails,omitempty"`
+	Stage         string             `json:"stage"`
+	Start         int64              `json:"start,omitempty"`
+	Stop          int64              `json:"stop,omitempty"`
+	Parameters    []allureLabel      `json:"parameters"`
+	Attachments   []allureAttachment `json:"attachments"`
+	Steps         []allureStep       `json:"steps"`
+}
+
+type allureDetails struct {
+	Message string `json:"message,omitempty"`
+	Trace   string `json:"trace,omitempty"`
+}
+
+// allureLabel is a label or parameter, both name and value pairs
+type allureLabel struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type allureAttachment struct {
+	Name   string `json:"name"`
+	Source string `json:"source"` // file in the results directory
+	Type   string `json:"type"`
+}
+
+// GetRunAllureResults serves a run as a zip of Allure results, a test
+// result with a step per action and its screenshots attached, to unpack
+// into the results directory Allure reports are generated from
+func (h *Handlers) GetRunAllureResults(w http.ResponseWriter, r *http.Request) {
+	rr := h.loadRunReport(w, r)
+	if rr == nil {
+		return
+	}
+	run := rr.Run
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	result := allureResult{
+		UUID:       uuid.New().String(),
+		HistoryID:  run.WorkflowID, // groups the workflow's runs into one test's history
+		TestCaseID: run.WorkflowID,
+		Name:       rr.Name,
+		FullName:   "browser-automation." + rr.Name,
+		Status:     allureStatus(run.Status, false),
+		Stage:      "finished",
+		Labels: []allureLabel{
+			{Name: "suite", Value: rr.Name},
+			{Name: "framework", Value: "browser-automation-go"},
+		},
+		Parameters:  []allureLabel{},
+		Steps:       []allureStep{},
+		Attachments: []allureAttachment{},
+		Links:       []map[string]string{{"name": "Run " + run.ID, "url": requestBaseURL(r) + "/api/runs/" + run.ID, "type": "link"}},
+	}
+	if run.ErrorMessage != "" {
+		result.StatusDetails = &allureDetails{Message: run.ErrorMessage}
+	}
+	if run.ScheduleID != "" {
+		result.Labels = append(result.Labels, allureLabel{Name: "tag", Value: "scheduled"})
+	}
+
+	var params map[string]string
+	json.Unmarshal([]byte(run.ParametersJSON), &params)
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		result.Parameters = append(result.Parameters, allureLabel{Name: name, Value: params[name]})
+	}
+
+	var clock int64
+	if run.StartedAt != nil {
+		clock = run.StartedAt.UnixMilli()
+		result.Start = clock
+		if run.CompletedAt != nil {
+			result.Stop = run.CompletedAt.UnixMilli()
+		}
+	}
+	for _, actionResult := range run.ActionResults {
+		action := rr.Actions[actionResult.ActionID]
+		step := allureStep{
+			Name:        rr.stepName(actionResult),
+			Status:      allureStatus(actionResult.Status, action.ActionType == models.ActionAssert),
+			Stage:       "finished",
+			Parameters:  []allureLabel{},
+			Attachments: []allureAttachment{},
+			Steps:       []allureStep{},
+		}
+		if clock > 0 {
+			step.Start, step.Stop = clock, clock+actionResult.Duration
+			clock = step.Stop
+		}
+		if actionResult.ErrorMessage != "" {
+			step.StatusDetails = &allureDetails{Message: actionResult.ErrorMessage}
+		}
+		if actionResult.Selector != "" {
+			step.Parameters = append(step.Parameters, allureLabel{Name: "selector", Value: actionResult.Selector})
+		}
+		if actionResult.Output != "" {
+			step.Parameters = append(step.Parameters, allureLabel{Name: "output", Value: actionResult.Output})
+		}
+		// A run stopped by an assertion failed rather than broke
+		if step.Status == "failed" && result.Status == "broken" {
+			result.Status = "failed"
+		}
+
+		for _, file := range []struct{ name, path, mime, ext string }{
+			{"Before", actionResult.BeforeScreenshotPath, "image/png", "png"},
+			{"After", actionResult.AfterScreenshotPath, "image/png", "png"},
+			{"Screenshot on failure", actionResult.ScreenshotPath, "image/png", "png"},
+			{"DOM snapshot", actionResult.DOMSnapshotPath, "text/html", "html"},
+		} {
+			if file.path == "" {
+				continue
+			}
+			data, err := h.readArtifact(r.Context(), artifacts.ScreenshotKey(path.Base(file.path)))
+			if err != nil {
+				continue
+			}
+			source := uuid.New().String() + "-attachment." + file.ext
+			if err := addZipFile(zw, source, data); err != nil {
+				http.Error(w, "Failed to build archive: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			step.Attachments = append(step.Attachments, allureAttachment{Name: file.name, Source: source, Type: file.mime})
+		}
+		if actionResult.GeneratedCode != "" {
+			source := uuid.New().String() + "-attachment.go"
+			if err := addZipFile(zw, source, []byte(actionResult.GeneratedCode)); err != nil {
+				http.Error(w, "Failed to build archive: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			step.Attachments = append(step.Attachments, allureAttachment{Name: "Generated code", Source: source, Type: "text/plain"})
+		}
+		result.Steps = append(result.Steps, step)
+	}
+
+	data, _ := json.MarshalIndent(result, "", "  ")
+	if err := addZipFile(zw, result.UUID+"-result.json", data); err != nil {
+		http.Error(w, "Failed to build archive: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := zw.Close(); err != nil {
+		http.Error(w, "Failed to build archive: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "allure-run-"+run.ID+".zip"))
+	w.Write(buf.Bytes())
+}
+
+// allureStatus maps a run or action status to Allure's. Failed assertions
+// are failures and other errors broken, as Allure tells test failures from
+// errors.
+func allureStatus(status models.RunStatus, assertion bool) string {
+	switch status {
+	case models.StatusSuccess, models.StatusWarning:
+		return "passed"
+	case models.StatusFailed:
+		if assertion {
+			return "failed"
+		}
+		return "broken"
+	case models.StatusCanceled:
+		return "skipped"
+	}
+	return "unknown"
+}
+
+// formatMS formats a duration in milliseconds for reading, such as "1.2s"
+func formatMS(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).Round(100 * time.Millisecond).String()
+}
+
+// junitSeconds formats a duration in milliseconds as JUnit's seconds
+func junitSeconds(ms int64) string {
+	return strconv.FormatFloat(float64(ms)/1000, 'f', 3, 64)
+}
+
+// requestBaseURL returns the scheme and host a request reached the API at,
+// for links followed outside the UI
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
+
+// CancelRun cancels a running workflow
+func (h *Handlers) CancelRun(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	run, err := h.db.GetWorkflowRun(ctx, id)
+	if err != nil || run == nil {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return
+	}
+
+	// Cancel Temporal workflow
+	if run.TemporalWorkflowID != "" {
+		err = h.temporalClient.CancelWorkflow(ctx, run.TemporalWorkflowID, run.TemporalRunID)
+		if err != nil {
+			http.Error(w, "Failed to cancel workflow: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	h.db.UpdateWorkflowRunStatus(ctx, id, models.StatusCanceled, "Cancelled by user")
+
+	respondJSON(w, map[string]string{"status": "canceled"})
+}
+
+// PauseRun holds a running workflow before its next action, or before the
+// step given in the body, until it is resumed
+func (h *Handlers) PauseRun(w http.ResponseWriter, r *http.Request) {
+	var req models.PauseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.BeforeStep < 0 {
+		http.Error(w, "before_step must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	if h.signalRun(w, r, models.SignalPause, req) {
+		respondJSON(w, map[string]string{"status": "pausing"})
+	}
+}
+
+// ResumeRun lets a paused workflow continue
+func (h *Handlers) ResumeRun(w http.ResponseWriter, r *http.Request) {
+	if h.signalRun(w, r, models.SignalResume, nil) {
+		respondJSON(w, map[string]string{"status": "resumed"})
+	}
+}
+
+// NextStep lets a debug run execute its next action, holding again after
+func (h *Handlers) NextStep(w http.ResponseWriter, r *http.Request) {
+	if h.signalRun(w, r, models.SignalNext, nil) {
+		respondJSON(w, map[string]string{"status": "stepping"})
+	}
+}
+
+// DecideStep skips a failed action of a run holding for a decision, or
+// retries it with the parameter overrides given in the body
+func (h *Handlers) DecideStep(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	step, err := strconv.Atoi(vars["step"])
+	if err != nil || step <= 0 {
+		http.Error(w, "Invalid step", http.StatusBadRequest)
+		return
+	}
+
+	decision := models.StepDecision{Step: step, Action: models.StepAction(vars["action"])}
+	switch decision.Action {
+	case models.StepSkip:
+	case models.StepRetry:
+		var req struct {
+			Parameters map[string]string `json:"parameters"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		decision.Parameters = req.Parameters
+	default:
+		http.Error(w, "Unknown step action: "+vars["action"], http.StatusBadRequest)
+		return
+	}
+
+	if h.signalRun(w, r, models.SignalStep, decision) {
+		respondJSON(w, map[string]interface{}{"step": step, "action": decision.Action})
+	}
+}
+
+// UpdateRunParameters sets parameter values of a running workflow, such
+// as a one-time code, for the actions it has yet to run
+func (h *Handlers) UpdateRunParameters(w http.ResponseWriter, r *http.Request) {
+	var values map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&values); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(values) == 0 {
+		http.Error(w, "No parameters given", http.StatusBadRequest)
+		return
+	}
+
+	run, ok := h.activeRun(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	handle, err := h.temporalClient.UpdateWorkflow(ctx, run.TemporalWorkflowID, run.TemporalRunID, models.UpdateParameters, values)
+	if err != nil {
+		http.Error(w, "Failed to update workflow: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := handle.Get(ctx, nil); err != nil {
+		http.Error(w, "Parameters rejected: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	respondJSON(w, map[string]interface{}{"updated": names})
+}
+
+// signalRun sends a signal to the Temporal workflow of an unfinished run,
+// writing the error response and returning false when it cannot
+func (h *Handlers) signalRun(w http.ResponseWriter, r *http.Request, signal string, arg interface{}) bool {
+	run, ok := h.activeRun(w, r)
+	if !ok {
+		return false
+	}
+
+	err := h.temporalClient.SignalWorkflow(r.Context(), run.TemporalWorkflowID, run.TemporalRunID, signal, arg)
+	if err != nil {
+		http.Error(w, "Failed to signal workflow: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// activeRun loads the run named in the path when its workflow has started
+// and not yet finished, writing the error response otherwise
+func (h *Handlers) activeRun(w http.ResponseWriter, r *http.Request) (*models.WorkflowRun, bool) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return nil, false
+	}
+
+	run, err := h.db.GetWorkflowRun(r.Context(), id)
+	if err != nil || run == nil {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	switch run.Status {
+	case models.StatusSuccess, models.StatusWarning, models.StatusFailed, models.StatusCanceled:
+		http.Error(w, "Run already finished", http.StatusConflict)
+		return nil, false
+	}
+	if run.TemporalWorkflowID == "" {
+		http.Error(w, "Run has not started", http.StatusConflict)
+		return nil, false
+	}
+	return run, true
+}
+
+// StreamRunUpdates streams run updates via WebSocket
+func (h *Handlers) StreamRunUpdates(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	runID := vars["id"]
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	h.watchRun(r.Context(), runID, func(msg models.WSMessage) {
+		conn.WriteJSON(msg)
+	})
+}
+
+// sseKeepAlive is how often an idle event stream sends a comment, so
+// proxies don't close it
+const sseKeepAlive = 15 * time.Second
+
+// StreamRunEvents streams the run updates of StreamRunUpdates as
+// Server-Sent Events, for clients behind proxies that drop WebSockets
+func (h *Handlers) StreamRunEvents(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	runID := vars["id"]
+
+	// EventSource reconnects when a stream ends; a 204 tells it the run
+	// it was following has finished
+	if r.Header.Get("Last-Event-ID") != "" && h.db != nil {
+		if run, err := h.db.GetWorkflowRun(r.Context(), runID); err == nil && run != nil && run.Status.Finished() {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// The stream outlives the server's write timeout
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	var mu sync.Mutex
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		ticker := time.NewTicker(sseKeepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				mu.Lock()
+				fmt.Fprint(w, ": keep-alive\n\n")
+				rc.Flush()
+				mu.Unlock()
+			}
+		}
+	}()
+
+	id := 0
+	h.watchRun(ctx, runID, func(msg models.WSMessage) {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return
+		}
+		id++
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, msg.Type, data)
+		rc.Flush()
+	})
+}
+
+// PublishRunProgress is called by workers when a run's progress changes,
+// and pushes the run to the clients streaming it. Workers authenticate
+// with the ProgressToken as a bearer token, since each push queries the
+// run's workflow. A push for an action moving to another phase carries the
+// phase, which the query may not reflect yet.
+func (h *Handlers) PublishRunProgress(w http.ResponseWriter, r *http.Request) {
+	if h.ProgressToken == "" {
+		http.Error(w, "Progress pushes are disabled, the API has no PROGRESS_TOKEN", http.StatusNotFound)
+		return
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.ProgressToken)) != 1 {
+		http.Error(w, "Invalid progress token", http.StatusUnauthorized)
+		return
+	}
+
+	var phase *models.ActionPhaseUpdate
+	if err := json.NewDecoder(r.Body).Decode(&phase); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	runID := vars["id"]
+
+	if h.progress.Followed(runID) {
+		if state, ok := h.runProgress(r.Context(), runID); ok {
+			h.progress.Publish(runID, withPhase(state, phase))
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// withPhase returns a run's progress with the phase its worker pushed, if
+// the run is still on the phase's action
+func withPhase(state models.WorkflowResult, phase *models.ActionPhaseUpdate) models.WorkflowResult {
+	if phase == nil || state.Progress == nil || state.Progress.SequenceID != phase.SequenceID || state.Status.Finished() {
+		return state
+	}
+	progress := *state.Progress
+	progress.Phase = phase.Phase
+	progress.Attempt = phase.Attempt
+	state.Progress = &progress
+	return state
+}
+
+// Runs are polled every runPollInterval until their worker publishes
+// their progress, then every runPollFallback in case a publish is lost
+const (
+	runPollInterval = 500 * time.Millisecond
+	runPollFallback = 5 * time.Second
+)
+
+// watchRun follows a run until it finishes or ctx is done, sending a
+// run_update whenever its status, results, progress or debug snapshot
+// change. The run's final status is recorded when it finishes.
+func (h *Handlers) watchRun(ctx context.Context, runID string, send func(models.WSMessage)) {
+	updates := h.progress.Subscribe(runID)
+	defer updates.Close()
+
+	ticker := time.NewTicker(runPollInterval)
+	defer ticker.Stop()
+	pushed := false
+
+	lastStatus := ""
+	lastActionCount := 0
+	lastDebugStep := ""
+	var lastProgress models.RunProgress
+
+	state, ok := h.runProgress(ctx, runID)
+	for {
+		if ok {
+			status := state.Status
+			actionResults := state.ActionResults
+			debug := state.Debug
+			var progress models.RunProgress
+			if state.Progress != nil {
+				progress = *state.Progress
+			}
+
+			// Send update if status, results, progress or the debug
+			// snapshot changed
+			debugStep := ""
+			if debug != nil {
+				debugStep = fmt.Sprintf("%d/%d", debug.SequenceID, debug.Iteration)
+			}
+			if string(status) != lastStatus || len(actionResults) != lastActionCount || debugStep != lastDebugStep || progress != lastProgress {
+				payload := map[string]interface{}{
+					"run_id":         runID,
+					"status":         status,
+					"action_results": actionResults,
+				}
+				if debug != nil {
+					payload["debug"] = debug
+				}
+				if state.Progress != nil {
+					payload["progress"] = state.Progress
+				}
+				send(models.WSMessage{
+					Type:    "run_update",
+					Payload: payload,
+				})
+
+				lastStatus = string(status)
+				lastActionCount = len(actionResults)
+				lastDebugStep = debugStep
+				lastProgress = progress
+
+				// Close if completed
+				if status.Finished() {
+					// Update database with final status
+					if h.db != nil {
+						errorMsg := ""
+						if status == models.StatusWarning {
+							errorMsg = state.ErrorMessage
+						}
+						if status == models.StatusFailed {
+							for _, ar := range actionResults {
+								if ar.ErrorMessage != "" {
+									errorMsg = ar.ErrorMessage
+									break
+								}
+							}
+						}
+						h.db.UpdateWorkflowRunStatus(ctx, runID, status, errorMsg)
+					}
+					return
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case state = <-updates.C:
+			ok = true
+			if !pushed {
+				pushed = true
+				ticker.Reset(runPollFallback)
+			}
+		case <-ticker.C:
+			state, ok = h.runProgress(ctx, runID)
+		}
+	}
+}
+
+// runProgress returns a run's progress, queried from its workflow or
+// otherwise read from the database
+func (h *Handlers) runProgress(ctx context.Context, runID string) (models.WorkflowResult, bool) {
+	// Try to query Temporal workflow directly for real-time progress
+	if h.temporalClient != nil {
+		// Query workflow for progress using the correct workflow ID format
+		temporalWorkflowID := fmt.Sprintf("browser-automation-%s", runID)
+		queryResp, err := h.temporalClient.QueryWorkflow(ctx, temporalWorkflowID, "", "getProgress")
+		if err == nil {
+			var result models.WorkflowResult
+			if queryResp.Get(&result) == nil && result.Status != "" {
+				return result, true
+			}
+		}
+	}
+
+	// Fall back to DB if Temporal query didn't work
+	if h.db == nil {
+		return models.WorkflowResult{}, false
+	}
+	run, err := h.db.GetWorkflowRun(ctx, runID)
+	if err != nil || run == nil {
+		return models.WorkflowResult{}, false
+	}
+	results, _ := h.db.GetActionResults(ctx, runID)
+	return models.WorkflowResult{
+		RunID:         runID,
+		Status:        run.Status,
+		ErrorMessage:  run.ErrorMessage,
+		ActionResults: results,
+	}, true
+}
+
+// StreamScreencast relays the live view of a run's browser from the worker
+// running it, as one binary WebSocket message per JPEG frame
+func (h *Handlers) StreamScreencast(w http.ResponseWriter, r *http.Request) {
+	run, ok := h.activeRun(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	queryResp, err := h.temporalClient.QueryWorkflow(ctx, run.TemporalWorkflowID, run.TemporalRunID, "getProgress")
+	if err != nil {
+		http.Error(w, "Failed to query run: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var result models.WorkflowResult
+	if err := queryResp.Get(&result); err != nil {
+		http.Error(w, "Failed to query run: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if result.ScreencastURL == "" {
+		http.Error(w, "Run has no browser to watch: it has not opened one yet, or its worker has no WORKER_URL", http.StatusConflict)
+		return
+	}
+
+	workerURL := "ws" + strings.TrimPrefix(result.ScreencastURL, "http")
+	upstream, resp, err := websocket.DefaultDialer.DialContext(ctx, workerURL, nil)
+	if err != nil {
+		status := http.StatusBadGateway
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			status = http.StatusConflict
+		}
+		http.Error(w, "Failed to reach the run's worker: "+err.Error(), status)
+		return
+	}
+	defer upstream.Close()
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// Closing the client closes the worker's stream in turn
+	go func() {
+		defer upstream.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		msgType, frame, err := upstream.ReadMessage()
+		if err != nil {
+			return
+		}
+		if err := conn.WriteMessage(msgType, frame); err != nil {
+			return
+		}
+	}
+}
+
+// ==================== LLM Handlers ====================
+
+// ListLLMProviders lists all available LLM providers with their status
+func (h *Handlers) ListLLMProviders(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Define all supported providers
+	allProviders := []struct {
+		name        string
+		displayName string
+		requiresKey bool
+	}{
+		{"ollama", "Ollama", false},
+		{"openai", "OpenAI", true},
+		{"anthropic", "Anthropic", true},
+		{"gemini", "Gemini", true},
+	}
+
+	providers := make([]map[string]interface{}, 0)
+
+	for _, p := range allProviders {
+		// Check if we have a config (from env) or runtime key
+		config, hasEnvConfig := h.llmConfigs[p.name]
+		runtimeKey, hasRuntimeKey := h.runtimeAPIKeys[p.name]
+
+		hasKey := false
+		available := false
+
+		if p.requiresKey {
+			// Check if API key is available from env or runtime
+			hasKey = (hasEnvConfig && config.APIKey != "") || hasRuntimeKey
+
+			if hasKey {
+				// Create config with runtime key if needed
+				testConfig := config
+				if hasRuntimeKey {
+					testConfig = llm.DefaultConfigs()[llm.ProviderName(p.name)]
+					testConfig.APIKey = runtimeKey
+				}
+				provider, _ := llm.NewProvider(testConfig)
+				available = provider != nil && provider.IsAvailable(ctx)
+			}
+		} else {
+			// Ollama doesn't require a key
+			hasKey = true
+			if hasEnvConfig {
+				provider, _ := llm.NewProvider(config)
+				available = provider != nil && provider.IsAvailable(ctx)
+			}
+		}
+
+		providers = append(providers, map[string]interface{}{
+			"name":      p.name,
+			"display":   p.displayName,
+			"has_key":   hasKey,
+			"available": available,
+		})
+	}
+
+	respondJSON(w, providers)
+}
+
+// SetAPIKey sets an API key for a provider
+func (h *Handlers) SetAPIKey(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	providerName := vars["name"]
+
+	// Validate provider name
+	validProviders := map[string]bool{"openai": true, "anthropic": true, "gemini": true}
+	if !validProviders[providerName] {
+		http.Error(w, "Invalid provider name", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		APIKey string `json:"api_key"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.APIKey == "" {
+		http.Error(w, "API key is required", http.StatusBadRequest)
+		return
+	}
+
+	// Store the runtime API key
+	h.runtimeAPIKeys[providerName] = req.APIKey
+
+	// Also update llmConfigs so it's used in workflow execution
+	config := llm.DefaultConfigs()[llm.ProviderName(providerName)]
+	config.APIKey = req.APIKey
+	h.llmConfigs[providerName] = config
+
+	// Verify the key works
+	ctx := r.Context()
+	provider, _ := llm.NewProvider(config)
+	available := provider != nil && provider.IsAvailable(ctx)
+
+	respondJSON(w, map[string]interface{}{
+		"success":   true,
+		"provider":  providerName,
+		"available": available,
+	})
+}
+
+// DeleteAPIKey removes an API key for a provider
+func (h *Handlers) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	providerName := vars["name"]
+
+	delete(h.runtimeAPIKeys, providerName)
+	delete(h.llmConfigs, providerName)
+
+	respondJSON(w, map[string]interface{}{
+		"success":  true,
+		"provider": providerName,
+	})
+}
+
+// ==================== Artifact Handlers ====================
+
+// DownloadArtifacts bundles the generated workflow code, the per-action code
+// written by the worker and the parameter schema into a zip archive
+func (h *Handlers) DownloadArtifacts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	workflow, err := h.db.GetWorkflowDefinition(ctx, id)
+	if err != nil || workflow == nil {
+		http.Error(w, "Workflow not found", http.StatusNotFound)
+		return
+	}
+
+	var params []models.WorkflowParameter
+	json.Unmarshal([]byte(workflow.ParametersJSON), &params)
+	if params == nil {
+		params = []models.WorkflowParameter{}
+	}
+
+	actionFiles, err := h.actionCodeFiles(ctx, id)
+	if err != nil {
+		http.Error(w, "Failed to read generated code: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if workflow.GeneratedCode == "" && len(actionFiles) == 0 {
+		http.Error(w, "No generated code for workflow", http.StatusNotFound)
+		return
+	}
+
+	// Build the archive in memory so failures can still be reported as errors
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	if workflow.GeneratedCode != "" {
+		if err := addZipFile(zw, artifactFilename(workflow.GeneratedFormat), []byte(workflow.GeneratedCode)); err != nil {
+			http.Error(w, "Failed to build archive: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	for _, f := range actionFiles {
+		if err := addZipFile(zw, "actions/"+f.name, f.data); err != nil {
+			http.Error(w, "Failed to build archive: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	schema, _ := json.MarshalIndent(params, "", "  ")
+	if err := addZipFile(zw, "parameters.json", schema); err != nil {
+		http.Error(w, "Failed to build archive: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := zw.Close(); err != nil {
+		http.Error(w, "Failed to build archive: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "workflow-"+id+".zip"))
+	w.Write(buf.Bytes())
+}
+
+// artifactFilename names the generated workflow program inside the archive
+func artifactFilename(format string) string {
+	switch format {
+	case "script":
+		return "main.go"
+	case "test":
+		return "workflow_test.go"
+	}
+	return "workflow.go"
+}
+
+// bundleFile is a named file of an archive
+type bundleFile struct {
+	name string
+	data []byte
+}
+
+// actionCodeFiles returns the per-action code of a workflow as action_<seq>.go
+// files: the code stored by its latest run, or else the code restored from
+// an imported bundle
+func (h *Handlers) actionCodeFiles(ctx context.Context, workflowID string) ([]bundleFile, error) {
+	codes, err := h.db.GetLatestActionCode(ctx, workflowID)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []bundleFile
+	if len(codes) > 0 {
+		sequenceIDs := make([]int, 0, len(codes))
+		for seq := range codes {
+			sequenceIDs = append(sequenceIDs, seq)
+		}
+		sort.Ints(sequenceIDs)
+		for _, seq := range sequenceIDs {
+			files = append(files, bundleFile{fmt.Sprintf("action_%d.go", seq), []byte(codes[seq])})
+		}
+		return files, nil
+	}
+
+	keys, err := h.artifacts.List(ctx, artifacts.Key(artifacts.KindCode, workflowID)+"/")
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if path.Ext(key) != ".go" {
+			continue
+		}
+		data, err := h.readArtifact(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, bundleFile{path.Base(key), data})
+	}
+	return files, nil
+}
+
+// readArtifact reads a whole artifact from the store
+func (h *Handlers) readArtifact(ctx context.Context, key string) ([]byte, error) {
+	r, err := h.artifacts.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// addZipFile writes a single file into a zip archive
+func addZipFile(zw *zip.Writer, name string, data []byte) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// ==================== Bundle Handlers ====================
+
+// ExportWorkflow packages a workflow into a self-contained bundle: the events
+// file, semantic actions, parameters, generated code and a manifest. The
+// bundle can be imported on another instance with ImportWorkflow.
+func (h *Handlers) ExportWorkflow(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	workflow, err := h.db.GetWorkflowDefinition(ctx, id)
+	if err != nil || workflow == nil {
+		http.Error(w, "Workflow not found", http.StatusNotFound)
+		return
+	}
+
+	actions, err := h.db.GetSemanticActions(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Workflows created from a template have no recording
+	var events []byte
+	if workflow.EventsFilePath != "" {
+		events, err = os.ReadFile(workflow.EventsFilePath)
+		if err != nil {
+			http.Error(w, "Failed to read events file: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	manifest := models.BundleManifest{
+		Version:         models.BundleVersion,
+		ExportedAt:      time.Now().UTC(),
+		WorkflowID:      workflow.ID,
+		Name:            workflow.Name,
+		Description:     workflow.Description,
+		StartURL:        workflow.StartURL,
+		GeneratedFormat: workflow.GeneratedFormat,
+		CreatedAt:       workflow.CreatedAt,
+		Tags:            workflow.Tags,
+	}
+	if events != nil {
+		manifest.EventsFile = "events/" + uploadName(workflow.EventsFilePath)
+	}
+
+	manifestJSON, _ := json.MarshalIndent(manifest, "", "  ")
+	actionsJSON, _ := json.MarshalIndent(actions, "", "  ")
+	files := []bundleFile{
+		{"manifest.json", manifestJSON},
+		{"actions.json", actionsJSON},
+		{"parameters.json", []byte(workflow.ParametersJSON)},
+	}
+	if events != nil {
+		files = append(files, bundleFile{manifest.EventsFile, events})
+	}
+	if workflow.GeneratedCode != "" {
+		files = append(files, bundleFile{"code/" + artifactFilename(workflow.GeneratedFormat), []byte(workflow.GeneratedCode)})
+	}
+
+	actionFiles, err := h.actionCodeFiles(ctx, id)
+	if err != nil {
+		http.Error(w, "Failed to read generated code: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, f := range actionFiles {
+		files = append(files, bundleFile{"code/actions/" + f.name, f.data})
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		if err := addZipFile(zw, f.name, f.data); err != nil {
+			http.Error(w, "Failed to build bundle: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		http.Error(w, "Failed to build bundle: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "workflow-"+id+".bundle.zip"))
+	w.Write(buf.Bytes())
+}
+
+// maxBundleSize caps the uncompressed size of the files in an imported bundle
+const maxBundleSize = 100 << 20
+
+// ImportWorkflow recreates a workflow from a bundle produced by
+// ExportWorkflow. The workflow and its actions get new IDs on this instance.
+func (h *Handlers) ImportWorkflow(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	if err := r.ParseMultipartForm(100 << 20); err != nil { // 100MB max
+		http.Error(w, "Failed to parse form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	file, _, err := r.FormFile("bundle")
+	if err != nil {
+		http.Error(w, "Missing bundle", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		return
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		http.Error(w, "Invalid bundle: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Entries are read up to what is left of the cap, whatever size their
+	// headers claim, so a small bundle can't expand into a huge one
+	bundle := make(map[string][]byte)
+	remaining := int64(maxBundleSize)
+	for _, f := range zr.File {
+		if f.UncompressedSize64 > uint64(remaining) {
+			http.Error(w, "Bundle is too large", http.StatusBadRequest)
+			return
+		}
+		rc, err := f.Open()
+		if err != nil {
+			http.Error(w, "Invalid bundle: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, remaining+1))
+		rc.Close()
+		if err != nil {
+			http.Error(w, "Invalid bundle: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if int64(len(data)) > remaining {
+			http.Error(w, "Bundle is too large", http.StatusBadRequest)
+			return
+		}
+		remaining -= int64(len(data))
+		bundle[f.Name] = data
+	}
+
+	var manifest models.BundleManifest
+	if err := json.Unmarshal(bundle["manifest.json"], &manifest); err != nil {
+		http.Error(w, "Invalid bundle manifest", http.StatusBadRequest)
+		return
+	}
+	if manifest.Version != models.BundleVersion {
+		http.Error(w, fmt.Sprintf("Unsupported bundle version %d", manifest.Version), http.StatusBadRequest)
+		return
+	}
+
+	events, ok := bundle[manifest.EventsFile]
+	if !ok && manifest.EventsFile != "" {
+		http.Error(w, "Bundle is missing the events file", http.StatusBadRequest)
+		return
+	}
+
+	var actions []models.SemanticAction
+	if err := json.Unmarshal(bundle["actions.json"], &actions); err != nil {
+		http.Error(w, "Invalid actions in bundle: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var params []models.WorkflowParameter
+	if data, ok := bundle["parameters.json"]; ok && len(data) > 0 {
+		if err := json.Unmarshal(data, &params); err != nil {
+			http.Error(w, "Invalid parameters in bundle: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := validateBundle(actions, params); err != nil {
+		http.Error(w, "Invalid bundle: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Undo what was stored if a later step fails, so a failed import leaves
+	// neither a partial workflow nor its files behind
+	var filePath, workflowID string
+	var codeKeys []string
+	imported := false
+	defer func() {
+		if imported {
+			return
+		}
+		cleanupCtx := context.WithoutCancel(ctx)
+		if workflowID != "" {
+			if err := h.db.DeleteWorkflowDefinition(cleanupCtx, workflowID); err != nil {
+				slog.ErrorContext(cleanupCtx, "Failed to remove partly imported workflow", "workflowID", workflowID, "error", err)
+			}
+		}
+		for _, key := range codeKeys {
+			h.artifacts.Delete(cleanupCtx, key)
+		}
+		if filePath != "" {
+			os.Remove(filePath)
+		}
+	}()
+
+	// Save events file to disk, unless the workflow was created from a template
+	if manifest.EventsFile != "" {
+		uploadsDir := "/tmp/uploads"
+		os.MkdirAll(uploadsDir, 0755)
+		path := filepath.Join(uploadsDir, fmt.Sprintf("%s_%s", uuid.New().String(), filepath.Base(manifest.EventsFile)))
+		if err := os.WriteFile(path, events, 0644); err != nil {
+			os.Remove(path)
+			http.Error(w, "Failed to save file", http.StatusInternalServerError)
+			return
+		}
+		filePath = path
+	}
+
+	actionsJSON, _ := json.Marshal(actions)
+	paramsJSON, _ := json.Marshal(params)
+
+	workflow := &models.WorkflowDefinition{
+		ID:              uuid.New().String(),
+		Name:            r.FormValue("name"),
+		Description:     manifest.Description,
+		EventsFilePath:  filePath,
+		StartURL:        manifest.StartURL,
+		SemanticContext: string(actionsJSON),
+		ParametersJSON:  string(paramsJSON),
+	}
+	if workflow.Name == "" {
+		workflow.Name = manifest.Name
+	}
+
+	if err := h.db.CreateWorkflowDefinition(ctx, workflow); err != nil {
+		http.Error(w, "Failed to create workflow: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	workflowID = workflow.ID
+
+	for i := range actions {
+		actions[i].ID = uuid.New().String()
+		actions[i].WorkflowID = workflow.ID
+	}
+	if err := h.db.CreateSemanticActions(ctx, workflow.ID, actions); err != nil {
+		http.Error(w, "Failed to store actions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	for _, tag := range manifest.Tags {
+		if tag, err := models.NormalizeTag(tag); err == nil {
+			workflow.Tags = append(workflow.Tags, tag)
+		}
+	}
+	if err := h.db.AddWorkflowTags(ctx, workflow.ID, workflow.Tags); err != nil {
+		http.Error(w, "Failed to tag workflow: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if code, ok := bundle["code/"+artifactFilename(manifest.GeneratedFormat)]; ok && manifest.GeneratedFormat != "" {
+		if err := h.db.SaveGeneratedCode(ctx, workflow.ID, workflow.Revision, manifest.GeneratedFormat, string(code)); err != nil {
+			http.Error(w, "Failed to save generated code: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		workflow.IsWorkflowGenerated = true
+		workflow.GeneratedCode = string(code)
+		workflow.GeneratedFormat = manifest.GeneratedFormat
+	}
+
+	// Restore per-action code where the worker and artifact downloads expect it
+	for name, data := range bundle {
+		if !strings.HasPrefix(name, "code/actions/") || filepath.Ext(name) != ".go" {
+			continue
+		}
+		key := artifacts.CodeKey(workflow.ID, name)
+		codeKeys = append(codeKeys, key)
+		if _, err := h.artifacts.Put(ctx, key, "text/x-go", bytes.NewReader(data)); err != nil {
+			http.Error(w, "Failed to restore generated code: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if _, err := h.recordVersion(ctx, workflow.ID, actions, params, models.VersionImported, 0); err != nil {
+		http.Error(w, "Failed to create version: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	imported = true
+
+	workflow.Actions = actions
+	workflow.Parameters = params
+
+	audit.SetResource(ctx, workflow.ID)
+	respondJSON(w, workflow)
+}
+
+// validateBundle checks a bundle's actions as they would be checked if added
+// one by one, and that each parameter fills one of them
+func validateBundle(actions []models.SemanticAction, params []models.WorkflowParameter) error {
+	for i, action := range actions {
+		if !action.ActionType.Known() {
+			return fmt.Errorf("action %d: unknown action type %q", i+1, action.ActionType)
+		}
+		if err := action.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, param := range params {
+		if param.SourceAction < 0 || param.SourceAction > len(actions) {
+			return fmt.Errorf("parameter %q fills no action: source_action must be between 1 and %d", param.Name, len(actions))
+		}
+	}
+	return nil
+}
+
+// uploadName strips the unique prefix CreateWorkflow adds to uploaded files
+func uploadName(path string) string {
+	name := filepath.Base(path)
+	if len(name) > 37 && name[36] == '_' {
+		if _, err := uuid.Parse(name[:36]); err == nil {
+			return name[37:]
+		}
+	}
+	return name
+}
+
+// ==================== Code Template Handlers ====================
+
+// ListCodeTemplates returns the built-in code templates and the custom
+// templates overriding them
+func (h *Handlers) ListCodeTemplates(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	templates, err := h.db.ListCodeTemplates(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if templates == nil {
+		templates = []models.CodeTemplate{}
+	}
+
+	respondJSON(w, map[string]interface{}{
+		"defaults": llm.DefaultTemplates().Sources(),
+		"custom":   templates,
+	})
+}
+
+// SaveCodeTemplate creates or replaces the custom template for an action type
+func (h *Handlers) SaveCodeTemplate(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	actionType := models.ActionType(mux.Vars(r)["action_type"])
+
+	var req struct {
+		Template string `json:"template"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Template == "" {
+		http.Error(w, "Template is required", http.StatusBadRequest)
+		return
+	}
+
+	// Reject templates that do not parse or render before they reach a worker
+	if err := llm.DefaultTemplates().Override(actionType, req.Template); err != nil {
+		http.Error(w, "Invalid template: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	t := &models.CodeTemplate{ActionType: actionType, Template: req.Template}
+	if err := h.db.SaveCodeTemplate(r.Context(), t); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, t)
+}
+
+// DeleteCodeTemplate removes the custom template for an action type
+func (h *Handlers) DeleteCodeTemplate(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	actionType := models.ActionType(mux.Vars(r)["action_type"])
+	if err := h.db.DeleteCodeTemplate(r.Context(), actionType); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ==================== Workflow Template Handlers ====================
+
+// ListWorkflowTemplates lists the templates shipped with the automator,
+// then the user-defined ones
+func (h *Handlers) ListWorkflowTemplates(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	templates, err := library.Templates()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	custom, err := h.db.ListWorkflowTemplates(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, append(templates, custom...))
+}
+
+// GetWorkflowTemplate returns a template with its actions and parameters
+func (h *Handlers) GetWorkflowTemplate(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	t, ok := h.workflowTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	respondJSON(w, t)
+}
+
+// CreateWorkflowTemplate saves a user-defined template. Its actions are
+// numbered in their order, which the parameters' source_action refers to.
+func (h *Handlers) CreateWorkflowTemplate(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var t models.WorkflowTemplate
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	t.ID = uuid.New().String()
+
+	audit.SetResource(r.Context(), t.ID)
+	h.saveWorkflowTemplate(w, r, &t)
+}
+
+// UpdateWorkflowTemplate replaces a user-defined template
+func (h *Handlers) UpdateWorkflowTemplate(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	existing, ok := h.workflowTemplate(w, r)
+	if !ok {
+		return
+	}
+	if existing.BuiltIn {
+		http.Error(w, "Built-in templates can't be changed", http.StatusForbidden)
+		return
+	}
+
+	var t models.WorkflowTemplate
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	t.ID = existing.ID
+
+	h.saveWorkflowTemplate(w, r, &t)
+}
+
+// DeleteWorkflowTemplate removes a user-defined template. Workflows created
+// from it are kept.
+func (h *Handlers) DeleteWorkflowTemplate(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	existing, ok := h.workflowTemplate(w, r)
+	if !ok {
+		return
+	}
+	if existing.BuiltIn {
+		http.Error(w, "Built-in templates can't be changed", http.StatusForbidden)
+		return
+	}
+
+	if _, err := h.db.DeleteWorkflowTemplate(r.Context(), existing.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// InstantiateRequest names a workflow created from a template and gives
+// the values of the template's parameters, which become their defaults
+type InstantiateRequest struct {
+	Name   string            `json:"name"`
+	Values map[string]string `json:"values"`
+}
+
+// InstantiateWorkflowTemplate creates a workflow from a template, without a
+// recording
+func (h *Handlers) InstantiateWorkflowTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req InstantiateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	t, ok := h.workflowTemplate(w, r)
+	if !ok {
+		return
+	}
+	actions, params, err := t.Instantiate(req.Values)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		req.Name = t.Name
+	}
+	if err := (models.WorkflowUpdate{Name: &req.Name}).Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	actionsJSON, _ := json.Marshal(actions)
+	paramsJSON, _ := json.Marshal(params)
+
+	workflow := &models.WorkflowDefinition{
+		ID:              uuid.New().String(),
+		Name:            strings.TrimSpace(req.Name),
+		Description:     t.Description,
+		StartURL:        t.StartURL,
+		SemanticContext: string(actionsJSON),
+		ParametersJSON:  string(paramsJSON),
+	}
+	if err := h.db.CreateWorkflowDefinition(ctx, workflow); err != nil {
+		http.Error(w, "Failed to create workflow: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	for i := range actions {
+		actions[i].ID = uuid.New().String()
+		actions[i].WorkflowID = workflow.ID
+	}
+	if err := h.db.CreateSemanticActions(ctx, workflow.ID, actions); err != nil {
+		http.Error(w, "Failed to store actions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := h.recordVersion(ctx, workflow.ID, actions, params, models.VersionCreated, 0); err != nil {
+		http.Error(w, "Failed to create version: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	workflow.Actions = actions
+	workflow.Parameters = params
+
+	audit.SetResource(ctx, workflow.ID)
+	respondJSON(w, workflow)
+}
+
+// workflowTemplate returns the built-in or user-defined template the URL
+// names, writing the error when there is none
+func (h *Handlers) workflowTemplate(w http.ResponseWriter, r *http.Request) (*models.WorkflowTemplate, bool) {
+	id := mux.Vars(r)["id"]
+
+	builtIn, err := library.Templates()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	for i := range builtIn {
+		if builtIn[i].ID == id {
+			return &builtIn[i], true
+		}
+	}
+
+	t, err := h.db.GetWorkflowTemplate(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if t == nil {
+		http.Error(w, "Template not found", http.StatusNotFound)
+		return nil, false
 	}
+	return t, true
+}
 
-	// Parse workflow parameter definitions
+// saveWorkflowTemplate numbers a template's actions, checks and stores it
+func (h *Handlers) saveWorkflowTemplate(w http.ResponseWriter, r *http.Request, t *models.WorkflowTemplate) {
+	t.Name = strings.TrimSpace(t.Name)
+	t.BuiltIn = false
+	models.ResequenceActions(t.Actions)
+	if err := t.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.db.SaveWorkflowTemplate(r.Context(), t); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, t)
+}
+
+// ==================== Browser Session Handlers ====================
+
+// ListBrowserSessions lists the saved browser sessions without their state
+func (h *Handlers) ListBrowserSessions(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	sessions, err := h.db.ListBrowserSessions(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if sessions == nil {
+		sessions = []models.SavedSession{}
+	}
+
+	respondJSON(w, sessions)
+}
+
+// DeleteBrowserSession removes a saved browser session
+func (h *Handlers) DeleteBrowserSession(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	if err := h.db.DeleteBrowserSession(r.Context(), mux.Vars(r)["name"]); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// workerClient calls the workers' HTTP servers
+var workerClient = &http.Client{Timeout: 10 * time.Second, Transport: telemetry.Transport(nil)}
+
+// OpenBrowserSessions lists the browser sessions open on the workers, with
+// the workers that could not be reached
+type OpenBrowserSessions struct {
+	Sessions []models.OpenBrowserSession `json:"sessions"`
+	Errors   map[string]string           `json:"errors,omitempty"` // by worker URL
+}
+
+// ListOpenBrowserSessions lists the browser sessions open on each worker,
+// oldest first, so operators can find stuck ones
+func (h *Handlers) ListOpenBrowserSessions(w http.ResponseWriter, r *http.Request) {
+	if len(h.WorkerURLs) == 0 {
+		http.Error(w, "No workers configured, set WORKER_URLS", http.StatusServiceUnavailable)
+		return
+	}
+
+	result := OpenBrowserSessions{Sessions: []models.OpenBrowserSession{}}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, workerURL := range h.WorkerURLs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sessions, err := listWorkerSessions(r.Context(), workerURL)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if result.Errors == nil {
+					result.Errors = make(map[string]string)
+				}
+				result.Errors[workerURL] = err.Error()
+				return
+			}
+			for i := range sessions {
+				sessions[i].Worker = workerURL
+			}
+			result.Sessions = append(result.Sessions, sessions...)
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(result.Sessions, func(i, j int) bool {
+		return result.Sessions[i].CreatedAt.Before(result.Sessions[j].CreatedAt)
+	})
+	respondJSON(w, result)
+}
+
+func listWorkerSessions(ctx context.Context, workerURL string) ([]models.OpenBrowserSession, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(workerURL, "/")+"/sessions/", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := workerClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("worker returned %s", resp.Status)
+	}
+
+	var sessions []models.OpenBrowserSession
+	if err := json.NewDecoder(resp.Body).Decode(&sessions); err != nil {
+		return nil, fmt.Errorf("failed to decode sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// CloseOpenBrowserSession force-closes a browser session on whichever
+// worker holds it. The run using it fails its next action.
+func (h *Handlers) CloseOpenBrowserSession(w http.ResponseWriter, r *http.Request) {
+	if len(h.WorkerURLs) == 0 {
+		http.Error(w, "No workers configured, set WORKER_URLS", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	var errs []string
+	for _, workerURL := range h.WorkerURLs {
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodDelete, strings.TrimSuffix(workerURL, "/")+"/sessions/"+url.PathEscape(id), nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp, err := workerClient.Do(req)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", workerURL, err))
+			continue
+		}
+		resp.Body.Close()
+		switch resp.StatusCode {
+		case http.StatusNoContent:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case http.StatusNotFound:
+		default:
+			errs = append(errs, fmt.Sprintf("%s: worker returned %s", workerURL, resp.Status))
+		}
+	}
+
+	// The session may be on a worker that could not be asked
+	if len(errs) > 0 {
+		http.Error(w, "Failed to reach workers: "+strings.Join(errs, "; "), http.StatusBadGateway)
+		return
+	}
+	http.Error(w, "Browser session not found", http.StatusNotFound)
+}
+
+// codeTemplateOverrides returns the custom templates keyed by action type,
+// passed to the worker with each run
+func (h *Handlers) codeTemplateOverrides(ctx context.Context) (map[models.ActionType]string, error) {
+	templates, err := h.db.ListCodeTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 {
+		return nil, nil
+	}
+	overrides := make(map[models.ActionType]string, len(templates))
+	for _, t := range templates {
+		overrides[t.ActionType] = t.Template
+	}
+	return overrides, nil
+}
+
+// ==================== Pipeline Handlers ====================
+
+// CreatePipeline saves a pipeline of workflows
+func (h *Handlers) CreatePipeline(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var pipeline models.Pipeline
+	if err := json.NewDecoder(r.Body).Decode(&pipeline); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := pipeline.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for i, step := range pipeline.Steps {
+		def, err := h.db.GetWorkflowDefinition(ctx, step.WorkflowID)
+		if err != nil || def == nil {
+			http.Error(w, fmt.Sprintf("Step %d: workflow %s not found", i+1, step.WorkflowID), http.StatusBadRequest)
+			return
+		}
+	}
+
+	pipeline.ID = uuid.New().String()
+	if err := h.db.CreatePipeline(ctx, &pipeline); err != nil {
+		http.Error(w, "Failed to create pipeline: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	audit.SetResource(ctx, pipeline.ID)
+	respondJSON(w, pipeline)
+}
+
+// ListPipelines lists the pipelines
+func (h *Handlers) ListPipelines(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	pipelines, err := h.db.ListPipelines(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if pipelines == nil {
+		pipelines = []models.Pipeline{}
+	}
+
+	respondJSON(w, pipelines)
+}
+
+// GetPipeline returns a pipeline
+func (h *Handlers) GetPipeline(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	pipeline, err := h.db.GetPipeline(r.Context(), mux.Vars(r)["id"])
+	if err != nil || pipeline == nil {
+		http.Error(w, "Pipeline not found", http.StatusNotFound)
+		return
+	}
+
+	respondJSON(w, pipeline)
+}
+
+// DeletePipeline removes a pipeline
+func (h *Handlers) DeletePipeline(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	if err := h.db.DeletePipeline(r.Context(), mux.Vars(r)["id"]); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RunPipeline starts a run of a pipeline's workflows
+func (h *Handlers) RunPipeline(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req struct {
+		LLMProvider string `json:"llm_provider"`
+		Headless    bool   `json:"headless"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	pipeline, err := h.db.GetPipeline(ctx, mux.Vars(r)["id"])
+	if err != nil || pipeline == nil {
+		http.Error(w, "Pipeline not found", http.StatusNotFound)
+		return
+	}
+
+	codeTemplates, err := h.codeTemplateOverrides(ctx)
+	if err != nil {
+		http.Error(w, "Failed to load code templates: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	deps, err := pipeline.Dependencies()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Each step runs the latest version of its workflow
+	runID := uuid.New().String()
+	input := workflows.PipelineInput{
+		PipelineID: pipeline.ID,
+		RunID:      runID,
+		Steps:      make([]workflows.PipelineStepInput, len(pipeline.Steps)),
+	}
+	stepRunIDs := make([]string, len(pipeline.Steps))
+	for i, step := range pipeline.Steps {
+		run, err := h.latestRunInput(ctx, step.WorkflowID, req.LLMProvider, req.Headless, codeTemplates)
+		if err != nil || run == nil {
+			http.Error(w, fmt.Sprintf("Step %d: workflow %s not found", i+1, step.WorkflowID), http.StatusBadRequest)
+			return
+		}
+
+		inputs := make(map[string]workflows.StepOutput, len(step.Inputs))
+		for param, source := range step.Inputs {
+			dep, output, err := pipeline.InputSource(i, deps[i], source)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Step %d: input %q: %v", i+1, param, err), http.StatusBadRequest)
+				return
+			}
+			inputs[param] = workflows.StepOutput{Step: dep, Output: output}
+		}
+
+		stepRunIDs[i] = uuid.New().String()
+		run.RunID = stepRunIDs[i]
+		run.Parameters = step.Parameters
+		input.Steps[i] = workflows.PipelineStepInput{
+			Run:          *run,
+			DependsOn:    deps[i],
+			Inputs:       inputs,
+			ShareSession: step.ShareSession,
+		}
+	}
+
+	workflowOptions := client.StartWorkflowOptions{
+		ID:        pipelineWorkflowID(runID),
+		TaskQueue: TaskQueue,
+	}
+	if _, err := h.temporalClient.ExecuteWorkflow(ctx, workflowOptions, "PipelineWorkflow", input); err != nil {
+		http.Error(w, "Failed to start pipeline: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	audit.SetResource(ctx, runID)
+	respondJSON(w, map[string]interface{}{
+		"pipeline_run_id": runID,
+		"step_run_ids":    stepRunIDs,
+		"status":          "running",
+	})
+}
+
+// GetPipelineRun returns the progress of a pipeline run, with the results
+// of the steps that ran
+func (h *Handlers) GetPipelineRun(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	resp, err := h.temporalClient.QueryWorkflow(ctx, pipelineWorkflowID(mux.Vars(r)["id"]), "", "getProgress")
+	if err != nil {
+		http.Error(w, "Pipeline run not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+	var progress workflows.PipelineResult
+	if err := resp.Get(&progress); err != nil {
+		http.Error(w, "Failed to read pipeline progress: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, progress)
+}
+
+// pipelineWorkflowID is the Temporal workflow ID of a pipeline run
+func pipelineWorkflowID(runID string) string {
+	return fmt.Sprintf("pipeline-%s", runID)
+}
+
+// latestRunInput builds the input for running the latest version of a
+// workflow with its settings, or returns nil if there is no such workflow.
+// The caller sets the run ID and parameters.
+func (h *Handlers) latestRunInput(ctx context.Context, workflowID, llmProvider string, headless bool, codeTemplates map[models.ActionType]string) (*models.WorkflowInput, error) {
+	def, err := h.db.GetWorkflowDefinition(ctx, workflowID)
+	if err != nil || def == nil {
+		return nil, err
+	}
 	var paramsDef []models.WorkflowParameter
-	if workflow.ParametersJSON != "" {
-		// Ignore error if parameters are malformed, treat as empty
-		_ = json.Unmarshal([]byte(workflow.ParametersJSON), &paramsDef)
+	if def.ParametersJSON != "" {
+		_ = json.Unmarshal([]byte(def.ParametersJSON), &paramsDef)
 	}
+	actions, _ := h.db.GetSemanticActions(ctx, workflowID)
 
-	input := models.WorkflowInput{
+	return &models.WorkflowInput{
 		WorkflowID:    workflowID,
-		RunID:         runID,
-		Parameters:    req.Parameters,
 		Params:        paramsDef,
-		Actions:       actions,
-		LLMProvider:   req.LLMProvider,
-		LLMAPIKey:     llmAPIKey,
-		Headless:      req.Headless,
+		Actions:       replayActions(actions),
+		LLMProvider:   llmProvider,
+		LLMAPIKey:     h.llmAPIKey(llmProvider),
+		Headless:      headless,
+		Dialogs:       def.Settings.Dialogs,
+		CodeTemplates: codeTemplates,
 		Timeout:       300,
 		RetryAttempts: 3,
+
+		SkipStabilityChecks: def.Settings.SkipStabilityChecks,
+		DecisionTimeout:     def.Settings.DecisionTimeout,
+
+		Loops: def.Settings.Loops,
+
+		MaxConcurrentRuns: def.Settings.MaxConcurrentRuns,
+		Notifications:     def.Settings.Notifications,
+	}, nil
+}
+
+// ==================== Schedule Handlers ====================
+
+// recentScheduleRuns is how many of a schedule's latest runs GetSchedule
+// lists
+const recentScheduleRuns = 20
+
+// CreateSchedule creates a schedule running a workflow
+func (h *Handlers) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
 	}
 
-	workflowOptions := client.StartWorkflowOptions{
-		ID:        fmt.Sprintf("browser-automation-%s", runID),
-		TaskQueue: TaskQueue,
+	var schedule models.Schedule
+	if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := schedule.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	we, err := h.temporalClient.ExecuteWorkflow(ctx, workflowOptions, "BrowserAutomationWorkflow", input)
+	schedule.ID = uuid.New().String()
+	options, ok := h.scheduleOptions(w, r, &schedule)
+	if !ok {
+		return
+	}
+
+	handle, err := h.temporalClient.ScheduleClient().Create(ctx, client.ScheduleOptions{
+		ID:     scheduleWorkflowID(schedule.ID),
+		Spec:   options.Spec,
+		Action: options.Action,
+		Paused: schedule.Paused,
+	})
 	if err != nil {
-		h.db.UpdateWorkflowRunStatus(ctx, runID, models.StatusFailed, err.Error())
-		http.Error(w, "Failed to start workflow: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to create schedule: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.db.CreateSchedule(ctx, &schedule); err != nil {
+		_ = handle.Delete(ctx)
+		http.Error(w, "Failed to save schedule: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Update run with Temporal IDs and mark as running
-	h.db.UpdateWorkflowRunStarted(ctx, runID, we.GetID(), we.GetRunID())
+	audit.SetResource(ctx, schedule.ID)
+	respondJSON(w, schedule)
+}
 
-	respondJSON(w, map[string]interface{}{
-		"run_id":               runID,
-		"temporal_workflow_id": we.GetID(),
-		"temporal_run_id":      we.GetRunID(),
-		"status":               "running",
-	})
+// ListSchedules lists the schedules
+func (h *Handlers) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	schedules, err := h.db.ListSchedules(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if schedules == nil {
+		schedules = []models.Schedule{}
+	}
+
+	respondJSON(w, schedules)
 }
 
-// ListRuns lists workflow runs
-func (h *Handlers) ListRuns(w http.ResponseWriter, r *http.Request) {
+// GetSchedule returns a schedule with the times of its next runs and its
+// latest runs
+func (h *Handlers) GetSchedule(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	workflowID := r.URL.Query().Get("workflow_id")
 
 	if h.db == nil {
 		http.Error(w, "Database not available", http.StatusServiceUnavailable)
 		return
 	}
 
-	var runs []models.WorkflowRun
-	var err error
+	schedule, err := h.db.GetSchedule(ctx, mux.Vars(r)["id"])
+	if err != nil || schedule == nil {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+		return
+	}
 
-	if workflowID != "" {
-		runs, err = h.db.ListWorkflowRuns(ctx, workflowID)
-	} else {
-		// List all recent runs
-		runs, err = h.db.ListAllWorkflowRuns(ctx, 50)
+	detail := models.ScheduleDetail{Schedule: *schedule, NextRuns: []time.Time{}}
+	desc, err := h.temporalClient.ScheduleClient().GetHandle(ctx, scheduleWorkflowID(schedule.ID)).Describe(ctx)
+	if err != nil {
+		http.Error(w, "Failed to describe schedule: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !schedule.Paused {
+		detail.NextRuns = desc.Info.NextActionTimes
 	}
 
+	detail.RecentRuns, err = h.db.ListScheduleRuns(ctx, schedule.ID, recentScheduleRuns)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if detail.RecentRuns == nil {
+		detail.RecentRuns = []models.WorkflowRun{}
+	}
 
-	respondJSON(w, runs)
+	respondJSON(w, detail)
 }
 
-// GetRun retrieves a workflow run
-func (h *Handlers) GetRun(w http.ResponseWriter, r *http.Request) {
+// UpdateSchedule changes when a schedule runs, what it runs with, or
+// pauses it
+func (h *Handlers) UpdateSchedule(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	vars := mux.Vars(r)
-	id := vars["id"]
 
 	if h.db == nil {
 		http.Error(w, "Database not available", http.StatusServiceUnavailable)
 		return
 	}
 
-	run, err := h.db.GetWorkflowRun(ctx, id)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	existing, err := h.db.GetSchedule(ctx, mux.Vars(r)["id"])
+	if err != nil || existing == nil {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
 		return
 	}
-	if run == nil {
-		http.Error(w, "Run not found", http.StatusNotFound)
+
+	var schedule models.Schedule
+	if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	schedule.ID = existing.ID
+	schedule.WorkflowID = existing.WorkflowID
+	schedule.CreatedAt = existing.CreatedAt
+	if err := schedule.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Get action results
-	results, _ := h.db.GetActionResults(ctx, id)
-	run.ActionResults = results
+	// The schedule runs the workflow as it is now from here on
+	options, ok := h.scheduleOptions(w, r, &schedule)
+	if !ok {
+		return
+	}
 
-	respondJSON(w, run)
+	handle := h.temporalClient.ScheduleClient().GetHandle(ctx, scheduleWorkflowID(schedule.ID))
+	err = handle.Update(ctx, client.ScheduleUpdateOptions{
+		DoUpdate: func(in client.ScheduleUpdateInput) (*client.ScheduleUpdate, error) {
+			updated := in.Description.Schedule
+			updated.Spec = &options.Spec
+			updated.Action = options.Action
+			if updated.State == nil {
+				updated.State = &client.ScheduleState{}
+			}
+			updated.State.Paused = schedule.Paused
+			return &client.ScheduleUpdate{Schedule: &updated}, nil
+		},
+	})
+	if err != nil {
+		http.Error(w, "Failed to update schedule: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.db.UpdateSchedule(ctx, &schedule); err != nil {
+		http.Error(w, "Failed to save schedule: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, schedule)
 }
 
-// CancelRun cancels a running workflow
-func (h *Handlers) CancelRun(w http.ResponseWriter, r *http.Request) {
+// DeleteSchedule removes a schedule. Runs it started keep running.
+func (h *Handlers) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	vars := mux.Vars(r)
-	id := vars["id"]
 
 	if h.db == nil {
 		http.Error(w, "Database not available", http.StatusServiceUnavailable)
 		return
 	}
 
-	run, err := h.db.GetWorkflowRun(ctx, id)
-	if err != nil || run == nil {
-		http.Error(w, "Run not found", http.StatusNotFound)
+	id := mux.Vars(r)["id"]
+	if err := h.temporalClient.ScheduleClient().GetHandle(ctx, scheduleWorkflowID(id)).Delete(ctx); err != nil {
+		http.Error(w, "Failed to delete schedule: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-
-	// Cancel Temporal workflow
-	if run.TemporalWorkflowID != "" {
-		err = h.temporalClient.CancelWorkflow(ctx, run.TemporalWorkflowID, run.TemporalRunID)
-		if err != nil {
-			http.Error(w, "Failed to cancel workflow: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
+	if err := h.db.DeleteSchedule(ctx, id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	h.db.UpdateWorkflowRunStatus(ctx, id, models.StatusCanceled, "Cancelled by user")
-
-	respondJSON(w, map[string]string{"status": "canceled"})
+	w.WriteHeader(http.StatusNoContent)
 }
 
-// StreamRunUpdates streams run updates via WebSocket
-func (h *Handlers) StreamRunUpdates(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	runID := vars["id"]
+// scheduleOptions builds the Temporal spec and action of a schedule,
+// running the latest version of its workflow, writing the error response
+// and returning false when it cannot
+func (h *Handlers) scheduleOptions(w http.ResponseWriter, r *http.Request, schedule *models.Schedule) (client.ScheduleOptions, bool) {
+	ctx := r.Context()
 
-	conn, err := h.upgrader.Upgrade(w, r, nil)
+	codeTemplates, err := h.codeTemplateOverrides(ctx)
 	if err != nil {
-		return
+		http.Error(w, "Failed to load code templates: "+err.Error(), http.StatusInternalServerError)
+		return client.ScheduleOptions{}, false
 	}
-	defer conn.Close()
-
-	ctx := r.Context()
-
-	// Poll for updates
-	ticker := time.NewTicker(500 * time.Millisecond) // Faster polling for better UX
-	defer ticker.Stop()
-
-	lastStatus := ""
-	lastActionCount := 0
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			var status models.RunStatus
-			var actionResults []models.ActionResult
-
-			// Try to query Temporal workflow directly for real-time progress
-			if h.temporalClient != nil {
-				// Query workflow for progress using the correct workflow ID format
-				temporalWorkflowID := fmt.Sprintf("browser-automation-%s", runID)
-				queryResp, err := h.temporalClient.QueryWorkflow(ctx, temporalWorkflowID, "", "getProgress")
-				if err == nil {
-					var result models.WorkflowResult
-					if queryResp.Get(&result) == nil {
-						status = result.Status
-						actionResults = result.ActionResults
-					}
-				}
-			}
-
-			// Fall back to DB if Temporal query didn't work
-			if status == "" && h.db != nil {
-				run, err := h.db.GetWorkflowRun(ctx, runID)
-				if err != nil || run == nil {
-					continue
-				}
-				status = run.Status
-				results, _ := h.db.GetActionResults(ctx, runID)
-				actionResults = results
-			}
-
-			// Send update if status or results changed
-			if string(status) != lastStatus || len(actionResults) != lastActionCount {
-				msg := models.WSMessage{
-					Type: "run_update",
-					Payload: map[string]interface{}{
-						"run_id":         runID,
-						"status":         status,
-						"action_results": actionResults,
-					},
-				}
-				conn.WriteJSON(msg)
-
-				lastStatus = string(status)
-				lastActionCount = len(actionResults)
-
-				// Close if completed
-				if status == models.StatusSuccess || status == models.StatusFailed || status == models.StatusCanceled {
-					// Update database with final status
-					if h.db != nil {
-						errorMsg := ""
-						if status == models.StatusFailed {
-							for _, ar := range actionResults {
-								if ar.ErrorMessage != "" {
-									errorMsg = ar.ErrorMessage
-									break
-								}
-							}
-						}
-						h.db.UpdateWorkflowRunStatus(ctx, runID, status, errorMsg)
-					}
-					return
-				}
-			}
-		}
+	run, err := h.latestRunInput(ctx, schedule.WorkflowID, schedule.LLMProvider, schedule.Headless, codeTemplates)
+	if err != nil || run == nil {
+		http.Error(w, "Workflow not found", http.StatusBadRequest)
+		return client.ScheduleOptions{}, false
 	}
-}
-
-// ==================== LLM Handlers ====================
-
-// ListLLMProviders lists all available LLM providers with their status
-func (h *Handlers) ListLLMProviders(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	run.Parameters = schedule.Parameters
 
-	// Define all supported providers
-	allProviders := []struct {
-		name        string
-		displayName string
-		requiresKey bool
-	}{
-		{"ollama", "Ollama", false},
-		{"openai", "OpenAI", true},
-		{"anthropic", "Anthropic", true},
-		{"gemini", "Gemini", true},
+	spec := client.ScheduleSpec{TimeZoneName: schedule.Timezone}
+	if schedule.Cron != "" {
+		spec.CronExpressions = []string{schedule.Cron}
+	} else {
+		spec.Intervals = []client.ScheduleIntervalSpec{{Every: time.Duration(schedule.Interval) * time.Second}}
 	}
 
-	providers := make([]map[string]interface{}, 0)
-
-	for _, p := range allProviders {
-		// Check if we have a config (from env) or runtime key
-		config, hasEnvConfig := h.llmConfigs[p.name]
-		runtimeKey, hasRuntimeKey := h.runtimeAPIKeys[p.name]
+	return client.ScheduleOptions{
+		Spec: spec,
+		Action: &client.ScheduleWorkflowAction{
+			ID:        fmt.Sprintf("scheduled-run-%s", schedule.ID),
+			Workflow:  "ScheduledRunWorkflow",
+			Args:      []interface{}{workflows.ScheduledRunInput{ScheduleID: schedule.ID, Run: *run}},
+			TaskQueue: TaskQueue,
+		},
+	}, true
+}
 
-		hasKey := false
-		available := false
+// scheduleWorkflowID is the Temporal schedule ID of a schedule
+func scheduleWorkflowID(scheduleID string) string {
+	return fmt.Sprintf("browser-automation-schedule-%s", scheduleID)
+}
 
-		if p.requiresKey {
-			// Check if API key is available from env or runtime
-			hasKey = (hasEnvConfig && config.APIKey != "") || hasRuntimeKey
+// ==================== Screenshot Handlers ====================
 
-			if hasKey {
-				// Create config with runtime key if needed
-				testConfig := config
-				if hasRuntimeKey {
-					testConfig = llm.DefaultConfigs()[llm.ProviderName(p.name)]
-					testConfig.APIKey = runtimeKey
-				}
-				provider, _ := llm.NewProvider(testConfig)
-				available = provider != nil && provider.IsAvailable(ctx)
-			}
-		} else {
-			// Ollama doesn't require a key
-			hasKey = true
-			if hasEnvConfig {
-				provider, _ := llm.NewProvider(config)
-				available = provider != nil && provider.IsAvailable(ctx)
-			}
-		}
+// ServeDownload serves a file a run downloaded
+func (h *Handlers) ServeDownload(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
 
-		providers = append(providers, map[string]interface{}{
-			"name":      p.name,
-			"display":   p.displayName,
-			"has_key":   hasKey,
-			"available": available,
-		})
+	// Only serve files from the run's downloads
+	key := artifacts.DownloadKey(vars["id"], vars["filename"])
+	disposition := fmt.Sprintf("attachment; filename=%q", path.Base(key))
+	if h.redirectToArtifact(w, r, key, disposition) {
+		return
+	}
+	body, ok := h.openArtifact(w, r, key, "Download not found")
+	if !ok {
+		return
 	}
+	defer body.Close()
 
-	respondJSON(w, providers)
+	w.Header().Set("Content-Disposition", disposition)
+	serveArtifact(w, r, key, body)
 }
 
-// SetAPIKey sets an API key for a provider
-func (h *Handlers) SetAPIKey(w http.ResponseWriter, r *http.Request) {
+// ServeScreenshot serves a screenshot or DOM snapshot file
+func (h *Handlers) ServeScreenshot(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	providerName := vars["name"]
 
-	// Validate provider name
-	validProviders := map[string]bool{"openai": true, "anthropic": true, "gemini": true}
-	if !validProviders[providerName] {
-		http.Error(w, "Invalid provider name", http.StatusBadRequest)
+	// Security: Only allow files from the screenshots
+	key := artifacts.ScreenshotKey(vars["filename"])
+	if h.redirectToArtifact(w, r, key, "") {
 		return
 	}
-
-	var req struct {
-		APIKey string `json:"api_key"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	body, ok := h.openArtifact(w, r, key, "Screenshot not found")
+	if !ok {
 		return
 	}
+	defer body.Close()
 
-	if req.APIKey == "" {
-		http.Error(w, "API key is required", http.StatusBadRequest)
-		return
+	// Serve the file. DOM snapshots are kept alongside the screenshots and
+	// shown sandboxed, so the captured page can't act as this origin.
+	if path.Ext(key) == ".html" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", "sandbox")
+	} else {
+		w.Header().Set("Content-Type", "image/png")
 	}
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	serveArtifact(w, r, key, body)
+}
 
-	// Store the runtime API key
-	h.runtimeAPIKeys[providerName] = req.APIKey
+// signedURLExpiry is how long the artifact URLs clients are redirected to
+// stay valid
+const signedURLExpiry = 15 * time.Minute
 
-	// Also update llmConfigs so it's used in workflow execution
-	config := llm.DefaultConfigs()[llm.ProviderName(providerName)]
-	config.APIKey = req.APIKey
-	h.llmConfigs[providerName] = config
+// redirectToArtifact redirects to a signed URL of the artifact when the
+// store is a bucket clients can fetch from, reporting whether it did
+func (h *Handlers) redirectToArtifact(w http.ResponseWriter, r *http.Request, key, disposition string) bool {
+	signer, ok := h.artifacts.(artifacts.Signer)
+	if !ok {
+		return false
+	}
+	signedURL, err := signer.SignedURL(key, disposition, signedURLExpiry)
+	if err != nil {
+		return false
+	}
+	http.Redirect(w, r, signedURL, http.StatusFound)
+	return true
+}
 
-	// Verify the key works
-	ctx := r.Context()
-	provider, _ := llm.NewProvider(config)
-	available := provider != nil && provider.IsAvailable(ctx)
+// openArtifact opens an artifact from the store, responding with notFound
+// when there is none
+func (h *Handlers) openArtifact(w http.ResponseWriter, r *http.Request, key, notFound string) (io.ReadCloser, bool) {
+	body, err := h.artifacts.Get(r.Context(), key)
+	if errors.Is(err, artifacts.ErrNotFound) {
+		http.Error(w, notFound, http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Failed to read artifact: "+err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return body, true
+}
 
-	respondJSON(w, map[string]interface{}{
-		"success":   true,
-		"provider":  providerName,
-		"available": available,
-	})
+// serveArtifact writes an artifact's body, serving range requests when it
+// is kept on disk
+func serveArtifact(w http.ResponseWriter, r *http.Request, key string, body io.Reader) {
+	if seeker, ok := body.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, path.Base(key), time.Time{}, seeker)
+		return
+	}
+	io.Copy(w, body)
 }
 
-// DeleteAPIKey removes an API key for a provider
-func (h *Handlers) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	providerName := vars["name"]
+// ==================== Audit Handlers ====================
 
-	delete(h.runtimeAPIKeys, providerName)
-	delete(h.llmConfigs, providerName)
+// ListAuditEvents lists the audit log, newest first, paged and filtered by
+// actor, resource, method and date
+func (h *Handlers) ListAuditEvents(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
 
-	respondJSON(w, map[string]interface{}{
-		"success":  true,
-		"provider": providerName,
+	opts, ok := queryList(w, r, models.AuditSorts)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	events, total, err := h.db.ListAuditEvents(r.Context(), models.AuditFilter{
+		ListOptions: opts,
+		Actor:       query.Get("actor"),
+		ResourceID:  query.Get("resource_id"),
+		Method:      strings.ToUpper(query.Get("method")),
 	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	respondJSON(w, events)
 }
 
-// ==================== Screenshot Handlers ====================
+// ==================== Helpers ====================
 
-// ServeScreenshot serves a screenshot file
-func (h *Handlers) ServeScreenshot(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	filename := vars["filename"]
+// workflowETag is the entity tag of a workflow's revision
+func workflowETag(workflow *models.WorkflowDefinition) string {
+	return fmt.Sprintf(`"%d"`, workflow.Revision)
+}
 
-	// Security: Only allow files from the screenshots directory
-	screenshotDir := os.Getenv("SCREENSHOT_DIR")
-	if screenshotDir == "" {
-		screenshotDir = "/tmp/screenshots"
+// ifMatch reports whether a change to a workflow was made from its current
+// revision, as the If-Match header says, writing a 409 when it wasn't.
+// Changes without the header apply to whichever revision is current.
+func ifMatch(w http.ResponseWriter, r *http.Request, workflow *models.WorkflowDefinition) bool {
+	match := r.Header.Get("If-Match")
+	if match == "" || match == "*" {
+		return true
 	}
+	for _, tag := range strings.Split(match, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == workflowETag(workflow) {
+			return true
+		}
+	}
+	w.Header().Set("ETag", workflowETag(workflow))
+	http.Error(w, fmt.Sprintf("Workflow was changed since; it is at revision %d", workflow.Revision), http.StatusConflict)
+	return false
+}
 
-	filePath := filepath.Join(screenshotDir, filepath.Base(filename))
-
-	// Check file exists
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		http.Error(w, "Screenshot not found", http.StatusNotFound)
+// updateFailed writes the error of storing a change to a workflow, a 409
+// when another change was stored since it was read
+func updateFailed(w http.ResponseWriter, err error) {
+	if errors.Is(err, database.ErrConflict) {
+		http.Error(w, "Workflow was changed by another update; get it again and retry", http.StatusConflict)
 		return
 	}
-
-	// Serve the file
-	w.Header().Set("Content-Type", "image/png")
-	w.Header().Set("Cache-Control", "public, max-age=3600")
-	http.ServeFile(w, r, filePath)
+	http.Error(w, "Failed to update workflow: "+err.Error(), http.StatusInternalServerError)
 }
 
-// ==================== Helpers ====================
-
 func respondJSON(w http.ResponseWriter, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(data)
@@ -0,0 +1,910 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"dev/bravebird/browser-automation-go/pkg/database"
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+func TestBatchStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		counts map[models.RunStatus]int
+		want   models.RunStatus
+	}{
+		{"running", map[models.RunStatus]int{models.StatusRunning: 1, models.StatusSuccess: 2}, models.StatusRunning},
+		{"paused", map[models.RunStatus]int{models.StatusPaused: 1, models.StatusFailed: 2}, models.StatusRunning},
+		{"all succeeded", map[models.RunStatus]int{models.StatusSuccess: 2, models.StatusWarning: 1}, models.StatusSuccess},
+		{"all failed", map[models.RunStatus]int{models.StatusFailed: 3}, models.StatusFailed},
+		{"failed or canceled", map[models.RunStatus]int{models.StatusFailed: 2, models.StatusCanceled: 1}, models.StatusFailed},
+		{"some failed", map[models.RunStatus]int{models.StatusSuccess: 2, models.StatusFailed: 1}, models.StatusPartial},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total := 0
+			for _, n := range tt.counts {
+				total += n
+			}
+			if got := batchStatus(tt.counts, total); got != tt.want {
+				t.Errorf("batchStatus(%v) = %q, want %q", tt.counts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublishRunProgressToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		progressToken string
+		authorization string
+		want          int
+	}{
+		{"disabled", "", "Bearer secret", http.StatusNotFound},
+		{"no token", "secret", "", http.StatusUnauthorized},
+		{"wrong token", "secret", "Bearer other", http.StatusUnauthorized},
+		{"not a bearer token", "secret", "secret", http.StatusUnauthorized},
+		{"token", "secret", "Bearer secret", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandlers(nil, nil, nil, nil, nil)
+			h.ProgressToken = tt.progressToken
+
+			req := httptest.NewRequest("POST", "/api/runs/run-1/progress", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			req = mux.SetURLVars(req, map[string]string{"id": "run-1"})
+			rec := httptest.NewRecorder()
+			h.PublishRunProgress(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithPhase(t *testing.T) {
+	running := models.WorkflowResult{
+		Status:   models.StatusRunning,
+		Progress: &models.RunProgress{Step: 2, Total: 3, SequenceID: 2, Phase: models.PhaseExecuting, Attempt: 1},
+	}
+	tests := []struct {
+		name  string
+		state models.WorkflowResult
+		phase *models.ActionPhaseUpdate
+		want  models.ActionPhase
+	}{
+		{"no phase", running, nil, models.PhaseExecuting},
+		{"signal not applied yet", running, &models.ActionPhaseUpdate{SequenceID: 2, Phase: models.PhaseRetrying, Attempt: 2}, models.PhaseRetrying},
+		{"another action", running, &models.ActionPhaseUpdate{SequenceID: 1, Phase: models.PhaseGenerating, Attempt: 1}, models.PhaseExecuting},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := withPhase(tt.state, tt.phase)
+			if got.Progress.Phase != tt.want {
+				t.Errorf("withPhase() phase = %q, want %q", got.Progress.Phase, tt.want)
+			}
+		})
+	}
+	if running.Progress.Phase != models.PhaseExecuting {
+		t.Errorf("withPhase() changed the queried progress to %q", running.Progress.Phase)
+	}
+}
+
+func TestOpenBrowserSessions(t *testing.T) {
+	closed := make(chan string, 1)
+	worker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/sessions/":
+			fmt.Fprint(w, `[{"id": "s-1", "run_id": "run-1", "url": "https://example.com", "created_at": "2024-01-01T00:00:00Z"}]`)
+		case r.Method == http.MethodDelete && r.URL.Path == "/sessions/s-1":
+			closed <- "s-1"
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer worker.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	h := NewHandlers(nil, nil, nil, nil, nil)
+	h.WorkerURLs = []string{worker.URL, down.URL}
+
+	rec := httptest.NewRecorder()
+	h.ListOpenBrowserSessions(rec, httptest.NewRequest("GET", "/api/admin/sessions", nil))
+	var list OpenBrowserSessions
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("decoding sessions: %v", err)
+	}
+	if len(list.Sessions) != 1 || list.Sessions[0].ID != "s-1" || list.Sessions[0].Worker != worker.URL {
+		t.Errorf("sessions = %+v, want s-1 on %s", list.Sessions, worker.URL)
+	}
+	if _, ok := list.Errors[down.URL]; !ok || len(list.Errors) != 1 {
+		t.Errorf("errors = %v, want the unreachable worker", list.Errors)
+	}
+
+	tests := []struct {
+		name    string
+		workers []string
+		id      string
+		want    int
+	}{
+		{"closed", []string{down.URL, worker.URL}, "s-1", http.StatusNoContent},
+		{"unknown", []string{worker.URL}, "s-2", http.StatusNotFound},
+		{"maybe on an unreachable worker", []string{worker.URL, down.URL}, "s-2", http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h.WorkerURLs = tt.workers
+			req := mux.SetURLVars(httptest.NewRequest("DELETE", "/api/admin/sessions/"+tt.id, nil), map[string]string{"id": tt.id})
+			rec := httptest.NewRecorder()
+			h.CloseOpenBrowserSession(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+	if len(closed) != 1 {
+		t.Errorf("worker closed %d sessions, want 1", len(closed))
+	}
+}
+
+func TestQueryList(t *testing.T) {
+	tests := []struct {
+		query string
+		want  models.ListOptions
+		ok    bool
+	}{
+		{"", models.ListOptions{}, true},
+		{"limit=20&offset=40&sort=name&order=asc", models.ListOptions{Limit: 20, Offset: 40, Sort: "name", Asc: true}, true},
+		{"since=2026-10-01&until=2026-10-08T12:00:00%2B02:00", models.ListOptions{
+			Since: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+			Until: time.Date(2026, 10, 8, 10, 0, 0, 0, time.UTC),
+		}, true},
+		{"limit=-1", models.ListOptions{}, false},
+		{"limit=5000", models.ListOptions{}, false},
+		{"sort=id", models.ListOptions{}, false},
+		{"order=up", models.ListOptions{}, false},
+		{"since=yesterday", models.ListOptions{}, false},
+		{"deleted=true", models.ListOptions{Deleted: true}, true},
+		{"deleted=maybe", models.ListOptions{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/workflows?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			got, ok := queryList(rec, req, models.WorkflowSorts)
+			if ok != tt.ok {
+				t.Fatalf("queryList() ok = %v, want %v (%s)", ok, tt.ok, rec.Body)
+			}
+			if ok && (got.Limit != tt.want.Limit || got.Offset != tt.want.Offset || got.Sort != tt.want.Sort ||
+				got.Asc != tt.want.Asc || !got.Since.Equal(tt.want.Since) || !got.Until.Equal(tt.want.Until) || got.Deleted != tt.want.Deleted) {
+				t.Errorf("queryList() = %+v, want %+v", got, tt.want)
+			}
+			if !ok && rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+// newTestDB opens a migrated SQLite database removed after the test
+func newTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.NewSQLite(filepath.Join(t.TempDir(), "automator.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestUpdateWorkflow(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	params, _ := json.Marshal([]models.WorkflowParameter{{Name: "email", DefaultValue: "a@example.com"}})
+	if err := db.CreateWorkflowDefinition(ctx, &models.WorkflowDefinition{
+		ID: "wf-1", Name: "Login", StartURL: "https://example.com", SemanticContext: "[]", ParametersJSON: string(params),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handlers{db: db}
+
+	tests := []struct {
+		name, id, body string
+		code           int
+	}{
+		{"unknown workflow", "wf-2", `{"name": "Sign in"}`, http.StatusNotFound},
+		{"blank name", "wf-1", `{"name": "  "}`, http.StatusBadRequest},
+		{"long name", "wf-1", `{"name": "` + strings.Repeat("x", 256) + `"}`, http.StatusBadRequest},
+		{"relative start URL", "wf-1", `{"start_url": "/login"}`, http.StatusBadRequest},
+		{"unknown parameter", "wf-1", `{"defaults": {"password": "secret"}}`, http.StatusBadRequest},
+		{"update", "wf-1", `{"name": " Sign in ", "description": "Signs in", "start_url": "https://example.com/login", "defaults": {"email": "b@example.com"}}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := mux.SetURLVars(httptest.NewRequest("PUT", "/api/workflows/"+tt.id, strings.NewReader(tt.body)), map[string]string{"id": tt.id})
+			rec := httptest.NewRecorder()
+			h.UpdateWorkflow(rec, req)
+			if rec.Code != tt.code {
+				t.Errorf("status = %d, want %d (%s)", rec.Code, tt.code, rec.Body)
+			}
+		})
+	}
+
+	got, err := db.GetWorkflowDefinition(ctx, "wf-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Sign in" || got.Description != "Signs in" || got.StartURL != "https://example.com/login" {
+		t.Errorf("workflow = %q, %q, %q, want the update", got.Name, got.Description, got.StartURL)
+	}
+	if !strings.Contains(got.ParametersJSON, "b@example.com") {
+		t.Errorf("parameters = %s, want the new default", got.ParametersJSON)
+	}
+	versions, err := db.ListWorkflowVersions(ctx, "wf-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 1 || versions[0].ChangeType != models.VersionParametersEdited {
+		t.Errorf("versions = %+v, want one recording the new default", versions)
+	}
+}
+
+func TestEditWorkflowActions(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	if err := db.CreateWorkflowDefinition(ctx, &models.WorkflowDefinition{
+		ID: "wf-1", Name: "Login", SemanticContext: "[]",
+		ParametersJSON: `[{"name": "username", "source_action": 2}, {"name": "offset", "source_action": 3}, {"name": "env"}]`,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateSemanticActions(ctx, "wf-1", []models.SemanticAction{
+		{ID: "a1", SequenceID: 1, ActionType: models.ActionNavigate, Value: "https://example.com"},
+		{ID: "a2", SequenceID: 2, ActionType: models.ActionInput, Value: "alice"},
+		{ID: "a3", SequenceID: 3, ActionType: models.ActionScroll},
+		{ID: "a4", SequenceID: 4, ActionType: models.ActionClick, Target: models.SemanticTarget{Selector: "#submit"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveGeneratedCode(ctx, "wf-1", 1, "script", "package main"); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handlers{db: db}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		seq     string
+		body    string
+		code    int
+		want    string // the actions' types and values after the request
+		params  string // the parameters' names and source actions after it
+	}{
+		{"delete noise", h.DeleteWorkflowAction, "3", "", http.StatusOK, "navigate:https://example.com input:alice click:", "username:2 env:0"},
+		{"delete missing action", h.DeleteWorkflowAction, "9", "", http.StatusNotFound, "", ""},
+		{"edit value", h.UpdateWorkflowAction, "2", `{"value": "bob"}`, http.StatusOK, "navigate:https://example.com input:bob click:", "username:2 env:0"},
+		{"edit unknown type", h.UpdateWorkflowAction, "2", `{"action_type": "wave"}`, http.StatusBadRequest, "", ""},
+		{"insert", h.InsertWorkflowAction, "", `{"sequence_id": 2, "action_type": "keypress", "value": "Tab"}`, http.StatusOK, "navigate:https://example.com keypress:Tab input:bob click:", "username:3 env:0"},
+		{"append", h.InsertWorkflowAction, "", `{"action_type": "submit"}`, http.StatusOK, "navigate:https://example.com keypress:Tab input:bob click: submit:", "username:3 env:0"},
+		{"insert out of range", h.InsertWorkflowAction, "", `{"sequence_id": 9, "action_type": "click"}`, http.StatusBadRequest, "", ""},
+		{"reorder", h.ReorderWorkflowActions, "", `{"order": [1, 3, 2, 4, 5]}`, http.StatusOK, "navigate:https://example.com input:bob keypress:Tab click: submit:", "username:2 env:0"},
+		{"reorder twice", h.ReorderWorkflowActions, "", `{"order": [1, 1, 2, 4, 5]}`, http.StatusBadRequest, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/workflows/wf-1/actions", strings.NewReader(tt.body))
+			req = mux.SetURLVars(req, map[string]string{"id": "wf-1", "seq": tt.seq})
+			rec := httptest.NewRecorder()
+			tt.handler(rec, req)
+			if rec.Code != tt.code {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.code, rec.Body)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+
+			actions, err := db.GetSemanticActions(ctx, "wf-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, len(actions))
+			for i, action := range actions {
+				if action.SequenceID != i+1 {
+					t.Errorf("action %d has sequence_id %d", i+1, action.SequenceID)
+				}
+				got[i] = fmt.Sprintf("%s:%s", action.ActionType, action.Value)
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("actions = %s, want %s", strings.Join(got, " "), tt.want)
+			}
+
+			// The parameters follow their actions, the recorded version too
+			workflow, err := db.GetWorkflowDefinition(ctx, "wf-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			version, err := db.GetWorkflowVersion(ctx, "wf-1", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var params []models.WorkflowParameter
+			if err := json.Unmarshal([]byte(workflow.ParametersJSON), &params); err != nil {
+				t.Fatal(err)
+			}
+			for _, got := range [][]models.WorkflowParameter{params, version.Parameters} {
+				names := make([]string, len(got))
+				for i, param := range got {
+					names[i] = fmt.Sprintf("%s:%d", param.Name, param.SourceAction)
+				}
+				if strings.Join(names, " ") != tt.params {
+					t.Errorf("parameters = %s, want %s", strings.Join(names, " "), tt.params)
+				}
+			}
+		})
+	}
+
+	workflow, err := db.GetWorkflowDefinition(ctx, "wf-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if workflow.GeneratedCode != "" || workflow.IsWorkflowGenerated {
+		t.Error("generated code was kept after the actions changed")
+	}
+}
+
+func TestCloneWorkflow(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	if err := db.CreateWorkflowDefinition(ctx, &models.WorkflowDefinition{
+		ID: "wf-1", Name: "Login", Description: "Signs in", SemanticContext: "[]", ParametersJSON: `[{"name": "email"}]`,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateSemanticActions(ctx, "wf-1", []models.SemanticAction{
+		{ID: "a1", SequenceID: 1, ActionType: models.ActionInput, Value: "{{email}}"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddWorkflowTags(ctx, "wf-1", []string{"auth"}); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handlers{db: db}
+
+	tests := []struct {
+		name, id, body string
+		code           int
+		wantName       string
+	}{
+		{"default name", "wf-1", "", http.StatusOK, "Login (copy)"},
+		{"named", "wf-1", `{"name": "Login as admin"}`, http.StatusOK, "Login as admin"},
+		{"unknown workflow", "wf-2", "", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := mux.SetURLVars(httptest.NewRequest("POST", "/api/workflows/"+tt.id+"/clone", strings.NewReader(tt.body)), map[string]string{"id": tt.id})
+			rec := httptest.NewRecorder()
+			h.CloneWorkflow(rec, req)
+			if rec.Code != tt.code {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.code, rec.Body)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+
+			var clone models.WorkflowDefinition
+			if err := json.Unmarshal(rec.Body.Bytes(), &clone); err != nil {
+				t.Fatal(err)
+			}
+			got, err := db.GetWorkflowDefinition(ctx, clone.ID)
+			if err != nil || got == nil {
+				t.Fatalf("GetWorkflowDefinition() = %v, %v, want the copy", got, err)
+			}
+			if clone.ID == "wf-1" || got.Name != tt.wantName || got.Description != "Signs in" ||
+				got.ParametersJSON != `[{"name": "email"}]` || strings.Join(got.Tags, ",") != "auth" {
+				t.Errorf("copy = %+v, want %q with the original's description, parameters and tags", got, tt.wantName)
+			}
+			actions, err := db.GetSemanticActions(ctx, clone.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(actions) != 1 || actions[0].ID == "a1" || actions[0].Value != "{{email}}" {
+				t.Errorf("copied actions = %+v, want a copy of the original's", actions)
+			}
+		})
+	}
+
+	if original, _ := db.GetSemanticActions(ctx, "wf-1"); len(original) != 1 || original[0].ID != "a1" {
+		t.Errorf("original actions = %+v, want them untouched", original)
+	}
+}
+
+func TestMergeWorkflows(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	for _, wf := range []struct {
+		id, name, params string
+		actions          []models.SemanticAction
+	}{
+		{"login", "Login", `[{"name": "email", "token_type": "variable", "source_action": 2}]`, []models.SemanticAction{
+			{SequenceID: 1, ActionType: models.ActionNavigate, Value: "https://example.com", Timestamp: 100},
+			{SequenceID: 2, ActionType: models.ActionInput, Value: "a@example.com", Timestamp: 300},
+		}},
+		{"report", "Report", `[{"name": "email", "token_type": "variable", "source_action": 1}]`, []models.SemanticAction{
+			{SequenceID: 1, ActionType: models.ActionInput, Value: "b@example.com", Timestamp: 200},
+			{SequenceID: 2, ActionType: models.ActionSubmit, Timestamp: 400},
+		}},
+	} {
+		if err := db.CreateWorkflowDefinition(ctx, &models.WorkflowDefinition{ID: wf.id, Name: wf.name, SemanticContext: "[]", ParametersJSON: wf.params}); err != nil {
+			t.Fatal(err)
+		}
+		for i := range wf.actions {
+			wf.actions[i].ID = fmt.Sprintf("%s-%d", wf.id, i+1)
+		}
+		if err := db.CreateSemanticActions(ctx, wf.id, wf.actions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := &Handlers{db: db}
+
+	tests := []struct {
+		name, body string
+		code       int
+		wantName   string
+		want       string // the actions' values and the parameters' names and actions
+	}{
+		{"concat", `{"workflow_ids": ["login", "report"]}`, http.StatusOK, "Login + Report",
+			"https://example.com a@example.com b@example.com  | email:2 email_2:3"},
+		{"interleave", `{"workflow_ids": ["login", "report"], "mode": "interleave", "name": "Mixed"}`, http.StatusOK, "Mixed",
+			"https://example.com b@example.com a@example.com  | email:3 email_2:2"},
+		{"one workflow", `{"workflow_ids": ["login"]}`, http.StatusBadRequest, "", ""},
+		{"unknown mode", `{"workflow_ids": ["login", "report"], "mode": "zip"}`, http.StatusBadRequest, "", ""},
+		{"unknown workflow", `{"workflow_ids": ["login", "missing"]}`, http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.MergeWorkflows(rec, httptest.NewRequest("POST", "/api/workflows/merge", strings.NewReader(tt.body)))
+			if rec.Code != tt.code {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.code, rec.Body)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+
+			var merged models.WorkflowDefinition
+			if err := json.Unmarshal(rec.Body.Bytes(), &merged); err != nil {
+				t.Fatal(err)
+			}
+			if merged.Name != tt.wantName {
+				t.Errorf("name = %q, want %q", merged.Name, tt.wantName)
+			}
+			actions, err := db.GetSemanticActions(ctx, merged.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for i, action := range actions {
+				if action.SequenceID != i+1 {
+					t.Errorf("action %d has sequence_id %d", i+1, action.SequenceID)
+				}
+				got = append(got, action.Value)
+			}
+			got = append(got, "|")
+			for _, param := range merged.Parameters {
+				got = append(got, fmt.Sprintf("%s:%d", param.Name, param.SourceAction))
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("merged = %s, want %s", strings.Join(got, " "), tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkflowTemplates(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	h := &Handlers{db: db}
+
+	serve := func(handler http.HandlerFunc, id, body string) *httptest.ResponseRecorder {
+		req := mux.SetURLVars(httptest.NewRequest("POST", "/api/workflow-templates", strings.NewReader(body)), map[string]string{"id": id})
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	rec := serve(h.CreateWorkflowTemplate, "", `{
+		"name": "Open page",
+		"actions": [{"action_type": "navigate"}, {"action_type": "click", "target": {"selector": "#accept"}}],
+		"parameters": [{"name": "url", "type": "url", "required": true, "source_action": 1}]
+	}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create status = %d (%s)", rec.Code, rec.Body)
+	}
+	var custom models.WorkflowTemplate
+	json.Unmarshal(rec.Body.Bytes(), &custom)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		id      string
+		body    string
+		code    int
+	}{
+		{"create without actions", h.CreateWorkflowTemplate, "", `{"name": "Empty"}`, http.StatusBadRequest},
+		{"create with a parameter filling no action", h.CreateWorkflowTemplate, "",
+			`{"name": "Bad", "actions": [{"action_type": "navigate"}], "parameters": [{"name": "url", "source_action": 2}]}`, http.StatusBadRequest},
+		{"change built-in", h.UpdateWorkflowTemplate, "google-search", `{"name": "Search"}`, http.StatusForbidden},
+		{"delete built-in", h.DeleteWorkflowTemplate, "generic-login", "", http.StatusForbidden},
+		{"get unknown", h.GetWorkflowTemplate, "missing", "", http.StatusNotFound},
+		{"rename", h.UpdateWorkflowTemplate, custom.ID, `{"name": "Open a page", "actions": [{"action_type": "navigate"}], "parameters": [{"name": "url", "source_action": 1}]}`, http.StatusOK},
+		{"instantiate without a required value", h.InstantiateWorkflowTemplate, "google-search", `{}`, http.StatusBadRequest},
+		{"instantiate with an unknown value", h.InstantiateWorkflowTemplate, "google-search", `{"values": {"query": "go", "lang": "en"}}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serve(tt.handler, tt.id, tt.body); rec.Code != tt.code {
+				t.Errorf("status = %d, want %d (%s)", rec.Code, tt.code, rec.Body)
+			}
+		})
+	}
+
+	var listed []models.WorkflowTemplate
+	json.Unmarshal(serve(h.ListWorkflowTemplates, "", "").Body.Bytes(), &listed)
+	var names []string
+	for _, tmpl := range listed {
+		names = append(names, tmpl.Name)
+	}
+	if got := strings.Join(names, ", "); got != "Generic login, Google search, Open a page" {
+		t.Errorf("templates = %s, want the built-in ones then the renamed one", got)
+	}
+
+	rec = serve(h.InstantiateWorkflowTemplate, "google-search", `{"name": "Search for Go", "values": {"query": "golang"}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("instantiate status = %d (%s)", rec.Code, rec.Body)
+	}
+	var workflow models.WorkflowDefinition
+	json.Unmarshal(rec.Body.Bytes(), &workflow)
+	actions, err := db.GetSemanticActions(ctx, workflow.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if workflow.Name != "Search for Go" || len(actions) != 3 || actions[1].Value != "golang" {
+		t.Errorf("workflow = %q with actions %+v, want the search filled in", workflow.Name, actions)
+	}
+	if len(workflow.Parameters) != 1 || workflow.Parameters[0].DefaultValue != "golang" || workflow.Parameters[0].SourceAction != 2 {
+		t.Errorf("parameters = %+v, want query defaulting to the value given", workflow.Parameters)
+	}
+
+	if rec := serve(h.DeleteWorkflowTemplate, custom.ID, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("delete status = %d (%s)", rec.Code, rec.Body)
+	}
+}
+
+func TestTrash(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	if err := db.CreateWorkflowDefinition(ctx, &models.WorkflowDefinition{ID: "wf-1", Name: "Login", SemanticContext: "[]", ParametersJSON: "[]"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"run-1", "run-2"} {
+		if reserved, _, err := db.ReserveWorkflowRun(ctx, &models.WorkflowRun{ID: id, WorkflowID: "wf-1", ParametersJSON: "{}"}, 10, time.Hour); err != nil || !reserved {
+			t.Fatalf("ReserveWorkflowRun(%s) = %v, %v", id, reserved, err)
+		}
+	}
+	if err := db.UpdateWorkflowRunStatus(ctx, "run-1", models.StatusSuccess, ""); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handlers{db: db}
+
+	type step struct {
+		name    string
+		handler http.HandlerFunc
+		id      string
+		code    int
+	}
+	serve := func(t *testing.T, steps []step) {
+		for _, tt := range steps {
+			t.Run(tt.name, func(t *testing.T) {
+				req := mux.SetURLVars(httptest.NewRequest("POST", "/api/"+tt.id, nil), map[string]string{"id": tt.id})
+				rec := httptest.NewRecorder()
+				tt.handler(rec, req)
+				if rec.Code != tt.code {
+					t.Errorf("status = %d, want %d (%s)", rec.Code, tt.code, rec.Body)
+				}
+			})
+		}
+	}
+
+	// run-2 is still running
+	serve(t, []step{
+		{"delete running run", h.DeleteRun, "run-2", http.StatusConflict},
+		{"delete workflow with running runs", h.DeleteWorkflow, "wf-1", http.StatusConflict},
+		{"delete finished run", h.DeleteRun, "run-1", http.StatusNoContent},
+		{"get deleted run", h.GetRun, "run-1", http.StatusNotFound},
+		{"delete deleted run", h.DeleteRun, "run-1", http.StatusNotFound},
+	})
+
+	if err := db.UpdateWorkflowRunStatus(ctx, "run-2", models.StatusFailed, ""); err != nil {
+		t.Fatal(err)
+	}
+	serve(t, []step{
+		{"delete workflow", h.DeleteWorkflow, "wf-1", http.StatusNoContent},
+		{"get deleted workflow", h.GetWorkflow, "wf-1", http.StatusNotFound},
+		{"delete deleted workflow", h.DeleteWorkflow, "wf-1", http.StatusNotFound},
+		{"restore workflow", h.RestoreWorkflow, "wf-1", http.StatusOK},
+		{"restore restored workflow", h.RestoreWorkflow, "wf-1", http.StatusNotFound},
+		{"restore run", h.RestoreRun, "run-1", http.StatusOK},
+		{"get restored run", h.GetRun, "run-1", http.StatusOK},
+		{"restore unknown run", h.RestoreRun, "run-3", http.StatusNotFound},
+	})
+}
+
+func TestWorkflowRevision(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	if err := db.CreateWorkflowDefinition(ctx, &models.WorkflowDefinition{ID: "wf-1", Name: "Login", SemanticContext: "[]", ParametersJSON: "[]"}); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handlers{db: db}
+
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		method   string
+		path     string
+		ifMatch  string
+		body     string
+		code     int
+		wantETag string
+	}{
+		{"get", h.GetWorkflow, "GET", "/api/workflows/wf-1", "", "", http.StatusOK, `"1"`},
+		{"update", h.UpdateWorkflow, "PUT", "/api/workflows/wf-1", `"1"`, `{"name": "Sign in"}`, http.StatusOK, `"2"`},
+		{"update from an earlier revision", h.UpdateWorkflow, "PUT", "/api/workflows/wf-1", `"1"`, `{"name": "Log in"}`, http.StatusConflict, `"2"`},
+		{"insert action", h.InsertWorkflowAction, "POST", "/api/workflows/wf-1/actions", `W/"2"`, `{"action_type": "click"}`, http.StatusOK, `"3"`},
+		{"insert action from an earlier revision", h.InsertWorkflowAction, "POST", "/api/workflows/wf-1/actions", `"2"`, `{"action_type": "click"}`, http.StatusConflict, `"3"`},
+		{"update without If-Match", h.UpdateWorkflow, "PUT", "/api/workflows/wf-1", "", `{"name": "Log in"}`, http.StatusOK, `"4"`},
+		{"update from any revision", h.UpdateWorkflowParameters, "PUT", "/api/workflows/wf-1/parameters", "*", `[]`, http.StatusOK, `"5"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := mux.SetURLVars(httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)), map[string]string{"id": "wf-1"})
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+			tt.handler(rec, req)
+			if rec.Code != tt.code {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.code, rec.Body)
+			}
+			if got := rec.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("ETag = %s, want %s", got, tt.wantETag)
+			}
+		})
+	}
+
+	got, err := db.GetWorkflowDefinition(ctx, "wf-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Log in" || got.Revision != 5 {
+		t.Errorf("workflow = %q at revision %d, want Log in at revision 5", got.Name, got.Revision)
+	}
+}
+
+func TestSelectorHealth(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	if err := db.CreateWorkflowDefinition(ctx, &models.WorkflowDefinition{ID: "wf-1", Name: "Login", SemanticContext: "[]", ParametersJSON: "[]"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateSemanticActions(ctx, "wf-1", []models.SemanticAction{
+		{ID: "a1", SequenceID: 1, ActionType: models.ActionClick, Target: models.SemanticTarget{Tag: "button", Selector: "#submit"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first selector found the button in one run, a fallback in the
+	// other two, and a failed run found nothing
+	runs := []struct {
+		status models.RunStatus
+		source models.SelectorSource
+	}{
+		{models.StatusSuccess, models.SelectorPrimary},
+		{models.StatusSuccess, models.SelectorFallback},
+		{models.StatusSuccess, models.SelectorFallback},
+		{models.StatusFailed, ""},
+	}
+	for i, run := range runs {
+		runID := fmt.Sprintf("run-%d", i+1)
+		if err := db.CreateWorkflowRun(ctx, &models.WorkflowRun{ID: runID, WorkflowID: "wf-1", Status: run.status, ParametersJSON: "{}"}); err != nil {
+			t.Fatal(err)
+		}
+		result := models.ActionResult{ID: runID + "-1", ActionID: "a1", SequenceID: 1, Status: run.status, SelectorSource: run.source}
+		switch run.source {
+		case models.SelectorPrimary:
+			result.Selector = "#submit"
+		case models.SelectorFallback:
+			result.Selector, result.SelectorFallbacks = "button[name='submit']", 1
+		}
+		if err := db.RecordActionResults(ctx, runID, []models.ActionResult{result}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := &Handlers{db: db}
+	req := mux.SetURLVars(httptest.NewRequest("GET", "/api/workflows/wf-1/selectors", nil), map[string]string{"id": "wf-1"})
+	rec := httptest.NewRecorder()
+	h.GetSelectorHealth(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (%s)", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	var health models.SelectorHealth
+	if err := json.Unmarshal(rec.Body.Bytes(), &health); err != nil {
+		t.Fatal(err)
+	}
+	if len(health.Actions) != 1 {
+		t.Fatalf("health of %d actions, want 1", len(health.Actions))
+	}
+	got := health.Actions[0]
+	if got.Executions != 3 || got.Primary != 1 || got.Fallback != 2 || len(got.Selectors) != 2 {
+		t.Errorf("health = %d executions, %d primary, %d fallback, %d selectors, want 3, 1, 2, 2",
+			got.Executions, got.Primary, got.Fallback, len(got.Selectors))
+	}
+	if got.Selectors[0].Selector != "button[name='submit']" || got.Selectors[0].Source != models.SelectorFallback {
+		t.Errorf("most used selector = %s (%s), want the fallback", got.Selectors[0].Selector, got.Selectors[0].Source)
+	}
+	if got.Description == "" {
+		t.Error("action has no description")
+	}
+}
+
+func TestRunReport(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	if err := db.CreateWorkflowDefinition(ctx, &models.WorkflowDefinition{ID: "wf-1", Name: "Login", SemanticContext: "[]", ParametersJSON: "[]"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateWorkflowRun(ctx, &models.WorkflowRun{ID: "run-1", WorkflowID: "wf-1", Status: models.StatusFailed, ParametersJSON: "{}"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RecordActionResults(ctx, "run-1", []models.ActionResult{
+		{ID: "r1", ActionID: "a1", SequenceID: 1, Status: models.StatusSuccess, Duration: 1500},
+		{ID: "r2", ActionID: "a2", SequenceID: 2, Status: models.StatusFailed, ErrorMessage: "element not found"},
+		{ID: "r3", ActionID: "a3", SequenceID: 3, Status: models.StatusCanceled},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &Handlers{db: db}
+	req := mux.SetURLVars(httptest.NewRequest("GET", "/api/runs/run-1/report", nil), map[string]string{"id": "run-1"})
+	rec := httptest.NewRecorder()
+	h.GetRunReport(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (%s)", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	// The run's recorded action results are the report's test cases
+	var report junitSuites
+	if err := xml.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Tests != 3 || report.Failures != 1 || len(report.Suites) != 1 || len(report.Suites[0].Cases) != 3 {
+		t.Fatalf("report = %d tests, %d failures, want 3 tests with 1 failure", report.Tests, report.Failures)
+	}
+	if failure := report.Suites[0].Cases[1].Failure; failure == nil || failure.Message != "element not found" {
+		t.Errorf("failure of step 2 = %+v, want element not found", failure)
+	}
+}
+
+// importRequest posts a bundle of the files to ImportWorkflow
+func importRequest(t *testing.T, files map[string][]byte) *http.Request {
+	t.Helper()
+	var bundle bytes.Buffer
+	zw := zip.NewWriter(&bundle)
+	for name, data := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write(data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("bundle", "login.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(bundle.Bytes())
+	mw.Close()
+	req := httptest.NewRequest("POST", "/api/workflows/import", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestImportWorkflow(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	h := &Handlers{db: db}
+
+	manifest := []byte(fmt.Sprintf(`{"version": %d, "name": "Login", "start_url": "https://example.com", "events_file": "events.json"}`, models.BundleVersion))
+	actions := []byte(`[{"sequence_id": 1, "action_type": "navigate", "value": "https://example.com"}, {"sequence_id": 2, "action_type": "input", "value": "alice"}]`)
+	tests := []struct {
+		name  string
+		files map[string][]byte
+		code  int
+	}{
+		{"unknown action type", map[string][]byte{
+			"manifest.json": manifest, "events.json": []byte("[]"),
+			"actions.json": []byte(`[{"sequence_id": 1, "action_type": "teleport"}]`),
+		}, http.StatusBadRequest},
+		{"invalid action", map[string][]byte{
+			"manifest.json": manifest, "events.json": []byte("[]"),
+			"actions.json": []byte(`[{"sequence_id": 1, "action_type": "extract"}]`),
+		}, http.StatusBadRequest},
+		{"parameter of a missing action", map[string][]byte{
+			"manifest.json": manifest, "events.json": []byte("[]"), "actions.json": actions,
+			"parameters.json": []byte(`[{"name": "username", "source_action": 3}]`),
+		}, http.StatusBadRequest},
+		{"oversized file", map[string][]byte{
+			"manifest.json": manifest, "events.json": make([]byte, maxBundleSize+1), "actions.json": actions,
+		}, http.StatusBadRequest},
+		{"import", map[string][]byte{
+			"manifest.json": manifest, "events.json": []byte("[]"), "actions.json": actions,
+			"parameters.json": []byte(`[{"name": "username", "source_action": 2}]`),
+		}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ImportWorkflow(rec, importRequest(t, tt.files))
+			if rec.Code != tt.code {
+				t.Errorf("status = %d, want %d (%s)", rec.Code, tt.code, rec.Body)
+			}
+		})
+	}
+
+	workflows, _, err := db.ListWorkflowDefinitions(ctx, models.WorkflowFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(workflows) != 1 {
+		t.Fatalf("imported %d workflows, want only the valid bundle's", len(workflows))
+	}
+	t.Cleanup(func() { os.Remove(workflows[0].EventsFilePath) })
+	got, err := db.GetSemanticActions(ctx, workflows[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Errorf("imported %d actions, want 2", len(got))
+	}
+}
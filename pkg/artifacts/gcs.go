@@ -0,0 +1,54 @@
+package artifacts
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// gcsEndpoint is Cloud Storage's XML API, which accepts S3 requests signed
+// with HMAC keys
+const gcsEndpoint = "https://storage.googleapis.com"
+
+// NewGCS creates a store for a Google Cloud Storage bucket, accessed with
+// the HMAC key of a service account through Cloud Storage's S3
+// interoperability
+func NewGCS(bucket, prefix string, creds Credentials) *S3 {
+	s := NewS3(bucket, prefix, "auto", gcsEndpoint, creds)
+	s.scheme = "gs"
+	return s
+}
+
+// FromEnv returns the store ARTIFACT_STORE names, falling back on local:
+//
+//   - s3://bucket/prefix, signed with AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY
+//     and AWS_SESSION_TOKEN in AWS_REGION, or at S3_ENDPOINT for other S3
+//     compatible stores
+//   - gs://bucket/prefix, signed with the HMAC key GCS_HMAC_ACCESS_ID and
+//     GCS_HMAC_SECRET
+func FromEnv(local *Local) (Store, error) {
+	location := os.Getenv("ARTIFACT_STORE")
+	if location == "" || location == "local" {
+		return local, nil
+	}
+	scheme, rest, ok := strings.Cut(location, "://")
+	bucket, prefix, _ := strings.Cut(rest, "/")
+	if !ok || bucket == "" {
+		return nil, fmt.Errorf("invalid ARTIFACT_STORE %q, want s3://bucket/prefix or gs://bucket/prefix", location)
+	}
+
+	switch scheme {
+	case "s3":
+		return NewS3(bucket, prefix, os.Getenv("AWS_REGION"), os.Getenv("S3_ENDPOINT"), Credentials{
+			AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		}), nil
+	case "gs":
+		return NewGCS(bucket, prefix, Credentials{
+			AccessKey: os.Getenv("GCS_HMAC_ACCESS_ID"),
+			SecretKey: os.Getenv("GCS_HMAC_SECRET"),
+		}), nil
+	}
+	return nil, fmt.Errorf("unsupported ARTIFACT_STORE scheme %q", scheme)
+}
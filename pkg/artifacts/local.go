@@ -0,0 +1,127 @@
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local keeps artifacts on disk, each kind in its own directory, so only
+// processes sharing those directories, such as containers sharing volumes,
+// see each other's artifacts
+type Local struct {
+	Dirs map[string]string // directory by kind
+}
+
+// NewLocal creates a local store keeping screenshots, downloads and
+// generated code in the given directories
+func NewLocal(screenshotDir, downloadDir, codeDir string) *Local {
+	return &Local{Dirs: map[string]string{
+		KindScreenshots: screenshotDir,
+		KindDownloads:   downloadDir,
+		KindCode:        codeDir,
+	}}
+}
+
+// path returns the file an artifact is kept in
+func (l *Local) path(key string) (string, error) {
+	kind, rest, _ := strings.Cut(key, "/")
+	dir, ok := l.Dirs[kind]
+	if !ok || dir == "" {
+		return "", fmt.Errorf("no directory for %s artifacts", kind)
+	}
+	return filepath.Join(dir, filepath.FromSlash(rest)), nil
+}
+
+// Put writes the artifact through a temporary file, so body may be the
+// artifact's own file, as with a download already in place
+func (l *Local) Put(ctx context.Context, key, contentType string, body io.Reader) (string, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create artifact dir: %w", err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".artifact-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to store artifact: %w", err)
+	}
+	defer os.Remove(f.Name())
+	_, err = io.Copy(f, body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to store artifact: %w", err)
+	}
+	return path, nil
+}
+
+// Get opens the artifact's file, which serves ranges as an io.ReadSeeker
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete removes the artifact's file, and its directory once empty unless
+// that is the kind's own
+func (l *Local) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete artifact: %w", err)
+	}
+	kind, _, _ := strings.Cut(key, "/")
+	if dir := filepath.Dir(path); filepath.Clean(dir) != filepath.Clean(l.Dirs[kind]) {
+		os.Remove(dir) // fails while other artifacts are left in it
+	}
+	return nil
+}
+
+func (l *Local) List(ctx context.Context, prefix string) ([]string, error) {
+	root, err := l.path(prefix)
+	if err != nil {
+		return nil, err
+	}
+	dir, filePrefix := root, ""
+	if !strings.HasSuffix(prefix, "/") {
+		dir, filePrefix = filepath.Dir(root), filepath.Base(root)
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keyDir := prefix[:strings.LastIndex(prefix, "/")+1]
+	var keys []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".artifact-") || !strings.HasPrefix(name, filePrefix) {
+			continue
+		}
+		keys = append(keys, keyDir+name)
+	}
+	return keys, nil
+}
@@ -0,0 +1,353 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unsignedPayload stands in for the hash of bodies streamed to the bucket
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// emptyPayload is the SHA-256 of an empty body
+const emptyPayload = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// Credentials sign requests to an object store
+type Credentials struct {
+	AccessKey    string
+	SecretKey    string
+	SessionToken string // for temporary credentials
+}
+
+// S3 keeps artifacts in an S3 bucket, or a bucket of another store with an
+// S3 compatible API such as MinIO, under an optional key prefix
+type S3 struct {
+	Bucket   string
+	Prefix   string
+	Region   string
+	Endpoint string // e.g. http://minio:9000, addressed path-style; AWS when empty
+	Creds    Credentials
+	Client   *http.Client
+	scheme   string // of the URLs Put returns
+}
+
+// NewS3 creates a store for an S3 bucket
+func NewS3(bucket, prefix, region, endpoint string, creds Credentials) *S3 {
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3{
+		Bucket:   bucket,
+		Prefix:   strings.Trim(prefix, "/"),
+		Region:   region,
+		Endpoint: strings.TrimSuffix(endpoint, "/"),
+		Creds:    creds,
+		Client:   &http.Client{Timeout: 5 * time.Minute},
+		scheme:   "s3",
+	}
+}
+
+// objectKey returns the bucket key of an artifact
+func (s *S3) objectKey(key string) string {
+	if s.Prefix == "" {
+		return key
+	}
+	return s.Prefix + "/" + key
+}
+
+// objectURL returns the URL of a bucket key, or of the bucket when empty
+func (s *S3) objectURL(objectKey string) *url.URL {
+	u := &url.URL{Scheme: "https", Host: fmt.Sprintf("%s.s3.%s.amazonaws.com", s.Bucket, s.Region)}
+	p := "/" + objectKey
+	if s.Endpoint != "" {
+		u, _ = url.Parse(s.Endpoint)
+		p = "/" + s.Bucket + "/" + objectKey
+	}
+	u.Path = p
+	u.RawPath = awsEscape(p, false)
+	return u
+}
+
+// do signs and sends a request, returning the response of a 2xx status
+func (s *S3) do(ctx context.Context, method string, u *url.URL, body io.Reader, size int64, payloadHash string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	// The URL was escaped as it is signed
+	req.URL = u
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	signV4(req, s.Creds, s.Region, payloadHash, time.Now())
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s %s: %s: %s", method, u.Path, resp.Status, bytes.TrimSpace(msg))
+	}
+	return resp, nil
+}
+
+// Put uploads the artifact. Bodies that can't seek are buffered to learn
+// their length.
+func (s *S3) Put(ctx context.Context, key, contentType string, body io.Reader) (string, error) {
+	var size int64
+	if seeker, ok := body.(io.ReadSeeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err == nil {
+			var end int64
+			if end, err = seeker.Seek(0, io.SeekEnd); err == nil {
+				size = end - start
+				_, err = seeker.Seek(start, io.SeekStart)
+			}
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to store artifact: %w", err)
+		}
+	} else {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return "", fmt.Errorf("failed to store artifact: %w", err)
+		}
+		body, size = bytes.NewReader(data), int64(len(data))
+	}
+
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	objectKey := s.objectKey(key)
+	resp, err := s.do(ctx, http.MethodPut, s.objectURL(objectKey), body, size, unsignedPayload, header)
+	if err != nil {
+		return "", fmt.Errorf("failed to store artifact: %w", err)
+	}
+	resp.Body.Close()
+	return fmt.Sprintf("%s://%s/%s", s.scheme, s.Bucket, objectKey), nil
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, s.objectURL(s.objectKey(key)), nil, 0, emptyPayload, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, s.objectURL(s.objectKey(key)), nil, 0, emptyPayload, nil)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete artifact: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// SignedURL returns a presigned URL of the artifact. A disposition is
+// returned by the bucket as the response's Content-Disposition.
+func (s *S3) SignedURL(key, disposition string, expires time.Duration) (string, error) {
+	u := s.objectURL(s.objectKey(key))
+	if disposition != "" {
+		u.RawQuery = url.Values{"response-content-disposition": {disposition}}.Encode()
+	}
+	return presignV4(u, s.Creds, s.Region, time.Now(), expires).String(), nil
+}
+
+// listResult is the part of a ListObjectsV2 response List reads
+type listResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List returns the artifacts directly under prefix, like the files of a
+// directory
+func (s *S3) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		u := s.objectURL("")
+		query := url.Values{"list-type": {"2"}, "prefix": {s.objectKey(prefix)}, "delimiter": {"/"}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		u.RawQuery = canonicalQuery(query)
+
+		resp, err := s.do(ctx, http.MethodGet, u, nil, 0, emptyPayload, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list artifacts: %w", err)
+		}
+		var result listResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list artifacts: %w", err)
+		}
+		for _, c := range result.Contents {
+			keys = append(keys, strings.TrimPrefix(c.Key, s.Prefix+"/"))
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return keys, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// signV4 signs a request to S3 with AWS Signature Version 4, covering the
+// host and the headers set on the request
+func signV4(req *http.Request, creds Credentials, region, payloadHash string, now time.Time) {
+	now = now.UTC()
+	req.Header.Set("X-Amz-Date", now.Format(amzDateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, strings.TrimSpace(headers[name]))
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsEscape(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKey, credentialScope(region, now), signedHeaders, signature(creds, region, now, canonicalRequest)))
+}
+
+// presignV4 signs a GET of u in its query string, so clients without
+// credentials can fetch it until it expires
+func presignV4(u *url.URL, creds Credentials, region string, now time.Time, expires time.Duration) *url.URL {
+	now = now.UTC()
+	query := u.Query()
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", creds.AccessKey+"/"+credentialScope(region, now))
+	query.Set("X-Amz-Date", now.Format(amzDateFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if creds.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		awsEscape(u.Path, false),
+		canonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	query.Set("X-Amz-Signature", signature(creds, region, now, canonicalRequest))
+
+	signed := *u
+	signed.RawQuery = canonicalQuery(query)
+	return &signed
+}
+
+// amzDateFormat is the timestamp format of signed requests
+const amzDateFormat = "20060102T150405Z"
+
+// credentialScope is the day and region a signature is valid for
+func credentialScope(region string, now time.Time) string {
+	return now.Format("20060102") + "/" + region + "/s3/aws4_request"
+}
+
+// signature signs a canonical request with a key derived from the secret
+// key for the request's day and region
+func signature(creds Credentials, region string, now time.Time, canonicalRequest string) string {
+	stringToSign := "AWS4-HMAC-SHA256\n" + now.Format(amzDateFormat) + "\n" + credentialScope(region, now) + "\n" + hashHex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretKey), now.Format("20060102"))
+	for _, part := range []string{region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalQuery encodes query parameters sorted by name, as signed
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var parts []string
+	for _, name := range names {
+		values := append([]string{}, query[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, awsEscape(name, true)+"="+awsEscape(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsEscape percent-encodes all but unreserved characters, and slashes
+// unless encodeSlash is set
+func awsEscape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,71 @@
+// Package artifacts keeps the files runs produce, such as screenshots,
+// downloads and generated code, on local disk or in an object store the
+// API server and every worker share.
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when a store has no artifact under a key
+var ErrNotFound = errors.New("artifact not found")
+
+// Kinds of artifact, the first segment of their keys
+const (
+	KindScreenshots = "screenshots" // screenshots and DOM snapshots, by filename
+	KindDownloads   = "downloads"   // files runs downloaded and their reports, by run ID
+	KindCode        = "code"        // per-action generated code, by workflow ID
+)
+
+// Store keeps artifacts by key, a slash separated path starting with the
+// artifact's kind such as "downloads/<run ID>/report.pdf"
+type Store interface {
+	// Put stores an artifact, returning where it is kept: a file path or
+	// a bucket URL whose base name is the artifact's filename
+	Put(ctx context.Context, key, contentType string, body io.Reader) (string, error)
+	// Get opens an artifact, returning ErrNotFound when there is none
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// List returns the keys of the artifacts under prefix
+	List(ctx context.Context, prefix string) ([]string, error)
+	// Delete removes an artifact, succeeding when there is none
+	Delete(ctx context.Context, key string) error
+}
+
+// Signer is implemented by stores clients can fetch artifacts from
+// directly, such as buckets, sparing the API server from relaying them
+type Signer interface {
+	// SignedURL returns a URL fetching an artifact until it expires, served
+	// with the given Content-Disposition unless empty
+	SignedURL(key, disposition string, expires time.Duration) (string, error)
+}
+
+// ScreenshotKey is the key of a screenshot or DOM snapshot
+func ScreenshotKey(filename string) string {
+	return Key(KindScreenshots, filename)
+}
+
+// DownloadKey is the key of a file a run downloaded or a report it saved
+// among its downloads
+func DownloadKey(runID, filename string) string {
+	return Key(KindDownloads, runID, filename)
+}
+
+// CodeKey is the key of a workflow's generated code file
+func CodeKey(workflowID, filename string) string {
+	return Key(KindCode, workflowID, filename)
+}
+
+// Key joins a kind and path segments into a key. Segments are reduced to
+// their base names so ones taken from requests can't leave their kind.
+func Key(kind string, segments ...string) string {
+	parts := []string{kind}
+	for _, s := range segments {
+		parts = append(parts, path.Base(strings.ReplaceAll(s, "\\", "/")))
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,100 @@
+// Package audit records the requests that change something through the API
+// in an audit log: who made them, to what, when and how they ended.
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/gorilla/mux"
+
+	"dev/bravebird/browser-automation-go/pkg/logging"
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// ActorHeader names the user a request is made for. The API has no users
+// of its own, so it is set by the authenticating proxy in front of it,
+// which must also set X-Forwarded-For and drop both from its clients.
+const ActorHeader = "X-Forwarded-User"
+
+// Recorder stores audit events
+type Recorder interface {
+	RecordAuditEvent(ctx context.Context, e *models.AuditEvent) error
+}
+
+type eventKey struct{}
+
+// Middleware records each request that may change something, any but a
+// GET, HEAD or OPTIONS, once it is served, except for the routes skipped.
+// A request whose event can't be stored is logged instead, as it has
+// already been served.
+func Middleware(recorder Recorder, skip ...string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var route string
+			if current := mux.CurrentRoute(r); current != nil {
+				route, _ = current.GetPathTemplate()
+			}
+			if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions || slices.Contains(skip, route) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			e := &models.AuditEvent{
+				Actor:      r.Header.Get(ActorHeader),
+				RemoteAddr: remoteAddr(r),
+				Method:     r.Method,
+				Route:      route,
+				Path:       r.URL.Path,
+				ResourceID: pathResource(mux.Vars(r)),
+				// logging.Requests gave the request its ID as it came in
+				RequestID: w.Header().Get(logging.RequestIDHeader),
+			}
+			m := httpsnoop.CaptureMetrics(next, w, r.WithContext(context.WithValue(r.Context(), eventKey{}, e)))
+			e.Status = m.Code
+
+			ctx := context.WithoutCancel(r.Context())
+			if err := recorder.RecordAuditEvent(ctx, e); err != nil {
+				slog.ErrorContext(ctx, "Failed to record audit event",
+					"error", err, "actor", e.Actor, "method", e.Method, "path", e.Path, "resourceID", e.ResourceID, "status", e.Status)
+			}
+		})
+	}
+}
+
+// SetResource records that the request of ctx created the resource with the
+// ID, such as the run it started, rather than changed the one its path
+// names
+func SetResource(ctx context.Context, id string) {
+	if e, ok := ctx.Value(eventKey{}).(*models.AuditEvent); ok {
+		e.ResourceID = id
+	}
+}
+
+// pathResource returns the ID or name of the resource a path names
+func pathResource(vars map[string]string) string {
+	for _, name := range []string{"id", "name", "action_type"} {
+		if value, ok := vars[name]; ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// remoteAddr returns the address of the client a request came from, the
+// first of X-Forwarded-For behind a proxy
+func remoteAddr(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		client, _, _ := strings.Cut(forwarded, ",")
+		return strings.TrimSpace(client)
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"dev/bravebird/browser-automation-go/pkg/logging"
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+type recorder []models.AuditEvent
+
+func (r *recorder) RecordAuditEvent(_ context.Context, e *models.AuditEvent) error {
+	*r = append(*r, *e)
+	return nil
+}
+
+func TestMiddleware(t *testing.T) {
+	var events recorder
+	router := mux.NewRouter()
+	api := router.PathPrefix("/api").Subrouter()
+	api.Use(Middleware(&events, "/api/runs/{id}/progress"))
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	api.HandleFunc("/workflows/{id}", ok).Methods("GET", "PUT")
+	api.HandleFunc("/workflows/{id}/run", func(w http.ResponseWriter, r *http.Request) {
+		SetResource(r.Context(), "run-1")
+	}).Methods("POST")
+	api.HandleFunc("/llm/providers/{name}/key", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Invalid provider name", http.StatusBadRequest)
+	}).Methods("POST")
+	api.HandleFunc("/runs/{id}/progress", ok).Methods("POST")
+	handler := logging.Requests(router)
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		headers map[string]string
+		want    *models.AuditEvent
+	}{
+		{"read", "GET", "/api/workflows/wf-1", nil, nil},
+		{"edit", "PUT", "/api/workflows/wf-1", map[string]string{ActorHeader: "ada", logging.RequestIDHeader: "req-1"}, &models.AuditEvent{
+			Actor: "ada", RemoteAddr: "192.0.2.1", Method: "PUT", Route: "/api/workflows/{id}", Path: "/api/workflows/wf-1",
+			ResourceID: "wf-1", Status: http.StatusOK, RequestID: "req-1",
+		}},
+		{"created", "POST", "/api/workflows/wf-1/run", map[string]string{"X-Forwarded-For": "203.0.113.7, 10.0.0.1"}, &models.AuditEvent{
+			RemoteAddr: "203.0.113.7", Method: "POST", Route: "/api/workflows/{id}/run", Path: "/api/workflows/wf-1/run",
+			ResourceID: "run-1", Status: http.StatusOK,
+		}},
+		{"refused", "POST", "/api/llm/providers/acme/key", nil, &models.AuditEvent{
+			RemoteAddr: "192.0.2.1", Method: "POST", Route: "/api/llm/providers/{name}/key", Path: "/api/llm/providers/acme/key",
+			ResourceID: "acme", Status: http.StatusBadRequest,
+		}},
+		{"skipped", "POST", "/api/runs/run-1/progress", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events = nil
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if tt.want == nil {
+				if len(events) != 0 {
+					t.Errorf("recorded %+v, want nothing", events)
+				}
+				return
+			}
+			if len(events) != 1 {
+				t.Fatalf("recorded %+v, want one event", events)
+			}
+			got := events[0]
+			if tt.want.RequestID == "" {
+				got.RequestID = "" // a new one
+			}
+			if got != *tt.want {
+				t.Errorf("recorded %+v, want %+v", got, *tt.want)
+			}
+		})
+	}
+}
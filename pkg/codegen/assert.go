@@ -0,0 +1,133 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// writeAssertStep emits the check of an assert action, which panics or, in
+// test mode, fails the test when the assertion doesn't hold
+func (b *scriptBuilder) writeAssertStep(action models.SemanticAction) {
+	check := action.Assert
+	if check == nil {
+		b.body.WriteString("\t// Assert action without an assertion\n")
+		return
+	}
+	step := action.SequenceID
+	want := b.assertValueExpr(check.Value)
+
+	switch check.Type {
+	case models.AssertTextEquals, models.AssertTextContains:
+		b.imports["strings"] = true
+		var text string
+		if b.test {
+			el := b.lookup(step, action.Target)
+			text = fmt.Sprintf("text%d", step)
+			fmt.Fprintf(&b.body, "\t%s, err := %s.Text()\n", text, el)
+			fmt.Fprintf(&b.body, "\tif err != nil {\n\t\tt.Fatalf(\"step %d: %%v\", err)\n\t}\n", step)
+		} else {
+			text = b.elementExpr(action.Target) + ".MustWaitVisible().MustText()"
+		}
+		if check.Type == models.AssertTextEquals {
+			b.assertFail(step, fmt.Sprintf("got := strings.TrimSpace(%s); got != %s", text, want),
+				"text is %q, expected %q", "got", want)
+		} else {
+			b.assertFail(step, fmt.Sprintf("got := strings.TrimSpace(%s); !strings.Contains(got, %s)", text, want),
+				"text %q does not contain %q", "got", want)
+		}
+
+	case models.AssertExists, models.AssertAbsent:
+		method, args := hasLookup(action.Target)
+		exists := models.AssertExists == check.Type
+		var cond string
+		switch {
+		case b.test && exists:
+			cond = fmt.Sprintf("has, _, err := page.%s(%s); err != nil || !has", method, args)
+		case b.test:
+			cond = fmt.Sprintf("has, _, err := page.%s(%s); err == nil && has", method, args)
+		case exists:
+			cond = fmt.Sprintf("!page.Must%s(%s)", method, args)
+		default:
+			cond = fmt.Sprintf("page.Must%s(%s)", method, args)
+		}
+		if exists {
+			b.assertFail(step, cond, "no element matches "+describeTarget(action.Target))
+		} else {
+			b.assertFail(step, cond, describeTarget(action.Target)+" is present")
+		}
+
+	case models.AssertURLMatches, models.AssertTitleEquals:
+		info := "page.MustInfo()"
+		if b.test {
+			info = fmt.Sprintf("info%d", step)
+			fmt.Fprintf(&b.body, "\t%s, err := page.Info()\n", info)
+			fmt.Fprintf(&b.body, "\tif err != nil {\n\t\tt.Fatalf(\"step %d: %%v\", err)\n\t}\n", step)
+		}
+		if check.Type == models.AssertTitleEquals {
+			b.assertFail(step, fmt.Sprintf("got := %s.Title; got != %s", info, want),
+				"title is %q, expected %q", "got", want)
+		} else {
+			b.imports["regexp"] = true
+			b.assertFail(step, fmt.Sprintf("got := %s.URL; !regexp.MustCompile(%s).MatchString(got)", info, want),
+				"URL %s does not match %s", "got", want)
+		}
+
+	default:
+		fmt.Fprintf(&b.body, "\t// Unsupported assertion: %s\n", check.Type)
+	}
+}
+
+// assertFail emits an if statement on cond that reports a failed assertion.
+// Without args, format is the message as is.
+func (b *scriptBuilder) assertFail(step int, cond, format string, args ...string) {
+	prefix := "assertion failed: "
+	if b.test {
+		prefix = fmt.Sprintf("step %d: ", step) + prefix
+		if len(args) == 0 {
+			format = escapeVerbs(format)
+		}
+	}
+	msg := strings.Join(append([]string{fmt.Sprintf("%q", prefix+format)}, args...), ", ")
+
+	var call string
+	switch {
+	case b.test:
+		call = fmt.Sprintf("t.Fatalf(%s)", msg)
+	case len(args) == 0:
+		call = "panic(" + msg + ")"
+	default:
+		b.imports["fmt"] = true
+		call = fmt.Sprintf("panic(fmt.Sprintf(%s))", msg)
+	}
+	fmt.Fprintf(&b.body, "\tif %s {\n\t\t%s\n\t}\n", cond, call)
+}
+
+// assertValueExpr returns the Go expression for an assertion value, its
+// {{name}} parameters replaced with the flag values at runtime
+func (b *scriptBuilder) assertValueExpr(value string) string {
+	expr := fmt.Sprintf("%q", value)
+	for _, p := range b.params {
+		token := "{{" + p.param.Name + "}}"
+		if !strings.Contains(value, token) {
+			continue
+		}
+		b.imports["strings"] = true
+		expr = fmt.Sprintf("strings.ReplaceAll(%s, %q, *%s)", expr, token, p.ident)
+	}
+	return expr
+}
+
+// hasLookup returns the page method and arguments that report whether a
+// target is on the page without waiting for it
+func hasLookup(target models.SemanticTarget) (method, args string) {
+	if (target.Selector == "" || target.Selector == "window") && target.Text != "" {
+		tag := target.Tag
+		if tag == "" {
+			tag = "*"
+		}
+		return "HasR", fmt.Sprintf("%q, %q", tag, regexpQuote(target.Text))
+	}
+	return "Has", fmt.Sprintf("%q", target.Selector)
+}
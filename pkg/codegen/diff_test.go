@@ -0,0 +1,33 @@
+package codegen
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	oldCode := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+	newCode := "a\nb\nc\nD\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+
+	want := `--- v1
++++ v2
+@@ -1,7 +1,7 @@
+ a
+ b
+ c
+-d
++D
+ e
+ f
+ g
+@@ -10,3 +10,4 @@
+ j
+ k
+ l
++m
+`
+	if got := UnifiedDiff(oldCode, newCode, "v1", "v2"); got != want {
+		t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := UnifiedDiff(oldCode, oldCode, "v1", "v2"); got != "" {
+		t.Errorf("UnifiedDiff() of identical code = %q, want empty", got)
+	}
+}
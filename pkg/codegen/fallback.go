@@ -0,0 +1,103 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// fallbackLookup is the pseudo lookup method of targets found through a
+// selector chain rather than a single page method
+const fallbackLookup = "findElement"
+
+// fallbackSelectors returns the selector chain for target, its selector
+// followed by the ranked candidates. A chain of one means no fallback.
+func fallbackSelectors(target models.SemanticTarget) []string {
+	if target.Selector == "" || target.Selector == "window" {
+		return nil
+	}
+	selectors := []string{target.Selector}
+	for _, candidate := range target.Candidates {
+		if candidate == "" || candidate == "window" || containsString(selectors, candidate) {
+			continue
+		}
+		selectors = append(selectors, candidate)
+	}
+	return selectors
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// useLookup records that the generated code calls method, pulling in the
+// fallback helpers and their imports when it is the selector chain
+func (b *scriptBuilder) useLookup(method string) {
+	if method != fallbackLookup {
+		return
+	}
+	b.fallback = true
+	b.imports["fmt"] = true
+	b.imports["time"] = true
+}
+
+// lookupCall returns the expression finding an element through recv, either
+// a Must* call or, in test mode, an error returning call bounded by stepTimeout
+func (b *scriptBuilder) lookupCall(recv, method, args string) string {
+	b.useLookup(method)
+	switch {
+	case method == fallbackLookup && b.test:
+		return fmt.Sprintf("findElement(%s, %s)", recv, args)
+	case method == fallbackLookup:
+		return fmt.Sprintf("mustFindElement(%s, %s)", recv, args)
+	case b.test:
+		return fmt.Sprintf("%s.Timeout(stepTimeout).%s(%s)", recv, method, args)
+	}
+	return fmt.Sprintf("%s.Must%s(%s)", recv, method, args)
+}
+
+// writeFallbackHelpers appends the selector chain helpers if any step uses them
+func (b *scriptBuilder) writeFallbackHelpers(out *strings.Builder) {
+	if !b.fallback {
+		return
+	}
+	out.WriteString(findElementHelper)
+	if !b.test {
+		out.WriteString(mustFindElementHelper)
+	}
+}
+
+const findElementHelper = `
+// candidateTimeout bounds the wait for each selector of a fallback chain
+const candidateTimeout = 5 * time.Second
+
+// findElement returns the first element matched by selectors, trying each in
+// order so one stale selector does not fail the whole step
+func findElement(page *rod.Page, selectors ...string) (*rod.Element, error) {
+	var err error
+	for _, selector := range selectors {
+		var el *rod.Element
+		if el, err = page.Timeout(candidateTimeout).Element(selector); err == nil {
+			return el.CancelTimeout(), nil
+		}
+	}
+	return nil, fmt.Errorf("no element matches %q: %w", selectors, err)
+}
+`
+
+const mustFindElementHelper = `
+// mustFindElement is like findElement but panics when no selector matches
+func mustFindElement(page *rod.Page, selectors ...string) *rod.Element {
+	el, err := findElement(page, selectors...)
+	if err != nil {
+		panic(err)
+	}
+	return el
+}
+`
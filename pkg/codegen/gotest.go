@@ -0,0 +1,213 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// GenerateGoRodTest renders actions as a TestWorkflow function that can be
+// dropped into an existing go test suite. Steps check errors and stop the test
+// with t.Fatalf, and elements that appeared after an action while recording
+// are asserted with t.Errorf. Variable tokens become package level flags, so
+// values can be overridden with `go test -args -name=value`.
+func GenerateGoRodTest(actions []models.SemanticAction, params []models.WorkflowParameter, opts ScriptOptions) string {
+	b := newScriptBuilder(params, opts)
+	b.test = true
+	b.imports["testing"] = true
+	b.imports["time"] = true
+	b.imports["github.com/go-rod/rod/lib/proto"] = true
+
+	for _, action := range actions {
+		b.writeStep(action)
+	}
+
+	return b.renderTest()
+}
+
+// renderTest assembles the package clause, flags and the test function
+func (b *scriptBuilder) renderTest() string {
+	var out strings.Builder
+
+	pkg := b.opts.Package
+	if pkg == "" {
+		pkg = "workflow_test"
+	}
+
+	out.WriteString("// Code generated by browser-automation-go. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\n", pkg)
+	b.writeImports(&out)
+
+	out.WriteString("var (\n")
+	for _, p := range b.params {
+		fmt.Fprintf(&out, "\t%s = flag.String(%q, %q, %q)\n", p.ident, p.param.Name, p.param.DefaultValue, flagUsage(p.param))
+	}
+	fmt.Fprintf(&out, "\theadless = flag.Bool(\"headless\", %t, \"run the browser without a window\")\n", b.opts.Headless)
+	out.WriteString(")\n\n")
+
+	out.WriteString("// stepTimeout bounds every element lookup and page load\n")
+	out.WriteString("const stepTimeout = 30 * time.Second\n\n")
+
+	out.WriteString("func TestWorkflow(t *testing.T) {\n")
+	out.WriteString("\tu, err := launcher.New().Headless(*headless).Launch()\n")
+	out.WriteString("\tif err != nil {\n\t\tt.Fatalf(\"launch browser: %v\", err)\n\t}\n")
+	out.WriteString("\tbrowser := rod.New().ControlURL(u)\n")
+	out.WriteString("\tif err := browser.Connect(); err != nil {\n\t\tt.Fatalf(\"connect browser: %v\", err)\n\t}\n")
+	out.WriteString("\tdefer browser.Close()\n\n")
+	out.WriteString("\tpage, err := browser.Page(proto.TargetCreateTarget{})\n")
+	out.WriteString("\tif err != nil {\n\t\tt.Fatalf(\"open page: %v\", err)\n\t}\n")
+	out.WriteString(b.body.String())
+	out.WriteString("}\n")
+	b.writePageObjects(&out)
+	b.writeFallbackHelpers(&out)
+	if b.opts.HumanLike {
+		out.WriteString(humanHelpers)
+	}
+
+	return formatSource(out.String())
+}
+
+// writeTestStep emits an action as error checked calls followed by its
+// waits and assertions on the elements it made appear
+func (b *scriptBuilder) writeTestStep(action models.SemanticAction) {
+	step := action.SequenceID
+
+	if b.opts.HumanLike && humanized(action.ActionType) {
+		el := b.lookup(step, action.Target)
+		b.fatalIf(step, b.humanCall(action, el))
+		b.writeWaits(action)
+		b.writeAssertions(action)
+		return
+	}
+
+	switch action.ActionType {
+	case models.ActionNavigate:
+		b.fatalIf(step, fmt.Sprintf("page.Navigate(%s)", b.urlExpr(action.Value)))
+		b.fatalIf(step, "page.Timeout(stepTimeout).WaitLoad()")
+
+	case models.ActionClick:
+		el := b.lookup(step, action.Target)
+		b.fatalIf(step, el+".Click(proto.InputMouseButtonLeft, 1)")
+
+	case models.ActionDblClick:
+		el := b.lookup(step, action.Target)
+		b.fatalIf(step, el+".Click(proto.InputMouseButtonLeft, 2)")
+
+	case models.ActionRightClick:
+		el := b.lookup(step, action.Target)
+		b.fatalIf(step, el+".Click(proto.InputMouseButtonRight, 1)")
+
+	case models.ActionHover:
+		el := b.lookup(step, action.Target)
+		b.fatalIf(step, el+".Hover()")
+
+	case models.ActionInput:
+		el := b.lookup(step, action.Target)
+		b.fatalIf(step, el+".SelectAllText()")
+		b.fatalIf(step, fmt.Sprintf("%s.Input(%s)", el, b.valueExpr(action)))
+
+	case models.ActionSelect:
+		el := b.lookup(step, action.Target)
+		b.fatalIf(step, el+".SelectAllText()")
+
+	case models.ActionScroll:
+		if action.Target.Selector == "" || action.Target.Selector == "window" {
+			b.body.WriteString("\t// Window scrolls are not replayed\n")
+			b.writeWaits(action)
+			return
+		}
+		el := b.lookup(step, action.Target)
+		b.fatalIf(step, el+".ScrollIntoView()")
+
+	case models.ActionKeypress, models.ActionCopy, models.ActionPaste, models.ActionCut:
+		combo := keyCombo(action)
+		call, ok := b.keysCall(combo)
+		if !ok {
+			fmt.Fprintf(&b.body, "\t// Unsupported key: %s\n", combo)
+			b.writeWaits(action)
+			return
+		}
+		b.fatalIf(step, call)
+
+	case models.ActionExtract:
+		if action.Extract == nil {
+			b.body.WriteString("\t// Extract action without an output\n")
+			b.writeWaits(action)
+			return
+		}
+		el := b.lookup(step, action.Target)
+		out := fmt.Sprintf("out%d", step)
+		if action.Extract.Attribute == "" {
+			fmt.Fprintf(&b.body, "\t%s, err := %s.Text()\n", out, el)
+		} else {
+			fmt.Fprintf(&b.body, "\t%sAttr, err := %s.Attribute(%q)\n", out, el, action.Extract.Attribute)
+		}
+		fmt.Fprintf(&b.body, "\tif err != nil {\n\t\tt.Fatalf(\"step %d: %%v\", err)\n\t}\n", step)
+		if action.Extract.Attribute != "" {
+			msg := fmt.Sprintf("step %d: element has no %s attribute", step, action.Extract.Attribute)
+			fmt.Fprintf(&b.body, "\tif %sAttr == nil {\n\t\tt.Fatal(%q)\n\t}\n", out, msg)
+			fmt.Fprintf(&b.body, "\t%s := *%sAttr\n", out, out)
+		}
+		fmt.Fprintf(&b.body, "\tt.Logf(%q, %s)\n", escapeVerbs(action.Extract.Output)+": %s", out)
+
+	case models.ActionAssert:
+		b.writeAssertStep(action)
+
+	default:
+		fmt.Fprintf(&b.body, "\t// Unsupported action type: %s\n", action.ActionType)
+		b.writeWaits(action)
+		return
+	}
+
+	b.writeWaits(action)
+	b.writeAssertions(action)
+}
+
+// writeAssertions checks that the elements which appeared after the action
+// while recording are present again
+func (b *scriptBuilder) writeAssertions(action models.SemanticAction) {
+	for _, target := range action.Context {
+		if target.Text == "" && target.Selector == "" {
+			continue
+		}
+		method, args := elementLookup(target)
+		msg := fmt.Sprintf("step %d: expected %s to appear: %%v", action.SequenceID, escapeVerbs(describeTarget(target)))
+		fmt.Fprintf(&b.body, "\tif _, err := %s; err != nil {\n\t\tt.Errorf(%q, err)\n\t}\n", b.lookupCall("page", method, args), msg)
+	}
+}
+
+// lookup emits the element lookup for a step and returns the variable
+// holding the element once it is visible
+func (b *scriptBuilder) lookup(step int, target models.SemanticTarget) string {
+	el := fmt.Sprintf("el%d", step)
+	var expr string
+	if b.opts.PageObjects {
+		expr = b.pageObjectCall(target)
+	} else {
+		method, args := elementLookup(target)
+		expr = b.lookupCall("page", method, args)
+	}
+	fmt.Fprintf(&b.body, "\t%s, err := %s\n", el, expr)
+	fmt.Fprintf(&b.body, "\tif err != nil {\n\t\tt.Fatalf(\"step %d: %%v\", err)\n\t}\n", step)
+	b.fatalIf(step, el+".WaitVisible()")
+	return el
+}
+
+// fatalIf emits a call returning an error that stops the test on failure
+func (b *scriptBuilder) fatalIf(step int, call string) {
+	fmt.Fprintf(&b.body, "\tif err := %s; err != nil {\n\t\tt.Fatalf(\"step %d: %%v\", err)\n\t}\n", call, step)
+}
+
+// describeTarget returns a short summary of an element for assertion messages
+func describeTarget(target models.SemanticTarget) string {
+	if target.Text != "" {
+		return fmt.Sprintf("%s %q", target.Tag, strings.Join(strings.Fields(target.Text), " "))
+	}
+	return target.Selector
+}
+
+// escapeVerbs escapes text embedded in a generated printf format string
+func escapeVerbs(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
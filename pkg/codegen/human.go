@@ -0,0 +1,102 @@
+package codegen
+
+import (
+	"fmt"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// humanized reports whether an action is replayed through the human-like helpers
+func humanized(t models.ActionType) bool {
+	switch t {
+	case models.ActionClick, models.ActionDblClick, models.ActionRightClick, models.ActionHover, models.ActionInput:
+		return true
+	}
+	return false
+}
+
+// humanCall returns the helper call replaying action on the element expression el
+func (b *scriptBuilder) humanCall(action models.SemanticAction, el string) string {
+	switch action.ActionType {
+	case models.ActionDblClick:
+		return fmt.Sprintf("humanClick(page, %s, proto.InputMouseButtonLeft, 2)", el)
+	case models.ActionRightClick:
+		return fmt.Sprintf("humanClick(page, %s, proto.InputMouseButtonRight, 1)", el)
+	case models.ActionHover:
+		return fmt.Sprintf("humanMove(page, %s)", el)
+	case models.ActionInput:
+		return fmt.Sprintf("humanType(page, %s, %s)", el, b.valueExpr(action))
+	}
+	return fmt.Sprintf("humanClick(page, %s, proto.InputMouseButtonLeft, 1)", el)
+}
+
+// humanHelpers is appended to generated programs in human-like mode
+const humanHelpers = `
+// humanPause waits a short random interval, like a person between actions
+func humanPause() {
+	time.Sleep(time.Duration(150+rand.Intn(450)) * time.Millisecond)
+}
+
+// humanMove glides the mouse along a curved path to a random point inside el
+func humanMove(page *rod.Page, el *rod.Element) error {
+	if err := el.ScrollIntoView(); err != nil {
+		return err
+	}
+	shape, err := el.Shape()
+	if err != nil {
+		return err
+	}
+	box := shape.Box()
+	if box == nil {
+		return el.Hover()
+	}
+
+	from := page.Mouse.Position()
+	to := proto.Point{
+		X: box.X + box.Width*(0.25+0.5*rand.Float64()),
+		Y: box.Y + box.Height*(0.25+0.5*rand.Float64()),
+	}
+	ctrl := proto.Point{
+		X: (from.X+to.X)/2 + (rand.Float64()-0.5)*200,
+		Y: (from.Y+to.Y)/2 + (rand.Float64()-0.5)*200,
+	}
+
+	steps := 15 + rand.Intn(20)
+	i := 0
+	return page.Mouse.MoveAlong(func() (proto.Point, bool) {
+		i++
+		time.Sleep(time.Duration(5+rand.Intn(15)) * time.Millisecond)
+		t := float64(i) / float64(steps)
+		return proto.Point{
+			X: (1-t)*(1-t)*from.X + 2*(1-t)*t*ctrl.X + t*t*to.X,
+			Y: (1-t)*(1-t)*from.Y + 2*(1-t)*t*ctrl.Y + t*t*to.Y,
+		}, i >= steps
+	})
+}
+
+// humanClick moves to el and clicks it after a brief hesitation
+func humanClick(page *rod.Page, el *rod.Element, button proto.InputMouseButton, clickCount int) error {
+	if err := humanMove(page, el); err != nil {
+		return err
+	}
+	time.Sleep(time.Duration(50+rand.Intn(150)) * time.Millisecond)
+	return page.Mouse.Click(button, clickCount)
+}
+
+// humanType focuses el and replaces its content one character at a time
+func humanType(page *rod.Page, el *rod.Element, text string) error {
+	if err := humanClick(page, el, proto.InputMouseButtonLeft, 1); err != nil {
+		return err
+	}
+	if err := el.SelectAllText(); err != nil {
+		return err
+	}
+	for _, r := range text {
+		if err := page.InsertText(string(r)); err != nil {
+			return err
+		}
+		time.Sleep(time.Duration(40+rand.Intn(160)) * time.Millisecond)
+	}
+	return nil
+}
+`
@@ -0,0 +1,205 @@
+package codegen
+
+import (
+	"fmt"
+	"go/token"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// pageObject is a generated struct grouping the elements used on one page
+type pageObject struct {
+	name     string
+	url      string
+	elements []pageElement
+}
+
+// pageElement is a generated accessor method returning one element
+type pageElement struct {
+	name   string
+	method string // page lookup method: Element, ElementR or fallbackLookup
+	args   string
+}
+
+// enterPage makes the page object for rawURL current, creating it on first visit.
+// Pages are keyed by host and path so query strings do not split them.
+func (b *scriptBuilder) enterPage(rawURL string) {
+	key := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		key = u.Host + u.Path
+	}
+	for _, po := range b.pages {
+		if po.url == key {
+			b.currentPage = po
+			return
+		}
+	}
+
+	name := pageName(rawURL)
+	for n := 2; b.hasPage(name); n++ {
+		name = fmt.Sprintf("%s%d", pageName(rawURL), n)
+	}
+	b.currentPage = &pageObject{name: name, url: key}
+	b.pages = append(b.pages, b.currentPage)
+}
+
+func (b *scriptBuilder) hasPage(name string) bool {
+	for _, po := range b.pages {
+		if po.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// pageObjectCall registers target on the current page object and returns the
+// expression calling its accessor
+func (b *scriptBuilder) pageObjectCall(target models.SemanticTarget) string {
+	if b.currentPage == nil {
+		b.currentPage = &pageObject{name: "StartPage"}
+		b.pages = append(b.pages, b.currentPage)
+	}
+	po := b.currentPage
+	method, args := elementLookup(target)
+	b.useLookup(method)
+
+	var elem *pageElement
+	for i := range po.elements {
+		if po.elements[i].method == method && po.elements[i].args == args {
+			elem = &po.elements[i]
+			break
+		}
+	}
+	if elem == nil {
+		name := elementName(target)
+		base := name
+		for n := 2; po.hasElement(name); n++ {
+			name = fmt.Sprintf("%s%d", base, n)
+		}
+		po.elements = append(po.elements, pageElement{name: name, method: method, args: args})
+		elem = &po.elements[len(po.elements)-1]
+	}
+
+	return fmt.Sprintf("%s{page}.%s()", po.name, elem.name)
+}
+
+func (po *pageObject) hasElement(name string) bool {
+	for _, e := range po.elements {
+		if e.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// writePageObjects writes the page object types and their accessors. Test
+// mode accessors return errors and honour stepTimeout.
+func (b *scriptBuilder) writePageObjects(out *strings.Builder) {
+	for _, po := range b.pages {
+		if len(po.elements) == 0 {
+			continue
+		}
+
+		if po.url != "" {
+			fmt.Fprintf(out, "\n// %s holds the elements used on %s\n", po.name, po.url)
+		} else {
+			fmt.Fprintf(out, "\n// %s holds the elements used before the first navigation\n", po.name)
+		}
+		fmt.Fprintf(out, "type %s struct {\n\t*rod.Page\n}\n", po.name)
+
+		for _, e := range po.elements {
+			recv := "p"
+			if e.method == fallbackLookup {
+				recv = "p.Page"
+			}
+			if b.test {
+				fmt.Fprintf(out, "\nfunc (p %s) %s() (*rod.Element, error) {\n\treturn %s\n}\n", po.name, e.name, b.lookupCall(recv, e.method, e.args))
+			} else {
+				fmt.Fprintf(out, "\nfunc (p %s) %s() *rod.Element {\n\treturn %s\n}\n", po.name, e.name, b.lookupCall(recv, e.method, e.args))
+			}
+		}
+	}
+}
+
+// pageName derives a page object type name from the last meaningful path
+// segment of a URL, falling back to the site name
+func pageName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "Page"
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		seg := segments[i]
+		if seg != "" && len(seg) <= 30 && !strings.ContainsFunc(seg, unicode.IsDigit) {
+			return exportedIdent(seg) + "Page"
+		}
+	}
+
+	host := strings.TrimPrefix(u.Hostname(), "www.")
+	if host == "" {
+		return "Page"
+	}
+	return exportedIdent(strings.Split(host, ".")[0]) + "Page"
+}
+
+// elementName derives an accessor name from the most descriptive attribute of
+// a target, suffixed with its role (UsernameInput, SubmitButton)
+func elementName(target models.SemanticTarget) string {
+	label := ""
+	for _, attr := range []string{"aria-label", "name", "placeholder", "id"} {
+		if v, ok := target.Attributes[attr].(string); ok && v != "" {
+			label = v
+			break
+		}
+	}
+	if label == "" && strings.HasPrefix(target.Selector, "#") && !strings.ContainsAny(target.Selector, " .[>:") {
+		label = target.Selector[1:]
+	}
+	if label == "" && target.Text != "" {
+		words := strings.Fields(target.Text)
+		if len(words) > 4 {
+			words = words[:4]
+		}
+		label = strings.Join(words, " ")
+	}
+
+	suffix := ""
+	switch strings.ToLower(target.Tag) {
+	case "input", "textarea":
+		suffix = "Input"
+	case "select":
+		suffix = "Select"
+	case "button":
+		suffix = "Button"
+	case "a":
+		suffix = "Link"
+	}
+	if label == "" {
+		label = target.Tag
+		if suffix != "" || label == "" {
+			label = "element"
+		}
+	}
+
+	name := exportedIdent(label)
+	if !strings.HasSuffix(name, suffix) {
+		name += suffix
+	}
+	return name
+}
+
+// exportedIdent converts a name into an exported Go identifier
+func exportedIdent(name string) string {
+	ident := goIdent(name)
+	if trimmed := strings.TrimSuffix(ident, "Value"); token.Lookup(trimmed).IsKeyword() {
+		ident = trimmed
+	}
+	runes := []rune(ident)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
@@ -0,0 +1,531 @@
+package codegen
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// ScriptOptions controls how GenerateGoRodScript renders a workflow
+type ScriptOptions struct {
+	// Headless is the default value of the generated -headless flag
+	Headless bool
+	// Package is the package clause of generated tests, defaults to "workflow_test"
+	Package string
+	// PageObjects groups element lookups into a struct per visited page
+	// instead of inlining selectors in every step
+	PageObjects bool
+	// HumanLike replaces instant clicks and input with curved mouse movement,
+	// per-character typing with jitter and short random pauses between steps
+	HumanLike bool
+	// Delay inserts waits between steps, using the same strategy as the
+	// workflow executor
+	Delay models.DelayConfig
+}
+
+// scriptParam is a workflow parameter bound to a generated flag variable
+type scriptParam struct {
+	param models.WorkflowParameter
+	ident string // Go identifier holding the flag pointer
+}
+
+// scriptBuilder accumulates the generated program
+type scriptBuilder struct {
+	opts     ScriptOptions
+	test     bool // render a go test with error checks instead of Must* calls
+	params   []scriptParam
+	bySource map[int]*scriptParam
+	imports  map[string]bool
+	body     strings.Builder
+
+	pages       []*pageObject
+	currentPage *pageObject
+	prev        *models.SemanticAction // last written action, for delays
+	fallback    bool                   // some element is found through a selector chain
+}
+
+// GenerateGoRodScript renders actions as a standalone Go Rod program.
+// Every variable token becomes a flag.String defaulting to the recorded value,
+// so the exported script can be re-run with different inputs without editing.
+func GenerateGoRodScript(actions []models.SemanticAction, params []models.WorkflowParameter, opts ScriptOptions) string {
+	b := newScriptBuilder(params, opts)
+
+	for _, action := range actions {
+		b.writeStep(action)
+	}
+
+	return b.render()
+}
+
+func newScriptBuilder(params []models.WorkflowParameter, opts ScriptOptions) *scriptBuilder {
+	b := &scriptBuilder{
+		opts:     opts,
+		bySource: make(map[int]*scriptParam),
+		imports: map[string]bool{
+			"flag":                               true,
+			"github.com/go-rod/rod":              true,
+			"github.com/go-rod/rod/lib/launcher": true,
+		},
+	}
+
+	// Identifiers of the generated program and its imported packages
+	used := map[string]bool{
+		"browser": true, "page": true, "headless": true, "u": true, "err": true, "t": true,
+		"stepTimeout": true, "flag": true, "rod": true, "launcher": true, "proto": true,
+		"input": true, "strings": true, "testing": true, "time": true, "rand": true,
+		"humanPause": true, "humanMove": true, "humanClick": true, "humanType": true,
+		"fmt": true, "findElement": true, "mustFindElement": true, "candidateTimeout": true,
+		"regexp": true, "got": true, "has": true, "main": true,
+	}
+	for _, p := range params {
+		if p.TokenType != models.TokenVariable || p.Name == "" {
+			continue
+		}
+		ident := goIdent(p.Name)
+		for used[ident] {
+			ident += "_"
+		}
+		used[ident] = true
+		b.params = append(b.params, scriptParam{param: p, ident: ident})
+	}
+	if opts.HumanLike {
+		b.imports["math/rand"] = true
+		b.imports["time"] = true
+		b.imports["github.com/go-rod/rod/lib/proto"] = true
+	}
+
+	for i := range b.params {
+		if src := b.params[i].param.SourceAction; src > 0 {
+			if _, exists := b.bySource[src]; !exists {
+				b.bySource[src] = &b.params[i]
+			}
+		}
+	}
+
+	return b
+}
+
+// render assembles the package clause, imports, flags and main function.
+// The flags are package variables, as in generated tests, so a parameter
+// no step uses doesn't leave an unused variable that fails the build.
+func (b *scriptBuilder) render() string {
+	var out strings.Builder
+
+	out.WriteString("// Code generated by browser-automation-go. DO NOT EDIT.\n\n")
+	out.WriteString("package main\n\n")
+	b.writeImports(&out)
+
+	out.WriteString("var (\n")
+	for _, p := range b.params {
+		fmt.Fprintf(&out, "\t%s = flag.String(%q, %q, %q)\n", p.ident, p.param.Name, p.param.DefaultValue, flagUsage(p.param))
+	}
+	fmt.Fprintf(&out, "\theadless = flag.Bool(\"headless\", %t, \"run the browser without a window\")\n", b.opts.Headless)
+	out.WriteString(")\n\n")
+
+	out.WriteString("func main() {\n")
+	out.WriteString("\tflag.Parse()\n\n")
+
+	out.WriteString("\tu := launcher.New().Headless(*headless).MustLaunch()\n")
+	out.WriteString("\tbrowser := rod.New().ControlURL(u).MustConnect()\n")
+	out.WriteString("\tdefer browser.MustClose()\n\n")
+	out.WriteString("\tpage := browser.MustPage()\n")
+	out.WriteString(b.body.String())
+	out.WriteString("}\n")
+	b.writePageObjects(&out)
+	b.writeFallbackHelpers(&out)
+	if b.opts.HumanLike {
+		out.WriteString(humanHelpers)
+	}
+
+	return formatSource(out.String())
+}
+
+// formatSource runs gofmt over generated code, returning it unchanged if it
+// does not parse
+func formatSource(code string) string {
+	formatted, err := format.Source([]byte(code))
+	if err != nil {
+		return code
+	}
+	return string(formatted)
+}
+
+// writeImports writes the import block
+func (b *scriptBuilder) writeImports(out *strings.Builder) {
+	out.WriteString("import (\n")
+	for i, group := range b.importGroups() {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		for _, imp := range group {
+			fmt.Fprintf(out, "\t%q\n", imp)
+		}
+	}
+	out.WriteString(")\n\n")
+}
+
+// importGroups returns the standard library and third-party imports as
+// separate sorted groups, mirroring goimports
+func (b *scriptBuilder) importGroups() [][]string {
+	var std, ext []string
+	for imp := range b.imports {
+		if strings.Contains(strings.SplitN(imp, "/", 2)[0], ".") {
+			ext = append(ext, imp)
+		} else {
+			std = append(std, imp)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(ext)
+	return [][]string{std, ext}
+}
+
+// writeStep emits the code for a single action
+func (b *scriptBuilder) writeStep(action models.SemanticAction) {
+	fmt.Fprintf(&b.body, "\n\t// Step %d: %s\n", action.SequenceID, DescribeAction(action))
+	fmt.Fprintf(&b.body, "\t// %s\n", Provenance(action))
+
+	if b.prev != nil {
+		if delay := b.opts.Delay.Between(*b.prev, action); delay > 0 {
+			b.imports["time"] = true
+			fmt.Fprintf(&b.body, "\ttime.Sleep(%d * time.Millisecond)\n", delay.Milliseconds())
+		}
+	}
+	b.prev = &action
+
+	if action.ActionType == models.ActionNavigate {
+		b.enterPage(action.Value)
+	}
+
+	b.armWaits(action)
+	if b.test {
+		b.writeTestStep(action)
+	} else {
+		b.writeMustStep(action)
+		b.writeWaits(action)
+	}
+
+	if b.opts.HumanLike {
+		b.body.WriteString("\thumanPause()\n")
+	}
+}
+
+// writeMustStep emits an action as Must* calls that panic on failure
+func (b *scriptBuilder) writeMustStep(action models.SemanticAction) {
+	if b.opts.HumanLike && humanized(action.ActionType) {
+		call := b.humanCall(action, b.elementExpr(action.Target)+".MustWaitVisible()")
+		fmt.Fprintf(&b.body, "\tif err := %s; err != nil {\n\t\tpanic(err)\n\t}\n", call)
+		return
+	}
+
+	switch action.ActionType {
+	case models.ActionNavigate:
+		fmt.Fprintf(&b.body, "\tpage.MustNavigate(%s).MustWaitLoad()\n", b.urlExpr(action.Value))
+
+	case models.ActionClick:
+		fmt.Fprintf(&b.body, "\t%s.MustWaitVisible().MustClick()\n", b.elementExpr(action.Target))
+
+	case models.ActionDblClick:
+		fmt.Fprintf(&b.body, "\t%s.MustWaitVisible().MustDoubleClick()\n", b.elementExpr(action.Target))
+
+	case models.ActionRightClick:
+		b.imports["github.com/go-rod/rod/lib/proto"] = true
+		fmt.Fprintf(&b.body, "\tif err := %s.MustWaitVisible().Click(proto.InputMouseButtonRight, 1); err != nil {\n\t\tpanic(err)\n\t}\n", b.elementExpr(action.Target))
+
+	case models.ActionHover:
+		fmt.Fprintf(&b.body, "\t%s.MustWaitVisible().MustHover()\n", b.elementExpr(action.Target))
+
+	case models.ActionInput:
+		fmt.Fprintf(&b.body, "\t%s.MustWaitVisible().MustSelectAllText().MustInput(%s)\n", b.elementExpr(action.Target), b.valueExpr(action))
+
+	case models.ActionSelect:
+		fmt.Fprintf(&b.body, "\t%s.MustWaitVisible().MustSelectAllText()\n", b.elementExpr(action.Target))
+
+	case models.ActionScroll:
+		if action.Target.Selector == "" || action.Target.Selector == "window" {
+			b.body.WriteString("\t// Window scrolls are not replayed\n")
+			return
+		}
+		fmt.Fprintf(&b.body, "\t%s.MustScrollIntoView()\n", b.elementExpr(action.Target))
+
+	case models.ActionKeypress, models.ActionCopy, models.ActionPaste, models.ActionCut:
+		combo := keyCombo(action)
+		if call, ok := b.keysCall(combo); ok {
+			fmt.Fprintf(&b.body, "\t%s\n", call)
+		} else {
+			fmt.Fprintf(&b.body, "\t// Unsupported key: %s\n", combo)
+		}
+
+	case models.ActionExtract:
+		if action.Extract == nil {
+			b.body.WriteString("\t// Extract action without an output\n")
+			return
+		}
+		b.imports["fmt"] = true
+		read := ".MustText()"
+		if action.Extract.Attribute != "" {
+			read = fmt.Sprintf(".MustAttribute(%q)", action.Extract.Attribute)
+			fmt.Fprintf(&b.body, "\tfmt.Println(%q, *%s.MustWaitVisible()%s)\n", action.Extract.Output+":", b.elementExpr(action.Target), read)
+			return
+		}
+		fmt.Fprintf(&b.body, "\tfmt.Println(%q, %s.MustWaitVisible()%s)\n", action.Extract.Output+":", b.elementExpr(action.Target), read)
+
+	case models.ActionAssert:
+		b.writeAssertStep(action)
+
+	default:
+		fmt.Fprintf(&b.body, "\t// Unsupported action type: %s\n", action.ActionType)
+	}
+}
+
+// keyCombo returns the key combination an action replays
+func keyCombo(action models.SemanticAction) string {
+	switch action.ActionType {
+	case models.ActionCopy:
+		return "Ctrl+C"
+	case models.ActionPaste:
+		return "Ctrl+V"
+	case models.ActionCut:
+		return "Ctrl+X"
+	}
+	return action.Value
+}
+
+// keysCall returns the call that presses a key or a modifier combination such
+// as "Ctrl+Shift+K". Test mode uses the error returning variants.
+func (b *scriptBuilder) keysCall(combo string) (string, bool) {
+	parts := strings.Split(combo, "+")
+	key, ok := keyIdent(parts[len(parts)-1])
+	if !ok {
+		return "", false
+	}
+	b.imports["github.com/go-rod/rod/lib/input"] = true
+
+	must := "Must"
+	if b.test {
+		must = ""
+	}
+
+	if len(parts) == 1 {
+		return fmt.Sprintf("page.Keyboard.%sType(%s)", must, key), true
+	}
+
+	var sb strings.Builder
+	sb.WriteString("page.KeyActions()")
+	for _, mod := range parts[:len(parts)-1] {
+		if m, ok := keyIdent(mod); ok {
+			fmt.Fprintf(&sb, ".Press(%s)", m)
+		}
+	}
+	fmt.Fprintf(&sb, ".Type(%s).%sDo()", key, must)
+	return sb.String(), true
+}
+
+// valueExpr returns the Go expression for an input value, using the flag
+// variable when the action is the source of a variable token
+func (b *scriptBuilder) valueExpr(action models.SemanticAction) string {
+	if p, ok := b.bySource[action.SequenceID]; ok {
+		return "*" + p.ident
+	}
+	for _, p := range b.params {
+		if p.param.DefaultValue != "" && p.param.DefaultValue == action.Value {
+			return "*" + p.ident
+		}
+	}
+	return fmt.Sprintf("%q", action.Value)
+}
+
+// urlExpr returns the Go expression for a navigation URL. Recorded variable
+// values embedded in the URL are replaced with the flag value at runtime.
+func (b *scriptBuilder) urlExpr(url string) string {
+	expr := fmt.Sprintf("%q", url)
+	for _, p := range b.params {
+		def := p.param.DefaultValue
+		if len(def) < 3 || !strings.Contains(url, def) {
+			continue
+		}
+		b.imports["strings"] = true
+		expr = fmt.Sprintf("strings.ReplaceAll(%s, %q, *%s)", expr, def, p.ident)
+	}
+	return expr
+}
+
+// elementExpr returns the Must* lookup for an action target
+func (b *scriptBuilder) elementExpr(target models.SemanticTarget) string {
+	if b.opts.PageObjects {
+		return b.pageObjectCall(target)
+	}
+	method, args := elementLookup(target)
+	return b.lookupCall("page", method, args)
+}
+
+// elementLookup returns the page method and arguments that find a target,
+// matching by text when there is no usable selector. Targets with fallback
+// candidates are found through the findElement selector chain.
+func elementLookup(target models.SemanticTarget) (method, args string) {
+	if selectors := fallbackSelectors(target); len(selectors) > 1 {
+		quoted := make([]string, len(selectors))
+		for i, selector := range selectors {
+			quoted[i] = fmt.Sprintf("%q", selector)
+		}
+		return fallbackLookup, strings.Join(quoted, ", ")
+	}
+	if (target.Selector == "" || target.Selector == "window") && target.Text != "" {
+		tag := target.Tag
+		if tag == "" {
+			tag = "*"
+		}
+		return "ElementR", fmt.Sprintf("%q, %q", tag, regexpQuote(target.Text))
+	}
+	return "Element", fmt.Sprintf("%q", target.Selector)
+}
+
+// DescribeAction returns a short human readable summary of an action, as in
+// step comments
+func DescribeAction(action models.SemanticAction) string {
+	switch action.ActionType {
+	case models.ActionNavigate:
+		return "navigate to " + action.Value
+	case models.ActionKeypress:
+		return "press " + action.Value
+	case models.ActionExtract:
+		if action.Extract != nil {
+			return fmt.Sprintf("extract %s into %s", shortText(action.Target.Selector), action.Extract.Output)
+		}
+	case models.ActionAssert:
+		if action.Assert != nil && action.Assert.Targeted() {
+			return fmt.Sprintf("assert %s %s", shortText(describeTarget(action.Target)), action.Assert.Type)
+		} else if action.Assert != nil {
+			return fmt.Sprintf("assert %s %s", action.Assert.Type, shortText(action.Assert.Value))
+		}
+	}
+
+	desc := action.Target.Text
+	if desc == "" {
+		desc = action.Target.Selector
+	}
+	return fmt.Sprintf("%s %s", action.ActionType, shortText(desc))
+}
+
+// Provenance returns a one line note tracing an action back to the recording:
+// when it happened, the rrweb node it targeted and the text or aria-label
+// the user saw
+func Provenance(action models.SemanticAction) string {
+	parts := []string{"Recorded at " + recordedAt(action.Timestamp)}
+	if action.ActionType != models.ActionNavigate {
+		if action.Target.NodeID > 0 {
+			parts = append(parts, fmt.Sprintf("node %d", action.Target.NodeID))
+		}
+		if label, ok := action.Target.Attributes["aria-label"].(string); ok && label != "" {
+			parts = append(parts, fmt.Sprintf("aria-label %q", shortText(label)))
+		}
+		if action.Target.Text != "" {
+			parts = append(parts, fmt.Sprintf("text %q", shortText(action.Target.Text)))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// recordedAt formats an event timestamp, which is either milliseconds since
+// the recording started or a Unix time in milliseconds
+func recordedAt(ms int64) string {
+	if ms > 1e12 {
+		return time.UnixMilli(ms).UTC().Format("2006-01-02 15:04:05.000 MST")
+	}
+	return fmt.Sprintf("%d.%03ds", ms/1000, ms%1000)
+}
+
+// shortText collapses whitespace and truncates text for comments
+func shortText(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > 60 {
+		s = strings.ToValidUTF8(s[:60], "") + "..."
+	}
+	return s
+}
+
+// flagUsage builds the usage string of a parameter flag
+func flagUsage(p models.WorkflowParameter) string {
+	if p.Description != "" {
+		return p.Description
+	}
+	return fmt.Sprintf("%s value (%s)", p.Name, p.Type)
+}
+
+// goIdent converts a parameter name into a valid, unexported Go identifier
+func goIdent(name string) string {
+	var sb strings.Builder
+	upperNext := false
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upperNext = sb.Len() > 0
+			continue
+		}
+		if upperNext {
+			r = unicode.ToUpper(r)
+			upperNext = false
+		}
+		sb.WriteRune(r)
+	}
+
+	ident := sb.String()
+	if ident == "" {
+		return "param"
+	}
+	if unicode.IsDigit(rune(ident[0])) {
+		ident = "p" + ident
+	}
+	runes := []rune(ident)
+	runes[0] = unicode.ToLower(runes[0])
+	ident = string(runes)
+	if token.Lookup(ident).IsKeyword() {
+		ident += "Value"
+	}
+	return ident
+}
+
+// regexpQuote escapes text for use in a JavaScript regex passed to MustElementR
+func regexpQuote(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	var sb strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`\.+*?()|[]{}^$`, r) {
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// keyIdent maps a recorded key name to the rod input.Key identifier
+func keyIdent(name string) (string, bool) {
+	named := map[string]string{
+		"enter": "Enter", "tab": "Tab", "escape": "Escape", "esc": "Escape",
+		"backspace": "Backspace", "delete": "Delete", "space": "Space", " ": "Space",
+		"arrowup": "ArrowUp", "arrowdown": "ArrowDown", "arrowleft": "ArrowLeft", "arrowright": "ArrowRight",
+		"home": "Home", "end": "End", "pageup": "PageUp", "pagedown": "PageDown",
+		"ctrl": "ControlLeft", "control": "ControlLeft", "shift": "ShiftLeft", "alt": "AltLeft",
+		"cmd": "MetaLeft", "meta": "MetaLeft",
+	}
+	if id, ok := named[strings.ToLower(name)]; ok {
+		return "input." + id, true
+	}
+
+	if len(name) == 1 {
+		r := rune(name[0])
+		switch {
+		case unicode.IsLetter(r):
+			return "input.Key" + strings.ToUpper(name), true
+		case unicode.IsDigit(r):
+			return "input.Digit" + name, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,362 @@
+package codegen
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+func sampleWorkflow() ([]models.SemanticAction, []models.WorkflowParameter) {
+	actions := []models.SemanticAction{
+		{SequenceID: 1, ActionType: models.ActionNavigate, Value: "https://example.com/search?q=cats"},
+		{SequenceID: 2, ActionType: models.ActionInput, Value: "cats", Target: models.SemanticTarget{Tag: "input", Selector: "input[name='q']"}},
+		{SequenceID: 3, ActionType: models.ActionKeypress, Value: "Enter"},
+		{SequenceID: 4, ActionType: models.ActionClick, Target: models.SemanticTarget{Tag: "a", Text: "Cats (1.5)"}},
+	}
+	params := []models.WorkflowParameter{
+		{Name: "search query", Type: models.ParamTypeString, DefaultValue: "cats", TokenType: models.TokenVariable, SourceAction: 2},
+	}
+	return actions, params
+}
+
+func TestGenerateGoRodScript(t *testing.T) {
+	actions, params := sampleWorkflow()
+	code := GenerateGoRodScript(actions, params, ScriptOptions{})
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "main.go", code, parser.AllErrors); err != nil {
+		t.Fatalf("generated script does not parse: %v\n%s", err, code)
+	}
+
+	wants := []string{
+		`searchQuery = flag.String("search query", "cats",`,
+		`MustInput(*searchQuery)`,
+		`strings.ReplaceAll("https://example.com/search?q=cats", "cats", *searchQuery)`,
+		`page.Keyboard.MustType(input.Enter)`,
+		`page.MustElementR("a", "Cats \\(1\\.5\\)")`,
+	}
+	for _, want := range wants {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated script missing %q\n%s", want, code)
+		}
+	}
+}
+
+func TestGoIdent(t *testing.T) {
+	tests := map[string]string{
+		"searchQuery": "searchQuery",
+		"first name":  "firstName",
+		"2fa-code":    "p2faCode",
+		"type":        "typeValue",
+		"!!!":         "param",
+	}
+	for in, want := range tests {
+		if got := goIdent(in); got != want {
+			t.Errorf("goIdent(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGenerateGoRodTest(t *testing.T) {
+	actions, params := sampleWorkflow()
+	actions[2].Context = []models.SemanticTarget{{Tag: "h3", Text: "Cat - 100% cute"}}
+	code := GenerateGoRodTest(actions, params, ScriptOptions{Headless: true})
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "workflow_test.go", code, parser.AllErrors); err != nil {
+		t.Fatalf("generated test does not parse: %v\n%s", err, code)
+	}
+
+	wants := []string{
+		`package workflow_test`,
+		`func TestWorkflow(t *testing.T) {`,
+		`searchQuery = flag.String("search query", "cats",`,
+		`if err := el2.Input(*searchQuery); err != nil {`,
+		`if err := page.Keyboard.Type(input.Enter); err != nil {`,
+		`page.Timeout(stepTimeout).ElementR("h3", "Cat - 100% cute")`,
+		`t.Errorf("step 3: expected h3 \"Cat - 100%% cute\" to appear: %v", err)`,
+	}
+	for _, want := range wants {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated test missing %q\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "Must") {
+		t.Errorf("generated test should not use Must* helpers\n%s", code)
+	}
+}
+
+func TestGeneratePageObjects(t *testing.T) {
+	actions, params := sampleWorkflow()
+	actions[1].Target.Attributes = map[string]interface{}{"name": "q"}
+	code := GenerateGoRodScript(actions, params, ScriptOptions{PageObjects: true})
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "main.go", code, parser.AllErrors); err != nil {
+		t.Fatalf("generated script does not parse: %v\n%s", err, code)
+	}
+
+	wants := []string{
+		"type SearchPage struct {",
+		"func (p SearchPage) QInput() *rod.Element {",
+		`return p.MustElement("input[name='q']")`,
+		"SearchPage{page}.QInput().MustWaitVisible()",
+		"SearchPage{page}.Cats15Link().MustWaitVisible().MustClick()",
+	}
+	for _, want := range wants {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated script missing %q\n%s", want, code)
+		}
+	}
+}
+
+func TestGenerateHumanLike(t *testing.T) {
+	actions, params := sampleWorkflow()
+	code := GenerateGoRodScript(actions, params, ScriptOptions{HumanLike: true})
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "main.go", code, parser.AllErrors); err != nil {
+		t.Fatalf("generated script does not parse: %v\n%s", err, code)
+	}
+
+	wants := []string{
+		`humanType(page, page.MustElement("input[name='q']").MustWaitVisible(), *searchQuery)`,
+		`humanClick(page, page.MustElementR("a", "Cats \\(1\\.5\\)").MustWaitVisible(), proto.InputMouseButtonLeft, 1)`,
+		"func humanMove(page *rod.Page, el *rod.Element) error {",
+		`"math/rand"`,
+	}
+	for _, want := range wants {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated script missing %q\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "MustInput(") || strings.Contains(code, "MustClick()") {
+		t.Errorf("human-like script should not use instant input or clicks\n%s", code)
+	}
+}
+
+func TestGenerateDelays(t *testing.T) {
+	actions, params := sampleWorkflow()
+	for i := range actions {
+		actions[i].Timestamp = int64(i) * 3000
+	}
+	actions[2].Timestamp = actions[1].Timestamp + 800
+
+	code := GenerateGoRodScript(actions, params, ScriptOptions{
+		Delay: models.DelayConfig{Strategy: models.DelayCappedRecorded},
+	})
+
+	for _, want := range []string{"time.Sleep(2000 * time.Millisecond)", "time.Sleep(800 * time.Millisecond)", `"time"`} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated script missing %q\n%s", want, code)
+		}
+	}
+
+	code = GenerateGoRodScript(actions, params, ScriptOptions{})
+	if strings.Contains(code, "time.Sleep") {
+		t.Errorf("script without delay strategy should not sleep\n%s", code)
+	}
+}
+
+func TestGenerateFallbackChain(t *testing.T) {
+	actions, params := sampleWorkflow()
+	actions[1].Target.Candidates = []string{"input[name='q']", "#search", ".search-box"}
+
+	code := GenerateGoRodScript(actions, params, ScriptOptions{})
+	for _, want := range []string{
+		`mustFindElement(page, "input[name='q']", "#search", ".search-box")`,
+		"func findElement(page *rod.Page, selectors ...string)",
+		`"fmt"`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated script missing %q\n%s", want, code)
+		}
+	}
+
+	code = GenerateGoRodTest(actions, params, ScriptOptions{})
+	if !strings.Contains(code, `el2, err := findElement(page, "input[name='q']", "#search", ".search-box")`) {
+		t.Errorf("generated test missing fallback lookup\n%s", code)
+	}
+	if strings.Contains(code, "mustFindElement") {
+		t.Errorf("generated test should not use Must helpers\n%s", code)
+	}
+
+	actions[1].Target.Candidates = nil
+	code = GenerateGoRodScript(actions, params, ScriptOptions{})
+	if strings.Contains(code, "findElement") {
+		t.Errorf("single selector should not use the fallback chain\n%s", code)
+	}
+}
+
+func TestGenerateWaits(t *testing.T) {
+	actions, params := sampleWorkflow()
+	actions[2].Waits = []models.WaitCondition{
+		{Type: models.WaitNavigation},
+		{Type: models.WaitVisible, Selector: "#results", Timeout: 5000},
+		{Type: models.WaitURL, Pattern: `q=\w+`},
+	}
+	actions[3].Waits = []models.WaitCondition{
+		{Type: models.WaitRequest, Method: "GET", Pattern: `/complete/search`, Status: 200},
+	}
+
+	code := GenerateGoRodScript(actions, params, ScriptOptions{})
+	if _, err := parser.ParseFile(token.NewFileSet(), "main.go", code, parser.AllErrors); err != nil {
+		t.Fatalf("generated script does not parse: %v\n%s", err, code)
+	}
+	for _, want := range []string{
+		"wait3_1 := page.Timeout(30 * time.Second).WaitNavigation(proto.PageLifecycleEventNameLoad)\n\tpage.Keyboard.MustType(input.Enter)\n\twait3_1()",
+		"page.Timeout(5*time.Second).MustWait(`(selector, visible) =>",
+		`, "#results", true)`,
+		`, "q=\\w+")`,
+		`if strings.EqualFold(e.Request.Method, "GET") && regexp.MustCompile("/complete/search").MatchString(e.Request.URL) {`,
+		"wait4_1()\n\tif wait4_1Status != 200 {",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated script missing %q\n%s", want, code)
+		}
+	}
+
+	code = GenerateGoRodTest(actions, params, ScriptOptions{})
+	if _, err := parser.ParseFile(token.NewFileSet(), "workflow_test.go", code, parser.AllErrors); err != nil {
+		t.Fatalf("generated test does not parse: %v\n%s", err, code)
+	}
+	for _, want := range []string{
+		"if err := wait3_1Page.GetContext().Err(); err != nil {",
+		`t.Fatalf("step 3: wait for #results visible: %v", err)`,
+		`t.Fatalf("step 4: wait for request GET /complete/search with status 200: got status %d", wait4_1Status)`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated test missing %q\n%s", want, code)
+		}
+	}
+}
+
+func TestGenerateExtract(t *testing.T) {
+	actions, params := sampleWorkflow()
+	actions = append(actions,
+		models.SemanticAction{SequenceID: 5, ActionType: models.ActionExtract, Target: models.SemanticTarget{Selector: "#result-stats"},
+			Extract: &models.Extraction{Output: "stats"}},
+		models.SemanticAction{SequenceID: 6, ActionType: models.ActionExtract, Target: models.SemanticTarget{Selector: "h3 a"},
+			Extract: &models.Extraction{Output: "first_link", Attribute: "href"}},
+	)
+
+	code := GenerateGoRodScript(actions, params, ScriptOptions{})
+	if _, err := parser.ParseFile(token.NewFileSet(), "main.go", code, parser.AllErrors); err != nil {
+		t.Fatalf("generated script does not parse: %v\n%s", err, code)
+	}
+	for _, want := range []string{
+		`fmt.Println("stats:", page.MustElement("#result-stats").MustWaitVisible().MustText())`,
+		`fmt.Println("first_link:", *page.MustElement("h3 a").MustWaitVisible().MustAttribute("href"))`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated script missing %q\n%s", want, code)
+		}
+	}
+
+	code = GenerateGoRodTest(actions, params, ScriptOptions{})
+	if _, err := parser.ParseFile(token.NewFileSet(), "workflow_test.go", code, parser.AllErrors); err != nil {
+		t.Fatalf("generated test does not parse: %v\n%s", err, code)
+	}
+	for _, want := range []string{
+		"out5, err := el5.Text()",
+		`t.Logf("stats: %s", out5)`,
+		`out6Attr, err := el6.Attribute("href")`,
+		"out6 := *out6Attr",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated test missing %q\n%s", want, code)
+		}
+	}
+}
+
+func TestGenerateAssert(t *testing.T) {
+	actions, params := sampleWorkflow()
+	actions = append(actions,
+		models.SemanticAction{SequenceID: 5, ActionType: models.ActionAssert, Target: models.SemanticTarget{Selector: "#result-stats"},
+			Assert: &models.Assertion{Type: models.AssertTextContains, Value: "results"}},
+		models.SemanticAction{SequenceID: 6, ActionType: models.ActionAssert, Target: models.SemanticTarget{Selector: ".error"},
+			Assert: &models.Assertion{Type: models.AssertAbsent}},
+		models.SemanticAction{SequenceID: 7, ActionType: models.ActionAssert,
+			Assert: &models.Assertion{Type: models.AssertURLMatches, Value: `/search\?q=`}},
+	)
+
+	code := GenerateGoRodScript(actions, params, ScriptOptions{})
+	if _, err := parser.ParseFile(token.NewFileSet(), "main.go", code, parser.AllErrors); err != nil {
+		t.Fatalf("generated script does not parse: %v\n%s", err, code)
+	}
+	for _, want := range []string{
+		`if got := strings.TrimSpace(page.MustElement("#result-stats").MustWaitVisible().MustText()); !strings.Contains(got, "results") {`,
+		`panic(fmt.Sprintf("assertion failed: text %q does not contain %q", got, "results"))`,
+		`if page.MustHas(".error") {`,
+		`panic("assertion failed: .error is present")`,
+		`if got := page.MustInfo().URL; !regexp.MustCompile("/search\\?q=").MatchString(got) {`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated script missing %q\n%s", want, code)
+		}
+	}
+
+	code = GenerateGoRodTest(actions, params, ScriptOptions{})
+	if _, err := parser.ParseFile(token.NewFileSet(), "workflow_test.go", code, parser.AllErrors); err != nil {
+		t.Fatalf("generated test does not parse: %v\n%s", err, code)
+	}
+	for _, want := range []string{
+		"text5, err := el5.Text()",
+		`t.Fatalf("step 5: assertion failed: text %q does not contain %q", got, "results")`,
+		`if has, _, err := page.Has(".error"); err == nil && has {`,
+		"info7, err := page.Info()",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated test missing %q\n%s", want, code)
+		}
+	}
+}
+
+func TestProvenance(t *testing.T) {
+	action := models.SemanticAction{
+		SequenceID: 2,
+		ActionType: models.ActionClick,
+		Timestamp:  18328,
+		Target: models.SemanticTarget{
+			Tag:        "button",
+			Text:       "Search\n  now",
+			NodeID:     2644,
+			Attributes: map[string]interface{}{"aria-label": "Google Search"},
+		},
+	}
+
+	want := `Recorded at 18.328s, node 2644, aria-label "Google Search", text "Search now"`
+	if got := Provenance(action); got != want {
+		t.Errorf("Provenance() = %q, want %q", got, want)
+	}
+
+	code := GenerateGoRodScript([]models.SemanticAction{action}, nil, ScriptOptions{})
+	if !strings.Contains(code, "\t// "+want+"\n") {
+		t.Errorf("generated script missing provenance comment\n%s", code)
+	}
+}
+
+// A parameter no step uses, such as one of an action edited since, still
+// builds
+func TestGenerateGoRodScriptCompiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("type-checks the script against the sources of its imports")
+	}
+	actions, params := sampleWorkflow()
+	params = append(params, models.WorkflowParameter{Name: "unused", DefaultValue: "x", TokenType: models.TokenVariable, SourceAction: 9})
+
+	for _, opts := range []ScriptOptions{{}, {PageObjects: true}, {HumanLike: true}} {
+		code := GenerateGoRodScript(actions, params, opts)
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, "main.go", code, 0)
+		if err != nil {
+			t.Fatalf("generated script does not parse: %v\n%s", err, code)
+		}
+		conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+		if _, err := conf.Check("main", fset, []*ast.File{file}, nil); err != nil {
+			t.Errorf("generated script with %+v does not compile: %v\n%s", opts, err, code)
+		}
+	}
+}
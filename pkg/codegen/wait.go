@@ -0,0 +1,140 @@
+package codegen
+
+import (
+	"fmt"
+	"time"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// shownScript reports whether an element matching the selector is as
+// visible as wanted, a missing element counting as hidden
+const shownScript = `(selector, visible) => { const el = document.querySelector(selector); if (!el) return !visible; const style = getComputedStyle(el); const box = el.getBoundingClientRect(); return (style.display !== 'none' && style.visibility !== 'hidden' && box.width > 0 && box.height > 0) === visible }`
+
+// urlScript reports whether the page URL matches a regular expression
+const urlScript = `(pattern) => new RegExp(pattern).test(location.href)`
+
+// armWaits emits the watchers of the page loads and requests an action
+// waits for, which have to start before the action runs
+func (b *scriptBuilder) armWaits(action models.SemanticAction) {
+	for i, cond := range action.Waits {
+		var call string
+		switch cond.Type {
+		case models.WaitNavigation:
+			b.imports["github.com/go-rod/rod/lib/proto"] = true
+			call = "WaitNavigation(proto.PageLifecycleEventNameLoad)"
+		case models.WaitNetworkIdle:
+			call = "WaitRequestIdle(500*time.Millisecond, nil, nil, nil)"
+		case models.WaitRequest:
+			b.armRequestWait(action, i, cond)
+			continue
+		default:
+			continue
+		}
+		b.imports["time"] = true
+		name := waitIdent(action, i)
+		if b.test {
+			fmt.Fprintf(&b.body, "\t%sPage := page.Timeout(%s)\n", name, durationExpr(cond.Duration()))
+			fmt.Fprintf(&b.body, "\t%s := %sPage.%s\n", name, name, call)
+		} else {
+			fmt.Fprintf(&b.body, "\t%s := page.Timeout(%s).%s\n", name, durationExpr(cond.Duration()), call)
+		}
+	}
+}
+
+// armRequestWait emits a watcher recording the status of the response to
+// the first request matching cond
+func (b *scriptBuilder) armRequestWait(action models.SemanticAction, i int, cond models.WaitCondition) {
+	b.imports["github.com/go-rod/rod/lib/proto"] = true
+	b.imports["regexp"] = true
+	b.imports["time"] = true
+	name := waitIdent(action, i)
+	match := fmt.Sprintf("regexp.MustCompile(%q).MatchString(e.Request.URL)", cond.Pattern)
+	if cond.Method != "" {
+		b.imports["strings"] = true
+		match = fmt.Sprintf("strings.EqualFold(e.Request.Method, %q) && %s", cond.Method, match)
+	}
+	fmt.Fprintf(&b.body, "\t%sRequests := map[proto.NetworkRequestID]bool{}\n", name)
+	fmt.Fprintf(&b.body, "\tvar %sStatus int\n", name)
+	fmt.Fprintf(&b.body, "\t%s := page.Timeout(%s).EachEvent(func(e *proto.NetworkRequestWillBeSent) {\n", name, durationExpr(cond.Duration()))
+	fmt.Fprintf(&b.body, "\t\tif %s {\n\t\t\t%sRequests[e.RequestID] = true\n\t\t}\n", match, name)
+	b.body.WriteString("\t}, func(e *proto.NetworkResponseReceived) bool {\n")
+	fmt.Fprintf(&b.body, "\t\tif !%sRequests[e.RequestID] {\n\t\t\treturn false\n\t\t}\n", name)
+	fmt.Fprintf(&b.body, "\t\t%sStatus = e.Response.Status\n\t\treturn true\n\t})\n", name)
+}
+
+// writeRequestCheck emits the check of the status a request wait's
+// response got, no response counting as status 0
+func (b *scriptBuilder) writeRequestCheck(action models.SemanticAction, i int, cond models.WaitCondition) {
+	name := waitIdent(action, i)
+	failed := fmt.Sprintf("%sStatus == 0 || %sStatus >= 400", name, name)
+	if cond.Status != 0 {
+		failed = fmt.Sprintf("%sStatus != %d", name, cond.Status)
+	}
+	msg := fmt.Sprintf("wait for %s: got status %%d", escapeVerbs(cond.String()))
+	if b.test {
+		msg = fmt.Sprintf("step %d: %s", action.SequenceID, msg)
+		fmt.Fprintf(&b.body, "\tif %s {\n\t\tt.Fatalf(%q, %sStatus)\n\t}\n", failed, msg, name)
+		return
+	}
+	b.imports["fmt"] = true
+	fmt.Fprintf(&b.body, "\tif %s {\n\t\tpanic(fmt.Sprintf(%q, %sStatus))\n\t}\n", failed, msg, name)
+}
+
+// writeWaits emits the waits for an action's conditions, in test mode
+// stopping the test when one times out
+func (b *scriptBuilder) writeWaits(action models.SemanticAction) {
+	for i, cond := range action.Waits {
+		name := waitIdent(action, i)
+		timeout := durationExpr(cond.Duration())
+		b.imports["time"] = true
+
+		var js, args string
+		switch cond.Type {
+		case models.WaitNavigation, models.WaitNetworkIdle:
+			fmt.Fprintf(&b.body, "\t%s()\n", name)
+			if b.test {
+				b.fatalWaitIf(action.SequenceID, cond, name+"Page.GetContext().Err()")
+			}
+			continue
+		case models.WaitRequest:
+			fmt.Fprintf(&b.body, "\t%s()\n", name)
+			b.writeRequestCheck(action, i, cond)
+			continue
+		case models.WaitVisible, models.WaitHidden:
+			js, args = "`"+shownScript+"`", fmt.Sprintf(", %q, %t", cond.Selector, cond.Type == models.WaitVisible)
+		case models.WaitURL:
+			js, args = "`"+urlScript+"`", fmt.Sprintf(", %q", cond.Pattern)
+		case models.WaitJS:
+			js = fmt.Sprintf("%q", cond.Script)
+		default:
+			fmt.Fprintf(&b.body, "\t// Unsupported wait: %s\n", cond.Type)
+			continue
+		}
+
+		if b.test {
+			b.fatalWaitIf(action.SequenceID, cond, fmt.Sprintf("page.Timeout(%s).Wait(rod.Eval(%s%s))", timeout, js, args))
+		} else {
+			fmt.Fprintf(&b.body, "\tpage.Timeout(%s).MustWait(%s%s)\n", timeout, js, args)
+		}
+	}
+}
+
+// fatalWaitIf emits a check stopping the test when a wait fails
+func (b *scriptBuilder) fatalWaitIf(step int, cond models.WaitCondition, call string) {
+	msg := fmt.Sprintf("step %d: wait for %s: %%v", step, escapeVerbs(cond.String()))
+	fmt.Fprintf(&b.body, "\tif err := %s; err != nil {\n\t\tt.Fatalf(%q, err)\n\t}\n", call, msg)
+}
+
+// waitIdent names the watcher of an action's i-th condition
+func waitIdent(action models.SemanticAction, i int) string {
+	return fmt.Sprintf("wait%d_%d", action.SequenceID, i+1)
+}
+
+// durationExpr returns the Go expression for a duration
+func durationExpr(d time.Duration) string {
+	if d%time.Second == 0 {
+		return fmt.Sprintf("%d * time.Second", d/time.Second)
+	}
+	return fmt.Sprintf("%d * time.Millisecond", d.Milliseconds())
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"dev/bravebird/browser-automation-go/migrations"
+
+	"github.com/pressly/goose/v3"
+)
+
+// dialect is the SQL that differs between MySQL and SQLite
+type dialect struct {
+	// goose is the dialect migrations are applied with
+	goose goose.Dialect
+	// migrations are the dialect's versioned schema changes
+	migrations fs.FS
+	// forUpdate ends a SELECT locking the rows it reads until the
+	// transaction ends. SQLite transactions lock the whole database as they
+	// begin, so it needs none.
+	forUpdate string
+	// reserveDomainAction counts an action against the current minute of a
+	// domain unless the minute reached the limit, bound after the domain.
+	// The counter only changes, and a row is only affected, while it is
+	// below the limit.
+	reserveDomainAction string
+	// ago returns the time a bound number of units before now, the unit
+	// being SECOND, MINUTE or DAY
+	ago func(unit string) string
+	// upsert ends an INSERT so that it replaces the columns of the row
+	// with the same key instead of failing
+	upsert func(key string, columns ...string) string
+	// match scores how well the text of the columns matches a bound search
+	// query, 0 when it doesn't
+	match func(columns ...string) string
+}
+
+var mysqlDialect = dialect{
+	goose:      goose.DialectMySQL,
+	migrations: sub(migrations.MySQL, "mysql"),
+	forUpdate:  " FOR UPDATE",
+	reserveDomainAction: `
+		INSERT INTO domain_action_windows (domain, window_start, actions)
+		VALUES (?, DATE_FORMAT(NOW(), '%Y-%m-%d %H:%i:00'), 1)
+		ON DUPLICATE KEY UPDATE actions = IF(actions < ?, actions + 1, actions)
+	`,
+	ago: func(unit string) string {
+		return "NOW() - INTERVAL ? " + unit
+	},
+	upsert: func(key string, columns ...string) string {
+		set := make([]string, len(columns))
+		for i, column := range columns {
+			set[i] = fmt.Sprintf("%s = VALUES(%s)", column, column)
+		}
+		return "ON DUPLICATE KEY UPDATE " + strings.Join(set, ", ")
+	},
+	// The columns need a FULLTEXT index of their own
+	match: func(columns ...string) string {
+		return fmt.Sprintf("MATCH(%s) AGAINST (? IN NATURAL LANGUAGE MODE)", strings.Join(columns, ", "))
+	},
+}
+
+// sqliteDialect relies on the now, ago and match_score functions registered
+// with the driver, as SQLite has neither NOW(), intervals nor FULLTEXT
+// indexes
+var sqliteDialect = dialect{
+	goose:      goose.DialectSQLite3,
+	migrations: sub(migrations.SQLite, "sqlite"),
+	reserveDomainAction: `
+		INSERT INTO domain_action_windows (domain, window_start, actions)
+		VALUES (?, substr(now(), 1, 16) || ':00', 1)
+		ON CONFLICT (domain, window_start) DO UPDATE SET actions = actions + 1 WHERE actions < ?
+	`,
+	ago: func(unit string) string {
+		return "ago(?, '" + unit + "')"
+	},
+	upsert: func(key string, columns ...string) string {
+		set := make([]string, len(columns))
+		for i, column := range columns {
+			set[i] = fmt.Sprintf("%s = excluded.%s", column, column)
+		}
+		return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", key, strings.Join(set, ", "))
+	},
+	match: func(columns ...string) string {
+		text := make([]string, len(columns))
+		for i, column := range columns {
+			text[i] = fmt.Sprintf("COALESCE(%s, '')", column)
+		}
+		return fmt.Sprintf("match_score(%s, ?)", strings.Join(text, " || ' ' || "))
+	},
+}
+
+// sub returns the files of an embedded directory
+func sub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
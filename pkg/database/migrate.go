@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/pressly/goose/v3"
+	goosedb "github.com/pressly/goose/v3/database"
+)
+
+// schemaChanges are a column each migration added, or one of the table it
+// created, by version. Databases created before migrations were versioned
+// were set up by docker-compose running the migrations MySQL's init
+// directory had at the time, so they have the changes up to one of them.
+var schemaChanges = []struct {
+	version       int64
+	table, column string
+}{
+	{1, "workflow_definitions", "id"},
+	{2, "semantic_actions", "interaction_rank"},
+	{3, "semantic_actions", "context"},
+	{4, "workflow_definitions", "generated_code"},
+	{5, "code_generations", "id"},
+	{6, "workflow_versions", "id"},
+	{7, "code_templates", "action_type"},
+	{8, "action_code", "run_id"},
+	{9, "browser_sessions", "name"},
+	{10, "workflow_definitions", "settings"},
+	{11, "semantic_actions", "waits"},
+	{12, "semantic_actions", "metadata"},
+	{13, "action_results", "selector"},
+	{14, "action_results", "iteration"},
+	{15, "pipelines", "id"},
+	{16, "schedules", "id"},
+	{17, "domain_action_windows", "domain"},
+	{18, "semantic_actions", "criticality"},
+	{19, "semantic_actions", "compensations"},
+	{20, "action_results", "before_screenshot_path"},
+	{21, "action_results", "metrics"},
+	{22, "action_results", "dom_snapshot_path"},
+	{23, "semantic_actions", "extract"},
+	{24, "workflow_runs", "dataset"},
+	{25, "semantic_actions", "assertion"},
+	{26, "action_results", "missing_outcomes"},
+	{27, "action_results", "selector_source"},
+	{28, "workflow_runs", "artifacts_pruned_at"},
+}
+
+// Migrate applies the migrations the database lacks, in version order
+func (db *DB) Migrate(ctx context.Context) error {
+	provider, err := goose.NewProvider(db.dialect.goose, db.conn, db.dialect.migrations)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	if err := db.baseline(ctx); err != nil {
+		return err
+	}
+
+	results, err := provider.Up(ctx)
+	for _, result := range results {
+		if result.Error == nil {
+			slog.Info("Applied migration", "migration", filepath.Base(result.Source.Path), "duration", result.Duration)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+	return nil
+}
+
+// baseline records the migrations whose changes a database that has tables
+// but no versions has as applied, so they aren't run again
+func (db *DB) baseline(ctx context.Context) error {
+	var n int
+	if err := db.conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+goose.DefaultTablename).Scan(&n); err == nil {
+		return nil
+	}
+	if err := db.conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM workflow_definitions`).Scan(&n); err != nil {
+		return nil // a new database
+	}
+
+	legacyVersion := db.legacyVersion(ctx)
+	store, err := goosedb.NewStore(db.dialect.goose, goose.DefaultTablename)
+	if err != nil {
+		return err
+	}
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := store.CreateVersionTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create version table: %w", err)
+	}
+	for version := int64(0); version <= legacyVersion; version++ {
+		if err := store.Insert(ctx, tx, goosedb.InsertRequest{Version: version}); err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", version, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	slog.Info("Recorded existing schema as migrated", "version", legacyVersion)
+	return nil
+}
+
+// legacyVersion returns the version of the last migration a database
+// created before migrations were versioned has the changes of
+func (db *DB) legacyVersion(ctx context.Context) int64 {
+	var version int64
+	for _, change := range schemaChanges {
+		rows, err := db.conn.QueryContext(ctx, `SELECT `+change.column+` FROM `+change.table+` LIMIT 0`)
+		if err != nil {
+			break
+		}
+		rows.Close()
+		version = change.version
+	}
+	return version
+}
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"github.com/pressly/goose/v3"
+)
+
+func dbVersion(t *testing.T, db *DB) int64 {
+	t.Helper()
+	var version int64
+	err := db.conn.QueryRow(`SELECT MAX(version_id) FROM ` + goose.DefaultTablename).Scan(&version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return version
+}
+
+// latestVersion is the version of the dialect's last migration
+func latestVersion(t *testing.T, d dialect) int64 {
+	t.Helper()
+	conn, err := sql.Open("mysql", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, err := goose.NewProvider(d.goose, conn, d.migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := provider.ListSources()
+	return sources[len(sources)-1].Version
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	if version, want := dbVersion(t, db), latestVersion(t, sqliteDialect); version != want {
+		t.Errorf("version = %d, want %d", version, want)
+	}
+	if err := db.Migrate(ctx); err != nil {
+		t.Errorf("Migrate() again = %v, want no migrations to apply", err)
+	}
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewSQLite(filepath.Join(t.TempDir(), "automator.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// A database whose tables were created before migrations were versioned
+	schema, err := fs.ReadFile(sqliteDialect.migrations, "028_initial_schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.conn.Exec(string(schema)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if version, want := dbVersion(t, db), latestVersion(t, sqliteDialect); version != want {
+		t.Errorf("version = %d, want %d", version, want)
+	}
+}
+
+func TestLegacyVersion(t *testing.T) {
+	ctx := context.Background()
+
+	// Databases MySQL's init directory set up when it had fewer migrations
+	tests := []struct {
+		name   string
+		schema string
+		want   int64
+	}{
+		{"initial schema", `CREATE TABLE workflow_definitions (id TEXT); CREATE TABLE semantic_actions (id TEXT)`, 1},
+		{"action context", `CREATE TABLE workflow_definitions (id TEXT);
+			CREATE TABLE semantic_actions (id TEXT, interaction_rank TEXT, timestamp INT, context TEXT)`, 3},
+		{"generated code", `CREATE TABLE workflow_definitions (id TEXT, generated_code TEXT);
+			CREATE TABLE semantic_actions (id TEXT, interaction_rank TEXT, timestamp INT, context TEXT)`, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := NewSQLite(filepath.Join(t.TempDir(), "automator.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			if _, err := db.conn.Exec(tt.schema); err != nil {
+				t.Fatal(err)
+			}
+			if got := db.legacyVersion(ctx); got != tt.want {
+				t.Errorf("legacyVersion() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrationVersions(t *testing.T) {
+	// Every schema change is made to both databases
+	if mysql, sqlite := latestVersion(t, mysqlDialect), latestVersion(t, sqliteDialect); mysql != sqlite {
+		t.Errorf("latest MySQL migration is %d, SQLite's %d, want the same", mysql, sqlite)
+	}
+}
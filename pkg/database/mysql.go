@@ -4,7 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"slices"
+	"strings"
 	"time"
 
 	"dev/bravebird/browser-automation-go/pkg/models"
@@ -14,9 +18,14 @@ import (
 
 // DB represents the database connection
 type DB struct {
-	conn *sql.DB
+	conn    *sql.DB
+	dialect dialect
 }
 
+// ErrConflict is returned by the updates of a workflow that changed since
+// the revision they were made from
+var ErrConflict = errors.New("workflow was changed by another update")
+
 // New creates a new database connection
 func New(dsn string) (*DB, error) {
 	conn, err := sql.Open("mysql", dsn)
@@ -37,7 +46,7 @@ func New(dsn string) (*DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &DB{conn: conn}, nil
+	return &DB{conn: conn, dialect: mysqlDialect}, nil
 }
 
 // Close closes the database connection
@@ -45,22 +54,29 @@ func (db *DB) Close() error {
 	return db.conn.Close()
 }
 
+// Ping checks that the database is reachable
+func (db *DB) Ping(ctx context.Context) error {
+	return db.conn.PingContext(ctx)
+}
+
 // ==================== Workflow Definitions ====================
 
 // CreateWorkflowDefinition creates a new workflow definition
 func (db *DB) CreateWorkflowDefinition(ctx context.Context, def *models.WorkflowDefinition) error {
 	query := `
-		INSERT INTO workflow_definitions (id, name, events_file_path, start_url, semantic_context, parameters, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO workflow_definitions (id, name, description, events_file_path, start_url, semantic_context, parameters, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	now := time.Now()
 	def.CreatedAt = now
 	def.UpdatedAt = now
+	def.Revision = 1
 
 	_, err := db.conn.ExecContext(ctx, query,
 		def.ID,
 		def.Name,
+		sql.NullString{String: def.Description, Valid: def.Description != ""},
 		def.EventsFilePath,
 		def.StartURL,
 		def.SemanticContext,
@@ -75,21 +91,27 @@ func (db *DB) CreateWorkflowDefinition(ctx context.Context, def *models.Workflow
 // GetWorkflowDefinition retrieves a workflow definition by ID
 func (db *DB) GetWorkflowDefinition(ctx context.Context, id string) (*models.WorkflowDefinition, error) {
 	query := `
-		SELECT id, name, events_file_path, is_workflow_generated, start_url, 
-		       semantic_context, parameters, created_at, updated_at
+		SELECT id, name, description, events_file_path, is_workflow_generated, start_url, 
+		       semantic_context, parameters, generated_code, generated_format, settings, revision, created_at, updated_at
 		FROM workflow_definitions
-		WHERE id = ?
+		WHERE id = ? AND deleted_at IS NULL
 	`
 
 	var def models.WorkflowDefinition
+	var description, generatedCode, generatedFormat, settings sql.NullString
 	err := db.conn.QueryRowContext(ctx, query, id).Scan(
 		&def.ID,
 		&def.Name,
+		&description,
 		&def.EventsFilePath,
 		&def.IsWorkflowGenerated,
 		&def.StartURL,
 		&def.SemanticContext,
 		&def.ParametersJSON,
+		&generatedCode,
+		&generatedFormat,
+		&settings,
+		&def.Revision,
 		&def.CreatedAt,
 		&def.UpdatedAt,
 	)
@@ -100,336 +122,1907 @@ func (db *DB) GetWorkflowDefinition(ctx context.Context, id string) (*models.Wor
 	if err != nil {
 		return nil, fmt.Errorf("failed to get workflow: %w", err)
 	}
+	def.Description = description.String
+	def.GeneratedCode = generatedCode.String
+	def.GeneratedFormat = generatedFormat.String
+	if settings.Valid {
+		if err := json.Unmarshal([]byte(settings.String), &def.Settings); err != nil {
+			return nil, fmt.Errorf("failed to parse workflow settings: %w", err)
+		}
+	}
 
-	return &def, nil
+	defs := []models.WorkflowDefinition{def}
+	if err := db.setWorkflowTags(ctx, defs); err != nil {
+		return nil, err
+	}
+	return &defs[0], nil
 }
 
-// ListWorkflowDefinitions retrieves all workflow definitions
-func (db *DB) ListWorkflowDefinitions(ctx context.Context) ([]models.WorkflowDefinition, error) {
+// ListWorkflowDefinitions retrieves the workflow definitions the filter
+// selects, newest first unless sorted otherwise, and how many it selects in
+// all
+func (db *DB) ListWorkflowDefinitions(ctx context.Context, filter models.WorkflowFilter) ([]models.WorkflowDefinition, int, error) {
+	where, args := listWhere(filter.ListOptions, "created_at")
+	where += inTrash(filter.Deleted, "deleted_at")
+	if filter.Name != "" {
+		where += " AND name LIKE ? ESCAPE '!'"
+		args = append(args, contains(filter.Name))
+	}
+	tagged, tagArgs := taggedWith("id", filter.Tags)
+	where += tagged
+	args = append(args, tagArgs...)
+	order, limitArgs, err := listOrder(filter.ListOptions, "", models.WorkflowSorts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	err = db.conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM workflow_definitions WHERE `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count workflows: %w", err)
+	}
+
 	query := `
-		SELECT id, name, events_file_path, is_workflow_generated, start_url,
-		       semantic_context, parameters, created_at, updated_at
+		SELECT id, name, description, events_file_path, is_workflow_generated, start_url,
+		       semantic_context, parameters, revision, created_at, updated_at, deleted_at
 		FROM workflow_definitions
-		ORDER BY created_at DESC
-	`
+		WHERE ` + where + order
 
-	rows, err := db.conn.QueryContext(ctx, query)
+	rows, err := db.conn.QueryContext(ctx, query, append(args, limitArgs...)...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list workflows: %w", err)
+		return nil, 0, fmt.Errorf("failed to list workflows: %w", err)
 	}
 	defer rows.Close()
 
-	var definitions []models.WorkflowDefinition
+	definitions := []models.WorkflowDefinition{}
 	for rows.Next() {
 		var def models.WorkflowDefinition
+		var description sql.NullString
 		err := rows.Scan(
 			&def.ID,
 			&def.Name,
+			&description,
 			&def.EventsFilePath,
 			&def.IsWorkflowGenerated,
 			&def.StartURL,
 			&def.SemanticContext,
 			&def.ParametersJSON,
+			&def.Revision,
 			&def.CreatedAt,
 			&def.UpdatedAt,
+			&def.DeletedAt,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan workflow: %w", err)
+			return nil, 0, fmt.Errorf("failed to scan workflow: %w", err)
 		}
+		def.Description = description.String
 		definitions = append(definitions, def)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	rows.Close()
+
+	if err := db.setWorkflowTags(ctx, definitions); err != nil {
+		return nil, 0, err
+	}
+	return definitions, total, nil
+}
+
+// listWhere returns the conditions of a list bounding the time column by
+// the options' dates, and the args they bind
+func listWhere(opts models.ListOptions, column string) (string, []interface{}) {
+	where := "TRUE"
+	var args []interface{}
+	// Times are bound in the zone they were stored in, which SQLite compares
+	// as text
+	if !opts.Since.IsZero() {
+		where += " AND " + column + " >= ?"
+		args = append(args, opts.Since.Local())
+	}
+	if !opts.Until.IsZero() {
+		where += " AND " + column + " < ?"
+		args = append(args, opts.Until.Local())
+	}
+	return where, args
+}
+
+// inTrash returns the condition of a list keeping the rows in the trash
+// when deleted, or else the rows out of it
+func inTrash(deleted bool, column string) string {
+	if deleted {
+		return " AND " + column + " IS NOT NULL"
+	}
+	return " AND " + column + " IS NULL"
+}
+
+// listOrder returns the ORDER BY and LIMIT clauses of a list sorted by one
+// of sorts, the first by default, on the table of the prefix, and the args
+// they bind. Rows sorting alike are ordered by ID, so pages don't overlap.
+func listOrder(opts models.ListOptions, prefix string, sorts []string) (string, []interface{}, error) {
+	sort := sorts[0]
+	if opts.Sort != "" {
+		if !slices.Contains(sorts, opts.Sort) {
+			return "", nil, fmt.Errorf("unknown sort field %q", opts.Sort)
+		}
+		sort = opts.Sort
+	}
+	direction := "DESC"
+	if opts.Asc {
+		direction = "ASC"
+	}
+	order := fmt.Sprintf(" ORDER BY %s%s %s, %sid %s", prefix, sort, direction, prefix, direction)
+
+	limit := opts.Limit
+	if limit <= 0 {
+		if opts.Offset <= 0 {
+			return order, nil, nil
+		}
+		limit = math.MaxInt32 // an offset needs a limit
+	}
+	return order + " LIMIT ? OFFSET ?", []interface{}{limit, max(opts.Offset, 0)}, nil
+}
 
-	return definitions, nil
+// contains returns the LIKE pattern, escaped with !, of the values that
+// contain s
+func contains(s string) string {
+	return "%" + strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s) + "%"
 }
 
-// UpdateWorkflowDefinition updates a workflow definition
+// UpdateWorkflowDefinition updates a workflow definition read at
+// def.Revision, moving it to the next revision, or returns ErrConflict when
+// the workflow changed since
 func (db *DB) UpdateWorkflowDefinition(ctx context.Context, def *models.WorkflowDefinition) error {
 	query := `
 		UPDATE workflow_definitions
-		SET name = ?, is_workflow_generated = ?, semantic_context = ?, 
-		    parameters = ?, updated_at = ?
-		WHERE id = ?
+		SET name = ?, description = ?, start_url = ?, is_workflow_generated = ?,
+		    semantic_context = ?, parameters = ?, updated_at = ?, revision = revision + 1
+		WHERE id = ? AND revision = ?
 	`
 
-	def.UpdatedAt = time.Now()
+	updatedAt := time.Now()
 
-	_, err := db.conn.ExecContext(ctx, query,
+	res, err := db.conn.ExecContext(ctx, query,
 		def.Name,
+		sql.NullString{String: def.Description, Valid: def.Description != ""},
+		def.StartURL,
 		def.IsWorkflowGenerated,
 		def.SemanticContext,
 		def.ParametersJSON,
-		def.UpdatedAt,
+		updatedAt,
 		def.ID,
+		def.Revision,
 	)
+	if err != nil {
+		return err
+	}
+	// The revision always changes, so a row matched is a row affected
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrConflict
+	}
+
+	def.UpdatedAt = updatedAt
+	def.Revision++
+	return nil
+}
+
+// SaveWorkflowSettings replaces the replay settings of a workflow
+func (db *DB) SaveWorkflowSettings(ctx context.Context, id string, settings models.WorkflowSettings) error {
+	query := `
+		UPDATE workflow_definitions
+		SET settings = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	settingsJSON, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	_, err = db.conn.ExecContext(ctx, query, string(settingsJSON), time.Now(), id)
+	return err
+}
+
+// SaveGeneratedCode stores the last generated workflow program and marks the
+// workflow as generated, or returns ErrConflict when the workflow changed
+// since the revision the program was generated from
+func (db *DB) SaveGeneratedCode(ctx context.Context, id string, revision int, format, code string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := db.checkRevision(ctx, tx, id, revision); err != nil {
+		return err
+	}
+	query := `
+		UPDATE workflow_definitions
+		SET generated_code = ?, generated_format = ?, is_workflow_generated = TRUE, updated_at = ?
+		WHERE id = ?
+	`
+	if _, err := tx.ExecContext(ctx, query, code, format, time.Now(), id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkRevision locks a workflow until the transaction ends, returning
+// ErrConflict unless it is at the revision
+func (db *DB) checkRevision(ctx context.Context, tx *sql.Tx, id string, revision int) error {
+	var current int
+	err := tx.QueryRowContext(ctx, `SELECT revision FROM workflow_definitions WHERE id = ?`+db.dialect.forUpdate, id).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get workflow revision: %w", err)
+	}
+	if err == sql.ErrNoRows || current != revision {
+		return ErrConflict
+	}
+	return nil
+}
 
+// ClearGeneratedCode forgets the generated program of a workflow, whose
+// actions changed since it was generated
+func (db *DB) ClearGeneratedCode(ctx context.Context, id string) error {
+	query := `
+		UPDATE workflow_definitions
+		SET generated_code = NULL, generated_format = NULL, is_workflow_generated = FALSE, updated_at = ?
+		WHERE id = ?
+	`
+
+	_, err := db.conn.ExecContext(ctx, query, time.Now(), id)
 	return err
 }
 
-// DeleteWorkflowDefinition deletes a workflow definition
+// DeleteWorkflowDefinition deletes a workflow for good, with its actions,
+// versions, runs and their results, which the foreign keys cascade to
 func (db *DB) DeleteWorkflowDefinition(ctx context.Context, id string) error {
 	query := `DELETE FROM workflow_definitions WHERE id = ?`
 	_, err := db.conn.ExecContext(ctx, query, id)
 	return err
 }
 
-// ==================== Semantic Actions ====================
+// TrashWorkflowDefinition moves a workflow to the trash, hiding it and its
+// runs until it is restored or purged, reporting whether it was out of it
+func (db *DB) TrashWorkflowDefinition(ctx context.Context, id string) (bool, error) {
+	return db.setDeletedAt(ctx, "workflow_definitions", id, true)
+}
 
-// CreateSemanticActions creates semantic actions for a workflow
-func (db *DB) CreateSemanticActions(ctx context.Context, workflowID string, actions []models.SemanticAction) error {
-	query := `
-		INSERT INTO semantic_actions (id, workflow_id, sequence_id, action_type, target, value, embeddings, interaction_rank, timestamp)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
+// RestoreWorkflowDefinition takes a workflow out of the trash, reporting
+// whether it was in it
+func (db *DB) RestoreWorkflowDefinition(ctx context.Context, id string) (bool, error) {
+	return db.setDeletedAt(ctx, "workflow_definitions", id, false)
+}
 
-	tx, err := db.conn.BeginTx(ctx, nil)
+// TrashedWorkflows lists up to limit workflows moved to the trash before
+// cutoff, longest there first, with only their IDs and events files
+func (db *DB) TrashedWorkflows(ctx context.Context, cutoff time.Time, limit int) ([]models.WorkflowDefinition, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT id, events_file_path FROM workflow_definitions
+		WHERE deleted_at < ?
+		ORDER BY deleted_at LIMIT ?
+	`, cutoff, limit)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, fmt.Errorf("failed to list trashed workflows: %w", err)
 	}
-	defer tx.Rollback()
+	defer rows.Close()
 
-	stmt, err := tx.PrepareContext(ctx, query)
+	var defs []models.WorkflowDefinition
+	for rows.Next() {
+		var def models.WorkflowDefinition
+		if err := rows.Scan(&def.ID, &def.EventsFilePath); err != nil {
+			return nil, fmt.Errorf("failed to scan workflow: %w", err)
+		}
+		defs = append(defs, def)
+	}
+	return defs, rows.Err()
+}
+
+// EventsFileInUse reports whether a workflow other than the given one, such
+// as a copy of it, has the events file at path
+func (db *DB) EventsFileInUse(ctx context.Context, path, workflowID string) (bool, error) {
+	var n int
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM workflow_definitions WHERE events_file_path = ? AND id <> ?`,
+		path, workflowID,
+	).Scan(&n)
 	if err != nil {
-		return fmt.Errorf("failed to prepare statement: %w", err)
+		return false, fmt.Errorf("failed to count workflows: %w", err)
 	}
-	defer stmt.Close()
+	return n > 0, nil
+}
 
-	for _, action := range actions {
-		targetJSON, _ := json.Marshal(action.Target)
-		embeddingsJSON, _ := json.Marshal(action.Embeddings)
+// setDeletedAt moves a row of the table to the trash or out of it,
+// reporting whether it was elsewhere
+func (db *DB) setDeletedAt(ctx context.Context, table, id string, deleted bool) (bool, error) {
+	deletedAt := "NULL"
+	if deleted {
+		deletedAt = "NOW()"
+	}
+	res, err := db.conn.ExecContext(ctx,
+		`UPDATE `+table+` SET deleted_at = `+deletedAt+` WHERE id = ?`+inTrash(!deleted, "deleted_at"), id)
+	if err != nil {
+		return false, fmt.Errorf("failed to update %s: %w", table, err)
+	}
+	updated, err := res.RowsAffected()
+	return updated > 0, err
+}
 
-		_, err := stmt.ExecContext(ctx,
-			action.ID,
-			workflowID,
-			action.SequenceID,
-			action.ActionType,
-			string(targetJSON),
-			action.Value,
-			embeddingsJSON,
-			action.InteractionRank,
-			action.Timestamp,
-		)
+// queryIDs returns the IDs a query selects
+func (db *DB) queryIDs(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list IDs: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ==================== Workflow Tags ====================
+
+// AddWorkflowTags tags a workflow, keeping the tags it already has
+func (db *DB) AddWorkflowTags(ctx context.Context, workflowID string, tags []string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, tag := range tags {
+		_, err := tx.ExecContext(ctx, `INSERT INTO workflow_tags (workflow_id, tag) VALUES (?, ?) `+db.dialect.upsert("workflow_id, tag", "tag"), workflowID, tag)
 		if err != nil {
-			return fmt.Errorf("failed to insert action: %w", err)
+			return fmt.Errorf("failed to add tag: %w", err)
 		}
 	}
 
 	return tx.Commit()
 }
 
-// GetSemanticActions retrieves all semantic actions for a workflow
-func (db *DB) GetSemanticActions(ctx context.Context, workflowID string) ([]models.SemanticAction, error) {
-	query := `
-		SELECT id, workflow_id, sequence_id, action_type, target, value, embeddings, interaction_rank, timestamp
-		FROM semantic_actions
-		WHERE workflow_id = ?
-		ORDER BY sequence_id
-	`
+// RemoveWorkflowTag removes a tag from a workflow, reporting whether the
+// workflow had it
+func (db *DB) RemoveWorkflowTag(ctx context.Context, workflowID, tag string) (bool, error) {
+	res, err := db.conn.ExecContext(ctx, `DELETE FROM workflow_tags WHERE workflow_id = ? AND tag = ?`, workflowID, tag)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove tag: %w", err)
+	}
+	removed, err := res.RowsAffected()
+	return removed > 0, err
+}
 
-	rows, err := db.conn.QueryContext(ctx, query, workflowID)
+// ListTags lists the tags workflows out of the trash have, with how many
+// have each, by name
+func (db *DB) ListTags(ctx context.Context) ([]models.TagCount, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT wt.tag, COUNT(*)
+		FROM workflow_tags wt
+		JOIN workflow_definitions wd ON wd.id = wt.workflow_id
+		WHERE wd.deleted_at IS NULL
+		GROUP BY wt.tag
+		ORDER BY tag
+	`)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get actions: %w", err)
+		return nil, fmt.Errorf("failed to list tags: %w", err)
 	}
 	defer rows.Close()
 
-	var actions []models.SemanticAction
+	tags := []models.TagCount{}
 	for rows.Next() {
-		var action models.SemanticAction
-		var targetJSON, embeddingsJSON string
-
-		err := rows.Scan(
-			&action.ID,
-			&action.WorkflowID,
-			&action.SequenceID,
-			&action.ActionType,
-			&targetJSON,
-			&action.Value,
-			&embeddingsJSON,
-			&action.InteractionRank,
-			&action.Timestamp,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan action: %w", err)
+		var tag models.TagCount
+		if err := rows.Scan(&tag.Tag, &tag.Workflows); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
 		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
 
-		json.Unmarshal([]byte(targetJSON), &action.Target)
-		json.Unmarshal([]byte(embeddingsJSON), &action.Embeddings)
+// setWorkflowTags fills in the tags of workflows, in order
+func (db *DB) setWorkflowTags(ctx context.Context, defs []models.WorkflowDefinition) error {
+	if len(defs) == 0 {
+		return nil
+	}
+	byID := make(map[string]*models.WorkflowDefinition, len(defs))
+	args := make([]interface{}, len(defs))
+	for i := range defs {
+		defs[i].Tags = []string{}
+		byID[defs[i].ID] = &defs[i]
+		args[i] = defs[i].ID
+	}
 
-		actions = append(actions, action)
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT workflow_id, tag
+		FROM workflow_tags
+		WHERE workflow_id IN (?`+strings.Repeat(", ?", len(defs)-1)+`)
+		ORDER BY tag
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get tags: %w", err)
 	}
+	defer rows.Close()
 
-	return actions, nil
+	for rows.Next() {
+		var workflowID, tag string
+		if err := rows.Scan(&workflowID, &tag); err != nil {
+			return fmt.Errorf("failed to scan tag: %w", err)
+		}
+		byID[workflowID].Tags = append(byID[workflowID].Tags, tag)
+	}
+	return rows.Err()
 }
 
-// ==================== Workflow Runs ====================
+// taggedWith returns the condition that the workflow of the ID column has
+// every tag, and the args it binds
+func taggedWith(column string, tags []string) (string, []interface{}) {
+	var where string
+	var args []interface{}
+	for _, tag := range tags {
+		where += " AND " + column + " IN (SELECT workflow_id FROM workflow_tags WHERE tag = ?)"
+		args = append(args, tag)
+	}
+	return where, args
+}
 
-// CreateWorkflowRun creates a new workflow run
-func (db *DB) CreateWorkflowRun(ctx context.Context, run *models.WorkflowRun) error {
-	query := `
-		INSERT INTO workflow_runs (id, workflow_id, temporal_run_id, temporal_workflow_id, status, parameters)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`
+// ==================== Code Generations ====================
 
-	_, err := db.conn.ExecContext(ctx, query,
-		run.ID,
-		run.WorkflowID,
-		run.TemporalRunID,
-		run.TemporalWorkflowID,
-		run.Status,
-		run.ParametersJSON,
-	)
+// CreateCodeGeneration stores a generation as the next version of its workflow
+func (db *DB) CreateCodeGeneration(ctx context.Context, gen *models.CodeGeneration) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	return err
-}
+	// Lock the workflow's generations so concurrent requests get distinct versions
+	err = tx.QueryRowContext(ctx,
+		`SELECT COALESCE(MAX(version), 0) + 1 FROM code_generations WHERE workflow_id = ?`+db.dialect.forUpdate,
+		gen.WorkflowID,
+	).Scan(&gen.Version)
+	if err != nil {
+		return fmt.Errorf("failed to get next version: %w", err)
+	}
 
-// GetWorkflowRun retrieves a workflow run by ID
-func (db *DB) GetWorkflowRun(ctx context.Context, id string) (*models.WorkflowRun, error) {
 	query := `
-		SELECT id, workflow_id, temporal_run_id, temporal_workflow_id, status,
-		       parameters, started_at, completed_at, error_message
-		FROM workflow_runs
-		WHERE id = ?
+		INSERT INTO code_generations (id, workflow_id, version, format, llm_provider, code, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
 	`
 
-	var run models.WorkflowRun
-	err := db.conn.QueryRowContext(ctx, query, id).Scan(
-		&run.ID,
-		&run.WorkflowID,
-		&run.TemporalRunID,
-		&run.TemporalWorkflowID,
-		&run.Status,
-		&run.ParametersJSON,
-		&run.StartedAt,
-		&run.CompletedAt,
-		&run.ErrorMessage,
+	gen.CreatedAt = time.Now()
+	_, err = tx.ExecContext(ctx, query,
+		gen.ID,
+		gen.WorkflowID,
+		gen.Version,
+		gen.Format,
+		gen.LLMProvider,
+		gen.Code,
+		gen.CreatedAt,
 	)
-
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get run: %w", err)
+		return fmt.Errorf("failed to insert generation: %w", err)
 	}
 
-	return &run, nil
+	return tx.Commit()
 }
 
-// ListWorkflowRuns retrieves runs for a workflow
-func (db *DB) ListWorkflowRuns(ctx context.Context, workflowID string) ([]models.WorkflowRun, error) {
+// ListCodeGenerations lists a workflow's generations, newest first, without their code
+func (db *DB) ListCodeGenerations(ctx context.Context, workflowID string) ([]models.CodeGeneration, error) {
 	query := `
-		SELECT id, workflow_id, temporal_run_id, temporal_workflow_id, status,
-		       parameters, started_at, completed_at, error_message
-		FROM workflow_runs
+		SELECT id, workflow_id, version, format, llm_provider, created_at
+		FROM code_generations
 		WHERE workflow_id = ?
-		ORDER BY started_at DESC
+		ORDER BY version DESC
 	`
 
 	rows, err := db.conn.QueryContext(ctx, query, workflowID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list runs: %w", err)
+		return nil, fmt.Errorf("failed to list generations: %w", err)
 	}
 	defer rows.Close()
 
-	var runs []models.WorkflowRun
+	var generations []models.CodeGeneration
 	for rows.Next() {
-		var run models.WorkflowRun
+		var gen models.CodeGeneration
+		var provider sql.NullString
 		err := rows.Scan(
-			&run.ID,
-			&run.WorkflowID,
-			&run.TemporalRunID,
-			&run.TemporalWorkflowID,
-			&run.Status,
-			&run.ParametersJSON,
-			&run.StartedAt,
-			&run.CompletedAt,
-			&run.ErrorMessage,
+			&gen.ID,
+			&gen.WorkflowID,
+			&gen.Version,
+			&gen.Format,
+			&provider,
+			&gen.CreatedAt,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan run: %w", err)
+			return nil, fmt.Errorf("failed to scan generation: %w", err)
 		}
-		runs = append(runs, run)
+		gen.LLMProvider = provider.String
+		generations = append(generations, gen)
 	}
 
-	return runs, nil
+	return generations, nil
 }
 
-// ListAllWorkflowRuns retrieves all recent runs
-func (db *DB) ListAllWorkflowRuns(ctx context.Context, limit int) ([]models.WorkflowRun, error) {
-	if limit <= 0 {
-		limit = 50
-	}
+// GetCodeGeneration retrieves one version of a workflow's generated code.
+// Version 0 selects the latest.
+func (db *DB) GetCodeGeneration(ctx context.Context, workflowID string, version int) (*models.CodeGeneration, error) {
 	query := `
-		SELECT id, workflow_id, temporal_run_id, temporal_workflow_id, status,
-		       parameters, started_at, completed_at, error_message
-		FROM workflow_runs
-		ORDER BY started_at DESC
-		LIMIT ?
+		SELECT id, workflow_id, version, format, llm_provider, code, created_at
+		FROM code_generations
+		WHERE workflow_id = ? AND (version = ? OR ? = 0)
+		ORDER BY version DESC
+		LIMIT 1
 	`
 
-	rows, err := db.conn.QueryContext(ctx, query, limit)
+	var gen models.CodeGeneration
+	var provider sql.NullString
+	err := db.conn.QueryRowContext(ctx, query, workflowID, version, version).Scan(
+		&gen.ID,
+		&gen.WorkflowID,
+		&gen.Version,
+		&gen.Format,
+		&provider,
+		&gen.Code,
+		&gen.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get generation: %w", err)
+	}
+	gen.LLMProvider = provider.String
+
+	return &gen, nil
+}
+
+// ==================== Workflow Versions ====================
+
+// CreateWorkflowVersion stores a snapshot as the next version of its workflow
+func (db *DB) CreateWorkflowVersion(ctx context.Context, v *models.WorkflowVersion) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		`SELECT COALESCE(MAX(version), 0) + 1 FROM workflow_versions WHERE workflow_id = ?`+db.dialect.forUpdate,
+		v.WorkflowID,
+	).Scan(&v.Version)
+	if err != nil {
+		return fmt.Errorf("failed to get next version: %w", err)
+	}
+
+	query := `
+		INSERT INTO workflow_versions (id, workflow_id, version, change_type, actions, parameters, code_version, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	actionsJSON, _ := json.Marshal(v.Actions)
+	paramsJSON, _ := json.Marshal(v.Parameters)
+	v.CreatedAt = time.Now()
+
+	_, err = tx.ExecContext(ctx, query,
+		v.ID,
+		v.WorkflowID,
+		v.Version,
+		v.ChangeType,
+		string(actionsJSON),
+		string(paramsJSON),
+		sql.NullInt64{Int64: int64(v.CodeVersion), Valid: v.CodeVersion > 0},
+		v.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert version: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// ListWorkflowVersions lists a workflow's versions, newest first, without
+// their actions and parameters
+func (db *DB) ListWorkflowVersions(ctx context.Context, workflowID string) ([]models.WorkflowVersion, error) {
+	query := `
+		SELECT id, workflow_id, version, change_type, code_version, created_at
+		FROM workflow_versions
+		WHERE workflow_id = ?
+		ORDER BY version DESC
+	`
+
+	rows, err := db.conn.QueryContext(ctx, query, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []models.WorkflowVersion
+	for rows.Next() {
+		var v models.WorkflowVersion
+		var codeVersion sql.NullInt64
+		err := rows.Scan(
+			&v.ID,
+			&v.WorkflowID,
+			&v.Version,
+			&v.ChangeType,
+			&codeVersion,
+			&v.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan version: %w", err)
+		}
+		v.CodeVersion = int(codeVersion.Int64)
+		versions = append(versions, v)
+	}
+
+	return versions, nil
+}
+
+// GetWorkflowVersion retrieves one version of a workflow. Version 0 selects the latest.
+func (db *DB) GetWorkflowVersion(ctx context.Context, workflowID string, version int) (*models.WorkflowVersion, error) {
+	query := `
+		SELECT id, workflow_id, version, change_type, actions, parameters, code_version, created_at
+		FROM workflow_versions
+		WHERE workflow_id = ? AND (version = ? OR ? = 0)
+		ORDER BY version DESC
+		LIMIT 1
+	`
+
+	var v models.WorkflowVersion
+	var actionsJSON string
+	var paramsJSON sql.NullString
+	var codeVersion sql.NullInt64
+	err := db.conn.QueryRowContext(ctx, query, workflowID, version, version).Scan(
+		&v.ID,
+		&v.WorkflowID,
+		&v.Version,
+		&v.ChangeType,
+		&actionsJSON,
+		&paramsJSON,
+		&codeVersion,
+		&v.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get version: %w", err)
+	}
+
+	json.Unmarshal([]byte(actionsJSON), &v.Actions)
+	if paramsJSON.Valid {
+		json.Unmarshal([]byte(paramsJSON.String), &v.Parameters)
+	}
+	v.CodeVersion = int(codeVersion.Int64)
+
+	return &v, nil
+}
+
+// ==================== Code Templates ====================
+
+// ListCodeTemplates lists the user-supplied code templates
+func (db *DB) ListCodeTemplates(ctx context.Context) ([]models.CodeTemplate, error) {
+	query := `SELECT action_type, template, updated_at FROM code_templates ORDER BY action_type`
+
+	rows, err := db.conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list code templates: %w", err)
+	}
+	defer rows.Close()
+
+	var templates []models.CodeTemplate
+	for rows.Next() {
+		var t models.CodeTemplate
+		if err := rows.Scan(&t.ActionType, &t.Template, &t.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan code template: %w", err)
+		}
+		templates = append(templates, t)
+	}
+
+	return templates, nil
+}
+
+// SaveCodeTemplate creates or replaces the template for an action type
+func (db *DB) SaveCodeTemplate(ctx context.Context, t *models.CodeTemplate) error {
+	query := `
+		INSERT INTO code_templates (action_type, template, updated_at)
+		VALUES (?, ?, ?)
+	` + db.dialect.upsert("action_type", "template", "updated_at")
+
+	t.UpdatedAt = time.Now()
+	_, err := db.conn.ExecContext(ctx, query, t.ActionType, t.Template, t.UpdatedAt)
+	return err
+}
+
+// DeleteCodeTemplate removes the template for an action type, restoring the built-in one
+func (db *DB) DeleteCodeTemplate(ctx context.Context, actionType models.ActionType) error {
+	query := `DELETE FROM code_templates WHERE action_type = ?`
+	_, err := db.conn.ExecContext(ctx, query, actionType)
+	return err
+}
+
+// ==================== Workflow Templates ====================
+
+// workflowTemplateColumns are the columns scanWorkflowTemplate reads
+const workflowTemplateColumns = `id, name, description, start_url, actions, parameters, updated_at`
+
+// ListWorkflowTemplates lists the user-defined workflow templates by name
+func (db *DB) ListWorkflowTemplates(ctx context.Context) ([]models.WorkflowTemplate, error) {
+	query := `SELECT ` + workflowTemplateColumns + ` FROM workflow_templates ORDER BY name, id`
+
+	rows, err := db.conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflow templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []models.WorkflowTemplate{}
+	for rows.Next() {
+		t, err := scanWorkflowTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan workflow template: %w", err)
+		}
+		templates = append(templates, *t)
+	}
+	return templates, rows.Err()
+}
+
+// GetWorkflowTemplate retrieves a user-defined workflow template by ID, or
+// nil if there is none
+func (db *DB) GetWorkflowTemplate(ctx context.Context, id string) (*models.WorkflowTemplate, error) {
+	query := `SELECT ` + workflowTemplateColumns + ` FROM workflow_templates WHERE id = ?`
+
+	t, err := scanWorkflowTemplate(db.conn.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow template: %w", err)
+	}
+	return t, nil
+}
+
+// scanWorkflowTemplate scans a row of workflowTemplateColumns
+func scanWorkflowTemplate(row interface{ Scan(...interface{}) error }) (*models.WorkflowTemplate, error) {
+	var t models.WorkflowTemplate
+	var description, startURL sql.NullString
+	var actions, params string
+	var updatedAt time.Time
+	if err := row.Scan(&t.ID, &t.Name, &description, &startURL, &actions, &params, &updatedAt); err != nil {
+		return nil, err
+	}
+	t.Description = description.String
+	t.StartURL = startURL.String
+	t.UpdatedAt = &updatedAt
+	if err := json.Unmarshal([]byte(actions), &t.Actions); err != nil {
+		return nil, fmt.Errorf("failed to parse template actions: %w", err)
+	}
+	if err := json.Unmarshal([]byte(params), &t.Parameters); err != nil {
+		return nil, fmt.Errorf("failed to parse template parameters: %w", err)
+	}
+	return &t, nil
+}
+
+// SaveWorkflowTemplate creates or replaces a user-defined workflow template
+func (db *DB) SaveWorkflowTemplate(ctx context.Context, t *models.WorkflowTemplate) error {
+	query := `
+		INSERT INTO workflow_templates (id, name, description, start_url, actions, parameters, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	` + db.dialect.upsert("id", "name", "description", "start_url", "actions", "parameters", "updated_at")
+
+	actionsJSON, _ := json.Marshal(t.Actions)
+	paramsJSON, _ := json.Marshal(t.Parameters)
+	now := time.Now()
+	t.UpdatedAt = &now
+
+	_, err := db.conn.ExecContext(ctx, query,
+		t.ID,
+		t.Name,
+		sql.NullString{String: t.Description, Valid: t.Description != ""},
+		sql.NullString{String: t.StartURL, Valid: t.StartURL != ""},
+		string(actionsJSON),
+		string(paramsJSON),
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save workflow template: %w", err)
+	}
+	return nil
+}
+
+// DeleteWorkflowTemplate removes a user-defined workflow template, reporting
+// whether there was one with the ID
+func (db *DB) DeleteWorkflowTemplate(ctx context.Context, id string) (bool, error) {
+	res, err := db.conn.ExecContext(ctx, `DELETE FROM workflow_templates WHERE id = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete workflow template: %w", err)
+	}
+	deleted, err := res.RowsAffected()
+	return deleted > 0, err
+}
+
+// ==================== Semantic Actions ====================
+
+// CreateSemanticActions creates semantic actions for a workflow
+func (db *DB) CreateSemanticActions(ctx context.Context, workflowID string, actions []models.SemanticAction) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertSemanticActions(ctx, tx, workflowID, actions); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReplaceSemanticActions replaces all actions of a workflow read at revision
+// and the parameters taken from them in one transaction, moving it to the
+// next revision, or returns ErrConflict when the workflow changed since
+func (db *DB) ReplaceSemanticActions(ctx context.Context, workflowID string, revision int, actions []models.SemanticAction, parametersJSON string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := db.checkRevision(ctx, tx, workflowID, revision); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE workflow_definitions SET parameters = ?, revision = revision + 1, updated_at = ? WHERE id = ?`, parametersJSON, time.Now(), workflowID); err != nil {
+		return fmt.Errorf("failed to update workflow: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM semantic_actions WHERE workflow_id = ?`, workflowID); err != nil {
+		return fmt.Errorf("failed to delete actions: %w", err)
+	}
+	if err := insertSemanticActions(ctx, tx, workflowID, actions); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertSemanticActions(ctx context.Context, tx *sql.Tx, workflowID string, actions []models.SemanticAction) error {
+	query := `
+		INSERT INTO semantic_actions (id, workflow_id, sequence_id, action_type, target, value, embeddings, interaction_rank, timestamp, context, waits, metadata, criticality, compensations, extract, assertion, search_text)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, action := range actions {
+		targetJSON, _ := json.Marshal(action.Target)
+		embeddingsJSON, _ := json.Marshal(action.Embeddings)
+		contextJSON, _ := json.Marshal(action.Context)
+		var waitsJSON sql.NullString
+		if len(action.Waits) > 0 {
+			data, _ := json.Marshal(action.Waits)
+			waitsJSON = sql.NullString{String: string(data), Valid: true}
+		}
+		var metadataJSON sql.NullString
+		if len(action.Metadata) > 0 {
+			data, _ := json.Marshal(action.Metadata)
+			metadataJSON = sql.NullString{String: string(data), Valid: true}
+		}
+		var compensationsJSON sql.NullString
+		if len(action.Compensations) > 0 {
+			data, _ := json.Marshal(action.Compensations)
+			compensationsJSON = sql.NullString{String: string(data), Valid: true}
+		}
+		var extractJSON sql.NullString
+		if action.Extract != nil {
+			data, _ := json.Marshal(action.Extract)
+			extractJSON = sql.NullString{String: string(data), Valid: true}
+		}
+		var assertJSON sql.NullString
+		if action.Assert != nil {
+			data, _ := json.Marshal(action.Assert)
+			assertJSON = sql.NullString{String: string(data), Valid: true}
+		}
+
+		_, err := stmt.ExecContext(ctx,
+			action.ID,
+			workflowID,
+			action.SequenceID,
+			action.ActionType,
+			string(targetJSON),
+			action.Value,
+			embeddingsJSON,
+			action.InteractionRank,
+			action.Timestamp,
+			string(contextJSON),
+			waitsJSON,
+			metadataJSON,
+			action.Criticality,
+			compensationsJSON,
+			extractJSON,
+			assertJSON,
+			searchText(action),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert action: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// searchText is the text an action is searched by: its element's text and
+// selector and its value
+func searchText(action models.SemanticAction) string {
+	var text []string
+	for _, s := range []string{action.Target.Text, action.Target.Selector, action.Value} {
+		if s != "" {
+			text = append(text, s)
+		}
+	}
+	return strings.Join(text, " ")
+}
+
+// SearchWorkflows returns up to limit workflows whose names or start URLs,
+// or whose actions' text, selectors or values, match the query, best
+// matches first
+func (db *DB) SearchWorkflows(ctx context.Context, query string, limit int) ([]models.WorkflowSearchResult, error) {
+	workflowMatch := db.dialect.match("wd.name", "wd.start_url")
+	actionMatch := db.dialect.match("search_text")
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT wd.id, wd.name, COALESCE(wd.start_url, ''), `+workflowMatch+` + COALESCE(a.score, 0) AS score
+		FROM workflow_definitions wd
+		LEFT JOIN (
+			SELECT workflow_id, SUM(`+actionMatch+`) AS score
+			FROM semantic_actions
+			WHERE `+actionMatch+`
+			GROUP BY workflow_id
+		) a ON a.workflow_id = wd.id
+		WHERE wd.deleted_at IS NULL AND (`+workflowMatch+` OR a.score IS NOT NULL)
+		ORDER BY score DESC, wd.created_at DESC
+		LIMIT ?
+	`, query, query, query, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search workflows: %w", err)
+	}
+	defer rows.Close()
+
+	results := []models.WorkflowSearchResult{}
+	found := make(map[string]int)
+	for rows.Next() {
+		var result models.WorkflowSearchResult
+		if err := rows.Scan(&result.WorkflowID, &result.Name, &result.StartURL, &result.Score); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		found[result.WorkflowID] = len(results)
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	// The actions that matched, of the workflows found
+	args := []interface{}{query}
+	for _, result := range results {
+		args = append(args, result.WorkflowID)
+	}
+	actionRows, err := db.conn.QueryContext(ctx, `
+		SELECT workflow_id, sequence_id, action_type, search_text
+		FROM semantic_actions
+		WHERE `+actionMatch+` AND workflow_id IN (?`+strings.Repeat(", ?", len(results)-1)+`)
+		ORDER BY workflow_id, sequence_id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search actions: %w", err)
+	}
+	defer actionRows.Close()
+
+	for actionRows.Next() {
+		var workflowID string
+		var match models.ActionMatch
+		if err := actionRows.Scan(&workflowID, &match.SequenceID, &match.ActionType, &match.Text); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		i := found[workflowID]
+		results[i].Actions = append(results[i].Actions, match)
+	}
+
+	return results, actionRows.Err()
+}
+
+// GetSemanticActions retrieves all semantic actions for a workflow
+func (db *DB) GetSemanticActions(ctx context.Context, workflowID string) ([]models.SemanticAction, error) {
+	query := `
+		SELECT id, workflow_id, sequence_id, action_type, target, value, embeddings, interaction_rank, timestamp, context, waits, metadata,
+		       COALESCE(criticality, ''), compensations, extract, assertion
+		FROM semantic_actions
+		WHERE workflow_id = ?
+		ORDER BY sequence_id
+	`
+
+	rows, err := db.conn.QueryContext(ctx, query, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get actions: %w", err)
+	}
+	defer rows.Close()
+
+	var actions []models.SemanticAction
+	for rows.Next() {
+		var action models.SemanticAction
+		var targetJSON, embeddingsJSON string
+		var contextJSON, waitsJSON, metadataJSON, compensationsJSON, extractJSON, assertJSON sql.NullString
+
+		err := rows.Scan(
+			&action.ID,
+			&action.WorkflowID,
+			&action.SequenceID,
+			&action.ActionType,
+			&targetJSON,
+			&action.Value,
+			&embeddingsJSON,
+			&action.InteractionRank,
+			&action.Timestamp,
+			&contextJSON,
+			&waitsJSON,
+			&metadataJSON,
+			&action.Criticality,
+			&compensationsJSON,
+			&extractJSON,
+			&assertJSON,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan action: %w", err)
+		}
+
+		json.Unmarshal([]byte(targetJSON), &action.Target)
+		json.Unmarshal([]byte(embeddingsJSON), &action.Embeddings)
+		if contextJSON.Valid {
+			json.Unmarshal([]byte(contextJSON.String), &action.Context)
+		}
+		if waitsJSON.Valid {
+			json.Unmarshal([]byte(waitsJSON.String), &action.Waits)
+		}
+		if metadataJSON.Valid {
+			json.Unmarshal([]byte(metadataJSON.String), &action.Metadata)
+		}
+		if compensationsJSON.Valid {
+			json.Unmarshal([]byte(compensationsJSON.String), &action.Compensations)
+		}
+		if extractJSON.Valid {
+			json.Unmarshal([]byte(extractJSON.String), &action.Extract)
+		}
+		if assertJSON.Valid {
+			json.Unmarshal([]byte(assertJSON.String), &action.Assert)
+		}
+
+		actions = append(actions, action)
+	}
+
+	return actions, nil
+}
+
+// ==================== Workflow Runs ====================
+
+// CreateWorkflowRun creates a new workflow run
+func (db *DB) CreateWorkflowRun(ctx context.Context, run *models.WorkflowRun) error {
+	query := `
+		INSERT INTO workflow_runs (id, workflow_id, temporal_run_id, temporal_workflow_id, status, parameters, workflow_version, schedule_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := db.conn.ExecContext(ctx, query,
+		run.ID,
+		run.WorkflowID,
+		run.TemporalRunID,
+		run.TemporalWorkflowID,
+		run.Status,
+		run.ParametersJSON,
+		sql.NullInt64{Int64: int64(run.WorkflowVersion), Valid: run.WorkflowVersion > 0},
+		sql.NullString{String: run.ScheduleID, Valid: run.ScheduleID != ""},
+	)
+
+	return err
+}
+
+// GetWorkflowRun retrieves a workflow run by ID
+func (db *DB) GetWorkflowRun(ctx context.Context, id string) (*models.WorkflowRun, error) {
+	query := `
+		SELECT id, workflow_id, COALESCE(temporal_run_id, ''), COALESCE(temporal_workflow_id, ''), status,
+		       parameters, started_at, completed_at, COALESCE(error_message, ''), workflow_version, outputs, dataset
+		FROM workflow_runs
+		WHERE id = ? AND deleted_at IS NULL
+	`
+
+	var run models.WorkflowRun
+	var workflowVersion sql.NullInt64
+	var outputsJSON, datasetJSON sql.NullString
+	err := db.conn.QueryRowContext(ctx, query, id).Scan(
+		&run.ID,
+		&run.WorkflowID,
+		&run.TemporalRunID,
+		&run.TemporalWorkflowID,
+		&run.Status,
+		&run.ParametersJSON,
+		&run.StartedAt,
+		&run.CompletedAt,
+		&run.ErrorMessage,
+		&workflowVersion,
+		&outputsJSON,
+		&datasetJSON,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get run: %w", err)
+	}
+	run.WorkflowVersion = int(workflowVersion.Int64)
+	if outputsJSON.Valid {
+		json.Unmarshal([]byte(outputsJSON.String), &run.Outputs)
+	}
+	if datasetJSON.Valid {
+		json.Unmarshal([]byte(datasetJSON.String), &run.Dataset)
+	}
+
+	return &run, nil
+}
+
+// ListWorkflowRuns retrieves the runs the filter selects, last started
+// first unless sorted otherwise, and how many it selects in all
+func (db *DB) ListWorkflowRuns(ctx context.Context, filter models.RunFilter) ([]models.WorkflowRun, int, error) {
+	// Runs of workflows in the trash are hidden with them
+	where, args := listWhere(filter.ListOptions, "wr.started_at")
+	where += inTrash(filter.Deleted, "wr.deleted_at") + " AND wd.deleted_at IS NULL"
+	if filter.WorkflowID != "" {
+		where += " AND wr.workflow_id = ?"
+		args = append(args, filter.WorkflowID)
+	}
+	if len(filter.Statuses) > 0 {
+		where += " AND wr.status IN (?" + strings.Repeat(", ?", len(filter.Statuses)-1) + ")"
+		for _, status := range filter.Statuses {
+			args = append(args, status)
+		}
+	}
+	if filter.WorkflowName != "" {
+		where += " AND wd.name LIKE ? ESCAPE '!'"
+		args = append(args, contains(filter.WorkflowName))
+	}
+	tagged, tagArgs := taggedWith("wr.workflow_id", filter.Tags)
+	where += tagged
+	args = append(args, tagArgs...)
+	order, limitArgs, err := listOrder(filter.ListOptions, "wr.", models.RunSorts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	from := `
+		FROM workflow_runs wr
+		JOIN workflow_definitions wd ON wd.id = wr.workflow_id
+		WHERE ` + where
+
+	var total int
+	if err := db.conn.QueryRowContext(ctx, `SELECT COUNT(*)`+from, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count runs: %w", err)
+	}
+
+	query := `
+		SELECT wr.id, wr.workflow_id, COALESCE(wr.temporal_run_id, ''), COALESCE(wr.temporal_workflow_id, ''), wr.status,
+		       wr.parameters, wr.started_at, wr.completed_at, COALESCE(wr.error_message, ''), wr.workflow_version, wr.deleted_at` + from + order
+
+	rows, err := db.conn.QueryContext(ctx, query, append(args, limitArgs...)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []models.WorkflowRun{}
+	for rows.Next() {
+		var run models.WorkflowRun
+		var workflowVersion sql.NullInt64
+		err := rows.Scan(
+			&run.ID,
+			&run.WorkflowID,
+			&run.TemporalRunID,
+			&run.TemporalWorkflowID,
+			&run.Status,
+			&run.ParametersJSON,
+			&run.StartedAt,
+			&run.CompletedAt,
+			&run.ErrorMessage,
+			&workflowVersion,
+			&run.DeletedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan run: %w", err)
+		}
+		run.WorkflowVersion = int(workflowVersion.Int64)
+		runs = append(runs, run)
+	}
+
+	return runs, total, rows.Err()
+}
+
+// ListScheduleRuns retrieves the latest runs a schedule started
+func (db *DB) ListScheduleRuns(ctx context.Context, scheduleID string, limit int) ([]models.WorkflowRun, error) {
+	query := `
+		SELECT id, workflow_id, temporal_run_id, temporal_workflow_id, status,
+		       parameters, started_at, completed_at, COALESCE(error_message, ''), workflow_version, schedule_id
+		FROM workflow_runs
+		WHERE schedule_id = ? AND deleted_at IS NULL
+		ORDER BY started_at DESC
+		LIMIT ?
+	`
+
+	rows, err := db.conn.QueryContext(ctx, query, scheduleID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedule runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []models.WorkflowRun
+	for rows.Next() {
+		var run models.WorkflowRun
+		var workflowVersion sql.NullInt64
+		err := rows.Scan(
+			&run.ID,
+			&run.WorkflowID,
+			&run.TemporalRunID,
+			&run.TemporalWorkflowID,
+			&run.Status,
+			&run.ParametersJSON,
+			&run.StartedAt,
+			&run.CompletedAt,
+			&run.ErrorMessage,
+			&workflowVersion,
+			&run.ScheduleID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan run: %w", err)
+		}
+		run.WorkflowVersion = int(workflowVersion.Int64)
+		runs = append(runs, run)
+	}
+
+	return runs, nil
+}
+
+// UpdateWorkflowRunStatus updates the status of a workflow run
+func (db *DB) UpdateWorkflowRunStatus(ctx context.Context, id string, status models.RunStatus, errorMsg string) error {
+	query := `
+		UPDATE workflow_runs
+		SET status = ?, error_message = ?, 
+		    completed_at = CASE WHEN ? IN ('success', 'warning', 'failed', 'canceled') THEN NOW() ELSE completed_at END
+		WHERE id = ?
+	`
+
+	_, err := db.conn.ExecContext(ctx, query, status, errorMsg, status, id)
+	return err
+}
+
+// UpdateWorkflowRunOutputs stores the values a run produced and the rows of
+// its dataset
+func (db *DB) UpdateWorkflowRunOutputs(ctx context.Context, id string, outputs map[string]string, dataset []map[string]string) error {
+	outputsJSON, err := json.Marshal(outputs)
+	if err != nil {
+		return fmt.Errorf("failed to encode outputs: %w", err)
+	}
+	var datasetJSON sql.NullString
+	if len(dataset) > 0 {
+		data, err := json.Marshal(dataset)
+		if err != nil {
+			return fmt.Errorf("failed to encode dataset: %w", err)
+		}
+		datasetJSON = sql.NullString{String: string(data), Valid: true}
+	}
+	_, err = db.conn.ExecContext(ctx, `UPDATE workflow_runs SET outputs = ?, dataset = ? WHERE id = ?`, string(outputsJSON), datasetJSON, id)
+	return err
+}
+
+// UpdateWorkflowRunStarted updates a workflow run when it starts executing
+func (db *DB) UpdateWorkflowRunStarted(ctx context.Context, id, temporalWorkflowID, temporalRunID string) error {
+	query := `
+		UPDATE workflow_runs
+		SET temporal_workflow_id = ?, temporal_run_id = ?, status = 'running', started_at = NOW()
+		WHERE id = ?
+	`
+
+	_, err := db.conn.ExecContext(ctx, query, temporalWorkflowID, temporalRunID, id)
+	return err
+}
+
+// CountActiveRuns counts a workflow's running runs started within maxAge,
+// older ones being taken as abandoned. Given a run ID, only the runs started
+// before that run are counted.
+func (db *DB) CountActiveRuns(ctx context.Context, workflowID, beforeRunID string, maxAge time.Duration) (int, error) {
+	query, args := db.activeRunsQuery(workflowID, beforeRunID, maxAge)
+
+	var count int
+	if err := db.conn.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count active runs: %w", err)
+	}
+	return count, nil
+}
+
+// activeRunsQuery is the query of CountActiveRuns
+func (db *DB) activeRunsQuery(workflowID, beforeRunID string, maxAge time.Duration) (string, []interface{}) {
+	query := `
+		SELECT COUNT(*)
+		FROM workflow_runs
+		WHERE workflow_id = ? AND status = 'running'
+		  AND started_at > ` + db.dialect.ago("SECOND") + `
+	`
+	args := []interface{}{workflowID, int(maxAge.Seconds())}
+	if beforeRunID != "" {
+		query += `
+		  AND id <> ?
+		  AND (started_at, id) < (COALESCE((SELECT started_at FROM workflow_runs WHERE id = ?), NOW()), ?)
+		`
+		args = append(args, beforeRunID, beforeRunID, beforeRunID)
+	}
+	return query, args
+}
+
+// ReserveWorkflowRun records a run as running unless limit of its
+// workflow's runs started within maxAge already are, reporting how many
+// are. The workflow is locked while its runs are counted, so concurrent
+// reservations can't both take its last slot.
+func (db *DB) ReserveWorkflowRun(ctx context.Context, run *models.WorkflowRun, limit int, maxAge time.Duration) (bool, int, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id string
+	err = tx.QueryRowContext(ctx, `SELECT id FROM workflow_definitions WHERE id = ?`+db.dialect.forUpdate, run.WorkflowID).Scan(&id)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to lock workflow: %w", err)
+	}
+
+	query, args := db.activeRunsQuery(run.WorkflowID, "", maxAge)
+	var active int
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&active); err != nil {
+		return false, 0, fmt.Errorf("failed to count active runs: %w", err)
+	}
+	if active >= limit {
+		return false, active, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO workflow_runs (id, workflow_id, status, parameters, workflow_version, schedule_id, started_at)
+		VALUES (?, ?, 'running', ?, ?, ?, NOW())
+	`,
+		run.ID,
+		run.WorkflowID,
+		run.ParametersJSON,
+		sql.NullInt64{Int64: int64(run.WorkflowVersion), Valid: run.WorkflowVersion > 0},
+		sql.NullString{String: run.ScheduleID, Valid: run.ScheduleID != ""},
+	)
+	if err != nil {
+		return false, active, fmt.Errorf("failed to create run: %w", err)
+	}
+	return true, active, tx.Commit()
+}
+
+// ExpiredRuns returns up to limit finished runs that ended before cutoff,
+// oldest first. With unpruned set, only runs whose artifacts are still kept
+// are returned.
+func (db *DB) ExpiredRuns(ctx context.Context, cutoff time.Time, unpruned bool, limit int) ([]string, error) {
+	query := `
+		SELECT id
+		FROM workflow_runs
+		WHERE status IN ('success', 'warning', 'failed', 'canceled')
+		  AND COALESCE(completed_at, started_at) < ?
+	`
+	if unpruned {
+		query += "  AND artifacts_pruned_at IS NULL\n"
+	}
+	query += "ORDER BY COALESCE(completed_at, started_at) LIMIT ?"
+
+	rows, err := db.conn.QueryContext(ctx, query, cutoff, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired runs: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan run: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ClearRunArtifacts forgets a run's artifacts once they are deleted: the
+// paths and generated code of its action results and its pre-generated
+// action code
+func (db *DB) ClearRunArtifacts(ctx context.Context, runID string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE action_results
+		SET screenshot_path = '', before_screenshot_path = NULL, after_screenshot_path = NULL,
+		    dom_snapshot_path = NULL, generated_code = ''
+		WHERE run_id = ?
+	`, runID); err != nil {
+		return fmt.Errorf("failed to clear action results: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM action_code WHERE run_id = ?`, runID); err != nil {
+		return fmt.Errorf("failed to delete action code: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE workflow_runs SET artifacts_pruned_at = NOW() WHERE id = ?`, runID); err != nil {
+		return fmt.Errorf("failed to mark run pruned: %w", err)
+	}
+	return tx.Commit()
+}
+
+// DeleteWorkflowRun deletes a run with its action results and action code
+func (db *DB) DeleteWorkflowRun(ctx context.Context, id string) error {
+	_, err := db.conn.ExecContext(ctx, `DELETE FROM workflow_runs WHERE id = ?`, id)
+	return err
+}
+
+// TrashWorkflowRun moves a run to the trash, hiding it until it is restored
+// or purged, reporting whether it was out of it
+func (db *DB) TrashWorkflowRun(ctx context.Context, id string) (bool, error) {
+	return db.setDeletedAt(ctx, "workflow_runs", id, true)
+}
+
+// RestoreWorkflowRun takes a run out of the trash, reporting whether it was
+// in it
+func (db *DB) RestoreWorkflowRun(ctx context.Context, id string) (bool, error) {
+	return db.setDeletedAt(ctx, "workflow_runs", id, false)
+}
+
+// TrashedRuns lists up to limit runs moved to the trash before cutoff,
+// longest there first
+func (db *DB) TrashedRuns(ctx context.Context, cutoff time.Time, limit int) ([]string, error) {
+	return db.queryIDs(ctx, `
+		SELECT id FROM workflow_runs
+		WHERE deleted_at < ?
+		ORDER BY deleted_at LIMIT ?
+	`, cutoff, limit)
+}
+
+// WorkflowRunIDs lists the IDs of every run of a workflow, in the trash or
+// not
+func (db *DB) WorkflowRunIDs(ctx context.Context, workflowID string) ([]string, error) {
+	return db.queryIDs(ctx, `SELECT id FROM workflow_runs WHERE workflow_id = ?`, workflowID)
+}
+
+// ReserveDomainAction takes one of the actions a domain may see this minute,
+// reporting false when all limit of them are taken. The budget is counted
+// per minute of the database's clock so every worker shares it.
+func (db *DB) ReserveDomainAction(ctx context.Context, domain string, limit int) (bool, error) {
+	res, err := db.conn.ExecContext(ctx, db.dialect.reserveDomainAction, domain, limit)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	// A new minute's first action clears the domain's past minutes. SQLite
+	// reports an update as one row too, so it clears them every time.
+	if affected == 1 {
+		_, err = db.conn.ExecContext(ctx,
+			`DELETE FROM domain_action_windows WHERE domain = ? AND window_start < `+db.dialect.ago("MINUTE"),
+			domain, 1,
+		)
+		if err != nil {
+			return true, err
+		}
+	}
+	return affected > 0, nil
+}
+
+// ==================== Action Code ====================
+
+// SaveActionCode stores the pre-generated code of one action of a run.
+// Saving again replaces the code, so retried activities are harmless.
+func (db *DB) SaveActionCode(ctx context.Context, runID, workflowID string, sequenceID int, code string) error {
+	query := `
+		INSERT INTO action_code (run_id, sequence_id, workflow_id, code, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	` + db.dialect.upsert("run_id, sequence_id", "code", "created_at")
+
+	_, err := db.conn.ExecContext(ctx, query, runID, sequenceID, workflowID, code, time.Now())
+	return err
+}
+
+// GetActionCode retrieves the pre-generated code of one action of a run
+func (db *DB) GetActionCode(ctx context.Context, runID string, sequenceID int) (string, error) {
+	query := `SELECT code FROM action_code WHERE run_id = ? AND sequence_id = ?`
+
+	var code string
+	err := db.conn.QueryRowContext(ctx, query, runID, sequenceID).Scan(&code)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("no code for run %s action %d", runID, sequenceID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get action code: %w", err)
+	}
+	return code, nil
+}
+
+// GetLatestActionCode returns the per-action code of a workflow's most
+// recent run that generated any, keyed by sequence ID
+func (db *DB) GetLatestActionCode(ctx context.Context, workflowID string) (map[int]string, error) {
+	query := `
+		SELECT sequence_id, code
+		FROM action_code
+		WHERE run_id = (
+			SELECT run_id FROM action_code
+			WHERE workflow_id = ?
+			ORDER BY created_at DESC
+			LIMIT 1
+		)
+		ORDER BY sequence_id
+	`
+
+	rows, err := db.conn.QueryContext(ctx, query, workflowID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list all runs: %w", err)
+		return nil, fmt.Errorf("failed to get action code: %w", err)
 	}
 	defer rows.Close()
 
-	var runs []models.WorkflowRun
+	codes := make(map[int]string)
 	for rows.Next() {
-		var run models.WorkflowRun
-		var errorMessage sql.NullString
-		err := rows.Scan(
-			&run.ID,
-			&run.WorkflowID,
-			&run.TemporalRunID,
-			&run.TemporalWorkflowID,
-			&run.Status,
-			&run.ParametersJSON,
-			&run.StartedAt,
-			&run.CompletedAt,
-			&errorMessage,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan run: %w", err)
+		var sequenceID int
+		var code string
+		if err := rows.Scan(&sequenceID, &code); err != nil {
+			return nil, fmt.Errorf("failed to scan action code: %w", err)
 		}
-		if errorMessage.Valid {
-			run.ErrorMessage = errorMessage.String
+		codes[sequenceID] = code
+	}
+
+	return codes, nil
+}
+
+// ==================== Browser Sessions ====================
+
+// SaveBrowserSession creates or replaces a saved session with its
+// encrypted storage state
+func (db *DB) SaveBrowserSession(ctx context.Context, s *models.SavedSession, stateEncrypted string) error {
+	query := `
+		INSERT INTO browser_sessions (name, state_encrypted, workflow_id, run_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	` + db.dialect.upsert("name", "state_encrypted", "workflow_id", "run_id", "updated_at")
+
+	now := time.Now()
+	s.CreatedAt = now
+	s.UpdatedAt = now
+	_, err := db.conn.ExecContext(ctx, query, s.Name, stateEncrypted, s.WorkflowID, s.RunID, s.CreatedAt, s.UpdatedAt)
+	return err
+}
+
+// GetBrowserSessionState returns the encrypted storage state of a saved
+// session, or "" if there is none of that name
+func (db *DB) GetBrowserSessionState(ctx context.Context, name string) (string, error) {
+	query := `SELECT state_encrypted FROM browser_sessions WHERE name = ?`
+
+	var state string
+	err := db.conn.QueryRowContext(ctx, query, name).Scan(&state)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get browser session: %w", err)
+	}
+	return state, nil
+}
+
+// ListBrowserSessions lists the saved sessions without their state
+func (db *DB) ListBrowserSessions(ctx context.Context) ([]models.SavedSession, error) {
+	query := `
+		SELECT name, COALESCE(workflow_id, ''), COALESCE(run_id, ''), created_at, updated_at
+		FROM browser_sessions
+		ORDER BY name
+	`
+
+	rows, err := db.conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list browser sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []models.SavedSession
+	for rows.Next() {
+		var s models.SavedSession
+		if err := rows.Scan(&s.Name, &s.WorkflowID, &s.RunID, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan browser session: %w", err)
 		}
-		runs = append(runs, run)
+		sessions = append(sessions, s)
 	}
 
-	return runs, nil
+	return sessions, nil
 }
 
-// UpdateWorkflowRunStatus updates the status of a workflow run
-func (db *DB) UpdateWorkflowRunStatus(ctx context.Context, id string, status models.RunStatus, errorMsg string) error {
+// DeleteBrowserSession removes a saved session
+func (db *DB) DeleteBrowserSession(ctx context.Context, name string) error {
+	query := `DELETE FROM browser_sessions WHERE name = ?`
+	_, err := db.conn.ExecContext(ctx, query, name)
+	return err
+}
+
+// ==================== Schedules ====================
+
+// scheduleColumns are the columns scanned by scanSchedule
+const scheduleColumns = `id, workflow_id, COALESCE(cron, ''), interval_seconds, COALESCE(timezone, ''),
+	parameters, headless, COALESCE(llm_provider, ''), paused, created_at, updated_at`
+
+// CreateSchedule creates a schedule
+func (db *DB) CreateSchedule(ctx context.Context, s *models.Schedule) error {
 	query := `
-		UPDATE workflow_runs
-		SET status = ?, error_message = ?, 
-		    completed_at = CASE WHEN ? IN ('success', 'failed', 'canceled') THEN NOW() ELSE completed_at END
-		WHERE id = ?
+		INSERT INTO schedules (id, workflow_id, cron, interval_seconds, timezone, parameters, headless, llm_provider, paused, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := db.conn.ExecContext(ctx, query, status, errorMsg, status, id)
+	params, err := json.Marshal(s.Parameters)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	s.CreatedAt = now
+	s.UpdatedAt = now
+
+	_, err = db.conn.ExecContext(ctx, query, s.ID, s.WorkflowID, s.Cron, s.Interval, s.Timezone,
+		string(params), s.Headless, s.LLMProvider, s.Paused, s.CreatedAt, s.UpdatedAt)
 	return err
 }
 
-// UpdateWorkflowRunStarted updates a workflow run when it starts executing
-func (db *DB) UpdateWorkflowRunStarted(ctx context.Context, id, temporalWorkflowID, temporalRunID string) error {
+// UpdateSchedule replaces a schedule's timing, parameters and state
+func (db *DB) UpdateSchedule(ctx context.Context, s *models.Schedule) error {
 	query := `
-		UPDATE workflow_runs
-		SET temporal_workflow_id = ?, temporal_run_id = ?, status = 'running', started_at = NOW()
+		UPDATE schedules
+		SET cron = ?, interval_seconds = ?, timezone = ?, parameters = ?, headless = ?,
+		    llm_provider = ?, paused = ?, updated_at = ?
 		WHERE id = ?
 	`
 
-	_, err := db.conn.ExecContext(ctx, query, temporalWorkflowID, temporalRunID, id)
+	params, err := json.Marshal(s.Parameters)
+	if err != nil {
+		return err
+	}
+	s.UpdatedAt = time.Now()
+
+	_, err = db.conn.ExecContext(ctx, query, s.Cron, s.Interval, s.Timezone, string(params),
+		s.Headless, s.LLMProvider, s.Paused, s.UpdatedAt, s.ID)
+	return err
+}
+
+// GetSchedule retrieves a schedule by ID, or nil if there is none
+func (db *DB) GetSchedule(ctx context.Context, id string) (*models.Schedule, error) {
+	query := `SELECT ` + scheduleColumns + ` FROM schedules WHERE id = ?`
+
+	s, err := scanSchedule(db.conn.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedule: %w", err)
+	}
+	return s, nil
+}
+
+// ListSchedules lists all schedules
+func (db *DB) ListSchedules(ctx context.Context) ([]models.Schedule, error) {
+	query := `SELECT ` + scheduleColumns + ` FROM schedules ORDER BY created_at DESC`
+
+	rows, err := db.conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+	defer rows.Close()
+
+	var schedules []models.Schedule
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan schedule: %w", err)
+		}
+		schedules = append(schedules, *s)
+	}
+
+	return schedules, nil
+}
+
+// DeleteSchedule removes a schedule
+func (db *DB) DeleteSchedule(ctx context.Context, id string) error {
+	query := `DELETE FROM schedules WHERE id = ?`
+	_, err := db.conn.ExecContext(ctx, query, id)
+	return err
+}
+
+// scanSchedule scans a row of scheduleColumns
+func scanSchedule(row interface{ Scan(...interface{}) error }) (*models.Schedule, error) {
+	var s models.Schedule
+	var params sql.NullString
+	err := row.Scan(&s.ID, &s.WorkflowID, &s.Cron, &s.Interval, &s.Timezone,
+		&params, &s.Headless, &s.LLMProvider, &s.Paused, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if params.Valid && params.String != "" {
+		if err := json.Unmarshal([]byte(params.String), &s.Parameters); err != nil {
+			return nil, fmt.Errorf("failed to parse schedule parameters: %w", err)
+		}
+	}
+	return &s, nil
+}
+
+// ==================== Pipelines ====================
+
+// CreatePipeline creates a pipeline
+func (db *DB) CreatePipeline(ctx context.Context, p *models.Pipeline) error {
+	query := `
+		INSERT INTO pipelines (id, name, steps, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	steps, err := json.Marshal(p.Steps)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	p.CreatedAt = now
+	p.UpdatedAt = now
+
+	_, err = db.conn.ExecContext(ctx, query, p.ID, p.Name, string(steps), p.CreatedAt, p.UpdatedAt)
+	return err
+}
+
+// GetPipeline retrieves a pipeline by ID, or nil if there is none
+func (db *DB) GetPipeline(ctx context.Context, id string) (*models.Pipeline, error) {
+	query := `SELECT id, name, steps, created_at, updated_at FROM pipelines WHERE id = ?`
+
+	var p models.Pipeline
+	var steps string
+	err := db.conn.QueryRowContext(ctx, query, id).Scan(&p.ID, &p.Name, &steps, &p.CreatedAt, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pipeline: %w", err)
+	}
+	if err := json.Unmarshal([]byte(steps), &p.Steps); err != nil {
+		return nil, fmt.Errorf("failed to parse pipeline steps: %w", err)
+	}
+	return &p, nil
+}
+
+// ListPipelines lists all pipelines
+func (db *DB) ListPipelines(ctx context.Context) ([]models.Pipeline, error) {
+	query := `
+		SELECT id, name, steps, created_at, updated_at
+		FROM pipelines
+		ORDER BY created_at DESC
+	`
+
+	rows, err := db.conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pipelines: %w", err)
+	}
+	defer rows.Close()
+
+	var pipelines []models.Pipeline
+	for rows.Next() {
+		var p models.Pipeline
+		var steps string
+		if err := rows.Scan(&p.ID, &p.Name, &steps, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan pipeline: %w", err)
+		}
+		if err := json.Unmarshal([]byte(steps), &p.Steps); err != nil {
+			return nil, fmt.Errorf("failed to parse pipeline steps: %w", err)
+		}
+		pipelines = append(pipelines, p)
+	}
+
+	return pipelines, nil
+}
+
+// DeletePipeline removes a pipeline
+func (db *DB) DeletePipeline(ctx context.Context, id string) error {
+	query := `DELETE FROM pipelines WHERE id = ?`
+	_, err := db.conn.ExecContext(ctx, query, id)
+	return err
+}
+
+// ==================== Audit Events ====================
+
+// RecordAuditEvent appends an event to the audit log
+func (db *DB) RecordAuditEvent(ctx context.Context, e *models.AuditEvent) error {
+	query := `
+		INSERT INTO audit_events (actor, remote_addr, method, route, path, resource_id, status, request_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	e.CreatedAt = time.Now()
+	res, err := db.conn.ExecContext(ctx, query,
+		e.Actor, e.RemoteAddr, e.Method, e.Route, e.Path, e.ResourceID, e.Status, e.RequestID, e.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	e.ID, err = res.LastInsertId()
 	return err
 }
 
+// ListAuditEvents retrieves the audit events the filter selects, newest
+// first unless sorted otherwise, and how many it selects in all
+func (db *DB) ListAuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, int, error) {
+	where, args := listWhere(filter.ListOptions, "created_at")
+	for column, value := range map[string]string{"actor": filter.Actor, "resource_id": filter.ResourceID, "method": filter.Method} {
+		if value != "" {
+			where += " AND " + column + " = ?"
+			args = append(args, value)
+		}
+	}
+	order, limitArgs, err := listOrder(filter.ListOptions, "", models.AuditSorts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	if err := db.conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_events WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit events: %w", err)
+	}
+
+	query := `
+		SELECT id, actor, remote_addr, method, route, path, resource_id, status, request_id, created_at
+		FROM audit_events
+		WHERE ` + where + order
+
+	rows, err := db.conn.QueryContext(ctx, query, append(args, limitArgs...)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	for rows.Next() {
+		var e models.AuditEvent
+		if err := rows.Scan(&e.ID, &e.Actor, &e.RemoteAddr, &e.Method, &e.Route, &e.Path, &e.ResourceID, &e.Status, &e.RequestID, &e.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, total, rows.Err()
+}
+
 // ==================== Action Results ====================
 
 // CreateActionResult creates an action result
 func (db *DB) CreateActionResult(ctx context.Context, result *models.ActionResult) error {
 	query := `
-		INSERT INTO action_results (id, run_id, action_id, sequence_id, status, generated_code)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO action_results (id, run_id, action_id, sequence_id, iteration, status, generated_code)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
 	`
 
 	_, err := db.conn.ExecContext(ctx, query,
@@ -437,6 +2030,7 @@ func (db *DB) CreateActionResult(ctx context.Context, result *models.ActionResul
 		result.RunID,
 		result.ActionID,
 		result.SequenceID,
+		result.Iteration,
 		result.Status,
 		result.GeneratedCode,
 	)
@@ -449,10 +2043,25 @@ func (db *DB) UpdateActionResult(ctx context.Context, result *models.ActionResul
 	query := `
 		UPDATE action_results
 		SET status = ?, retry_count = ?, screenshot_path = ?, 
-		    error_message = ?, executed_at = ?, duration_ms = ?
+		    error_message = ?, executed_at = ?, duration_ms = ?,
+		    selector = ?, selector_fallbacks = ?,
+		    before_screenshot_path = ?, after_screenshot_path = ?,
+		    metrics = ?, dom_snapshot_path = ?, missing_outcomes = ?,
+		    selector_source = ?
 		WHERE id = ?
 	`
 
+	var metricsJSON sql.NullString
+	if result.Metrics != nil {
+		data, _ := json.Marshal(result.Metrics)
+		metricsJSON = sql.NullString{String: string(data), Valid: true}
+	}
+	var missingJSON sql.NullString
+	if len(result.MissingOutcomes) > 0 {
+		data, _ := json.Marshal(result.MissingOutcomes)
+		missingJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
 	_, err := db.conn.ExecContext(ctx, query,
 		result.Status,
 		result.RetryCount,
@@ -460,20 +2069,241 @@ func (db *DB) UpdateActionResult(ctx context.Context, result *models.ActionResul
 		result.ErrorMessage,
 		result.ExecutedAt,
 		result.Duration,
+		sql.NullString{String: result.Selector, Valid: result.Selector != ""},
+		result.SelectorFallbacks,
+		sql.NullString{String: result.BeforeScreenshotPath, Valid: result.BeforeScreenshotPath != ""},
+		sql.NullString{String: result.AfterScreenshotPath, Valid: result.AfterScreenshotPath != ""},
+		metricsJSON,
+		sql.NullString{String: result.DOMSnapshotPath, Valid: result.DOMSnapshotPath != ""},
+		missingJSON,
+		sql.NullString{String: string(result.SelectorSource), Valid: result.SelectorSource != ""},
 		result.ID,
 	)
 
 	return err
 }
 
+// RecordActionResults stores the results of a run's actions in place of the
+// ones stored for it before, so recording them again changes nothing
+func (db *DB) RecordActionResults(ctx context.Context, runID string, results []models.ActionResult) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM action_results WHERE run_id = ?`, runID); err != nil {
+		return fmt.Errorf("failed to delete results: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO action_results (id, run_id, action_id, sequence_id, iteration, status, retry_count,
+		    screenshot_path, generated_code, error_message, executed_at, duration_ms,
+		    selector, selector_fallbacks, before_screenshot_path, after_screenshot_path,
+		    metrics, dom_snapshot_path, missing_outcomes, selector_source)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, result := range results {
+		var metricsJSON sql.NullString
+		if result.Metrics != nil {
+			data, _ := json.Marshal(result.Metrics)
+			metricsJSON = sql.NullString{String: string(data), Valid: true}
+		}
+		var missingJSON sql.NullString
+		if len(result.MissingOutcomes) > 0 {
+			data, _ := json.Marshal(result.MissingOutcomes)
+			missingJSON = sql.NullString{String: string(data), Valid: true}
+		}
+
+		_, err := stmt.ExecContext(ctx,
+			result.ID,
+			runID,
+			result.ActionID,
+			result.SequenceID,
+			result.Iteration,
+			result.Status,
+			result.RetryCount,
+			result.ScreenshotPath,
+			result.GeneratedCode,
+			result.ErrorMessage,
+			result.ExecutedAt,
+			result.Duration,
+			sql.NullString{String: result.Selector, Valid: result.Selector != ""},
+			result.SelectorFallbacks,
+			sql.NullString{String: result.BeforeScreenshotPath, Valid: result.BeforeScreenshotPath != ""},
+			sql.NullString{String: result.AfterScreenshotPath, Valid: result.AfterScreenshotPath != ""},
+			metricsJSON,
+			sql.NullString{String: result.DOMSnapshotPath, Valid: result.DOMSnapshotPath != ""},
+			missingJSON,
+			sql.NullString{String: string(result.SelectorSource), Valid: result.SelectorSource != ""},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert result: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetWorkflowAnalytics aggregates the finished action results of a
+// workflow's runs by action, over the runs started in the last days when
+// days is positive
+func (db *DB) GetWorkflowAnalytics(ctx context.Context, workflowID string, days int) (*models.WorkflowAnalytics, error) {
+	window := ""
+	args := []interface{}{workflowID}
+	if days > 0 {
+		window = "AND wr.started_at > " + db.dialect.ago("DAY")
+		args = append(args, days)
+	}
+
+	analytics := &models.WorkflowAnalytics{WorkflowID: workflowID, Days: days, Actions: []models.ActionAnalytics{}}
+	runsQuery := `
+		SELECT COUNT(*)
+		FROM workflow_runs wr
+		WHERE wr.workflow_id = ? AND wr.status IN ('success', 'warning', 'failed') ` + window
+	if err := db.conn.QueryRowContext(ctx, runsQuery, args...).Scan(&analytics.Runs); err != nil {
+		return nil, fmt.Errorf("failed to count runs: %w", err)
+	}
+
+	query := `
+		SELECT ar.action_id, MAX(ar.sequence_id),
+		       COUNT(*), COUNT(DISTINCT ar.run_id), SUM(ar.status = 'failed'),
+		       COALESCE(AVG(ar.retry_count), 0), COALESCE(AVG(ar.selector_fallbacks), 0),
+		       AVG(ar.duration_ms), AVG(ar.duration_ms * ar.duration_ms) - AVG(ar.duration_ms) * AVG(ar.duration_ms),
+		       MIN(ar.duration_ms), MAX(ar.duration_ms)
+		FROM action_results ar
+		JOIN workflow_runs wr ON wr.id = ar.run_id
+		WHERE wr.workflow_id = ? AND ar.status IN ('success', 'failed') ` + window + `
+		GROUP BY ar.action_id
+		ORDER BY MAX(ar.sequence_id)
+	`
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get analytics: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a models.ActionAnalytics
+		var avgDuration, variance sql.NullFloat64
+		var minDuration, maxDuration sql.NullInt64
+		err := rows.Scan(
+			&a.ActionID,
+			&a.SequenceID,
+			&a.Executions,
+			&a.Runs,
+			&a.Failures,
+			&a.AvgRetries,
+			&a.AvgSelectorFallbacks,
+			&avgDuration,
+			&variance,
+			&minDuration,
+			&maxDuration,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan analytics: %w", err)
+		}
+		a.AvgDuration = avgDuration.Float64
+		// The population variance, computed as SQLite has no VAR_POP, can
+		// come out slightly below zero from rounding
+		a.DurationVariance = max(variance.Float64, 0)
+		a.DurationStdDev = math.Sqrt(a.DurationVariance)
+		a.MinDuration = minDuration.Int64
+		a.MaxDuration = maxDuration.Int64
+		a.FailureRate = float64(a.Failures) / float64(a.Executions)
+		a.Flaky = a.Failures > 0 && a.Failures < a.Executions
+		analytics.Actions = append(analytics.Actions, a)
+	}
+
+	return analytics, rows.Err()
+}
+
+// GetSelectorHealth counts the selectors that found each action's element
+// across a workflow's runs, over the runs started in the last days when
+// days is positive
+func (db *DB) GetSelectorHealth(ctx context.Context, workflowID string, days int) (*models.SelectorHealth, error) {
+	query := `
+		SELECT ar.action_id, MAX(ar.sequence_id), ar.selector, ar.selector_source, COUNT(*)
+		FROM action_results ar
+		JOIN workflow_runs wr ON wr.id = ar.run_id
+		WHERE wr.workflow_id = ? AND ar.status = 'success'
+		  AND ar.selector IS NOT NULL AND ar.selector_source IS NOT NULL
+	`
+	args := []interface{}{workflowID}
+	if days > 0 {
+		query += "  AND wr.started_at > " + db.dialect.ago("DAY") + "\n"
+		args = append(args, days)
+	}
+	query += `
+		GROUP BY ar.action_id, ar.selector, ar.selector_source
+		ORDER BY MAX(ar.sequence_id), COUNT(*) DESC
+	`
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get selector health: %w", err)
+	}
+	defer rows.Close()
+
+	health := &models.SelectorHealth{WorkflowID: workflowID, Days: days, Actions: []models.ActionSelectorHealth{}}
+	index := make(map[string]int)
+	for rows.Next() {
+		var actionID string
+		var sequenceID int
+		var usage models.SelectorUsage
+		if err := rows.Scan(&actionID, &sequenceID, &usage.Selector, &usage.Source, &usage.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan selector health: %w", err)
+		}
+		i, ok := index[actionID]
+		if !ok {
+			i = len(health.Actions)
+			index[actionID] = i
+			health.Actions = append(health.Actions, models.ActionSelectorHealth{ActionID: actionID, SequenceID: sequenceID})
+		}
+		action := &health.Actions[i]
+		if sequenceID > action.SequenceID {
+			action.SequenceID = sequenceID
+		}
+		action.Executions += usage.Count
+		switch usage.Source {
+		case models.SelectorPrimary:
+			action.Primary += usage.Count
+		case models.SelectorFallback:
+			action.Fallback += usage.Count
+		case models.SelectorHealed:
+			action.Healed += usage.Count
+		}
+		action.Selectors = append(action.Selectors, usage)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range health.Actions {
+		action := &health.Actions[i]
+		action.FallbackRate = float64(action.Fallback+action.Healed) / float64(action.Executions)
+		action.NeedsMaintenance = action.Executions >= models.SelectorMaintenanceRuns &&
+			action.FallbackRate >= models.SelectorMaintenanceRate
+	}
+	return health, nil
+}
+
 // GetActionResults retrieves action results for a run
 func (db *DB) GetActionResults(ctx context.Context, runID string) ([]models.ActionResult, error) {
 	query := `
 		SELECT id, run_id, action_id, sequence_id, status, retry_count,
-		       screenshot_path, generated_code, error_message, executed_at, duration_ms
+		       screenshot_path, generated_code, error_message, executed_at, duration_ms,
+		       selector, selector_fallbacks, iteration,
+		       before_screenshot_path, after_screenshot_path, metrics, dom_snapshot_path, missing_outcomes,
+		       selector_source
 		FROM action_results
 		WHERE run_id = ?
-		ORDER BY sequence_id
+		ORDER BY sequence_id, iteration
 	`
 
 	rows, err := db.conn.QueryContext(ctx, query, runID)
@@ -485,6 +2315,7 @@ func (db *DB) GetActionResults(ctx context.Context, runID string) ([]models.Acti
 	var results []models.ActionResult
 	for rows.Next() {
 		var result models.ActionResult
+		var selector, before, after, metricsJSON, snapshot, missingJSON, source sql.NullString
 		err := rows.Scan(
 			&result.ID,
 			&result.RunID,
@@ -497,10 +2328,30 @@ func (db *DB) GetActionResults(ctx context.Context, runID string) ([]models.Acti
 			&result.ErrorMessage,
 			&result.ExecutedAt,
 			&result.Duration,
+			&selector,
+			&result.SelectorFallbacks,
+			&result.Iteration,
+			&before,
+			&after,
+			&metricsJSON,
+			&snapshot,
+			&missingJSON,
+			&source,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan result: %w", err)
 		}
+		result.Selector = selector.String
+		result.BeforeScreenshotPath = before.String
+		result.AfterScreenshotPath = after.String
+		result.DOMSnapshotPath = snapshot.String
+		result.SelectorSource = models.SelectorSource(source.String)
+		if metricsJSON.Valid {
+			json.Unmarshal([]byte(metricsJSON.String), &result.Metrics)
+		}
+		if missingJSON.Valid {
+			json.Unmarshal([]byte(missingJSON.String), &result.MissingOutcomes)
+		}
 		results = append(results, result)
 	}
 
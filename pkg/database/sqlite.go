@@ -0,0 +1,94 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"modernc.org/sqlite"
+)
+
+// sqliteTimeFormat is how the driver writes times, set by _time_format=sqlite,
+// and how now and ago return them so they compare as text
+const sqliteTimeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+func init() {
+	// now() returns the current time, standing in for MySQL's NOW()
+	sqlite.MustRegisterScalarFunction("now", 0, func(_ *sqlite.FunctionContext, _ []driver.Value) (driver.Value, error) {
+		return time.Now().Format(sqliteTimeFormat), nil
+	})
+
+	// ago(n, unit) returns the time n SECONDs, MINUTEs or DAYs before now,
+	// standing in for MySQL's NOW() - INTERVAL n unit
+	sqlite.MustRegisterScalarFunction("ago", 2, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		n, ok := args[0].(int64)
+		if !ok {
+			return nil, fmt.Errorf("ago: count %v is not an integer", args[0])
+		}
+		var unit time.Duration
+		switch args[1] {
+		case "SECOND":
+			unit = time.Second
+		case "MINUTE":
+			unit = time.Minute
+		case "DAY":
+			unit = 24 * time.Hour
+		default:
+			return nil, fmt.Errorf("ago: unknown unit %v", args[1])
+		}
+		return time.Now().Add(-time.Duration(n) * unit).Format(sqliteTimeFormat), nil
+	})
+
+	// match_score(text, query) counts the times the words of the query
+	// appear in the text, standing in for MySQL's MATCH() AGAINST()
+	sqlite.MustRegisterScalarFunction("match_score", 2, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		text, _ := args[0].(string)
+		query, _ := args[1].(string)
+		return float64(matchScore(text, query)), nil
+	})
+}
+
+// matchScore counts the times the words of query appear in text, ignoring
+// case
+func matchScore(text, query string) int {
+	text = strings.ToLower(text)
+	score := 0
+	for _, word := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		score += strings.Count(text, word)
+	}
+	return score
+}
+
+// NewSQLite opens the SQLite database file at path, creating it if needed,
+// for running without a MySQL server
+func NewSQLite(path string) (*DB, error) {
+	// Transactions take the write lock as they begin, which is what the
+	// MySQL queries' FOR UPDATE locks stand for, and wait for one another
+	// rather than failing as busy
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() + "?" + url.Values{
+		"_pragma":      {"busy_timeout(5000)", "journal_mode(WAL)", "foreign_keys(1)"},
+		"_txlock":      {"immediate"},
+		"_time_format": {"sqlite"},
+	}.Encode()
+	conn, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := conn.PingContext(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	return &DB{conn: conn, dialect: sqliteDialect}, nil
+}
@@ -0,0 +1,646 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := NewSQLite(filepath.Join(t.TempDir(), "automator.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestSQLiteWorkflows(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	def := &models.WorkflowDefinition{ID: "wf-1", Name: "Login", EventsFilePath: "events.json", SemanticContext: "[]", ParametersJSON: "{}"}
+	if err := db.CreateWorkflowDefinition(ctx, def); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveGeneratedCode(ctx, def.ID, def.Revision, "go", "package main"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.GetWorkflowDefinition(ctx, def.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || got.Name != "Login" || got.GeneratedCode != "package main" {
+		t.Fatalf("GetWorkflowDefinition() = %+v, want the saved workflow", got)
+	}
+	if got.CreatedAt.IsZero() {
+		t.Error("CreatedAt wasn't read back as a time")
+	}
+
+	if err := db.DeleteWorkflowDefinition(ctx, def.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := db.GetWorkflowDefinition(ctx, def.ID); err != nil || got != nil {
+		t.Errorf("GetWorkflowDefinition() after delete = %+v, %v, want nil", got, err)
+	}
+}
+
+func TestSQLiteReserveWorkflowRun(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	def := &models.WorkflowDefinition{ID: "wf-1", Name: "Login", EventsFilePath: "events.json"}
+	if err := db.CreateWorkflowDefinition(ctx, def); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		runID      string
+		reserved   bool
+		wantActive int
+	}{
+		{"run-1", true, 0},
+		{"run-2", true, 1},
+		{"run-3", false, 2},
+	}
+	for _, tt := range tests {
+		run := &models.WorkflowRun{ID: tt.runID, WorkflowID: def.ID, ParametersJSON: "{}"}
+		reserved, active, err := db.ReserveWorkflowRun(ctx, run, 2, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reserved != tt.reserved || active != tt.wantActive {
+			t.Errorf("ReserveWorkflowRun(%s) = %v, %d, want %v, %d", tt.runID, reserved, active, tt.reserved, tt.wantActive)
+		}
+	}
+
+	// Runs started before run-2 count against it, run-2 itself doesn't
+	count, err := db.CountActiveRuns(ctx, def.ID, "run-2", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("CountActiveRuns(before run-2) = %d, want 1", count)
+	}
+
+	if err := db.UpdateWorkflowRunStatus(ctx, "run-1", models.RunStatus("success"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := db.CountActiveRuns(ctx, def.ID, "", time.Hour); err != nil || count != 1 {
+		t.Errorf("CountActiveRuns() after run-1 ended = %d, %v, want 1", count, err)
+	}
+}
+
+// A batch creates its runs pending and starts them a few at a time, so the
+// ones it hasn't started must not count against those waiting for a slot
+func TestSQLiteCountActiveRunsBatch(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	def := &models.WorkflowDefinition{ID: "wf-1", Name: "Login", EventsFilePath: "events.json"}
+	if err := db.CreateWorkflowDefinition(ctx, def); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"run-1", "run-2", "run-3", "run-4", "run-5"} {
+		run := &models.WorkflowRun{ID: id, WorkflowID: def.ID, Status: models.StatusPending, ParametersJSON: "{}"}
+		if err := db.CreateWorkflowRun(ctx, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const limit = 2
+	tests := []struct {
+		name   string
+		start  string // run the batch starts before counting
+		end    string // run that ends before counting
+		runID  string
+		active int
+	}{
+		{"first run", "run-1", "", "run-1", 0},
+		{"second run", "run-2", "", "run-2", 1},
+		{"third run waits", "run-3", "", "run-3", limit},
+		{"third run once the first ended", "", "run-1", "run-3", 1},
+		{"fourth run waits", "run-4", "", "run-4", limit},
+	}
+	for _, tt := range tests {
+		if tt.start != "" {
+			if err := db.UpdateWorkflowRunStarted(ctx, tt.start, "browser-automation-"+tt.start, ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if tt.end != "" {
+			if err := db.UpdateWorkflowRunStatus(ctx, tt.end, models.StatusSuccess, ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+		active, err := db.CountActiveRuns(ctx, def.ID, tt.runID, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if active != tt.active {
+			t.Errorf("%s: CountActiveRuns(before %s) = %d, want %d", tt.name, tt.runID, active, tt.active)
+		}
+	}
+}
+
+func TestSQLiteRecordActionResults(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	def := &models.WorkflowDefinition{ID: "wf-1", Name: "Login", EventsFilePath: "events.json"}
+	if err := db.CreateWorkflowDefinition(ctx, def); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateWorkflowRun(ctx, &models.WorkflowRun{ID: "run-1", WorkflowID: def.ID, Status: models.StatusPending, ParametersJSON: "{}"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The actions ran are no longer the workflow's, and the run was canceled
+	// before its last one
+	executedAt := time.Now().Truncate(time.Second)
+	results := []models.ActionResult{
+		{ID: "r1", ActionID: "a1", SequenceID: 1, Status: models.StatusSuccess, RetryCount: 1, ExecutedAt: &executedAt, Duration: 120,
+			Selector: "#email", SelectorSource: models.SelectorPrimary},
+		{ID: "r2", ActionID: "a2", SequenceID: 2, Iteration: 1, Status: models.StatusSuccess, Selector: "button", SelectorFallbacks: 1,
+			SelectorSource: models.SelectorFallback},
+		{ID: "r3", ActionID: "a2", SequenceID: 2, Iteration: 2, Status: models.StatusCanceled, ErrorMessage: "Workflow canceled"},
+	}
+	// Recording them again, as a retried activity does, keeps one of each
+	for range 2 {
+		if err := db.RecordActionResults(ctx, "run-1", results); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := db.GetActionResults(ctx, "run-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(results) {
+		t.Fatalf("GetActionResults() = %d results, want %d", len(got), len(results))
+	}
+	for i, want := range results {
+		g := got[i]
+		if g.ID != want.ID || g.Status != want.Status || g.Iteration != want.Iteration || g.RetryCount != want.RetryCount ||
+			g.Selector != want.Selector || g.SelectorSource != want.SelectorSource || g.SelectorFallbacks != want.SelectorFallbacks {
+			t.Errorf("result %d = %+v, want %+v", i+1, g, want)
+		}
+	}
+	if got[0].ExecutedAt == nil || !got[0].ExecutedAt.Equal(executedAt) {
+		t.Errorf("ExecutedAt = %v, want %v", got[0].ExecutedAt, executedAt)
+	}
+}
+
+func TestSQLiteReserveDomainAction(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	for i, want := range []bool{true, true, false, false} {
+		reserved, err := db.ReserveDomainAction(ctx, "example.com", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reserved != want {
+			t.Errorf("ReserveDomainAction() #%d = %v, want %v", i+1, reserved, want)
+		}
+	}
+	if reserved, err := db.ReserveDomainAction(ctx, "other.example", 2); err != nil || !reserved {
+		t.Errorf("ReserveDomainAction(other domain) = %v, %v, want true", reserved, err)
+	}
+}
+
+func TestSQLiteUpsert(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	for _, template := range []string{"first", "second"} {
+		if err := db.SaveCodeTemplate(ctx, &models.CodeTemplate{ActionType: "click", Template: template}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	templates, err := db.ListCodeTemplates(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != 1 || templates[0].Template != "second" {
+		t.Errorf("ListCodeTemplates() = %+v, want the second template only", templates)
+	}
+}
+
+func TestSQLiteListWorkflowRuns(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	for _, def := range []*models.WorkflowDefinition{
+		{ID: "wf-1", Name: "Vendor onboarding", EventsFilePath: "events.json"},
+		{ID: "wf-2", Name: "100% discount_check", EventsFilePath: "events.json"},
+	} {
+		if err := db.CreateWorkflowDefinition(ctx, def); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, run := range []struct{ id, workflowID, status string }{
+		{"run-1", "wf-1", "success"},
+		{"run-2", "wf-1", "failed"},
+		{"run-3", "wf-2", "success"},
+		{"run-4", "wf-1", "success"},
+	} {
+		reserved, _, err := db.ReserveWorkflowRun(ctx, &models.WorkflowRun{ID: run.id, WorkflowID: run.workflowID, ParametersJSON: "{}"}, 10, time.Hour)
+		if err != nil || !reserved {
+			t.Fatalf("ReserveWorkflowRun(%s) = %v, %v", run.id, reserved, err)
+		}
+		if err := db.UpdateWorkflowRunStatus(ctx, run.id, models.RunStatus(run.status), ""); err != nil {
+			t.Fatal(err)
+		}
+		// Runs start in order
+		started := time.Now().Add(time.Duration(i-10) * time.Minute)
+		if _, err := db.conn.Exec(`UPDATE workflow_runs SET started_at = ? WHERE id = ?`, started, run.id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		filter    models.RunFilter
+		want      []string
+		wantTotal int
+	}{
+		{"all", models.RunFilter{}, []string{"run-4", "run-3", "run-2", "run-1"}, 4},
+		{"page", models.RunFilter{ListOptions: models.ListOptions{Limit: 2, Offset: 1}}, []string{"run-3", "run-2"}, 4},
+		{"ascending", models.RunFilter{ListOptions: models.ListOptions{Asc: true, Limit: 1}}, []string{"run-1"}, 4},
+		{"workflow", models.RunFilter{WorkflowID: "wf-2"}, []string{"run-3"}, 1},
+		{"statuses", models.RunFilter{Statuses: []models.RunStatus{"failed", "canceled"}}, []string{"run-2"}, 1},
+		{"workflow name", models.RunFilter{WorkflowName: "ONBOARD"}, []string{"run-4", "run-2", "run-1"}, 3},
+		{"escaped workflow name", models.RunFilter{WorkflowName: "0% discount_"}, []string{"run-3"}, 1},
+		{"unmatched wildcard", models.RunFilter{WorkflowName: "vendor_"}, []string{}, 0},
+		{"since", models.RunFilter{ListOptions: models.ListOptions{Since: time.Now().Add(-8*time.Minute - 30*time.Second)}}, []string{"run-4", "run-3"}, 2},
+		{"until", models.RunFilter{ListOptions: models.ListOptions{Until: time.Now().UTC().Add(-8*time.Minute - 30*time.Second)}}, []string{"run-2", "run-1"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs, total, err := db.ListWorkflowRuns(ctx, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, run := range runs {
+				got = append(got, run.ID)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) || total != tt.wantTotal {
+				t.Errorf("ListWorkflowRuns() = %v, %d, want %v, %d", got, total, tt.want, tt.wantTotal)
+			}
+		})
+	}
+
+	workflows, total, err := db.ListWorkflowDefinitions(ctx, models.WorkflowFilter{
+		ListOptions: models.ListOptions{Sort: "name", Asc: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(workflows) != 2 || workflows[0].ID != "wf-2" {
+		t.Errorf("ListWorkflowDefinitions(by name) = %+v, %d, want wf-2 first of 2", workflows, total)
+	}
+	if _, _, err := db.ListWorkflowDefinitions(ctx, models.WorkflowFilter{ListOptions: models.ListOptions{Sort: "id; DROP"}}); err == nil {
+		t.Error("ListWorkflowDefinitions(unknown sort) succeeded, want an error")
+	}
+}
+
+func TestSQLiteSearchWorkflows(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	workflows := []struct {
+		def     *models.WorkflowDefinition
+		actions []models.SemanticAction
+	}{
+		{
+			&models.WorkflowDefinition{ID: "wf-1", Name: "Supplier setup", StartURL: "https://erp.example.com/vendors/new", EventsFilePath: "events.json"},
+			[]models.SemanticAction{
+				{ID: "a-1", SequenceID: 1, ActionType: "input", Target: models.SemanticTarget{Selector: "#vendor-name"}, Value: "Acme"},
+				{ID: "a-2", SequenceID: 2, ActionType: "click", Target: models.SemanticTarget{Text: "Submit onboarding form", Selector: "button.submit"}},
+			},
+		},
+		{
+			&models.WorkflowDefinition{ID: "wf-2", Name: "Vendor report", EventsFilePath: "events.json"},
+			[]models.SemanticAction{
+				{ID: "a-3", SequenceID: 1, ActionType: "click", Target: models.SemanticTarget{Text: "Export"}},
+			},
+		},
+		{
+			&models.WorkflowDefinition{ID: "wf-3", Name: "Login", EventsFilePath: "events.json"},
+			nil,
+		},
+	}
+	for _, wf := range workflows {
+		if err := db.CreateWorkflowDefinition(ctx, wf.def); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.CreateSemanticActions(ctx, wf.def.ID, wf.actions); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := db.SearchWorkflows(ctx, "Vendor onboarding FORM", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].WorkflowID != "wf-1" || results[1].WorkflowID != "wf-2" {
+		t.Fatalf("SearchWorkflows() = %+v, want wf-1 then wf-2", results)
+	}
+	var matched []int
+	for _, action := range results[0].Actions {
+		matched = append(matched, action.SequenceID)
+	}
+	if fmt.Sprint(matched) != "[1 2]" {
+		t.Errorf("matched actions of wf-1 = %v, want [1 2]", matched)
+	}
+	if len(results[1].Actions) != 0 {
+		t.Errorf("matched actions of wf-2 = %+v, want none, only its name matched", results[1].Actions)
+	}
+
+	if results, err := db.SearchWorkflows(ctx, "payroll", 10); err != nil || len(results) != 0 {
+		t.Errorf("SearchWorkflows(payroll) = %+v, %v, want nothing", results, err)
+	}
+}
+
+func TestSQLiteWorkflowTags(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	for _, id := range []string{"wf-1", "wf-2", "wf-3"} {
+		if err := db.CreateWorkflowDefinition(ctx, &models.WorkflowDefinition{ID: id, Name: id, EventsFilePath: "events.json"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for id, tags := range map[string][]string{"wf-1": {"finance", "erp"}, "wf-2": {"finance"}} {
+		if err := db.AddWorkflowTags(ctx, id, tags); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Adding a tag again keeps it once
+	if err := db.AddWorkflowTags(ctx, "wf-1", []string{"erp"}); err != nil {
+		t.Fatal(err)
+	}
+
+	def, err := db.GetWorkflowDefinition(ctx, "wf-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(def.Tags) != "[erp finance]" {
+		t.Errorf("tags of wf-1 = %v, want [erp finance]", def.Tags)
+	}
+
+	tests := []struct {
+		tags []string
+		want string
+	}{
+		{nil, "[wf-1:[erp finance] wf-2:[finance] wf-3:[]]"},
+		{[]string{"finance"}, "[wf-1:[erp finance] wf-2:[finance]]"},
+		{[]string{"finance", "erp"}, "[wf-1:[erp finance]]"},
+		{[]string{"hr"}, "[]"},
+	}
+	for _, tt := range tests {
+		workflows, _, err := db.ListWorkflowDefinitions(ctx, models.WorkflowFilter{
+			ListOptions: models.ListOptions{Sort: "name", Asc: true},
+			Tags:        tt.tags,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, def := range workflows {
+			got = append(got, fmt.Sprintf("%s:%v", def.ID, def.Tags))
+		}
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("ListWorkflowDefinitions(tags %v) = %v, want %s", tt.tags, got, tt.want)
+		}
+	}
+
+	tags, err := db.ListTags(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(tags) != "[{erp 1} {finance 2}]" {
+		t.Errorf("ListTags() = %v, want erp on 1 and finance on 2", tags)
+	}
+
+	for _, want := range []bool{true, false} {
+		if removed, err := db.RemoveWorkflowTag(ctx, "wf-2", "finance"); err != nil || removed != want {
+			t.Errorf("RemoveWorkflowTag() = %v, %v, want %v", removed, err, want)
+		}
+	}
+}
+
+func TestSQLiteTrash(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	for _, def := range []*models.WorkflowDefinition{
+		{ID: "wf-1", Name: "Login", EventsFilePath: "events.json"},
+		{ID: "wf-2", Name: "Login (copy)", EventsFilePath: "events.json"},
+	} {
+		if err := db.CreateWorkflowDefinition(ctx, def); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, run := range []*models.WorkflowRun{
+		{ID: "run-1", WorkflowID: "wf-1", ParametersJSON: "{}"},
+		{ID: "run-2", WorkflowID: "wf-2", ParametersJSON: "{}"},
+		{ID: "run-3", WorkflowID: "wf-2", ParametersJSON: "{}"},
+	} {
+		if reserved, _, err := db.ReserveWorkflowRun(ctx, run, 10, time.Hour); err != nil || !reserved {
+			t.Fatalf("ReserveWorkflowRun(%s) = %v, %v", run.ID, reserved, err)
+		}
+	}
+	if err := db.CreateSemanticActions(ctx, "wf-1", []models.SemanticAction{{ID: "a1", SequenceID: 1, ActionType: models.ActionClick}}); err != nil {
+		t.Fatal(err)
+	}
+
+	listed := func(filter models.RunFilter) string {
+		t.Helper()
+		runs, _, err := db.ListWorkflowRuns(ctx, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, run := range runs {
+			ids = append(ids, run.ID)
+		}
+		return fmt.Sprint(ids)
+	}
+
+	if trashed, err := db.TrashWorkflowDefinition(ctx, "wf-1"); err != nil || !trashed {
+		t.Fatalf("TrashWorkflowDefinition() = %v, %v, want true", trashed, err)
+	}
+	if trashed, err := db.TrashWorkflowDefinition(ctx, "wf-1"); err != nil || trashed {
+		t.Errorf("TrashWorkflowDefinition() twice = %v, %v, want false", trashed, err)
+	}
+	if trashed, err := db.TrashWorkflowRun(ctx, "run-2"); err != nil || !trashed {
+		t.Fatalf("TrashWorkflowRun() = %v, %v, want true", trashed, err)
+	}
+
+	if got, err := db.GetWorkflowDefinition(ctx, "wf-1"); err != nil || got != nil {
+		t.Errorf("GetWorkflowDefinition(trashed) = %+v, %v, want nil", got, err)
+	}
+	if got, err := db.GetWorkflowRun(ctx, "run-2"); err != nil || got != nil {
+		t.Errorf("GetWorkflowRun(trashed) = %+v, %v, want nil", got, err)
+	}
+	workflows, _, err := db.ListWorkflowDefinitions(ctx, models.WorkflowFilter{ListOptions: models.ListOptions{Deleted: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(workflows) != 1 || workflows[0].ID != "wf-1" || workflows[0].DeletedAt == nil {
+		t.Errorf("ListWorkflowDefinitions(deleted) = %+v, want wf-1 with when it was deleted", workflows)
+	}
+	// The runs of a trashed workflow go with it
+	if got := listed(models.RunFilter{}); got != "[run-3]" {
+		t.Errorf("ListWorkflowRuns() = %s, want [run-3]", got)
+	}
+	if got := listed(models.RunFilter{ListOptions: models.ListOptions{Deleted: true}}); got != "[run-2]" {
+		t.Errorf("ListWorkflowRuns(deleted) = %s, want [run-2]", got)
+	}
+
+	if restored, err := db.RestoreWorkflowRun(ctx, "run-2"); err != nil || !restored {
+		t.Fatalf("RestoreWorkflowRun() = %v, %v, want true", restored, err)
+	}
+	if restored, err := db.RestoreWorkflowRun(ctx, "run-3"); err != nil || restored {
+		t.Errorf("RestoreWorkflowRun(not trashed) = %v, %v, want false", restored, err)
+	}
+
+	// Only what was trashed before the cutoff is purged
+	if defs, err := db.TrashedWorkflows(ctx, time.Now().Add(-time.Hour), 10); err != nil || len(defs) != 0 {
+		t.Errorf("TrashedWorkflows(an hour ago) = %+v, %v, want none", defs, err)
+	}
+	defs, err := db.TrashedWorkflows(ctx, time.Now().Add(time.Second), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(defs) != 1 || defs[0].ID != "wf-1" || defs[0].EventsFilePath != "events.json" {
+		t.Fatalf("TrashedWorkflows() = %+v, want wf-1 with its events file", defs)
+	}
+	if shared, err := db.EventsFileInUse(ctx, "events.json", "wf-1"); err != nil || !shared {
+		t.Errorf("EventsFileInUse() = %v, %v, want the copy's", shared, err)
+	}
+	if ids, err := db.WorkflowRunIDs(ctx, "wf-1"); err != nil || fmt.Sprint(ids) != "[run-1]" {
+		t.Errorf("WorkflowRunIDs() = %v, %v, want [run-1]", ids, err)
+	}
+
+	if err := db.DeleteWorkflowDefinition(ctx, "wf-1"); err != nil {
+		t.Fatal(err)
+	}
+	var actions, runs int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM semantic_actions WHERE workflow_id = 'wf-1'`).Scan(&actions); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM workflow_runs WHERE workflow_id = 'wf-1'`).Scan(&runs); err != nil {
+		t.Fatal(err)
+	}
+	if actions != 0 || runs != 0 {
+		t.Errorf("purged workflow left %d actions and %d runs", actions, runs)
+	}
+}
+
+func TestSQLiteWorkflowRevision(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	def := &models.WorkflowDefinition{ID: "wf-1", Name: "Login", EventsFilePath: "events.json"}
+	if err := db.CreateWorkflowDefinition(ctx, def); err != nil {
+		t.Fatal(err)
+	}
+	stale, err := db.GetWorkflowDefinition(ctx, def.ID)
+	if err != nil || stale.Revision != 1 {
+		t.Fatalf("GetWorkflowDefinition() = %+v, %v, want revision 1", stale, err)
+	}
+
+	def.Name = "Sign in"
+	if err := db.UpdateWorkflowDefinition(ctx, def); err != nil || def.Revision != 2 {
+		t.Fatalf("UpdateWorkflowDefinition() = %v with revision %d, want revision 2", err, def.Revision)
+	}
+	stale.Name = "Log in"
+	if err := db.UpdateWorkflowDefinition(ctx, stale); !errors.Is(err, ErrConflict) {
+		t.Errorf("UpdateWorkflowDefinition(stale) = %v, want ErrConflict", err)
+	}
+
+	actions := []models.SemanticAction{{ID: "a1", SequenceID: 1, ActionType: models.ActionClick}}
+	if err := db.ReplaceSemanticActions(ctx, def.ID, 1, actions, "[]"); !errors.Is(err, ErrConflict) {
+		t.Errorf("ReplaceSemanticActions(stale) = %v, want ErrConflict", err)
+	}
+	if err := db.ReplaceSemanticActions(ctx, def.ID, 2, actions, "[]"); err != nil {
+		t.Fatal(err)
+	}
+	// Code generated from the actions before they were replaced is stale
+	if err := db.SaveGeneratedCode(ctx, def.ID, 2, "script", "package main"); !errors.Is(err, ErrConflict) {
+		t.Errorf("SaveGeneratedCode(stale) = %v, want ErrConflict", err)
+	}
+	for range 2 {
+		if err := db.SaveGeneratedCode(ctx, def.ID, 3, "script", "package main"); err != nil {
+			t.Fatalf("SaveGeneratedCode() = %v", err)
+		}
+	}
+
+	got, err := db.GetWorkflowDefinition(ctx, def.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Sign in" || got.Revision != 3 || got.GeneratedCode != "package main" {
+		t.Errorf("GetWorkflowDefinition() = %+v, want Sign in at revision 3 with its code", got)
+	}
+}
+
+func TestSQLiteAuditEvents(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	for _, e := range []*models.AuditEvent{
+		{Actor: "ada", Method: "POST", Route: "/api/workflows", Path: "/api/workflows", ResourceID: "wf-1", Status: 200},
+		{Actor: "bob", Method: "PUT", Route: "/api/workflows/{id}", Path: "/api/workflows/wf-1", ResourceID: "wf-1", Status: 409},
+		{Actor: "ada", Method: "DELETE", Route: "/api/workflows/{id}", Path: "/api/workflows/wf-2", ResourceID: "wf-2", Status: 204},
+	} {
+		if err := db.RecordAuditEvent(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+		if e.ID == 0 || e.CreatedAt.IsZero() {
+			t.Errorf("RecordAuditEvent() left %+v without an ID or time", e)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		filter    models.AuditFilter
+		want      string
+		wantTotal int
+	}{
+		{"all", models.AuditFilter{}, "[3 2 1]", 3},
+		{"page", models.AuditFilter{ListOptions: models.ListOptions{Limit: 1, Offset: 1}}, "[2]", 3},
+		{"actor", models.AuditFilter{Actor: "ada", ListOptions: models.ListOptions{Asc: true}}, "[1 3]", 2},
+		{"resource and method", models.AuditFilter{ResourceID: "wf-1", Method: "PUT"}, "[2]", 1},
+		{"since", models.AuditFilter{ListOptions: models.ListOptions{Since: time.Now().Add(time.Minute)}}, "[]", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, total, err := db.ListAuditEvents(ctx, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := []int64{}
+			for _, e := range events {
+				ids = append(ids, e.ID)
+			}
+			if fmt.Sprint(ids) != tt.want || total != tt.wantTotal {
+				t.Errorf("ListAuditEvents() = %v, %d, want %s, %d", ids, total, tt.want, tt.wantTotal)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// Package health reports whether a service's dependencies are reachable,
+// for orchestrators and load balancers deciding where to send traffic
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds each check of a readiness request
+const DefaultTimeout = 3 * time.Second
+
+// Check is a dependency's check, which returns an error while it's down.
+// A service without an optional dependency is degraded but still ready.
+type Check struct {
+	Name     string
+	Optional bool
+	Check    func(ctx context.Context) error
+}
+
+// Statuses of a dependency
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Statuses of a service
+const (
+	StatusOK          = "ok"          // all dependencies are up
+	StatusDegraded    = "degraded"    // optional dependencies are down
+	StatusUnavailable = "unavailable" // required dependencies are down
+)
+
+// Result is the outcome of a dependency's check
+type Result struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Optional  bool   `json:"optional,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Report is the status of a service and its dependencies
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Run runs the checks at once, each for at most timeout
+func Run(ctx context.Context, timeout time.Duration, checks []Check) Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c Check) {
+			defer wg.Done()
+			result := run(ctx, timeout, c)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[c.Name] = result
+			switch {
+			case result.Status == StatusUp:
+			case !c.Optional:
+				report.Status = StatusUnavailable
+			case report.Status == StatusOK:
+				report.Status = StatusDegraded
+			}
+		}(c)
+	}
+	wg.Wait()
+	return report
+}
+
+func run(ctx context.Context, timeout time.Duration, c Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// A check ignoring its context still can't hold up the report
+	done := make(chan error, 1)
+	start := time.Now()
+	go func() { done <- c.Check(ctx) }()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("no response within %s", timeout)
+	}
+
+	result := Result{
+		Status:    StatusUp,
+		LatencyMS: time.Since(start).Milliseconds(),
+		Optional:  c.Optional,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Handler serves the report of the checks, with 503 Service Unavailable
+// while a required dependency is down
+func Handler(timeout time.Duration, checks ...Check) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := Run(r.Context(), timeout, checks)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if report.Status == StatusUnavailable {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	})
+}
+
+// HTTP checks that url answers a GET with a 2xx status
+func HTTP(client *http.Client, url string) func(ctx context.Context) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("%s returned %s", url, resp.Status)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,100 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func up(context.Context) error { return nil }
+
+func down(context.Context) error { return errors.New("connection refused") }
+
+func hang(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     []Check
+		wantCode   int
+		wantStatus string
+		wantDown   []string
+	}{
+		{
+			name:       "all up",
+			checks:     []Check{{Name: "mysql", Check: up}, {Name: "ollama", Optional: true, Check: up}},
+			wantCode:   http.StatusOK,
+			wantStatus: StatusOK,
+		},
+		{
+			name:       "optional down",
+			checks:     []Check{{Name: "mysql", Check: up}, {Name: "ollama", Optional: true, Check: down}},
+			wantCode:   http.StatusOK,
+			wantStatus: StatusDegraded,
+			wantDown:   []string{"ollama"},
+		},
+		{
+			name:       "required down",
+			checks:     []Check{{Name: "mysql", Check: down}, {Name: "ollama", Optional: true, Check: down}},
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: StatusUnavailable,
+			wantDown:   []string{"mysql", "ollama"},
+		},
+		{
+			name:       "timed out",
+			checks:     []Check{{Name: "temporal", Check: hang}},
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: StatusUnavailable,
+			wantDown:   []string{"temporal"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			Handler(50*time.Millisecond, tt.checks...).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status code = %d, want %d", rec.Code, tt.wantCode)
+			}
+			var report Report
+			if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+				t.Fatalf("decoding report: %v", err)
+			}
+			if report.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", report.Status, tt.wantStatus)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Errorf("got %d checks, want %d", len(report.Checks), len(tt.checks))
+			}
+			for _, name := range tt.wantDown {
+				if result := report.Checks[name]; result.Status != StatusDown || result.Error == "" {
+					t.Errorf("%s = %+v, want down with an error", name, result)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	if err := HTTP(nil, server.URL+"/api/tags")(context.Background()); err != nil {
+		t.Errorf("HTTP() of a live endpoint = %v", err)
+	}
+	if err := HTTP(nil, server.URL+"/missing")(context.Background()); err == nil {
+		t.Error("HTTP() of a 404 succeeded")
+	}
+}
@@ -10,6 +10,14 @@ import (
 	"dev/bravebird/browser-automation-go/pkg/models"
 )
 
+const (
+	// contextWindowMs bounds how long after an action DOM additions are still
+	// attributed to it
+	contextWindowMs = 2000
+	// maxContextTargets caps the number of appeared elements kept per action
+	maxContextTargets = 5
+)
+
 // HybridParser parses hybrid_events.json files containing both rrweb and custom events
 type HybridParser struct {
 	events       []models.HybridEvent
@@ -194,6 +202,10 @@ func (p *HybridParser) ExtractSemanticActions() []models.SemanticAction {
 
 			// Handle Incremental events
 			if intEventType == models.RRWebEventIncremental {
+				if len(actions) > 0 {
+					p.attachMutationContext(&actions[len(actions)-1], event)
+				}
+
 				action := p.rrwebIncrementalToAction(event, &sequenceID)
 				if action != nil {
 					actions = append(actions, *action)
@@ -465,6 +477,62 @@ func (p *HybridParser) customEventToAction(event models.HybridEvent, eventType s
 	return action
 }
 
+// attachMutationContext records elements with visible text that a DOM mutation
+// added shortly after the action, so they can be asserted on during replay
+func (p *HybridParser) attachMutationContext(action *models.SemanticAction, event models.HybridEvent) {
+	if action.ActionType == models.ActionNavigate || len(action.Context) >= maxContextTargets {
+		return
+	}
+	if event.Timestamp-action.Timestamp > contextWindowMs {
+		return
+	}
+
+	var incr models.RRWebIncrementalData
+	if err := json.Unmarshal(event.Data, &incr); err != nil || incr.Source != models.SourceMutation {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, c := range action.Context {
+		seen[c.Tag+"|"+c.Text] = true
+	}
+
+	var visit func(node *models.SerializedNode, parentID int)
+	visit = func(node *models.SerializedNode, parentID int) {
+		if node == nil || len(action.Context) >= maxContextTargets {
+			return
+		}
+		if node.Type == 3 {
+			text := strings.Join(strings.Fields(node.TextContent), " ")
+			parent := p.GetNode(parentID)
+			if text == "" || parent == nil || parent.TagName == "" {
+				return
+			}
+			switch strings.ToLower(parent.TagName) {
+			case "script", "style", "noscript", "title":
+				return
+			}
+			target := models.SemanticTarget{
+				Tag:    parent.TagName,
+				Text:   truncateText(text, 100),
+				NodeID: parentID,
+			}
+			if key := target.Tag + "|" + target.Text; !seen[key] {
+				seen[key] = true
+				action.Context = append(action.Context, target)
+			}
+			return
+		}
+		for _, child := range node.ChildNodes {
+			visit(child, node.ID)
+		}
+	}
+
+	for _, add := range incr.Adds {
+		visit(add.Node, add.ParentID)
+	}
+}
+
 // formatKeyCombo formats a keyboard shortcut
 func (p *HybridParser) formatKeyCombo(event models.HybridEvent) string {
 	var parts []string
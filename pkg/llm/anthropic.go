@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"dev/bravebird/browser-automation-go/pkg/models"
+	"dev/bravebird/browser-automation-go/pkg/telemetry"
 )
 
 // AnthropicProvider implements the Provider interface for Anthropic Claude
@@ -39,7 +40,8 @@ func NewAnthropicProvider(config Config) *AnthropicProvider {
 	return &AnthropicProvider{
 		config: config,
 		httpClient: &http.Client{
-			Timeout: time.Duration(config.Timeout) * time.Second,
+			Timeout:   time.Duration(config.Timeout) * time.Second,
+			Transport: telemetry.Transport(nil),
 		},
 	}
 }
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"dev/bravebird/browser-automation-go/pkg/models"
+	"dev/bravebird/browser-automation-go/pkg/telemetry"
 )
 
 // GeminiProvider implements the Provider interface for Google Gemini
@@ -39,7 +40,8 @@ func NewGeminiProvider(config Config) *GeminiProvider {
 	return &GeminiProvider{
 		config: config,
 		httpClient: &http.Client{
-			Timeout: time.Duration(config.Timeout) * time.Second,
+			Timeout:   time.Duration(config.Timeout) * time.Second,
+			Transport: telemetry.Transport(nil),
 		},
 	}
 }
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"dev/bravebird/browser-automation-go/pkg/models"
+	"dev/bravebird/browser-automation-go/pkg/telemetry"
 )
 
 // OllamaProvider implements the Provider interface for Ollama
@@ -37,7 +38,8 @@ func NewOllamaProvider(config Config) *OllamaProvider {
 	return &OllamaProvider{
 		config: config,
 		httpClient: &http.Client{
-			Timeout: time.Duration(config.Timeout) * time.Second,
+			Timeout:   time.Duration(config.Timeout) * time.Second,
+			Transport: telemetry.Transport(nil),
 		},
 	}
 }
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"dev/bravebird/browser-automation-go/pkg/models"
+	"dev/bravebird/browser-automation-go/pkg/telemetry"
 )
 
 // OpenAIProvider implements the Provider interface for OpenAI
@@ -36,7 +37,8 @@ func NewOpenAIProvider(config Config) *OpenAIProvider {
 	return &OpenAIProvider{
 		config: config,
 		httpClient: &http.Client{
-			Timeout: time.Duration(config.Timeout) * time.Second,
+			Timeout:   time.Duration(config.Timeout) * time.Second,
+			Transport: telemetry.Transport(nil),
 		},
 	}
 }
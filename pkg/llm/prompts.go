@@ -208,109 +208,66 @@ func BuildWorkflowPrompt(actions []models.SemanticAction, params []models.Workfl
 	return fmt.Sprintf(WorkflowPrompt, string(paramsJSON), string(actionsJSON))
 }
 
+// The code templates below are text/template sources rendered with an
+// ActionTemplateData. They are the defaults of a TemplateSet and can be
+// overridden per action type.
+
 // NavigateTemplate returns Go code template for navigation
-const NavigateTemplate = `// Navigate to %s
-page.MustNavigate(%s).MustWaitLoad()
+const NavigateTemplate = `// Navigate to {{.Description}}
+page.MustNavigate({{.Value}}).MustWaitLoad()
 `
 
 // ClickTemplate returns Go code template for clicking
-const ClickTemplate = `// Click %s
-page.MustElement(%s).MustWaitVisible().MustClick()
+const ClickTemplate = `// Click {{.Description}}
+page.MustElement({{.Selector}}).MustWaitVisible().MustClick()
 `
 
 // InputTemplate returns Go code template for input
-const InputTemplate = `// Input into %s
-elem := page.MustElement(%s).MustWaitVisible()
-elem.MustSelectAllText().MustInput(%s)
+const InputTemplate = `// Input into {{.Description}}
+elem := page.MustElement({{.Selector}}).MustWaitVisible()
+elem.MustSelectAllText().MustInput({{.Value}})
 `
 
 // KeypressTemplate returns Go code template for keypress
-const KeypressTemplate = `// Press %s key
-page.Keyboard.MustType(input.%s)
+const KeypressTemplate = `// Press {{.Description}} key
+page.Keyboard.MustType(input.{{.Key}})
 `
 
 // DblClickTemplate returns Go code template for double click
-const DblClickTemplate = `// Double click %s
-page.MustElement(%s).MustWaitVisible().MustAny("dblclick")
+const DblClickTemplate = `// Double click {{.Description}}
+page.MustElement({{.Selector}}).MustWaitVisible().MustAny("dblclick")
 `
 
 // RightClickTemplate returns Go code template for right click
-const RightClickTemplate = `// Right click %s
-page.MustElement(%s).MustWaitVisible().MustClick("right")
+const RightClickTemplate = `// Right click {{.Description}}
+page.MustElement({{.Selector}}).MustWaitVisible().MustClick("right")
 `
 
 // SelectTemplate returns Go code template for select text
-const SelectTemplate = `// Select text %s
-page.MustElement(%s).MustWaitVisible().MustSelectAllText()
+const SelectTemplate = `// Select text {{.Description}}
+page.MustElement({{.Selector}}).MustWaitVisible().MustSelectAllText()
 `
 
 // ScrollTemplate returns Go code template for scrolling
-const ScrollTemplate = `// Scroll %s
-page.MustElement(%s).MustWaitVisible().MustScrollIntoView()
+const ScrollTemplate = `// Scroll {{.Description}}
+page.MustElement({{.Selector}}).MustWaitVisible().MustScrollIntoView()
 `
 
-// GenerateCodeFromAction generates simple Go code from an action without LLM
-// This is a fallback when LLM is not available
-func GenerateCodeFromAction(action models.SemanticAction, variables map[string]string) string {
-	selector := fmt.Sprintf("%q", action.Target.Selector)
-	value := action.Value
-
-	// Check if value should be replaced with a variable
-	for varName, varValue := range variables {
-		if value == varValue {
-			value = varName
-			break
-		}
-	}
-	if action.ActionType == models.ActionInput && value == action.Value {
-		value = fmt.Sprintf("%q", value)
-	}
-
-	switch action.ActionType {
-	case models.ActionNavigate:
-		url := action.Value
-		if urlVar, ok := variables[action.Value]; ok {
-			return fmt.Sprintf(NavigateTemplate, action.Value, urlVar)
-		}
-		return fmt.Sprintf(NavigateTemplate, action.Value, fmt.Sprintf("%q", url))
-
-	case models.ActionClick:
-		desc := action.Target.Text
-		if desc == "" {
-			desc = action.Target.Selector
-		}
-		return fmt.Sprintf(ClickTemplate, desc, selector)
-
-	case models.ActionInput:
-		desc := action.Target.Selector
-		return fmt.Sprintf(InputTemplate, desc, selector, value)
+// FocusTemplate returns Go code template for focusing
+const FocusTemplate = `// Focus {{.Description}}
+page.MustElement({{.Selector}}).MustWaitVisible().MustFocus()`
 
-	case models.ActionKeypress:
-		key := action.Value
-		if key == "Enter" {
-			return fmt.Sprintf(KeypressTemplate, key, "Enter")
-		}
-		return fmt.Sprintf(KeypressTemplate, key, key)
+// BlurTemplate returns Go code template for blurring
+const BlurTemplate = `// Blur {{.Description}}
+page.MustElement({{.Selector}}).MustWaitVisible().MustBlur()`
 
-	case models.ActionDblClick:
-		return fmt.Sprintf(DblClickTemplate, action.Target.Selector, selector)
+// ExtractTemplate returns Go code template for reading an element into a
+// run output
+const ExtractTemplate = `// Extract {{.Description}}
+{{if .Attribute}}outputs[{{.Output}}] = *page.MustElement({{.Selector}}).MustWaitVisible().MustAttribute({{.Attribute}}){{else}}outputs[{{.Output}}] = page.MustElement({{.Selector}}).MustWaitVisible().MustText(){{end}}`
 
-	case models.ActionRightClick:
-		return fmt.Sprintf(RightClickTemplate, action.Target.Selector, selector)
-
-	case models.ActionSelect:
-		return fmt.Sprintf(SelectTemplate, action.Value, selector)
-
-	case models.ActionScroll:
-		return fmt.Sprintf(ScrollTemplate, action.Target.Selector, selector)
-
-	case models.ActionFocus:
-		return fmt.Sprintf("// Focus %s\npage.MustElement(%s).MustWaitVisible().MustFocus()", action.Target.Selector, selector)
-
-	case models.ActionBlur:
-		return fmt.Sprintf("// Blur %s\npage.MustElement(%s).MustWaitVisible().MustBlur()", action.Target.Selector, selector)
-
-	default:
-		return fmt.Sprintf("// Unsupported action type: %s\n", action.ActionType)
-	}
+// GenerateCodeFromAction generates simple Go code from an action without LLM
+// This is a fallback when LLM is not available
+func GenerateCodeFromAction(action models.SemanticAction, variables map[string]string) string {
+	return defaultTemplates.Generate(action, variables)
 }
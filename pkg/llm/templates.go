@@ -0,0 +1,234 @@
+package llm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// TemplateExt is the file extension of code templates in a template directory
+const TemplateExt = ".tmpl"
+
+// ActionTemplateData is the data a code template is rendered with
+type ActionTemplateData struct {
+	Action      models.SemanticAction // the action being generated
+	Description string                // human readable target for the leading comment
+	Selector    string                // quoted selector, a Go string literal
+	Value       string                // Go expression for the value, a literal or a variable
+	Key         string                // key name for keypress actions, e.g. Enter
+	Output      string                // quoted run output an extract action reads into
+	Attribute   string                // quoted attribute an extract action reads, empty for its text
+	Variables   map[string]string     // workflow parameters
+}
+
+// TemplateSet holds the code templates of the template-based generator,
+// one per action type. Action types without a template generate an
+// "unsupported" comment.
+type TemplateSet struct {
+	templates map[models.ActionType]*template.Template
+	sources   map[models.ActionType]string
+}
+
+// defaultTemplateSources are the built-in templates
+var defaultTemplateSources = map[models.ActionType]string{
+	models.ActionNavigate:   NavigateTemplate,
+	models.ActionClick:      ClickTemplate,
+	models.ActionInput:      InputTemplate,
+	models.ActionKeypress:   KeypressTemplate,
+	models.ActionDblClick:   DblClickTemplate,
+	models.ActionRightClick: RightClickTemplate,
+	models.ActionSelect:     SelectTemplate,
+	models.ActionScroll:     ScrollTemplate,
+	models.ActionFocus:      FocusTemplate,
+	models.ActionBlur:       BlurTemplate,
+	models.ActionExtract:    ExtractTemplate,
+}
+
+var defaultTemplates = DefaultTemplates()
+
+// DefaultTemplates returns a template set with the built-in templates
+func DefaultTemplates() *TemplateSet {
+	ts := &TemplateSet{
+		templates: make(map[models.ActionType]*template.Template),
+		sources:   make(map[models.ActionType]string),
+	}
+	for actionType, source := range defaultTemplateSources {
+		if err := ts.Override(actionType, source); err != nil {
+			panic(fmt.Sprintf("invalid built-in %s template: %v", actionType, err))
+		}
+	}
+	return ts
+}
+
+// LoadTemplateDir returns the default templates overridden by the
+// <action_type>.tmpl files in dir, e.g. click.tmpl. A missing dir is not
+// an error.
+func LoadTemplateDir(dir string) (*TemplateSet, error) {
+	ts := DefaultTemplates()
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return ts, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != TemplateExt {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template %s: %w", entry.Name(), err)
+		}
+		actionType := models.ActionType(strings.TrimSuffix(entry.Name(), TemplateExt))
+		if err := ts.Override(actionType, string(content)); err != nil {
+			return nil, fmt.Errorf("template %s: %w", entry.Name(), err)
+		}
+	}
+
+	return ts, nil
+}
+
+// Clone returns a copy of the set that can be overridden independently
+func (ts *TemplateSet) Clone() *TemplateSet {
+	clone := &TemplateSet{
+		templates: make(map[models.ActionType]*template.Template, len(ts.templates)),
+		sources:   make(map[models.ActionType]string, len(ts.sources)),
+	}
+	for actionType, tmpl := range ts.templates {
+		clone.templates[actionType] = tmpl
+		clone.sources[actionType] = ts.sources[actionType]
+	}
+	return clone
+}
+
+// Override replaces the template for actionType. The template is parsed and
+// rendered once against a sample action so mistakes such as unknown fields
+// are reported here rather than during a run.
+func (ts *TemplateSet) Override(actionType models.ActionType, source string) error {
+	if !isKnownActionType(actionType) {
+		return fmt.Errorf("unknown action type %q", actionType)
+	}
+	tmpl, err := template.New(string(actionType)).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return err
+	}
+	sample := buildTemplateData(models.SemanticAction{
+		ActionType: actionType,
+		Value:      "value",
+		Target:     models.SemanticTarget{Tag: "input", Selector: "#sample", Text: "Sample"},
+	}, nil)
+	if err := tmpl.Execute(&strings.Builder{}, sample); err != nil {
+		return err
+	}
+
+	ts.templates[actionType] = tmpl
+	ts.sources[actionType] = source
+	return nil
+}
+
+// Sources returns the template source for each action type in the set
+func (ts *TemplateSet) Sources() map[models.ActionType]string {
+	sources := make(map[models.ActionType]string, len(ts.sources))
+	for actionType, source := range ts.sources {
+		sources[actionType] = source
+	}
+	return sources
+}
+
+// Render generates code for action from its template
+func (ts *TemplateSet) Render(action models.SemanticAction, variables map[string]string) (string, error) {
+	tmpl, ok := ts.templates[action.ActionType]
+	if !ok {
+		return fmt.Sprintf("// Unsupported action type: %s\n", action.ActionType), nil
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, buildTemplateData(action, variables)); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", action.ActionType, err)
+	}
+	return out.String(), nil
+}
+
+// Generate is like Render but falls back to the built-in template when a
+// custom one fails for this action
+func (ts *TemplateSet) Generate(action models.SemanticAction, variables map[string]string) string {
+	code, err := ts.Render(action, variables)
+	if err == nil {
+		return code
+	}
+	if ts != defaultTemplates {
+		if code, defaultErr := defaultTemplates.Render(action, variables); defaultErr == nil {
+			return fmt.Sprintf("// Custom template failed: %v\n%s", err, code)
+		}
+	}
+	return fmt.Sprintf("// Template error: %v\n", err)
+}
+
+func isKnownActionType(actionType models.ActionType) bool {
+	for _, known := range models.ActionTypes {
+		if actionType == known {
+			return true
+		}
+	}
+	return false
+}
+
+// buildTemplateData prepares the template fields for action, substituting
+// parameter variables for recorded values
+func buildTemplateData(action models.SemanticAction, variables map[string]string) ActionTemplateData {
+	data := ActionTemplateData{
+		Action:      action,
+		Description: action.Target.Selector,
+		Selector:    fmt.Sprintf("%q", action.Target.Selector),
+		Value:       action.Value,
+		Variables:   variables,
+	}
+
+	// Check if value should be replaced with a variable
+	for varName, varValue := range variables {
+		if data.Value == varValue {
+			data.Value = varName
+			break
+		}
+	}
+	if data.Value == action.Value {
+		data.Value = fmt.Sprintf("%q", data.Value)
+	}
+
+	switch action.ActionType {
+	case models.ActionNavigate:
+		data.Description = action.Value
+		if urlVar, ok := variables[action.Value]; ok {
+			data.Value = urlVar
+		} else {
+			data.Value = fmt.Sprintf("%q", action.Value)
+		}
+	case models.ActionClick:
+		if action.Target.Text != "" {
+			data.Description = action.Target.Text
+		}
+	case models.ActionKeypress:
+		data.Description = action.Value
+		data.Key = action.Value
+	case models.ActionSelect:
+		data.Description = action.Value
+	case models.ActionExtract:
+		var extract models.Extraction
+		if action.Extract != nil {
+			extract = *action.Extract
+		}
+		data.Output = fmt.Sprintf("%q", extract.Output)
+		if extract.Attribute != "" {
+			data.Attribute = fmt.Sprintf("%q", extract.Attribute)
+		}
+	}
+
+	return data
+}
@@ -0,0 +1,57 @@
+package llm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+func TestLoadTemplateDir(t *testing.T) {
+	dir := t.TempDir()
+	custom := "// Click {{.Description}}\nif err := clickWithRetry(page, {{.Selector}}); err != nil {\n\treturn err\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "click.tmpl"), []byte(custom), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := LoadTemplateDir(dir)
+	if err != nil {
+		t.Fatalf("LoadTemplateDir() error = %v", err)
+	}
+
+	click := models.SemanticAction{ActionType: models.ActionClick, Target: models.SemanticTarget{Selector: "#go", Text: "Go"}}
+	want := "// Click Go\nif err := clickWithRetry(page, \"#go\"); err != nil {\n\treturn err\n}\n"
+	if got := templates.Generate(click, nil); got != want {
+		t.Errorf("custom click = %q, want %q", got, want)
+	}
+
+	// Action types without a file keep the built-in template
+	input := models.SemanticAction{ActionType: models.ActionInput, Value: "cats", Target: models.SemanticTarget{Selector: "#q"}}
+	if got, want := templates.Generate(input, nil), GenerateCodeFromAction(input, nil); got != want {
+		t.Errorf("input = %q, want built-in %q", got, want)
+	}
+}
+
+func TestTemplateOverrideErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		actionType models.ActionType
+		source     string
+		wantErr    string
+	}{
+		{"Unknown action type", "clik", "{{.Selector}}", "unknown action type"},
+		{"Parse error", models.ActionClick, "{{.Selector", "unclosed action"},
+		{"Unknown field", models.ActionClick, "{{.Locator}}", "can't evaluate field Locator"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DefaultTemplates().Override(tt.actionType, tt.source)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Override() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+// Package logging writes structured logs whose lines are tagged with the
+// request, workflow, run and step they belong to
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Setup makes the default logger write LOG_FORMAT lines, "text" or "json",
+// at LOG_LEVEL and above, "info" by default. Lines logged with a context
+// are tagged with its fields and the IDs of its trace.
+func Setup(service string) (*slog.Logger, error) {
+	level := slog.LevelInfo
+	if value := os.Getenv("LOG_LEVEL"); value != "" {
+		if err := level.UnmarshalText([]byte(value)); err != nil {
+			return nil, fmt.Errorf("invalid LOG_LEVEL %q: %w", value, err)
+		}
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch format := strings.ToLower(os.Getenv("LOG_FORMAT")); format {
+	case "", "text":
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return nil, fmt.Errorf("invalid LOG_FORMAT %q, want text or json", format)
+	}
+
+	logger := slog.New(contextHandler{handler}).With("service", service)
+	slog.SetDefault(logger)
+	return logger, nil
+}
+
+// Fatal logs msg at error level and exits, like log.Fatal
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+type fieldsKey struct{}
+
+// fields tag the lines logged with a context. Handlers add to the fields
+// of their request, which its access log line includes.
+type fields struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+func (f *fields) list() []slog.Attr {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]slog.Attr(nil), f.attrs...)
+}
+
+// NewContext returns a context whose lines are tagged with args, as
+// key-value pairs or slog.Attrs, besides the fields of ctx
+func NewContext(ctx context.Context, args ...any) context.Context {
+	f := &fields{}
+	if parent, ok := ctx.Value(fieldsKey{}).(*fields); ok {
+		f.attrs = parent.list()
+	}
+	f.attrs = append(f.attrs, attrs(args)...)
+	return context.WithValue(ctx, fieldsKey{}, f)
+}
+
+// Add tags the lines logged with ctx with args from now on, including the
+// access log line of the request ctx belongs to
+func Add(ctx context.Context, args ...any) {
+	f, ok := ctx.Value(fieldsKey{}).(*fields)
+	if !ok {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attrs = append(f.attrs, attrs(args)...)
+}
+
+// attrs converts key-value pairs and slog.Attrs like slog.Logger.With does
+func attrs(args []any) []slog.Attr {
+	var r slog.Record
+	r.Add(args...)
+	list := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		list = append(list, a)
+		return true
+	})
+	return list
+}
+
+// contextHandler tags records with the fields and trace of their context
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if f, ok := ctx.Value(fieldsKey{}).(*fields); ok {
+		r.AddAttrs(f.list()...)
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		r.AddAttrs(slog.String("traceID", span.TraceID().String()), slog.String("spanID", span.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// RequestIDHeader carries a request's ID, which callers may set to find
+// the API's log lines for their request
+const RequestIDHeader = "X-Request-ID"
+
+// Requests is middleware giving each request an ID, the caller's
+// X-Request-ID or a new one, which is echoed in the response and tags the
+// lines logged with the request's context. Each request is logged once it
+// is served, at debug level outside the API such as health checks.
+func Requests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		ctx := NewContext(r.Context(), "requestID", id)
+
+		m := httpsnoop.CaptureMetrics(next, w, r.WithContext(ctx))
+
+		level := slog.LevelInfo
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			level = slog.LevelDebug
+		}
+		slog.Log(ctx, level, "Request served",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", m.Code,
+			"duration", m.Duration,
+		)
+	})
+}
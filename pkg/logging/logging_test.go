@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequests(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		wantID string
+	}{
+		{name: "caller's ID", header: "req-1", wantID: "req-1"},
+		{name: "new ID", header: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			previous := slog.Default()
+			slog.SetDefault(slog.New(contextHandler{slog.NewJSONHandler(&buf, nil)}))
+			defer slog.SetDefault(previous)
+
+			handler := Requests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Add(r.Context(), "runID", "run-1")
+				slog.InfoContext(r.Context(), "Handling request")
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			req := httptest.NewRequest(http.MethodPost, "/api/workflows/wf-1/run", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(RequestIDHeader)
+			if id == "" || (tt.wantID != "" && id != tt.wantID) {
+				t.Fatalf("%s = %q, want %q", RequestIDHeader, id, tt.wantID)
+			}
+
+			// Both the handler's line and the access log line are tagged
+			dec := json.NewDecoder(&buf)
+			for _, msg := range []string{"Handling request", "Request served"} {
+				var line map[string]any
+				if err := dec.Decode(&line); err != nil {
+					t.Fatalf("decoding %q line: %v", msg, err)
+				}
+				if line["msg"] != msg || line["requestID"] != id || line["runID"] != "run-1" {
+					t.Errorf("line = %v, want %q tagged with requestID %q and runID run-1", line, msg, id)
+				}
+			}
+		})
+	}
+}
@@ -1,8 +1,18 @@
 package models
 
 import (
+	"cmp"
 	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // ==================== Hybrid Event Types ====================
@@ -99,19 +109,21 @@ type RRWebMetaData struct {
 
 // RRWebIncrementalData represents incremental snapshot data
 type RRWebIncrementalData struct {
-	Source  int            `json:"source"`
-	Type    int            `json:"type,omitempty"` // Mouse event type
-	ID      int            `json:"id,omitempty"`
-	X       int            `json:"x,omitempty"`
-	Y       int            `json:"y,omitempty"`
-	Text    string         `json:"text,omitempty"`
-	Adds    []NodeAddition `json:"adds,omitempty"`
-	Removes []NodeRemoval  `json:"removes,omitempty"`
+	Source     int                 `json:"source"`
+	Type       int                 `json:"type,omitempty"` // Mouse event type
+	ID         int                 `json:"id,omitempty"`
+	X          int                 `json:"x,omitempty"`
+	Y          int                 `json:"y,omitempty"`
+	Text       string              `json:"text,omitempty"`
+	Adds       []NodeAddition      `json:"adds,omitempty"`
+	Removes    []NodeRemoval       `json:"removes,omitempty"`
+	Attributes []AttributeMutation `json:"attributes,omitempty"`
 }
 
 // NodeAddition represents a DOM node addition
 type NodeAddition struct {
 	ParentID int             `json:"parentId"`
+	NextID   *int            `json:"nextId"` // sibling the node was inserted before, nil when appended
 	Node     *SerializedNode `json:"node"`
 }
 
@@ -121,6 +133,13 @@ type NodeRemoval struct {
 	ID       int `json:"id"`
 }
 
+// AttributeMutation represents attribute changes on a DOM node, a nil
+// value meaning the attribute was removed
+type AttributeMutation struct {
+	ID         int                    `json:"id"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
 // SerializedNode represents a serialized DOM node
 type SerializedNode struct {
 	ID          int                    `json:"id"`
@@ -145,7 +164,304 @@ type SemanticAction struct {
 	Context         []SemanticTarget       `json:"context,omitempty"` // New elements that appeared
 	Embeddings      []float32              `json:"embeddings,omitempty"`
 	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+	Waits           []WaitCondition        `json:"waits,omitempty"` // Conditions awaited after the action
 	Timestamp       int64                  `json:"timestamp"`
+	// Criticality is whether the run stops when the action fails
+	Criticality Criticality `json:"criticality,omitempty"`
+	// Compensations undo the action, such as deleting the record it
+	// created, when a later step aborts the run
+	Compensations []SemanticAction `json:"compensations,omitempty"`
+	// Extract is what an extract action reads from its element
+	Extract *Extraction `json:"extract,omitempty"`
+	// Assert is what an assert action checks on the page
+	Assert *Assertion `json:"assert,omitempty"`
+}
+
+// ResequenceActions numbers actions 1, 2, ... in their order, as the
+// extractor does, and returns the number each had mapped to its new one.
+// Actions without a number yet are new and left out.
+func ResequenceActions(actions []SemanticAction) map[int]int {
+	renumbered := make(map[int]int, len(actions))
+	for i := range actions {
+		if actions[i].SequenceID != 0 {
+			renumbered[actions[i].SequenceID] = i + 1
+		}
+		actions[i].SequenceID = i + 1
+	}
+	return renumbered
+}
+
+// RenumberParameters points the parameters taken from an action at the
+// number ResequenceActions gave it, dropping the ones whose action was
+// removed
+func RenumberParameters(params []WorkflowParameter, renumbered map[int]int) []WorkflowParameter {
+	kept := make([]WorkflowParameter, 0, len(params))
+	for _, param := range params {
+		if param.SourceAction != 0 {
+			sequenceID, ok := renumbered[param.SourceAction]
+			if !ok {
+				continue
+			}
+			param.SourceAction = sequenceID
+		}
+		kept = append(kept, param)
+	}
+	return kept
+}
+
+// ReorderActions returns the actions in the order of the sequence IDs given,
+// which must name every action once
+func ReorderActions(actions []SemanticAction, order []int) ([]SemanticAction, error) {
+	if len(order) != len(actions) {
+		return nil, fmt.Errorf("order has %d actions, want %d", len(order), len(actions))
+	}
+	bySequence := make(map[int]SemanticAction, len(actions))
+	for _, action := range actions {
+		bySequence[action.SequenceID] = action
+	}
+	reordered := make([]SemanticAction, 0, len(actions))
+	for _, sequenceID := range order {
+		action, ok := bySequence[sequenceID]
+		if !ok {
+			return nil, fmt.Errorf("order names action %d twice or it doesn't exist", sequenceID)
+		}
+		delete(bySequence, sequenceID)
+		reordered = append(reordered, action)
+	}
+	return reordered, nil
+}
+
+// Validate checks the action's wait conditions and execution options
+func (a SemanticAction) Validate() error {
+	for _, wait := range a.Waits {
+		if err := wait.Validate(); err != nil {
+			return fmt.Errorf("action %d: %w", a.SequenceID, err)
+		}
+	}
+	if _, err := a.Options(); err != nil {
+		return fmt.Errorf("action %d: %w", a.SequenceID, err)
+	}
+	if err := a.Criticality.Validate(); err != nil {
+		return fmt.Errorf("action %d: %w", a.SequenceID, err)
+	}
+	for _, comp := range a.Compensations {
+		if len(comp.Compensations) > 0 {
+			return fmt.Errorf("action %d: compensations cannot have compensations", a.SequenceID)
+		}
+		if err := comp.Validate(); err != nil {
+			return fmt.Errorf("action %d compensation: %w", a.SequenceID, err)
+		}
+	}
+	if a.ActionType == ActionExtract {
+		if a.Extract == nil {
+			return fmt.Errorf("action %d: extract action needs an extract output", a.SequenceID)
+		}
+		if err := a.Extract.Validate(); err != nil {
+			return fmt.Errorf("action %d: %w", a.SequenceID, err)
+		}
+	}
+	if a.ActionType == ActionAssert {
+		if a.Assert == nil {
+			return fmt.Errorf("action %d: assert action needs an assertion", a.SequenceID)
+		}
+		if err := a.Assert.Validate(); err != nil {
+			return fmt.Errorf("action %d: %w", a.SequenceID, err)
+		}
+	}
+	return nil
+}
+
+// Extraction is what an extract action reads from its element into a run
+// output: its text, or the value of one of its attributes
+type Extraction struct {
+	Output    string `json:"output"`              // run output name, later actions reading it as {{output}}
+	Attribute string `json:"attribute,omitempty"` // the element's text when empty
+}
+
+// Validate checks the extraction names a usable output
+func (e Extraction) Validate() error {
+	if strings.TrimSpace(e.Output) == "" {
+		return fmt.Errorf("extraction needs an output name")
+	}
+	if e.Output == ClipboardOutput {
+		return fmt.Errorf("%s is set by copy actions", ClipboardOutput)
+	}
+	return nil
+}
+
+// AssertionType is what an assert action checks
+type AssertionType string
+
+const (
+	AssertTextEquals   AssertionType = "text_equals"   // The element's text is the value
+	AssertTextContains AssertionType = "text_contains" // The element's text contains the value
+	AssertExists       AssertionType = "exists"        // An element matches the target
+	AssertAbsent       AssertionType = "absent"        // No element matches the target
+	AssertURLMatches   AssertionType = "url_matches"   // The page URL matches the value as a regular expression
+	AssertTitleEquals  AssertionType = "title_equals"  // The page title is the value
+)
+
+// Assertion is a check an assert action makes on the page, failing the run
+// when it doesn't hold. Its value may use parameters as {{name}}.
+type Assertion struct {
+	Type  AssertionType `json:"type"`
+	Value string        `json:"value,omitempty"`
+}
+
+// Validate checks the assertion's type and that its value suits it
+func (a Assertion) Validate() error {
+	switch a.Type {
+	case AssertTextEquals, AssertExists, AssertAbsent, AssertTitleEquals:
+		// An empty value asserts an empty text or title
+		return nil
+	case AssertTextContains:
+		if a.Value == "" {
+			return fmt.Errorf("text_contains assertion needs a value")
+		}
+		return nil
+	case AssertURLMatches:
+		if a.Value == "" {
+			return fmt.Errorf("url_matches assertion needs a pattern")
+		}
+		// Patterns with parameters are compiled once they are substituted
+		if !strings.Contains(a.Value, "{{") {
+			if _, err := regexp.Compile(a.Value); err != nil {
+				return fmt.Errorf("invalid url_matches pattern: %w", err)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown assertion type %q", a.Type)
+}
+
+// Targeted reports whether the assertion checks an element rather than
+// the page
+func (a Assertion) Targeted() bool {
+	return a.Type != AssertURLMatches && a.Type != AssertTitleEquals
+}
+
+// Criticality is whether a run carries on after an action fails
+type Criticality string
+
+const (
+	// CriticalityOptional actions may fail without failing the run, as
+	// actions without a criticality other than assertions do
+	CriticalityOptional Criticality = "optional"
+	// CriticalityCritical actions stop and fail the run when they fail
+	CriticalityCritical Criticality = "critical"
+)
+
+// Validate reports an unknown criticality
+func (c Criticality) Validate() error {
+	switch c {
+	case "", CriticalityOptional, CriticalityCritical:
+		return nil
+	}
+	return fmt.Errorf("unknown criticality: %s", c)
+}
+
+// Metadata keys overriding the run's timeout and retries for one action
+const (
+	MetaTimeout      = "timeout_seconds"
+	MetaMaxRetries   = "max_retries"
+	MetaRetryBackoff = "retry_backoff_ms"
+)
+
+// MetaHealedSelector is the metadata key of the selector a run found the
+// action's element with after its recorded selectors failed
+const MetaHealedSelector = "healed_selector"
+
+// HealedSelector returns the selector a run healed the action with, tried
+// before the recorded ones
+func (a SemanticAction) HealedSelector() string {
+	selector, _ := a.Metadata[MetaHealedSelector].(string)
+	return selector
+}
+
+// CSSSelectors returns the CSS selectors locating the action's element, most
+// stable first: its healed selector, its aria-label, name, placeholder and
+// test ID, then the ranked selectors of the recording
+func (a SemanticAction) CSSSelectors() []string {
+	attrs := a.Target.Attributes
+	tag := strings.ToLower(a.Target.Tag)
+
+	var selectors []string
+	add := func(selector string) {
+		if selector == "" || selector == "window" || slices.Contains(selectors, selector) {
+			return
+		}
+		selectors = append(selectors, selector)
+	}
+
+	// A selector an earlier run healed the action with goes first
+	add(a.HealedSelector())
+	if ariaLabel, ok := attrs["aria-label"].(string); ok && ariaLabel != "" {
+		add(fmt.Sprintf("%s[aria-label='%s']", tag, ariaLabel))
+	}
+	if name, ok := attrs["name"].(string); ok && name != "" {
+		add(fmt.Sprintf("%s[name='%s']", tag, name))
+	}
+	if placeholder, ok := attrs["placeholder"].(string); ok && placeholder != "" {
+		add(fmt.Sprintf("%s[placeholder='%s']", tag, placeholder))
+	}
+	if testID, ok := attrs["data-testid"].(string); ok && testID != "" {
+		add(fmt.Sprintf("[data-testid='%s']", testID))
+	}
+	for _, selector := range a.Target.Candidates {
+		add(selector)
+	}
+	add(a.Target.Selector)
+	return selectors
+}
+
+// ActionOptions are an action's own timeout and retries
+type ActionOptions struct {
+	Timeout      time.Duration // zero keeps the run's timeout
+	MaxRetries   int           // retries after the first attempt
+	RetryBackoff time.Duration // delay before the first retry, doubling after; zero for a second
+}
+
+// Options reads the action's execution options from its metadata
+func (a SemanticAction) Options() (ActionOptions, error) {
+	var opts ActionOptions
+	timeout, err := metadataInt(a.Metadata, MetaTimeout)
+	if err != nil {
+		return opts, err
+	}
+	retries, err := metadataInt(a.Metadata, MetaMaxRetries)
+	if err != nil {
+		return opts, err
+	}
+	backoff, err := metadataInt(a.Metadata, MetaRetryBackoff)
+	if err != nil {
+		return opts, err
+	}
+	opts.Timeout = time.Duration(timeout) * time.Second
+	opts.MaxRetries = retries
+	opts.RetryBackoff = time.Duration(backoff) * time.Millisecond
+	return opts, nil
+}
+
+// metadataInt reads a non-negative whole number from metadata, zero when
+// the key is missing. Decoded JSON holds numbers as float64.
+func metadataInt(metadata map[string]interface{}, key string) (int, error) {
+	var n float64
+	switch v := metadata[key].(type) {
+	case nil:
+		return 0, nil
+	case int:
+		n = float64(v)
+	case int64:
+		n = float64(v)
+	case float64:
+		n = v
+	default:
+		return 0, fmt.Errorf("%s must be a number, got %v", key, v)
+	}
+	if n < 0 || n != float64(int(n)) {
+		return 0, fmt.Errorf("%s must be a non-negative whole number, got %v", key, n)
+	}
+	return int(n), nil
 }
 
 // ActionType represents the type of browser action
@@ -173,8 +489,32 @@ const (
 	ActionMediaSeek  ActionType = "media_seek"  // Video/audio seek
 	ActionFileUpload ActionType = "file_upload" // File input
 	ActionSubmit     ActionType = "submit"      // Form submit
+	ActionExtract    ActionType = "extract"     // Read an element into a run output
+	ActionAssert     ActionType = "assert"      // Check the page, failing the run when the check fails
 )
 
+// actionTypes are the action types a step can be added or changed to
+var actionTypes = []ActionType{
+	ActionNavigate, ActionClick, ActionDblClick, ActionRightClick, ActionInput, ActionKeypress,
+	ActionScroll, ActionHover, ActionFocus, ActionBlur, ActionSelect, ActionCopy, ActionPaste,
+	ActionCut, ActionDrag, ActionDrop, ActionMediaPlay, ActionMediaPause, ActionMediaSeek,
+	ActionFileUpload, ActionSubmit, ActionExtract, ActionAssert,
+}
+
+// Known reports whether the type is one replay knows
+func (t ActionType) Known() bool {
+	return slices.Contains(actionTypes, t)
+}
+
+// ActionTypes lists every known action type
+var ActionTypes = []ActionType{
+	ActionNavigate, ActionClick, ActionDblClick, ActionRightClick, ActionInput,
+	ActionKeypress, ActionScroll, ActionHover, ActionFocus, ActionBlur,
+	ActionSelect, ActionCopy, ActionPaste, ActionCut, ActionDrag, ActionDrop,
+	ActionMediaPlay, ActionMediaPause, ActionMediaSeek, ActionFileUpload, ActionSubmit,
+	ActionExtract, ActionAssert,
+}
+
 // InteractionRank represents how important/reliable an interaction is
 type InteractionRank string
 
@@ -192,27 +532,636 @@ type SemanticTarget struct {
 	Attributes map[string]interface{} `json:"attributes,omitempty"`
 	XPath      string                 `json:"xpath,omitempty"`
 	NodeID     int                    `json:"node_id,omitempty"`
+	Candidates []string               `json:"candidates,omitempty"` // Fallback selectors, most stable first
+}
+
+// WaitType is what a wait condition waits for
+type WaitType string
+
+const (
+	WaitNavigation  WaitType = "navigation"   // A new document loaded
+	WaitNetworkIdle WaitType = "network_idle" // No requests in flight
+	WaitVisible     WaitType = "visible"      // Selector matches a visible element
+	WaitHidden      WaitType = "hidden"       // Selector matches nothing visible
+	WaitURL         WaitType = "url"          // URL matches Pattern
+	WaitJS          WaitType = "js"           // Script returns true
+	WaitRequest     WaitType = "request"      // A request matching Method and Pattern gets a response with Status
+)
+
+// DefaultWaitTimeout bounds wait conditions without a timeout of their own
+const DefaultWaitTimeout = 30 * time.Second
+
+// WaitCondition is a condition an action waits for after it runs
+type WaitCondition struct {
+	Type     WaitType `json:"type"`
+	Selector string   `json:"selector,omitempty"` // visible and hidden
+	Pattern  string   `json:"pattern,omitempty"`  // url and request, a regular expression
+	Script   string   `json:"script,omitempty"`   // js, a function such as "() => window.ready"
+	Method   string   `json:"method,omitempty"`   // request, any method when empty
+	Status   int      `json:"status,omitempty"`   // request, any status below 400 when 0
+	Timeout  int      `json:"timeout_ms,omitempty"`
+}
+
+// Validate checks the condition has what its type needs
+func (c WaitCondition) Validate() error {
+	switch c.Type {
+	case WaitNavigation, WaitNetworkIdle:
+	case WaitVisible, WaitHidden:
+		if c.Selector == "" {
+			return fmt.Errorf("%s wait needs a selector", c.Type)
+		}
+	case WaitURL:
+		if _, err := regexp.Compile(c.Pattern); c.Pattern == "" || err != nil {
+			return fmt.Errorf("url wait needs a valid pattern: %q", c.Pattern)
+		}
+	case WaitJS:
+		if strings.TrimSpace(c.Script) == "" {
+			return fmt.Errorf("js wait needs a script")
+		}
+	case WaitRequest:
+		if _, err := regexp.Compile(c.Pattern); c.Pattern == "" || err != nil {
+			return fmt.Errorf("request wait needs a valid pattern: %q", c.Pattern)
+		}
+		if c.Status != 0 && (c.Status < 100 || c.Status > 599) {
+			return fmt.Errorf("request wait status must be an HTTP status: %d", c.Status)
+		}
+	default:
+		return fmt.Errorf("unknown wait type: %q", c.Type)
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("wait timeout must not be negative")
+	}
+	return nil
+}
+
+// Duration returns how long to wait before giving up
+func (c WaitCondition) Duration() time.Duration {
+	if c.Timeout > 0 {
+		return time.Duration(c.Timeout) * time.Millisecond
+	}
+	return DefaultWaitTimeout
+}
+
+// String describes the condition for errors and logs
+func (c WaitCondition) String() string {
+	switch c.Type {
+	case WaitVisible, WaitHidden:
+		return fmt.Sprintf("%s %s", c.Selector, c.Type)
+	case WaitURL:
+		return fmt.Sprintf("url matching %s", c.Pattern)
+	case WaitJS:
+		return "script " + c.Script
+	case WaitRequest:
+		desc := "request " + strings.TrimSpace(strings.ToUpper(c.Method)+" "+c.Pattern)
+		if c.Status != 0 {
+			desc += fmt.Sprintf(" with status %d", c.Status)
+		}
+		return desc
+	}
+	return string(c.Type)
+}
+
+// CheckResponse checks the status a request wait's request got
+func (c WaitCondition) CheckResponse(status int) error {
+	if c.Status != 0 && status != c.Status {
+		return fmt.Errorf("%s got status %d", c, status)
+	}
+	if c.Status == 0 && status >= 400 {
+		return fmt.Errorf("%s failed with status %d", c, status)
+	}
+	return nil
 }
 
 // ==================== Workflow Types ====================
 
+// ListOptions pages and sorts a list of workflows, runs or audit events
+type ListOptions struct {
+	// Limit is how many to list at most, all of them when 0
+	Limit int
+	// Offset is how many to skip
+	Offset int
+	// Sort is the field sorted by, the list's default when empty
+	Sort string
+	// Asc sorts in ascending order rather than newest or last first
+	Asc bool
+	// Since and Until bound when the workflows were created, the runs
+	// started or the audit events were recorded, unbounded when zero
+	Since, Until time.Time
+	// Deleted lists the workflows or runs in the trash instead
+	Deleted bool
+}
+
+// Fields workflows and runs can be sorted by
+var (
+	WorkflowSorts = []string{"created_at", "updated_at", "name"}
+	RunSorts      = []string{"started_at", "completed_at", "status"}
+)
+
+// WorkflowSearchResult is a workflow a search found, best matches first
+type WorkflowSearchResult struct {
+	WorkflowID string  `json:"workflow_id"`
+	Name       string  `json:"name"`
+	StartURL   string  `json:"start_url,omitempty"`
+	Score      float64 `json:"score"`
+	// Actions are the workflow's actions that matched, in sequence
+	Actions []ActionMatch `json:"actions,omitempty"`
+}
+
+// ActionMatch is an action whose text, selector or value matched a search
+type ActionMatch struct {
+	SequenceID int        `json:"sequence_id"`
+	ActionType ActionType `json:"action_type"`
+	// Text is the action's element text, selector and value
+	Text string `json:"text"`
+}
+
+// WorkflowFilter selects the workflows to list
+type WorkflowFilter struct {
+	ListOptions
+	// Name is part of the workflows' names
+	Name string
+	// Tags are tags the workflows all have
+	Tags []string
+}
+
+// RunFilter selects the runs to list
+type RunFilter struct {
+	ListOptions
+	// WorkflowID is the workflow of the runs, any when empty
+	WorkflowID string
+	// Statuses are the statuses the runs have, any when empty
+	Statuses []RunStatus
+	// WorkflowName is part of the names of the runs' workflows
+	WorkflowName string
+	// Tags are tags the runs' workflows all have
+	Tags []string
+}
+
 // WorkflowDefinition represents a stored workflow created from recorded events
 type WorkflowDefinition struct {
 	ID                  string    `json:"id" db:"id"`
 	Name                string    `json:"name" db:"name"`
+	Description         string    `json:"description,omitempty" db:"description"`
 	EventsFilePath      string    `json:"events_file_path" db:"events_file_path"`
 	IsWorkflowGenerated bool      `json:"is_workflow_generated" db:"is_workflow_generated"`
 	SemanticContext     string    `json:"semantic_context" db:"semantic_context"` // JSON string
 	ParametersJSON      string    `json:"parameters" db:"parameters"`             // JSON string
 	StartURL            string    `json:"start_url" db:"start_url"`
+	GeneratedCode       string    `json:"generated_code,omitempty" db:"generated_code"`     // Last generated workflow program
+	GeneratedFormat     string    `json:"generated_format,omitempty" db:"generated_format"` // "llm", "script" or "test"
 	CreatedAt           time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt           time.Time `json:"updated_at" db:"updated_at"`
+	// Revision counts the changes to the workflow's fields and actions, so
+	// an update made from an earlier revision can be refused
+	Revision int `json:"revision" db:"revision"`
+	// DeletedAt is when the workflow was moved to the trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+
+	Settings WorkflowSettings `json:"settings" db:"settings"` // JSON column
+
+	// Tags group the workflow with others, such as by team or system,
+	// stored in the workflow_tags table
+	Tags []string `json:"tags"`
 
 	// Computed fields (not stored directly)
 	Actions    []SemanticAction    `json:"actions,omitempty"`
 	Parameters []WorkflowParameter `json:"params,omitempty"`
 }
 
+// maxWorkflowNameLength is the longest a workflow name may be, the size of
+// its column
+const maxWorkflowNameLength = 255
+
+// WorkflowUpdate changes the metadata of a workflow. Fields left out keep
+// their value.
+type WorkflowUpdate struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	StartURL    *string `json:"start_url,omitempty"`
+	// Defaults replaces the default values of parameters, by name
+	Defaults map[string]string `json:"defaults,omitempty"`
+}
+
+// Validate checks the name isn't blank or too long and the start URL, unless
+// cleared, is an http(s) URL
+func (u WorkflowUpdate) Validate() error {
+	if u.Name != nil {
+		name := strings.TrimSpace(*u.Name)
+		if name == "" {
+			return fmt.Errorf("name must not be empty")
+		}
+		if utf8.RuneCountInString(name) > maxWorkflowNameLength {
+			return fmt.Errorf("name is longer than %d characters", maxWorkflowNameLength)
+		}
+	}
+	if u.StartURL != nil && *u.StartURL != "" {
+		parsed, err := url.Parse(*u.StartURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("start_url must be an http or https URL")
+		}
+	}
+	return nil
+}
+
+// Apply changes the workflow and its parameters as the update says,
+// reporting whether a default value changed. It fails, changing nothing,
+// when a default names no parameter.
+func (u WorkflowUpdate) Apply(def *WorkflowDefinition, params []WorkflowParameter) (bool, error) {
+	for name := range u.Defaults {
+		if !slices.ContainsFunc(params, func(p WorkflowParameter) bool { return p.Name == name }) {
+			return false, fmt.Errorf("workflow has no parameter %q", name)
+		}
+	}
+
+	if u.Name != nil {
+		def.Name = strings.TrimSpace(*u.Name)
+	}
+	if u.Description != nil {
+		def.Description = strings.TrimSpace(*u.Description)
+	}
+	if u.StartURL != nil {
+		def.StartURL = *u.StartURL
+	}
+	changed := false
+	for i, param := range params {
+		if value, ok := u.Defaults[param.Name]; ok && value != param.DefaultValue {
+			params[i].DefaultValue = value
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+// maxTagLength is the longest a tag may be
+const maxTagLength = 64
+
+// NormalizeTag returns a tag trimmed and lowercased, so tags differing in
+// case are the same, or an error when it is empty, too long or has a slash,
+// which URL paths couldn't name it with
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return "", fmt.Errorf("tag is empty")
+	}
+	if strings.Contains(tag, "/") {
+		return "", fmt.Errorf("tag %q has a slash", tag)
+	}
+	if utf8.RuneCountInString(tag) > maxTagLength {
+		return "", fmt.Errorf("tag %q is longer than %d characters", tag, maxTagLength)
+	}
+	return tag, nil
+}
+
+// TagCount is a tag and how many workflows have it
+type TagCount struct {
+	Tag       string `json:"tag"`
+	Workflows int    `json:"workflows"`
+}
+
+// WorkflowSettings are replay settings applied to every run of a workflow
+type WorkflowSettings struct {
+	Dialogs *DialogPolicy `json:"dialogs,omitempty"` // accepted when unset
+	// SkipStabilityChecks clicks and types without first scrolling the
+	// element into view and waiting for it to stop moving
+	SkipStabilityChecks bool `json:"skip_stability_checks,omitempty"`
+	// HealSelectors writes the selectors runs fall back on to the actions,
+	// as a new version, so later runs try them first
+	HealSelectors bool `json:"heal_selectors,omitempty"`
+	// DecisionTimeout is how many seconds a run holds after a failed action
+	// for an operator to skip or retry it
+	DecisionTimeout int `json:"decision_timeout_seconds,omitempty"`
+	// Loops repeat blocks of actions for each row of a dataset
+	Loops []Loop `json:"loops,omitempty"`
+	// MaxConcurrentRuns caps how many runs of the workflow execute at once,
+	// for sites that can't take two sessions of one account, unlimited when 0
+	MaxConcurrentRuns int `json:"max_concurrent_runs,omitempty"`
+	// QueueRuns has runs started at the limit wait for a free slot rather
+	// than be rejected. Batch, pipeline and scheduled runs always wait.
+	QueueRuns bool `json:"queue_runs,omitempty"`
+	// Output describes the dataset the extract actions produce
+	Output *OutputSchema `json:"output,omitempty"`
+	// Notifications announce the runs that end with the statuses they're on
+	Notifications []NotificationTarget `json:"notifications,omitempty"`
+}
+
+// Validate checks the settings
+func (s WorkflowSettings) Validate() error {
+	if s.DecisionTimeout < 0 {
+		return fmt.Errorf("decision_timeout_seconds must not be negative")
+	}
+	if s.MaxConcurrentRuns < 0 {
+		return fmt.Errorf("max_concurrent_runs must not be negative")
+	}
+	if err := ValidateLoops(s.Loops); err != nil {
+		return err
+	}
+	if err := s.Output.Validate(); err != nil {
+		return err
+	}
+	for _, target := range s.Notifications {
+		if err := target.Validate(); err != nil {
+			return err
+		}
+	}
+	return s.Dialogs.Validate()
+}
+
+// NotificationType is the service a notification is sent to
+type NotificationType string
+
+const (
+	// NotifySlack posts to a Slack incoming webhook, or to a channel with
+	// the worker's bot token
+	NotifySlack NotificationType = "slack"
+	// NotifyEmail emails the recipients through the worker's SMTP server
+	NotifyEmail NotificationType = "email"
+)
+
+// NotificationTarget is where a workflow's runs are announced
+type NotificationTarget struct {
+	Type NotificationType `json:"type"`
+	// WebhookURL is a Slack incoming webhook. Without one, messages are
+	// posted to Channel with the worker's SLACK_BOT_TOKEN.
+	WebhookURL string `json:"webhook_url,omitempty"`
+	Channel    string `json:"channel,omitempty"`
+	// To are the recipients of emails
+	To []string `json:"to,omitempty"`
+	// On are the run statuses announced, failed when empty
+	On []RunStatus `json:"on,omitempty"`
+	// Scheduled only announces the runs schedules start
+	Scheduled bool `json:"scheduled,omitempty"`
+}
+
+// Notifies reports whether runs ending with the status are announced
+func (t NotificationTarget) Notifies(status RunStatus) bool {
+	if len(t.On) == 0 {
+		return status == StatusFailed
+	}
+	for _, on := range t.On {
+		if on == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the target
+func (t NotificationTarget) Validate() error {
+	switch t.Type {
+	case NotifySlack:
+		if t.WebhookURL != "" {
+			u, err := url.Parse(t.WebhookURL)
+			if err != nil || u.Scheme != "https" || u.Host == "" {
+				return fmt.Errorf("notification webhook_url must be an https URL")
+			}
+		} else if t.Channel == "" {
+			return fmt.Errorf("slack notification needs a webhook_url or a channel")
+		}
+	case NotifyEmail:
+		if len(t.To) == 0 {
+			return fmt.Errorf("email notification needs recipients")
+		}
+		for _, to := range t.To {
+			if _, err := mail.ParseAddress(to); err != nil {
+				return fmt.Errorf("invalid email recipient %q", to)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown notification type: %q", t.Type)
+	}
+	for _, status := range t.On {
+		switch status {
+		case StatusFailed, StatusWarning, StatusSuccess, StatusCanceled:
+		default:
+			return fmt.Errorf("notifications can't be sent on %q runs", status)
+		}
+	}
+	return nil
+}
+
+// OutputSchema lays out the dataset a workflow's extract actions produce:
+// its columns in order, each holding the values of an extract output
+type OutputSchema struct {
+	Columns []OutputColumn `json:"columns"`
+}
+
+// OutputColumn is a column of a run's dataset
+type OutputColumn struct {
+	Name string     `json:"name"`           // the extract output it holds
+	Type ColumnType `json:"type,omitempty"` // string when empty
+}
+
+// ColumnType is the type a dataset column's values are converted to
+type ColumnType string
+
+const (
+	ColumnString  ColumnType = "string"
+	ColumnNumber  ColumnType = "number"  // currency symbols and thousands separators are ignored
+	ColumnBoolean ColumnType = "boolean" // true, false, yes, no, 1 or 0
+)
+
+// Validate checks the schema's columns. A nil schema is valid.
+func (s *OutputSchema) Validate() error {
+	if s == nil {
+		return nil
+	}
+	if len(s.Columns) == 0 {
+		return fmt.Errorf("output schema has no columns")
+	}
+	seen := make(map[string]bool, len(s.Columns))
+	for _, col := range s.Columns {
+		if strings.TrimSpace(col.Name) == "" {
+			return fmt.Errorf("output column needs a name")
+		}
+		if seen[col.Name] {
+			return fmt.Errorf("output column %q is listed twice", col.Name)
+		}
+		seen[col.Name] = true
+		switch col.Type {
+		case "", ColumnString, ColumnNumber, ColumnBoolean:
+		default:
+			return fmt.Errorf("output column %q has unknown type %q", col.Name, col.Type)
+		}
+	}
+	return nil
+}
+
+// ColumnsFor returns the schema's columns, or without a schema a string
+// column for each value the rows hold, by name
+func (s *OutputSchema) ColumnsFor(rows []map[string]string) []OutputColumn {
+	if s != nil {
+		return s.Columns
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		for name := range row {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	cols := make([]OutputColumn, len(names))
+	for i, name := range names {
+		cols[i] = OutputColumn{Name: name}
+	}
+	return cols
+}
+
+// Parse converts a value read from the page to the column's type, nil when
+// the value is missing or isn't of that type
+func (c OutputColumn) Parse(raw string) interface{} {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	switch c.Type {
+	case ColumnNumber:
+		n, err := strconv.ParseFloat(strings.Map(func(r rune) rune {
+			if (r >= '0' && r <= '9') || r == '.' || r == '-' || r == 'e' || r == 'E' {
+				return r
+			}
+			return -1
+		}, raw), 64)
+		if err != nil {
+			return nil
+		}
+		return n
+	case ColumnBoolean:
+		switch strings.ToLower(raw) {
+		case "true", "yes", "1":
+			return true
+		case "false", "no", "0":
+			return false
+		}
+		return nil
+	}
+	return raw
+}
+
+// Loop repeats the actions from FirstStep through LastStep, by sequence ID,
+// once for each row of its dataset. A row's columns are bound as parameters
+// for its iteration.
+type Loop struct {
+	FirstStep int                 `json:"first_step"`
+	LastStep  int                 `json:"last_step"`
+	Rows      []map[string]string `json:"rows"`
+}
+
+// Validate checks the loop
+func (l Loop) Validate() error {
+	if l.FirstStep <= 0 || l.LastStep < l.FirstStep {
+		return fmt.Errorf("loop steps %d-%d are not a valid range", l.FirstStep, l.LastStep)
+	}
+	if len(l.Rows) == 0 {
+		return fmt.Errorf("loop over steps %d-%d has no rows", l.FirstStep, l.LastStep)
+	}
+	return nil
+}
+
+// Contains reports whether the loop repeats the action with a sequence ID
+func (l Loop) Contains(sequenceID int) bool {
+	return sequenceID >= l.FirstStep && sequenceID <= l.LastStep
+}
+
+// ValidateLoops checks each loop and that no two loops overlap
+func ValidateLoops(loops []Loop) error {
+	for i, loop := range loops {
+		if err := loop.Validate(); err != nil {
+			return err
+		}
+		for _, other := range loops[:i] {
+			if loop.FirstStep <= other.LastStep && other.FirstStep <= loop.LastStep {
+				return fmt.Errorf("loops over steps %d-%d and %d-%d overlap",
+					other.FirstStep, other.LastStep, loop.FirstStep, loop.LastStep)
+			}
+		}
+	}
+	return nil
+}
+
+// DialogAction is how replay answers alert, confirm and prompt dialogs
+type DialogAction string
+
+const (
+	DialogAccept  DialogAction = "accept"  // Click OK, prompts keep their default text
+	DialogDismiss DialogAction = "dismiss" // Click Cancel
+	DialogAnswer  DialogAction = "answer"  // Click OK, answering prompts with a parameter
+)
+
+// DialogPolicy answers the JavaScript dialogs pages open during replay,
+// which would otherwise block the run
+type DialogPolicy struct {
+	Action DialogAction `json:"action"`
+	// Parameter is the workflow parameter prompts are answered with
+	Parameter string `json:"parameter,omitempty"`
+}
+
+// Validate checks the policy. A nil policy accepts dialogs.
+func (p *DialogPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	switch p.Action {
+	case DialogAccept, DialogDismiss:
+	case DialogAnswer:
+		if p.Parameter == "" {
+			return fmt.Errorf("dialog action %q needs a parameter", p.Action)
+		}
+	default:
+		return fmt.Errorf("unknown dialog action: %q", p.Action)
+	}
+	return nil
+}
+
+// DialogEvent is a JavaScript dialog a page opened during an action
+type DialogEvent struct {
+	Type     string `json:"type"` // alert, confirm, prompt or beforeunload
+	Message  string `json:"message"`
+	Accepted bool   `json:"accepted"`
+}
+
+// CodeGeneration is one stored output of GenerateWorkflow. Versions count up
+// from 1 per workflow.
+type CodeGeneration struct {
+	ID          string    `json:"id" db:"id"`
+	WorkflowID  string    `json:"workflow_id" db:"workflow_id"`
+	Version     int       `json:"version" db:"version"`
+	Format      string    `json:"format" db:"format"`
+	LLMProvider string    `json:"llm_provider,omitempty" db:"llm_provider"`
+	Code        string    `json:"code,omitempty" db:"code"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// WorkflowVersion is an immutable snapshot of a workflow's actions and
+// parameters. A new version is created on every change.
+type WorkflowVersion struct {
+	ID          string              `json:"id" db:"id"`
+	WorkflowID  string              `json:"workflow_id" db:"workflow_id"`
+	Version     int                 `json:"version" db:"version"`
+	ChangeType  VersionChange       `json:"change_type" db:"change_type"`
+	Actions     []SemanticAction    `json:"actions" db:"actions"`
+	Parameters  []WorkflowParameter `json:"params" db:"parameters"`
+	CodeVersion int                 `json:"code_version,omitempty" db:"code_version"` // CodeGeneration created with this version
+	CreatedAt   time.Time           `json:"created_at" db:"created_at"`
+}
+
+// VersionChange records why a workflow version was created
+type VersionChange string
+
+const (
+	VersionCreated          VersionChange = "created"
+	VersionImported         VersionChange = "imported"
+	VersionCloned           VersionChange = "cloned" // copied from another workflow
+	VersionMerged           VersionChange = "merged" // joined from other workflows
+	VersionActionsEdited    VersionChange = "actions_edited"
+	VersionParametersEdited VersionChange = "parameters_edited"
+	VersionRegenerated      VersionChange = "regenerated"
+	VersionSelectorsHealed  VersionChange = "selectors_healed" // a run's fallback selectors written back
+)
+
 // WorkflowParameter represents a variable or fixed token in the workflow
 type WorkflowParameter struct {
 	Name         string        `json:"name"`
@@ -224,6 +1173,141 @@ type WorkflowParameter struct {
 	SourceAction int           `json:"source_action,omitempty"` // Which action this came from
 }
 
+// WorkflowTemplate is a sequence of actions, such as a search or a login,
+// that workflows can be created from without a recording. Its parameters
+// fill the values of the actions their source_action numbers.
+type WorkflowTemplate struct {
+	ID          string              `json:"id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	StartURL    string              `json:"start_url,omitempty"`
+	Actions     []SemanticAction    `json:"actions"`
+	Parameters  []WorkflowParameter `json:"parameters"`
+	BuiltIn     bool                `json:"built_in"`   // shipped with the automator, so it can't be changed
+	UpdatedAt   *time.Time          `json:"updated_at"` // nil for built-in templates
+}
+
+// Validate checks the template has a name and known, numbered actions, and
+// its parameters have unique names and fill one of them each
+func (t WorkflowTemplate) Validate() error {
+	if err := (WorkflowUpdate{Name: &t.Name, StartURL: &t.StartURL}).Validate(); err != nil {
+		return err
+	}
+	if len(t.Actions) == 0 {
+		return fmt.Errorf("template has no actions")
+	}
+	for i, action := range t.Actions {
+		if action.SequenceID != i+1 {
+			return fmt.Errorf("action %d has sequence_id %d", i+1, action.SequenceID)
+		}
+		if !action.ActionType.Known() {
+			return fmt.Errorf("action %d: unknown action type %q", i+1, action.ActionType)
+		}
+		if err := action.Validate(); err != nil {
+			return err
+		}
+	}
+
+	names := map[string]bool{}
+	for _, param := range t.Parameters {
+		if param.Name == "" {
+			return fmt.Errorf("parameter has no name")
+		}
+		if names[param.Name] {
+			return fmt.Errorf("parameter %q is listed twice", param.Name)
+		}
+		names[param.Name] = true
+		if param.SourceAction < 1 || param.SourceAction > len(t.Actions) {
+			return fmt.Errorf("parameter %q fills no action: source_action must be between 1 and %d", param.Name, len(t.Actions))
+		}
+	}
+	return nil
+}
+
+// Instantiate returns the template's actions and parameters with the values
+// given, or else the parameters' defaults, filled in. It fails when a value
+// names no parameter or a required one has no value.
+func (t WorkflowTemplate) Instantiate(values map[string]string) ([]SemanticAction, []WorkflowParameter, error) {
+	for name := range values {
+		if !slices.ContainsFunc(t.Parameters, func(p WorkflowParameter) bool { return p.Name == name }) {
+			return nil, nil, fmt.Errorf("template has no parameter %q", name)
+		}
+	}
+
+	actions := slices.Clone(t.Actions)
+	params := slices.Clone(t.Parameters)
+	for i, param := range params {
+		value, ok := values[param.Name]
+		if !ok {
+			value = param.DefaultValue
+		}
+		if value == "" && param.Required {
+			return nil, nil, fmt.Errorf("parameter %q is required", param.Name)
+		}
+		params[i].DefaultValue = value
+		params[i].TokenType = TokenVariable
+		actions[param.SourceAction-1].Value = value
+	}
+	return actions, params, nil
+}
+
+// Recording is the actions and parameters of one recorded workflow
+type Recording struct {
+	Actions    []SemanticAction
+	Parameters []WorkflowParameter
+}
+
+// MergeRecordings joins recordings into one, their actions one recording
+// after another or, interleaved, in the order they were recorded in. The
+// actions are numbered again, and the parameters point at them; a parameter
+// named like one of an earlier recording gets a suffix such as "_2", so each
+// stays its own input.
+func MergeRecordings(recordings []Recording, interleave bool) Recording {
+	type source struct {
+		recording int
+		action    SemanticAction
+	}
+	var sources []source
+	for i, recording := range recordings {
+		for _, action := range recording.Actions {
+			sources = append(sources, source{i, action})
+		}
+	}
+	if interleave {
+		slices.SortStableFunc(sources, func(a, b source) int {
+			return cmp.Compare(a.action.Timestamp, b.action.Timestamp)
+		})
+	}
+
+	var merged Recording
+	renumbered := make([]map[int]int, len(recordings))
+	for i := range renumbered {
+		renumbered[i] = map[int]int{}
+	}
+	for i, src := range sources {
+		renumbered[src.recording][src.action.SequenceID] = i + 1
+		src.action.SequenceID = i + 1
+		merged.Actions = append(merged.Actions, src.action)
+	}
+
+	names := map[string]bool{}
+	for i, recording := range recordings {
+		for _, param := range recording.Parameters {
+			if param.SourceAction != 0 {
+				param.SourceAction = renumbered[i][param.SourceAction]
+			}
+			name := param.Name
+			for n := 2; names[name]; n++ {
+				name = fmt.Sprintf("%s_%d", param.Name, n)
+			}
+			param.Name = name
+			names[name] = true
+			merged.Parameters = append(merged.Parameters, param)
+		}
+	}
+	return merged
+}
+
 // ParameterType represents the data type of a parameter
 type ParameterType string
 
@@ -243,6 +1327,256 @@ const (
 	TokenFixed    TokenType = "fixed"
 )
 
+// CodeTemplate is a user-supplied text/template overriding the built-in
+// template-based code for one action type
+type CodeTemplate struct {
+	ActionType ActionType `json:"action_type" db:"action_type"`
+	Template   string     `json:"template" db:"template"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// SavedSession is a browser storage state saved at the end of a run for
+// later runs to start from. The state itself is stored encrypted and never
+// returned by the API.
+type SavedSession struct {
+	Name       string    `json:"name" db:"name"`
+	WorkflowID string    `json:"workflow_id,omitempty" db:"workflow_id"`
+	RunID      string    `json:"run_id,omitempty" db:"run_id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ==================== Audit Types ====================
+
+// AuditEvent records a request to the API that changed something: who made
+// it, from where, to what and how it ended. Request bodies, which may hold
+// secrets such as API keys, aren't recorded.
+type AuditEvent struct {
+	ID int64 `json:"id" db:"id"`
+	// Actor is the user the authenticating proxy in front of the API made
+	// the request for, empty without one
+	Actor      string `json:"actor" db:"actor"`
+	RemoteAddr string `json:"remote_addr" db:"remote_addr"`
+	Method     string `json:"method" db:"method"`
+	// Route is the path template the request matched, such as
+	// /api/workflows/{id}, and Path the path it was made to
+	Route string `json:"route" db:"route"`
+	Path  string `json:"path" db:"path"`
+	// ResourceID is the workflow, run or other resource the request created
+	// or else the one its path names
+	ResourceID string    `json:"resource_id,omitempty" db:"resource_id"`
+	Status     int       `json:"status" db:"status"`
+	RequestID  string    `json:"request_id,omitempty" db:"request_id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// AuditFilter selects the audit events to list
+type AuditFilter struct {
+	ListOptions
+	// Actor, ResourceID and Method match the events' exactly, any when
+	// empty
+	Actor, ResourceID, Method string
+}
+
+// AuditSorts are the fields audit events can be sorted by
+var AuditSorts = []string{"created_at"}
+
+// ==================== Pipeline Types ====================
+
+// Pipeline runs workflows one after another, feeding each the outputs of
+// the one before. When any step names the steps it depends on, the steps
+// form a graph instead: each runs once its dependencies have succeeded, in
+// parallel with the other steps that are ready.
+type Pipeline struct {
+	ID        string         `json:"id" db:"id"`
+	Name      string         `json:"name" db:"name"`
+	Steps     []PipelineStep `json:"steps" db:"steps"` // JSON column
+	CreatedAt time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// PipelineStep is a workflow run of a pipeline
+type PipelineStep struct {
+	// ID names the step for the steps depending on it
+	ID         string            `json:"id,omitempty"`
+	WorkflowID string            `json:"workflow_id"`
+	DependsOn  []string          `json:"depends_on,omitempty"`
+	Parameters map[string]string `json:"parameters,omitempty"`
+	// Inputs set parameters, by name, to outputs of a dependency: "output"
+	// of the only one, or "step.output"
+	Inputs map[string]string `json:"inputs,omitempty"`
+	// ShareSession starts the step with the cookies and localStorage its
+	// only dependency ended with
+	ShareSession bool `json:"share_session,omitempty"`
+}
+
+// Validate checks the pipeline, and that its steps form no cycle
+func (p Pipeline) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("pipeline name is required")
+	}
+	if len(p.Steps) == 0 {
+		return fmt.Errorf("pipeline has no steps")
+	}
+
+	ids := make(map[string]bool, len(p.Steps))
+	for i, step := range p.Steps {
+		if step.WorkflowID == "" {
+			return fmt.Errorf("step %d: workflow_id is required", i+1)
+		}
+		if step.ID != "" {
+			if ids[step.ID] {
+				return fmt.Errorf("step %d: duplicate id %q", i+1, step.ID)
+			}
+			ids[step.ID] = true
+		}
+	}
+
+	deps, err := p.Dependencies()
+	if err != nil {
+		return err
+	}
+	for i, step := range p.Steps {
+		if step.ShareSession && len(deps[i]) != 1 {
+			return fmt.Errorf("step %d: share_session needs exactly one dependency", i+1)
+		}
+		for param, source := range step.Inputs {
+			if _, _, err := p.InputSource(i, deps[i], source); err != nil {
+				return fmt.Errorf("step %d: input %q: %w", i+1, param, err)
+			}
+		}
+	}
+
+	// Visit the steps in dependency order; steps left over are on a cycle
+	done := make([]bool, len(p.Steps))
+	for progress := true; progress; {
+		progress = false
+		for i := range p.Steps {
+			if done[i] {
+				continue
+			}
+			ready := true
+			for _, dep := range deps[i] {
+				ready = ready && done[dep]
+			}
+			if ready {
+				done[i] = true
+				progress = true
+			}
+		}
+	}
+	for i := range p.Steps {
+		if !done[i] {
+			return fmt.Errorf("step %d: dependencies form a cycle", i+1)
+		}
+	}
+	return nil
+}
+
+// Dependencies returns the indexes of the steps each step depends on:
+// the step before it, unless some step names its dependencies
+func (p Pipeline) Dependencies() ([][]int, error) {
+	deps := make([][]int, len(p.Steps))
+
+	graph := false
+	for _, step := range p.Steps {
+		graph = graph || len(step.DependsOn) > 0
+	}
+	if !graph {
+		for i := 1; i < len(p.Steps); i++ {
+			deps[i] = []int{i - 1}
+		}
+		return deps, nil
+	}
+
+	index := make(map[string]int, len(p.Steps))
+	for i, step := range p.Steps {
+		if step.ID != "" {
+			index[step.ID] = i
+		}
+	}
+	for i, step := range p.Steps {
+		for _, id := range step.DependsOn {
+			dep, ok := index[id]
+			if !ok {
+				return nil, fmt.Errorf("step %d: depends on unknown step %q", i+1, id)
+			}
+			if dep == i {
+				return nil, fmt.Errorf("step %d: depends on itself", i+1)
+			}
+			deps[i] = append(deps[i], dep)
+		}
+	}
+	return deps, nil
+}
+
+// InputSource resolves the source of an input of step i, given its
+// dependencies, to the dependency and its output the value comes from
+func (p Pipeline) InputSource(i int, deps []int, source string) (int, string, error) {
+	if step, output, ok := strings.Cut(source, "."); ok {
+		for _, dep := range deps {
+			if p.Steps[dep].ID == step {
+				return dep, output, nil
+			}
+		}
+	}
+	if len(deps) != 1 {
+		return 0, "", fmt.Errorf("%q must name one of the step's dependencies as step.output", source)
+	}
+	return deps[0], source, nil
+}
+
+// ==================== Schedule Types ====================
+
+// minScheduleInterval is the shortest interval a schedule may run at
+const minScheduleInterval = 60
+
+// Schedule runs a workflow on a cron expression or at a fixed interval
+type Schedule struct {
+	ID          string            `json:"id" db:"id"`
+	WorkflowID  string            `json:"workflow_id" db:"workflow_id"`
+	Cron        string            `json:"cron,omitempty" db:"cron"`                         // e.g. "0 9 * * 1-5"
+	Interval    int               `json:"interval_seconds,omitempty" db:"interval_seconds"` // instead of a cron expression
+	Timezone    string            `json:"timezone,omitempty" db:"timezone"`                 // IANA zone for cron, UTC when empty
+	Parameters  map[string]string `json:"parameters,omitempty" db:"parameters"`             // JSON column
+	Headless    bool              `json:"headless" db:"headless"`
+	LLMProvider string            `json:"llm_provider,omitempty" db:"llm_provider"`
+	Paused      bool              `json:"paused" db:"paused"`
+	CreatedAt   time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// Validate checks the schedule has a workflow and one valid timing
+func (s Schedule) Validate() error {
+	if s.WorkflowID == "" {
+		return fmt.Errorf("workflow_id is required")
+	}
+	if (s.Cron == "") == (s.Interval == 0) {
+		return fmt.Errorf("set either cron or interval_seconds")
+	}
+	if s.Cron != "" && !strings.HasPrefix(s.Cron, "@") {
+		if n := len(strings.Fields(s.Cron)); n < 5 || n > 7 {
+			return fmt.Errorf("invalid cron expression: %s", s.Cron)
+		}
+	}
+	if s.Cron == "" && s.Interval < minScheduleInterval {
+		return fmt.Errorf("interval_seconds must be at least %d", minScheduleInterval)
+	}
+	if s.Timezone != "" {
+		if _, err := time.LoadLocation(s.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone: %s", s.Timezone)
+		}
+	}
+	return nil
+}
+
+// ScheduleDetail is a schedule with its upcoming and latest runs
+type ScheduleDetail struct {
+	Schedule
+	NextRuns   []time.Time   `json:"next_runs"`
+	RecentRuns []WorkflowRun `json:"recent_runs"`
+}
+
 // ==================== Workflow Run Types ====================
 
 // WorkflowRun represents a single execution of a workflow
@@ -256,6 +1590,16 @@ type WorkflowRun struct {
 	StartedAt          *time.Time `json:"started_at" db:"started_at"`
 	CompletedAt        *time.Time `json:"completed_at" db:"completed_at"`
 	ErrorMessage       string     `json:"error_message,omitempty" db:"error_message"`
+	WorkflowVersion    int        `json:"workflow_version,omitempty" db:"workflow_version"`
+	// ScheduleID is the schedule that started the run
+	ScheduleID string `json:"schedule_id,omitempty" db:"schedule_id"`
+	// Outputs are the values the run produced by name, such as the ones
+	// its extract actions read
+	Outputs map[string]string `json:"outputs,omitempty" db:"outputs"`
+	// Dataset are the rows of values the run's extract actions read
+	Dataset []map[string]string `json:"dataset,omitempty" db:"dataset"`
+	// DeletedAt is when the run was moved to the trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
 
 	// Computed fields
 	Parameters    map[string]string `json:"params,omitempty"`
@@ -271,8 +1615,64 @@ const (
 	StatusSuccess  RunStatus = "success"
 	StatusFailed   RunStatus = "failed"
 	StatusCanceled RunStatus = "canceled"
+	StatusPaused   RunStatus = "paused"
+	// StatusWarning is a run that succeeded although some of its actions
+	// didn't have the outcomes they had while recording
+	StatusWarning RunStatus = "warning"
+	// StatusPartial is a batch some of whose runs failed or were canceled
+	StatusPartial RunStatus = "partial"
+)
+
+// Succeeded reports whether a run finished without failing, possibly with
+// warnings
+func (s RunStatus) Succeeded() bool {
+	return s == StatusSuccess || s == StatusWarning
+}
+
+// Finished reports whether a run with the status has ended
+func (s RunStatus) Finished() bool {
+	return s.Succeeded() || s == StatusFailed || s == StatusCanceled
+}
+
+// Signals an operator sends a running workflow to hold it before an action
+// and let it continue
+const (
+	SignalPause  = "pause"
+	SignalResume = "resume"
 )
 
+// SignalNext lets a debug run execute its next action and hold again
+const SignalNext = "next"
+
+// PauseRequest is the payload of a pause signal. Without a step the run
+// holds before its next action.
+type PauseRequest struct {
+	BeforeStep int `json:"before_step,omitempty"`
+}
+
+// UpdateParameters sets parameter values of a running workflow, taking
+// effect from its next action
+const UpdateParameters = "parameters"
+
+// SignalStep decides what a run holding after a failed action does next
+const SignalStep = "step"
+
+// StepAction is what to do with a failed action
+type StepAction string
+
+const (
+	StepSkip  StepAction = "skip"  // Move on to the next action
+	StepRetry StepAction = "retry" // Run the action again
+)
+
+// StepDecision is the payload of a step signal
+type StepDecision struct {
+	Step   int        `json:"step"`
+	Action StepAction `json:"action"`
+	// Parameters override run parameters for the retry and later actions
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
 // ActionResult represents the result of executing a single action
 type ActionResult struct {
 	ID             string     `json:"id" db:"id"`
@@ -286,22 +1686,575 @@ type ActionResult struct {
 	ErrorMessage   string     `json:"error_message,omitempty" db:"error_message"`
 	ExecutedAt     *time.Time `json:"executed_at" db:"executed_at"`
 	Duration       int64      `json:"duration_ms,omitempty" db:"duration_ms"`
+	// Dialogs are the JavaScript dialogs answered during the action
+	Dialogs []DialogEvent `json:"dialogs,omitempty" db:"-"`
+	// Downloads are the files the action downloaded
+	Downloads []DownloadedFile `json:"downloads,omitempty" db:"-"`
+	// Output is the text a copy action copied or an extract action read
+	Output string `json:"output,omitempty" db:"-"`
+	// TabSwitches are the tabs the run moved to around the action
+	TabSwitches []TabSwitch `json:"tab_switches,omitempty" db:"-"`
+	// Selector is the candidate of the selector chain that found the
+	// action's element, after SelectorFallbacks candidates found nothing
+	Selector          string `json:"selector,omitempty" db:"selector"`
+	SelectorFallbacks int    `json:"selector_fallbacks,omitempty" db:"selector_fallbacks"`
+
+	// Iteration is the 1-based loop iteration the action ran in, 0 outside
+	// loops
+	Iteration int `json:"iteration,omitempty" db:"iteration"`
+
+	// BeforeScreenshotPath and AfterScreenshotPath capture the page around
+	// the action when the run takes screenshots of every action
+	BeforeScreenshotPath string `json:"before_screenshot_path,omitempty" db:"before_screenshot_path"`
+	AfterScreenshotPath  string `json:"after_screenshot_path,omitempty" db:"after_screenshot_path"`
+
+	// Metrics time the page load of a navigate action
+	Metrics *PageMetrics `json:"metrics,omitempty" db:"metrics"`
+
+	// Accessibility is the accessibility audit of the page a navigate
+	// action opened, when the run audits pages
+	Accessibility *AccessibilityAudit `json:"accessibility,omitempty" db:"-"`
+
+	// DOMSnapshotPath holds the page's DOM when the action failed, or after
+	// it when the run takes screenshots of every action
+	DOMSnapshotPath string `json:"dom_snapshot_path,omitempty" db:"dom_snapshot_path"`
+
+	// MissingOutcomes are the elements that appeared after the action
+	// while recording but not when the run verified its outcome
+	MissingOutcomes []SemanticTarget `json:"missing_outcomes,omitempty" db:"missing_outcomes"`
+
+	// SelectorSource is which kind of selector found the action's element
+	SelectorSource SelectorSource `json:"selector_source,omitempty" db:"selector_source"`
+}
+
+// SelectorSource is which kind of selector of an action's chain found its
+// element
+type SelectorSource string
+
+const (
+	SelectorPrimary  SelectorSource = "primary"  // the first recorded selector
+	SelectorFallback SelectorSource = "fallback" // a later recorded selector
+	SelectorHealed   SelectorSource = "healed"   // the selector an earlier run healed the action with
+)
+
+// SelectorHealth reports how a workflow's actions found their elements
+// across its runs, to show which selectors need maintenance
+type SelectorHealth struct {
+	WorkflowID string `json:"workflow_id"`
+	// Days is the window of runs reported, all runs when 0
+	Days    int                    `json:"days,omitempty"`
+	Actions []ActionSelectorHealth `json:"actions"`
+}
+
+// ActionSelectorHealth counts the kinds of selector that found an action's
+// element
+type ActionSelectorHealth struct {
+	ActionID    string `json:"action_id"`
+	SequenceID  int    `json:"sequence_id"`
+	Description string `json:"description,omitempty"`
+	Executions  int    `json:"executions"`
+	Primary     int    `json:"primary"`
+	Fallback    int    `json:"fallback"`
+	Healed      int    `json:"healed"`
+	// FallbackRate is the share of executions the primary selector missed
+	FallbackRate float64 `json:"fallback_rate"`
+	// NeedsMaintenance is whether the primary selector consistently
+	// misses, as defined by SelectorMaintenanceRate
+	NeedsMaintenance bool            `json:"needs_maintenance"`
+	Selectors        []SelectorUsage `json:"selectors"`
+}
+
+// SelectorMaintenanceRate and SelectorMaintenanceRuns define a selector that
+// consistently needs a fallback: one missing in at least this share of at
+// least this many executions
+const (
+	SelectorMaintenanceRate = 0.5
+	SelectorMaintenanceRuns = 3
+)
+
+// SelectorUsage counts the executions a selector found an action's element
+type SelectorUsage struct {
+	Selector string         `json:"selector"`
+	Source   SelectorSource `json:"source"`
+	Count    int            `json:"count"`
+}
+
+// WorkflowAnalytics aggregates a workflow's action results across its runs
+// to show which steps are flaky
+type WorkflowAnalytics struct {
+	WorkflowID string `json:"workflow_id"`
+	// Days is the window of runs aggregated, all runs when 0
+	Days    int               `json:"days,omitempty"`
+	Runs    int               `json:"runs"`
+	Actions []ActionAnalytics `json:"actions"`
+}
+
+// ActionAnalytics are the statistics of an action's results across runs.
+// Durations are in milliseconds.
+type ActionAnalytics struct {
+	ActionID    string `json:"action_id"`
+	SequenceID  int    `json:"sequence_id"`
+	Description string `json:"description,omitempty"`
+	// Executions counts the action's finished results, more than its runs
+	// when it ran in loops
+	Executions  int     `json:"executions"`
+	Runs        int     `json:"runs"`
+	Failures    int     `json:"failures"`
+	FailureRate float64 `json:"failure_rate"`
+	// Flaky is whether the action both failed and succeeded
+	Flaky                bool    `json:"flaky"`
+	AvgRetries           float64 `json:"avg_retries"`
+	AvgSelectorFallbacks float64 `json:"avg_selector_fallbacks"`
+	AvgDuration          float64 `json:"avg_duration_ms"`
+	DurationStdDev       float64 `json:"duration_stddev_ms"`
+	DurationVariance     float64 `json:"duration_variance"` // in ms²
+	MinDuration          int64   `json:"min_duration_ms"`
+	MaxDuration          int64   `json:"max_duration_ms"`
+}
+
+// AccessibilityAudit summarizes the axe-core scan of a page. The full
+// report, with each violating element, is kept among the run's downloads.
+type AccessibilityAudit struct {
+	URL        string                   `json:"url"`
+	Violations []AccessibilityViolation `json:"violations"`
+	Report     string                   `json:"report,omitempty"` // file name under /runs/{id}/downloads/
+}
+
+// AccessibilityViolation is an axe-core rule the page breaks
+type AccessibilityViolation struct {
+	Rule    string `json:"rule"`
+	Impact  string `json:"impact,omitempty"` // minor, moderate, serious or critical
+	Help    string `json:"help"`
+	HelpURL string `json:"help_url,omitempty"`
+	Nodes   int    `json:"nodes"` // elements breaking the rule
+}
+
+// PageMetrics are the navigation timings of a page load, in milliseconds
+// since the navigation started. Timings the browser didn't report are 0.
+type PageMetrics struct {
+	URL              string  `json:"url"`
+	TTFB             float64 `json:"ttfb_ms"`
+	DOMContentLoaded float64 `json:"dom_content_loaded_ms"`
+	Load             float64 `json:"load_ms"`
+	FCP              float64 `json:"fcp_ms,omitempty"`
+	// LCP approximates the largest contentful paint by the largest paint
+	// up to when the metrics were taken
+	LCP          float64 `json:"lcp_ms,omitempty"`
+	TransferSize int64   `json:"transfer_bytes,omitempty"`
+}
+
+// TabSwitch is the run moving to a tab a page opened, or back to the opener
+// after the tab closed
+type TabSwitch struct {
+	URL    string `json:"url"`
+	Closed bool   `json:"closed,omitempty"` // back to the opener
+}
+
+// DownloadedFile is a file a run downloaded, kept in the worker's download
+// directory under the run's ID
+type DownloadedFile struct {
+	Filename string `json:"filename"`
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+	URL      string `json:"url"` // where it was downloaded from
+}
+
+// DelayStrategy controls how long replay waits between consecutive actions
+type DelayStrategy string
+
+const (
+	DelayNone           DelayStrategy = "none"            // Run actions back to back
+	DelayFixed          DelayStrategy = "fixed"           // Wait a constant interval
+	DelayRecorded       DelayStrategy = "recorded"        // Reproduce the recorded gaps
+	DelayCappedRecorded DelayStrategy = "capped_recorded" // Recorded gaps, limited to a maximum
+)
+
+// Default delay intervals in milliseconds
+const (
+	DefaultFixedDelayMs = 500
+	DefaultMaxDelayMs   = 2000
+)
+
+// DelayConfig selects the delay strategy shared by the script generator and
+// the workflow executor. The zero value runs actions without delays.
+type DelayConfig struct {
+	Strategy DelayStrategy `json:"strategy,omitempty"`
+	FixedMs  int64         `json:"fixed_ms,omitempty"` // Interval for DelayFixed
+	MaxMs    int64         `json:"max_ms,omitempty"`   // Cap for DelayCappedRecorded
+}
+
+// Validate reports an unknown strategy or negative interval
+func (c DelayConfig) Validate() error {
+	switch c.Strategy {
+	case "", DelayNone, DelayFixed, DelayRecorded, DelayCappedRecorded:
+	default:
+		return fmt.Errorf("unknown delay strategy: %s", c.Strategy)
+	}
+	if c.FixedMs < 0 || c.MaxMs < 0 {
+		return fmt.Errorf("delay intervals must not be negative")
+	}
+	return nil
+}
+
+// Between returns how long to wait after prev before running next
+func (c DelayConfig) Between(prev, next SemanticAction) time.Duration {
+	var ms int64
+	switch c.Strategy {
+	case DelayFixed:
+		ms = c.FixedMs
+		if ms == 0 {
+			ms = DefaultFixedDelayMs
+		}
+	case DelayRecorded:
+		ms = next.Timestamp - prev.Timestamp
+	case DelayCappedRecorded:
+		ms = next.Timestamp - prev.Timestamp
+		max := c.MaxMs
+		if max == 0 {
+			max = DefaultMaxDelayMs
+		}
+		if ms > max {
+			ms = max
+		}
+	}
+	if ms < 0 {
+		ms = 0
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// BrowserEngine selects the browser a run executes in
+type BrowserEngine string
+
+const (
+	BrowserChromium BrowserEngine = "chromium" // Go Rod, the default
+	BrowserFirefox  BrowserEngine = "firefox"  // Playwright
+	BrowserWebKit   BrowserEngine = "webkit"   // Playwright
+)
+
+// Validate reports an unknown engine. The empty engine is Chromium.
+func (e BrowserEngine) Validate() error {
+	switch e {
+	case "", BrowserChromium, BrowserFirefox, BrowserWebKit:
+		return nil
+	}
+	return fmt.Errorf("unknown browser: %s", e)
+}
+
+// RunPriority is how urgently a run is to get a worker and a browser
+type RunPriority string
+
+const (
+	PriorityHigh   RunPriority = "high"   // urgent operational runs
+	PriorityNormal RunPriority = "normal" // the default
+	PriorityLow    RunPriority = "low"    // background work such as batches
+)
+
+// RunPriorities are the priorities, most urgent first
+var RunPriorities = []RunPriority{PriorityHigh, PriorityNormal, PriorityLow}
+
+// Validate reports an unknown priority. The empty priority is normal.
+func (p RunPriority) Validate() error {
+	switch p {
+	case "", PriorityHigh, PriorityNormal, PriorityLow:
+		return nil
+	}
+	return fmt.Errorf("unknown priority: %s, want high, normal or low", p)
+}
+
+// Rank orders priorities, 0 being the most urgent
+func (p RunPriority) Rank() int {
+	switch p {
+	case PriorityHigh:
+		return 0
+	case PriorityLow:
+		return 2
+	}
+	return 1
+}
+
+// DeviceConfig emulates a device's viewport, either a preset by name or
+// explicit metrics. Explicit fields override the preset's.
+type DeviceConfig struct {
+	Name      string  `json:"name,omitempty"` // Preset from DevicePresets
+	Width     int     `json:"width,omitempty"`
+	Height    int     `json:"height,omitempty"`
+	Scale     float64 `json:"scale,omitempty"` // Device pixel ratio
+	Mobile    bool    `json:"mobile,omitempty"`
+	Touch     bool    `json:"touch,omitempty"`
+	UserAgent string  `json:"user_agent,omitempty"`
+}
+
+// DevicePresets are the devices a DeviceConfig can name
+var DevicePresets = map[string]DeviceConfig{
+	"desktop": {Width: 1920, Height: 1080, Scale: 1},
+	"laptop":  {Width: 1366, Height: 768, Scale: 1},
+	"iphone-se": {Width: 375, Height: 667, Scale: 2, Mobile: true, Touch: true,
+		UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"},
+	"iphone-14": {Width: 390, Height: 844, Scale: 3, Mobile: true, Touch: true,
+		UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"},
+	"pixel-7": {Width: 412, Height: 915, Scale: 2.625, Mobile: true, Touch: true,
+		UserAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36"},
+	"ipad": {Width: 820, Height: 1180, Scale: 2, Mobile: true, Touch: true,
+		UserAgent: "Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"},
+}
+
+// Resolve applies the named preset and validates the metrics, returning the
+// device to emulate. A nil config emulates nothing.
+func (d *DeviceConfig) Resolve() (*DeviceConfig, error) {
+	if d == nil {
+		return nil, nil
+	}
+
+	resolved := DeviceConfig{}
+	if d.Name != "" {
+		preset, ok := DevicePresets[strings.ToLower(d.Name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown device: %s", d.Name)
+		}
+		resolved = preset
+		resolved.Name = strings.ToLower(d.Name)
+	}
+	if d.Width != 0 {
+		resolved.Width = d.Width
+	}
+	if d.Height != 0 {
+		resolved.Height = d.Height
+	}
+	if d.Scale != 0 {
+		resolved.Scale = d.Scale
+	}
+	if d.UserAgent != "" {
+		resolved.UserAgent = d.UserAgent
+	}
+	resolved.Mobile = resolved.Mobile || d.Mobile
+	resolved.Touch = resolved.Touch || d.Touch
+
+	if resolved.Width <= 0 || resolved.Height <= 0 {
+		return nil, fmt.Errorf("device needs a preset name or a positive width and height")
+	}
+	if resolved.Scale < 0 {
+		return nil, fmt.Errorf("device scale must not be negative")
+	}
+	if resolved.Scale == 0 {
+		resolved.Scale = 1
+	}
+	return &resolved, nil
+}
+
+// localePattern matches BCP 47 language tags such as en, de-DE or zh-Hant-TW
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// ValidateLocale reports a malformed locale or unknown IANA timezone. Empty
+// values keep the browser's defaults.
+func ValidateLocale(locale, timezone string) error {
+	if locale != "" && !localePattern.MatchString(locale) {
+		return fmt.Errorf("invalid locale: %s", locale)
+	}
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return fmt.Errorf("invalid timezone: %s", timezone)
+		}
+	}
+	return nil
+}
+
+// ProxyConfig routes a run's traffic through an HTTP or SOCKS proxy
+type ProxyConfig struct {
+	Server   string `json:"server"` // host:port or http://, https://, socks4:// or socks5:// URL
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Bypass   string `json:"bypass,omitempty"` // Comma-separated hosts reached directly
+}
+
+// Resolve normalizes the server to a scheme://host:port URL, moving any
+// credentials in it to Username and Password. A nil config uses no proxy.
+func (p *ProxyConfig) Resolve() (*ProxyConfig, error) {
+	if p == nil {
+		return nil, nil
+	}
+
+	server := strings.TrimSpace(p.Server)
+	if server == "" {
+		return nil, fmt.Errorf("proxy server is required")
+	}
+	if !strings.Contains(server, "://") {
+		server = "http://" + server
+	}
+	u, err := url.Parse(server)
+	if err != nil || u.Hostname() == "" || u.Port() == "" {
+		return nil, fmt.Errorf("invalid proxy server %q: want scheme://host:port", p.Server)
+	}
+
+	resolved := *p
+	if u.User != nil {
+		resolved.Username = u.User.Username()
+		resolved.Password, _ = u.User.Password()
+	}
+	switch u.Scheme {
+	case "http", "https":
+	case "socks4", "socks5":
+		// Chrome cannot answer SOCKS authentication
+		if resolved.Username != "" {
+			return nil, fmt.Errorf("SOCKS proxies with credentials are not supported")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: %s", u.Scheme)
+	}
+	resolved.Server = u.Scheme + "://" + u.Host
+	return &resolved, nil
+}
+
+// HTTPCredentials answer the HTTP authentication challenges of sites
+type HTTPCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Origin limits the credentials to one site, such as
+	// https://intranet.example.com. Any site that asks gets them when empty.
+	Origin string `json:"origin,omitempty"`
+}
+
+// Validate checks the credentials. A nil config sends none.
+func (c *HTTPCredentials) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.Username == "" {
+		return fmt.Errorf("http_credentials username is required")
+	}
+	if c.Origin != "" {
+		u, err := url.Parse(strings.TrimSuffix(c.Origin, "/"))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" {
+			return fmt.Errorf("invalid http_credentials origin %q: want scheme://host[:port]", c.Origin)
+		}
+	}
+	return nil
+}
+
+// StorageState is a browser's cookies and localStorage in Playwright's
+// storageState format, injected before a run to start it logged in
+type StorageState struct {
+	Cookies []StorageCookie `json:"cookies"`
+	Origins []StorageOrigin `json:"origins"`
+}
+
+// StorageCookie is a cookie of a StorageState
+type StorageCookie struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	Domain   string  `json:"domain"`
+	Path     string  `json:"path"`
+	Expires  float64 `json:"expires"` // Unix seconds, -1 for a session cookie
+	HTTPOnly bool    `json:"httpOnly"`
+	Secure   bool    `json:"secure"`
+	SameSite string  `json:"sameSite,omitempty"` // Strict, Lax or None
+}
+
+// StorageOrigin is the localStorage of one origin of a StorageState
+type StorageOrigin struct {
+	Origin       string        `json:"origin"`
+	LocalStorage []StorageItem `json:"localStorage"`
+}
+
+// StorageItem is a localStorage entry
+type StorageItem struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Validate reports cookies without a name or domain, unknown SameSite
+// values and origins that are not scheme://host URLs
+func (s *StorageState) Validate() error {
+	if s == nil {
+		return nil
+	}
+	for _, c := range s.Cookies {
+		if c.Name == "" || c.Domain == "" {
+			return fmt.Errorf("storage state cookies need a name and domain")
+		}
+		switch c.SameSite {
+		case "", "Strict", "Lax", "None":
+		default:
+			return fmt.Errorf("cookie %s: unknown sameSite %q", c.Name, c.SameSite)
+		}
+	}
+	for _, o := range s.Origins {
+		u, err := url.Parse(o.Origin)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid storage state origin: %q", o.Origin)
+		}
+	}
+	return nil
 }
 
 // ==================== API Request/Response Types ====================
 
 // WorkflowInput represents input for executing a workflow
 type WorkflowInput struct {
-	WorkflowID    string              `json:"workflow_id"`
-	RunID         string              `json:"run_id"`
-	Parameters    map[string]string   `json:"parameters"`
-	Params        []WorkflowParameter `json:"params"`
-	Actions       []SemanticAction    `json:"actions"`
-	LLMProvider   string              `json:"llm_provider"`
-	LLMAPIKey     string              `json:"llm_api_key,omitempty"` // API key from UI
-	Headless      bool                `json:"headless"`
-	Timeout       int                 `json:"timeout_seconds"`
-	RetryAttempts int                 `json:"retry_attempts"`
+	WorkflowID   string              `json:"workflow_id"`
+	RunID        string              `json:"run_id"`
+	Parameters   map[string]string   `json:"parameters"`
+	Params       []WorkflowParameter `json:"params"`
+	Actions      []SemanticAction    `json:"actions"`
+	LLMProvider  string              `json:"llm_provider"`
+	LLMAPIKey    string              `json:"llm_api_key,omitempty"` // API key from UI
+	Headless     bool                `json:"headless"`
+	ReuseBrowser bool                `json:"reuse_browser"` // run in an incognito context of a warm browser
+	// BrowserEndpoint is a remote Chrome to run in instead of the worker's own
+	BrowserEndpoint string        `json:"browser_endpoint,omitempty"`
+	Browser         BrowserEngine `json:"browser,omitempty"` // chromium when empty
+	Device          *DeviceConfig `json:"device,omitempty"`  // resolved device to emulate
+	UserAgent       string        `json:"user_agent,omitempty"`
+	Locale          string        `json:"locale,omitempty"`
+	Timezone        string        `json:"timezone,omitempty"`
+	Proxy           *ProxyConfig  `json:"proxy,omitempty"`
+	Stealth         bool          `json:"stealth,omitempty"` // hide automation signals from sites
+	StorageState    *StorageState `json:"storage_state,omitempty"`
+	// Session is a saved session to start from instead of StorageState.
+	// The worker opens it, so its cookies stay out of the run's history.
+	Session string `json:"session,omitempty"`
+	// HTTPCredentials answer sites' basic, digest and NTLM challenges
+	HTTPCredentials *HTTPCredentials `json:"http_credentials,omitempty"`
+	Dialogs         *DialogPolicy    `json:"dialogs,omitempty"`      // the workflow's dialog policy
+	SaveSession     string           `json:"save_session,omitempty"` // name to save the final storage state under
+	Timeout         int              `json:"timeout_seconds"`
+	RetryAttempts   int              `json:"retry_attempts"`
+	Retry           *RetryPolicy     `json:"retry,omitempty"` // overrides the default retries
+	Delay           DelayConfig      `json:"delay"`
+	// CodeTemplates overrides the worker's code templates by action type
+	CodeTemplates map[ActionType]string `json:"code_templates,omitempty"`
+
+	// SkipStabilityChecks, HealSelectors, DecisionTimeout and Loops are the
+	// workflow's settings, selectors only healed by runs of the latest version
+	SkipStabilityChecks bool `json:"skip_stability_checks,omitempty"`
+	HealSelectors       bool `json:"heal_selectors,omitempty"`
+	DecisionTimeout     int  `json:"decision_timeout_seconds,omitempty"`
+
+	Loops []Loop `json:"loops,omitempty"`
+	// MaxConcurrentRuns has the run wait for the workflow's runs started
+	// before it until fewer than this many are active
+	MaxConcurrentRuns int `json:"max_concurrent_runs,omitempty"`
+	// Notifications announce how the run ended
+	Notifications []NotificationTarget `json:"notifications,omitempty"`
+	// ScheduleID is the schedule that started the run
+	ScheduleID string `json:"schedule_id,omitempty"`
+	// Priority has the run's browser go to it before runs of lower
+	// priority waiting for one
+	Priority RunPriority `json:"priority,omitempty"`
+	// DryRun generates the code and resolves the values of every step
+	// without launching a browser
+	DryRun bool `json:"dry_run,omitempty"`
+	// Debug holds the run before each action until a next signal, capturing
+	// the page after each
+	Debug bool `json:"debug,omitempty"`
+	// Screenshots captures the page before and after every action
+	Screenshots bool `json:"screenshots,omitempty"`
+	// Accessibility audits the page after every navigate action
+	Accessibility bool `json:"accessibility,omitempty"`
+	// VerifyOutcomes checks that actions have the outcomes they had while
+	// recording, ending the run with a warning when they don't
+	VerifyOutcomes bool `json:"verify_outcomes,omitempty"`
 }
 
 // WorkflowResult represents the result of a workflow execution
@@ -311,8 +2264,96 @@ type WorkflowResult struct {
 	ActionResults []ActionResult `json:"action_results"`
 	TotalDuration int64          `json:"total_duration_ms"`
 	ErrorMessage  string         `json:"error_message,omitempty"`
+	// Outputs are values the run produced by name, such as ClipboardOutput
+	// and the values extract actions read
+	Outputs map[string]string `json:"outputs,omitempty"`
+	// Compensations are the results of the compensations run after the run
+	// was aborted, latest action first, by the sequence ID they undo
+	Compensations []ActionResult `json:"compensations,omitempty"`
+	// Plan is what a dry run would have executed, step by step
+	Plan []PlannedAction `json:"plan,omitempty"`
+	// Debug is the page after the latest step of a debug run
+	Debug *DebugSnapshot `json:"debug,omitempty"`
+	// ScreencastURL is where the worker streams the run's browser while it
+	// is open
+	ScreencastURL string `json:"screencast_url,omitempty"`
+	// Dataset are the values extract actions read: a row for each loop
+	// iteration extracting values, or a single row
+	Dataset []map[string]string `json:"dataset,omitempty"`
+	// Progress is the step the run is on, while it runs its steps
+	Progress *RunProgress `json:"progress,omitempty"`
+}
+
+// RunProgress is the step a run is on and what it's doing with it
+type RunProgress struct {
+	Step       int         `json:"step"`  // 1-based, among the steps loops expand to
+	Total      int         `json:"total"` // steps the run has
+	SequenceID int         `json:"sequence_id"`
+	Iteration  int         `json:"iteration,omitempty"` // 1-based loop iteration, 0 outside loops
+	Phase      ActionPhase `json:"phase"`
+	Attempt    int         `json:"attempt,omitempty"` // of the action, 1 unless it's retried
+}
+
+// ActionPhase is what a run is doing with its current step
+type ActionPhase string
+
+const (
+	PhaseWaiting    ActionPhase = "waiting"    // the delay before the action
+	PhaseGenerating ActionPhase = "generating" // code for an action without pre-generated code
+	PhaseExecuting  ActionPhase = "executing"
+	PhaseRetrying   ActionPhase = "retrying" // executing again after failing
+)
+
+// SignalActionPhase is sent by the worker executing an action when it
+// moves to another phase, such as generating code or retrying
+const SignalActionPhase = "action_phase"
+
+// ActionPhaseUpdate is the payload of an action phase signal
+type ActionPhaseUpdate struct {
+	SequenceID int         `json:"sequence_id"`
+	Phase      ActionPhase `json:"phase"`
+	Attempt    int         `json:"attempt"`
 }
 
+// DebugSnapshot is the page as a debug run left it after a step
+type DebugSnapshot struct {
+	SequenceID     int          `json:"sequence_id"`
+	Iteration      int          `json:"iteration,omitempty"` // 1-based loop iteration, 0 outside loops
+	ScreenshotPath string       `json:"screenshot_path,omitempty"`
+	Page           *PageSummary `json:"page,omitempty"`
+	ErrorMessage   string       `json:"error_message,omitempty"` // why the page could not be captured
+}
+
+// PageSummary outlines a page: where it is and the elements an action
+// could target
+type PageSummary struct {
+	URL      string        `json:"url"`
+	Title    string        `json:"title"`
+	Elements []PageElement `json:"elements"`
+}
+
+// PageElement is a visible element of a page a user can interact with
+type PageElement struct {
+	Tag      string `json:"tag"`
+	Text     string `json:"text,omitempty"`
+	Selector string `json:"selector"`
+}
+
+// PlannedAction is a step of a dry run: an action with the code generated
+// for it and its value as it would be typed, parameters substituted
+type PlannedAction struct {
+	SequenceID    int        `json:"sequence_id"`
+	Iteration     int        `json:"iteration,omitempty"` // 1-based loop iteration, 0 outside loops
+	ActionType    ActionType `json:"action_type"`
+	Selector      string     `json:"selector,omitempty"`
+	Value         string     `json:"value,omitempty"`
+	GeneratedCode string     `json:"generated_code,omitempty"`
+}
+
+// ClipboardOutput names the run output holding the last copied text, which
+// later actions reference as {{clipboard}}
+const ClipboardOutput = "clipboard"
+
 // ExecuteRequest represents a request to execute a workflow
 type ExecuteRequest struct {
 	WorkflowID  string            `json:"workflow_id"`
@@ -320,6 +2361,129 @@ type ExecuteRequest struct {
 	Parallelism int               `json:"parallelism"`
 	LLMProvider string            `json:"llm_provider"`
 	Headless    bool              `json:"headless"`
+	Delay       DelayConfig       `json:"delay"`
+	Version     int               `json:"version,omitempty"` // Pin a workflow version, latest when unset
+	// ReuseBrowser runs in a fresh incognito context of a warm browser on the
+	// worker instead of launching Chrome for the run
+	ReuseBrowser bool `json:"reuse_browser,omitempty"`
+	// BrowserEndpoint runs in a remote Chrome, a DevTools WebSocket URL or
+	// an http://host:port address, instead of a browser on the worker
+	BrowserEndpoint string `json:"browser_endpoint,omitempty"`
+	// Browser runs the workflow in Firefox or WebKit instead of Chromium
+	Browser BrowserEngine `json:"browser,omitempty"`
+	// Device emulates a preset device or explicit viewport metrics
+	Device *DeviceConfig `json:"device,omitempty"`
+	// UserAgent, Locale and Timezone override what the browser reports, so
+	// runs behave the same wherever the worker is. Locale is a BCP 47 tag
+	// sent as Accept-Language, Timezone an IANA name such as Europe/Berlin.
+	UserAgent string `json:"user_agent,omitempty"`
+	Locale    string `json:"locale,omitempty"`
+	Timezone  string `json:"timezone,omitempty"`
+	// Proxy routes the run's traffic through a proxy, answering its
+	// authentication challenges with the given credentials
+	Proxy *ProxyConfig `json:"proxy,omitempty"`
+	// Stealth hides navigator.webdriver and the other signals sites use to
+	// block headless automation
+	Stealth bool `json:"stealth,omitempty"`
+	// HTTPCredentials log in to sites behind HTTP basic, digest or NTLM
+	// authentication, such as intranet tools
+	HTTPCredentials *HTTPCredentials `json:"http_credentials,omitempty"`
+	// StorageState is injected before the run so it starts with the
+	// cookies and localStorage of an authenticated session
+	StorageState *StorageState `json:"storage_state,omitempty"`
+	// Session starts the run from a saved session's storage state instead
+	Session string `json:"session,omitempty"`
+	// SaveSession saves the cookies and localStorage under this name when
+	// the run succeeds, replacing any session of that name
+	SaveSession string `json:"save_session,omitempty"`
+	// Retry overrides how the run's activities are retried
+	Retry *RetryPolicy `json:"retry,omitempty"`
+	// Requirements are the labels a worker needs to take the run, such as
+	// has-display for a headful run or region=eu
+	Requirements []string `json:"requirements,omitempty"`
+	// Priority queues the run ahead of runs of lower priority, normal when
+	// empty
+	Priority RunPriority `json:"priority,omitempty"`
+	// DryRun returns the generated code and resolved values of every step
+	// without launching a browser or recording a run
+	DryRun bool `json:"dry_run,omitempty"`
+	// Debug single-steps the run: it holds before each action until
+	// POST /runs/{id}/next, streaming a screenshot and page summary after
+	Debug bool `json:"debug,omitempty"`
+	// Screenshots captures the page before and after every action rather
+	// than only when one fails
+	Screenshots bool `json:"screenshots,omitempty"`
+	// Accessibility scans the page after every navigate action with
+	// axe-core, keeping the reports among the run's downloads
+	Accessibility bool `json:"accessibility,omitempty"`
+	// VerifyOutcomes checks that the elements which appeared after each
+	// action while recording, such as a modal or a toast, appear again.
+	// A run whose actions succeed without them ends with a warning.
+	VerifyOutcomes bool `json:"verify_outcomes,omitempty"`
+}
+
+// RetryPolicy is how a run retries its activities. Unset fields keep the
+// defaults: a 1s initial interval doubling each attempt and 3 attempts for
+// browser setup, a single attempt for actions without retries of their own.
+type RetryPolicy struct {
+	InitialIntervalMs  int64   `json:"initial_interval_ms,omitempty"`
+	BackoffCoefficient float64 `json:"backoff_coefficient,omitempty"`
+	MaxAttempts        int     `json:"max_attempts,omitempty"`
+	// NonRetryableErrorTypes fail an activity at once, in addition to
+	// FatalBrowserError and InvalidSelectorError
+	NonRetryableErrorTypes []string `json:"non_retryable_error_types,omitempty"`
+}
+
+// Validate checks the policy, which may be nil
+func (p *RetryPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.InitialIntervalMs < 0 {
+		return fmt.Errorf("retry initial_interval_ms must not be negative")
+	}
+	if p.BackoffCoefficient != 0 && p.BackoffCoefficient < 1 {
+		return fmt.Errorf("retry backoff_coefficient must be at least 1")
+	}
+	if p.MaxAttempts < 0 {
+		return fmt.Errorf("retry max_attempts must not be negative")
+	}
+	for _, errType := range p.NonRetryableErrorTypes {
+		if strings.TrimSpace(errType) == "" {
+			return fmt.Errorf("retry non_retryable_error_types must not be empty")
+		}
+	}
+	return nil
+}
+
+// BundleVersion is the format version of exported workflow bundles
+const BundleVersion = 1
+
+// BundleManifest describes an exported workflow bundle. The archive holds the
+// manifest, the events file, actions.json, parameters.json and any generated code.
+type BundleManifest struct {
+	Version         int       `json:"version"`
+	ExportedAt      time.Time `json:"exported_at"`
+	WorkflowID      string    `json:"workflow_id"` // ID on the exporting instance
+	Name            string    `json:"name"`
+	Description     string    `json:"description,omitempty"`
+	StartURL        string    `json:"start_url"`
+	EventsFile      string    `json:"events_file"` // Path of the events file inside the bundle, empty for workflows created from a template
+	GeneratedFormat string    `json:"generated_format,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	Tags            []string  `json:"tags,omitempty"`
+}
+
+// OpenBrowserSession describes a browser session open on a worker, for
+// operators finding stuck ones
+type OpenBrowserSession struct {
+	ID         string    `json:"id"`
+	RunID      string    `json:"run_id,omitempty"` // run that opened it, empty for older runs
+	URL        string    `json:"url,omitempty"`    // page shown, empty when the browser didn't answer
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	Remote     bool      `json:"remote,omitempty"` // on a remote browser endpoint
+	Worker     string    `json:"worker,omitempty"` // URL of the worker holding it
 }
 
 // ==================== WebSocket Message Types ====================
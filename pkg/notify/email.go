@@ -0,0 +1,194 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// smtpTimeout bounds a send when the context has no deadline
+const smtpTimeout = time.Minute
+
+// SMTPConfig is the mail server emails are sent through
+type SMTPConfig struct {
+	Host     string
+	Port     int // 587 when 0; 465 connects over TLS, other ports upgrade with STARTTLS when offered
+	Username string
+	Password string
+	From     string
+}
+
+// Enabled reports whether a mail server is configured
+func (c SMTPConfig) Enabled() bool {
+	return c.Host != "" && c.From != ""
+}
+
+func (c SMTPConfig) port() int {
+	if c.Port == 0 {
+		return 587
+	}
+	return c.Port
+}
+
+// Email sends notifications to a list of recipients
+type Email struct {
+	To   []string
+	SMTP SMTPConfig
+}
+
+// NewEmail creates an email notifier sending through the server
+func NewEmail(to []string, config SMTPConfig) *Email {
+	return &Email{To: to, SMTP: config}
+}
+
+// Notify emails the notification, with the failure screenshot attached
+func (e *Email) Notify(ctx context.Context, n Notification) error {
+	msg, err := emailMessage(e.SMTP.From, e.To, n, time.Now())
+	if err != nil {
+		return err
+	}
+	return e.send(ctx, msg)
+}
+
+// emailText is the plain text body of a notification
+func emailText(n Notification) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\nRun: %s\n", n.title(), n.RunID)
+	if n.Step != "" {
+		fmt.Fprintf(&b, "Failed step: %s\n", n.Step)
+	}
+	if n.Error != "" {
+		fmt.Fprintf(&b, "Error: %s\n", n.Error)
+	}
+	if n.RunURL != "" {
+		fmt.Fprintf(&b, "\nReport: %s\n", n.RunURL)
+	}
+	return b.String()
+}
+
+// emailMessage builds the message, a multipart one when it attaches the
+// screenshot
+func emailMessage(from string, to []string, n Notification, date time.Time) ([]byte, error) {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", n.title()))
+	fmt.Fprintf(&msg, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+
+	text := strings.ReplaceAll(emailText(n), "\n", "\r\n")
+	if len(n.Screenshot) == 0 {
+		msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+		msg.WriteString(text)
+		return msg.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&msg)
+	fmt.Fprintf(&msg, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+	if err != nil {
+		return nil, err
+	}
+	part.Write([]byte(text))
+
+	part, err = mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"image/png"},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", n.RunID+"_failure.png")},
+	})
+	if err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(n.Screenshot)
+	for len(encoded) > 76 {
+		part.Write([]byte(encoded[:76] + "\r\n"))
+		encoded = encoded[76:]
+	}
+	part.Write([]byte(encoded + "\r\n"))
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return msg.Bytes(), nil
+}
+
+// send delivers the message to the server
+func (e *Email) send(ctx context.Context, msg []byte) error {
+	port := e.SMTP.port()
+	addr := net.JoinHostPort(e.SMTP.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: e.SMTP.Host}
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to reach smtp server %s: %w", addr, err)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+	conn.SetDeadline(deadline)
+	if port == 465 {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	c, err := smtp.NewClient(conn, e.SMTP.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp server %s: %w", addr, err)
+	}
+	defer c.Close()
+
+	if port != 465 {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("smtp starttls failed: %w", err)
+			}
+		}
+	}
+	if e.SMTP.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.SMTP.Username, e.SMTP.Password, e.SMTP.Host)); err != nil {
+			return fmt.Errorf("smtp authentication failed: %w", err)
+		}
+	}
+
+	if err := c.Mail(envelopeAddress(e.SMTP.From)); err != nil {
+		return fmt.Errorf("smtp sender rejected: %w", err)
+	}
+	for _, to := range e.To {
+		if err := c.Rcpt(envelopeAddress(to)); err != nil {
+			return fmt.Errorf("smtp recipient %s rejected: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp server refused the message: %w", err)
+	}
+	return c.Quit()
+}
+
+// envelopeAddress is the bare address of one such as "Ops <ops@example.com>"
+func envelopeAddress(address string) string {
+	if addr, err := mail.ParseAddress(address); err == nil {
+		return addr.Address
+	}
+	return address
+}
@@ -0,0 +1,105 @@
+package notify
+
+import (
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+func TestEmailMessage(t *testing.T) {
+	n := failure
+	n.Screenshot = []byte(strings.Repeat("png", 40))
+	raw, err := emailMessage("Automation <bot@example.com>", []string{"ops@example.com", "qa@example.com"}, n, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := msg.Header.Get("Subject"); got != "Checkout failed" {
+		t.Errorf("Subject = %q, want %q", got, "Checkout failed")
+	}
+	if got := msg.Header.Get("To"); got != "ops@example.com, qa@example.com" {
+		t.Errorf("To = %q", got)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %q, %v", mediaType, err)
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+
+	text, err := parts.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(text)
+	for _, want := range []string{"Failed step: Click the Pay button", "Error: " + failure.Error, "Report: " + failure.RunURL} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("body %q doesn't contain %q", body, want)
+		}
+	}
+
+	attachment, err := parts.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attachment.FileName() != "run-1_failure.png" {
+		t.Errorf("attachment is named %q", attachment.FileName())
+	}
+	encoded, _ := io.ReadAll(attachment)
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+	if err != nil || string(decoded) != string(n.Screenshot) {
+		t.Errorf("attachment = %q, %v", decoded, err)
+	}
+}
+
+func TestEmailMessageWithoutScreenshot(t *testing.T) {
+	n := failure
+	n.WorkflowName = "Nightly\r\nBcc: everyone@example.com"
+	raw, err := emailMessage("bot@example.com", []string{"ops@example.com"}, n, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Header.Get("Bcc") != "" {
+		t.Error("the workflow name added a header")
+	}
+	if got := msg.Header.Get("Content-Type"); got != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+}
+
+func TestEmailMessageCanceled(t *testing.T) {
+	n := failure
+	n.Status = models.StatusCanceled
+	n.Error = "Workflow canceled by user"
+	n.Step = ""
+	raw, err := emailMessage("bot@example.com", []string{"ops@example.com"}, n, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := msg.Header.Get("Subject"); got != "Checkout canceled" {
+		t.Errorf("Subject = %q, want %q", got, "Checkout canceled")
+	}
+	body, _ := io.ReadAll(msg.Body)
+	if strings.Contains(string(body), "succeeded") {
+		t.Errorf("body %q announces a canceled run as succeeded", body)
+	}
+}
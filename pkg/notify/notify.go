@@ -0,0 +1,71 @@
+// Package notify announces how runs ended, such as in a Slack channel
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// Notification is a run to announce
+type Notification struct {
+	WorkflowID   string
+	WorkflowName string // the workflow ID when empty
+	RunID        string
+	Status       models.RunStatus
+	Error        string
+	// Step describes the action the run failed on, if any
+	Step string
+	// Screenshot is the page when the step failed, uploaded with the
+	// message where the service takes files. Elsewhere it's linked from
+	// ScreenshotURL, which the service must be able to fetch.
+	Screenshot    []byte
+	ScreenshotURL string
+	RunURL        string // the run's report, not linked when empty
+}
+
+// Notifier sends notifications to a service
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// Config holds the worker's credentials for the services
+type Config struct {
+	SlackToken string // bot token for posting to channels rather than webhooks
+	SMTP       SMTPConfig
+}
+
+// New returns the notifier for a workflow's target
+func New(target models.NotificationTarget, config Config) (Notifier, error) {
+	switch target.Type {
+	case models.NotifySlack:
+		if target.WebhookURL == "" && config.SlackToken == "" {
+			return nil, fmt.Errorf("slack channel %s needs the worker's SLACK_BOT_TOKEN", target.Channel)
+		}
+		return NewSlack(target.WebhookURL, target.Channel, config.SlackToken), nil
+	case models.NotifyEmail:
+		if !config.SMTP.Enabled() {
+			return nil, fmt.Errorf("email notifications need the worker's SMTP_HOST and SMTP_FROM")
+		}
+		return NewEmail(target.To, config.SMTP), nil
+	default:
+		return nil, fmt.Errorf("unknown notification type: %q", target.Type)
+	}
+}
+
+// title is the first line of a notification, such as "Checkout failed"
+func (n Notification) title() string {
+	name := n.WorkflowName
+	if name == "" {
+		name = n.WorkflowID
+	}
+	switch n.Status {
+	case models.StatusSuccess:
+		return name + " succeeded"
+	case models.StatusWarning:
+		return name + " succeeded with warnings"
+	default:
+		return fmt.Sprintf("%s %s", name, n.Status)
+	}
+}
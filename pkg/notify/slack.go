@@ -0,0 +1,226 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// slackAPI is where Slack's Web API is
+const slackAPI = "https://slack.com/api/"
+
+// maxSlackText is how much of an error a message quotes, well under the
+// 3000 characters of a section block
+const maxSlackText = 2000
+
+// Slack posts notifications to an incoming webhook or, with a bot token,
+// to a channel. Webhooks can only show screenshots Slack can fetch; the
+// bot uploads them.
+type Slack struct {
+	WebhookURL string
+	Channel    string // channel ID or name, with Token
+	Token      string
+	Client     *http.Client
+
+	api string
+}
+
+// NewSlack creates a Slack notifier posting to the webhook when set,
+// otherwise to the channel with the bot token
+func NewSlack(webhookURL, channel, token string) *Slack {
+	return &Slack{
+		WebhookURL: webhookURL,
+		Channel:    channel,
+		Token:      token,
+		Client:     &http.Client{Timeout: 30 * time.Second},
+		api:        slackAPI,
+	}
+}
+
+// Notify posts the notification
+func (s *Slack) Notify(ctx context.Context, n Notification) error {
+	if s.WebhookURL != "" {
+		return s.postJSON(ctx, s.WebhookURL, "", slackMessage(n, ""), nil)
+	}
+	if len(n.Screenshot) > 0 {
+		return s.upload(ctx, n)
+	}
+	return s.call(ctx, "chat.postMessage", slackMessage(n, s.Channel), nil)
+}
+
+// slackText is the notification as mrkdwn
+func slackText(n Notification) string {
+	var b strings.Builder
+	title := slackEscape(n.title())
+	if n.RunURL != "" {
+		title = "<" + n.RunURL + "|" + title + ">"
+	}
+	fmt.Fprintf(&b, "%s *%s*\nRun `%s`", slackEmoji(n.Status), title, n.RunID)
+	if n.Step != "" {
+		fmt.Fprintf(&b, "\n*Failed step:* %s", slackEscape(n.Step))
+	}
+	if n.Error != "" {
+		msg := n.Error
+		if len(msg) > maxSlackText {
+			msg = strings.ToValidUTF8(msg[:maxSlackText], "") + "…"
+		}
+		fmt.Fprintf(&b, "\n```%s```", slackEscape(msg))
+	}
+	return b.String()
+}
+
+func slackEmoji(status models.RunStatus) string {
+	switch status {
+	case models.StatusSuccess:
+		return ":white_check_mark:"
+	case models.StatusWarning:
+		return ":warning:"
+	case models.StatusFailed:
+		return ":x:"
+	case models.StatusCanceled:
+		return ":no_entry_sign:"
+	default:
+		return ":grey_question:"
+	}
+}
+
+// slackEscape escapes the characters mrkdwn reads as markup
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// slackMessage is a chat.postMessage or webhook payload, with the
+// screenshot as an image block when Slack can fetch it
+func slackMessage(n Notification, channel string) map[string]any {
+	text := slackText(n)
+	blocks := []map[string]any{{
+		"type": "section",
+		"text": map[string]string{"type": "mrkdwn", "text": text},
+	}}
+	if n.ScreenshotURL != "" {
+		blocks = append(blocks, map[string]any{
+			"type":      "image",
+			"image_url": n.ScreenshotURL,
+			"alt_text":  "Page when the step failed",
+		})
+	}
+	msg := map[string]any{"text": n.title(), "blocks": blocks}
+	if channel != "" {
+		msg["channel"] = channel
+	}
+	return msg
+}
+
+// upload posts the notification as the comment of its uploaded screenshot
+func (s *Slack) upload(ctx context.Context, n Notification) error {
+	var ticket struct {
+		UploadURL string `json:"upload_url"`
+		FileID    string `json:"file_id"`
+	}
+	form := url.Values{
+		"filename": {n.RunID + "_failure.png"},
+		"length":   {strconv.Itoa(len(n.Screenshot))},
+	}
+	if err := s.callForm(ctx, "files.getUploadURLExternal", form, &ticket); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", ticket.UploadURL, bytes.NewReader(n.Screenshot))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "image/png")
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("slack upload failed: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack upload returned status %d", resp.StatusCode)
+	}
+
+	return s.call(ctx, "files.completeUploadExternal", map[string]any{
+		"files":           []map[string]string{{"id": ticket.FileID, "title": "Page when the step failed"}},
+		"channel_id":      s.Channel,
+		"initial_comment": slackText(n),
+	}, nil)
+}
+
+// slackResponse is the envelope of every Web API response
+type slackResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+}
+
+// call calls a Web API method with a JSON body, decoding the response
+// into out when set
+func (s *Slack) call(ctx context.Context, method string, body, out any) error {
+	return s.postJSON(ctx, s.api+method, s.Token, body, out)
+}
+
+// callForm calls a Web API method that only takes form values
+func (s *Slack) callForm(ctx context.Context, method string, form url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", s.api+method, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+s.Token)
+	return s.do(req, method, out)
+}
+
+func (s *Slack) postJSON(ctx context.Context, endpoint, token string, body, out any) error {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(jsonBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	method := "webhook"
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+		method = strings.TrimPrefix(endpoint, s.api)
+	}
+	return s.do(req, method, out)
+}
+
+// do sends a request. Webhooks answer "ok" in plain text; the Web API
+// answers with a JSON envelope and status 200 even when the call failed.
+func (s *Slack) do(req *http.Request, method string, out any) error {
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("slack %s failed: %w", method, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack %s returned status %d: %s", method, resp.StatusCode, string(body))
+	}
+	if method == "webhook" {
+		return nil
+	}
+
+	var envelope slackResponse
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("slack %s: %w", method, err)
+	}
+	if !envelope.OK {
+		return fmt.Errorf("slack %s failed: %s", method, envelope.Error)
+	}
+	if out != nil {
+		return json.Unmarshal(body, out)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+var failure = Notification{
+	WorkflowID:   "wf-1",
+	WorkflowName: "Checkout",
+	RunID:        "run-1",
+	Status:       models.StatusFailed,
+	Error:        "Action click failed: no element matched <button>",
+	Step:         "Click the Pay button",
+	RunURL:       "https://automation.example.com/api/runs/run-1/report.html",
+}
+
+func TestSlackWebhook(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	n := failure
+	n.ScreenshotURL = "https://bucket.example.com/run-1_3_0_failure.png"
+	if err := NewSlack(server.URL, "", "").Notify(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+
+	if got["text"] != "Checkout failed" {
+		t.Errorf("text = %q, want %q", got["text"], "Checkout failed")
+	}
+	blocks := got["blocks"].([]any)
+	if len(blocks) != 2 {
+		t.Fatalf("got %d blocks, want a section and an image", len(blocks))
+	}
+	text := blocks[0].(map[string]any)["text"].(map[string]any)["text"].(string)
+	for _, want := range []string{"<" + failure.RunURL + "|Checkout failed>", "Click the Pay button", "no element matched &lt;button&gt;"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("message %q doesn't contain %q", text, want)
+		}
+	}
+	if image := blocks[1].(map[string]any); image["image_url"] != n.ScreenshotURL {
+		t.Errorf("image block = %v", image)
+	}
+}
+
+func TestSlackUpload(t *testing.T) {
+	var calls []string
+	var comment map[string]any
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		if r.URL.Path != "/upload" && r.Header.Get("Authorization") != "Bearer xoxb-token" {
+			t.Errorf("%s called without the token", r.URL.Path)
+		}
+		switch r.URL.Path {
+		case "/api/files.getUploadURLExternal":
+			if r.FormValue("length") != "3" {
+				t.Errorf("length = %q, want 3", r.FormValue("length"))
+			}
+			io.WriteString(w, `{"ok":true,"upload_url":"`+server.URL+`/upload","file_id":"F1"}`)
+		case "/upload":
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != "png" {
+				t.Errorf("uploaded %q", body)
+			}
+		case "/api/files.completeUploadExternal":
+			json.NewDecoder(r.Body).Decode(&comment)
+			io.WriteString(w, `{"ok":true}`)
+		}
+	}))
+	defer server.Close()
+
+	s := NewSlack("", "C123", "xoxb-token")
+	s.api = server.URL + "/api/"
+	n := failure
+	n.Screenshot = []byte("png")
+	if err := s.Notify(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"/api/files.getUploadURLExternal", "/upload", "/api/files.completeUploadExternal"}
+	if strings.Join(calls, " ") != strings.Join(want, " ") {
+		t.Errorf("calls = %q, want %q", calls, want)
+	}
+	if comment["channel_id"] != "C123" || !strings.Contains(comment["initial_comment"].(string), "Click the Pay button") {
+		t.Errorf("completeUploadExternal got %v", comment)
+	}
+}
+
+func TestSlackCanceled(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	n := failure
+	n.Status = models.StatusCanceled
+	n.Error = "Workflow canceled by user"
+	n.Step = ""
+	if err := NewSlack(server.URL, "", "").Notify(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+
+	if got["text"] != "Checkout canceled" {
+		t.Errorf("text = %q, want %q", got["text"], "Checkout canceled")
+	}
+	text := got["blocks"].([]any)[0].(map[string]any)["text"].(map[string]any)["text"].(string)
+	if !strings.HasPrefix(text, ":no_entry_sign: ") || strings.Contains(text, "succeeded") {
+		t.Errorf("message %q doesn't announce a canceled run", text)
+	}
+}
+
+func TestSlackAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"ok":false,"error":"channel_not_found"}`)
+	}))
+	defer server.Close()
+
+	s := NewSlack("", "#missing", "xoxb-token")
+	s.api = server.URL + "/"
+	err := s.Notify(context.Background(), failure)
+	if err == nil || !strings.Contains(err.Error(), "channel_not_found") {
+		t.Errorf("Notify() = %v, want channel_not_found", err)
+	}
+}
@@ -0,0 +1,76 @@
+// Package progress fans the progress workers publish for runs out to the
+// clients following them
+package progress
+
+import (
+	"sync"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// Hub delivers run progress to its subscribers
+type Hub struct {
+	mu   sync.Mutex
+	subs map[string]map[*Subscription]struct{}
+}
+
+// NewHub creates a hub without subscribers
+func NewHub() *Hub {
+	return &Hub{subs: make(map[string]map[*Subscription]struct{})}
+}
+
+// Subscription receives the progress of a run. A subscriber that falls
+// behind only receives the latest progress.
+type Subscription struct {
+	C <-chan models.WorkflowResult
+
+	c     chan models.WorkflowResult
+	hub   *Hub
+	runID string
+}
+
+// Subscribe follows a run until the subscription is closed
+func (h *Hub) Subscribe(runID string) *Subscription {
+	c := make(chan models.WorkflowResult, 1)
+	sub := &Subscription{C: c, c: c, hub: h, runID: runID}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[runID] == nil {
+		h.subs[runID] = make(map[*Subscription]struct{})
+	}
+	h.subs[runID][sub] = struct{}{}
+	return sub
+}
+
+// Close stops the subscription
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	delete(s.hub.subs[s.runID], s)
+	if len(s.hub.subs[s.runID]) == 0 {
+		delete(s.hub.subs, s.runID)
+	}
+}
+
+// Followed reports whether a run has subscribers, so publishers can skip
+// fetching progress no one receives
+func (h *Hub) Followed(runID string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs[runID]) > 0
+}
+
+// Publish delivers a run's progress to its subscribers without waiting
+// for them, replacing the progress they haven't received yet
+func (h *Hub) Publish(runID string, progress models.WorkflowResult) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs[runID] {
+		select {
+		case <-sub.c:
+		default:
+		}
+		sub.c <- progress
+	}
+}
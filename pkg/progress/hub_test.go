@@ -0,0 +1,49 @@
+package progress
+
+import (
+	"testing"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+func TestHub(t *testing.T) {
+	hub := NewHub()
+	a := hub.Subscribe("run-1")
+	b := hub.Subscribe("run-1")
+	other := hub.Subscribe("run-2")
+	if !hub.Followed("run-1") || hub.Followed("run-3") {
+		t.Fatal("Followed() doesn't match the subscriptions")
+	}
+
+	// Subscribers that haven't caught up only get the latest progress
+	hub.Publish("run-1", models.WorkflowResult{RunID: "run-1", Status: models.StatusRunning})
+	hub.Publish("run-1", models.WorkflowResult{RunID: "run-1", Status: models.StatusSuccess})
+	for _, sub := range []*Subscription{a, b} {
+		select {
+		case got := <-sub.C:
+			if got.Status != models.StatusSuccess {
+				t.Errorf("received %s, want the latest progress", got.Status)
+			}
+		default:
+			t.Error("subscriber received nothing")
+		}
+		select {
+		case got := <-sub.C:
+			t.Errorf("received stale progress %s", got.Status)
+		default:
+		}
+	}
+	select {
+	case <-other.C:
+		t.Error("another run's subscriber received the progress")
+	default:
+	}
+
+	a.Close()
+	b.Close()
+	if hub.Followed("run-1") {
+		t.Error("run still followed after its subscriptions closed")
+	}
+	hub.Publish("run-1", models.WorkflowResult{RunID: "run-1"})
+	other.Close()
+}
@@ -0,0 +1,302 @@
+// Package retention prunes old runs and their artifacts, and purges the
+// workflows and runs left in the trash, so the database and artifact store
+// don't grow without bound.
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"dev/bravebird/browser-automation-go/pkg/artifacts"
+	"dev/bravebird/browser-automation-go/pkg/database"
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// batchSize is how many runs are pruned per query
+const batchSize = 100
+
+// Policy is how long finished runs and their artifacts are kept
+type Policy struct {
+	Runs      time.Duration // runs with their results, forever when 0
+	Artifacts time.Duration // screenshots, downloads and generated code, as long as their runs when 0
+	Deleted   time.Duration // workflows and runs in the trash, forever when 0
+	Interval  time.Duration // between prunes
+}
+
+// Enabled reports whether the policy prunes anything
+func (p Policy) Enabled() bool {
+	return p.Runs > 0 || p.Artifacts > 0 || p.Deleted > 0
+}
+
+// ParsePolicy parses the days runs, artifacts and the trash are kept, empty
+// or 0 for forever, and the interval between prunes as a duration such as
+// "1h"
+func ParsePolicy(runDays, artifactDays, deletedDays, interval string) (Policy, error) {
+	var p Policy
+	var err error
+	if p.Runs, err = parseDays(runDays); err != nil {
+		return p, fmt.Errorf("run retention: %w", err)
+	}
+	if p.Artifacts, err = parseDays(artifactDays); err != nil {
+		return p, fmt.Errorf("artifact retention: %w", err)
+	}
+	if p.Deleted, err = parseDays(deletedDays); err != nil {
+		return p, fmt.Errorf("deleted retention: %w", err)
+	}
+	if p.Runs > 0 && p.Artifacts > p.Runs {
+		return p, fmt.Errorf("artifacts can't be kept longer than their runs")
+	}
+	p.Interval = time.Hour
+	if interval != "" {
+		if p.Interval, err = time.ParseDuration(interval); err != nil || p.Interval <= 0 {
+			return p, fmt.Errorf("retention interval %q must be a positive duration", interval)
+		}
+	}
+	return p, nil
+}
+
+func parseDays(days string) (time.Duration, error) {
+	if days == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(days)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q must be a number of days", days)
+	}
+	return time.Duration(n) * 24 * time.Hour, nil
+}
+
+// Stats count what a prune removed
+type Stats struct {
+	RunsDeleted     int
+	ArtifactsPruned int // runs whose artifacts were deleted
+	RunsPurged      int // from the trash
+	WorkflowsPurged int // from the trash, with all their runs
+}
+
+// Cleaner enforces a retention policy
+type Cleaner struct {
+	DB        *database.DB
+	Artifacts artifacts.Store
+	Policy    Policy
+}
+
+// Run prunes now and then every Policy.Interval until ctx is done,
+// reporting each prune
+func (c *Cleaner) Run(ctx context.Context, report func(Stats, error)) {
+	ticker := time.NewTicker(c.Policy.Interval)
+	defer ticker.Stop()
+
+	now := time.Now()
+	for {
+		stats, err := c.Prune(ctx, now)
+		if report != nil {
+			report(stats, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+	}
+}
+
+// Prune deletes the artifacts of runs that finished longer ago than the
+// artifacts are kept, then the runs that finished longer ago than runs are
+// kept along with their artifacts
+func (c *Cleaner) Prune(ctx context.Context, now time.Time) (Stats, error) {
+	var stats Stats
+	if c.Policy.Artifacts > 0 {
+		for {
+			ids, err := c.DB.ExpiredRuns(ctx, now.Add(-c.Policy.Artifacts), true, batchSize)
+			if err != nil {
+				return stats, err
+			}
+			for _, id := range ids {
+				if err := c.deleteArtifacts(ctx, id); err != nil {
+					return stats, err
+				}
+				if err := c.DB.ClearRunArtifacts(ctx, id); err != nil {
+					return stats, err
+				}
+				stats.ArtifactsPruned++
+			}
+			if len(ids) < batchSize {
+				break
+			}
+		}
+	}
+
+	if c.Policy.Runs > 0 {
+		for {
+			ids, err := c.DB.ExpiredRuns(ctx, now.Add(-c.Policy.Runs), false, batchSize)
+			if err != nil {
+				return stats, err
+			}
+			for _, id := range ids {
+				if err := c.deleteArtifacts(ctx, id); err != nil {
+					return stats, err
+				}
+				if err := c.DB.DeleteWorkflowRun(ctx, id); err != nil {
+					return stats, fmt.Errorf("failed to delete run %s: %w", id, err)
+				}
+				stats.RunsDeleted++
+			}
+			if len(ids) < batchSize {
+				break
+			}
+		}
+	}
+
+	if c.Policy.Deleted > 0 {
+		if err := c.purge(ctx, now.Add(-c.Policy.Deleted), &stats); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// purge deletes the runs and workflows moved to the trash before cutoff,
+// with the artifacts of the runs, the workflows' generated code and the
+// recordings no other workflow shares
+func (c *Cleaner) purge(ctx context.Context, cutoff time.Time, stats *Stats) error {
+	for {
+		ids, err := c.DB.TrashedRuns(ctx, cutoff, batchSize)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := c.deleteArtifacts(ctx, id); err != nil {
+				return err
+			}
+			if err := c.DB.DeleteWorkflowRun(ctx, id); err != nil {
+				return fmt.Errorf("failed to delete run %s: %w", id, err)
+			}
+			stats.RunsPurged++
+		}
+		if len(ids) < batchSize {
+			break
+		}
+	}
+
+	for {
+		defs, err := c.DB.TrashedWorkflows(ctx, cutoff, batchSize)
+		if err != nil {
+			return err
+		}
+		for _, def := range defs {
+			if err := c.purgeWorkflow(ctx, def); err != nil {
+				return err
+			}
+			stats.WorkflowsPurged++
+		}
+		if len(defs) < batchSize {
+			break
+		}
+	}
+	return nil
+}
+
+// purgeWorkflow deletes a workflow with everything that belongs to it. Its
+// actions, versions, runs and their results go with it in the database.
+func (c *Cleaner) purgeWorkflow(ctx context.Context, def models.WorkflowDefinition) error {
+	runIDs, err := c.DB.WorkflowRunIDs(ctx, def.ID)
+	if err != nil {
+		return err
+	}
+	for _, id := range runIDs {
+		if err := c.deleteArtifacts(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	code, err := c.Artifacts.List(ctx, artifacts.Key(artifacts.KindCode, def.ID)+"/")
+	if err != nil {
+		return err
+	}
+	for _, key := range code {
+		if err := c.Artifacts.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	// Copies and merges of a workflow share its recording
+	if def.EventsFilePath != "" {
+		shared, err := c.DB.EventsFileInUse(ctx, def.EventsFilePath, def.ID)
+		if err != nil {
+			return err
+		}
+		if !shared {
+			if err := os.Remove(def.EventsFilePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("failed to delete events file: %w", err)
+			}
+		}
+	}
+
+	if err := c.DB.DeleteWorkflowDefinition(ctx, def.ID); err != nil {
+		return fmt.Errorf("failed to delete workflow %s: %w", def.ID, err)
+	}
+	return nil
+}
+
+// deleteArtifacts deletes a run's screenshots, DOM snapshots and downloads.
+// Screenshots are named after the run, so the ones of a run whose results
+// weren't recorded are found too.
+func (c *Cleaner) deleteArtifacts(ctx context.Context, runID string) error {
+	results, err := c.DB.GetActionResults(ctx, runID)
+	if err != nil {
+		return err
+	}
+	keys := runArtifactKeys(runID, results)
+
+	screenshots, err := c.Artifacts.List(ctx, artifacts.ScreenshotKey(runID+"_"))
+	if err != nil {
+		return err
+	}
+	for _, key := range screenshots {
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	downloads, err := c.Artifacts.List(ctx, artifacts.Key(artifacts.KindDownloads, runID)+"/")
+	if err != nil {
+		return err
+	}
+	keys = append(keys, downloads...)
+
+	for _, key := range keys {
+		if err := c.Artifacts.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runArtifactKeys returns the keys of the screenshots and DOM snapshots a
+// run's results point at. Only files named after the run belong to it;
+// older runs named failure screenshots after the action alone, and later
+// runs may have overwritten them.
+func runArtifactKeys(runID string, results []models.ActionResult) []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, result := range results {
+		for _, location := range []string{result.ScreenshotPath, result.BeforeScreenshotPath, result.AfterScreenshotPath, result.DOMSnapshotPath} {
+			name := path.Base(strings.ReplaceAll(location, "\\", "/"))
+			if location == "" || !strings.HasPrefix(name, runID+"_") || seen[name] {
+				continue
+			}
+			seen[name] = true
+			keys = append(keys, artifacts.ScreenshotKey(name))
+		}
+	}
+	return keys
+}
@@ -0,0 +1,97 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"dev/bravebird/browser-automation-go/pkg/artifacts"
+	"dev/bravebird/browser-automation-go/pkg/database"
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy("30", "7", "14", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Policy{Runs: 30 * 24 * time.Hour, Artifacts: 7 * 24 * time.Hour, Deleted: 14 * 24 * time.Hour, Interval: time.Hour}
+	if p != want {
+		t.Errorf("ParsePolicy() = %+v, want %+v", p, want)
+	}
+
+	if p, err := ParsePolicy("", "", "", "10m"); err != nil || p.Enabled() || p.Interval != 10*time.Minute {
+		t.Errorf("ParsePolicy() = %+v, %v, want a disabled policy pruning every 10m", p, err)
+	}
+	for _, bad := range [][4]string{{"x", "", "", ""}, {"", "-1", "", ""}, {"7", "30", "", ""}, {"", "", "y", ""}, {"", "", "", "0s"}} {
+		if _, err := ParsePolicy(bad[0], bad[1], bad[2], bad[3]); err == nil {
+			t.Errorf("ParsePolicy(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestRunArtifactKeys(t *testing.T) {
+	results := []models.ActionResult{
+		{BeforeScreenshotPath: "/tmp/screenshots/run-1_1_0_before.png", AfterScreenshotPath: "/tmp/screenshots/run-1_1_0_after.png"},
+		{ScreenshotPath: "s3://bucket/screenshots/run-1_2_0_failure.png", DOMSnapshotPath: "s3://bucket/screenshots/run-1_2_0_failure.html"},
+		// Named after the action, possibly shared with later runs
+		{ScreenshotPath: "/tmp/screenshots/action-3_failure.png"},
+	}
+	want := []string{
+		"screenshots/run-1_1_0_before.png",
+		"screenshots/run-1_1_0_after.png",
+		"screenshots/run-1_2_0_failure.png",
+		"screenshots/run-1_2_0_failure.html",
+	}
+	if got := runArtifactKeys("run-1", results); !reflect.DeepEqual(got, want) {
+		t.Errorf("runArtifactKeys() = %q, want %q", got, want)
+	}
+}
+
+func TestDeleteArtifacts(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.NewSQLite(filepath.Join(t.TempDir(), "automator.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	store := artifacts.NewLocal(filepath.Join(dir, "screenshots"), filepath.Join(dir, "downloads"), filepath.Join(dir, "code"))
+
+	// run-1 recorded no results, so only the names of its files tell
+	// they are its own
+	keys := map[string]bool{ // deleted or kept
+		"screenshots/run-1_1_0_before.png":   true,
+		"screenshots/run-1_2_0_failure.png":  true,
+		"screenshots/run-1_2_0_failure.html": true,
+		"downloads/run-1/report.csv":         true,
+		"screenshots/run-10_1_0_before.png":  false,
+		"screenshots/action-3_failure.png":   false,
+	}
+	for key := range keys {
+		if _, err := store.Put(ctx, key, "", strings.NewReader("artifact")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := &Cleaner{DB: db, Artifacts: store}
+	if err := c.deleteArtifacts(ctx, "run-1"); err != nil {
+		t.Fatal(err)
+	}
+	for key, deleted := range keys {
+		body, err := store.Get(ctx, key)
+		if err == nil {
+			body.Close()
+		}
+		if gone := errors.Is(err, artifacts.ErrNotFound); gone != deleted {
+			t.Errorf("%s deleted = %v, want %v", key, gone, deleted)
+		}
+	}
+}
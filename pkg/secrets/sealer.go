@@ -0,0 +1,66 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrNoKey is returned when no encryption key is configured
+var ErrNoKey = errors.New("no encryption key configured")
+
+// Sealer encrypts data stored at rest with AES-256-GCM
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// NewSealer creates a sealer from a passphrase, which is hashed to the
+// AES key so any length works
+func NewSealer(key string) (*Sealer, error) {
+	if key == "" {
+		return nil, ErrNoKey
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealer{aead: aead}, nil
+}
+
+// Seal encrypts plaintext, returning the nonce and ciphertext as base64
+func (s *Sealer) Seal(plaintext []byte) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := s.aead.Seal(nonce, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value sealed with the same key
+func (s *Sealer) Open(sealed string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, fmt.Errorf("malformed sealed value: %w", err)
+	}
+	if len(data) < s.aead.NonceSize() {
+		return nil, errors.New("malformed sealed value: too short")
+	}
+
+	nonce, ciphertext := data[:s.aead.NonceSize()], data[s.aead.NonceSize():]
+	plaintext, err := s.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("failed to decrypt: wrong key or corrupted value")
+	}
+	return plaintext, nil
+}
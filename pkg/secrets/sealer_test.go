@@ -0,0 +1,38 @@
+package secrets
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSealerRoundTrip(t *testing.T) {
+	s, err := NewSealer("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("NewSealer() error = %v", err)
+	}
+
+	sealed, err := s.Seal([]byte(`{"cookies":[]}`))
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	again, _ := s.Seal([]byte(`{"cookies":[]}`))
+	if sealed == again {
+		t.Error("Seal() should use a fresh nonce each time")
+	}
+
+	plaintext, err := s.Open(sealed)
+	if err != nil || string(plaintext) != `{"cookies":[]}` {
+		t.Errorf("Open() = %q, %v, want the original plaintext", plaintext, err)
+	}
+
+	other, _ := NewSealer("another key")
+	if _, err := other.Open(sealed); err == nil {
+		t.Error("Open() with another key should fail")
+	}
+}
+
+func TestNewSealerNoKey(t *testing.T) {
+	if _, err := NewSealer(""); !errors.Is(err, ErrNoKey) {
+		t.Errorf("NewSealer(\"\") error = %v, want ErrNoKey", err)
+	}
+}
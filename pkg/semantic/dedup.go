@@ -0,0 +1,197 @@
+package semantic
+
+import (
+	"strings"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+const (
+	// duplicateWindowMs is the gap under which two identical actions are
+	// treated as one (e.g. the same click captured by both event sources)
+	duplicateWindowMs = 500
+	// dblClickWindowMs bounds the clicks that are folded into a following dblclick
+	dblClickWindowMs = 600
+	// captureSkewMs is the largest gap between the rrweb and CDP records of one event
+	captureSkewMs = 50
+)
+
+// deduplicate runs the dedup rules in order. Each rule only removes or
+// merges actions, so the relative order of the remaining actions is kept.
+func (e *Extractor) deduplicate(actions []models.SemanticAction) []models.SemanticAction {
+	actions = e.deduplicateNavigations(actions)
+	actions = e.upgradeDoubleClicks(actions)
+	actions = e.collapseInputTriads(actions)
+	actions = e.mergeSupersededInputs(actions)
+	actions = e.dropRepeatedActions(actions)
+	return actions
+}
+
+// upgradeDoubleClicks drops the single clicks recorded just before a dblclick
+// on the same element, since replaying the dblclick already produces them
+func (e *Extractor) upgradeDoubleClicks(actions []models.SemanticAction) []models.SemanticAction {
+	var result []models.SemanticAction
+
+	for _, curr := range actions {
+		if curr.ActionType == models.ActionDblClick {
+			for len(result) > 0 {
+				prev := result[len(result)-1]
+				if prev.ActionType != models.ActionClick || !sameTarget(prev.Target, curr.Target) ||
+					curr.Timestamp-prev.Timestamp > dblClickWindowMs {
+					break
+				}
+				result = result[:len(result)-1]
+			}
+		}
+		result = append(result, curr)
+	}
+
+	return result
+}
+
+// collapseInputTriads drops the click and focus that directly precede an input
+// on the same element. Typing into a field focuses it, so replaying the
+// click+focus+input triad only adds steps that can fail.
+func (e *Extractor) collapseInputTriads(actions []models.SemanticAction) []models.SemanticAction {
+	var result []models.SemanticAction
+
+	for _, curr := range actions {
+		if curr.ActionType == models.ActionInput {
+			for len(result) > 0 {
+				prev := result[len(result)-1]
+				if (prev.ActionType != models.ActionClick && prev.ActionType != models.ActionFocus) ||
+					!sameTarget(prev.Target, curr.Target) {
+					break
+				}
+				result = result[:len(result)-1]
+			}
+		}
+		result = append(result, curr)
+	}
+
+	return result
+}
+
+// mergeSupersededInputs drops an input when a later input on the same element
+// overwrites it before the value is committed. Unlike adjacent debouncing this
+// also merges inputs separated by unrelated actions, such as typing into
+// another field or a stray focus change.
+func (e *Extractor) mergeSupersededInputs(actions []models.SemanticAction) []models.SemanticAction {
+	superseded := make([]bool, len(actions))
+
+	for i, curr := range actions {
+		if curr.ActionType != models.ActionInput {
+			continue
+		}
+		for j := i + 1; j < len(actions); j++ {
+			next := actions[j]
+			if next.ActionType == models.ActionInput && sameTarget(next.Target, curr.Target) {
+				superseded[i] = true
+				actions[j].Context = append(append([]models.SemanticTarget{}, curr.Context...), next.Context...)
+				break
+			}
+			if commitsInput(next, curr.Target) {
+				break
+			}
+		}
+	}
+
+	var result []models.SemanticAction
+	for i, action := range actions {
+		if !superseded[i] {
+			result = append(result, action)
+		}
+	}
+
+	return result
+}
+
+// commitsInput reports whether action may act on the value typed into target,
+// after which a later input on the same field is a new value, not a correction
+func commitsInput(action models.SemanticAction, target models.SemanticTarget) bool {
+	switch action.ActionType {
+	case models.ActionNavigate, models.ActionSubmit:
+		return true
+	case models.ActionKeypress:
+		key := strings.ToLower(action.Value)
+		return key == "enter" || key == "tab"
+	case models.ActionClick, models.ActionDblClick, models.ActionRightClick, models.ActionCopy, models.ActionCut:
+		return !sameTarget(action.Target, target)
+	}
+	return false
+}
+
+// dropRepeatedActions removes an action identical to the previous one when
+// both happened within duplicateWindowMs. Key presses and pastes are kept, as
+// repeating them changes the page. Of two duplicates the higher ranked copy
+// survives, so a later rank filter does not drop both.
+func (e *Extractor) dropRepeatedActions(actions []models.SemanticAction) []models.SemanticAction {
+	var result []models.SemanticAction
+
+	for _, curr := range actions {
+		if len(result) > 0 {
+			prev := result[len(result)-1]
+			if prev.ActionType == curr.ActionType && idempotentAction(curr.ActionType) &&
+				prev.Value == curr.Value && curr.Timestamp-prev.Timestamp <= duplicateWindowMs &&
+				(sameTarget(prev.Target, curr.Target) || sameCapture(prev, curr)) {
+				if rankValue(curr.InteractionRank) > rankValue(prev.InteractionRank) {
+					result[len(result)-1] = curr
+				}
+				continue
+			}
+		}
+		result = append(result, curr)
+	}
+
+	return result
+}
+
+// idempotentAction reports whether replaying an action twice in a row has the
+// same effect as replaying it once
+func idempotentAction(t models.ActionType) bool {
+	switch t {
+	case models.ActionClick, models.ActionRightClick, models.ActionInput, models.ActionFocus,
+		models.ActionBlur, models.ActionHover, models.ActionScroll, models.ActionSelect:
+		return true
+	}
+	return false
+}
+
+// rankValue orders interaction ranks from least to most reliable
+func rankValue(r models.InteractionRank) int {
+	switch r {
+	case models.RankHigh:
+		return 3
+	case models.RankMedium:
+		return 2
+	case models.RankLow:
+		return 1
+	}
+	return 0
+}
+
+// sameCapture reports whether two actions are the rrweb and CDP records of a
+// single event. Only one side knows the node ID, so the selectors can differ.
+func sameCapture(a, b models.SemanticAction) bool {
+	if (a.Target.NodeID > 0) == (b.Target.NodeID > 0) {
+		return false
+	}
+	skew := a.Timestamp - b.Timestamp
+	if skew < 0 {
+		skew = -skew
+	}
+	return skew <= captureSkewMs && a.Target.Tag != "" && strings.EqualFold(a.Target.Tag, b.Target.Tag)
+}
+
+// sameTarget reports whether two targets refer to the same element. Node IDs
+// are authoritative when both are known; otherwise the selector or the tag and
+// text must match.
+func sameTarget(a, b models.SemanticTarget) bool {
+	if a.NodeID > 0 && b.NodeID > 0 {
+		return a.NodeID == b.NodeID
+	}
+	if a.Selector != "" && a.Selector != "window" && a.Selector == b.Selector {
+		return true
+	}
+	return a.Text != "" && a.Text == b.Text && strings.EqualFold(a.Tag, b.Tag)
+}
@@ -0,0 +1,144 @@
+package semantic
+
+import (
+	"testing"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+func TestDeduplicate(t *testing.T) {
+	search := models.SemanticTarget{NodeID: 10, Tag: "input", Selector: "#q"}
+	email := models.SemanticTarget{NodeID: 11, Tag: "input", Selector: "#email"}
+	button := models.SemanticTarget{NodeID: 12, Tag: "button", Selector: "#go", Text: "Go"}
+
+	tests := []struct {
+		name    string
+		actions []models.SemanticAction
+		wantIDs []int
+	}{
+		{
+			name: "Clicks before dblclick are upgraded",
+			actions: []models.SemanticAction{
+				{SequenceID: 1, ActionType: models.ActionClick, Target: button, Timestamp: 1000},
+				{SequenceID: 2, ActionType: models.ActionClick, Target: button, Timestamp: 1100},
+				{SequenceID: 3, ActionType: models.ActionDblClick, Target: button, Timestamp: 1150},
+			},
+			wantIDs: []int{3},
+		},
+		{
+			name: "Earlier click on same element is not part of the dblclick",
+			actions: []models.SemanticAction{
+				{SequenceID: 1, ActionType: models.ActionClick, Target: button, Timestamp: 1000},
+				{SequenceID: 2, ActionType: models.ActionClick, Target: button, Timestamp: 5000},
+				{SequenceID: 3, ActionType: models.ActionDblClick, Target: button, Timestamp: 5100},
+			},
+			wantIDs: []int{1, 3},
+		},
+		{
+			name: "Click focus input triad collapses to input",
+			actions: []models.SemanticAction{
+				{SequenceID: 1, ActionType: models.ActionClick, Target: search, Timestamp: 1000},
+				{SequenceID: 2, ActionType: models.ActionFocus, Target: search, Timestamp: 1001},
+				{SequenceID: 3, ActionType: models.ActionInput, Target: search, Value: "cats", Timestamp: 2000},
+			},
+			wantIDs: []int{3},
+		},
+		{
+			name: "Click on another element is kept before input",
+			actions: []models.SemanticAction{
+				{SequenceID: 1, ActionType: models.ActionClick, Target: button, Timestamp: 1000},
+				{SequenceID: 2, ActionType: models.ActionInput, Target: search, Value: "cats", Timestamp: 2000},
+			},
+			wantIDs: []int{1, 2},
+		},
+		{
+			name: "Repeated navigation to same page",
+			actions: []models.SemanticAction{
+				{SequenceID: 1, ActionType: models.ActionNavigate, Value: "https://example.com/a"},
+				{SequenceID: 2, ActionType: models.ActionNavigate, Value: "https://example.com/a?utm=x"},
+			},
+			wantIDs: []int{1},
+		},
+		{
+			name: "Input corrected after typing elsewhere is merged",
+			actions: []models.SemanticAction{
+				{SequenceID: 1, ActionType: models.ActionInput, Target: search, Value: "cat", Timestamp: 1000},
+				{SequenceID: 2, ActionType: models.ActionInput, Target: email, Value: "a@b.c", Timestamp: 2000},
+				{SequenceID: 3, ActionType: models.ActionInput, Target: search, Value: "cats", Timestamp: 3000},
+			},
+			wantIDs: []int{2, 3},
+		},
+		{
+			name: "Input after submit is a new value",
+			actions: []models.SemanticAction{
+				{SequenceID: 1, ActionType: models.ActionInput, Target: search, Value: "cats", Timestamp: 1000},
+				{SequenceID: 2, ActionType: models.ActionKeypress, Target: search, Value: "Enter", Timestamp: 1500},
+				{SequenceID: 3, ActionType: models.ActionInput, Target: search, Value: "dogs", Timestamp: 3000},
+			},
+			wantIDs: []int{1, 2, 3},
+		},
+		{
+			name: "Input before clicking a button is committed",
+			actions: []models.SemanticAction{
+				{SequenceID: 1, ActionType: models.ActionInput, Target: search, Value: "cats", Timestamp: 1000},
+				{SequenceID: 2, ActionType: models.ActionClick, Target: button, Timestamp: 1500},
+				{SequenceID: 3, ActionType: models.ActionInput, Target: search, Value: "dogs", Timestamp: 3000},
+			},
+			wantIDs: []int{1, 2, 3},
+		},
+		{
+			name: "Duplicate capture keeps higher ranked copy",
+			actions: []models.SemanticAction{
+				{SequenceID: 1, ActionType: models.ActionClick, InteractionRank: models.RankLow,
+					Target: models.SemanticTarget{Tag: "p", Selector: "#intro", Text: "Intro"}, Timestamp: 1000},
+				{SequenceID: 2, ActionType: models.ActionClick, InteractionRank: models.RankHigh,
+					Target: models.SemanticTarget{NodeID: 20, Tag: "p", Selector: "#intro"}, Timestamp: 1000},
+			},
+			wantIDs: []int{2},
+		},
+		{
+			name: "rrweb and CDP records with different selectors",
+			actions: []models.SemanticAction{
+				{SequenceID: 1, ActionType: models.ActionClick,
+					Target: models.SemanticTarget{Tag: "textarea", Selector: "#edit"}, Timestamp: 1000},
+				{SequenceID: 2, ActionType: models.ActionClick,
+					Target: models.SemanticTarget{NodeID: 30, Tag: "textarea", Selector: "textarea[name='note']"}, Timestamp: 1000},
+			},
+			wantIDs: []int{1},
+		},
+		{
+			name: "Repeated key presses are kept",
+			actions: []models.SemanticAction{
+				{SequenceID: 1, ActionType: models.ActionKeypress, Target: search, Value: "Backspace", Timestamp: 1000},
+				{SequenceID: 2, ActionType: models.ActionKeypress, Target: search, Value: "Backspace", Timestamp: 1100},
+			},
+			wantIDs: []int{1, 2},
+		},
+		{
+			name: "Same click well apart is kept",
+			actions: []models.SemanticAction{
+				{SequenceID: 1, ActionType: models.ActionClick, Target: button, Timestamp: 1000},
+				{SequenceID: 2, ActionType: models.ActionClick, Target: button, Timestamp: 4000},
+			},
+			wantIDs: []int{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Extractor{}
+			got := e.deduplicate(tt.actions)
+
+			if len(got) != len(tt.wantIDs) {
+				t.Errorf("got %d actions, want %d", len(got), len(tt.wantIDs))
+				return
+			}
+
+			for i, action := range got {
+				if action.SequenceID != tt.wantIDs[i] {
+					t.Errorf("action[%d].SequenceID = %d, want %d", i, action.SequenceID, tt.wantIDs[i])
+				}
+			}
+		})
+	}
+}
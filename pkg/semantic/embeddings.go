@@ -6,10 +6,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"dev/bravebird/browser-automation-go/pkg/models"
+	"dev/bravebird/browser-automation-go/pkg/telemetry"
 )
 
 // EmbeddingService generates embeddings using Ollama
@@ -31,7 +33,8 @@ func NewEmbeddingService(ollamaHost, model string) *EmbeddingService {
 		ollamaHost: ollamaHost,
 		model:      model,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: telemetry.Transport(nil),
 		},
 	}
 }
@@ -137,7 +140,7 @@ func (e *EmbeddingService) EmbedActions(ctx context.Context, actions []models.Se
 		embedding, err := e.GenerateActionEmbedding(ctx, actions[i])
 		if err != nil {
 			// Log but don't fail - embeddings are optional
-			fmt.Printf("Warning: failed to generate embedding for action %d: %v\n", actions[i].SequenceID, err)
+			slog.WarnContext(ctx, "Failed to generate embedding for action", "sequenceID", actions[i].SequenceID, "error", err)
 			continue
 		}
 		actions[i].Embeddings = embedding
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	"unicode"
 
@@ -39,9 +40,8 @@ func (e *Extractor) ExtractActions() []models.SemanticAction {
 	actions := e.parser.ExtractSemanticActions()
 
 	// Post-process actions
-	actions = e.deduplicateNavigations(actions)
-	actions = e.debounceInputs(actions)
 	actions = e.enrichSelectors(actions)
+	actions = e.deduplicate(actions)
 	actions = e.filterLowValueActions(actions)
 	actions = e.resequence(actions)
 
@@ -163,37 +163,6 @@ func (e *Extractor) normalizeURL(url string) string {
 	return baseURL + "?" + strings.Join(filtered, "&")
 }
 
-// debounceInputs combines consecutive input actions on the same element
-func (e *Extractor) debounceInputs(actions []models.SemanticAction) []models.SemanticAction {
-	if len(actions) == 0 {
-		return actions
-	}
-
-	var result []models.SemanticAction
-
-	for i := 0; i < len(actions); i++ {
-		curr := actions[i]
-
-		// If this is an input action, look ahead for more inputs on the same element
-		if curr.ActionType == models.ActionInput && i < len(actions)-1 {
-			for j := i + 1; j < len(actions); j++ {
-				next := actions[j]
-				if next.ActionType == models.ActionInput && next.Target.Selector == curr.Target.Selector {
-					// Take the later value (final input)
-					curr.Value = next.Value
-					i = j
-				} else {
-					break
-				}
-			}
-		}
-
-		result = append(result, curr)
-	}
-
-	return result
-}
-
 // enrichSelectors improves selectors with semantic information
 func (e *Extractor) enrichSelectors(actions []models.SemanticAction) []models.SemanticAction {
 	for i := range actions {
@@ -220,6 +189,7 @@ func (e *Extractor) enrichSelectors(actions []models.SemanticAction) []models.Se
 				if robustSelector != "" {
 					action.Target.Selector = robustSelector
 				}
+				action.Target.Candidates = e.selectorCandidates(action.Target, "")
 			}
 			continue
 		}
@@ -239,11 +209,14 @@ func (e *Extractor) enrichSelectors(actions []models.SemanticAction) []models.Se
 			}
 		}
 
-		// Try to generate a more robust selector
+		// Try to generate a more robust selector, keeping the recorded one as
+		// the last fallback
+		recorded := action.Target.Selector
 		robustSelector := e.generateRobustSelector(action.Target)
 		if robustSelector != "" && robustSelector != action.Target.Selector {
 			action.Target.Selector = robustSelector
 		}
+		action.Target.Candidates = e.selectorCandidates(action.Target, recorded)
 	}
 
 	return actions
@@ -251,48 +224,79 @@ func (e *Extractor) enrichSelectors(actions []models.SemanticAction) []models.Se
 
 // generateRobustSelector creates a selector that's more likely to work across runs
 func (e *Extractor) generateRobustSelector(target models.SemanticTarget) string {
+	if ranked := e.rankSelectors(target); len(ranked) > 0 {
+		return ranked[0]
+	}
+
+	// Fallback: Use the original selector
+	return target.Selector
+}
+
+// rankSelectors returns every stable selector that can find target, most
+// stable first
+func (e *Extractor) rankSelectors(target models.SemanticTarget) []string {
 	attrs := target.Attributes
 	tag := strings.ToLower(target.Tag)
+	var ranked []string
 
 	// Priority 1: Accessibility attributes (most stable)
 	if ariaLabel, ok := attrs["aria-label"].(string); ok && ariaLabel != "" {
-		return fmt.Sprintf("%s[aria-label='%s']", tag, escapeAttrValue(ariaLabel))
+		ranked = append(ranked, fmt.Sprintf("%s[aria-label='%s']", tag, escapeAttrValue(ariaLabel)))
 	}
 
 	// Priority 2: Name attribute (stable for forms)
 	if name, ok := attrs["name"].(string); ok && name != "" {
-		return fmt.Sprintf("%s[name='%s']", tag, escapeAttrValue(name))
+		ranked = append(ranked, fmt.Sprintf("%s[name='%s']", tag, escapeAttrValue(name)))
 	}
 
 	// Priority 3: Placeholder (stable for inputs)
 	if placeholder, ok := attrs["placeholder"].(string); ok && placeholder != "" {
-		return fmt.Sprintf("%s[placeholder='%s']", tag, escapeAttrValue(placeholder))
+		ranked = append(ranked, fmt.Sprintf("%s[placeholder='%s']", tag, escapeAttrValue(placeholder)))
 	}
 
-	// Priority 4: Data attributes (often stable)
-	for key, value := range attrs {
+	// Priority 4: Data attributes (often stable), sorted so the ranking is deterministic
+	var dataKeys []string
+	for key := range attrs {
 		if strings.HasPrefix(key, "data-") && !containsNumbersAndLetters(key) {
-			if strVal, ok := value.(string); ok && strVal != "" && len(strVal) < 50 {
-				return fmt.Sprintf("%s[%s='%s']", tag, key, escapeAttrValue(strVal))
-			}
+			dataKeys = append(dataKeys, key)
+		}
+	}
+	sort.Strings(dataKeys)
+	for _, key := range dataKeys {
+		if strVal, ok := attrs[key].(string); ok && strVal != "" && len(strVal) < 50 {
+			ranked = append(ranked, fmt.Sprintf("%s[%s='%s']", tag, key, escapeAttrValue(strVal)))
 		}
 	}
 
 	// Priority 5: ID (if it doesn't look dynamic)
 	if id, ok := attrs["id"].(string); ok && id != "" && !containsNumbersAndLetters(id) {
-		return "#" + id
+		ranked = append(ranked, "#"+id)
 	}
 
 	// Priority 6: Class (filter out dynamic classes)
 	if class, ok := attrs["class"].(string); ok && class != "" {
 		staticClass := e.extractStaticClass(class)
 		if staticClass != "" {
-			return "." + staticClass
+			ranked = append(ranked, "."+staticClass)
 		}
 	}
 
-	// Fallback: Use the original selector
-	return target.Selector
+	return ranked
+}
+
+// selectorCandidates returns the fallback chain for target: the ranked
+// selectors followed by the selector captured while recording
+func (e *Extractor) selectorCandidates(target models.SemanticTarget, recorded string) []string {
+	var candidates []string
+	seen := make(map[string]bool)
+	for _, selector := range append(e.rankSelectors(target), recorded) {
+		if selector == "" || selector == "window" || seen[selector] {
+			continue
+		}
+		seen[selector] = true
+		candidates = append(candidates, selector)
+	}
+	return candidates
 }
 
 // extractStaticClass finds a non-dynamic class from a class string
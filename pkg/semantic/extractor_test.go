@@ -142,3 +142,28 @@ func TestDeduplicateNavigations(t *testing.T) {
 		})
 	}
 }
+
+func TestSelectorCandidates(t *testing.T) {
+	e := &Extractor{}
+	target := models.SemanticTarget{
+		Tag: "input",
+		Attributes: map[string]interface{}{
+			"name":        "q",
+			"placeholder": "Search",
+			"id":          "search",
+			"class":       "css-1x2y3z search-box",
+		},
+	}
+
+	got := e.selectorCandidates(target, "#search")
+	want := []string{"input[name='q']", "input[placeholder='Search']", "#search", ".search-box"}
+
+	if len(got) != len(want) {
+		t.Fatalf("selectorCandidates() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("candidate[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
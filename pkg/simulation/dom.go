@@ -0,0 +1,378 @@
+package simulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// nodeElement is rrweb's node type for elements
+const nodeElement = 2
+
+// node is an element or other node of the replayed DOM
+type node struct {
+	id       int
+	typ      int
+	tag      string // lowercase
+	attrs    map[string]string
+	parent   *node
+	children []*node
+}
+
+// DOM is the page as a recording saw it, rebuilt from rrweb's full snapshots
+// and the mutations that followed them
+type DOM struct {
+	root  *node
+	nodes map[int]*node
+}
+
+// NewDOM creates an empty DOM, filled by the first full snapshot
+func NewDOM() *DOM {
+	return &DOM{nodes: make(map[int]*node)}
+}
+
+// Loaded reports whether a full snapshot has been applied
+func (d *DOM) Loaded() bool {
+	return d.root != nil
+}
+
+// Apply updates the DOM with an rrweb event: a full snapshot replaces the
+// page and mutations change it. Other events are ignored.
+func (d *DOM) Apply(event models.HybridEvent) error {
+	if event.Source != "rrweb" {
+		return nil
+	}
+	switch eventType(event.Type) {
+	case models.RRWebEventFullSnapshot:
+		var snapshot struct {
+			Node *models.SerializedNode `json:"node"`
+		}
+		if err := json.Unmarshal(event.Data, &snapshot); err != nil {
+			return fmt.Errorf("invalid full snapshot: %w", err)
+		}
+		d.nodes = make(map[int]*node)
+		d.root = d.build(snapshot.Node, nil)
+
+	case models.RRWebEventIncremental:
+		var incr models.RRWebIncrementalData
+		if err := json.Unmarshal(event.Data, &incr); err != nil {
+			return fmt.Errorf("invalid incremental snapshot: %w", err)
+		}
+		if incr.Source == models.SourceMutation {
+			d.mutate(incr)
+		}
+	}
+	return nil
+}
+
+// build adds a serialized node and its subtree under parent
+func (d *DOM) build(sn *models.SerializedNode, parent *node) *node {
+	if sn == nil {
+		return nil
+	}
+	n := &node{id: sn.ID, typ: sn.Type, tag: strings.ToLower(sn.TagName), parent: parent}
+	if len(sn.Attributes) > 0 {
+		n.attrs = make(map[string]string, len(sn.Attributes))
+		for name, value := range sn.Attributes {
+			n.attrs[strings.ToLower(name)] = attrString(value)
+		}
+	}
+	d.nodes[n.id] = n
+	for _, child := range sn.ChildNodes {
+		if c := d.build(child, n); c != nil {
+			n.children = append(n.children, c)
+		}
+	}
+	return n
+}
+
+// mutate applies a mutation batch in rrweb's order: removals, additions,
+// then attribute changes. Text changes don't affect selectors.
+func (d *DOM) mutate(incr models.RRWebIncrementalData) {
+	for _, rm := range incr.Removes {
+		if n := d.nodes[rm.ID]; n != nil {
+			d.detach(n)
+		}
+	}
+
+	for _, add := range incr.Adds {
+		parent := d.nodes[add.ParentID]
+		if parent == nil || add.Node == nil {
+			continue
+		}
+		if old := d.nodes[add.Node.ID]; old != nil {
+			d.detach(old)
+		}
+		n := d.build(add.Node, parent)
+		at := len(parent.children)
+		if add.NextID != nil {
+			for i, sibling := range parent.children {
+				if sibling.id == *add.NextID {
+					at = i
+					break
+				}
+			}
+		}
+		parent.children = append(parent.children, nil)
+		copy(parent.children[at+1:], parent.children[at:])
+		parent.children[at] = n
+	}
+
+	for _, change := range incr.Attributes {
+		n := d.nodes[change.ID]
+		if n == nil {
+			continue
+		}
+		if n.attrs == nil {
+			n.attrs = make(map[string]string)
+		}
+		for name, value := range change.Attributes {
+			name = strings.ToLower(name)
+			if value == nil {
+				delete(n.attrs, name)
+			} else {
+				n.attrs[name] = attrString(value)
+			}
+		}
+	}
+}
+
+// detach removes a node and its subtree
+func (d *DOM) detach(n *node) {
+	if p := n.parent; p != nil {
+		for i, child := range p.children {
+			if child == n {
+				p.children = append(p.children[:i], p.children[i+1:]...)
+				break
+			}
+		}
+	}
+	var forget func(*node)
+	forget = func(n *node) {
+		delete(d.nodes, n.id)
+		for _, child := range n.children {
+			forget(child)
+		}
+	}
+	forget(n)
+}
+
+// queryAll returns the elements matching a CSS selector, in document order
+func (d *DOM) queryAll(css string) ([]*node, error) {
+	sel, err := parseSelector(css)
+	if err != nil {
+		return nil, err
+	}
+	var matches []*node
+	var walk func(*node)
+	walk = func(n *node) {
+		if n.typ == nodeElement && sel.matches(n) {
+			matches = append(matches, n)
+		}
+		for _, child := range n.children {
+			walk(child)
+		}
+	}
+	if d.root != nil {
+		walk(d.root)
+	}
+	return matches, nil
+}
+
+// matches reports whether any selector of the list matches n
+func (s selector) matches(n *node) bool {
+	for _, c := range s {
+		if c.matches(n, 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether n matches the chain from parts[i] on
+func (c complexSelector) matches(n *node, i int) bool {
+	if !c.parts[i].matches(n) {
+		return false
+	}
+	if i == len(c.parts)-1 {
+		return true
+	}
+	switch c.combinators[i] {
+	case '>':
+		p := elementParent(n)
+		return p != nil && c.matches(p, i+1)
+	case ' ':
+		for p := elementParent(n); p != nil; p = elementParent(p) {
+			if c.matches(p, i+1) {
+				return true
+			}
+		}
+	case '+':
+		prev := previousSiblings(n)
+		return len(prev) > 0 && c.matches(prev[len(prev)-1], i+1)
+	case '~':
+		for _, sibling := range previousSiblings(n) {
+			if c.matches(sibling, i+1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matches reports whether n matches the compound selector
+func (c compound) matches(n *node) bool {
+	if c.tag != "" && c.tag != n.tag {
+		return false
+	}
+	if c.id != "" && n.attrs["id"] != c.id {
+		return false
+	}
+	if len(c.classes) > 0 {
+		classes := strings.Fields(n.attrs["class"])
+		for _, want := range c.classes {
+			if !contains(classes, want) {
+				return false
+			}
+		}
+	}
+	for _, a := range c.attrs {
+		if !a.matches(n) {
+			return false
+		}
+	}
+	for _, ps := range c.pseudos {
+		if !ps.matches(n) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether n's attribute satisfies the attribute selector
+func (a attrMatcher) matches(n *node) bool {
+	value, ok := n.attrs[a.name]
+	if !ok {
+		return false
+	}
+	want := a.value
+	if a.fold {
+		value, want = strings.ToLower(value), strings.ToLower(want)
+	}
+	switch a.op {
+	case "":
+		return true
+	case "=":
+		return value == want
+	case "~=":
+		return contains(strings.Fields(value), want)
+	case "|=":
+		return value == want || strings.HasPrefix(value, want+"-")
+	case "^=":
+		return want != "" && strings.HasPrefix(value, want)
+	case "$=":
+		return want != "" && strings.HasSuffix(value, want)
+	case "*=":
+		return want != "" && strings.Contains(value, want)
+	}
+	return false
+}
+
+// matches reports whether n's position among its siblings satisfies the
+// pseudo-class
+func (ps pseudo) matches(n *node) bool {
+	p := n.parent
+	if p == nil {
+		return false
+	}
+	var siblings []*node
+	for _, child := range p.children {
+		if child.typ == nodeElement && (!ps.ofType || child.tag == n.tag) {
+			siblings = append(siblings, child)
+		}
+	}
+	pos := 0
+	for i, sibling := range siblings {
+		if sibling == n {
+			pos = i + 1
+			break
+		}
+	}
+	if ps.last {
+		pos = len(siblings) - pos + 1
+	}
+
+	// pos = a*k + b for some k >= 0
+	if ps.a == 0 {
+		return pos == ps.b
+	}
+	k := pos - ps.b
+	return k%ps.a == 0 && k/ps.a >= 0
+}
+
+// elementParent returns n's parent element, nil at the document
+func elementParent(n *node) *node {
+	if p := n.parent; p != nil && p.typ == nodeElement {
+		return p
+	}
+	return nil
+}
+
+// previousSiblings returns the elements before n under its parent
+func previousSiblings(n *node) []*node {
+	if n.parent == nil {
+		return nil
+	}
+	var prev []*node
+	for _, sibling := range n.parent.children {
+		if sibling == n {
+			break
+		}
+		if sibling.typ == nodeElement {
+			prev = append(prev, sibling)
+		}
+	}
+	return prev
+}
+
+// attrString converts a serialized attribute value, which rrweb may record
+// as a boolean or number, to the string the DOM would hold
+func attrString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		if v {
+			return ""
+		}
+		return "false"
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func contains(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
+
+// eventType returns an rrweb event's numeric type, decoded JSON holding it
+// as float64
+func eventType(t interface{}) int {
+	switch v := t.(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	default:
+		return -1
+	}
+}
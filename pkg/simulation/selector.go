@@ -0,0 +1,382 @@
+package simulation
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupported reports selector syntax the simulation can't evaluate,
+// such as pseudo-classes that depend on the page's live state
+var ErrUnsupported = errors.New("unsupported selector")
+
+// selector is a parsed selector list, matching an element any of its
+// complex selectors match
+type selector []complexSelector
+
+// complexSelector is a chain of compound selectors joined by combinators,
+// stored right to left: parts[0] is the element itself
+type complexSelector struct {
+	parts       []compound
+	combinators []byte // combinators[i] joins parts[i] to parts[i+1]: ' ', '>', '+' or '~'
+}
+
+// compound is a compound selector such as input.search[name='q']
+type compound struct {
+	tag     string // lowercase, empty for any
+	id      string
+	classes []string
+	attrs   []attrMatcher
+	pseudos []pseudo
+}
+
+// attrMatcher is an attribute selector such as [name='q']
+type attrMatcher struct {
+	name  string
+	op    string // empty for presence, or one of = ~= |= ^= $= *=
+	value string
+	fold  bool // the i flag, comparing case-insensitively
+}
+
+// pseudo is a structural pseudo-class such as :nth-of-type(2)
+type pseudo struct {
+	name   string
+	a, b   int // position an+b, from the start or, for the nth-last ones, the end
+	ofType bool
+	last   bool
+}
+
+// parseSelector parses a CSS selector list. Selector syntax the
+// simulation can't evaluate is reported as ErrUnsupported.
+func parseSelector(s string) (selector, error) {
+	p := &selectorParser{src: s}
+	var list selector
+	for {
+		p.skipSpace()
+		complex, err := p.complex()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, complex)
+		p.skipSpace()
+		if p.done() {
+			return list, nil
+		}
+		if p.peek() != ',' {
+			return nil, fmt.Errorf("invalid selector %q: unexpected %q", s, p.peek())
+		}
+		p.pos++
+	}
+}
+
+type selectorParser struct {
+	src string
+	pos int
+}
+
+func (p *selectorParser) done() bool { return p.pos >= len(p.src) }
+
+func (p *selectorParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *selectorParser) skipSpace() bool {
+	start := p.pos
+	for !p.done() && isSpace(p.peek()) {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+// complex parses compounds and combinators up to a comma or the end
+func (p *selectorParser) complex() (complexSelector, error) {
+	var parts []compound
+	var combinators []byte
+	for {
+		part, err := p.compound()
+		if err != nil {
+			return complexSelector{}, err
+		}
+		parts = append(parts, part)
+
+		spaced := p.skipSpace()
+		if p.done() || p.peek() == ',' {
+			break
+		}
+		combinator := byte(' ')
+		switch c := p.peek(); c {
+		case '>', '+', '~':
+			combinator = c
+			p.pos++
+			p.skipSpace()
+		default:
+			if !spaced {
+				return complexSelector{}, fmt.Errorf("invalid selector %q: unexpected %q", p.src, c)
+			}
+		}
+		combinators = append(combinators, combinator)
+	}
+
+	// Matching walks from the element up, so store the chain reversed
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	for i, j := 0, len(combinators)-1; i < j; i, j = i+1, j-1 {
+		combinators[i], combinators[j] = combinators[j], combinators[i]
+	}
+	return complexSelector{parts: parts, combinators: combinators}, nil
+}
+
+// compound parses a compound selector
+func (p *selectorParser) compound() (compound, error) {
+	var c compound
+	start := p.pos
+	if p.peek() == '*' {
+		p.pos++
+	} else if isNameStart(p.peek()) {
+		c.tag = strings.ToLower(p.ident())
+	}
+
+	for !p.done() {
+		switch p.peek() {
+		case '#':
+			p.pos++
+			c.id = p.ident()
+			if c.id == "" {
+				return c, fmt.Errorf("invalid selector %q: empty id", p.src)
+			}
+		case '.':
+			p.pos++
+			class := p.ident()
+			if class == "" {
+				return c, fmt.Errorf("invalid selector %q: empty class", p.src)
+			}
+			c.classes = append(c.classes, class)
+		case '[':
+			attr, err := p.attr()
+			if err != nil {
+				return c, err
+			}
+			c.attrs = append(c.attrs, attr)
+		case ':':
+			ps, err := p.pseudo()
+			if err != nil {
+				return c, err
+			}
+			c.pseudos = append(c.pseudos, ps)
+		default:
+			if p.pos == start {
+				return c, fmt.Errorf("invalid selector %q: unexpected %q", p.src, p.peek())
+			}
+			return c, nil
+		}
+	}
+	if p.pos == start {
+		return c, fmt.Errorf("invalid selector %q: empty", p.src)
+	}
+	return c, nil
+}
+
+// attr parses an attribute selector
+func (p *selectorParser) attr() (attrMatcher, error) {
+	var a attrMatcher
+	p.pos++ // [
+	p.skipSpace()
+	a.name = strings.ToLower(p.ident())
+	if a.name == "" {
+		return a, fmt.Errorf("invalid selector %q: empty attribute name", p.src)
+	}
+	p.skipSpace()
+
+	if p.peek() != ']' {
+		for _, op := range []string{"=", "~=", "|=", "^=", "$=", "*="} {
+			if strings.HasPrefix(p.src[p.pos:], op) {
+				a.op = op
+				break
+			}
+		}
+		if a.op == "" {
+			return a, fmt.Errorf("invalid selector %q: bad attribute operator", p.src)
+		}
+		p.pos += len(a.op)
+		p.skipSpace()
+
+		if q := p.peek(); q == '"' || q == '\'' {
+			value, err := p.quoted(q)
+			if err != nil {
+				return a, err
+			}
+			a.value = value
+		} else {
+			a.value = p.ident()
+		}
+		p.skipSpace()
+		if f := p.peek(); f == 'i' || f == 'I' {
+			a.fold = true
+			p.pos++
+			p.skipSpace()
+		}
+	}
+
+	if p.peek() != ']' {
+		return a, fmt.Errorf("invalid selector %q: unterminated attribute selector", p.src)
+	}
+	p.pos++
+	return a, nil
+}
+
+// pseudo parses a structural pseudo-class, the only kind the recorded DOM
+// can answer
+func (p *selectorParser) pseudo() (pseudo, error) {
+	p.pos++ // :
+	if p.peek() == ':' {
+		return pseudo{}, fmt.Errorf("%w: pseudo-element in %q", ErrUnsupported, p.src)
+	}
+	ps := pseudo{name: strings.ToLower(p.ident())}
+	switch ps.name {
+	case "first-child":
+		ps.b = 1
+	case "last-child":
+		ps.b, ps.last = 1, true
+	case "first-of-type":
+		ps.b, ps.ofType = 1, true
+	case "last-of-type":
+		ps.b, ps.ofType, ps.last = 1, true, true
+	case "nth-child", "nth-of-type", "nth-last-child", "nth-last-of-type":
+		ps.ofType = strings.HasSuffix(ps.name, "of-type")
+		ps.last = strings.Contains(ps.name, "last")
+		if p.peek() != '(' {
+			return ps, fmt.Errorf("invalid selector %q: %s needs an argument", p.src, ps.name)
+		}
+		end := strings.IndexByte(p.src[p.pos:], ')')
+		if end < 0 {
+			return ps, fmt.Errorf("invalid selector %q: unterminated %s", p.src, ps.name)
+		}
+		a, b, err := parseNth(p.src[p.pos+1 : p.pos+end])
+		if err != nil {
+			return ps, fmt.Errorf("invalid selector %q: %w", p.src, err)
+		}
+		ps.a, ps.b = a, b
+		p.pos += end + 1
+	default:
+		return ps, fmt.Errorf("%w: :%s in %q", ErrUnsupported, ps.name, p.src)
+	}
+	return ps, nil
+}
+
+// parseNth parses the an+b argument of the nth pseudo-classes
+func parseNth(arg string) (int, int, error) {
+	arg = strings.ToLower(strings.ReplaceAll(arg, " ", ""))
+	switch arg {
+	case "odd":
+		return 2, 1, nil
+	case "even":
+		return 2, 0, nil
+	}
+	n := strings.IndexByte(arg, 'n')
+	if n < 0 {
+		b, err := strconv.Atoi(arg)
+		return 0, b, err
+	}
+
+	a := 1
+	switch coeff := arg[:n]; coeff {
+	case "", "+":
+	case "-":
+		a = -1
+	default:
+		v, err := strconv.Atoi(coeff)
+		if err != nil {
+			return 0, 0, fmt.Errorf("bad nth argument %q", arg)
+		}
+		a = v
+	}
+	b := 0
+	if rest := arg[n+1:]; rest != "" {
+		v, err := strconv.Atoi(rest)
+		if err != nil {
+			return 0, 0, fmt.Errorf("bad nth argument %q", arg)
+		}
+		b = v
+	}
+	return a, b, nil
+}
+
+// ident reads an identifier, resolving backslash escapes
+func (p *selectorParser) ident() string {
+	var b strings.Builder
+	for !p.done() {
+		c := p.peek()
+		switch {
+		case c == '\\':
+			p.pos++
+			b.WriteString(p.escape())
+		case isNameChar(c):
+			b.WriteByte(c)
+			p.pos++
+		default:
+			return b.String()
+		}
+	}
+	return b.String()
+}
+
+// quoted reads a quoted string, resolving backslash escapes
+func (p *selectorParser) quoted(q byte) (string, error) {
+	p.pos++
+	var b strings.Builder
+	for !p.done() {
+		c := p.peek()
+		switch c {
+		case q:
+			p.pos++
+			return b.String(), nil
+		case '\\':
+			p.pos++
+			b.WriteString(p.escape())
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", fmt.Errorf("invalid selector %q: unterminated string", p.src)
+}
+
+// escape resolves the escape after a backslash: up to six hex digits and an
+// optional space, or a character taken literally
+func (p *selectorParser) escape() string {
+	start := p.pos
+	for p.pos < len(p.src) && p.pos-start < 6 && isHex(p.src[p.pos]) {
+		p.pos++
+	}
+	if p.pos > start {
+		code, _ := strconv.ParseUint(p.src[start:p.pos], 16, 32)
+		if isSpace(p.peek()) {
+			p.pos++
+		}
+		return string(rune(code))
+	}
+	if p.done() {
+		return ""
+	}
+	p.pos++
+	return p.src[start:p.pos]
+}
+
+func isSpace(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' }
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || c == '-' || c == '\\' || c >= 0x80 || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
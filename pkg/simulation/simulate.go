@@ -0,0 +1,102 @@
+// Package simulation checks a workflow's actions against the DOM its
+// recording captured, without a browser: each action's selectors are run
+// against the page as rrweb saw it when the action happened, flagging those
+// that already don't resolve.
+package simulation
+
+import (
+	"fmt"
+	"strings"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// CheckStatus is the outcome of checking an action against the recorded DOM
+type CheckStatus string
+
+const (
+	CheckOK       CheckStatus = "ok"        // The action's primary selector resolves
+	CheckFallback CheckStatus = "fallback"  // Only a fallback selector resolves
+	CheckNotFound CheckStatus = "not_found" // No selector resolves
+	CheckSkipped  CheckStatus = "skipped"   // Nothing to check, or nothing to check against
+)
+
+// ActionCheck is the result of checking one action
+type ActionCheck struct {
+	SequenceID int               `json:"sequence_id"`
+	ActionType models.ActionType `json:"action_type"`
+	Status     CheckStatus       `json:"status"`
+	Selector   string            `json:"selector,omitempty"` // The selector that resolved
+	Matches    int               `json:"matches,omitempty"`  // Elements it resolved to
+	Reason     string            `json:"reason,omitempty"`
+}
+
+// Failed reports whether the check flags the action as broken
+func (c ActionCheck) Failed() bool {
+	return c.Status == CheckNotFound
+}
+
+// Check replays a recording's events and checks each action's selectors
+// against the DOM as it stood at the action's timestamp. Actions are
+// checked in order; one without a timestamp sees the DOM the previous
+// action saw.
+func Check(events []models.HybridEvent, actions []models.SemanticAction) []ActionCheck {
+	dom := NewDOM()
+	next := 0
+	var checks []ActionCheck
+	for _, action := range actions {
+		for action.Timestamp > 0 && next < len(events) && events[next].Timestamp < action.Timestamp {
+			// A malformed event leaves the DOM as it was
+			_ = dom.Apply(events[next])
+			next++
+		}
+		checks = append(checks, checkAction(dom, action))
+	}
+	return checks
+}
+
+// checkAction resolves an action's selector chain against dom
+func checkAction(dom *DOM, action models.SemanticAction) ActionCheck {
+	check := ActionCheck{SequenceID: action.SequenceID, ActionType: action.ActionType}
+
+	candidates := action.CSSSelectors()
+	switch {
+	case action.ActionType == models.ActionNavigate || len(candidates) == 0:
+		check.Status = CheckSkipped
+		check.Reason = "no element to locate"
+		return check
+	case !dom.Loaded():
+		check.Status = CheckSkipped
+		check.Reason = "no DOM snapshot recorded before the action"
+		return check
+	}
+
+	var unsupported []string
+	for i, candidate := range candidates {
+		matches, err := dom.queryAll(candidate)
+		if err != nil {
+			unsupported = append(unsupported, err.Error())
+			continue
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		check.Status = CheckOK
+		if i > 0 {
+			check.Status = CheckFallback
+			check.Reason = fmt.Sprintf("primary selector %q does not resolve", candidates[0])
+		}
+		check.Selector = candidate
+		check.Matches = len(matches)
+		return check
+	}
+
+	if len(unsupported) == len(candidates) {
+		check.Status = CheckSkipped
+		check.Reason = strings.Join(unsupported, "; ")
+		return check
+	}
+	check.Status = CheckNotFound
+	check.Reason = fmt.Sprintf("none of %d selectors resolve", len(candidates)-len(unsupported))
+	return check
+}
@@ -0,0 +1,158 @@
+package simulation
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// page is a recorded document:
+//
+//	<html><body>
+//	  <form id="search" class="box wide">
+//	    <input name="q" placeholder="Search">
+//	    <button type="submit" data-testid="go">Go</button>
+//	  </form>
+//	  <ul><li>a</li><li class="sel">b</li><li>c</li></ul>
+//	</body></html>
+const page = `{"node":{"id":1,"type":0,"childNodes":[
+	{"id":2,"type":2,"tagName":"html","childNodes":[
+		{"id":3,"type":2,"tagName":"body","childNodes":[
+			{"id":4,"type":2,"tagName":"form","attributes":{"id":"search","class":"box wide"},"childNodes":[
+				{"id":5,"type":2,"tagName":"input","attributes":{"name":"q","placeholder":"Search"}},
+				{"id":6,"type":2,"tagName":"button","attributes":{"type":"submit","data-testid":"go"},"childNodes":[
+					{"id":7,"type":3,"textContent":"Go"}]}]},
+			{"id":8,"type":2,"tagName":"ul","childNodes":[
+				{"id":9,"type":2,"tagName":"li"},
+				{"id":10,"type":2,"tagName":"li","attributes":{"class":"sel"}},
+				{"id":11,"type":2,"tagName":"li"}]}]}]}]}}`
+
+func rrwebEvent(ts int64, typ int, data string) models.HybridEvent {
+	return models.HybridEvent{Source: "rrweb", Timestamp: ts, Type: float64(typ), Data: json.RawMessage(data)}
+}
+
+func loadedDOM(t *testing.T) *DOM {
+	t.Helper()
+	dom := NewDOM()
+	if err := dom.Apply(rrwebEvent(1, models.RRWebEventFullSnapshot, page)); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	return dom
+}
+
+func TestQueryAll(t *testing.T) {
+	dom := loadedDOM(t)
+
+	tests := []struct {
+		selector string
+		want     int
+	}{
+		{"input", 1},
+		{"#search", 1},
+		{"form.box.wide", 1},
+		{".box.narrow", 0},
+		{"input[name='q']", 1},
+		{`input[placeholder="search" i]`, 1},
+		{"[data-testid^=g]", 1},
+		{"[class~=wide]", 1},
+		{"form > button", 1},
+		{"body > button", 0},
+		{"body button", 1},
+		{"input + button", 1},
+		{"button + input", 0},
+		{"form ~ ul", 1},
+		{"li:first-child", 1},
+		{"li:nth-child(2).sel", 1},
+		{"li:nth-child(odd)", 2},
+		{"li:nth-last-child(1)", 1},
+		{"li:nth-child(-n+2)", 2},
+		{"input, button, li", 5},
+		{"#sea\\72 ch", 1},
+	}
+	for _, tt := range tests {
+		got, err := dom.queryAll(tt.selector)
+		if err != nil {
+			t.Errorf("queryAll(%q) error = %v", tt.selector, err)
+			continue
+		}
+		if len(got) != tt.want {
+			t.Errorf("queryAll(%q) matched %d, want %d", tt.selector, len(got), tt.want)
+		}
+	}
+
+	if _, err := dom.queryAll("button:hover"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("queryAll(:hover) error = %v, want ErrUnsupported", err)
+	}
+	for _, bad := range []string{"", "input[name", "a >", "#"} {
+		if _, err := dom.queryAll(bad); err == nil {
+			t.Errorf("queryAll(%q) error = nil, want error", bad)
+		}
+	}
+}
+
+func TestDOMMutations(t *testing.T) {
+	dom := loadedDOM(t)
+	mutation := `{"source":0,
+		"removes":[{"parentId":8,"id":9}],
+		"adds":[{"parentId":4,"nextId":6,"node":{"id":12,"type":2,"tagName":"select","attributes":{"name":"sort"}}}],
+		"attributes":[{"id":5,"attributes":{"name":null,"id":"query"}}]}`
+	if err := dom.Apply(rrwebEvent(2, models.RRWebEventIncremental, mutation)); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	tests := []struct {
+		selector string
+		want     int
+	}{
+		{"li", 2},
+		{"li.sel:first-child", 1},
+		{"input + select + button", 1},
+		{"input[name]", 0},
+		{"input#query", 1},
+	}
+	for _, tt := range tests {
+		got, _ := dom.queryAll(tt.selector)
+		if len(got) != tt.want {
+			t.Errorf("queryAll(%q) after mutation matched %d, want %d", tt.selector, len(got), tt.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	events := []models.HybridEvent{
+		rrwebEvent(100, models.RRWebEventFullSnapshot, page),
+		rrwebEvent(300, models.RRWebEventIncremental, `{"source":0,"removes":[{"parentId":4,"id":6}]}`),
+	}
+	actions := []models.SemanticAction{
+		{SequenceID: 1, ActionType: models.ActionNavigate, Timestamp: 50, Target: models.SemanticTarget{Selector: "window"}},
+		{SequenceID: 2, ActionType: models.ActionClick, Timestamp: 80, Target: models.SemanticTarget{Selector: "#search"}},
+		{SequenceID: 3, ActionType: models.ActionInput, Timestamp: 200, Target: models.SemanticTarget{
+			Tag: "input", Selector: "#q", Attributes: map[string]interface{}{"name": "q"}}},
+		{SequenceID: 4, ActionType: models.ActionClick, Timestamp: 250, Target: models.SemanticTarget{
+			Selector: "form > button", Candidates: []string{"#go"}}},
+		{SequenceID: 5, ActionType: models.ActionClick, Timestamp: 400, Target: models.SemanticTarget{Selector: "form > button"}},
+		{SequenceID: 6, ActionType: models.ActionClick, Target: models.SemanticTarget{Selector: "li:hover"}},
+	}
+
+	want := []CheckStatus{CheckSkipped, CheckSkipped, CheckOK, CheckFallback, CheckNotFound, CheckSkipped}
+	checks := Check(events, actions)
+	if len(checks) != len(want) {
+		t.Fatalf("Check() returned %d checks, want %d", len(checks), len(want))
+	}
+	for i, check := range checks {
+		if check.Status != want[i] {
+			t.Errorf("action %d status = %s (%s), want %s", check.SequenceID, check.Status, check.Reason, want[i])
+		}
+	}
+	if got := checks[2].Selector; got != "input[name='q']" {
+		t.Errorf("action 3 selector = %q, want input[name='q']", got)
+	}
+	if got := checks[3].Selector; got != "form > button" {
+		t.Errorf("action 4 selector = %q, want form > button", got)
+	}
+	if !checks[4].Failed() {
+		t.Error("action 5 Failed() = false, want true")
+	}
+}
@@ -0,0 +1,112 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/log"
+)
+
+// temporalHeaderKey is the Temporal header carrying the trace, the one
+// Temporal's own OpenTelemetry interceptor uses
+const temporalHeaderKey = "_tracer-data"
+
+// spanContextKey holds the span in workflow contexts
+type spanContextKey struct{}
+
+// TemporalInterceptor traces the workflows a client starts, and the
+// workflows and activities its workers run, as spans of the trace they were
+// started in. Their loggers tag each line with the trace and span IDs.
+func TemporalInterceptor() interceptor.Interceptor {
+	return interceptor.NewTracingInterceptor(temporalTracer{})
+}
+
+// temporalTracer adapts OpenTelemetry to Temporal's tracing interceptor
+type temporalTracer struct {
+	interceptor.BaseTracer
+}
+
+// temporalSpan is a span started for Temporal, or one continued from a
+// header when its trace is only referenced
+type temporalSpan struct {
+	trace.Span
+}
+
+func (s temporalSpan) Finish(opts *interceptor.TracerFinishSpanOptions) {
+	if opts.Error != nil {
+		s.RecordError(opts.Error)
+		s.SetStatus(codes.Error, opts.Error.Error())
+	}
+	s.End()
+}
+
+func (temporalTracer) Options() interceptor.TracerOptions {
+	return interceptor.TracerOptions{
+		SpanContextKey: spanContextKey{},
+		HeaderKey:      temporalHeaderKey,
+	}
+}
+
+func (temporalTracer) UnmarshalSpan(m map[string]string) (interceptor.TracerSpanRef, error) {
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier(m))
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if !spanCtx.IsValid() {
+		return nil, fmt.Errorf("no trace in Temporal header")
+	}
+	return spanCtx, nil
+}
+
+func (temporalTracer) MarshalSpan(span interceptor.TracerSpan) (map[string]string, error) {
+	m := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(trace.ContextWithSpan(context.Background(), span.(temporalSpan).Span), m)
+	return m, nil
+}
+
+func (temporalTracer) SpanFromContext(ctx context.Context) interceptor.TracerSpan {
+	span := trace.SpanFromContext(ctx)
+	if !span.SpanContext().IsValid() {
+		return nil
+	}
+	return temporalSpan{span}
+}
+
+func (temporalTracer) ContextWithSpan(ctx context.Context, span interceptor.TracerSpan) context.Context {
+	return trace.ContextWithSpan(ctx, span.(temporalSpan).Span)
+}
+
+func (temporalTracer) StartSpan(opts *interceptor.TracerStartSpanOptions) (interceptor.TracerSpan, error) {
+	ctx := context.Background()
+	switch parent := opts.Parent.(type) {
+	case nil:
+	case temporalSpan:
+		ctx = trace.ContextWithSpan(ctx, parent.Span)
+	case trace.SpanContext:
+		ctx = trace.ContextWithRemoteSpanContext(ctx, parent)
+	default:
+		return nil, fmt.Errorf("unknown parent span %T", parent)
+	}
+
+	attrs := make([]attribute.KeyValue, 0, len(opts.Tags))
+	for k, v := range opts.Tags {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	_, span := Tracer().Start(ctx, opts.Operation+":"+opts.Name,
+		trace.WithTimestamp(opts.Time),
+		trace.WithAttributes(attrs...),
+	)
+	return temporalSpan{span}, nil
+}
+
+func (temporalTracer) GetLogger(logger log.Logger, ref interceptor.TracerSpanRef) log.Logger {
+	span, ok := ref.(temporalSpan)
+	if !ok {
+		return logger
+	}
+	return log.With(logger, "traceID", span.SpanContext().TraceID().String(), "spanID", span.SpanContext().SpanID().String())
+}
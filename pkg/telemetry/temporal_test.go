@@ -0,0 +1,66 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.temporal.io/sdk/interceptor"
+)
+
+func TestTemporalTracerContinuesTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	// A request's span is carried in the header of the workflow it starts
+	ctx, request := Tracer().Start(context.Background(), "POST /api/workflows/{id}/run")
+	tracer := temporalTracer{}
+	header, err := tracer.MarshalSpan(tracer.SpanFromContext(ctx))
+	if err != nil {
+		t.Fatalf("MarshalSpan() error = %v", err)
+	}
+
+	// and the workflow's span continues its trace on the worker
+	parent, err := tracer.UnmarshalSpan(header)
+	if err != nil {
+		t.Fatalf("UnmarshalSpan() error = %v", err)
+	}
+	span, err := tracer.StartSpan(&interceptor.TracerStartSpanOptions{
+		Parent:    parent,
+		Operation: "RunWorkflow",
+		Name:      "BrowserAutomationWorkflow",
+		Tags:      map[string]string{"temporalRunID": "run-1"},
+	})
+	if err != nil {
+		t.Fatalf("StartSpan() error = %v", err)
+	}
+	span.Finish(&interceptor.TracerFinishSpanOptions{})
+	request.End()
+
+	ended := recorder.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("ended %d spans, want 2", len(ended))
+	}
+	workflow := ended[0]
+	if workflow.Name() != "RunWorkflow:BrowserAutomationWorkflow" {
+		t.Errorf("span name = %q, want RunWorkflow:BrowserAutomationWorkflow", workflow.Name())
+	}
+	if workflow.Parent().SpanID() != request.SpanContext().SpanID() || workflow.SpanContext().TraceID() != request.SpanContext().TraceID() {
+		t.Errorf("workflow span is not a child of the request span")
+	}
+}
+
+func TestTemporalTracerWithoutTrace(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	tracer := temporalTracer{}
+	if span := tracer.SpanFromContext(context.Background()); span != nil {
+		t.Errorf("SpanFromContext() = %v, want nil", span)
+	}
+	if _, err := tracer.UnmarshalSpan(map[string]string{}); err == nil {
+		t.Error("UnmarshalSpan() of an empty header succeeded")
+	}
+}
@@ -0,0 +1,147 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/go-rod/rod/lib/proto"
+	"go.temporal.io/sdk/activity"
+
+	"dev/bravebird/browser-automation-go/pkg/artifacts"
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// DefaultAxeCore is where workers load axe-core from unless configured
+// otherwise
+const DefaultAxeCore = "https://cdn.jsdelivr.net/npm/axe-core@4.10.2/axe.min.js"
+
+// axeRunScript runs axe-core injected into the page and returns its
+// results as JSON, leaving out the rules the page passes
+const axeRunScript = `() => axe.run(document, {resultTypes: ['violations']}).then((r) => JSON.stringify({url: r.url, timestamp: r.timestamp, violations: r.violations}))`
+
+// axeScript caches axe-core once a worker loaded it
+type axeScript struct {
+	mu     sync.Mutex
+	script string
+}
+
+// load returns axe-core from a URL or file path, loaded on first use. A
+// failed load is retried by the next audit.
+func (ax *axeScript) load(source string) (string, error) {
+	ax.mu.Lock()
+	defer ax.mu.Unlock()
+	if ax.script != "" {
+		return ax.script, nil
+	}
+
+	var data []byte
+	var err error
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		var resp *http.Response
+		if resp, err = http.Get(source); err != nil {
+			return "", fmt.Errorf("failed to fetch axe-core: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("failed to fetch axe-core: %s", resp.Status)
+		}
+		data, err = io.ReadAll(resp.Body)
+	} else {
+		data, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load axe-core: %w", err)
+	}
+	ax.script = string(data)
+	return ax.script, nil
+}
+
+// axeReport is the part of axe-core's results an audit summarizes
+type axeReport struct {
+	URL        string `json:"url"`
+	Violations []struct {
+		ID      string            `json:"id"`
+		Impact  string            `json:"impact"`
+		Help    string            `json:"help"`
+		HelpURL string            `json:"helpUrl"`
+		Nodes   []json.RawMessage `json:"nodes"`
+	} `json:"violations"`
+}
+
+// parseAudit summarizes axe-core's results
+func parseAudit(report string) (*models.AccessibilityAudit, error) {
+	var results axeReport
+	if err := json.Unmarshal([]byte(report), &results); err != nil {
+		return nil, fmt.Errorf("failed to parse accessibility report: %w", err)
+	}
+	audit := &models.AccessibilityAudit{URL: results.URL, Violations: []models.AccessibilityViolation{}}
+	for _, v := range results.Violations {
+		audit.Violations = append(audit.Violations, models.AccessibilityViolation{
+			Rule:    v.ID,
+			Impact:  v.Impact,
+			Help:    v.Help,
+			HelpURL: v.HelpURL,
+			Nodes:   len(v.Nodes),
+		})
+	}
+	return audit, nil
+}
+
+func (p *rodPage) Audit(axe string) (string, error) {
+	// Evaluated through the protocol, the script isn't subject to the
+	// page's content security policy
+	res, err := proto.RuntimeEvaluate{Expression: axe}.Call(p.page)
+	if err != nil {
+		return "", fmt.Errorf("failed to inject axe-core: %w", err)
+	}
+	if res.ExceptionDetails != nil {
+		return "", fmt.Errorf("failed to inject axe-core: %s", res.ExceptionDetails.Text)
+	}
+	report, err := p.page.Eval(axeRunScript)
+	if err != nil {
+		return "", fmt.Errorf("accessibility audit failed: %w", err)
+	}
+	return report.Value.Str(), nil
+}
+
+// auditAccessibility audits the page an action opened, saving axe-core's
+// report among the run's downloads. An audit that fails is logged and left
+// out rather than failing the action.
+func (a *Activities) auditAccessibility(ctx context.Context, page BrowserPage, runID, filename string) *models.AccessibilityAudit {
+	logger := activity.GetLogger(ctx)
+	if a.AxeCore == "" {
+		logger.Warn("Skipping accessibility audit, no axe-core configured")
+		return nil
+	}
+	axe, err := a.axe.load(a.AxeCore)
+	if err != nil {
+		logger.Warn("Skipping accessibility audit", "error", err)
+		return nil
+	}
+	report, err := page.Audit(axe)
+	if err != nil {
+		logger.Warn("Accessibility audit failed", "error", err)
+		return nil
+	}
+	audit, err := parseAudit(report)
+	if err != nil {
+		logger.Warn("Accessibility audit failed", "error", err)
+		return nil
+	}
+
+	if a.downloadDir(runID) != "" {
+		_, err := a.artifactStore().Put(ctx, artifacts.DownloadKey(runID, filename), "application/json", strings.NewReader(report))
+		if err != nil {
+			logger.Warn("Failed to save accessibility report", "file", filename, "error", err)
+		} else {
+			audit.Report = filename
+		}
+	}
+	return audit
+}
@@ -0,0 +1,94 @@
+package activities
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// sessionsPath is where the worker's HTTP server lists its browser
+// sessions, and force-closes one when followed by its ID
+const sessionsPath = "/sessions/"
+
+// pageInfoTimeout bounds how long listing sessions waits for each browser
+// to report its page, as stuck browsers are what operators look for
+const pageInfoTimeout = 2 * time.Second
+
+// SessionsHandler lists the browser sessions open on this worker on GET
+// /sessions/, and closes one on DELETE /sessions/{id}, for operators
+// cleaning up stuck sessions without restarting the worker
+func (a *Activities) SessionsHandler() http.Handler {
+	return http.StripPrefix(sessionsPath, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Path
+		switch {
+		case r.Method == http.MethodGet && id == "":
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(a.listSessions())
+		case r.Method == http.MethodDelete && id != "":
+			if _, ok := a.Pool.Sessions()[id]; !ok {
+				http.Error(w, "Browser session not found", http.StatusNotFound)
+				return
+			}
+			a.Pool.Close(id)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+}
+
+// listSessions describes the open sessions, oldest first
+func (a *Activities) listSessions() []models.OpenBrowserSession {
+	sessions := a.Pool.Sessions()
+	list := make([]models.OpenBrowserSession, 0, len(sessions))
+	pages := make([]BrowserPage, 0, len(sessions))
+	for id, session := range sessions {
+		list = append(list, models.OpenBrowserSession{
+			ID:         id,
+			RunID:      session.RunID,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			Remote:     session.disconnect != nil,
+			Worker:     a.WorkerURL,
+		})
+		pages = append(pages, session.Page)
+	}
+
+	// Browsers are asked for their page at once, so stuck ones only hold
+	// the list up for pageInfoTimeout
+	var wg sync.WaitGroup
+	for i, page := range pages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			list[i].URL = pageURL(page)
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// pageURL returns the URL the page shows, empty when its browser doesn't
+// answer within pageInfoTimeout
+func pageURL(page BrowserPage) string {
+	if page == nil {
+		return ""
+	}
+	info := make(chan string, 1)
+	go func() {
+		url, _, _ := page.Info()
+		info <- url
+	}()
+	select {
+	case url := <-info:
+		return url
+	case <-time.After(pageInfoTimeout):
+		return ""
+	}
+}
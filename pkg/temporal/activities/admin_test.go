@@ -0,0 +1,53 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+func TestSessionsHandler(t *testing.T) {
+	a := &Activities{Pool: NewBrowserPool(PoolConfig{MaxSessions: 2}), WorkerURL: "http://worker:8081"}
+	for _, id := range []string{"s-1", "s-2"} {
+		a.Pool.Reserve(context.Background())
+		a.Pool.Add(id, &BrowserSessionData{RunID: "run-" + id})
+	}
+	handler := a.SessionsHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sessions/", nil))
+	var sessions []models.OpenBrowserSession
+	if err := json.NewDecoder(rec.Body).Decode(&sessions); err != nil {
+		t.Fatalf("decoding sessions: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].RunID != "run-"+sessions[0].ID || sessions[0].Worker != a.WorkerURL {
+		t.Fatalf("sessions = %+v, want s-1 and s-2 with their runs", sessions)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   int
+	}{
+		{"close", http.MethodDelete, "/sessions/s-1", http.StatusNoContent},
+		{"already closed", http.MethodDelete, "/sessions/s-1", http.StatusNotFound},
+		{"get one", http.MethodGet, "/sessions/s-2", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+	if a.Pool.Len() != 1 {
+		t.Errorf("%d sessions open, want 1", a.Pool.Len())
+	}
+}
@@ -0,0 +1,46 @@
+package activities
+
+import (
+	"context"
+	"os"
+
+	"go.temporal.io/sdk/activity"
+
+	"dev/bravebird/browser-automation-go/pkg/artifacts"
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// artifactStore returns where the worker keeps artifacts, its screenshot
+// and download directories unless a store is configured
+func (a *Activities) artifactStore() artifacts.Store {
+	if a.Artifacts != nil {
+		return a.Artifacts
+	}
+	return artifacts.NewLocal(a.ScreenshotDir, a.DownloadDir, "")
+}
+
+// storeDownloads moves a run's downloads from the browser's download
+// directory into an object store, pointing their paths at it. Downloads
+// that fail to upload are logged and left on disk.
+func (a *Activities) storeDownloads(ctx context.Context, runID string, files []models.DownloadedFile) []models.DownloadedFile {
+	store := a.artifactStore()
+	if _, local := store.(*artifacts.Local); local {
+		return files
+	}
+	for i, file := range files {
+		f, err := os.Open(file.Path)
+		if err != nil {
+			activity.GetLogger(ctx).Warn("Failed to store download", "file", file.Filename, "error", err)
+			continue
+		}
+		location, err := store.Put(ctx, artifacts.DownloadKey(runID, file.Filename), "", f)
+		f.Close()
+		if err != nil {
+			activity.GetLogger(ctx).Warn("Failed to store download", "file", file.Filename, "error", err)
+			continue
+		}
+		os.Remove(file.Path)
+		files[i].Path = location
+	}
+	return files
+}
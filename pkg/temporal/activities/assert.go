@@ -0,0 +1,117 @@
+package activities
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// AssertionError is returned by an assert action whose check doesn't hold,
+// as opposed to one that couldn't be made, such as when its element isn't
+// found. Temporal reports it with the AssertionError type, which retry
+// policies can name.
+type AssertionError struct {
+	Assertion models.Assertion
+	Actual    string // The text, URL or title the page had
+}
+
+func (e *AssertionError) Error() string {
+	switch e.Assertion.Type {
+	case models.AssertTextEquals:
+		return fmt.Sprintf("assertion failed: text is %q, expected %q", e.Actual, e.Assertion.Value)
+	case models.AssertTextContains:
+		return fmt.Sprintf("assertion failed: text %q does not contain %q", e.Actual, e.Assertion.Value)
+	case models.AssertExists:
+		return fmt.Sprintf("assertion failed: no element matches %s", e.Actual)
+	case models.AssertAbsent:
+		return fmt.Sprintf("assertion failed: %s is present", e.Actual)
+	case models.AssertURLMatches:
+		return fmt.Sprintf("assertion failed: URL %s does not match %s", e.Actual, e.Assertion.Value)
+	case models.AssertTitleEquals:
+		return fmt.Sprintf("assertion failed: title is %q, expected %q", e.Actual, e.Assertion.Value)
+	}
+	return fmt.Sprintf("assertion failed: %s", e.Assertion.Type)
+}
+
+// assert checks an assert action's assertion, its value having parameters
+// substituted
+func (a *Activities) assert(page BrowserPage, action models.SemanticAction, params map[string]string, result *models.ActionResult) error {
+	if action.Assert == nil {
+		return fmt.Errorf("assert action %d has no assertion", action.SequenceID)
+	}
+	check := *action.Assert
+	for name, value := range params {
+		check.Value = strings.ReplaceAll(check.Value, "{{"+name+"}}", value)
+	}
+
+	switch check.Type {
+	case models.AssertURLMatches, models.AssertTitleEquals:
+		url, title, err := page.Info()
+		if err != nil {
+			return err
+		}
+		if check.Type == models.AssertTitleEquals {
+			if title != check.Value {
+				return &AssertionError{Assertion: check, Actual: title}
+			}
+			return nil
+		}
+		pattern, err := regexp.Compile(check.Value)
+		if err != nil {
+			return fmt.Errorf("invalid url_matches pattern: %w", err)
+		}
+		if !pattern.MatchString(url) {
+			return &AssertionError{Assertion: check, Actual: url}
+		}
+		return nil
+
+	case models.AssertExists, models.AssertAbsent:
+		candidates := a.selectorCandidates(action)
+		if len(candidates) == 0 {
+			return fmt.Errorf("no selector for %s target", action.Target.Tag)
+		}
+		i, err := page.Locate(candidates, action.Target.Tag)
+		if check.Type == models.AssertExists && err != nil {
+			return &AssertionError{Assertion: check, Actual: describeTarget(action.Target)}
+		}
+		if check.Type == models.AssertAbsent && err == nil {
+			result.Selector = candidates[i]
+			return &AssertionError{Assertion: check, Actual: candidates[i]}
+		}
+		if err == nil {
+			result.Selector = candidates[i]
+			result.SelectorFallbacks = i
+			result.SelectorSource = selectorSource(action, candidates, i)
+		}
+		return nil
+	}
+
+	selector, err := a.locate(page, action, result)
+	if err != nil {
+		return err
+	}
+	text, err := page.Read(selector, action.Target.Tag, action.Target.Text, "")
+	if err != nil {
+		return err
+	}
+	text = strings.TrimSpace(text)
+	expected := strings.TrimSpace(check.Value)
+	if check.Type == models.AssertTextEquals && text != expected ||
+		check.Type == models.AssertTextContains && !strings.Contains(text, expected) {
+		return &AssertionError{Assertion: check, Actual: text}
+	}
+	return nil
+}
+
+// describeTarget names an element in assertion messages
+func describeTarget(target models.SemanticTarget) string {
+	if target.Selector != "" && target.Selector != "window" {
+		return target.Selector
+	}
+	if target.Text != "" {
+		return fmt.Sprintf("%s %q", target.Tag, target.Text)
+	}
+	return target.Tag
+}
@@ -0,0 +1,59 @@
+package activities
+
+import (
+	"strings"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// handleAuth answers the proxy's and the sites' authentication challenges
+// for the page's requests until it closes. Chrome negotiates the scheme, so
+// basic, digest and NTLM challenges all take the same credentials.
+func handleAuth(page *rod.Page, proxy *models.ProxyConfig, creds *models.HTTPCredentials) error {
+	if (proxy == nil || proxy.Username == "") && creds == nil {
+		return nil
+	}
+
+	// Subscribe before enabling interception so no paused request is missed
+	wait := page.EachEvent(func(e *proto.FetchRequestPaused) {
+		_ = proto.FetchContinueRequest{RequestID: e.RequestID}.Call(page)
+	}, func(e *proto.FetchAuthRequired) {
+		_ = proto.FetchContinueWithAuth{
+			RequestID:             e.RequestID,
+			AuthChallengeResponse: authResponse(e.AuthChallenge, proxy, creds),
+		}.Call(page)
+	})
+	go wait()
+
+	return proto.FetchEnable{HandleAuthRequests: true}.Call(page)
+}
+
+// authResponse picks the credentials for a challenge. Proxy credentials are
+// only sent to the proxy and site credentials only to their origin, so
+// neither leaks elsewhere; other challenges get the browser's default
+// response.
+func authResponse(challenge *proto.FetchAuthChallenge, proxy *models.ProxyConfig, creds *models.HTTPCredentials) *proto.FetchAuthChallengeResponse {
+	response := &proto.FetchAuthChallengeResponse{
+		Response: proto.FetchAuthChallengeResponseResponseDefault,
+	}
+	if challenge == nil {
+		return response
+	}
+
+	switch {
+	case challenge.Source == proto.FetchAuthChallengeSourceProxy:
+		if proxy != nil && proxy.Username != "" {
+			response.Response = proto.FetchAuthChallengeResponseResponseProvideCredentials
+			response.Username = proxy.Username
+			response.Password = proxy.Password
+		}
+	case creds != nil && (creds.Origin == "" || strings.EqualFold(strings.TrimSuffix(creds.Origin, "/"), challenge.Origin)):
+		response.Response = proto.FetchAuthChallengeResponseResponseProvideCredentials
+		response.Username = creds.Username
+		response.Password = creds.Password
+	}
+	return response
+}
@@ -1,49 +1,57 @@
 package activities
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-rod/rod"
-	"github.com/go-rod/rod/lib/input"
+	"github.com/go-rod/rod/lib/cdp"
 	"github.com/go-rod/rod/lib/launcher"
-	"github.com/go-rod/rod/lib/proto"
 	"github.com/google/uuid"
 	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/log"
 
+	"dev/bravebird/browser-automation-go/pkg/artifacts"
+	"dev/bravebird/browser-automation-go/pkg/codegen"
+	"dev/bravebird/browser-automation-go/pkg/database"
 	"dev/bravebird/browser-automation-go/pkg/llm"
 	"dev/bravebird/browser-automation-go/pkg/models"
+	"dev/bravebird/browser-automation-go/pkg/notify"
+	"dev/bravebird/browser-automation-go/pkg/secrets"
 	"dev/bravebird/browser-automation-go/pkg/temporal/workflows"
 )
 
-// BrowserPool manages browser sessions
-type BrowserPool struct {
-	sessions map[string]*BrowserSessionData
-	mu       sync.RWMutex
-}
-
-// BrowserSessionData holds data for a browser session
-type BrowserSessionData struct {
-	Browser     *rod.Browser
-	Page        *rod.Page
-	LLMProvider llm.Provider
-	CreatedAt   time.Time
-}
-
-var browserPool = &BrowserPool{
-	sessions: make(map[string]*BrowserSessionData),
-}
-
 // Activities holds activity implementations
 type Activities struct {
 	LLMConfigs    map[string]llm.Config
 	ScreenshotDir string
+	Templates     *llm.TemplateSet // templates for the fallback generator
+	DB            *database.DB     // stores pre-generated code, inline when nil
+	Pool          *BrowserPool     // browsers open on this worker
+	Remote        *RemoteEndpoints // remote Chrome fleet, local browsers when empty
+	Sessions      *secrets.Sealer  // encrypts saved sessions, saving disabled when nil
+	DownloadDir   string           // downloads are kept under the run's ID, discarded when empty
+	DomainLimits  *DomainLimits    // actions per minute by target domain, unlimited when nil
+	WorkerURL     string           // where the API reaches this worker's HTTP server, no screencasts when empty
+	AxeCore       string           // URL or file path of axe-core, no accessibility audits when empty
+	Artifacts     artifacts.Store  // where screenshots and downloads are kept, the dirs above when nil
+	Notify        notify.Config    // Slack token and mail server for run notifications
+	Temporal      client.Client    // reports the phases of actions to their runs, unreported when nil
+	PublicURL     string           // where the API is reached from outside, for links in notifications
+	APIURL        string           // where the worker reaches the API to push run progress, polled when empty
+	ProgressToken string           // the API's PROGRESS_TOKEN, progress is polled when empty
+
+	axe axeScript
 }
 
 // NewActivities creates new activities
@@ -51,52 +59,189 @@ func NewActivities(llmConfigs map[string]llm.Config, screenshotDir string) *Acti
 	return &Activities{
 		LLMConfigs:    llmConfigs,
 		ScreenshotDir: screenshotDir,
+		Templates:     llm.DefaultTemplates(),
+		Pool:          NewBrowserPool(DefaultPoolConfig()),
+	}
+}
+
+// templatesFor returns the worker's templates with the run's custom
+// templates applied. Invalid overrides are logged and skipped.
+func (a *Activities) templatesFor(ctx context.Context, overrides map[models.ActionType]string) *llm.TemplateSet {
+	if len(overrides) == 0 {
+		return a.Templates
+	}
+	templates := a.Templates.Clone()
+	for actionType, source := range overrides {
+		if err := templates.Override(actionType, source); err != nil {
+			activity.GetLogger(ctx).Warn("Ignoring invalid code template", "actionType", actionType, "error", err)
+		}
 	}
+	return templates
 }
 
 // InitializeBrowserActivity initializes a browser session
 func (a *Activities) InitializeBrowserActivity(ctx context.Context, input workflows.BrowserInitInput) (workflows.BrowserSession, error) {
-	logger := activity.GetLogger(ctx)
+	logger := log.With(activity.GetLogger(ctx), "runID", input.RunID)
 	logger.Info("Initializing browser session", "headless", input.Headless)
 
-	// Launch browser
-	l := launcher.New()
+	if input.Session != "" {
+		state, err := a.openSession(ctx, input.Session)
+		if err != nil {
+			return workflows.BrowserSession{}, err
+		}
+		input.StorageState = state
+	}
 
-	// Use CHROME_BIN if set (Docker environment)
-	if chromeBin := os.Getenv("CHROME_BIN"); chromeBin != "" {
-		l = l.Bin(chromeBin)
+	// Remote browsers run elsewhere and take no local slot. Remote endpoints
+	// are Chrome, other engines launch on the worker.
+	chromium := input.Browser == "" || input.Browser == models.BrowserChromium
+	if input.Endpoint != "" && !chromium {
+		return workflows.BrowserSession{}, fmt.Errorf("browser endpoints only serve chromium, not %s", input.Browser)
+	}
+	if chromium && (input.Endpoint != "" || a.Remote.Len() > 0) {
+		return a.initializeRemoteBrowser(ctx, input)
 	}
 
-	// Configure headless mode
-	if input.Headless {
-		l = l.Headless(true)
+	// Wait for a free browser slot, heartbeating so a queued init is not
+	// mistaken for a stuck one. Urgent runs get the slots first.
+	reserved := make(chan error, 1)
+	go func() { reserved <- a.Pool.ReserveFor(ctx, input.Priority) }()
+	for waiting := true; waiting; {
+		select {
+		case err := <-reserved:
+			if err != nil {
+				return workflows.BrowserSession{}, fmt.Errorf("failed to reserve browser: %w", err)
+			}
+			waiting = false
+		case <-time.After(10 * time.Second):
+			logger.Info("Waiting for a free browser slot", "open", a.Pool.Len())
+			activity.RecordHeartbeat(ctx, "waiting for browser slot")
+		}
+	}
+	launched := false
+	defer func() {
+		if !launched {
+			a.Pool.Release()
+		}
+	}()
+
+	session := &BrowserSessionData{}
+	var err error
+	if chromium {
+		session.Page, session.chrome, err = a.launchChromium(ctx, input)
 	} else {
-		// Non-headless mode - use the DISPLAY env var for Xvfb
-		l = l.Headless(false)
+		var driver BrowserDriver
+		if driver, err = driverFor(input.Browser); err == nil {
+			logger.Info("Launching browser", "browser", input.Browser)
+			opts := launchOptions(input)
+			opts.DownloadDir = a.downloadDir(input.RunID)
+			session.Page, err = driver.Launch(ctx, opts)
+		}
+	}
+	if err != nil {
+		return workflows.BrowserSession{}, err
 	}
 
-	// Additional Chrome flags for Docker compatibility
-	l = l.Set("no-sandbox")
-	l = l.Set("disable-gpu")
-	l = l.Set("disable-dev-shm-usage")
+	sessionID := a.addSession(input, session)
+	launched = true
 
-	url, err := l.Launch()
+	logger.Info("Browser session created", "sessionID", sessionID)
+
+	return workflows.BrowserSession{
+		SessionID:     sessionID,
+		PageURL:       "about:blank",
+		ScreencastURL: a.ScreencastURL(sessionID),
+	}, nil
+}
+
+// launchChromium launches Chrome for the run, or opens a fresh incognito
+// context in the worker's warm browser so the run skips Chrome's startup.
+// The Chrome launched for the run is returned for the session to stop,
+// and its CDP calls traced, unlike those of the warm browser runs share.
+func (a *Activities) launchChromium(ctx context.Context, input workflows.BrowserInitInput) (BrowserPage, *launchedBrowser, error) {
+	var browser *rod.Browser
+	var launched *launchedBrowser
+	var err error
+	if input.ReuseBrowser {
+		var shared *rod.Browser
+		shared, err = a.Pool.WarmBrowser(input.Headless, func() (*launchedBrowser, error) {
+			activity.GetLogger(ctx).Info("Launching warm browser", "headless", input.Headless)
+			return a.Pool.launch(LaunchOptions{Headless: input.Headless})
+		})
+		if err == nil {
+			browser, err = newContext(shared, input.Proxy)
+		}
+	} else if launched, err = a.Pool.launch(launchOptions(input)); err == nil {
+		browser = launched.Browser
+	}
 	if err != nil {
-		return workflows.BrowserSession{}, fmt.Errorf("failed to launch browser: %w", err)
+		return nil, nil, err
 	}
 
-	browser := rod.New().ControlURL(url)
-	if err := browser.Connect(); err != nil {
-		return workflows.BrowserSession{}, fmt.Errorf("failed to connect to browser: %w", err)
+	opts := launchOptions(input)
+	opts.DownloadDir = a.downloadDir(input.RunID)
+	page, err := openPage(browser, opts)
+	if err != nil {
+		if launched != nil {
+			launched.stop()
+		} else {
+			browser.Close()
+		}
+		return nil, nil, err
+	}
+	return page, launched, nil
+}
+
+// downloadDir returns the directory a run's downloads are saved to. Remote
+// browsers save downloads on their own machine, so they get none.
+func (a *Activities) downloadDir(runID string) string {
+	if a.DownloadDir == "" || runID == "" {
+		return ""
+	}
+	return filepath.Join(a.DownloadDir, filepath.Base(runID))
+}
+
+// initializeRemoteBrowser opens the run's browser context in the requested
+// remote Chrome, or the next browser of the worker's fleet
+func (a *Activities) initializeRemoteBrowser(ctx context.Context, input workflows.BrowserInitInput) (workflows.BrowserSession, error) {
+	logger := activity.GetLogger(ctx)
+
+	var browser *rod.Browser
+	var disconnect func()
+	var err error
+	endpoint := input.Endpoint
+	if endpoint != "" {
+		browser, disconnect, err = connectRemote(ctx, endpoint, input.Proxy)
+	} else {
+		browser, disconnect, endpoint, err = a.Remote.connectFleet(ctx, input.Proxy)
+	}
+	if err != nil {
+		return workflows.BrowserSession{}, err
 	}
 
-	page, err := browser.Page(proto.TargetCreateTarget{URL: "about:blank"})
+	page, err := openPage(browser, launchOptions(input))
 	if err != nil {
 		browser.Close()
-		return workflows.BrowserSession{}, fmt.Errorf("failed to create page: %w", err)
+		disconnect()
+		return workflows.BrowserSession{}, err
 	}
 
-	// Create LLM provider
+	sessionID := a.addSession(input, &BrowserSessionData{
+		Page:       page,
+		disconnect: disconnect,
+	})
+
+	logger.Info("Remote browser session created", "sessionID", sessionID, "endpoint", endpoint)
+
+	return workflows.BrowserSession{
+		SessionID:     sessionID,
+		PageURL:       "about:blank",
+		ScreencastURL: a.ScreencastURL(sessionID),
+	}, nil
+}
+
+// addSession gives a session the run's LLM provider and stores it in the pool
+func (a *Activities) addSession(input workflows.BrowserInitInput, session *BrowserSessionData) string {
 	// Create LLM provider
 	var llmProvider llm.Provider
 	providerName := input.LLMProvider
@@ -121,52 +266,130 @@ func (a *Activities) InitializeBrowserActivity(ctx context.Context, input workfl
 
 	// Store session
 	sessionID := uuid.New().String()
-	browserPool.mu.Lock()
-	browserPool.sessions[sessionID] = &BrowserSessionData{
-		Browser:     browser,
-		Page:        page,
-		LLMProvider: llmProvider,
-		CreatedAt:   time.Now(),
-	}
-	browserPool.mu.Unlock()
+	session.LLMProvider = llmProvider
+	session.RunID = input.RunID
+	a.Pool.Add(sessionID, session)
+	return sessionID
+}
 
-	logger.Info("Browser session created", "sessionID", sessionID)
+// chromeExitTimeout bounds how long a closed Chrome gets to exit before
+// its processes are killed
+const chromeExitTimeout = 5 * time.Second
 
-	return workflows.BrowserSession{
-		SessionID: sessionID,
-		PageURL:   "about:blank",
-	}, nil
+// launchedBrowser is a Chrome the worker started, whose processes and
+// profile it has to clean up
+type launchedBrowser struct {
+	*rod.Browser
+	cdp        *cdpTracer // traces the browser's CDP calls
+	launcher   *launcher.Launcher
+	launchedAt time.Time
+
+	stopOnce sync.Once
+	stopped  func() // called once the browser is stopped
 }
 
-// CloseBrowserActivity closes a browser session
-func (a *Activities) CloseBrowserActivity(ctx context.Context, sessionID string) error {
-	logger := activity.GetLogger(ctx)
-	logger.Info("Closing browser session", "sessionID", sessionID)
+// stop closes the browser, kills its processes if they haven't exited
+// within chromeExitTimeout, and removes its profile. Stopping a stopped
+// browser is a no-op.
+func (b *launchedBrowser) stop() {
+	b.stopOnce.Do(func() {
+		b.Browser.Close()
+
+		exited := make(chan struct{})
+		go func() {
+			b.launcher.Cleanup()
+			close(exited)
+		}()
+		select {
+		case <-exited:
+		case <-time.After(chromeExitTimeout):
+			b.launcher.Kill()
+			<-exited
+		}
+		if b.stopped != nil {
+			b.stopped()
+		}
+	})
+}
 
-	browserPool.mu.Lock()
-	defer browserPool.mu.Unlock()
+// launchBrowser starts Chrome with the run's headless, proxy and stealth
+// flags, keeping its profile in profileDir or a directory of its own when
+// empty, and connects to it through a client whose calls can be traced
+func launchBrowser(opts LaunchOptions, profileDir string) (*launchedBrowser, error) {
+	l := launcher.New()
+	if profileDir != "" {
+		l = l.UserDataDir(profileDir)
+	}
 
-	session, ok := browserPool.sessions[sessionID]
-	if !ok {
-		return nil // Already closed
+	// Use CHROME_BIN if set (Docker environment)
+	if chromeBin := os.Getenv("CHROME_BIN"); chromeBin != "" {
+		l = l.Bin(chromeBin)
+	}
+
+	// Configure headless mode
+	if opts.Headless {
+		l = l.Headless(true)
+	} else {
+		// Non-headless mode - use the DISPLAY env var for Xvfb
+		l = l.Headless(false)
+	}
+
+	// Additional Chrome flags for Docker compatibility
+	l = l.Set("no-sandbox")
+	l = l.Set("disable-gpu")
+	l = l.Set("disable-dev-shm-usage")
+
+	if proxy := opts.Proxy; proxy != nil {
+		l = l.Proxy(proxy.Server)
+		if bypass := chromeBypassList(proxy.Bypass); bypass != "" {
+			l = l.Set("proxy-bypass-list", bypass)
+		}
 	}
 
-	if session.Browser != nil {
-		session.Browser.Close()
+	// Stops Blink from flagging the session as automated
+	if opts.Stealth {
+		l = l.Set("disable-blink-features", "AutomationControlled")
 	}
 
-	delete(browserPool.sessions, sessionID)
+	url, err := l.Launch()
+	if err != nil {
+		return nil, fmt.Errorf("failed to launch browser: %w", err)
+	}
+
+	client, err := cdp.StartWithURL(context.Background(), url, nil)
+	if err != nil {
+		l.Kill()
+		l.Cleanup()
+		return nil, fmt.Errorf("failed to connect to browser: %w", err)
+	}
+	tracer := &cdpTracer{CDPClient: client}
+	browser := rod.New().Client(tracer)
+	if err := browser.Connect(); err != nil {
+		l.Kill()
+		l.Cleanup()
+		return nil, fmt.Errorf("failed to connect to browser: %w", err)
+	}
+	return &launchedBrowser{Browser: browser, cdp: tracer, launcher: l, launchedAt: time.Now()}, nil
+}
+
+// CloseBrowserActivity closes a browser session
+func (a *Activities) CloseBrowserActivity(ctx context.Context, sessionID string) error {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Closing browser session", "sessionID", sessionID)
+
+	a.Pool.Close(sessionID) // No-op if already closed
 	return nil
 }
 
 // PreGenerateCodeActivity pre-generates Go Rod code for all actions before browser execution
 func (a *Activities) PreGenerateCodeActivity(ctx context.Context, input workflows.PreGenerateCodeInput) (workflows.PreGeneratedCode, error) {
-	logger := activity.GetLogger(ctx)
+	logger := log.With(activity.GetLogger(ctx), "workflowID", input.WorkflowID, "runID", input.RunID)
 	logger.Info("Pre-generating Go Rod code", "actionCount", len(input.Actions), "llmProvider", input.LLMProvider)
 
 	result := workflows.PreGeneratedCode{
 		ActionCodes: make(map[int]string),
 	}
+	templates := a.templatesFor(ctx, input.CodeTemplates)
 
 	// Get LLM provider
 	var llmProvider llm.Provider
@@ -203,20 +426,12 @@ func (a *Activities) PreGenerateCodeActivity(ctx context.Context, input workflow
 		// Fall back to template-based generation
 		logger.Warn("LLM provider not available, using template-based code generation")
 		for _, action := range input.Actions {
-			code := llm.GenerateCodeFromAction(action, input.Parameters)
-			result.ActionCodes[action.SequenceID] = code
+			code := templates.Generate(action, input.Parameters)
+			a.storeActionCode(ctx, input, action, code, &result)
 		}
 		return result, nil
 	}
 
-	// Ensure generated code directory exists
-	baseDir := "generated_code"
-	workflowDir := filepath.Join(baseDir, input.WorkflowID)
-	if err := os.MkdirAll(workflowDir, 0755); err != nil {
-		logger.Error("Failed to create directory for generated code", "dir", workflowDir, "error", err)
-		return result, err
-	}
-
 	// Generate code for each action using LLM
 	pageCtx := llm.PageContext{
 		URL:   "about:blank", // Will be updated at runtime
@@ -232,87 +447,109 @@ func (a *Activities) PreGenerateCodeActivity(ctx context.Context, input workflow
 		code, err := llmProvider.GenerateBrowserCode(ctx, action, pageCtx)
 		if err != nil {
 			logger.Warn("LLM generation failed for action, using fallback", "sequence", action.SequenceID, "error", err)
-			code = llm.GenerateCodeFromAction(action, input.Parameters)
-		}
-
-		// Save code to file
-		filename := fmt.Sprintf("action_%d.go", action.SequenceID)
-		filePath := filepath.Join(workflowDir, filename)
-		if err := os.WriteFile(filePath, []byte(code), 0644); err != nil {
-			logger.Error("Failed to write generated code to file", "path", filePath, "error", err)
-			// Continue, but maybe we should fail?
-			// Fallback: put code in map if file write fails? No, keep path convention.
-			// If file fail, we can't save path.
-			// Let's assume write success or error out.
-			continue
+			code = templates.Generate(action, input.Parameters)
 		}
 
-		// Return absolute path so other activities can find it easily
-		absPath, _ := filepath.Abs(filePath)
-		result.ActionCodes[action.SequenceID] = absPath
+		a.storeActionCode(ctx, input, action, code, &result)
 	}
 
-	logger.Info("Pre-generation complete", "generatedCount", len(result.ActionCodes))
+	logger.Info("Pre-generation complete", "inline", len(result.ActionCodes), "stored", len(result.CodeRefs))
 	return result, nil
 }
 
+// storeActionCode persists the code of one action and records a reference to
+// it, so the executing activity can load it on any worker. Without a
+// database, or if saving fails, the code is carried inline instead, as it
+// is when the input asks for it.
+func (a *Activities) storeActionCode(ctx context.Context, input workflows.PreGenerateCodeInput, action models.SemanticAction, code string, result *workflows.PreGeneratedCode) {
+	code = withProvenance(action, code)
+
+	if a.DB != nil && input.RunID != "" && !input.Inline {
+		err := a.DB.SaveActionCode(ctx, input.RunID, input.WorkflowID, action.SequenceID, code)
+		if err == nil {
+			if result.CodeRefs == nil {
+				result.CodeRefs = make(map[int]workflows.CodeRef)
+			}
+			result.CodeRefs[action.SequenceID] = workflows.CodeRef{RunID: input.RunID, SequenceID: action.SequenceID}
+			return
+		}
+		activity.GetLogger(ctx).Warn("Failed to store generated code, passing it inline", "sequence", action.SequenceID, "error", err)
+	}
+
+	result.ActionCodes[action.SequenceID] = code
+}
+
+// withProvenance prefixes generated action code with a comment tracing it
+// back to the recorded event
+func withProvenance(action models.SemanticAction, code string) string {
+	return fmt.Sprintf("// Step %d: %s\n%s", action.SequenceID, codegen.Provenance(action), code)
+}
+
 // ExecuteBrowserActionActivity executes a single browser action
 func (a *Activities) ExecuteBrowserActionActivity(ctx context.Context, actionInput workflows.ActionInput) (models.ActionResult, error) {
-	logger := activity.GetLogger(ctx)
-	logger.Info("Executing browser action", "type", actionInput.Action.ActionType, "sequence", actionInput.Action.SequenceID)
+	logger := log.With(activity.GetLogger(ctx), "runID", actionInput.RunID, "sequence", actionInput.Action.SequenceID)
+	logger.Info("Executing browser action", "type", actionInput.Action.ActionType)
 
+	startTime := time.Now()
 	result := models.ActionResult{
-		Status: models.StatusRunning,
+		Status:     models.StatusRunning,
+		ExecutedAt: &startTime,
+	}
+
+	// Actions with retries configured run again after failing
+	executing := models.PhaseExecuting
+	if attempt := int(activity.GetInfo(ctx).Attempt); attempt > 1 {
+		result.RetryCount = attempt - 1
+		logger.Info("Retrying browser action", "attempt", attempt)
+		executing = models.PhaseRetrying
+		a.reportPhase(ctx, actionInput, executing)
 	}
-	startTime := time.Now()
 
 	// Get session
-	browserPool.mu.RLock()
-	session, ok := browserPool.sessions[actionInput.SessionID]
-	browserPool.mu.RUnlock()
+	session, ok := a.Pool.Get(actionInput.SessionID)
 
 	if !ok {
 		return result, fmt.Errorf("browser session not found: %s", actionInput.SessionID)
 	}
+	defer session.traceCDP(ctx)()
+	defer heartbeat(ctx)()
 
+	// Drive the tab the previous action left open
 	page := session.Page
+	result.TabSwitches = page.SwitchTabs()
 
 	// Get page context for LLM
+	url, title, _ := page.Info()
 	pageCtx := llm.PageContext{
-		URL:   page.MustInfo().URL,
-		Title: page.MustInfo().Title,
+		URL:   url,
+		Title: title,
 	}
 
 	// Use pre-generated code if available, otherwise generate on-the-fly
 	var code string
 	var err error
 
-	if actionInput.GeneratedCode != "" {
-		// Calculate if it is a file path or raw code
-		// Simple check: does it look like a path?
-		if strings.HasPrefix(actionInput.GeneratedCode, "/") || strings.Contains(actionInput.GeneratedCode, string(os.PathSeparator)) {
-			// It's a path, read the file
-			content, err := os.ReadFile(actionInput.GeneratedCode)
-			if err != nil {
-				logger.Error("Failed to read generated code file", "path", actionInput.GeneratedCode, "error", err)
-				return result, fmt.Errorf("failed to read generated code: %w", err)
-			}
-			code = string(content)
-			logger.Info("Loaded generated code from file", "path", actionInput.GeneratedCode, "size", len(code))
-		} else {
-			// Backward compatibility or fallback
-			code = actionInput.GeneratedCode
-			logger.Info("Using pre-generated code (inline)", "sequence", actionInput.Action.SequenceID)
+	if actionInput.CodeRef != nil && a.DB != nil {
+		code, err = a.DB.GetActionCode(ctx, actionInput.CodeRef.RunID, actionInput.CodeRef.SequenceID)
+		if err != nil {
+			logger.Error("Failed to load generated code", "run", actionInput.CodeRef.RunID, "sequence", actionInput.CodeRef.SequenceID, "error", err)
+			return result, fmt.Errorf("failed to load generated code: %w", err)
 		}
+		logger.Info("Loaded generated code from store", "size", len(code))
+	} else if actionInput.GeneratedCode != "" {
+		code = actionInput.GeneratedCode
+		logger.Info("Using pre-generated code (inline)")
 	} else if session.LLMProvider != nil && session.LLMProvider.IsAvailable(ctx) {
 		// Generate code on-the-fly
+		a.reportPhase(ctx, actionInput, models.PhaseGenerating)
 		code, err = session.LLMProvider.GenerateBrowserCode(ctx, actionInput.Action, pageCtx)
 		if err != nil {
 			logger.Warn("LLM code generation failed, using fallback", "error", err)
-			code = llm.GenerateCodeFromAction(actionInput.Action, actionInput.Parameters)
+			code = a.templatesFor(ctx, actionInput.CodeTemplates).Generate(actionInput.Action, actionInput.Parameters)
 		}
+		a.reportPhase(ctx, actionInput, executing)
 	} else {
-		code = llm.GenerateCodeFromAction(actionInput.Action, actionInput.Parameters)
+		code = a.templatesFor(ctx, actionInput.CodeTemplates).Generate(actionInput.Action, actionInput.Parameters)
 	}
 
 	result.GeneratedCode = code
@@ -344,8 +581,37 @@ func (a *Activities) ExecuteBrowserActionActivity(ctx context.Context, actionInp
 		}
 	}
 
-	err = a.executeAction(page, actionInput.Action, actionInput.Parameters)
+	// Wait for the target domain's rate limit, a navigation counting
+	// against the site it goes to
+	target := url
+	if actionInput.Action.ActionType == models.ActionNavigate {
+		target = actionInput.Action.Value
+		for name, value := range actionInput.Parameters {
+			target = strings.ReplaceAll(target, "{{"+name+"}}", value)
+		}
+	}
+	if err := a.throttle(ctx, target); err != nil {
+		result.ErrorMessage = err.Error()
+		return result, err
+	}
+
+	if actionInput.ScreenshotName != "" {
+		result.BeforeScreenshotPath = a.actionScreenshot(ctx, page, actionInput.ScreenshotName+"_before.png")
+	}
+
+	err = a.executeAction(page, actionInput.Action, actionInput.Parameters, &result)
+	if actionInput.ScreenshotName != "" {
+		result.AfterScreenshotPath = a.actionScreenshot(ctx, page, actionInput.ScreenshotName+"_after.png")
+		if path, err := a.saveDOMSnapshot(ctx, page, actionInput.ScreenshotName+"_after.html"); err != nil {
+			logger.Warn("Failed to capture DOM snapshot", "error", err)
+		} else {
+			result.DOMSnapshotPath = path
+		}
+	}
 	if err != nil {
+		result.Dialogs = page.Dialogs()
+		result.Downloads = a.storeDownloads(ctx, actionInput.RunID, page.Downloads())
+		result.TabSwitches = append(result.TabSwitches, page.SwitchTabs()...)
 		result.ErrorMessage = err.Error()
 		result.Duration = time.Since(startTime).Milliseconds()
 		return result, err
@@ -353,6 +619,22 @@ func (a *Activities) ExecuteBrowserActionActivity(ctx context.Context, actionInp
 
 	result.Status = models.StatusSuccess
 	result.Duration = time.Since(startTime).Milliseconds()
+	if actionInput.Action.ActionType == models.ActionNavigate {
+		if result.Metrics, err = page.Metrics(); err != nil {
+			logger.Warn("Failed to measure page load", "error", err)
+		}
+	}
+	if actionInput.VerifyOutcome && len(actionInput.Action.Context) > 0 {
+		if result.MissingOutcomes, err = missingOutcomes(page, actionInput.Action.Context); err != nil {
+			logger.Warn("Failed to verify action outcome", "error", err)
+		}
+	}
+	if actionInput.AccessibilityReport != "" {
+		result.Accessibility = a.auditAccessibility(ctx, page, actionInput.RunID, actionInput.AccessibilityReport)
+	}
+	result.Dialogs = page.Dialogs()
+	result.Downloads = a.storeDownloads(ctx, actionInput.RunID, page.Downloads())
+	result.TabSwitches = append(result.TabSwitches, page.SwitchTabs()...)
 
 	// Heartbeat for long-running activities
 	activity.RecordHeartbeat(ctx, fmt.Sprintf("Completed action %d", actionInput.Action.SequenceID))
@@ -360,8 +642,18 @@ func (a *Activities) ExecuteBrowserActionActivity(ctx context.Context, actionInp
 	return result, nil
 }
 
-// executeAction executes a browser action using Go Rod
-func (a *Activities) executeAction(page *rod.Page, action models.SemanticAction, params map[string]string) error {
+// executeAction executes a browser action on the session's page and waits
+// for the action's conditions, recording the selector that found its element
+// and the text a copy copied in result
+func (a *Activities) executeAction(page BrowserPage, action models.SemanticAction, params map[string]string, result *models.ActionResult) error {
+	return page.Wait(func() error {
+		return a.performAction(page, action, params, result)
+	}, action.Waits...)
+}
+
+// performAction performs a browser action without waiting for anything but
+// its element
+func (a *Activities) performAction(page BrowserPage, action models.SemanticAction, params map[string]string, result *models.ActionResult) error {
 	// Substitute parameters in values
 	value := action.Value
 	for paramName, paramValue := range params {
@@ -382,68 +674,66 @@ func (a *Activities) executeAction(page *rod.Page, action models.SemanticAction,
 		return page.Navigate(url)
 
 	case models.ActionClick:
-		selector := a.getBestSelector(action)
-		var elem *rod.Element
-		var err error
-
-		if selector == "" && action.Target.Text != "" {
-			elem, err = page.ElementR(action.Target.Tag, action.Target.Text)
-		} else {
-			elem, err = page.Element(selector)
-		}
-
+		selector, err := a.locate(page, action, result)
 		if err != nil {
-			return fmt.Errorf("element not found: %s (text: %s)", selector, action.Target.Text)
+			return err
 		}
-		return elem.Click(proto.InputMouseButtonLeft, 1)
+		return page.Click(selector, action.Target.Tag, action.Target.Text)
 
 	case models.ActionInput:
-		selector := a.getBestSelector(action)
-		var elem *rod.Element
-		var err error
-
-		if selector == "" && action.Target.Text != "" {
-			elem, err = page.ElementR(action.Target.Tag, action.Target.Text)
-		} else {
-			elem, err = page.Element(selector)
-		}
-
+		selector, err := a.locate(page, action, result)
 		if err != nil {
-			return fmt.Errorf("element not found: %s (text: %s)", selector, action.Target.Text)
+			return err
 		}
-		// Clear existing text and input new value
-		elem.MustSelectAllText()
-		return elem.Input(value)
+		return page.Fill(selector, action.Target.Tag, action.Target.Text, value)
 
 	case models.ActionFocus:
-		selector := a.getBestSelector(action)
-		elem, err := page.Element(selector)
+		selector, err := a.locate(page, action, result)
 		if err != nil {
-			return fmt.Errorf("element not found: %s", selector)
+			return err
 		}
-		return elem.Focus()
+		return page.Focus(selector, action.Target.Tag, action.Target.Text)
 
 	case models.ActionBlur:
-		selector := a.getBestSelector(action)
-		elem, err := page.Element(selector)
+		selector, err := a.locate(page, action, result)
 		if err != nil {
-			return fmt.Errorf("element not found: %s", selector)
+			return err
 		}
-		return elem.Blur()
+		return page.Blur(selector, action.Target.Tag, action.Target.Text)
 
 	case models.ActionKeypress:
-		key := getKeyFromValue(value)
-		return page.Keyboard.Press(key)
+		return page.Press(value)
 
 	case models.ActionCopy:
-		// Press Ctrl+C for copy (use Type with modifier)
-		page.KeyActions().Press(input.ControlLeft).Type(input.KeyC).MustDo()
-		return nil
+		var err error
+		result.Output, err = page.Copy()
+		return err
+
+	case models.ActionExtract:
+		if action.Extract == nil {
+			return fmt.Errorf("extract action %d has no extract output", action.SequenceID)
+		}
+		selector, err := a.locate(page, action, result)
+		if err != nil {
+			return err
+		}
+		value, err := page.Read(selector, action.Target.Tag, action.Target.Text, action.Extract.Attribute)
+		result.Output = strings.TrimSpace(value)
+		return err
+
+	case models.ActionAssert:
+		return a.assert(page, action, params, result)
+
+	case models.ActionCut:
+		return page.Shortcut("x")
 
 	case models.ActionPaste:
-		// Press Ctrl+V for paste
-		page.KeyActions().Press(input.ControlLeft).Type(input.KeyV).MustDo()
-		return nil
+		// Paste the parameter or copied value the workflow set, falling
+		// back to whatever the browser's clipboard holds
+		if value != "" {
+			return page.InsertText(value)
+		}
+		return page.Shortcut("v")
 
 	case models.ActionScroll:
 		// Scroll is usually not critical, just log it
@@ -454,61 +744,124 @@ func (a *Activities) executeAction(page *rod.Page, action models.SemanticAction,
 	}
 }
 
-// getBestSelector returns the best selector for an action
-func (a *Activities) getBestSelector(action models.SemanticAction) string {
-	attrs := action.Target.Attributes
-	tag := strings.ToLower(action.Target.Tag)
+// selectorCandidates returns the chain of selectors locating an action's
+// element, most stable first: its healed selector, its aria-label, name,
+// placeholder and test ID, the ranked selectors of the recording, then its
+// text and XPath
+func (a *Activities) selectorCandidates(action models.SemanticAction) []string {
+	candidates := action.CSSSelectors()
+	add := func(candidate string) {
+		if !slices.Contains(candidates, candidate) {
+			candidates = append(candidates, candidate)
+		}
+	}
+	// Matching text needs a tag, or the whole document would match
+	if action.Target.Text != "" && action.Target.Tag != "" {
+		add(textLocator + action.Target.Text)
+	}
+	if action.Target.XPath != "" {
+		add(xpathLocator + action.Target.XPath)
+	}
+	return candidates
+}
 
-	// Priority 1: aria-label
-	if ariaLabel, ok := attrs["aria-label"].(string); ok && ariaLabel != "" {
-		return fmt.Sprintf("%s[aria-label='%s']", tag, ariaLabel)
+// locate finds an action's element through its selector chain, recording
+// the selector that matched in result
+func (a *Activities) locate(page BrowserPage, action models.SemanticAction, result *models.ActionResult) (string, error) {
+	candidates := a.selectorCandidates(action)
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no selector for %s target", action.Target.Tag)
+	}
+	i, err := page.Locate(candidates, action.Target.Tag)
+	if err != nil {
+		return "", err
 	}
+	result.Selector = candidates[i]
+	result.SelectorFallbacks = i
+	result.SelectorSource = selectorSource(action, candidates, i)
+	return candidates[i], nil
+}
 
-	// Priority 2: name
-	if name, ok := attrs["name"].(string); ok && name != "" {
-		return fmt.Sprintf("%s[name='%s']", tag, name)
+// selectorSource tells which kind of selector candidates[i] is. A healed
+// selector is tried before the recorded ones, the first of which is the
+// primary one.
+func selectorSource(action models.SemanticAction, candidates []string, i int) models.SelectorSource {
+	primary := 0
+	if healed := action.HealedSelector(); healed != "" && candidates[0] == healed {
+		if i == 0 {
+			return models.SelectorHealed
+		}
+		primary = 1
+	}
+	if i == primary {
+		return models.SelectorPrimary
 	}
+	return models.SelectorFallback
+}
+
+// HealSelectorsActivity writes the selectors a run found elements with
+// after their recorded selectors failed to the workflow's actions, recording
+// a new version, so later runs try them first. The actions are matched by
+// ID, so none are healed once the workflow was edited since the run
+// started, as its actions may since be others.
+func (a *Activities) HealSelectorsActivity(ctx context.Context, input workflows.HealSelectorsInput) error {
+	logger := activity.GetLogger(ctx)
 
-	// Priority 3: placeholder
-	if placeholder, ok := attrs["placeholder"].(string); ok && placeholder != "" {
-		return fmt.Sprintf("%s[placeholder='%s']", tag, placeholder)
+	if a.DB == nil {
+		return fmt.Errorf("healing selectors needs MYSQL_DSN on the worker")
 	}
 
-	// Priority 4: data-testid
-	if testID, ok := attrs["data-testid"].(string); ok && testID != "" {
-		return fmt.Sprintf("[data-testid='%s']", testID)
+	workflow, err := a.DB.GetWorkflowDefinition(ctx, input.WorkflowID)
+	if err != nil || workflow == nil {
+		return fmt.Errorf("workflow not found: %s", input.WorkflowID)
+	}
+	actions, err := a.DB.GetSemanticActions(ctx, input.WorkflowID)
+	if err != nil {
+		return err
 	}
 
-	// Fallback to provided selector
-	return action.Target.Selector
-}
+	var params []models.WorkflowParameter
+	if workflow.ParametersJSON != "" {
+		if err := json.Unmarshal([]byte(workflow.ParametersJSON), &params); err != nil {
+			return fmt.Errorf("failed to read parameters: %w", err)
+		}
+	}
 
-// getKeyFromValue converts a key name to rod input key
-func getKeyFromValue(value string) input.Key {
-	switch strings.ToLower(value) {
-	case "enter":
-		return input.Enter
-	case "tab":
-		return input.Tab
-	case "escape":
-		return input.Escape
-	case "backspace":
-		return input.Backspace
-	case "arrowup":
-		return input.ArrowUp
-	case "arrowdown":
-		return input.ArrowDown
-	case "arrowleft":
-		return input.ArrowLeft
-	case "arrowright":
-		return input.ArrowRight
-	default:
-		// For single characters, return as-is
-		if len(value) == 1 {
-			return input.Key(value[0])
+	healed := 0
+	for i, action := range actions {
+		selector, ok := input.Selectors[action.ID]
+		if !ok || selector == action.HealedSelector() {
+			continue
 		}
-		return input.Enter // Default fallback
+		metadata := make(map[string]interface{}, len(action.Metadata)+1)
+		for key, value := range action.Metadata {
+			metadata[key] = value
+		}
+		metadata[models.MetaHealedSelector] = selector
+		actions[i].Metadata = metadata
+		healed++
+		logger.Info("Healing selector", "sequence", action.SequenceID, "selector", selector)
+	}
+	if healed == 0 {
+		logger.Info("No selectors to heal", "workflowID", input.WorkflowID, "found", len(input.Selectors))
+		return nil
+	}
+
+	// Stored actions are immutable per version, so healed actions get new IDs
+	for i := range actions {
+		actions[i].ID = uuid.New().String()
+	}
+	if err := a.DB.ReplaceSemanticActions(ctx, input.WorkflowID, workflow.Revision, actions, workflow.ParametersJSON); err != nil {
+		return err
 	}
+
+	return a.DB.CreateWorkflowVersion(ctx, &models.WorkflowVersion{
+		ID:         uuid.New().String(),
+		WorkflowID: input.WorkflowID,
+		ChangeType: models.VersionSelectorsHealed,
+		Actions:    actions,
+		Parameters: params,
+	})
 }
 
 // TakeScreenshotActivity takes a screenshot
@@ -516,36 +869,110 @@ func (a *Activities) TakeScreenshotActivity(ctx context.Context, screenshotInput
 	logger := activity.GetLogger(ctx)
 	logger.Info("Taking screenshot", "sessionID", screenshotInput.SessionID)
 
-	browserPool.mu.RLock()
-	session, ok := browserPool.sessions[screenshotInput.SessionID]
-	browserPool.mu.RUnlock()
+	session, ok := a.Pool.Get(screenshotInput.SessionID)
 
 	if !ok {
 		return "", fmt.Errorf("browser session not found")
 	}
 
-	// Ensure screenshot directory exists
-	if err := os.MkdirAll(a.ScreenshotDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create screenshot dir: %w", err)
-	}
+	return a.saveScreenshot(ctx, session.Page, screenshotInput.Filename)
+}
 
-	// Take screenshot
-	screenshotPath := filepath.Join(a.ScreenshotDir, screenshotInput.Filename)
-	data, err := session.Page.Screenshot(true, &proto.PageCaptureScreenshot{
-		Format: proto.PageCaptureScreenshotFormatPng,
-	})
+// saveScreenshot captures the page into the artifact store, returning
+// where it was kept
+func (a *Activities) saveScreenshot(ctx context.Context, page BrowserPage, filename string) (string, error) {
+	data, err := page.Screenshot()
 	if err != nil {
 		return "", fmt.Errorf("failed to take screenshot: %w", err)
 	}
 
-	// Save to file
-	if err := os.WriteFile(screenshotPath, data, 0644); err != nil {
+	screenshotPath, err := a.artifactStore().Put(ctx, artifacts.ScreenshotKey(filename), "image/png", bytes.NewReader(data))
+	if err != nil {
 		return "", fmt.Errorf("failed to save screenshot: %w", err)
 	}
 
 	return screenshotPath, nil
 }
 
+// actionScreenshot captures the page around an action. A screenshot that
+// fails is logged and left out rather than failing the action.
+func (a *Activities) actionScreenshot(ctx context.Context, page BrowserPage, filename string) string {
+	path, err := a.saveScreenshot(ctx, page, filename)
+	if err != nil {
+		activity.GetLogger(ctx).Warn("Failed to capture action screenshot", "file", filename, "error", err)
+	}
+	return path
+}
+
+// SaveStorageStateActivity encrypts the session's cookies and localStorage
+// and stores them under a name later runs can start from
+func (a *Activities) SaveStorageStateActivity(ctx context.Context, input workflows.SaveSessionInput) error {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Saving browser session", "sessionID", input.SessionID, "name", input.Name)
+
+	if a.DB == nil || a.Sessions == nil {
+		return fmt.Errorf("saving sessions needs MYSQL_DSN and SESSION_ENCRYPTION_KEY on the worker")
+	}
+
+	session, ok := a.Pool.Get(input.SessionID)
+	if !ok {
+		return fmt.Errorf("browser session not found")
+	}
+
+	state, err := session.Page.StorageState()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	sealed, err := a.Sessions.Seal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt storage state: %w", err)
+	}
+
+	return a.DB.SaveBrowserSession(ctx, &models.SavedSession{
+		Name:       input.Name,
+		WorkflowID: input.WorkflowID,
+		RunID:      input.RunID,
+	}, sealed)
+}
+
+// openSession decrypts a saved session's cookies and localStorage, which
+// only the worker opening the browser sees
+func (a *Activities) openSession(ctx context.Context, name string) (*models.StorageState, error) {
+	if a.DB == nil || a.Sessions == nil {
+		return nil, fmt.Errorf("saved sessions need MYSQL_DSN and SESSION_ENCRYPTION_KEY on the worker")
+	}
+
+	sealed, err := a.DB.GetBrowserSessionState(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if sealed == "" {
+		return nil, fmt.Errorf("browser session %q not found", name)
+	}
+	data, err := a.Sessions.Open(sealed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt storage state: %w", err)
+	}
+	var state models.StorageState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse storage state: %w", err)
+	}
+	return &state, nil
+}
+
+// DeleteStorageStateActivity removes a saved session, such as the ones a
+// pipeline hands from one step to the next once the pipeline is done
+func (a *Activities) DeleteStorageStateActivity(ctx context.Context, name string) error {
+	if a.DB == nil {
+		return nil
+	}
+	return a.DB.DeleteBrowserSession(ctx, name)
+}
+
 func getProviderNames(configs map[string]llm.Config) []string {
 	names := make([]string, 0, len(configs))
 	for name := range configs {
@@ -0,0 +1,369 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+
+	"dev/bravebird/browser-automation-go/pkg/llm"
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// ErrPoolFull is returned when no browser slot frees up within the queue timeout
+var ErrPoolFull = errors.New("browser pool is full")
+
+// PoolConfig limits the browsers a worker keeps open
+type PoolConfig struct {
+	MaxSessions  int           // concurrent browsers, unlimited when 0
+	QueueTimeout time.Duration // how long a new session waits for a slot, rejected at once when 0
+	TTL          time.Duration // maximum session lifetime, unlimited when 0
+	IdleTimeout  time.Duration // maximum time between uses, unlimited when 0
+	ReapInterval time.Duration // how often expired sessions and orphaned browsers are closed
+	ProfileDir   string        // where launched Chromes keep their profiles, in the temp directory when empty
+}
+
+// DefaultPoolConfig returns the pool limits used when the worker sets none
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		MaxSessions:  5,
+		QueueTimeout: 5 * time.Minute,
+		TTL:          2 * time.Hour,
+		IdleTimeout:  15 * time.Minute,
+		ReapInterval: time.Minute,
+	}
+}
+
+// BrowserPool manages browser sessions
+type BrowserPool struct {
+	config   PoolConfig
+	sessions map[string]*BrowserSessionData
+	mu       sync.RWMutex
+
+	// Browser slots, taken by open and launching browsers up to
+	// MaxSessions. Freed slots go to the waiters in order, the most urgent
+	// first.
+	slotsMu sync.Mutex
+	used    int
+	waiters []*slotWaiter
+
+	// Warm browsers shared by runs that opt in, keyed by headless mode. Each
+	// run gets its own incognito context, which is what its session closes.
+	warm   map[bool]*warmBrowser
+	warmMu sync.Mutex
+
+	// Chromes the pool launched by profile directory, nil while launching
+	chromes   map[string]*launchedBrowser
+	chromesMu sync.Mutex
+}
+
+// slotWaiter is a Reserve waiting for a browser slot, handed one by
+// closing ready
+type slotWaiter struct {
+	rank  int
+	ready chan struct{}
+}
+
+// warmBrowser is a browser kept running between runs
+type warmBrowser struct {
+	browser    *launchedBrowser
+	lastUsedAt time.Time
+}
+
+// BrowserSessionData holds data for a browser session
+type BrowserSessionData struct {
+	Page        BrowserPage
+	LLMProvider llm.Provider
+	RunID       string // run that opened the session
+	CreatedAt   time.Time
+	LastUsedAt  time.Time
+
+	// disconnect drops the connection to a remote browser. Remote sessions
+	// hold no local slot and only close their own browser context.
+	disconnect func()
+	// chrome is the browser launched for the session alone, stopped with
+	// it and whose CDP calls are traced, nil for warm and remote browsers
+	chrome *launchedBrowser
+}
+
+// traceCDP records the session's CDP calls as spans of ctx until the
+// returned function is called
+func (s *BrowserSessionData) traceCDP(ctx context.Context) func() {
+	if s.chrome == nil {
+		return func() {}
+	}
+	return s.chrome.cdp.trace(ctx)
+}
+
+// NewBrowserPool creates a pool with the given limits
+func NewBrowserPool(config PoolConfig) *BrowserPool {
+	p := &BrowserPool{
+		config:   config,
+		sessions: make(map[string]*BrowserSessionData),
+		warm:     make(map[bool]*warmBrowser),
+		chromes:  make(map[string]*launchedBrowser),
+	}
+	return p
+}
+
+// Reserve takes a slot for a new browser of a normal priority run, as
+// ReserveFor does
+func (p *BrowserPool) Reserve(ctx context.Context) error {
+	return p.ReserveFor(ctx, models.PriorityNormal)
+}
+
+// ReserveFor takes a slot for a new browser, waiting up to the queue timeout
+// for one to free up. Freed slots go to the runs of the highest priority
+// waiting, and among them to the one waiting longest. Each successful
+// ReserveFor must be followed by Add or Release.
+func (p *BrowserPool) ReserveFor(ctx context.Context, priority models.RunPriority) error {
+	if p.config.MaxSessions <= 0 {
+		return nil
+	}
+
+	p.slotsMu.Lock()
+	if p.used < p.config.MaxSessions {
+		p.used++
+		p.slotsMu.Unlock()
+		return nil
+	}
+	if p.config.QueueTimeout <= 0 {
+		p.slotsMu.Unlock()
+		return ErrPoolFull
+	}
+	w := &slotWaiter{rank: priority.Rank(), ready: make(chan struct{})}
+	i := len(p.waiters)
+	for i > 0 && p.waiters[i-1].rank > w.rank {
+		i--
+	}
+	p.waiters = slices.Insert(p.waiters, i, w)
+	p.slotsMu.Unlock()
+
+	timer := time.NewTimer(p.config.QueueTimeout)
+	defer timer.Stop()
+	var err error
+	select {
+	case <-w.ready:
+		return nil
+	case <-timer.C:
+		err = ErrPoolFull
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	p.slotsMu.Lock()
+	defer p.slotsMu.Unlock()
+	select {
+	case <-w.ready:
+		// Handed a slot while giving up, which goes to the next waiter
+		p.releaseLocked()
+	default:
+		p.waiters = slices.DeleteFunc(p.waiters, func(other *slotWaiter) bool { return other == w })
+	}
+	return err
+}
+
+// Release returns a reserved slot whose browser was never added
+func (p *BrowserPool) Release() {
+	if p.config.MaxSessions <= 0 {
+		return
+	}
+	p.slotsMu.Lock()
+	defer p.slotsMu.Unlock()
+	p.releaseLocked()
+}
+
+// releaseLocked hands a freed slot to the first waiter, if any
+func (p *BrowserPool) releaseLocked() {
+	if len(p.waiters) == 0 {
+		p.used--
+		return
+	}
+	w := p.waiters[0]
+	p.waiters = p.waiters[1:]
+	close(w.ready)
+}
+
+// Add stores a session in a reserved slot
+func (p *BrowserPool) Add(id string, session *BrowserSessionData) {
+	now := time.Now()
+	session.CreatedAt = now
+	session.LastUsedAt = now
+
+	p.mu.Lock()
+	p.sessions[id] = session
+	p.mu.Unlock()
+}
+
+// Get returns a session and marks it used
+func (p *BrowserPool) Get(id string) (*BrowserSessionData, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	session, ok := p.sessions[id]
+	if ok {
+		session.LastUsedAt = time.Now()
+	}
+	return session, ok
+}
+
+// Close closes a session's browser and frees its slot. Closing an unknown
+// session is a no-op.
+func (p *BrowserPool) Close(id string) {
+	p.mu.Lock()
+	session, ok := p.sessions[id]
+	delete(p.sessions, id)
+	p.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	if session.Page != nil {
+		session.Page.Close()
+	}
+	if session.chrome != nil {
+		session.chrome.stop()
+	}
+	if session.disconnect != nil {
+		session.disconnect()
+		return
+	}
+	p.Release()
+}
+
+// WarmBrowser returns the running browser for the headless mode, launching
+// one with launch if there is none or it stopped responding
+func (p *BrowserPool) WarmBrowser(headless bool, launch func() (*launchedBrowser, error)) (*rod.Browser, error) {
+	p.warmMu.Lock()
+	defer p.warmMu.Unlock()
+
+	if w, ok := p.warm[headless]; ok {
+		if _, err := w.browser.Version(); err == nil {
+			w.lastUsedAt = time.Now()
+			return w.browser.Browser, nil
+		}
+		w.browser.stop()
+		delete(p.warm, headless)
+	}
+
+	browser, err := launch()
+	if err != nil {
+		return nil, err
+	}
+	p.warm[headless] = &warmBrowser{browser: browser, lastUsedAt: time.Now()}
+	return browser.Browser, nil
+}
+
+// reapWarm closes warm browsers no run has checked out for the idle timeout
+func (p *BrowserPool) reapWarm(now time.Time, all bool) {
+	p.warmMu.Lock()
+	defer p.warmMu.Unlock()
+
+	for headless, w := range p.warm {
+		if all || (p.config.IdleTimeout > 0 && now.Sub(w.lastUsedAt) > p.config.IdleTimeout && p.Len() == 0) {
+			w.browser.stop()
+			delete(p.warm, headless)
+		}
+	}
+}
+
+// Sessions returns copies of the open sessions by ID, without marking
+// them used
+func (p *BrowserPool) Sessions() map[string]BrowserSessionData {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	sessions := make(map[string]BrowserSessionData, len(p.sessions))
+	for id, session := range p.sessions {
+		sessions[id] = *session
+	}
+	return sessions
+}
+
+// Len returns the number of open sessions
+func (p *BrowserPool) Len() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.sessions)
+}
+
+// Reap closes sessions past their TTL or idle timeout, for browsers whose
+// CloseBrowserActivity never ran. It returns the IDs closed.
+func (p *BrowserPool) Reap(now time.Time) []string {
+	var expired []string
+
+	p.mu.RLock()
+	for id, session := range p.sessions {
+		if p.config.TTL > 0 && now.Sub(session.CreatedAt) > p.config.TTL {
+			expired = append(expired, id)
+		} else if p.config.IdleTimeout > 0 && now.Sub(session.LastUsedAt) > p.config.IdleTimeout {
+			expired = append(expired, id)
+		}
+	}
+	p.mu.RUnlock()
+
+	for _, id := range expired {
+		p.Close(id)
+	}
+	p.reapWarm(now, false)
+	return expired
+}
+
+// RunReaper reaps expired sessions and orphaned Chromes every ReapInterval
+// until ctx is done, then closes the remaining sessions and warm browsers.
+// onReap is called with the sessions and profiles of the Chromes reaped.
+func (p *BrowserPool) RunReaper(ctx context.Context, onReap func(sessions, profiles []string)) {
+	interval := p.config.ReapInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Chromes left behind by an earlier run of the worker go at once
+	if profiles := p.ReapOrphans(time.Now()); len(profiles) > 0 && onReap != nil {
+		onReap(nil, profiles)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			p.closeAll()
+			return
+		case now := <-ticker.C:
+			ids := p.Reap(now)
+			profiles := p.ReapOrphans(now)
+			if (len(ids) > 0 || len(profiles) > 0) && onReap != nil {
+				onReap(ids, profiles)
+			}
+		}
+	}
+}
+
+// closeAll closes every browser at once, so stopping Chromes that are slow
+// to exit takes no longer than the slowest of them
+func (p *BrowserPool) closeAll() {
+	p.mu.RLock()
+	ids := make([]string, 0, len(p.sessions))
+	for id := range p.sessions {
+		ids = append(ids, id)
+	}
+	p.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Close(id)
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.reapWarm(time.Now(), true)
+	}()
+	wg.Wait()
+}
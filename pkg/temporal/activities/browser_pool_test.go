@@ -0,0 +1,164 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+func TestBrowserPoolLimit(t *testing.T) {
+	pool := NewBrowserPool(PoolConfig{MaxSessions: 1})
+	ctx := context.Background()
+
+	if err := pool.Reserve(ctx); err != nil {
+		t.Fatalf("first Reserve() error = %v", err)
+	}
+	pool.Add("a", &BrowserSessionData{})
+
+	if err := pool.Reserve(ctx); !errors.Is(err, ErrPoolFull) {
+		t.Fatalf("Reserve() on full pool error = %v, want ErrPoolFull", err)
+	}
+
+	pool.Close("a")
+	if err := pool.Reserve(ctx); err != nil {
+		t.Fatalf("Reserve() after Close error = %v", err)
+	}
+}
+
+func TestBrowserPoolQueue(t *testing.T) {
+	pool := NewBrowserPool(PoolConfig{MaxSessions: 1, QueueTimeout: time.Second})
+	ctx := context.Background()
+
+	pool.Reserve(ctx)
+	pool.Add("a", &BrowserSessionData{})
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		pool.Close("a")
+	}()
+	if err := pool.Reserve(ctx); err != nil {
+		t.Fatalf("queued Reserve() error = %v", err)
+	}
+}
+
+func TestBrowserPoolQueuePriority(t *testing.T) {
+	pool := NewBrowserPool(PoolConfig{MaxSessions: 1, QueueTimeout: time.Second})
+	pool.Reserve(context.Background())
+
+	// A low and a normal priority run queue before an urgent one, which
+	// still gets the freed slot first
+	order := make(chan models.RunPriority, 3)
+	for _, priority := range []models.RunPriority{models.PriorityLow, models.PriorityNormal, models.PriorityHigh} {
+		go func() {
+			if err := pool.ReserveFor(context.Background(), priority); err == nil {
+				order <- priority
+				pool.Release()
+			}
+		}()
+		time.Sleep(20 * time.Millisecond)
+	}
+	pool.Release()
+
+	for _, want := range []models.RunPriority{models.PriorityHigh, models.PriorityNormal, models.PriorityLow} {
+		if got := <-order; got != want {
+			t.Fatalf("slot went to a %s priority run, want %s", got, want)
+		}
+	}
+}
+
+func TestBrowserPoolQueueCanceled(t *testing.T) {
+	pool := NewBrowserPool(PoolConfig{MaxSessions: 1, QueueTimeout: time.Second})
+	pool.Reserve(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := pool.ReserveFor(ctx, models.PriorityHigh); !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled ReserveFor() error = %v, want context.Canceled", err)
+	}
+
+	// The canceled run left the queue, so the freed slot is free again
+	pool.Release()
+	if err := pool.ReserveFor(context.Background(), models.PriorityLow); err != nil {
+		t.Errorf("ReserveFor() after Release error = %v", err)
+	}
+}
+
+func TestBrowserPoolReap(t *testing.T) {
+	pool := NewBrowserPool(PoolConfig{MaxSessions: 3, TTL: time.Hour, IdleTimeout: 10 * time.Minute})
+	for _, id := range []string{"fresh", "idle", "old"} {
+		pool.Reserve(context.Background())
+		pool.Add(id, &BrowserSessionData{})
+	}
+
+	now := time.Now()
+	pool.sessions["idle"].LastUsedAt = now.Add(-11 * time.Minute)
+	pool.sessions["old"].CreatedAt = now.Add(-2 * time.Hour)
+
+	reaped := pool.Reap(now)
+	if len(reaped) != 2 {
+		t.Fatalf("Reap() closed %v, want idle and old", reaped)
+	}
+	if _, ok := pool.Get("fresh"); !ok || pool.Len() != 1 {
+		t.Errorf("Reap() should keep the fresh session, %d open", pool.Len())
+	}
+	if err := pool.Reserve(context.Background()); err != nil {
+		t.Errorf("Reap() should free slots, Reserve() error = %v", err)
+	}
+}
+
+func TestRemoteEndpointsRoundRobin(t *testing.T) {
+	r := NewRemoteEndpoints(" ws://a:9222 ,, http://b:9222")
+	if r.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", r.Len())
+	}
+
+	first, second := r.order(), r.order()
+	if first[0] != "ws://a:9222" || second[0] != "http://b:9222" || second[1] != "ws://a:9222" {
+		t.Errorf("order() = %v then %v, want each endpoint in turn followed by the rest", first, second)
+	}
+	if (*RemoteEndpoints)(nil).Len() != 0 {
+		t.Error("nil fleet should be empty")
+	}
+}
+
+// slowPage is a page whose browser takes a while to close
+type slowPage struct {
+	BrowserPage
+	closed *atomic.Int32
+}
+
+func (p slowPage) Close() error {
+	time.Sleep(100 * time.Millisecond)
+	p.closed.Add(1)
+	return nil
+}
+
+func TestBrowserPoolReaperClosesAllOnShutdown(t *testing.T) {
+	pool := NewBrowserPool(PoolConfig{MaxSessions: 3})
+	var closed atomic.Int32
+	for _, id := range []string{"a", "b", "c"} {
+		pool.Reserve(context.Background())
+		pool.Add(id, &BrowserSessionData{Page: slowPage{closed: &closed}})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pool.RunReaper(ctx, nil)
+	}()
+	start := time.Now()
+	cancel()
+	<-done
+
+	if closed.Load() != 3 || pool.Len() != 0 {
+		t.Errorf("closed %d of 3 sessions, %d still open", closed.Load(), pool.Len())
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("closing took %s, want the sessions closed at once", elapsed)
+	}
+}
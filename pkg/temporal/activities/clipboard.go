@@ -0,0 +1,68 @@
+package activities
+
+import (
+	"net/url"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// selectionScript returns the text selected in the page, including inside
+// the focused input, which window.getSelection does not report
+const selectionScript = `() => {
+	const el = document.activeElement;
+	if (el && (el.tagName === 'INPUT' || el.tagName === 'TEXTAREA') && typeof el.selectionStart === 'number') {
+		return el.value.substring(el.selectionStart, el.selectionEnd);
+	}
+	return String(window.getSelection() || '');
+}`
+
+// Copy presses Ctrl+C and returns what it copied. Pages can only read the
+// clipboard once granted access, so the selection stands in when it can't.
+func (p *rodPage) Copy() (string, error) {
+	selection := ""
+	if res, err := p.page.Eval(selectionScript); err == nil {
+		selection = res.Value.Str()
+	}
+	if err := p.Shortcut("c"); err != nil {
+		return "", err
+	}
+	if text, err := p.readClipboard(); err == nil && text != "" {
+		return text, nil
+	}
+	return selection, nil
+}
+
+// readClipboard grants the page's origin clipboard access and reads it
+func (p *rodPage) readClipboard() (string, error) {
+	info, err := p.page.Info()
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(info.URL)
+	if err != nil {
+		return "", err
+	}
+	err = proto.BrowserGrantPermissions{
+		Permissions: []proto.BrowserPermissionType{
+			proto.BrowserPermissionTypeClipboardReadWrite,
+			proto.BrowserPermissionTypeClipboardSanitizedWrite,
+		},
+		Origin:           u.Scheme + "://" + u.Host,
+		BrowserContextID: p.browser.BrowserContextID,
+	}.Call(p.browser)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := p.page.Evaluate(rod.Eval(`() => navigator.clipboard.readText()`).ByPromise().ByUser())
+	if err != nil {
+		return "", err
+	}
+	return res.Value.Str(), nil
+}
+
+// InsertText types text at the cursor as a paste would
+func (p *rodPage) InsertText(text string) error {
+	return p.page.InsertText(text)
+}
@@ -0,0 +1,89 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.temporal.io/sdk/activity"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+	"dev/bravebird/browser-automation-go/pkg/temporal/workflows"
+)
+
+// maxSummaryElements caps the elements a page summary lists
+const maxSummaryElements = 50
+
+// pageSummaryScript returns the visible elements of the page a user can
+// interact with as JSON page elements, each with the simplest selector
+// finding it. Field values are left out, as they may be secrets.
+const pageSummaryScript = `(max) => {
+	const attr = (v) => v.replace(/\\/g, '\\\\').replace(/'/g, "\\'");
+	const out = [];
+	for (const el of document.querySelectorAll('a[href], button, input, select, textarea, [role=button], [role=link], [contenteditable=true]')) {
+		if (out.length >= max) break;
+		const r = el.getBoundingClientRect();
+		if (r.width === 0 || r.height === 0 || (el.type === 'hidden')) continue;
+		const tag = el.tagName.toLowerCase();
+		let selector = tag;
+		if (el.id) selector = '#' + CSS.escape(el.id);
+		else if (el.getAttribute('data-testid')) selector = "[data-testid='" + attr(el.getAttribute('data-testid')) + "']";
+		else if (el.getAttribute('name')) selector = tag + "[name='" + attr(el.getAttribute('name')) + "']";
+		else if (el.getAttribute('aria-label')) selector = tag + "[aria-label='" + attr(el.getAttribute('aria-label')) + "']";
+		const text = (el.innerText || el.getAttribute('placeholder') || el.getAttribute('title') || '').trim().replace(/\s+/g, ' ').slice(0, 80);
+		out.push({tag, text, selector});
+	}
+	return JSON.stringify(out);
+}`
+
+// parsePageElements decodes the result of pageSummaryScript
+func parsePageElements(result string) ([]models.PageElement, error) {
+	elements := []models.PageElement{}
+	if err := json.Unmarshal([]byte(result), &elements); err != nil {
+		return nil, fmt.Errorf("failed to parse page summary: %w", err)
+	}
+	return elements, nil
+}
+
+func (p *rodPage) Summary() (*models.PageSummary, error) {
+	url, title, err := p.Info()
+	if err != nil {
+		return nil, err
+	}
+	res, err := p.page.Eval(pageSummaryScript, maxSummaryElements)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize page: %w", err)
+	}
+	elements, err := parsePageElements(res.Value.Str())
+	if err != nil {
+		return nil, err
+	}
+	return &models.PageSummary{URL: url, Title: title, Elements: elements}, nil
+}
+
+// DebugSnapshotActivity captures the page a debug run is holding on: a
+// screenshot saved as the given file and a summary of the page. What it
+// couldn't capture is reported in the snapshot rather than failing the run.
+func (a *Activities) DebugSnapshotActivity(ctx context.Context, input workflows.ScreenshotInput) (models.DebugSnapshot, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Capturing debug snapshot", "sessionID", input.SessionID)
+
+	var snapshot models.DebugSnapshot
+	session, ok := a.Pool.Get(input.SessionID)
+	if !ok {
+		return snapshot, fmt.Errorf("browser session not found")
+	}
+
+	summary, err := session.Page.Summary()
+	if err != nil {
+		snapshot.ErrorMessage = err.Error()
+	}
+	snapshot.Page = summary
+
+	path, err := a.saveScreenshot(ctx, session.Page, input.Filename)
+	if err != nil && snapshot.ErrorMessage == "" {
+		snapshot.ErrorMessage = err.Error()
+	}
+	snapshot.ScreenshotPath = path
+	return snapshot, nil
+}
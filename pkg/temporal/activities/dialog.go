@@ -0,0 +1,66 @@
+package activities
+
+import (
+	"sync"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// dialogLog keeps the dialogs a page answered until the action that opened
+// them collects them
+type dialogLog struct {
+	mu      sync.Mutex
+	dialogs []models.DialogEvent
+}
+
+func (l *dialogLog) add(dialog models.DialogEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.dialogs = append(l.dialogs, dialog)
+}
+
+// take returns the dialogs answered since the last call
+func (l *dialogLog) take() []models.DialogEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	dialogs := l.dialogs
+	l.dialogs = nil
+	return dialogs
+}
+
+// dialogAnswer decides whether to accept a dialog and what to answer a
+// prompt with. Without a policy dialogs are accepted, as the user most
+// likely did while recording.
+func dialogAnswer(policy *models.DialogPolicy, answer, dialogType string) (bool, string) {
+	if policy == nil {
+		return true, ""
+	}
+	switch policy.Action {
+	case models.DialogDismiss:
+		return false, ""
+	case models.DialogAnswer:
+		if dialogType == string(proto.PageDialogTypePrompt) {
+			return true, answer
+		}
+	}
+	return true, ""
+}
+
+// handleDialogs answers the page's dialogs with the policy until it closes,
+// adding them to log. Dialogs block the page's scripts and any input
+// dispatched to it, so they are answered as soon as they open.
+func handleDialogs(page *rod.Page, policy *models.DialogPolicy, answer string, log *dialogLog) {
+	wait := page.EachEvent(func(e *proto.PageJavascriptDialogOpening) {
+		accept, text := dialogAnswer(policy, answer, string(e.Type))
+		// Prompts keep their default text unless answered
+		if accept && text == "" {
+			text = e.DefaultPrompt
+		}
+		_ = proto.PageHandleJavaScriptDialog{Accept: accept, PromptText: text}.Call(page)
+		log.add(models.DialogEvent{Type: string(e.Type), Message: e.Message, Accepted: accept})
+	})
+	go wait()
+}
@@ -0,0 +1,84 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.temporal.io/sdk/activity"
+
+	"dev/bravebird/browser-automation-go/pkg/artifacts"
+	"dev/bravebird/browser-automation-go/pkg/temporal/workflows"
+)
+
+// maxDOMSnapshot caps the size of a DOM snapshot in bytes
+const maxDOMSnapshot = 2 << 20
+
+// domSnapshotScript serializes the page's DOM trimmed for reading after the
+// fact: scripts and inline images are dropped, password values blanked and
+// field values written into the markup, and a base URL added so the
+// snapshot's relative links resolve against the page
+const domSnapshotScript = `(max) => {
+	const root = document.documentElement.cloneNode(true);
+	root.querySelectorAll('script, noscript, template, iframe, object, embed').forEach((el) => el.remove());
+	root.querySelectorAll('[src^="data:"], [href^="data:"]').forEach((el) => { el.removeAttribute('src'); el.removeAttribute('href'); });
+	const live = document.querySelectorAll('input, textarea, select');
+	root.querySelectorAll('input, textarea, select').forEach((el, i) => {
+		const src = live[i];
+		if (!src) return;
+		if (el.tagName === 'INPUT' && (src.type === 'checkbox' || src.type === 'radio')) {
+			src.checked ? el.setAttribute('checked', '') : el.removeAttribute('checked');
+		} else if (el.tagName === 'INPUT') {
+			el.setAttribute('value', src.type === 'password' ? '' : src.value);
+		} else if (el.tagName === 'TEXTAREA') {
+			el.textContent = src.value;
+		} else {
+			[...el.options].forEach((o, j) => o.selected = src.options[j] && src.options[j].selected);
+		}
+	});
+	root.querySelectorAll('*').forEach((el) => {
+		for (const attr of [...el.attributes]) if (attr.name.startsWith('on')) el.removeAttribute(attr.name);
+	});
+	const head = root.querySelector('head');
+	if (head) {
+		const base = document.createElement('base');
+		base.href = location.href;
+		head.prepend(base);
+	}
+	const html = '<!DOCTYPE html>\n' + root.outerHTML;
+	return html.length > max ? html.slice(0, max) : html;
+}`
+
+func (p *rodPage) DOMSnapshot() (string, error) {
+	res, err := p.page.Eval(domSnapshotScript, maxDOMSnapshot)
+	if err != nil {
+		return "", fmt.Errorf("failed to capture DOM: %w", err)
+	}
+	return res.Value.Str(), nil
+}
+
+// DOMSnapshotActivity saves the session page's DOM as the given file in the
+// screenshot directory, returning its path
+func (a *Activities) DOMSnapshotActivity(ctx context.Context, input workflows.ScreenshotInput) (string, error) {
+	activity.GetLogger(ctx).Info("Capturing DOM snapshot", "sessionID", input.SessionID)
+
+	session, ok := a.Pool.Get(input.SessionID)
+	if !ok {
+		return "", fmt.Errorf("browser session not found")
+	}
+	return a.saveDOMSnapshot(ctx, session.Page, input.Filename)
+}
+
+// saveDOMSnapshot captures the page's DOM into the artifact store alongside
+// the screenshots
+func (a *Activities) saveDOMSnapshot(ctx context.Context, page BrowserPage, filename string) (string, error) {
+	html, err := page.DOMSnapshot()
+	if err != nil {
+		return "", err
+	}
+	path, err := a.artifactStore().Put(ctx, artifacts.ScreenshotKey(filename), "text/html; charset=utf-8", strings.NewReader(html))
+	if err != nil {
+		return "", fmt.Errorf("failed to save DOM snapshot: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,180 @@
+package activities
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// downloadWait is how long an action waits for the downloads it started
+const downloadWait = 30 * time.Second
+
+// downloadLog moves the files a page downloads into the run's directory and
+// keeps them until the action that started them collects them
+type downloadLog struct {
+	dir     string
+	mu      sync.Mutex
+	frames  map[proto.PageFrameID]bool                 // main frames of the run's tabs
+	pending map[string]*proto.BrowserDownloadWillBegin // by GUID
+	done    []models.DownloadedFile
+	stop    func()
+}
+
+// watch captures the downloads a tab's main frame starts
+func (l *downloadLog) watch(frameID proto.PageFrameID) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.frames[frameID] = true
+}
+
+func (l *downloadLog) begin(e *proto.BrowserDownloadWillBegin) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pending[e.GUID] = e
+}
+
+// finish saves a completed download under its suggested name, or drops a
+// canceled one
+func (l *downloadLog) finish(guid string, completed bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	started, ok := l.pending[guid]
+	if !ok {
+		return
+	}
+	delete(l.pending, guid)
+	if !completed {
+		return
+	}
+	file, err := saveDownload(l.dir, guid, started.SuggestedFilename)
+	if err != nil {
+		return
+	}
+	file.URL = started.URL
+	l.done = append(l.done, file)
+}
+
+// take waits up to timeout for the downloads in progress, then returns the
+// files saved since the last call
+func (l *downloadLog) take(timeout time.Duration) []models.DownloadedFile {
+	if l == nil {
+		return nil
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		l.mu.Lock()
+		if len(l.pending) == 0 || time.Now().After(deadline) {
+			files := l.done
+			l.done = nil
+			l.mu.Unlock()
+			return files
+		}
+		l.mu.Unlock()
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func (l *downloadLog) close() {
+	if l != nil && l.stop != nil {
+		l.stop()
+	}
+}
+
+// captureDownloads saves the files the run's tabs download to dir. Chrome
+// names them by GUID until they complete. Only downloads started by the
+// main frames of watched tabs are captured, since a shared browser serves
+// other runs too.
+func captureDownloads(browser *rod.Browser, dir string) (*downloadLog, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create download dir: %w", err)
+	}
+
+	log := &downloadLog{
+		dir:     dir,
+		frames:  make(map[proto.PageFrameID]bool),
+		pending: make(map[string]*proto.BrowserDownloadWillBegin),
+	}
+	events, stop := browser.WithCancel()
+	log.stop = stop
+	wait := events.EachEvent(func(e *proto.BrowserDownloadWillBegin) {
+		log.mu.Lock()
+		ours := log.frames[e.FrameID]
+		log.mu.Unlock()
+		if ours {
+			log.begin(e)
+		}
+	}, func(e *proto.BrowserDownloadProgress) {
+		switch e.State {
+		case proto.BrowserDownloadProgressStateCompleted:
+			log.finish(e.GUID, true)
+		case proto.BrowserDownloadProgressStateCanceled:
+			log.finish(e.GUID, false)
+		}
+	})
+	go wait()
+
+	err := proto.BrowserSetDownloadBehavior{
+		Behavior:         proto.BrowserSetDownloadBehaviorBehaviorAllowAndName,
+		BrowserContextID: browser.BrowserContextID,
+		DownloadPath:     dir,
+		EventsEnabled:    true,
+	}.Call(browser)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	return log, nil
+}
+
+// saveDownload renames a downloaded file to its suggested name, numbering
+// it when the run already downloaded a file of that name, and hashes it
+func saveDownload(dir, tempName, suggested string) (models.DownloadedFile, error) {
+	name := filepath.Base(suggested)
+	if name == "." || name == string(filepath.Separator) || name == "" {
+		name = "download"
+	}
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(filepath.Join(dir, name)); os.IsNotExist(err) {
+			break
+		}
+		name = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.Rename(filepath.Join(dir, tempName), path); err != nil {
+		return models.DownloadedFile{}, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return models.DownloadedFile{}, err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return models.DownloadedFile{}, err
+	}
+	return models.DownloadedFile{
+		Filename: name,
+		Path:     path,
+		Size:     size,
+		SHA256:   hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
@@ -0,0 +1,251 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+	"dev/bravebird/browser-automation-go/pkg/temporal/workflows"
+)
+
+// BrowserPage is an open page of a browser session. Actions execute through
+// it, so a run behaves the same whichever engine drives the browser.
+type BrowserPage interface {
+	// Info returns the current URL and title
+	Info() (url, title string, err error)
+	Navigate(url string) error
+	// Locate returns the index of the first candidate that finds an
+	// element, trying each for up to candidateWait. Candidates are CSS
+	// selectors or text= and xpath= locators, text matched within tag.
+	Locate(candidates []string, tag string) (int, error)
+	// Click, Fill, Focus and Blur find their element by selector or
+	// locator, or by tag and text when the selector is empty
+	Click(selector, tag, text string) error
+	Fill(selector, tag, text, value string) error
+	Focus(selector, tag, text string) error
+	Blur(selector, tag, text string) error
+	// Read returns the text of the element, the value of a form field, or
+	// the value of attribute when one is given
+	Read(selector, tag, text, attribute string) (string, error)
+	// Press presses a key by its recorded name, such as "enter" or "a", or
+	// a combination such as "Ctrl+Shift+K"
+	Press(key string) error
+	// Shortcut presses a key with Control held
+	Shortcut(key string) error
+	// Copy presses Ctrl+C and returns the copied text
+	Copy() (string, error)
+	// InsertText types text at the cursor as a paste would
+	InsertText(text string) error
+	// Wait runs action and then waits for each condition in turn.
+	// Navigation and network idle are watched from before the action, so
+	// a quick page load is not missed.
+	Wait(action func() error, conds ...models.WaitCondition) error
+	// Screenshot captures the full page as PNG
+	Screenshot() ([]byte, error)
+	// Summary returns the URL and title and the visible elements actions
+	// could target
+	Summary() (*models.PageSummary, error)
+	// DOMSnapshot serializes the page's DOM as HTML, trimmed of scripts
+	DOMSnapshot() (string, error)
+	// Metrics returns the navigation timings of the current document
+	Metrics() (*models.PageMetrics, error)
+	// Audit injects the axe-core script and returns its accessibility
+	// results for the page as JSON
+	Audit(axe string) (string, error)
+	// VisibleTexts returns the texts of the visible elements with each of
+	// tags, keyed by tag
+	VisibleTexts(tags []string) (map[string][]string, error)
+	// SwitchTabs moves to the tabs the page opened since the last call and
+	// back from tabs that closed, returning the switches
+	SwitchTabs() []models.TabSwitch
+	// Dialogs returns the JavaScript dialogs answered since the last call
+	Dialogs() []models.DialogEvent
+	// Downloads waits for the downloads in progress and returns the files
+	// saved since the last call
+	Downloads() []models.DownloadedFile
+	// StorageState captures the session's cookies and the localStorage of
+	// the current origin
+	StorageState() (*models.StorageState, error)
+	// Close closes what the session opened: its browser, or its context in
+	// a shared browser
+	Close() error
+}
+
+// Prefixes of the candidates locating elements other than by CSS selector
+const (
+	textLocator  = "text="
+	xpathLocator = "xpath="
+)
+
+// LaunchOptions configure the browser a driver launches
+type LaunchOptions struct {
+	Engine   models.BrowserEngine
+	Headless bool
+	Device   *models.DeviceConfig // emulated device, the browser's default when nil
+
+	// UserAgent overrides the device's. Empty values keep the browser's.
+	UserAgent string
+	Locale    string
+	Timezone  string
+	Proxy     *models.ProxyConfig
+	Stealth   bool // hide automation signals from the sites' scripts
+
+	// HTTPCredentials answer the sites' basic, digest and NTLM challenges
+	HTTPCredentials *models.HTTPCredentials
+
+	// Dialogs answers the pages' dialogs, DialogAnswer is the prompt answer
+	Dialogs      *models.DialogPolicy
+	DialogAnswer string
+
+	// DownloadDir keeps the pages' downloads, discarded when empty
+	DownloadDir string
+
+	// SkipStabilityChecks clicks and fills elements as soon as they are
+	// visible, even off-screen or mid-animation
+	SkipStabilityChecks bool
+
+	StorageState *models.StorageState // cookies and localStorage injected before the run
+}
+
+// userAgent returns the user agent to report, empty for the browser's own
+func (o LaunchOptions) userAgent() string {
+	if o.UserAgent == "" && o.Device != nil {
+		return o.Device.UserAgent
+	}
+	return o.UserAgent
+}
+
+// launchOptions collects a run's browser settings
+func launchOptions(input workflows.BrowserInitInput) LaunchOptions {
+	return LaunchOptions{
+		Engine:    input.Browser,
+		Headless:  input.Headless,
+		Device:    input.Device,
+		UserAgent: input.UserAgent,
+		Locale:    input.Locale,
+		Timezone:  input.Timezone,
+		Proxy:     input.Proxy,
+		Stealth:   input.Stealth,
+
+		HTTPCredentials: input.HTTPCredentials,
+		StorageState:    input.StorageState,
+		Dialogs:         input.Dialogs,
+		DialogAnswer:    input.DialogAnswer,
+
+		SkipStabilityChecks: input.SkipStabilityChecks,
+	}
+}
+
+// BrowserDriver launches browsers of engines other than Chromium, which the
+// activities drive with Go Rod themselves
+type BrowserDriver interface {
+	Launch(ctx context.Context, opts LaunchOptions) (BrowserPage, error)
+}
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[models.BrowserEngine]BrowserDriver)
+)
+
+// RegisterDriver makes a driver available for an engine. Drivers register
+// themselves from init, like database/sql drivers, so engines whose
+// dependencies are not compiled in are simply unavailable.
+func RegisterDriver(engine models.BrowserEngine, driver BrowserDriver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	drivers[engine] = driver
+}
+
+// driverFor returns the driver registered for an engine
+func driverFor(engine models.BrowserEngine) (BrowserDriver, error) {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	driver, ok := drivers[engine]
+	if !ok {
+		return nil, fmt.Errorf("browser %s is not available on this worker (build it with -tags playwright)", engine)
+	}
+	return driver, nil
+}
+
+// Modifiers of a key combination
+const (
+	modifierCtrl  = "ctrl"
+	modifierAlt   = "alt"
+	modifierShift = "shift"
+	modifierMeta  = "meta"
+)
+
+// primaryModifier is what a recorded Cmd replays as: Meta on macOS and
+// Ctrl elsewhere, where web apps bind the same shortcuts to Ctrl
+var primaryModifier = func() string {
+	if runtime.GOOS == "darwin" {
+		return modifierMeta
+	}
+	return modifierCtrl
+}()
+
+// modifierNames maps the modifier names recordings use
+var modifierNames = map[string]string{
+	"ctrl":    modifierCtrl,
+	"control": modifierCtrl,
+	"alt":     modifierAlt,
+	"option":  modifierAlt,
+	"shift":   modifierShift,
+	"meta":    modifierMeta,
+	"win":     modifierMeta,
+	"cmd":     "", // the primary modifier
+	"command": "",
+}
+
+// keyCombo is a key pressed with modifiers held, such as Ctrl+Shift+K
+type keyCombo struct {
+	Modifiers []string
+	Key       string
+}
+
+// parseKeyCombo splits a recorded combination such as "Ctrl+Shift+K" or
+// "Alt+ARROWDOWN" into its modifiers and key. Values that are not a
+// combination, including "+" itself, are a single key. Letters of a
+// combination are lowercased since Shift is pressed explicitly.
+func parseKeyCombo(value string) keyCombo {
+	rest, key := value, ""
+	if strings.HasSuffix(value, "++") {
+		rest, key = strings.TrimSuffix(value, "++"), "+"
+	} else if i := strings.LastIndex(value, "+"); i > 0 && i < len(value)-1 {
+		rest, key = value[:i], value[i+1:]
+	} else {
+		return keyCombo{Key: value}
+	}
+
+	var modifiers []string
+	for _, name := range strings.Split(rest, "+") {
+		modifier, ok := modifierNames[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return keyCombo{Key: value}
+		}
+		if modifier == "" {
+			modifier = primaryModifier
+		}
+		modifiers = append(modifiers, modifier)
+	}
+	if len(key) == 1 {
+		key = strings.ToLower(key)
+	}
+	return keyCombo{Modifiers: modifiers, Key: key}
+}
+
+// functionKey returns the number of a function key name such as "F5"
+func functionKey(value string) (int, bool) {
+	if len(value) < 2 || (value[0] != 'f' && value[0] != 'F') {
+		return 0, false
+	}
+	n, err := strconv.Atoi(value[1:])
+	if err != nil || n < 1 || n > 12 {
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,470 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-rod/rod/lib/proto"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// recordingPage records the calls actions make
+type recordingPage struct {
+	calls []string
+	found string // the only candidate Locate finds, any when empty
+}
+
+func (p *recordingPage) record(format string, args ...interface{}) error {
+	p.calls = append(p.calls, fmt.Sprintf(format, args...))
+	return nil
+}
+
+func (p *recordingPage) Info() (string, string, error) { return "about:blank", "", nil }
+func (p *recordingPage) Navigate(url string) error     { return p.record("navigate %s", url) }
+func (p *recordingPage) Locate(candidates []string, tag string) (int, error) {
+	for i, candidate := range candidates {
+		if p.found == "" || candidate == p.found {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("no element matches %q", candidates)
+}
+func (p *recordingPage) Click(selector, tag, text string) error {
+	return p.record("click %s %s %s", selector, tag, text)
+}
+func (p *recordingPage) Fill(selector, tag, text, value string) error {
+	return p.record("fill %s %s", selector, value)
+}
+func (p *recordingPage) Focus(selector, tag, text string) error {
+	return p.record("focus %s", selector)
+}
+func (p *recordingPage) Blur(selector, tag, text string) error { return p.record("blur %s", selector) }
+func (p *recordingPage) Read(selector, tag, text, attribute string) (string, error) {
+	return "$42.00", p.record("read %s %s", selector, attribute)
+}
+func (p *recordingPage) Press(key string) error       { return p.record("press %s", key) }
+func (p *recordingPage) Shortcut(key string) error    { return p.record("ctrl+%s", key) }
+func (p *recordingPage) Copy() (string, error)        { return "ORD-1042", p.record("ctrl+c") }
+func (p *recordingPage) InsertText(text string) error { return p.record("insert %s", text) }
+func (p *recordingPage) Screenshot() ([]byte, error)  { return nil, nil }
+func (p *recordingPage) Summary() (*models.PageSummary, error) {
+	return &models.PageSummary{URL: "about:blank"}, nil
+}
+func (p *recordingPage) DOMSnapshot() (string, error) { return "<html></html>", nil }
+func (p *recordingPage) Metrics() (*models.PageMetrics, error) {
+	return &models.PageMetrics{URL: "about:blank"}, nil
+}
+func (p *recordingPage) VisibleTexts(tags []string) (map[string][]string, error) {
+	return map[string][]string{"div": {"Order #1043 created"}}, nil
+}
+func (p *recordingPage) Audit(axe string) (string, error) {
+	return `{"url":"about:blank","violations":[]}`, p.record("audit")
+}
+func (p *recordingPage) Wait(action func() error, conds ...models.WaitCondition) error {
+	if err := action(); err != nil {
+		return err
+	}
+	for _, cond := range conds {
+		p.record("wait %s", cond)
+	}
+	return nil
+}
+func (p *recordingPage) SwitchTabs() []models.TabSwitch     { return nil }
+func (p *recordingPage) Dialogs() []models.DialogEvent      { return nil }
+func (p *recordingPage) Downloads() []models.DownloadedFile { return nil }
+func (p *recordingPage) StorageState() (*models.StorageState, error) {
+	return &models.StorageState{}, nil
+}
+func (p *recordingPage) Close() error { return nil }
+
+func TestExecuteActionDrivesPage(t *testing.T) {
+	a := &Activities{}
+	page := &recordingPage{}
+	params := map[string]string{"query": "dogs"}
+
+	actions := []models.SemanticAction{
+		{ActionType: models.ActionNavigate, Value: "https://example.com/?q={{query}}"},
+		{ActionType: models.ActionInput, Value: "{{query}}", Target: models.SemanticTarget{
+			Tag: "input", Attributes: map[string]interface{}{"name": "q"},
+		}},
+		{ActionType: models.ActionClick, Target: models.SemanticTarget{Tag: "button", Text: "Search"}},
+		{ActionType: models.ActionKeypress, Value: "Enter"},
+		{ActionType: models.ActionPaste},
+		{ActionType: models.ActionPaste, Value: "{{query}}"},
+	}
+	for _, action := range actions {
+		if err := a.executeAction(page, action, params, &models.ActionResult{}); err != nil {
+			t.Fatalf("executeAction(%s) error = %v", action.ActionType, err)
+		}
+	}
+	var result models.ActionResult
+	if a.executeAction(page, models.SemanticAction{ActionType: models.ActionCopy}, params, &result); result.Output != "ORD-1042" {
+		t.Errorf("executeAction(copy) output = %q, want the copied text", result.Output)
+	}
+	extract := models.SemanticAction{
+		ActionType: models.ActionExtract,
+		Target:     models.SemanticTarget{Selector: "#total"},
+		Extract:    &models.Extraction{Output: "total", Attribute: "data-amount"},
+	}
+	if a.executeAction(page, extract, params, &result); result.Output != "$42.00" {
+		t.Errorf("executeAction(extract) output = %q, want the element's value", result.Output)
+	}
+
+	want := []string{
+		"navigate https://example.com/?q=dogs",
+		"fill input[name='q'] dogs",
+		"click text=Search button Search",
+		"press Enter",
+		"ctrl+v",
+		"insert dogs",
+		"ctrl+c",
+		"read #total data-amount",
+	}
+	if !reflect.DeepEqual(page.calls, want) {
+		t.Errorf("calls = %q, want %q", page.calls, want)
+	}
+}
+
+func TestExecuteActionWaits(t *testing.T) {
+	a := &Activities{}
+	page := &recordingPage{}
+	action := models.SemanticAction{
+		ActionType: models.ActionClick,
+		Target:     models.SemanticTarget{Selector: "#checkout"},
+		Waits: []models.WaitCondition{
+			{Type: models.WaitNavigation},
+			{Type: models.WaitURL, Pattern: `/orders/\d+$`},
+			{Type: models.WaitHidden, Selector: ".spinner"},
+		},
+	}
+	if err := a.executeAction(page, action, nil, &models.ActionResult{}); err != nil {
+		t.Fatalf("executeAction() error = %v", err)
+	}
+
+	want := []string{
+		"click #checkout  ",
+		"wait navigation",
+		`wait url matching /orders/\d+$`,
+		"wait .spinner hidden",
+	}
+	if !reflect.DeepEqual(page.calls, want) {
+		t.Errorf("calls = %q, want %q", page.calls, want)
+	}
+}
+
+func TestSelectorChain(t *testing.T) {
+	a := &Activities{}
+	action := models.SemanticAction{
+		ActionType: models.ActionClick,
+		Target: models.SemanticTarget{
+			Tag:        "BUTTON",
+			Text:       "Checkout",
+			Selector:   "div > button:nth-child(3)",
+			XPath:      "/html/body/div/button[3]",
+			Attributes: map[string]interface{}{"aria-label": "Checkout", "data-testid": "checkout"},
+			Candidates: []string{"[data-testid='checkout']", "#checkout"},
+		},
+	}
+
+	want := []string{
+		"button[aria-label='Checkout']",
+		"[data-testid='checkout']",
+		"#checkout",
+		"div > button:nth-child(3)",
+		"text=Checkout",
+		"xpath=/html/body/div/button[3]",
+	}
+	if got := a.selectorCandidates(action); !reflect.DeepEqual(got, want) {
+		t.Errorf("selectorCandidates() = %q, want %q", got, want)
+	}
+
+	page := &recordingPage{found: "text=Checkout"}
+	var result models.ActionResult
+	if err := a.executeAction(page, action, nil, &result); err != nil {
+		t.Fatalf("executeAction() error = %v", err)
+	}
+	if result.Selector != "text=Checkout" || result.SelectorFallbacks != 4 {
+		t.Errorf("result selector = %q after %d fallbacks, want text=Checkout after 4", result.Selector, result.SelectorFallbacks)
+	}
+	if result.SelectorSource != models.SelectorFallback {
+		t.Errorf("selector source = %q, want fallback", result.SelectorSource)
+	}
+	if want := []string{"click text=Checkout BUTTON Checkout"}; !reflect.DeepEqual(page.calls, want) {
+		t.Errorf("calls = %q, want %q", page.calls, want)
+	}
+
+	// A healed selector is tried first
+	action.Metadata = map[string]interface{}{models.MetaHealedSelector: "text=Checkout"}
+	if got := a.selectorCandidates(action); got[0] != "text=Checkout" || len(got) != len(want) {
+		t.Errorf("selectorCandidates() = %q, want text=Checkout first", got)
+	}
+	for found, source := range map[string]models.SelectorSource{
+		"text=Checkout":                 models.SelectorHealed,
+		"button[aria-label='Checkout']": models.SelectorPrimary,
+		"#checkout":                     models.SelectorFallback,
+	} {
+		result = models.ActionResult{}
+		if err := a.executeAction(&recordingPage{found: found}, action, nil, &result); err != nil {
+			t.Fatalf("executeAction() error = %v", err)
+		}
+		if result.SelectorSource != source {
+			t.Errorf("selector source of %s = %q, want %q", found, result.SelectorSource, source)
+		}
+	}
+}
+
+func TestDriverFor(t *testing.T) {
+	if _, err := driverFor("netscape"); err == nil {
+		t.Error("driverFor() should fail for an unregistered engine")
+	}
+
+	driver := &fakeDriver{}
+	RegisterDriver("test", driver)
+	defer func() {
+		driversMu.Lock()
+		delete(drivers, "test")
+		driversMu.Unlock()
+	}()
+	if got, err := driverFor("test"); err != nil || got != driver {
+		t.Errorf("driverFor() = %v, %v, want the registered driver", got, err)
+	}
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Launch(context.Context, LaunchOptions) (BrowserPage, error) {
+	return &recordingPage{}, nil
+}
+
+func TestCookieParams(t *testing.T) {
+	params := cookieParams([]models.StorageCookie{
+		{Name: "session", Value: "abc", Domain: ".example.com", Expires: -1, HTTPOnly: true, SameSite: "Lax"},
+		{Name: "pref", Value: "dark", Domain: "example.com", Path: "/app", Expires: 1893456000},
+	})
+
+	if len(params) != 2 {
+		t.Fatalf("cookieParams() returned %d cookies, want 2", len(params))
+	}
+	if p := params[0]; p.Path != "/" || p.Expires != 0 || !p.HTTPOnly || p.SameSite != "Lax" {
+		t.Errorf("session cookie = %+v, want path / and no expiry", p)
+	}
+	if p := params[1]; p.Path != "/app" || p.Expires != 1893456000 {
+		t.Errorf("persistent cookie = %+v, want its path and expiry", p)
+	}
+}
+
+func TestAuthResponse(t *testing.T) {
+	proxy := &models.ProxyConfig{Server: "http://proxy:3128", Username: "proxy-user", Password: "p"}
+	creds := &models.HTTPCredentials{Username: "intranet-user", Password: "s", Origin: "https://intranet.example.com/"}
+
+	tests := []struct {
+		name      string
+		challenge *proto.FetchAuthChallenge
+		wantUser  string
+	}{
+		{"proxy", &proto.FetchAuthChallenge{Source: proto.FetchAuthChallengeSourceProxy, Origin: "http://proxy:3128"}, "proxy-user"},
+		{"site", &proto.FetchAuthChallenge{Source: proto.FetchAuthChallengeSourceServer, Origin: "https://intranet.example.com", Scheme: "NTLM"}, "intranet-user"},
+		{"other site", &proto.FetchAuthChallenge{Source: proto.FetchAuthChallengeSourceServer, Origin: "https://evil.example.com"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := authResponse(tt.challenge, proxy, creds)
+			provided := got.Response == proto.FetchAuthChallengeResponseResponseProvideCredentials
+			if got.Username != tt.wantUser || provided != (tt.wantUser != "") {
+				t.Errorf("authResponse() = %+v, want username %q", got, tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestAssert(t *testing.T) {
+	a := &Activities{}
+	params := map[string]string{"amount": "42"}
+	tests := []struct {
+		name   string
+		check  models.Assertion
+		found  string
+		failed bool // the assertion doesn't hold
+		err    bool // the assertion couldn't be checked
+	}{
+		{name: "text equals", check: models.Assertion{Type: models.AssertTextEquals, Value: "$42.00"}},
+		{name: "text contains parameter", check: models.Assertion{Type: models.AssertTextContains, Value: "{{amount}}"}},
+		{name: "text differs", check: models.Assertion{Type: models.AssertTextEquals, Value: "$40.00"}, failed: true},
+		{name: "text of missing element", check: models.Assertion{Type: models.AssertTextEquals, Value: "$42.00"}, found: "#other", err: true},
+		{name: "exists", check: models.Assertion{Type: models.AssertExists}, found: "#total"},
+		{name: "does not exist", check: models.Assertion{Type: models.AssertExists}, found: "#other", failed: true},
+		{name: "absent", check: models.Assertion{Type: models.AssertAbsent}, found: "#other"},
+		{name: "present", check: models.Assertion{Type: models.AssertAbsent}, found: "#total", failed: true},
+		{name: "url matches", check: models.Assertion{Type: models.AssertURLMatches, Value: "^about:"}},
+		{name: "url differs", check: models.Assertion{Type: models.AssertURLMatches, Value: "/checkout$"}, failed: true},
+		{name: "title differs", check: models.Assertion{Type: models.AssertTitleEquals, Value: "Checkout"}, failed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action := models.SemanticAction{
+				ActionType: models.ActionAssert,
+				Target:     models.SemanticTarget{Tag: "SPAN", Selector: "#total"},
+				Assert:     &tt.check,
+			}
+			var result models.ActionResult
+			err := a.executeAction(&recordingPage{found: tt.found}, action, params, &result)
+			var assertErr *AssertionError
+			if failed := errors.As(err, &assertErr); failed != tt.failed {
+				t.Errorf("executeAction() error = %v, want assertion failure %v", err, tt.failed)
+			}
+			if (err != nil && !tt.failed) != tt.err {
+				t.Errorf("executeAction() error = %v, want error %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestMissingOutcomes(t *testing.T) {
+	expected := []models.SemanticTarget{
+		{Tag: "DIV", Text: "Order #1042 created"},
+		{Tag: "SPAN", Text: "3 items"},
+	}
+	missing, err := missingOutcomes(&recordingPage{}, expected[:1])
+	if err != nil || len(missing) != 0 {
+		t.Errorf("missingOutcomes() = %v, %v, want the order toast found", missing, err)
+	}
+
+	texts := map[string][]string{
+		"div":  {"Welcome back", "Your ORDER  #1,043 created successfully"},
+		"span": {"Cart is empty"},
+	}
+	if got := unmatchedOutcomes(expected, texts); !reflect.DeepEqual(got, expected[1:]) {
+		t.Errorf("unmatchedOutcomes() = %v, want %v", got, expected[1:])
+	}
+}
+
+func TestDialogAnswer(t *testing.T) {
+	answer := &models.DialogPolicy{Action: models.DialogAnswer, Parameter: "reason"}
+
+	tests := []struct {
+		name       string
+		policy     *models.DialogPolicy
+		dialogType string
+		wantAccept bool
+		wantText   string
+	}{
+		{"no policy", nil, "confirm", true, ""},
+		{"dismiss", &models.DialogPolicy{Action: models.DialogDismiss}, "confirm", false, ""},
+		{"answer prompt", answer, "prompt", true, "duplicate order"},
+		{"answer confirm", answer, "confirm", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accept, text := dialogAnswer(tt.policy, "duplicate order", tt.dialogType)
+			if accept != tt.wantAccept || text != tt.wantText {
+				t.Errorf("dialogAnswer() = %v, %q, want %v, %q", accept, text, tt.wantAccept, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestRequestWait(t *testing.T) {
+	cond := models.WaitCondition{Type: models.WaitRequest, Method: "post", Pattern: `/api/orders$`, Status: 201}
+	if err := cond.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	matches := []struct {
+		method, url string
+		want        bool
+	}{
+		{"POST", "https://shop.test/api/orders", true},
+		{"GET", "https://shop.test/api/orders", false},
+		{"POST", "https://shop.test/api/orders/1", false},
+	}
+	for _, m := range matches {
+		if got := requestMatches(cond, m.method, m.url); got != m.want {
+			t.Errorf("requestMatches(%s %s) = %v, want %v", m.method, m.url, got, m.want)
+		}
+	}
+
+	if err := cond.CheckResponse(201); err != nil {
+		t.Errorf("CheckResponse(201) error = %v", err)
+	}
+	if err := cond.CheckResponse(200); err == nil {
+		t.Error("CheckResponse(200) error = nil, want error")
+	}
+	cond.Status = 0
+	if err := cond.CheckResponse(302); err != nil {
+		t.Errorf("CheckResponse(302) without status error = %v", err)
+	}
+	if err := cond.CheckResponse(500); err == nil {
+		t.Error("CheckResponse(500) without status error = nil, want error")
+	}
+}
+
+func TestParseAudit(t *testing.T) {
+	report := `{"url":"https://shop.test/","violations":[
+		{"id":"image-alt","impact":"critical","help":"Images must have alternate text","helpUrl":"https://dequeuniversity.com/rules/axe/4.10/image-alt","nodes":[{},{}]},
+		{"id":"label","impact":"serious","help":"Form elements must have labels","nodes":[{}]}]}`
+	audit, err := parseAudit(report)
+	if err != nil {
+		t.Fatalf("parseAudit() error = %v", err)
+	}
+	if audit.URL != "https://shop.test/" || len(audit.Violations) != 2 {
+		t.Fatalf("parseAudit() = %+v, want 2 violations on https://shop.test/", audit)
+	}
+	if v := audit.Violations[0]; v.Rule != "image-alt" || v.Impact != "critical" || v.Nodes != 2 {
+		t.Errorf("first violation = %+v, want image-alt, critical, 2 nodes", v)
+	}
+}
+
+func TestSaveDownload(t *testing.T) {
+	dir := t.TempDir()
+	for _, guid := range []string{"guid-1", "guid-2"} {
+		if err := os.WriteFile(filepath.Join(dir, guid), []byte("id,total\n1,9.99\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first, err := saveDownload(dir, "guid-1", "report.csv")
+	if err != nil {
+		t.Fatalf("saveDownload() error = %v", err)
+	}
+	wantHash := "0e96f5383bd9ccbee267bb4d0491c055dd5f80cdd7ea3813d247e891ecc119f1"
+	if first.Filename != "report.csv" || first.Size != 16 || first.SHA256 != wantHash {
+		t.Errorf("saveDownload() = %+v, want report.csv of 16 bytes hashing to %s", first, wantHash)
+	}
+
+	second, err := saveDownload(dir, "guid-2", "../report.csv")
+	if err != nil {
+		t.Fatalf("saveDownload() error = %v", err)
+	}
+	if second.Filename != "report (1).csv" || second.Path != filepath.Join(dir, "report (1).csv") {
+		t.Errorf("saveDownload() = %+v, want a numbered name in the run's directory", second)
+	}
+	if second.SHA256 != first.SHA256 {
+		t.Errorf("identical files hashed differently: %s, %s", first.SHA256, second.SHA256)
+	}
+}
+
+func TestParseKeyCombo(t *testing.T) {
+	tests := []struct {
+		value string
+		want  keyCombo
+	}{
+		{"Enter", keyCombo{Key: "Enter"}},
+		{"+", keyCombo{Key: "+"}},
+		{"Ctrl+Shift+K", keyCombo{Modifiers: []string{"ctrl", "shift"}, Key: "k"}},
+		{"Alt+ARROWDOWN", keyCombo{Modifiers: []string{"alt"}, Key: "ARROWDOWN"}},
+		{"Cmd+S", keyCombo{Modifiers: []string{primaryModifier}, Key: "s"}},
+		{"Ctrl++", keyCombo{Modifiers: []string{"ctrl"}, Key: "+"}},
+		{"Hyper+K", keyCombo{Key: "Hyper+K"}},
+	}
+	for _, tt := range tests {
+		if got := parseKeyCombo(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseKeyCombo(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
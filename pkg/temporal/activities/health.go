@@ -0,0 +1,78 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/go-rod/rod/lib/launcher"
+
+	"dev/bravebird/browser-automation-go/pkg/health"
+)
+
+// CheckBrowser checks that the worker can get a browser: that one of the
+// remote endpoints is reachable, or that Chrome runs on this machine
+func (a *Activities) CheckBrowser(ctx context.Context) error {
+	if a.Remote.Len() > 0 {
+		return a.Remote.check(ctx)
+	}
+
+	bin := os.Getenv("CHROME_BIN")
+	if bin == "" {
+		found, ok := launcher.LookPath()
+		if !ok {
+			return errors.New("no Chrome installed, set CHROME_BIN")
+		}
+		bin = found
+	}
+	if out, err := exec.CommandContext(ctx, bin, "--version").CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", bin, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// check succeeds when any endpoint of the fleet is reachable. HTTP
+// endpoints must answer /json/version; WebSocket ones, which open a session
+// when connected to, only have to accept a connection.
+func (r *RemoteEndpoints) check(ctx context.Context) error {
+	var errs []error
+	for _, endpoint := range r.urls {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		switch u.Scheme {
+		case "ws", "wss":
+			err = dialEndpoint(ctx, u)
+		default:
+			err = health.HTTP(nil, strings.TrimSuffix(endpoint, "/")+"/json/version")(ctx)
+		}
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return fmt.Errorf("no browser endpoint reachable: %w", errors.Join(errs...))
+}
+
+func dialEndpoint(ctx context.Context, u *url.URL) error {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "wss" {
+			port = "443"
+		}
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,40 @@
+package activities
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRemoteEndpointsCheck(t *testing.T) {
+	chrome := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/json/version" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer chrome.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	tests := []struct {
+		name      string
+		endpoints string
+		wantErr   bool
+	}{
+		{name: "http endpoint", endpoints: chrome.URL},
+		{name: "websocket endpoint", endpoints: "ws" + strings.TrimPrefix(chrome.URL, "http") + "/devtools/browser/1"},
+		{name: "one of several reachable", endpoints: closed.URL + "," + chrome.URL},
+		{name: "none reachable", endpoints: closed.URL, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewRemoteEndpoints(tt.endpoints).check(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
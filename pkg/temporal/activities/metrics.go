@@ -0,0 +1,48 @@
+package activities
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// pageMetricsScript returns the navigation timings of the current document
+// as JSON page metrics. Largest contentful paint entries are only handed to
+// observers, so the script waits a moment for the buffered ones.
+const pageMetricsScript = `() => new Promise((resolve) => {
+	const nav = performance.getEntriesByType('navigation')[0] || {};
+	const fcp = performance.getEntriesByName('first-contentful-paint')[0];
+	let lcp = 0;
+	try {
+		new PerformanceObserver((list) => {
+			for (const e of list.getEntries()) lcp = Math.max(lcp, e.renderTime || e.loadTime || e.startTime);
+		}).observe({type: 'largest-contentful-paint', buffered: true});
+	} catch (e) {}
+	setTimeout(() => resolve(JSON.stringify({
+		url: location.href,
+		ttfb_ms: nav.responseStart || 0,
+		dom_content_loaded_ms: nav.domContentLoadedEventEnd || 0,
+		load_ms: nav.loadEventEnd || 0,
+		fcp_ms: fcp ? fcp.startTime : 0,
+		lcp_ms: lcp,
+		transfer_bytes: nav.transferSize || 0,
+	})), 50);
+})`
+
+// parsePageMetrics decodes the result of pageMetricsScript
+func parsePageMetrics(result string) (*models.PageMetrics, error) {
+	var metrics models.PageMetrics
+	if err := json.Unmarshal([]byte(result), &metrics); err != nil {
+		return nil, fmt.Errorf("failed to parse page metrics: %w", err)
+	}
+	return &metrics, nil
+}
+
+func (p *rodPage) Metrics() (*models.PageMetrics, error) {
+	res, err := p.page.Eval(pageMetricsScript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure page: %w", err)
+	}
+	return parsePageMetrics(res.Value.Str())
+}
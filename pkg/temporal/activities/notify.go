@@ -0,0 +1,97 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+
+	"dev/bravebird/browser-automation-go/pkg/artifacts"
+	"dev/bravebird/browser-automation-go/pkg/codegen"
+	"dev/bravebird/browser-automation-go/pkg/notify"
+	"dev/bravebird/browser-automation-go/pkg/temporal/workflows"
+)
+
+// notifyScreenshotExpiry is how long the signed screenshot URLs posted to
+// chat services stay valid, the longest object stores sign for
+const notifyScreenshotExpiry = 7 * 24 * time.Hour
+
+// maxNotifyScreenshot is the largest failure screenshot sent with a
+// notification
+const maxNotifyScreenshot = 10 << 20
+
+// NotifyRunActivity announces how a run ended to its workflow's targets,
+// with the step it failed on and the page when it did
+func (a *Activities) NotifyRunActivity(ctx context.Context, input workflows.NotifyInput) error {
+	logger := activity.GetLogger(ctx)
+
+	n := notify.Notification{
+		WorkflowID: input.WorkflowID,
+		RunID:      input.RunID,
+		Status:     input.Status,
+		Error:      input.ErrorMessage,
+	}
+	if input.FailedAction != nil {
+		n.Step = codegen.DescribeAction(*input.FailedAction)
+	}
+	if a.DB != nil {
+		if def, err := a.DB.GetWorkflowDefinition(ctx, input.WorkflowID); err == nil {
+			n.WorkflowName = def.Name
+		}
+	}
+	if a.PublicURL != "" {
+		n.RunURL = a.PublicURL + "/api/runs/" + url.PathEscape(input.RunID) + "/report.html"
+	}
+	if input.ScreenshotPath != "" {
+		n.Screenshot, n.ScreenshotURL = a.notifyScreenshot(ctx, input.ScreenshotPath)
+	}
+
+	var errs []error
+	for _, target := range input.Targets {
+		notifier, err := notify.New(target, a.Notify)
+		if err == nil {
+			err = notifier.Notify(ctx, n)
+		}
+		if err != nil {
+			logger.Warn("Failed to send run notification", "type", target.Type, "error", err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// notifyScreenshot reads a failure screenshot for the targets that upload
+// it, and returns a URL chat services can fetch it from for the ones that
+// link it: a signed URL when artifacts are in a bucket, otherwise the
+// API's, empty without a PublicURL.
+func (a *Activities) notifyScreenshot(ctx context.Context, location string) ([]byte, string) {
+	name := path.Base(strings.ReplaceAll(location, "\\", "/"))
+	key := artifacts.ScreenshotKey(name)
+	store := a.artifactStore()
+
+	var screenshotURL string
+	if signer, ok := store.(artifacts.Signer); ok {
+		if signed, err := signer.SignedURL(key, "inline", notifyScreenshotExpiry); err == nil {
+			screenshotURL = signed
+		}
+	} else if a.PublicURL != "" {
+		screenshotURL = a.PublicURL + "/api/screenshots/" + url.PathEscape(name)
+	}
+
+	body, err := store.Get(ctx, key)
+	if err != nil {
+		activity.GetLogger(ctx).Warn("Failed to read failure screenshot", "key", key, "error", err)
+		return nil, screenshotURL
+	}
+	defer body.Close()
+	screenshot, err := io.ReadAll(io.LimitReader(body, maxNotifyScreenshot+1))
+	if err != nil || len(screenshot) > maxNotifyScreenshot {
+		return nil, screenshotURL
+	}
+	return screenshot, screenshotURL
+}
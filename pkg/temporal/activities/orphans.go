@@ -0,0 +1,175 @@
+package activities
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// orphanGrace is how long a launched Chrome may go unclaimed by a session
+// or warm browser before it is taken for one its activity abandoned
+const orphanGrace = 2 * time.Minute
+
+// procDir lists the machine's processes, on Linux
+var procDir = "/proc"
+
+// killProcess kills a process left behind by a worker
+var killProcess = func(pid int) {
+	if p, err := os.FindProcess(pid); err == nil {
+		p.Kill()
+	}
+}
+
+// profileRoot is the directory launched Chromes keep their profiles in,
+// one named "<worker PID>-<ID>" each
+func (p *BrowserPool) profileRoot() string {
+	if p.config.ProfileDir != "" {
+		return p.config.ProfileDir
+	}
+	return filepath.Join(os.TempDir(), "browser-automation-chrome")
+}
+
+// launch starts Chrome with a profile in the pool's profile directory. The
+// pool tracks it from then on, and stops it if no session or warm browser
+// holds it within orphanGrace.
+func (p *BrowserPool) launch(opts LaunchOptions) (*launchedBrowser, error) {
+	profile := filepath.Join(p.profileRoot(), fmt.Sprintf("%d-%s", os.Getpid(), uuid.NewString()))
+
+	// The profile is claimed before Chrome creates it, so it isn't reaped
+	// while Chrome starts
+	p.chromesMu.Lock()
+	p.chromes[profile] = nil
+	p.chromesMu.Unlock()
+	untrack := func() {
+		p.chromesMu.Lock()
+		delete(p.chromes, profile)
+		p.chromesMu.Unlock()
+	}
+
+	browser, err := launchBrowser(opts, profile)
+	if err != nil {
+		untrack()
+		return nil, err
+	}
+	browser.stopped = untrack
+
+	p.chromesMu.Lock()
+	p.chromes[profile] = browser
+	p.chromesMu.Unlock()
+	return browser, nil
+}
+
+// held returns the launched browsers that sessions or warm browsers hold
+func (p *BrowserPool) held() map[*launchedBrowser]bool {
+	held := make(map[*launchedBrowser]bool)
+	p.mu.RLock()
+	for _, session := range p.sessions {
+		if session.chrome != nil {
+			held[session.chrome] = true
+		}
+	}
+	p.mu.RUnlock()
+
+	p.warmMu.Lock()
+	for _, w := range p.warm {
+		held[w.browser] = true
+	}
+	p.warmMu.Unlock()
+	return held
+}
+
+// ReapOrphans stops the Chromes this worker launched that no session or
+// warm browser took within orphanGrace, such as those of activities that
+// crashed while opening their page. It also kills the Chromes and removes
+// the profiles left in the profile directory by this worker or by workers
+// that are gone, such as a worker that crashed before this one started. It
+// returns the profiles removed.
+func (p *BrowserPool) ReapOrphans(now time.Time) []string {
+	// Profiles are listed before the tracked ones, as launches claim them
+	// before they are created
+	entries, _ := os.ReadDir(p.profileRoot())
+
+	held := p.held()
+	tracked := make(map[string]bool)
+	abandoned := make(map[string]*launchedBrowser)
+	p.chromesMu.Lock()
+	for profile, browser := range p.chromes {
+		tracked[profile] = true
+		if browser != nil && !held[browser] && now.Sub(browser.launchedAt) > orphanGrace {
+			abandoned[profile] = browser
+		}
+	}
+	p.chromesMu.Unlock()
+
+	var reaped []string
+	for profile, browser := range abandoned {
+		browser.stop()
+		reaped = append(reaped, profile)
+	}
+	for _, entry := range entries {
+		profile := filepath.Join(p.profileRoot(), entry.Name())
+		if !entry.IsDir() || tracked[profile] || !ownerGone(entry.Name()) {
+			continue
+		}
+		for _, pid := range profileProcesses(profile) {
+			killProcess(pid)
+		}
+		if err := os.RemoveAll(profile); err == nil {
+			reaped = append(reaped, profile)
+		}
+	}
+	return reaped
+}
+
+// ownerGone reports whether the worker that launched the Chrome of a
+// profile, named after the worker's PID, is no longer running. The
+// profiles of this worker are only left behind when untracked.
+func ownerGone(name string) bool {
+	owner, _, ok := strings.Cut(name, "-")
+	pid, err := strconv.Atoi(owner)
+	if !ok || err != nil {
+		return false
+	}
+	if pid == os.Getpid() {
+		return true
+	}
+	if _, err := os.Stat(procDir); err != nil {
+		return false // no telling whether it runs
+	}
+	_, err = os.Stat(filepath.Join(procDir, owner))
+	return os.IsNotExist(err)
+}
+
+// profileProcesses returns the PIDs of the processes started with the
+// profile, found through /proc on Linux and none elsewhere
+func profileProcesses(profile string) []int {
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return nil
+	}
+	flag := []byte("--user-data-dir=" + profile)
+	var pids []int
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		cmdline, err := os.ReadFile(filepath.Join(procDir, entry.Name(), "cmdline"))
+		if err != nil {
+			continue
+		}
+		for _, arg := range bytes.Split(cmdline, []byte{0}) {
+			if bytes.Equal(arg, flag) {
+				pids = append(pids, pid)
+				break
+			}
+		}
+	}
+	return pids
+}
@@ -0,0 +1,66 @@
+package activities
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestReapOrphans(t *testing.T) {
+	root := t.TempDir()
+	pool := NewBrowserPool(PoolConfig{ProfileDir: root})
+
+	// A fake /proc, where worker 1234 runs and Chrome 4242 uses the profile
+	// of a worker that is gone
+	proc := t.TempDir()
+	defer func(dir string) { procDir = dir }(procDir)
+	procDir = proc
+	var killed []int
+	defer func(kill func(int)) { killProcess = kill }(killProcess)
+	killProcess = func(pid int) { killed = append(killed, pid) }
+
+	self := os.Getpid()
+	profiles := map[string]bool{ // reaped or kept
+		fmt.Sprintf("%d-launching", self): false,
+		fmt.Sprintf("%d-untracked", self): true,
+		"1234-live-worker":                false,
+		"99999999-gone-worker":            true,
+		"not-a-profile":                   false,
+	}
+	for name := range profiles {
+		if err := os.Mkdir(filepath.Join(root, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pool.chromes[filepath.Join(root, fmt.Sprintf("%d-launching", self))] = nil
+	os.Mkdir(filepath.Join(proc, "1234"), 0o755)
+	os.Mkdir(filepath.Join(proc, "4242"), 0o755)
+	cmdline := "/usr/bin/chromium\x00--user-data-dir=" + filepath.Join(root, "99999999-gone-worker") + "\x00--headless\x00"
+	os.WriteFile(filepath.Join(proc, "4242", "cmdline"), []byte(cmdline), 0o644)
+
+	reaped := pool.ReapOrphans(time.Now())
+
+	var want []string
+	for name, reap := range profiles {
+		_, err := os.Stat(filepath.Join(root, name))
+		if reap {
+			want = append(want, filepath.Join(root, name))
+			if err == nil {
+				t.Errorf("%s was kept, want it removed", name)
+			}
+		} else if err != nil {
+			t.Errorf("%s was removed, want it kept", name)
+		}
+	}
+	sort.Strings(want)
+	sort.Strings(reaped)
+	if fmt.Sprint(reaped) != fmt.Sprint(want) {
+		t.Errorf("ReapOrphans() = %v, want %v", reaped, want)
+	}
+	if len(killed) != 1 || killed[0] != 4242 {
+		t.Errorf("killed %v, want the gone worker's Chrome 4242", killed)
+	}
+}
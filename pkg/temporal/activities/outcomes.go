@@ -0,0 +1,114 @@
+package activities
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// outcomeWait is how long the elements an action made appear while
+// recording have to appear again when a run verifies outcomes
+const outcomeWait = 3 * time.Second
+
+// outcomePoll is how often the page is checked for them meanwhile
+const outcomePoll = 250 * time.Millisecond
+
+// maxOutcomeTexts caps the texts read per tag
+const maxOutcomeTexts = 200
+
+// visibleTextsScript returns the texts of the visible elements with each of
+// the given tags as JSON, keyed by tag
+const visibleTextsScript = `({tags, max}) => {
+	const texts = {};
+	for (const tag of tags) {
+		texts[tag] = [...document.querySelectorAll(tag)]
+			.filter((el) => el.getClientRects().length > 0 && getComputedStyle(el).visibility !== 'hidden')
+			.map((el) => (el.innerText || el.textContent || '').trim())
+			.filter((text) => text)
+			.slice(0, max);
+	}
+	return JSON.stringify(texts);
+}`
+
+// parseVisibleTexts decodes the result of visibleTextsScript
+func parseVisibleTexts(result string) (map[string][]string, error) {
+	var texts map[string][]string
+	if err := json.Unmarshal([]byte(result), &texts); err != nil {
+		return nil, fmt.Errorf("failed to parse page texts: %w", err)
+	}
+	return texts, nil
+}
+
+func (p *rodPage) VisibleTexts(tags []string) (map[string][]string, error) {
+	res, err := p.page.Eval(visibleTextsScript, map[string]interface{}{"tags": tags, "max": maxOutcomeTexts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read page texts: %w", err)
+	}
+	return parseVisibleTexts(res.Value.Str())
+}
+
+// missingOutcomes returns the elements that appeared after an action while
+// recording, its context, which haven't appeared after it within
+// outcomeWait
+func missingOutcomes(page BrowserPage, expected []models.SemanticTarget) ([]models.SemanticTarget, error) {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, target := range expected {
+		tag := strings.ToLower(target.Tag)
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	deadline := time.Now().Add(outcomeWait)
+	for {
+		texts, err := page.VisibleTexts(tags)
+		if err != nil {
+			return nil, err
+		}
+		missing := unmatchedOutcomes(expected, texts)
+		if len(missing) == 0 || time.Now().After(deadline) {
+			return missing, nil
+		}
+		time.Sleep(outcomePoll)
+	}
+}
+
+// unmatchedOutcomes returns the expected elements without an element of
+// the same tag and kind of text among texts
+func unmatchedOutcomes(expected []models.SemanticTarget, texts map[string][]string) []models.SemanticTarget {
+	var missing []models.SemanticTarget
+	for _, target := range expected {
+		found := false
+		for _, text := range texts[strings.ToLower(target.Tag)] {
+			if sameOutcome(target.Text, text) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, target)
+		}
+	}
+	return missing
+}
+
+// outcomeNumbers matches the numbers of a text, such as IDs and counts,
+// which differ from run to run
+var outcomeNumbers = regexp.MustCompile(`[0-9]+([.,][0-9]+)*`)
+
+// sameOutcome reports whether text shows the same kind of change as the
+// recorded text, ignoring case, spacing and numbers. Recorded texts are
+// truncated, so text only has to contain it.
+func sameOutcome(recorded, text string) bool {
+	normalize := func(s string) string {
+		s = outcomeNumbers.ReplaceAllString(strings.ToLower(s), "#")
+		return strings.Join(strings.Fields(s), " ")
+	}
+	return strings.Contains(normalize(text), normalize(recorded))
+}
@@ -0,0 +1,564 @@
+//go:build playwright
+
+package activities
+
+// Firefox and WebKit runs use playwright-go, which is only compiled into
+// the worker with the playwright tag. Build the worker with it to enable
+// them:
+//
+//	go run github.com/playwright-community/playwright-go/cmd/playwright install --with-deps firefox webkit
+//	go build -tags playwright ./cmd/worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/google/uuid"
+	"github.com/playwright-community/playwright-go"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+func init() {
+	driver := &playwrightDriver{}
+	RegisterDriver(models.BrowserFirefox, driver)
+	RegisterDriver(models.BrowserWebKit, driver)
+}
+
+// playwrightDriver launches Firefox and WebKit through one Playwright
+// server, started on first use
+type playwrightDriver struct {
+	once sync.Once
+	pw   *playwright.Playwright
+	err  error
+}
+
+func (d *playwrightDriver) Launch(ctx context.Context, opts LaunchOptions) (BrowserPage, error) {
+	d.once.Do(func() {
+		d.pw, d.err = playwright.Run()
+	})
+	if d.err != nil {
+		return nil, fmt.Errorf("failed to start playwright: %w", d.err)
+	}
+
+	browserType := d.pw.Firefox
+	if opts.Engine == models.BrowserWebKit {
+		browserType = d.pw.WebKit
+	}
+
+	browser, err := browserType.Launch(playwright.BrowserTypeLaunchOptions{
+		Headless: playwright.Bool(opts.Headless),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to launch %s: %w", opts.Engine, err)
+	}
+
+	pageOpts := pageOptions(opts)
+	if opts.StorageState != nil {
+		path, err := writeStorageState(opts.StorageState)
+		if err != nil {
+			browser.Close()
+			return nil, err
+		}
+		defer os.Remove(path)
+		pageOpts.StorageStatePath = playwright.String(path)
+	}
+
+	page, err := browser.NewPage(pageOpts)
+	if err != nil {
+		browser.Close()
+		return nil, fmt.Errorf("failed to create page: %w", err)
+	}
+	if opts.Stealth {
+		// The Chrome patches would make these engines inconsistent, so only
+		// the webdriver flag is hidden
+		script := playwright.Script{Content: playwright.String(webdriverPatch)}
+		if err := page.Context().AddInitScript(script); err != nil {
+			browser.Close()
+			return nil, fmt.Errorf("failed to apply stealth patches: %w", err)
+		}
+	}
+
+	pwPage := &playwrightPage{
+		browser: browser,
+		page:    page,
+		tabs:    []playwright.Page{page},
+		opts:    opts,
+		dialogs: &dialogLog{},
+	}
+	if opts.DownloadDir != "" {
+		if err := os.MkdirAll(opts.DownloadDir, 0755); err != nil {
+			browser.Close()
+			return nil, fmt.Errorf("failed to create download dir: %w", err)
+		}
+		pwPage.downloads = &downloadLog{dir: opts.DownloadDir, pending: make(map[string]*proto.BrowserDownloadWillBegin)}
+	}
+	pwPage.attach(page)
+
+	// Tabs the run's pages open wait for SwitchTabs to move to them
+	page.Context().OnPage(func(opened playwright.Page) {
+		pwPage.attach(opened)
+		pwPage.mu.Lock()
+		defer pwPage.mu.Unlock()
+		pwPage.opened = append(pwPage.opened, opened)
+	})
+	return pwPage, nil
+}
+
+// writeStorageState saves a storage state for Playwright to load, which
+// reads the format from a file
+func writeStorageState(state *models.StorageState) (string, error) {
+	f, err := os.CreateTemp("", "storage-state-*.json")
+	if err != nil {
+		return "", fmt.Errorf("failed to write storage state: %w", err)
+	}
+	defer f.Close()
+	if err := json.NewEncoder(f).Encode(state); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write storage state: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// webdriverPatch hides navigator.webdriver, which Playwright sets
+const webdriverPatch = `Object.defineProperty(Object.getPrototypeOf(navigator), 'webdriver', {get: () => undefined, configurable: true})`
+
+// pageOptions emulates the device, user agent, locale and timezone and sets
+// the proxy, which Playwright applies when the page's context is created.
+// Firefox ignores the mobile flag.
+func pageOptions(opts LaunchOptions) playwright.BrowserNewPageOptions {
+	pageOpts := playwright.BrowserNewPageOptions{}
+	if device := opts.Device; device != nil {
+		pageOpts.Viewport = &playwright.Size{Width: device.Width, Height: device.Height}
+		pageOpts.DeviceScaleFactor = playwright.Float(device.Scale)
+		pageOpts.HasTouch = playwright.Bool(device.Touch)
+		if device.Mobile && opts.Engine != models.BrowserFirefox {
+			pageOpts.IsMobile = playwright.Bool(true)
+		}
+	}
+	if userAgent := opts.userAgent(); userAgent != "" {
+		pageOpts.UserAgent = playwright.String(userAgent)
+	}
+	if opts.Locale != "" {
+		pageOpts.Locale = playwright.String(opts.Locale)
+	}
+	if opts.Timezone != "" {
+		pageOpts.TimezoneId = playwright.String(opts.Timezone)
+	}
+	if proxy := opts.Proxy; proxy != nil {
+		pageOpts.Proxy = &playwright.Proxy{Server: proxy.Server}
+		if proxy.Bypass != "" {
+			pageOpts.Proxy.Bypass = playwright.String(proxy.Bypass)
+		}
+		if proxy.Username != "" {
+			pageOpts.Proxy.Username = playwright.String(proxy.Username)
+			pageOpts.Proxy.Password = playwright.String(proxy.Password)
+		}
+	}
+	if creds := opts.HTTPCredentials; creds != nil {
+		pageOpts.HttpCredentials = &playwright.HttpCredentials{
+			Username: creds.Username,
+			Password: creds.Password,
+		}
+		if creds.Origin != "" {
+			pageOpts.HttpCredentials.Origin = playwright.String(creds.Origin)
+		}
+	}
+	return pageOpts
+}
+
+// playwrightPage drives a Firefox or WebKit page with Playwright
+type playwrightPage struct {
+	browser   playwright.Browser
+	page      playwright.Page   // the tab actions drive
+	tabs      []playwright.Page // the tabs followed, page last
+	opts      LaunchOptions
+	dialogs   *dialogLog
+	downloads *downloadLog // nil when downloads are not kept
+
+	mu     sync.Mutex
+	opened []playwright.Page // not yet followed, oldest first
+}
+
+// attach answers a tab's dialogs and saves its downloads. Playwright
+// dismisses dialogs nobody handles, so they are answered first.
+func (p *playwrightPage) attach(page playwright.Page) {
+	page.OnDialog(func(dialog playwright.Dialog) {
+		accept, text := dialogAnswer(p.opts.Dialogs, p.opts.DialogAnswer, dialog.Type())
+		if accept {
+			if text == "" {
+				text = dialog.DefaultValue()
+			}
+			_ = dialog.Accept(text)
+		} else {
+			_ = dialog.Dismiss()
+		}
+		p.dialogs.add(models.DialogEvent{Type: dialog.Type(), Message: dialog.Message(), Accepted: accept})
+	})
+	if p.downloads != nil {
+		page.OnDownload(p.saveDownload)
+	}
+}
+
+func (p *playwrightPage) SwitchTabs() []models.TabSwitch {
+	var switches []models.TabSwitch
+	for len(p.tabs) > 1 && p.page.IsClosed() {
+		p.tabs = p.tabs[:len(p.tabs)-1]
+		p.page = p.tabs[len(p.tabs)-1]
+		switches = append(switches, models.TabSwitch{URL: p.page.URL(), Closed: true})
+	}
+
+	p.mu.Lock()
+	opened := p.opened
+	p.opened = nil
+	p.mu.Unlock()
+	for _, page := range opened {
+		if page.IsClosed() {
+			continue
+		}
+		p.tabs = append(p.tabs, page)
+		p.page = page
+		switches = append(switches, models.TabSwitch{URL: page.URL()})
+	}
+	return switches
+}
+
+// saveDownload moves a download into the run's directory once it completes,
+// tracked like Chrome's downloads by a GUID of its own. SaveAs blocks until
+// then, so it runs outside Playwright's event handler.
+func (p *playwrightPage) saveDownload(download playwright.Download) {
+	guid := uuid.New().String()
+	p.downloads.begin(&proto.BrowserDownloadWillBegin{
+		GUID:              guid,
+		URL:               download.URL(),
+		SuggestedFilename: download.SuggestedFilename(),
+	})
+	go func() {
+		err := download.SaveAs(filepath.Join(p.downloads.dir, guid))
+		p.downloads.finish(guid, err == nil)
+	}()
+}
+
+func (p *playwrightPage) Info() (string, string, error) {
+	title, err := p.page.Title()
+	return p.page.URL(), title, err
+}
+
+func (p *playwrightPage) Navigate(url string) error {
+	_, err := p.page.Goto(url)
+	return err
+}
+
+// locator finds an element by selector or locator, or by tag and text.
+// Playwright understands xpath= locators itself.
+func (p *playwrightPage) locator(selector, tag, text string) playwright.Locator {
+	if selector == "" && text != "" {
+		selector = textLocator + text
+	}
+	if strings.HasPrefix(selector, textLocator) {
+		return p.page.Locator(tag).Filter(playwright.LocatorFilterOptions{HasText: strings.TrimPrefix(selector, textLocator)}).First()
+	}
+	return p.page.Locator(selector).First()
+}
+
+func (p *playwrightPage) Locate(candidates []string, tag string) (int, error) {
+	timeout := float64(candidateWait.Milliseconds())
+	for i, candidate := range candidates {
+		err := p.locator(candidate, tag, "").WaitFor(playwright.LocatorWaitForOptions{State: playwright.WaitForSelectorStateAttached, Timeout: &timeout})
+		if err == nil {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("no element matches %q", candidates)
+}
+
+// Click and Fill leave the stability checks to Playwright, which scrolls the
+// element into view and waits for it to be stable and receive events unless
+// forced
+func (p *playwrightPage) Click(selector, tag, text string) error {
+	opts := playwright.LocatorClickOptions{Force: playwright.Bool(p.opts.SkipStabilityChecks)}
+	if err := p.locator(selector, tag, text).Click(opts); err != nil {
+		return fmt.Errorf("element not found: %s (text: %s): %w", selector, text, err)
+	}
+	return nil
+}
+
+func (p *playwrightPage) Fill(selector, tag, text, value string) error {
+	opts := playwright.LocatorFillOptions{Force: playwright.Bool(p.opts.SkipStabilityChecks)}
+	if err := p.locator(selector, tag, text).Fill(value, opts); err != nil {
+		return fmt.Errorf("element not found: %s (text: %s): %w", selector, text, err)
+	}
+	return nil
+}
+
+func (p *playwrightPage) Focus(selector, tag, text string) error {
+	return p.locator(selector, tag, text).Focus()
+}
+
+func (p *playwrightPage) Blur(selector, tag, text string) error {
+	return p.locator(selector, tag, text).Blur()
+}
+
+// fieldTextScript returns a form field's value and other elements' text,
+// as rod reads an element's text
+const fieldTextScript = `(el) => ['INPUT', 'TEXTAREA', 'SELECT'].includes(el.tagName) ? el.value : el.innerText`
+
+func (p *playwrightPage) Read(selector, tag, text, attribute string) (string, error) {
+	locator := p.locator(selector, tag, text)
+	if attribute != "" {
+		value, err := locator.GetAttribute(attribute)
+		if err != nil {
+			return "", fmt.Errorf("element not found: %s (text: %s): %w", selector, text, err)
+		}
+		return value, nil
+	}
+	res, err := locator.Evaluate(fieldTextScript, nil)
+	if err != nil {
+		return "", fmt.Errorf("element not found: %s (text: %s): %w", selector, text, err)
+	}
+	value, _ := res.(string)
+	return value, nil
+}
+
+func (p *playwrightPage) Press(key string) error {
+	return p.page.Keyboard().Press(playwrightCombo(parseKeyCombo(key)))
+}
+
+func (p *playwrightPage) Shortcut(key string) error {
+	return p.page.Keyboard().Press("Control+" + playwrightKey(key))
+}
+
+// Copy returns the selection Ctrl+C copied, since clipboard access can't
+// be granted to pages in every engine
+func (p *playwrightPage) Copy() (string, error) {
+	selection, _ := p.page.Evaluate(selectionScript)
+	if err := p.Shortcut("c"); err != nil {
+		return "", err
+	}
+	text, _ := selection.(string)
+	return text, nil
+}
+
+func (p *playwrightPage) InsertText(text string) error {
+	return p.page.Keyboard().InsertText(text)
+}
+
+func (p *playwrightPage) Screenshot() ([]byte, error) {
+	return p.page.Screenshot(playwright.PageScreenshotOptions{
+		FullPage: playwright.Bool(true),
+	})
+}
+
+func (p *playwrightPage) Summary() (*models.PageSummary, error) {
+	url, title, err := p.Info()
+	if err != nil {
+		return nil, err
+	}
+	res, err := p.page.Evaluate(pageSummaryScript, maxSummaryElements)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize page: %w", err)
+	}
+	result, _ := res.(string)
+	elements, err := parsePageElements(result)
+	if err != nil {
+		return nil, err
+	}
+	return &models.PageSummary{URL: url, Title: title, Elements: elements}, nil
+}
+
+func (p *playwrightPage) DOMSnapshot() (string, error) {
+	res, err := p.page.Evaluate(domSnapshotScript, maxDOMSnapshot)
+	if err != nil {
+		return "", fmt.Errorf("failed to capture DOM: %w", err)
+	}
+	html, _ := res.(string)
+	return html, nil
+}
+
+func (p *playwrightPage) Metrics() (*models.PageMetrics, error) {
+	res, err := p.page.Evaluate(pageMetricsScript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure page: %w", err)
+	}
+	result, _ := res.(string)
+	return parsePageMetrics(result)
+}
+
+func (p *playwrightPage) VisibleTexts(tags []string) (map[string][]string, error) {
+	res, err := p.page.Evaluate(visibleTextsScript, map[string]interface{}{"tags": tags, "max": maxOutcomeTexts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read page texts: %w", err)
+	}
+	result, _ := res.(string)
+	return parseVisibleTexts(result)
+}
+
+func (p *playwrightPage) Audit(axe string) (string, error) {
+	if _, err := p.page.AddScriptTag(playwright.PageAddScriptTagOptions{Content: &axe}); err != nil {
+		return "", fmt.Errorf("failed to inject axe-core: %w", err)
+	}
+	res, err := p.page.Evaluate(axeRunScript)
+	if err != nil {
+		return "", fmt.Errorf("accessibility audit failed: %w", err)
+	}
+	report, _ := res.(string)
+	return report, nil
+}
+
+func (p *playwrightPage) Wait(action func() error, conds ...models.WaitCondition) error {
+	// Playwright expects responses and navigations around the action that
+	// causes them
+	for _, cond := range conds {
+		if cond.Type != models.WaitRequest {
+			continue
+		}
+		cond, inner := cond, action
+		action = func() error {
+			timeout := float64(cond.Duration().Milliseconds())
+			resp, err := p.page.ExpectResponse(func(r playwright.Response) bool {
+				return requestMatches(cond, r.Request().Method(), r.URL())
+			}, inner, playwright.PageExpectResponseOptions{Timeout: &timeout})
+			if err != nil {
+				return fmt.Errorf("wait for %s: %w", cond, err)
+			}
+			return cond.CheckResponse(resp.Status())
+		}
+	}
+	for _, cond := range conds {
+		if cond.Type == models.WaitNavigation {
+			timeout := float64(cond.Duration().Milliseconds())
+			if _, err := p.page.ExpectNavigation(action, playwright.PageExpectNavigationOptions{Timeout: &timeout}); err != nil {
+				return fmt.Errorf("wait for %s: %w", cond, err)
+			}
+			action = nil
+			break
+		}
+	}
+	if action != nil {
+		if err := action(); err != nil {
+			return err
+		}
+	}
+
+	for _, cond := range conds {
+		timeout := float64(cond.Duration().Milliseconds())
+		var err error
+		switch cond.Type {
+		case models.WaitNetworkIdle:
+			err = p.page.WaitForLoadState(playwright.PageWaitForLoadStateOptions{State: playwright.LoadStateNetworkidle, Timeout: &timeout})
+		case models.WaitVisible:
+			err = p.page.Locator(cond.Selector).First().WaitFor(playwright.LocatorWaitForOptions{State: playwright.WaitForSelectorStateVisible, Timeout: &timeout})
+		case models.WaitHidden:
+			err = p.page.Locator(cond.Selector).First().WaitFor(playwright.LocatorWaitForOptions{State: playwright.WaitForSelectorStateHidden, Timeout: &timeout})
+		case models.WaitURL:
+			var pattern *regexp.Regexp
+			if pattern, err = regexp.Compile(cond.Pattern); err == nil {
+				err = p.page.WaitForURL(pattern, playwright.PageWaitForURLOptions{Timeout: &timeout})
+			}
+		case models.WaitJS:
+			_, err = p.page.WaitForFunction(cond.Script, nil, playwright.PageWaitForFunctionOptions{Timeout: &timeout})
+		}
+		if err != nil {
+			return fmt.Errorf("wait for %s: %w", cond, err)
+		}
+	}
+	return nil
+}
+
+func (p *playwrightPage) StorageState() (*models.StorageState, error) {
+	// Playwright writes the state in the format StorageState mirrors
+	f, err := os.CreateTemp("", "storage-state-*.json")
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	if _, err := p.page.Context().StorageState(f.Name()); err != nil {
+		return nil, fmt.Errorf("failed to capture storage state: %w", err)
+	}
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return nil, err
+	}
+	var state models.StorageState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse storage state: %w", err)
+	}
+	return &state, nil
+}
+
+func (p *playwrightPage) Dialogs() []models.DialogEvent {
+	return p.dialogs.take()
+}
+
+func (p *playwrightPage) Downloads() []models.DownloadedFile {
+	return p.downloads.take(downloadWait)
+}
+
+func (p *playwrightPage) Close() error {
+	return p.browser.Close()
+}
+
+// playwrightKeys maps recorded key names to Playwright's
+var playwrightKeys = map[string]string{
+	"enter":      "Enter",
+	"tab":        "Tab",
+	"escape":     "Escape",
+	"backspace":  "Backspace",
+	"arrowup":    "ArrowUp",
+	"arrowdown":  "ArrowDown",
+	"arrowleft":  "ArrowLeft",
+	"arrowright": "ArrowRight",
+	"delete":     "Delete",
+	"home":       "Home",
+	"end":        "End",
+	"pageup":     "PageUp",
+	"pagedown":   "PageDown",
+	"space":      "Space",
+}
+
+// playwrightModifiers maps the modifiers of a key combination to Playwright's
+var playwrightModifiers = map[string]string{
+	modifierCtrl:  "Control",
+	modifierAlt:   "Alt",
+	modifierShift: "Shift",
+	modifierMeta:  "Meta",
+}
+
+// playwrightCombo converts a key combination to Playwright's notation, such
+// as "Control+Shift+K". Playwright's letters are case-sensitive, so they
+// are uppercased when Shift is held.
+func playwrightCombo(combo keyCombo) string {
+	key := playwrightKey(combo.Key)
+	parts := make([]string, 0, len(combo.Modifiers)+1)
+	for _, modifier := range combo.Modifiers {
+		parts = append(parts, playwrightModifiers[modifier])
+		if modifier == modifierShift && len(key) == 1 {
+			key = strings.ToUpper(key)
+		}
+	}
+	return strings.Join(append(parts, key), "+")
+}
+
+// playwrightKey converts a key name to a Playwright key, defaulting to
+// Enter like getKeyFromValue
+func playwrightKey(value string) string {
+	if key, ok := playwrightKeys[strings.ToLower(value)]; ok {
+		return key
+	}
+	if fn, ok := functionKey(value); ok {
+		return fmt.Sprintf("F%d", fn)
+	}
+	if len(value) == 1 {
+		return value
+	}
+	return "Enter"
+}
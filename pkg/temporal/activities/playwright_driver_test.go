@@ -0,0 +1,41 @@
+//go:build playwright
+
+package activities
+
+import "testing"
+
+func TestPlaywrightCombo(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"Ctrl+Shift+K", "Control+Shift+K"},
+		{"Alt+ARROWDOWN", "Alt+ArrowDown"},
+		{"Meta+a", "Meta+a"},
+		{"Shift+F5", "Shift+F5"},
+		{"+", "+"},
+	}
+	for _, tt := range tests {
+		if got := playwrightCombo(parseKeyCombo(tt.value)); got != tt.want {
+			t.Errorf("playwrightCombo(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestPlaywrightKey(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"ENTER", "Enter"},
+		{"tab", "Tab"},
+		{"F12", "F12"},
+		{"x", "x"},
+		{"unknown", "Enter"},
+	}
+	for _, tt := range tests {
+		if got := playwrightKey(tt.value); got != tt.want {
+			t.Errorf("playwrightKey(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package activities
+
+import (
+	"strings"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// newContext opens an incognito context in a shared browser, routed through
+// the run's proxy when it has one
+func newContext(browser *rod.Browser, proxy *models.ProxyConfig) (*rod.Browser, error) {
+	if proxy == nil {
+		return browser.Incognito()
+	}
+
+	res, err := proto.TargetCreateBrowserContext{
+		ProxyServer:     proxy.Server,
+		ProxyBypassList: chromeBypassList(proxy.Bypass),
+	}.Call(browser)
+	if err != nil {
+		return nil, err
+	}
+
+	incognito := *browser
+	incognito.BrowserContextID = res.BrowserContextID
+	return &incognito, nil
+}
+
+// chromeBypassList converts a comma-separated bypass list to Chrome's,
+// which is separated by semicolons
+func chromeBypassList(bypass string) string {
+	var hosts []string
+	for _, host := range strings.Split(bypass, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return strings.Join(hosts, ";")
+}
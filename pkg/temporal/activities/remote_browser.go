@@ -0,0 +1,103 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/cdp"
+	"github.com/go-rod/rod/lib/launcher"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// remoteConnectTimeout bounds the websocket handshake with a remote browser
+const remoteConnectTimeout = 30 * time.Second
+
+// RemoteEndpoints is a fleet of remote Chrome endpoints, such as a Selenium
+// grid or browserless.io, handed out round-robin
+type RemoteEndpoints struct {
+	urls []string
+	next uint32
+}
+
+// NewRemoteEndpoints creates a fleet from a comma-separated list of endpoints.
+// Each is a DevTools WebSocket URL (ws:// or wss://) used as is, or an HTTP
+// address such as http://chrome:9222 resolved through /json/version.
+func NewRemoteEndpoints(list string) *RemoteEndpoints {
+	r := &RemoteEndpoints{}
+	for _, u := range strings.Split(list, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			r.urls = append(r.urls, u)
+		}
+	}
+	return r
+}
+
+// Len returns the number of endpoints, 0 for a nil fleet
+func (r *RemoteEndpoints) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.urls)
+}
+
+// order returns every endpoint, starting with the one whose turn it is, so a
+// caller can fail over to the rest
+func (r *RemoteEndpoints) order() []string {
+	n := len(r.urls)
+	start := int(atomic.AddUint32(&r.next, 1)-1) % n
+	return append(append([]string{}, r.urls[start:]...), r.urls[:start]...)
+}
+
+// connectRemote connects to a remote browser and opens an incognito context
+// for the run, routed through proxy when it is set. The returned disconnect
+// drops the connection without closing the remote browser, which other
+// workers may share.
+func connectRemote(ctx context.Context, endpoint string, proxy *models.ProxyConfig) (*rod.Browser, func(), error) {
+	wsURL := endpoint
+	if !strings.HasPrefix(endpoint, "ws://") && !strings.HasPrefix(endpoint, "wss://") {
+		var err error
+		if wsURL, err = launcher.ResolveURL(endpoint); err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve browser endpoint %s: %w", endpoint, err)
+		}
+	}
+
+	connectCtx, cancel := context.WithTimeout(ctx, remoteConnectTimeout)
+	defer cancel()
+	ws := &cdp.WebSocket{}
+	if err := ws.Connect(connectCtx, wsURL, nil); err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to browser endpoint %s: %w", endpoint, err)
+	}
+	disconnect := func() { ws.Close() }
+
+	remote := rod.New().Client(cdp.New().Start(ws))
+	if err := remote.Connect(); err != nil {
+		disconnect()
+		return nil, nil, fmt.Errorf("failed to connect to browser endpoint %s: %w", endpoint, err)
+	}
+
+	browser, err := newContext(remote, proxy)
+	if err != nil {
+		disconnect()
+		return nil, nil, fmt.Errorf("failed to create browser context on %s: %w", endpoint, err)
+	}
+	return browser, disconnect, nil
+}
+
+// connectFleet connects to the fleet's next endpoint, trying the others in
+// turn when it is unreachable
+func (r *RemoteEndpoints) connectFleet(ctx context.Context, proxy *models.ProxyConfig) (*rod.Browser, func(), string, error) {
+	var lastErr error
+	for _, endpoint := range r.order() {
+		browser, disconnect, err := connectRemote(ctx, endpoint, proxy)
+		if err == nil {
+			return browser, disconnect, endpoint, nil
+		}
+		lastErr = err
+	}
+	return nil, nil, "", lastErr
+}
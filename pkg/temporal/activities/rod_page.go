@@ -0,0 +1,358 @@
+package activities
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/input"
+	"github.com/go-rod/rod/lib/proto"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// stealthJS hides the automation signals of headless Chrome from the
+// scripts of every document a stealth run loads
+//
+//go:embed stealth.js
+var stealthJS string
+
+// rodPage drives a Chromium page with Go Rod
+type rodPage struct {
+	browser   *rod.Browser
+	page      *rod.Page   // the tab actions drive
+	tabs      []*rod.Page // the tabs followed, page last
+	tracker   *tabTracker
+	opts      LaunchOptions
+	dialogs   *dialogLog
+	downloads *downloadLog // nil when downloads are not kept
+}
+
+func (p *rodPage) Info() (string, string, error) {
+	info, err := p.page.Info()
+	if err != nil {
+		return "", "", err
+	}
+	return info.URL, info.Title, nil
+}
+
+func (p *rodPage) Navigate(url string) error {
+	return p.page.Navigate(url)
+}
+
+// findElement finds an element by CSS selector, or by text= or xpath=
+// locator
+func findElement(page *rod.Page, locator, tag string) (*rod.Element, error) {
+	switch {
+	case strings.HasPrefix(locator, textLocator):
+		return page.ElementR(tag, strings.TrimPrefix(locator, textLocator))
+	case strings.HasPrefix(locator, xpathLocator):
+		return page.ElementX(strings.TrimPrefix(locator, xpathLocator))
+	}
+	return page.Element(locator)
+}
+
+func (p *rodPage) Locate(candidates []string, tag string) (int, error) {
+	for i, candidate := range candidates {
+		page := p.page.Timeout(candidateWait)
+		_, err := findElement(page, candidate, tag)
+		page.CancelTimeout()
+		if err == nil {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("no element matches %q", candidates)
+}
+
+// element finds an element by selector or locator, or by tag and text,
+// waiting up to elementWait for it to appear and become visible
+func (p *rodPage) element(selector, tag, text string) (*rod.Element, error) {
+	if selector == "" && text != "" {
+		selector = textLocator + text
+	}
+	page := p.page.Timeout(elementWait)
+	elem, err := findElement(page, selector, tag)
+	if err != nil {
+		page.CancelTimeout()
+		return nil, fmt.Errorf("element not found: %s (text: %s)", selector, text)
+	}
+	if err := elem.WaitVisible(); err != nil {
+		page.CancelTimeout()
+		return nil, fmt.Errorf("element not visible: %s (text: %s)", selector, text)
+	}
+	return elem.CancelTimeout(), nil
+}
+
+func (p *rodPage) Click(selector, tag, text string) error {
+	elem, err := p.element(selector, tag, text)
+	if err != nil {
+		return err
+	}
+	if err := p.settle(elem); err != nil {
+		return err
+	}
+	return elem.Click(proto.InputMouseButtonLeft, 1)
+}
+
+func (p *rodPage) Fill(selector, tag, text, value string) error {
+	elem, err := p.element(selector, tag, text)
+	if err != nil {
+		return err
+	}
+	if err := p.settle(elem); err != nil {
+		return err
+	}
+	// Clear existing text and input new value
+	if err := elem.SelectAllText(); err != nil {
+		return err
+	}
+	return elem.Input(value)
+}
+
+func (p *rodPage) Focus(selector, tag, text string) error {
+	elem, err := p.element(selector, tag, text)
+	if err != nil {
+		return err
+	}
+	return elem.Focus()
+}
+
+func (p *rodPage) Blur(selector, tag, text string) error {
+	elem, err := p.element(selector, tag, text)
+	if err != nil {
+		return err
+	}
+	return elem.Blur()
+}
+
+func (p *rodPage) Read(selector, tag, text, attribute string) (string, error) {
+	elem, err := p.element(selector, tag, text)
+	if err != nil {
+		return "", err
+	}
+	if attribute == "" {
+		return elem.Text()
+	}
+	value, err := elem.Attribute(attribute)
+	if err != nil {
+		return "", err
+	}
+	if value == nil {
+		return "", fmt.Errorf("element has no %s attribute: %s", attribute, selector)
+	}
+	return *value, nil
+}
+
+func (p *rodPage) Press(key string) error {
+	combo := parseKeyCombo(key)
+	if len(combo.Modifiers) == 0 {
+		return p.page.Keyboard.Press(getKeyFromValue(combo.Key))
+	}
+	modifiers := make([]input.Key, 0, len(combo.Modifiers))
+	for _, modifier := range combo.Modifiers {
+		modifiers = append(modifiers, rodModifiers[modifier])
+	}
+	return p.page.KeyActions().Press(modifiers...).Type(getKeyFromValue(combo.Key)).Do()
+}
+
+func (p *rodPage) Shortcut(key string) error {
+	return p.page.KeyActions().Press(input.ControlLeft).Type(getKeyFromValue(key)).Do()
+}
+
+func (p *rodPage) Screenshot() ([]byte, error) {
+	return p.page.Screenshot(true, &proto.PageCaptureScreenshot{
+		Format: proto.PageCaptureScreenshotFormatPng,
+	})
+}
+
+func (p *rodPage) StorageState() (*models.StorageState, error) {
+	cookies, err := p.browser.GetCookies()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cookies: %w", err)
+	}
+	state := &models.StorageState{
+		Cookies: storageCookies(cookies),
+		Origins: []models.StorageOrigin{},
+	}
+
+	// Pages without an origin, like about:blank, have no localStorage
+	res, err := p.page.Eval(localStorageCapture)
+	if err != nil {
+		return state, nil
+	}
+	var origin models.StorageOrigin
+	if json.Unmarshal([]byte(res.Value.Str()), &origin) == nil && origin.Origin != "null" && len(origin.LocalStorage) > 0 {
+		state.Origins = append(state.Origins, origin)
+	}
+	return state, nil
+}
+
+func (p *rodPage) Dialogs() []models.DialogEvent {
+	return p.dialogs.take()
+}
+
+func (p *rodPage) Downloads() []models.DownloadedFile {
+	return p.downloads.take(downloadWait)
+}
+
+func (p *rodPage) Close() error {
+	p.tracker.close()
+	p.downloads.close()
+	return p.browser.Close()
+}
+
+// openPage opens the run's page in browser with its storage state and
+// download capture, and follows the tabs it opens
+func openPage(browser *rod.Browser, opts LaunchOptions) (*rodPage, error) {
+	page, err := browser.Page(proto.TargetCreateTarget{URL: "about:blank"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create page: %w", err)
+	}
+	rp := &rodPage{browser: browser, page: page, tabs: []*rod.Page{page}, opts: opts, dialogs: &dialogLog{}}
+	if err := rp.prepare(page); err != nil {
+		return nil, err
+	}
+	if err := injectStorageState(page, opts.StorageState); err != nil {
+		return nil, err
+	}
+	if opts.DownloadDir != "" {
+		if rp.downloads, err = captureDownloads(browser, opts.DownloadDir); err != nil {
+			return nil, fmt.Errorf("failed to capture downloads: %w", err)
+		}
+		rp.downloads.watch(page.FrameID)
+	}
+	rp.tracker = watchTabs(browser, page)
+	return rp, nil
+}
+
+// prepare applies the run's stealth patches, emulation, authentication and
+// dialog policy to a tab before it is driven
+func (p *rodPage) prepare(page *rod.Page) error {
+	if p.opts.Stealth {
+		if _, err := page.EvalOnNewDocument(stealthJS); err != nil {
+			return fmt.Errorf("failed to apply stealth patches: %w", err)
+		}
+	}
+	if err := emulate(page, p.opts); err != nil {
+		return fmt.Errorf("failed to apply browser emulation: %w", err)
+	}
+	if err := handleAuth(page, p.opts.Proxy, p.opts.HTTPCredentials); err != nil {
+		return fmt.Errorf("failed to set up authentication: %w", err)
+	}
+	handleDialogs(page, p.opts.Dialogs, p.opts.DialogAnswer, p.dialogs)
+	return nil
+}
+
+// emulate applies the device, user agent, locale and timezone to a page
+// before it navigates
+func emulate(page *rod.Page, opts LaunchOptions) error {
+	if device := opts.Device; device != nil {
+		err := page.SetViewport(&proto.EmulationSetDeviceMetricsOverride{
+			Width:             device.Width,
+			Height:            device.Height,
+			DeviceScaleFactor: device.Scale,
+			Mobile:            device.Mobile,
+		})
+		if err != nil {
+			return err
+		}
+		if device.Touch {
+			if err := (proto.EmulationSetTouchEmulationEnabled{Enabled: true}).Call(page); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Accept-Language is part of the user agent override, which keeps the
+	// page's current user agent when only the locale changes. Stealth also
+	// drops the headless marker from it.
+	userAgent := opts.userAgent()
+	if userAgent == "" && (opts.Locale != "" || opts.Stealth) {
+		res, err := page.Eval(`() => navigator.userAgent`)
+		if err != nil {
+			return err
+		}
+		userAgent = res.Value.Str()
+	}
+	if opts.Stealth {
+		userAgent = strings.Replace(userAgent, "HeadlessChrome", "Chrome", 1)
+	}
+	if userAgent != "" {
+		err := page.SetUserAgent(&proto.NetworkSetUserAgentOverride{
+			UserAgent:      userAgent,
+			AcceptLanguage: opts.Locale,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if opts.Locale != "" {
+		if err := (proto.EmulationSetLocaleOverride{Locale: opts.Locale}).Call(page); err != nil {
+			return err
+		}
+	}
+	if opts.Timezone != "" {
+		if err := (proto.EmulationSetTimezoneOverride{TimezoneID: opts.Timezone}).Call(page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getKeyFromValue converts a key name to rod input key
+// rodModifiers maps the modifiers of a key combination to rod's keys
+var rodModifiers = map[string]input.Key{
+	modifierCtrl:  input.ControlLeft,
+	modifierAlt:   input.AltLeft,
+	modifierShift: input.ShiftLeft,
+	modifierMeta:  input.MetaLeft,
+}
+
+var rodFunctionKeys = [12]input.Key{
+	input.F1, input.F2, input.F3, input.F4, input.F5, input.F6,
+	input.F7, input.F8, input.F9, input.F10, input.F11, input.F12,
+}
+
+func getKeyFromValue(value string) input.Key {
+	if fn, ok := functionKey(value); ok {
+		return rodFunctionKeys[fn-1]
+	}
+	switch strings.ToLower(value) {
+	case "enter":
+		return input.Enter
+	case "tab":
+		return input.Tab
+	case "escape":
+		return input.Escape
+	case "backspace":
+		return input.Backspace
+	case "arrowup":
+		return input.ArrowUp
+	case "arrowdown":
+		return input.ArrowDown
+	case "arrowleft":
+		return input.ArrowLeft
+	case "arrowright":
+		return input.ArrowRight
+	case "delete":
+		return input.Delete
+	case "home":
+		return input.Home
+	case "end":
+		return input.End
+	case "pageup":
+		return input.PageUp
+	case "pagedown":
+		return input.PageDown
+	case "space", " ":
+		return input.Space
+	default:
+		// For single characters, return as-is
+		if len(value) == 1 {
+			return input.Key(value[0])
+		}
+		return input.Enter // Default fallback
+	}
+}
@@ -0,0 +1,200 @@
+package activities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/activity"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+	"dev/bravebird/browser-automation-go/pkg/temporal/workflows"
+)
+
+// RecordRunActivity records a run the API didn't start, such as one a
+// schedule started, as running
+func (a *Activities) RecordRunActivity(ctx context.Context, run models.WorkflowRun) error {
+	if a.DB == nil {
+		activity.GetLogger(ctx).Warn("Run not recorded, the worker has no MYSQL_DSN", "runID", run.ID)
+		return nil
+	}
+
+	if err := a.DB.CreateWorkflowRun(ctx, &run); err != nil {
+		return err
+	}
+	return a.DB.UpdateWorkflowRunStarted(ctx, run.ID, run.TemporalWorkflowID, run.TemporalRunID)
+}
+
+// RecordRunStartedActivity records a run the API created but left for its
+// workflow to start, such as a batch's, as running
+func (a *Activities) RecordRunStartedActivity(ctx context.Context, input workflows.RunStartedInput) error {
+	if a.DB == nil {
+		return nil
+	}
+	return a.DB.UpdateWorkflowRunStarted(ctx, input.RunID, input.TemporalWorkflowID, "")
+}
+
+// UpdateRunStatusActivity records how a run ended for the runs no client
+// is expected to stream, such as scheduled ones
+func (a *Activities) UpdateRunStatusActivity(ctx context.Context, input workflows.RunStatusInput) error {
+	if a.DB == nil {
+		return nil
+	}
+	return a.DB.UpdateWorkflowRunStatus(ctx, input.RunID, input.Status, input.ErrorMessage)
+}
+
+// RecordRunOutputsActivity stores the values a run produced, such as the
+// ones its extract actions read, and its dataset with the run
+func (a *Activities) RecordRunOutputsActivity(ctx context.Context, input workflows.RunOutputsInput) error {
+	if a.DB == nil {
+		activity.GetLogger(ctx).Warn("Run outputs not recorded, the worker has no MYSQL_DSN", "runID", input.RunID)
+		return nil
+	}
+	return a.DB.UpdateWorkflowRunOutputs(ctx, input.RunID, input.Outputs, input.Dataset)
+}
+
+// RecordActionResultsActivity stores the results of a run's actions with
+// the run, for its reports and its workflow's analytics
+func (a *Activities) RecordActionResultsActivity(ctx context.Context, input workflows.ActionResultsInput) error {
+	if a.DB == nil {
+		activity.GetLogger(ctx).Warn("Action results not recorded, the worker has no MYSQL_DSN", "runID", input.RunID)
+		return nil
+	}
+	for i := range input.Results {
+		input.Results[i].ID = uuid.New().String()
+		input.Results[i].RunID = input.RunID
+	}
+	return a.DB.RecordActionResults(ctx, input.RunID, input.Results)
+}
+
+// progressClient publishes run progress, which must not hold up the run
+var progressClient = &http.Client{Timeout: 3 * time.Second}
+
+// PublishProgressActivity tells the API at APIURL that a run's progress
+// changed, so it pushes the run to the clients streaming it. Without an
+// APIURL and ProgressToken the API polls runs instead.
+func (a *Activities) PublishProgressActivity(ctx context.Context, input workflows.ProgressInput) error {
+	return a.publishProgress(ctx, input.RunID, nil)
+}
+
+// publishProgress pushes a run's progress, along with the phase its action
+// moved to when one is given. The phase is sent rather than queried since
+// the run may not have applied its signal yet.
+func (a *Activities) publishProgress(ctx context.Context, runID string, phase *models.ActionPhaseUpdate) error {
+	if a.APIURL == "" || a.ProgressToken == "" || runID == "" {
+		return nil
+	}
+	var body io.Reader
+	if phase != nil {
+		data, err := json.Marshal(phase)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", a.APIURL+"/api/runs/"+url.PathEscape(runID)+"/progress", body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+a.ProgressToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := progressClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("api returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// reportPhase tells the run executing an action that the action moved to
+// another phase, for its progress, and records it as the activity's
+// heartbeat. Reports that fail are only logged.
+func (a *Activities) reportPhase(ctx context.Context, input workflows.ActionInput, phase models.ActionPhase) {
+	info := activity.GetInfo(ctx)
+	activity.RecordHeartbeat(ctx, string(phase))
+	if a.Temporal == nil {
+		return
+	}
+
+	update := models.ActionPhaseUpdate{
+		SequenceID: input.Action.SequenceID,
+		Phase:      phase,
+		Attempt:    int(info.Attempt),
+	}
+	err := a.Temporal.SignalWorkflow(ctx, info.WorkflowExecution.ID, info.WorkflowExecution.RunID, models.SignalActionPhase, update)
+	if err == nil {
+		err = a.publishProgress(ctx, input.RunID, &update)
+	}
+	if err != nil {
+		activity.GetLogger(ctx).Warn("Failed to report action phase", "sequence", input.Action.SequenceID, "phase", phase, "error", err)
+	}
+}
+
+// heartbeatInterval is how often an action records its heartbeat while it
+// runs, well within the heartbeat timeout its run gives it
+const heartbeatInterval = 2 * time.Second
+
+// heartbeat records the activity's heartbeat until the returned function is
+// called, so an action that runs long isn't taken for a stuck one. The
+// replies to heartbeats are how the worker learns the run was canceled,
+// which cancels ctx.
+func heartbeat(ctx context.Context) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				activity.RecordHeartbeat(ctx)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// runSlotPollInterval is how often AcquireRunSlotActivity checks whether
+// the earlier runs made room
+const runSlotPollInterval = 5 * time.Second
+
+// AcquireRunSlotActivity waits until fewer than the limit of the workflow's
+// runs started before the run are active
+func (a *Activities) AcquireRunSlotActivity(ctx context.Context, input workflows.RunSlotInput) error {
+	logger := activity.GetLogger(ctx)
+	if a.DB == nil {
+		logger.Warn("Concurrency limit not enforced, the worker has no MYSQL_DSN", "workflowID", input.WorkflowID)
+		return nil
+	}
+
+	for {
+		active, err := a.DB.CountActiveRuns(ctx, input.WorkflowID, input.RunID, workflows.ActiveRunAge)
+		if err != nil {
+			return err
+		}
+		if active < input.Limit {
+			return nil
+		}
+		activity.RecordHeartbeat(ctx, active)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(runSlotPollInterval):
+		}
+	}
+}
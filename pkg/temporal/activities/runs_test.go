@@ -0,0 +1,145 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+
+	"dev/bravebird/browser-automation-go/pkg/database"
+	"dev/bravebird/browser-automation-go/pkg/models"
+	"dev/bravebird/browser-automation-go/pkg/temporal/workflows"
+)
+
+// TestRecordActionResults runs a workflow whose actions find their element
+// with the first selector, fall back on another and fail, and reads the
+// workflow's analytics and selector health back from the results the run
+// recorded
+func TestRecordActionResults(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.NewSQLite(filepath.Join(t.TempDir(), "automator.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateWorkflowDefinition(ctx, &models.WorkflowDefinition{ID: "wf-1", Name: "Login", SemanticContext: "[]", ParametersJSON: "[]"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateWorkflowRun(ctx, &models.WorkflowRun{ID: "run-1", WorkflowID: "wf-1", Status: models.StatusPending, ParametersJSON: "{}"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetWorkerOptions(worker.Options{EnableSessionWorker: true})
+	register := func(fn interface{}, name string) {
+		env.RegisterActivityWithOptions(fn, activity.RegisterOptions{Name: name})
+	}
+	register(func(ctx context.Context, input workflows.PreGenerateCodeInput) (workflows.PreGeneratedCode, error) {
+		return workflows.PreGeneratedCode{ActionCodes: map[int]string{}}, nil
+	}, "PreGenerateCodeActivity")
+	register(func(ctx context.Context, input workflows.BrowserInitInput) (workflows.BrowserSession, error) {
+		return workflows.BrowserSession{SessionID: "session-1"}, nil
+	}, "InitializeBrowserActivity")
+	register(func(ctx context.Context, input workflows.ActionInput) (models.ActionResult, error) {
+		switch input.Action.SequenceID {
+		case 1:
+			return models.ActionResult{Selector: "#email", SelectorSource: models.SelectorPrimary, Duration: 100}, nil
+		case 2:
+			return models.ActionResult{Selector: "button", SelectorFallbacks: 1, SelectorSource: models.SelectorFallback, Duration: 300}, nil
+		}
+		return models.ActionResult{}, temporal.NewNonRetryableApplicationError("element not found", "ElementNotFound", errors.New("element not found"))
+	}, "ExecuteBrowserActionActivity")
+	register(func(ctx context.Context, input workflows.ScreenshotInput) (string, error) {
+		return "screenshots/" + input.Filename, nil
+	}, "TakeScreenshotActivity")
+	register(func(ctx context.Context, input workflows.ScreenshotInput) (string, error) {
+		return "", nil
+	}, "DOMSnapshotActivity")
+	register(func(ctx context.Context, sessionID string) error {
+		return nil
+	}, "CloseBrowserActivity")
+	register(func(ctx context.Context, input workflows.ProgressInput) error {
+		return nil
+	}, "PublishProgressActivity")
+	acts := &Activities{DB: db}
+	env.RegisterActivity(acts.RecordActionResultsActivity)
+
+	env.ExecuteWorkflow(workflows.BrowserAutomationWorkflow, models.WorkflowInput{
+		WorkflowID: "wf-1",
+		RunID:      "run-1",
+		Actions: []models.SemanticAction{
+			{ID: "a1", SequenceID: 1, ActionType: models.ActionInput, Value: "alice@example.com"},
+			{ID: "a2", SequenceID: 2, ActionType: models.ActionClick},
+			{ID: "a3", SequenceID: 3, ActionType: models.ActionClick},
+		},
+		Timeout: 60,
+	})
+	if !env.IsWorkflowCompleted() {
+		t.Fatal("workflow did not complete")
+	}
+	var result models.WorkflowResult
+	if err := env.GetWorkflowResult(&result); err != nil {
+		t.Fatalf("GetWorkflowResult() error = %v", err)
+	}
+	if err := db.UpdateWorkflowRunStatus(ctx, "run-1", result.Status, result.ErrorMessage); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := db.GetActionResults(ctx, "run-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("recorded %d action results, want 3", len(results))
+	}
+	if failed := results[2]; failed.Status != models.StatusFailed || failed.ScreenshotPath == "" {
+		t.Errorf("failed action result = %q with screenshot %q, want it failed with its screenshot", failed.Status, failed.ScreenshotPath)
+	}
+
+	analytics, err := db.GetWorkflowAnalytics(ctx, "wf-1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analytics.Runs != 1 || len(analytics.Actions) != 3 {
+		t.Fatalf("analytics over %d runs of %d actions, want 1 run of 3", analytics.Runs, len(analytics.Actions))
+	}
+	tests := []struct {
+		actionID  string
+		failures  int
+		fallbacks float64
+	}{
+		{"a1", 0, 0},
+		{"a2", 0, 1},
+		{"a3", 1, 0},
+	}
+	for i, tt := range tests {
+		got := analytics.Actions[i]
+		if got.ActionID != tt.actionID || got.Failures != tt.failures || got.AvgSelectorFallbacks != tt.fallbacks {
+			t.Errorf("analytics of action %d = %s with %d failures and %v fallbacks, want %s with %d and %v",
+				i+1, got.ActionID, got.Failures, got.AvgSelectorFallbacks, tt.actionID, tt.failures, tt.fallbacks)
+		}
+	}
+
+	health, err := db.GetSelectorHealth(ctx, "wf-1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(health.Actions) != 2 {
+		t.Fatalf("selector health of %d actions, want the 2 that found their element", len(health.Actions))
+	}
+	if primary := health.Actions[0]; primary.ActionID != "a1" || primary.Primary != 1 {
+		t.Errorf("health of %s = %d primary, want a1 with 1", primary.ActionID, primary.Primary)
+	}
+	if fallback := health.Actions[1]; fallback.ActionID != "a2" || fallback.Fallback != 1 {
+		t.Errorf("health of %s = %d fallback, want a2 with 1", fallback.ActionID, fallback.Fallback)
+	}
+}
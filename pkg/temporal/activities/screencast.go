@@ -0,0 +1,105 @@
+package activities
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/gorilla/websocket"
+)
+
+// screencastPath is where the worker's HTTP server streams a session's
+// browser, followed by the session ID
+const screencastPath = "/screencast/"
+
+// screencastQuality is the JPEG quality of screencast frames, traded for
+// bandwidth as the frames are for watching rather than evidence
+const screencastQuality = 60
+
+// screencaster is a page that can stream what its browser shows
+type screencaster interface {
+	// Screencast calls frame with each JPEG frame the page paints until
+	// ctx is done or frame returns an error
+	Screencast(ctx context.Context, frame func([]byte) error) error
+}
+
+func (p *rodPage) Screencast(ctx context.Context, frame func([]byte) error) error {
+	page := p.page.Context(ctx)
+	quality, everyFrame := screencastQuality, 1
+	err := proto.PageStartScreencast{
+		Format:        proto.PageStartScreencastFormatJpeg,
+		Quality:       &quality,
+		EveryNthFrame: &everyFrame,
+	}.Call(page)
+	if err != nil {
+		return err
+	}
+	defer proto.PageStopScreencast{}.Call(p.page)
+
+	var sendErr error
+	page.EachEvent(func(e *proto.PageScreencastFrame) bool {
+		// Chrome sends the next frame once this one is acknowledged
+		_ = proto.PageScreencastFrameAck{SessionID: e.SessionID}.Call(page)
+		if sendErr = frame(e.Data); sendErr != nil {
+			return true
+		}
+		return false
+	})()
+	if sendErr != nil {
+		return sendErr
+	}
+	return ctx.Err()
+}
+
+// ScreencastURL returns where the worker streams a session's browser, empty
+// when the worker has no advertised URL
+func (a *Activities) ScreencastURL(sessionID string) string {
+	if a.WorkerURL == "" {
+		return ""
+	}
+	return strings.TrimSuffix(a.WorkerURL, "/") + screencastPath + sessionID
+}
+
+// ScreencastHandler streams the browser of a session open on this worker
+// as JPEG frames over a WebSocket, one binary message per frame, until the
+// client disconnects or the session closes
+func (a *Activities) ScreencastHandler() http.Handler {
+	upgrader := websocket.Upgrader{}
+	return http.StripPrefix(screencastPath, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, ok := a.Pool.Get(r.URL.Path)
+		if !ok {
+			http.Error(w, "Browser session not found", http.StatusNotFound)
+			return
+		}
+		page, ok := session.Page.(screencaster)
+		if !ok {
+			http.Error(w, "Browser does not support screencasts", http.StatusNotImplemented)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// Reading notices the client going away
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		go func() {
+			defer cancel()
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		_ = page.Screencast(ctx, func(frame []byte) error {
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			return conn.WriteMessage(websocket.BinaryMessage, frame)
+		})
+	}))
+}
@@ -0,0 +1,67 @@
+// Evaluated in every document before the page's own scripts to hide the
+// signals automation-detection scripts look for in headless Chrome.
+(() => {
+  const define = (obj, prop, get) => {
+    try {
+      Object.defineProperty(obj, prop, { get, configurable: true });
+    } catch (e) {}
+  };
+
+  // navigator.webdriver is true under CDP control
+  define(Object.getPrototypeOf(navigator), 'webdriver', () => undefined);
+
+  // Headless Chrome reports no languages when none are configured
+  if (!navigator.languages || navigator.languages.length === 0) {
+    define(Object.getPrototypeOf(navigator), 'languages', () => ['en-US', 'en']);
+  }
+
+  // Headless Chrome has no plugins or MIME types
+  if (navigator.plugins.length === 0) {
+    const mimeType = { type: 'application/pdf', suffixes: 'pdf', description: 'Portable Document Format' };
+    const plugin = { name: 'PDF Viewer', filename: 'internal-pdf-viewer', description: 'Portable Document Format', length: 1, 0: mimeType };
+    mimeType.enabledPlugin = plugin;
+    const names = ['PDF Viewer', 'Chrome PDF Viewer', 'Chromium PDF Viewer', 'Microsoft Edge PDF Viewer', 'WebKit built-in PDF'];
+    const plugins = names.map((name) => Object.assign({}, plugin, { name }));
+    plugins.item = (i) => plugins[i] || null;
+    plugins.namedItem = (name) => plugins.find((p) => p.name === name) || null;
+    plugins.refresh = () => {};
+    const mimeTypes = [mimeType];
+    mimeTypes.item = (i) => mimeTypes[i] || null;
+    mimeTypes.namedItem = (type) => mimeTypes.find((m) => m.type === type) || null;
+    define(Object.getPrototypeOf(navigator), 'plugins', () => plugins);
+    define(Object.getPrototypeOf(navigator), 'mimeTypes', () => mimeTypes);
+  }
+
+  // window.chrome exists in regular Chrome only
+  if (!window.chrome) {
+    window.chrome = { runtime: {}, app: { isInstalled: false }, csi: () => ({}), loadTimes: () => ({}) };
+  }
+
+  // Headless Chrome denies notifications while reporting them as default
+  const query = window.navigator.permissions && window.navigator.permissions.query;
+  if (query) {
+    window.navigator.permissions.query = (parameters) =>
+      parameters && parameters.name === 'notifications'
+        ? Promise.resolve({ state: Notification.permission, onchange: null })
+        : query.call(window.navigator.permissions, parameters);
+  }
+
+  // SwiftShader gives headless Chrome away through WebGL
+  const patchWebGL = (proto) => {
+    if (!proto) return;
+    const getParameter = proto.getParameter;
+    proto.getParameter = function (parameter) {
+      if (parameter === 37445) return 'Intel Inc.'; // UNMASKED_VENDOR_WEBGL
+      if (parameter === 37446) return 'Intel Iris OpenGL Engine'; // UNMASKED_RENDERER_WEBGL
+      return getParameter.call(this, parameter);
+    };
+  };
+  patchWebGL(window.WebGLRenderingContext && WebGLRenderingContext.prototype);
+  patchWebGL(window.WebGL2RenderingContext && WebGL2RenderingContext.prototype);
+
+  // Headless windows have no outer dimensions
+  if (window.outerWidth === 0 && window.outerHeight === 0) {
+    define(window, 'outerWidth', () => window.innerWidth);
+    define(window, 'outerHeight', () => window.innerHeight + 85);
+  }
+})();
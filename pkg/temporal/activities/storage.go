@@ -0,0 +1,106 @@
+package activities
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// localStorageScript fills an origin's localStorage the first time one of
+// its documents loads in the tab, before the page's scripts read it. Later
+// navigations keep whatever the site changed.
+const localStorageScript = `(() => {
+	const origins = %s;
+	const state = origins.find((o) => o.origin === location.origin);
+	if (!state) return;
+	try {
+		if (sessionStorage.getItem('__ba_storage_state')) return;
+		for (const item of state.localStorage || []) localStorage.setItem(item.name, item.value);
+		sessionStorage.setItem('__ba_storage_state', '1');
+	} catch (e) {}
+})();`
+
+// localStorageCapture returns the current origin's localStorage as a JSON
+// storage state origin
+const localStorageCapture = `() => JSON.stringify({
+	origin: location.origin,
+	localStorage: Object.entries(localStorage).map(([name, value]) => ({name, value})),
+})`
+
+// injectStorageState sets the state's cookies in the page's browser context
+// and its localStorage for when the page reaches each origin
+func injectStorageState(page *rod.Page, state *models.StorageState) error {
+	if state == nil {
+		return nil
+	}
+
+	// SetCookies clears every cookie when given none
+	if len(state.Cookies) > 0 {
+		if err := page.SetCookies(cookieParams(state.Cookies)); err != nil {
+			return fmt.Errorf("failed to set cookies: %w", err)
+		}
+	}
+
+	if len(state.Origins) > 0 {
+		origins, err := json.Marshal(state.Origins)
+		if err != nil {
+			return err
+		}
+		if _, err := page.EvalOnNewDocument(fmt.Sprintf(localStorageScript, origins)); err != nil {
+			return fmt.Errorf("failed to set localStorage: %w", err)
+		}
+	}
+	return nil
+}
+
+// cookieParams converts storage state cookies to CDP cookies. Cookies
+// without an expiry stay session cookies.
+func cookieParams(cookies []models.StorageCookie) []*proto.NetworkCookieParam {
+	params := make([]*proto.NetworkCookieParam, 0, len(cookies))
+	for _, c := range cookies {
+		param := &proto.NetworkCookieParam{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HTTPOnly: c.HTTPOnly,
+			SameSite: proto.NetworkCookieSameSite(c.SameSite),
+		}
+		if param.Path == "" {
+			param.Path = "/"
+		}
+		if c.Expires > 0 {
+			param.Expires = proto.TimeSinceEpoch(c.Expires)
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+// storageCookies converts CDP cookies to storage state cookies, with -1 as
+// the expiry of session cookies
+func storageCookies(cookies []*proto.NetworkCookie) []models.StorageCookie {
+	result := make([]models.StorageCookie, 0, len(cookies))
+	for _, c := range cookies {
+		expires := float64(c.Expires)
+		if c.Session {
+			expires = -1
+		}
+		result = append(result, models.StorageCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  expires,
+			HTTPOnly: c.HTTPOnly,
+			Secure:   c.Secure,
+			SameSite: string(c.SameSite),
+		})
+	}
+	return result
+}
@@ -0,0 +1,102 @@
+package activities
+
+import (
+	"sync"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// tabTracker notes the tabs a run's pages open, through target=_blank links
+// or window.open, and which of them closed
+type tabTracker struct {
+	mu     sync.Mutex
+	ours   map[proto.TargetTargetID]bool
+	opened []proto.TargetTargetID // not yet followed, oldest first
+	closed map[proto.TargetTargetID]bool
+	stop   func()
+}
+
+func watchTabs(browser *rod.Browser, page *rod.Page) *tabTracker {
+	t := &tabTracker{
+		ours:   map[proto.TargetTargetID]bool{page.TargetID: true},
+		closed: make(map[proto.TargetTargetID]bool),
+	}
+	events, stop := browser.WithCancel()
+	t.stop = stop
+	wait := events.EachEvent(func(e *proto.TargetTargetCreated) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if info := e.TargetInfo; info.Type == proto.TargetTargetInfoTypePage && t.ours[info.OpenerID] {
+			t.ours[info.TargetID] = true
+			t.opened = append(t.opened, info.TargetID)
+		}
+	}, func(e *proto.TargetTargetDestroyed) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.ours[e.TargetID] {
+			t.closed[e.TargetID] = true
+		}
+	})
+	go wait()
+	return t
+}
+
+// takeOpened returns the tabs opened since the last call
+func (t *tabTracker) takeOpened() []proto.TargetTargetID {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	opened := t.opened
+	t.opened = nil
+	return opened
+}
+
+func (t *tabTracker) isClosed(id proto.TargetTargetID) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.closed[id]
+}
+
+func (t *tabTracker) close() {
+	if t != nil {
+		t.stop()
+	}
+}
+
+// SwitchTabs returns to the opener of a tab that closed and moves to the
+// tabs opened since the last call, the newest last, as a user would look at
+// a popup. New tabs get the run's emulation, authentication and dialog
+// policy, though scripts of their first document may already have run.
+func (p *rodPage) SwitchTabs() []models.TabSwitch {
+	var switches []models.TabSwitch
+	for len(p.tabs) > 1 && p.tracker.isClosed(p.page.TargetID) {
+		p.tabs = p.tabs[:len(p.tabs)-1]
+		p.page = p.tabs[len(p.tabs)-1]
+		switches = append(switches, models.TabSwitch{URL: p.tabURL(), Closed: true})
+	}
+
+	for _, id := range p.tracker.takeOpened() {
+		if p.tracker.isClosed(id) {
+			continue
+		}
+		page, err := p.browser.PageFromTarget(id)
+		if err != nil {
+			continue
+		}
+		if err := p.prepare(page); err != nil {
+			continue
+		}
+		p.downloads.watch(page.FrameID)
+		p.tabs = append(p.tabs, page)
+		p.page = page
+		switches = append(switches, models.TabSwitch{URL: p.tabURL()})
+	}
+	return switches
+}
+
+func (p *rodPage) tabURL() string {
+	url, _, _ := p.Info()
+	return url
+}
@@ -0,0 +1,139 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+)
+
+// throttlePollInterval is how often a throttled action checks whether its
+// domain's budget freed up, heartbeating meanwhile
+const throttlePollInterval = 5 * time.Second
+
+// DomainLimits caps the actions per minute runs take on each target domain
+// so parallel runs don't get a site to ban them. Workers with a database
+// share the budget, others each keep their own.
+type DomainLimits struct {
+	perDomain map[string]int
+	fallback  int // for the domains not listed, unlimited when 0
+
+	mu     sync.Mutex
+	window time.Time      // the minute local counts are for
+	counts map[string]int // actions taken this minute by domain
+}
+
+// ParseDomainLimits parses a comma-separated list of domain=actions-per-minute
+// pairs such as "example.com=30,*=120". A domain also covers its subdomains
+// and "*" covers every domain not listed.
+func ParseDomainLimits(list string) (*DomainLimits, error) {
+	l := &DomainLimits{perDomain: make(map[string]int), counts: make(map[string]int)}
+	for _, pair := range strings.Split(list, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		domain, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("domain limit %q is not domain=actions", pair)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("domain limit %q must be a positive number of actions", pair)
+		}
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain == "*" {
+			l.fallback = limit
+		} else {
+			l.perDomain[domain] = limit
+		}
+	}
+	return l, nil
+}
+
+// Limit returns the domain a host's actions count against and its actions
+// per minute, 0 when the host is unlimited
+func (l *DomainLimits) Limit(host string) (string, int) {
+	host = strings.ToLower(host)
+	for domain := host; domain != ""; {
+		if limit, ok := l.perDomain[domain]; ok {
+			return domain, limit
+		}
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok {
+			break
+		}
+		domain = parent
+	}
+	return host, l.fallback
+}
+
+// reserveLocal takes one of a domain's actions this minute on this worker,
+// reporting whether the budget had one left
+func (l *DomainLimits) reserveLocal(domain string, limit int, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if minute := now.Truncate(time.Minute); !minute.Equal(l.window) {
+		l.window = minute
+		l.counts = make(map[string]int)
+	}
+	if l.counts[domain] >= limit {
+		return false
+	}
+	l.counts[domain]++
+	return true
+}
+
+// throttle waits until the target domain's budget has an action left for
+// one on rawURL, returning early when the activity is canceled
+func (a *Activities) throttle(ctx context.Context, rawURL string) error {
+	if a.DomainLimits == nil {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return nil
+	}
+	domain, limit := a.DomainLimits.Limit(u.Hostname())
+	if limit == 0 {
+		return nil
+	}
+
+	for waited := false; ; waited = true {
+		ok, err := a.reserveDomainAction(ctx, domain, limit)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		if !waited {
+			activity.GetLogger(ctx).Info("Throttling action", "domain", domain, "actionsPerMinute", limit)
+		}
+		activity.RecordHeartbeat(ctx, "throttled on "+domain)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(throttlePollInterval):
+		}
+	}
+}
+
+// reserveDomainAction takes one of the domain's actions this minute, from
+// the budget shared through the database when the worker has one
+func (a *Activities) reserveDomainAction(ctx context.Context, domain string, limit int) (bool, error) {
+	if a.DB == nil {
+		return a.DomainLimits.reserveLocal(domain, limit, time.Now()), nil
+	}
+	ok, err := a.DB.ReserveDomainAction(ctx, domain, limit)
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve action on %s: %w", domain, err)
+	}
+	return ok, nil
+}
@@ -0,0 +1,54 @@
+package activities
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDomainLimits(t *testing.T) {
+	limits, err := ParseDomainLimits("example.com=30, Shop.test=5,*=120")
+	if err != nil {
+		t.Fatalf("ParseDomainLimits() error = %v", err)
+	}
+
+	tests := []struct {
+		host   string
+		domain string
+		limit  int
+	}{
+		{"example.com", "example.com", 30},
+		{"www.example.com", "example.com", 30},
+		{"shop.test", "shop.test", 5},
+		{"other.org", "other.org", 120},
+		{"notexample.com", "notexample.com", 120},
+	}
+	for _, tt := range tests {
+		domain, limit := limits.Limit(tt.host)
+		if domain != tt.domain || limit != tt.limit {
+			t.Errorf("Limit(%q) = %q, %d, want %q, %d", tt.host, domain, limit, tt.domain, tt.limit)
+		}
+	}
+
+	for _, list := range []string{"example.com", "example.com=0", "example.com=fast"} {
+		if _, err := ParseDomainLimits(list); err == nil {
+			t.Errorf("ParseDomainLimits(%q) error = nil, want error", list)
+		}
+	}
+}
+
+func TestDomainLimitsReserveLocal(t *testing.T) {
+	limits, _ := ParseDomainLimits("example.com=2")
+	now := time.Date(2024, 1, 1, 12, 0, 10, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		if !limits.reserveLocal("example.com", 2, now) {
+			t.Fatalf("reserveLocal() #%d = false, want true", i+1)
+		}
+	}
+	if limits.reserveLocal("example.com", 2, now.Add(30*time.Second)) {
+		t.Error("reserveLocal() over the limit = true, want false")
+	}
+	if !limits.reserveLocal("example.com", 2, now.Add(time.Minute)) {
+		t.Error("reserveLocal() in the next minute = false, want true")
+	}
+}
@@ -0,0 +1,57 @@
+package activities
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-rod/rod"
+	"go.opentelemetry.io/otel/codes"
+
+	"dev/bravebird/browser-automation-go/pkg/telemetry"
+)
+
+// cdpTracer records the CDP calls of a browser as spans of the action
+// driving it, so a run's trace shows how long each command took. The calls
+// are bound to the action's context too, so canceling the run aborts the
+// command the browser is in.
+type cdpTracer struct {
+	rod.CDPClient
+
+	mu  sync.Mutex
+	ctx context.Context // of the action driving the browser, nil between actions
+}
+
+func (t *cdpTracer) Call(ctx context.Context, sessionID, method string, params interface{}) ([]byte, error) {
+	t.mu.Lock()
+	actionCtx := t.ctx
+	t.mu.Unlock()
+	if actionCtx == nil {
+		return t.CDPClient.Call(ctx, sessionID, method, params)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(actionCtx, cancel)()
+
+	_, span := telemetry.Tracer().Start(actionCtx, "cdp "+method)
+	defer span.End()
+	res, err := t.CDPClient.Call(ctx, sessionID, method, params)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return res, err
+}
+
+// trace parents the browser's CDP calls to the span of ctx, and aborts
+// them when ctx is done, until the returned function is called
+func (t *cdpTracer) trace(ctx context.Context) func() {
+	t.mu.Lock()
+	t.ctx = ctx
+	t.mu.Unlock()
+	return func() {
+		t.mu.Lock()
+		t.ctx = nil
+		t.mu.Unlock()
+	}
+}
@@ -0,0 +1,42 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-rod/rod/lib/cdp"
+)
+
+// hungClient is a browser that never answers a command
+type hungClient struct{}
+
+func (hungClient) Event() <-chan *cdp.Event { return nil }
+
+func (hungClient) Call(ctx context.Context, sessionID, method string, params interface{}) ([]byte, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestCDPCallAbortedWithAction(t *testing.T) {
+	tracer := &cdpTracer{CDPClient: hungClient{}}
+	actionCtx, cancel := context.WithCancel(context.Background())
+	defer tracer.trace(actionCtx)()
+
+	called := make(chan error, 1)
+	go func() {
+		_, err := tracer.Call(context.Background(), "", "Runtime.evaluate", nil)
+		called <- err
+	}()
+	cancel()
+
+	select {
+	case err := <-called:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Call() error = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Call() still running after the action was canceled")
+	}
+}
@@ -0,0 +1,198 @@
+package activities
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/go-rod/rod/lib/utils"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// elementWait bounds how long an action waits for its element to show up
+// and become visible
+const elementWait = 10 * time.Second
+
+// candidateWait bounds the search for each candidate of an element's
+// selector chain
+const candidateWait = 2 * time.Second
+
+// stableTime is how long an element's box must stay put for it to count as
+// no longer moving
+const stableTime = 100 * time.Millisecond
+
+// networkIdleTime is how long no request may be in flight for the network
+// to count as idle
+const networkIdleTime = 500 * time.Millisecond
+
+// hiddenScript reports whether no element matching the selector is visible
+const hiddenScript = `(selector) => {
+	const el = document.querySelector(selector);
+	if (!el) return true;
+	const style = getComputedStyle(el);
+	const box = el.getBoundingClientRect();
+	return style.display === 'none' || style.visibility === 'hidden' || box.width === 0 || box.height === 0;
+}`
+
+// settle scrolls an element into view and waits up to elementWait for it to
+// stop moving and to receive the pointer rather than an overlay, unless the
+// workflow skips stability checks
+func (p *rodPage) settle(elem *rod.Element) error {
+	if p.opts.SkipStabilityChecks {
+		return nil
+	}
+	elem = elem.Timeout(elementWait)
+	defer elem.CancelTimeout()
+
+	if err := elem.ScrollIntoView(); err != nil {
+		return fmt.Errorf("scroll element into view: %w", err)
+	}
+	if err := elem.WaitStable(stableTime); err != nil {
+		return fmt.Errorf("element kept moving: %w", err)
+	}
+	if _, err := elem.WaitInteractable(); err != nil {
+		return fmt.Errorf("element not interactable: %w", err)
+	}
+	return nil
+}
+
+func (p *rodPage) Wait(action func() error, conds ...models.WaitCondition) error {
+	// Page loads and requests are watched from before the action, the
+	// other conditions are only checked after it
+	pages := make([]*rod.Page, len(conds))
+	armed := make([]func() error, len(conds))
+	for i, cond := range conds {
+		switch cond.Type {
+		case models.WaitNavigation:
+			pages[i] = p.page.Timeout(cond.Duration())
+			wait := pages[i].WaitNavigation(proto.PageLifecycleEventNameLoad)
+			armed[i] = waitDone(pages[i], wait)
+		case models.WaitNetworkIdle:
+			pages[i] = p.page.Timeout(cond.Duration())
+			wait := pages[i].WaitRequestIdle(networkIdleTime, nil, nil, nil)
+			armed[i] = waitDone(pages[i], wait)
+		case models.WaitRequest:
+			pages[i] = p.page.Timeout(cond.Duration())
+			armed[i] = waitRequest(pages[i], cond)
+		}
+	}
+	defer func() {
+		for _, page := range pages {
+			if page != nil {
+				page.CancelTimeout()
+			}
+		}
+	}()
+
+	if err := action(); err != nil {
+		return err
+	}
+
+	for i, cond := range conds {
+		var err error
+		if armed[i] != nil {
+			err = armed[i]()
+		} else {
+			page := p.page.Timeout(cond.Duration())
+			err = rodWait(page, cond)
+			page.CancelTimeout()
+		}
+		if err != nil {
+			return fmt.Errorf("wait for %s: %w", cond, err)
+		}
+	}
+	return nil
+}
+
+// waitDone turns a rod wait into one failing when page's timeout ran out
+func waitDone(page *rod.Page, wait func()) func() error {
+	return func() error {
+		wait()
+		return page.GetContext().Err()
+	}
+}
+
+// waitRequest starts watching page for a request matching cond and returns
+// a wait for its response, which fails when the response has the wrong
+// status, the request fails or none is made before page's timeout
+func waitRequest(page *rod.Page, cond models.WaitCondition) func() error {
+	pending := map[proto.NetworkRequestID]bool{}
+	var status int
+	var failure string
+	wait := page.EachEvent(func(e *proto.NetworkRequestWillBeSent) {
+		if requestMatches(cond, e.Request.Method, e.Request.URL) {
+			pending[e.RequestID] = true
+		}
+	}, func(e *proto.NetworkResponseReceived) bool {
+		if !pending[e.RequestID] {
+			return false
+		}
+		status = e.Response.Status
+		return true
+	}, func(e *proto.NetworkLoadingFailed) bool {
+		if !pending[e.RequestID] {
+			return false
+		}
+		failure = e.ErrorText
+		return true
+	})
+	return func() error {
+		wait()
+		switch {
+		case failure != "":
+			return fmt.Errorf("request failed: %s", failure)
+		case status == 0:
+			if err := page.GetContext().Err(); err != nil {
+				return fmt.Errorf("no matching request: %w", err)
+			}
+			return fmt.Errorf("no matching request")
+		}
+		return cond.CheckResponse(status)
+	}
+}
+
+// requestMatches reports whether a request is the one a request wait
+// expects
+func requestMatches(cond models.WaitCondition, method, url string) bool {
+	if cond.Method != "" && !strings.EqualFold(cond.Method, method) {
+		return false
+	}
+	matched, err := regexp.MatchString(cond.Pattern, url)
+	return err == nil && matched
+}
+
+// rodWait waits on page for a condition checked after the action
+func rodWait(page *rod.Page, cond models.WaitCondition) error {
+	switch cond.Type {
+	case models.WaitVisible:
+		elem, err := page.Element(cond.Selector)
+		if err != nil {
+			return err
+		}
+		return elem.WaitVisible()
+
+	case models.WaitHidden:
+		return page.Wait(rod.Eval(hiddenScript, cond.Selector))
+
+	case models.WaitURL:
+		pattern, err := regexp.Compile(cond.Pattern)
+		if err != nil {
+			return err
+		}
+		return utils.Retry(page.GetContext(), rod.DefaultSleeper(), func() (bool, error) {
+			info, err := page.Info()
+			if err != nil {
+				return true, err
+			}
+			return pattern.MatchString(info.URL), nil
+		})
+
+	case models.WaitJS:
+		return page.Wait(rod.Eval(cond.Script))
+	}
+	return fmt.Errorf("unknown wait type: %q", cond.Type)
+}
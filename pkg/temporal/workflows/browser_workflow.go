@@ -1,18 +1,34 @@
 package workflows
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"go.temporal.io/sdk/log"
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 
 	"dev/bravebird/browser-automation-go/pkg/models"
 )
 
+// browserSessionTimeout bounds how long a run may hold a worker's browser
+const browserSessionTimeout = 2 * time.Hour
+
+// runQueueTimeout bounds how long a run waits for its workflow's earlier
+// runs to make room under the workflow's concurrency limit
+const runQueueTimeout = 2 * time.Hour
+
+// ActiveRunAge is how long after starting a run can still be active. Runs
+// recorded as running for longer are taken as abandoned rather than counted
+// against a workflow's concurrency limit.
+const ActiveRunAge = runQueueTimeout + browserSessionTimeout
+
 // BrowserAutomationWorkflow executes a browser automation workflow
 func BrowserAutomationWorkflow(ctx workflow.Context, input models.WorkflowInput) (models.WorkflowResult, error) {
-	logger := workflow.GetLogger(ctx)
-	logger.Info("Starting browser automation workflow", "workflowID", input.WorkflowID, "runID", input.RunID)
+	logger := log.With(workflow.GetLogger(ctx), "workflowID", input.WorkflowID, "runID", input.RunID)
+	logger.Info("Starting browser automation workflow")
 
 	result := models.WorkflowResult{
 		RunID:         input.RunID,
@@ -28,19 +44,169 @@ func BrowserAutomationWorkflow(ctx workflow.Context, input models.WorkflowInput)
 		logger.Error("Failed to register query handler", "error", err)
 	}
 
+	// Actions see the run's parameters plus the outputs of earlier actions,
+	// so a copied value can be referenced as {{clipboard}}
+	params := make(map[string]string, len(input.Parameters)+1)
+	for name, value := range input.Parameters {
+		params[name] = value
+	}
+	if input.Parameters == nil {
+		input.Parameters = make(map[string]string)
+	}
+
+	// Parameter values can be set during the run, such as a one-time code
+	// the site sent, for the actions still to come
+	err = workflow.SetUpdateHandlerWithOptions(ctx, models.UpdateParameters,
+		func(ctx workflow.Context, values map[string]string) error {
+			for name, value := range values {
+				input.Parameters[name] = value
+				params[name] = value
+			}
+			logger.Info("Parameters updated", "count", len(values))
+			return nil
+		},
+		workflow.UpdateHandlerOptions{
+			Validator: func(ctx workflow.Context, values map[string]string) error {
+				for name := range values {
+					if name == "" {
+						return fmt.Errorf("parameter name must not be empty")
+					}
+					if name == models.ClipboardOutput {
+						return fmt.Errorf("%s is set by copy actions", name)
+					}
+				}
+				return nil
+			},
+		})
+	if err != nil {
+		logger.Error("Failed to register parameters update handler", "error", err)
+	}
+
+	// An operator can hold the run before its next action, or before a
+	// given step, and let it continue later. A debug run holds before every
+	// action, a next signal running one and resume running the rest.
+	var paused bool
+	var pauseBefore int
+	stepping := input.Debug
+	pauseCh := workflow.GetSignalChannel(ctx, models.SignalPause)
+	resumeCh := workflow.GetSignalChannel(ctx, models.SignalResume)
+	nextCh := workflow.GetSignalChannel(ctx, models.SignalNext)
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		for {
+			workflow.NewSelector(ctx).
+				AddReceive(pauseCh, func(c workflow.ReceiveChannel, more bool) {
+					var req models.PauseRequest
+					c.Receive(ctx, &req)
+					if req.BeforeStep > 0 {
+						pauseBefore = req.BeforeStep
+					} else {
+						paused = true
+					}
+				}).
+				AddReceive(resumeCh, func(c workflow.ReceiveChannel, more bool) {
+					c.Receive(ctx, nil)
+					paused = false
+					pauseBefore = 0
+					stepping = false
+				}).
+				AddReceive(nextCh, func(c workflow.ReceiveChannel, more bool) {
+					c.Receive(ctx, nil)
+					paused = false
+				}).
+				Select(ctx)
+		}
+	})
+
+	// Workers report the phase of the action they execute, such as
+	// generating its code or retrying it
+	phaseCh := workflow.GetSignalChannel(ctx, models.SignalActionPhase)
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		for {
+			var update models.ActionPhaseUpdate
+			phaseCh.Receive(ctx, &update)
+			if result.Progress != nil && result.Progress.SequenceID == update.SequenceID {
+				result.Progress.Phase = update.Phase
+				result.Progress.Attempt = update.Attempt
+			}
+		}
+	})
+
+	// Decisions on failed actions are received while the run holds for one
+	stepCh := workflow.GetSignalChannel(ctx, models.SignalStep)
+
+	// Publish how the run ended once its result is final
+	defer func() {
+		publishCtx, _ := workflow.NewDisconnectedContext(ctx)
+		publishProgress(publishCtx, input)
+	}()
+
+	// Announce how the run ended to the workflow's notification targets.
+	// Like healing, a failed notification is only logged, and not retried
+	// so the targets already notified aren't notified twice.
+	if len(input.Notifications) > 0 && !input.DryRun {
+		defer func() {
+			notifyCtx, _ := workflow.NewDisconnectedContext(ctx)
+			notifyCtx = workflow.WithActivityOptions(notifyCtx, workflow.ActivityOptions{
+				StartToCloseTimeout: time.Minute,
+				RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 1},
+			})
+			notify := notifyInput(input, result)
+			if len(notify.Targets) == 0 {
+				return
+			}
+			err := workflow.ExecuteActivity(notifyCtx, "NotifyRunActivity", notify).Get(notifyCtx, nil)
+			if err != nil {
+				logger.Warn("Failed to send run notifications", "status", result.Status, "error", err.Error())
+			}
+		}()
+	}
+
+	// A workflow limited to a number of concurrent runs has its runs wait
+	// for the ones started before them to make room. The run's end is
+	// recorded so its slot frees without a client streaming it.
+	if input.MaxConcurrentRuns > 0 {
+		defer func() {
+			recordCtx, _ := workflow.NewDisconnectedContext(ctx)
+			recordCtx = workflow.WithActivityOptions(recordCtx, workflow.ActivityOptions{
+				StartToCloseTimeout: 30 * time.Second,
+			})
+			err := workflow.ExecuteActivity(recordCtx, "UpdateRunStatusActivity", RunStatusInput{
+				RunID:        input.RunID,
+				Status:       result.Status,
+				ErrorMessage: result.ErrorMessage,
+			}).Get(recordCtx, nil)
+			if err != nil {
+				logger.Warn("Failed to record run status", "error", err.Error())
+			}
+		}()
+
+		result.Status = models.StatusPending
+		publishProgress(ctx, input)
+		slotCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+			ScheduleToCloseTimeout: runQueueTimeout,
+			HeartbeatTimeout:       time.Minute,
+		})
+		err := workflow.ExecuteActivity(slotCtx, "AcquireRunSlotActivity", RunSlotInput{
+			WorkflowID: input.WorkflowID,
+			RunID:      input.RunID,
+			Limit:      input.MaxConcurrentRuns,
+		}).Get(ctx, nil)
+		if err != nil {
+			result.Status = models.StatusFailed
+			result.ErrorMessage = "Failed waiting for a free run slot: " + err.Error()
+			return result, nil
+		}
+		result.Status = models.StatusRunning
+		publishProgress(ctx, input)
+	}
+
 	startTime := workflow.Now(ctx)
 
 	// Configure activity options with retry policy
 	activityOptions := workflow.ActivityOptions{
 		StartToCloseTimeout: time.Duration(input.Timeout) * time.Second,
 		HeartbeatTimeout:    30 * time.Second,
-		RetryPolicy: &temporal.RetryPolicy{
-			InitialInterval:        time.Second,
-			BackoffCoefficient:     2.0,
-			MaximumInterval:        time.Minute,
-			MaximumAttempts:        int32(input.RetryAttempts),
-			NonRetryableErrorTypes: []string{"FatalBrowserError", "InvalidSelectorError"},
-		},
+		RetryPolicy:         runRetryPolicy(input),
 	}
 	ctx = workflow.WithActivityOptions(ctx, activityOptions)
 
@@ -61,25 +227,70 @@ func BrowserAutomationWorkflow(ctx workflow.Context, input models.WorkflowInput)
 	})
 
 	err = workflow.ExecuteActivity(preGenCtx, "PreGenerateCodeActivity", PreGenerateCodeInput{
-		WorkflowID:  input.WorkflowID,
-		Actions:     input.Actions,
-		Parameters:  input.Parameters,
-		LLMProvider: input.LLMProvider,
-		LLMAPIKey:   input.LLMAPIKey,
+		WorkflowID:    input.WorkflowID,
+		RunID:         input.RunID,
+		Actions:       input.Actions,
+		Parameters:    input.Parameters,
+		LLMProvider:   input.LLMProvider,
+		LLMAPIKey:     input.LLMAPIKey,
+		CodeTemplates: input.CodeTemplates,
+		Inline:        input.DryRun,
 	}).Get(ctx, &preGeneratedCode)
 	if err != nil {
 		logger.Warn("Pre-generation failed, will generate code during execution", "error", err.Error())
 		preGeneratedCode.ActionCodes = make(map[int]string)
 	} else {
-		logger.Info("Pre-generated code for actions", "count", len(preGeneratedCode.ActionCodes))
+		logger.Info("Pre-generated code for actions", "inline", len(preGeneratedCode.ActionCodes), "stored", len(preGeneratedCode.CodeRefs))
 	}
 
+	// A dry run stops short of launching a browser, returning what each
+	// step would execute instead
+	if input.DryRun {
+		result.Plan = planSteps(input, params, preGeneratedCode)
+		result.Status = models.StatusSuccess
+		result.TotalDuration = workflow.Now(ctx).Sub(startTime).Milliseconds()
+		logger.Info("Dry run completed", "steps", len(result.Plan))
+		return result, nil
+	}
+
+	// Pin the browser activities to one worker. The browser lives in the
+	// memory of the worker that launched it, so every activity using it must
+	// run there; code generation above can run anywhere.
+	sessionCtx, err := workflow.CreateSession(ctx, &workflow.SessionOptions{
+		CreationTimeout:  time.Minute,
+		ExecutionTimeout: browserSessionTimeout,
+	})
+	if err != nil {
+		result.Status = models.StatusFailed
+		result.ErrorMessage = "Failed to create browser session: " + err.Error()
+		return result, nil
+	}
+	defer workflow.CompleteSession(sessionCtx)
+
 	// Execute browser initialization activity
 	var browserSession BrowserSession
-	err = workflow.ExecuteActivity(ctx, "InitializeBrowserActivity", BrowserInitInput{
-		Headless:    input.Headless,
-		LLMProvider: input.LLMProvider,
-		LLMAPIKey:   input.LLMAPIKey,
+	err = workflow.ExecuteActivity(sessionCtx, "InitializeBrowserActivity", BrowserInitInput{
+		RunID:           input.RunID,
+		Headless:        input.Headless,
+		ReuseBrowser:    input.ReuseBrowser,
+		Endpoint:        input.BrowserEndpoint,
+		Browser:         input.Browser,
+		Device:          input.Device,
+		UserAgent:       input.UserAgent,
+		Locale:          input.Locale,
+		Timezone:        input.Timezone,
+		Proxy:           input.Proxy,
+		Stealth:         input.Stealth,
+		StorageState:    input.StorageState,
+		Session:         input.Session,
+		HTTPCredentials: input.HTTPCredentials,
+		Dialogs:         input.Dialogs,
+		DialogAnswer:    dialogAnswer(input),
+		LLMProvider:     input.LLMProvider,
+		LLMAPIKey:       input.LLMAPIKey,
+
+		SkipStabilityChecks: input.SkipStabilityChecks,
+		Priority:            input.Priority,
 	}).Get(ctx, &browserSession)
 	if err != nil {
 		result.Status = models.StatusFailed
@@ -87,63 +298,177 @@ func BrowserAutomationWorkflow(ctx workflow.Context, input models.WorkflowInput)
 		return result, nil
 	}
 
+	// Close the browser however the run ends. A canceled run closes it too,
+	// rather than leaving it to the session's timeout.
 	defer func() {
-		// Cleanup browser session
-		_ = workflow.ExecuteActivity(ctx, "CloseBrowserActivity", browserSession.SessionID).Get(ctx, nil)
+		closeCtx, _ := workflow.NewDisconnectedContext(sessionCtx)
+		_ = workflow.ExecuteActivity(closeCtx, "CloseBrowserActivity", browserSession.SessionID).Get(closeCtx, nil)
 	}()
 
-	// Execute each action sequentially
-	for i, action := range input.Actions {
-		logger.Info("Executing action", "sequence", action.SequenceID, "type", action.ActionType)
+	// The browser can be watched live while the run uses it
+	result.ScreencastURL = browserSession.ScreencastURL
+
+	// Selectors that found elements after the recorded ones failed, by the
+	// ID of their action
+	healed := make(map[string]string)
+
+	// Steps that succeeded with compensations to undo them should the run
+	// be aborted
+	var done []step
+
+	// Values extract actions read, as the rows of the run's dataset
+	var extracted dataset
+
+	// Execute each action sequentially, loops repeating theirs for each row
+	steps := expandLoops(input.Actions, input.Loops)
+	for i, step := range steps {
+		action := step.Action
+		result.Progress = &models.RunProgress{
+			Step:       i + 1,
+			Total:      len(steps),
+			SequenceID: action.SequenceID,
+			Iteration:  step.Iteration,
+			Phase:      models.PhaseWaiting,
+		}
+
+		// Wait between actions according to the configured delay strategy
+		waited := false
+		if i > 0 {
+			if delay := input.Delay.Between(steps[i-1].Action, action); delay > 0 {
+				publishProgress(ctx, input)
+				waited = true
+				if err := workflow.Sleep(ctx, delay); err != nil {
+					result.ActionResults = append(result.ActionResults, canceledStep(step))
+					result.Status = models.StatusCanceled
+					result.ErrorMessage = "Workflow canceled by user"
+					break
+				}
+			}
+		}
+
+		if pauseBefore == action.SequenceID || stepping {
+			paused = true
+			pauseBefore = 0
+		}
+		if paused {
+			logger.Info("Run paused", "sequence", action.SequenceID)
+			result.Status = models.StatusPaused
+			publishProgress(ctx, input)
+			if err := workflow.Await(ctx, func() bool { return !paused }); err != nil {
+				result.ActionResults = append(result.ActionResults, canceledStep(step))
+				result.Status = models.StatusCanceled
+				result.ErrorMessage = "Workflow canceled by user"
+				break
+			}
+			result.Status = models.StatusRunning
+			logger.Info("Run resumed", "sequence", action.SequenceID)
+		}
+
+		logger.Info("Executing action", "sequence", action.SequenceID, "type", action.ActionType, "iteration", step.Iteration)
 
 		// Get pre-generated code if available
 		generatedCode := preGeneratedCode.ActionCodes[action.SequenceID]
+		var codeRef *CodeRef
+		if ref, ok := preGeneratedCode.CodeRefs[action.SequenceID]; ok {
+			codeRef = &ref
+		}
+
+		// A loop's row binds parameters for its iteration
+		values := withRow(input.Parameters, step.Row)
 
 		// Override action value if it matches a parameter
 		// This ensures that runtime parameters are used instead of recorded values
-		currentAction := action
-		for _, param := range input.Params {
-			if param.TokenType == models.TokenVariable && param.SourceAction == action.SequenceID {
-				if val, ok := input.Parameters[param.Name]; ok {
-					logger.Info("Injecting parameter value", "param", param.Name, "original", action.Value, "new", val)
-					currentAction.Value = val
-				}
-			}
+		currentAction, injected := injectParameters(action, input.Params, values)
+		if injected {
+			logger.Info("Injecting parameter value", "sequence", action.SequenceID, "original", action.Value, "new", currentAction.Value)
+		}
+
+		// Paste what the run copied rather than what was copied while
+		// recording, unless a parameter supplies the value
+		if copied, ok := result.Outputs[models.ClipboardOutput]; ok && action.ActionType == models.ActionPaste && !injected {
+			currentAction.Value = copied
 		}
 
 		actionInput := ActionInput{
 			SessionID:     browserSession.SessionID,
+			RunID:         input.RunID,
 			Action:        currentAction,
-			Parameters:    input.Parameters,
+			Parameters:    withRow(params, step.Row),
 			LLMProvider:   input.LLMProvider,
 			GeneratedCode: generatedCode,
+			CodeRef:       codeRef,
+			CodeTemplates: input.CodeTemplates,
+		}
+		if input.Screenshots {
+			actionInput.ScreenshotName = fmt.Sprintf("%s_%d_%d", input.RunID, action.SequenceID, step.Iteration)
 		}
+		if input.Accessibility && action.ActionType == models.ActionNavigate {
+			actionInput.AccessibilityReport = fmt.Sprintf("accessibility_%d_%d.json", action.SequenceID, step.Iteration)
+		}
+		actionInput.VerifyOutcome = input.VerifyOutcomes && len(action.Context) > 0
 
 		var actionResult models.ActionResult
 
-		// Use specific retry policy for action execution (single retry)
-		actionCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
-			StartToCloseTimeout: time.Duration(input.Timeout) * time.Second,
-			HeartbeatTimeout:    30 * time.Second,
-			RetryPolicy: &temporal.RetryPolicy{
-				InitialInterval:    time.Second,
-				BackoffCoefficient: 2.0,
-				MaximumInterval:    time.Minute,
-				MaximumAttempts:    1, // Single attempt, no retries
-			},
-		})
+		// Actions run once within the run's timeout unless they set their own
+		actionCtx := workflow.WithActivityOptions(sessionCtx, actionActivityOptions(action, input.Timeout, input.Retry))
 
+		result.Progress.Phase = models.PhaseExecuting
+		result.Progress.Attempt = 1
+		if waited {
+			publishProgress(ctx, input)
+		}
 		err := workflow.ExecuteActivity(actionCtx, "ExecuteBrowserActionActivity", actionInput).Get(ctx, &actionResult)
 
+		// Hold the run after a failure for an operator to skip the action,
+		// or retry it with other parameter values
+		skipped := false
+		for err != nil && !temporal.IsCanceledError(err) && input.DecisionTimeout > 0 {
+			result.Status = models.StatusPaused
+			result.ErrorMessage = fmt.Sprintf("Step %d failed, awaiting skip or retry: %v", action.SequenceID, err)
+			publishProgress(ctx, input)
+			decision, ok := awaitDecision(ctx, stepCh, action.SequenceID, time.Duration(input.DecisionTimeout)*time.Second)
+			result.Status = models.StatusRunning
+			result.ErrorMessage = ""
+			if !ok {
+				break
+			}
+			if decision.Action == models.StepSkip {
+				logger.Info("Skipping failed action", "sequence", action.SequenceID)
+				skipped = true
+				break
+			}
+
+			for name, value := range decision.Parameters {
+				input.Parameters[name] = value
+				params[name] = value
+				actionInput.Parameters[name] = value
+			}
+			for _, param := range input.Params {
+				if val, ok := decision.Parameters[param.Name]; ok && param.TokenType == models.TokenVariable && param.SourceAction == action.SequenceID {
+					actionInput.Action.Value = val
+				}
+			}
+
+			logger.Info("Retrying failed action", "sequence", action.SequenceID, "overrides", len(decision.Parameters))
+			actionResult = models.ActionResult{}
+			result.Progress.Phase = models.PhaseRetrying
+			result.Progress.Attempt++
+			err = workflow.ExecuteActivity(actionCtx, "ExecuteBrowserActionActivity", actionInput).Get(ctx, &actionResult)
+		}
+
 		actionResult.SequenceID = action.SequenceID
 		actionResult.ActionID = action.ID
+		actionResult.Iteration = step.Iteration
+
+		// A debug run shows the page the step left before holding again
+		if input.Debug && !temporal.IsCanceledError(err) {
+			result.Debug = debugSnapshot(ctx, sessionCtx, input.RunID, browserSession.SessionID, step)
+		}
 
 		if err != nil {
 			// Check for cancellation
 			if temporal.IsCanceledError(err) {
-				actionResult.Status = models.StatusCanceled
-				actionResult.ErrorMessage = "Workflow canceled"
-				result.ActionResults = append(result.ActionResults, actionResult)
+				result.ActionResults = append(result.ActionResults, canceledStep(step))
 
 				result.Status = models.StatusCanceled
 				result.ErrorMessage = "Workflow canceled by user"
@@ -153,18 +478,28 @@ func BrowserAutomationWorkflow(ctx workflow.Context, input models.WorkflowInput)
 			actionResult.Status = models.StatusFailed
 			actionResult.ErrorMessage = err.Error()
 
-			// Take screenshot on failure
+			// Take screenshot on failure, named after the run so retention
+			// can prune it with the run
+			failureName := fmt.Sprintf("%s_%d_%d_failure", input.RunID, action.SequenceID, step.Iteration)
 			var screenshotPath string
-			_ = workflow.ExecuteActivity(ctx, "TakeScreenshotActivity", ScreenshotInput{
+			_ = workflow.ExecuteActivity(sessionCtx, "TakeScreenshotActivity", ScreenshotInput{
 				SessionID: browserSession.SessionID,
-				Filename:  action.ID + "_failure.png",
+				Filename:  failureName + ".png",
 			}).Get(ctx, &screenshotPath)
 			actionResult.ScreenshotPath = screenshotPath
 
+			// And the DOM, to see what a failed selector was looking at
+			var snapshotPath string
+			_ = workflow.ExecuteActivity(sessionCtx, "DOMSnapshotActivity", ScreenshotInput{
+				SessionID: browserSession.SessionID,
+				Filename:  failureName + ".html",
+			}).Get(ctx, &snapshotPath)
+			actionResult.DOMSnapshotPath = snapshotPath
+
 			result.ActionResults = append(result.ActionResults, actionResult)
 
 			// Check if we should continue on failure
-			if !shouldContinueOnFailure(action) {
+			if !skipped && !shouldContinueOnFailure(action) {
 				result.Status = models.StatusFailed
 				result.ErrorMessage = "Action " + string(action.ActionType) + " failed: " + err.Error()
 				break
@@ -172,48 +507,400 @@ func BrowserAutomationWorkflow(ctx workflow.Context, input models.WorkflowInput)
 		} else {
 			actionResult.Status = models.StatusSuccess
 			result.ActionResults = append(result.ActionResults, actionResult)
-		}
 
-		// Signal progress for UI updates
-		if i < len(input.Actions)-1 {
-			workflow.SignalExternalWorkflow(ctx, "", "", "actionComplete", actionResult)
+			if actionResult.SelectorFallbacks > 0 && action.ID != "" {
+				healed[action.ID] = actionResult.Selector
+			}
+			if len(action.Compensations) > 0 {
+				done = append(done, step)
+			}
+
+			if action.ActionType == models.ActionCopy {
+				if result.Outputs == nil {
+					result.Outputs = make(map[string]string)
+				}
+				result.Outputs[models.ClipboardOutput] = actionResult.Output
+				params[models.ClipboardOutput] = actionResult.Output
+			}
+			if action.ActionType == models.ActionExtract && action.Extract != nil {
+				if result.Outputs == nil {
+					result.Outputs = make(map[string]string)
+				}
+				result.Outputs[action.Extract.Output] = actionResult.Output
+				params[action.Extract.Output] = actionResult.Output
+				extracted.add(step, input.Loops, action.Extract.Output, actionResult.Output)
+			}
 		}
+
+		// Push progress for UI updates
+		publishProgress(ctx, input)
+	}
+	result.Progress = nil
+
+	// Undo what the steps did before a critical failure or cancellation
+	if len(done) > 0 && (result.Status == models.StatusFailed || result.Status == models.StatusCanceled) {
+		result.Compensations = compensate(sessionCtx, input, browserSession.SessionID, params, done)
 	}
 
 	// Calculate total duration
 	result.TotalDuration = workflow.Now(ctx).Sub(startTime).Milliseconds()
 
 	// Set final status
-	if result.Status != models.StatusFailed {
+	if result.Status != models.StatusFailed && result.Status != models.StatusCanceled {
 		// If we reached here without initialization failure, mark as success
 		// even if individual actions failed, as requested.
 		result.Status = models.StatusSuccess
 	}
+	// Actions missing the outcomes they had while recording downgrade the
+	// run to a warning
+	if warning := outcomeWarning(result.ActionResults); warning != "" && result.Status == models.StatusSuccess {
+		result.Status = models.StatusWarning
+		result.ErrorMessage = warning
+	}
 
+	// Write the selectors the run fell back on to the workflow. Like a
+	// failed session save, a failed write is only logged.
+	if input.HealSelectors && len(healed) > 0 {
+		err = workflow.ExecuteActivity(ctx, "HealSelectorsActivity", HealSelectorsInput{
+			WorkflowID: input.WorkflowID,
+			RunID:      input.RunID,
+			Selectors:  healed,
+		}).Get(ctx, nil)
+		if err != nil {
+			logger.Warn("Failed to heal selectors", "count", len(healed), "error", err.Error())
+		}
+	}
+
+	// Keep what the run produced with the run. Like healing, a failed
+	// write is only logged.
+	result.Dataset = extracted.Rows()
+	if len(result.Outputs) > 0 && input.RunID != "" {
+		err = workflow.ExecuteActivity(ctx, "RecordRunOutputsActivity", RunOutputsInput{
+			RunID:   input.RunID,
+			Outputs: result.Outputs,
+			Dataset: result.Dataset,
+		}).Get(ctx, nil)
+		if err != nil {
+			logger.Warn("Failed to record run outputs", "count", len(result.Outputs), "error", err.Error())
+		}
+	}
+
+	// Keep the results of the run's actions, a canceled run's too, for its
+	// reports and the workflow's analytics. Like healing, a failed write
+	// is only logged.
+	if len(result.ActionResults) > 0 && input.RunID != "" {
+		recordCtx, _ := workflow.NewDisconnectedContext(ctx)
+		err = workflow.ExecuteActivity(recordCtx, "RecordActionResultsActivity", ActionResultsInput{
+			RunID:   input.RunID,
+			Results: result.ActionResults,
+		}).Get(recordCtx, nil)
+		if err != nil {
+			logger.Warn("Failed to record action results", "count", len(result.ActionResults), "error", err.Error())
+		}
+	}
+
+	// Save the storage state while the browser is still open. A failed save
+	// is logged rather than failing a run whose actions completed.
+	if input.SaveSession != "" && result.Status.Succeeded() {
+		err = workflow.ExecuteActivity(sessionCtx, "SaveStorageStateActivity", SaveSessionInput{
+			SessionID:  browserSession.SessionID,
+			Name:       input.SaveSession,
+			WorkflowID: input.WorkflowID,
+			RunID:      input.RunID,
+		}).Get(ctx, nil)
+		if err != nil {
+			logger.Warn("Failed to save browser session", "name", input.SaveSession, "error", err.Error())
+		}
+	}
+
+	result.ScreencastURL = ""
 	logger.Info("Workflow completed", "status", result.Status, "duration", result.TotalDuration)
 	return result, nil
 }
 
+// notifyInput is the notification of how the run ended for the targets on
+// its status, with the action it failed on
+func notifyInput(input models.WorkflowInput, result models.WorkflowResult) NotifyInput {
+	notify := NotifyInput{
+		WorkflowID:   input.WorkflowID,
+		RunID:        input.RunID,
+		Status:       result.Status,
+		ErrorMessage: result.ErrorMessage,
+	}
+	for _, target := range input.Notifications {
+		if target.Scheduled && input.ScheduleID == "" {
+			continue
+		}
+		if target.Notifies(result.Status) {
+			notify.Targets = append(notify.Targets, target)
+		}
+	}
+	if result.Status != models.StatusFailed {
+		return notify
+	}
+
+	for i := len(result.ActionResults) - 1; i >= 0; i-- {
+		failed := result.ActionResults[i]
+		if failed.Status != models.StatusFailed {
+			continue
+		}
+		notify.ScreenshotPath = failed.ScreenshotPath
+		for j := range input.Actions {
+			if input.Actions[j].SequenceID == failed.SequenceID {
+				notify.FailedAction = &input.Actions[j]
+				break
+			}
+		}
+		break
+	}
+	return notify
+}
+
+// outcomeWarning describes the steps that didn't have the outcomes they had
+// while recording, empty when all did
+func outcomeWarning(results []models.ActionResult) string {
+	var steps []string
+	var first *models.ActionResult
+	for i, r := range results {
+		if len(r.MissingOutcomes) == 0 {
+			continue
+		}
+		if first == nil {
+			first = &results[i]
+		}
+		if step := strconv.Itoa(r.SequenceID); len(steps) == 0 || steps[len(steps)-1] != step {
+			steps = append(steps, step)
+		}
+	}
+	if first == nil {
+		return ""
+	}
+	missing := first.MissingOutcomes[0]
+	return fmt.Sprintf("Outcomes differ from the recording at steps %s: %s %q did not appear after step %d",
+		strings.Join(steps, ", "), strings.ToLower(missing.Tag), missing.Text, first.SequenceID)
+}
+
+// canceledStep is the result of a step the run was canceled at, whether
+// waiting to run it or running it
+func canceledStep(s step) models.ActionResult {
+	return models.ActionResult{
+		ActionID:     s.Action.ID,
+		SequenceID:   s.Action.SequenceID,
+		Iteration:    s.Iteration,
+		Status:       models.StatusCanceled,
+		ErrorMessage: "Workflow canceled",
+	}
+}
+
+// injectParameters returns the action with its recorded value replaced by
+// the value of the variable parameter recorded from it, if the run has one
+func injectParameters(action models.SemanticAction, params []models.WorkflowParameter, values map[string]string) (models.SemanticAction, bool) {
+	injected := false
+	for _, param := range params {
+		if param.TokenType == models.TokenVariable && param.SourceAction == action.SequenceID {
+			if val, ok := values[param.Name]; ok {
+				action.Value = val
+				injected = true
+			}
+		}
+	}
+	return action, injected
+}
+
+// awaitDecision waits up to timeout for an operator's decision on a failed
+// step, dropping decisions about other steps
+func awaitDecision(ctx workflow.Context, ch workflow.ReceiveChannel, step int, timeout time.Duration) (models.StepDecision, bool) {
+	deadline := workflow.Now(ctx).Add(timeout)
+	for {
+		remaining := deadline.Sub(workflow.Now(ctx))
+		if remaining <= 0 {
+			return models.StepDecision{}, false
+		}
+		var decision models.StepDecision
+		if ok, _ := ch.ReceiveWithTimeout(ctx, remaining, &decision); !ok {
+			return models.StepDecision{}, false
+		}
+		if decision.Step == step {
+			return decision, true
+		}
+		workflow.GetLogger(ctx).Warn("Dropping decision for another step", "step", decision.Step, "failed", step)
+	}
+}
+
+// dialogAnswer returns the value of the parameter the dialog policy answers
+// prompts with, falling back to its default
+func dialogAnswer(input models.WorkflowInput) string {
+	if input.Dialogs == nil || input.Dialogs.Parameter == "" {
+		return ""
+	}
+	if value, ok := input.Parameters[input.Dialogs.Parameter]; ok {
+		return value
+	}
+	for _, param := range input.Params {
+		if param.Name == input.Dialogs.Parameter {
+			return param.DefaultValue
+		}
+	}
+	return ""
+}
+
 // BrowserSession holds browser session information
 type BrowserSession struct {
-	SessionID string `json:"session_id"`
-	PageURL   string `json:"page_url"`
+	SessionID     string `json:"session_id"`
+	PageURL       string `json:"page_url"`
+	ScreencastURL string `json:"screencast_url,omitempty"` // streams the browser live, empty when the worker can't
 }
 
 // BrowserInitInput is the input for browser initialization
 type BrowserInitInput struct {
-	Headless    bool   `json:"headless"`
+	RunID        string               `json:"run_id,omitempty"` // names the run's download directory
+	Headless     bool                 `json:"headless"`
+	ReuseBrowser bool                 `json:"reuse_browser"`      // incognito context in a warm browser
+	Endpoint     string               `json:"endpoint,omitempty"` // remote Chrome, the worker's fleet or a local launch when empty
+	Browser      models.BrowserEngine `json:"browser,omitempty"`
+	Device       *models.DeviceConfig `json:"device,omitempty"` // emulated before the first navigation
+	UserAgent    string               `json:"user_agent,omitempty"`
+	Locale       string               `json:"locale,omitempty"`
+	Timezone     string               `json:"timezone,omitempty"`
+	Proxy        *models.ProxyConfig  `json:"proxy,omitempty"`
+	Stealth      bool                 `json:"stealth,omitempty"`
+	StorageState *models.StorageState `json:"storage_state,omitempty"` // cookies and localStorage to start with
+	// Session names the saved session to start with instead, opened on the
+	// worker
+	Session string `json:"session,omitempty"`
+	// HTTPCredentials answer the sites' HTTP authentication challenges
+	HTTPCredentials *models.HTTPCredentials `json:"http_credentials,omitempty"`
+	Dialogs         *models.DialogPolicy    `json:"dialogs,omitempty"`
+	DialogAnswer    string                  `json:"dialog_answer,omitempty"` // the policy's parameter value
+
+	SkipStabilityChecks bool `json:"skip_stability_checks,omitempty"`
+
 	LLMProvider string `json:"llm_provider"`
 	LLMAPIKey   string `json:"llm_api_key,omitempty"`
+
+	// Priority orders the run among those waiting for a browser slot
+	Priority models.RunPriority `json:"priority,omitempty"`
 }
 
 // ActionInput is the input for executing a browser action
 type ActionInput struct {
-	SessionID     string                `json:"session_id"`
-	Action        models.SemanticAction `json:"action"`
-	Parameters    map[string]string     `json:"parameters"`
-	LLMProvider   string                `json:"llm_provider"`
-	GeneratedCode string                `json:"generated_code,omitempty"` // Pre-generated Go Rod code
+	SessionID     string                       `json:"session_id"`
+	Action        models.SemanticAction        `json:"action"`
+	Parameters    map[string]string            `json:"parameters"`
+	LLMProvider   string                       `json:"llm_provider"`
+	GeneratedCode string                       `json:"generated_code,omitempty"` // Pre-generated Go Rod code
+	CodeRef       *CodeRef                     `json:"code_ref,omitempty"`       // Pre-generated code in the store
+	CodeTemplates map[models.ActionType]string `json:"code_templates,omitempty"`
+	// ScreenshotName names the screenshots taken before and after the
+	// action and the DOM snapshot taken after it, none being taken when
+	// empty
+	ScreenshotName string `json:"screenshot_name,omitempty"`
+	// RunID is the run the action is part of, whose downloads keep the
+	// files it downloads
+	RunID string `json:"run_id,omitempty"`
+	// AccessibilityReport names the report of the accessibility audit run
+	// after the action, kept among the run's downloads; no audit when empty
+	AccessibilityReport string `json:"accessibility_report,omitempty"`
+	// VerifyOutcome checks that the elements which appeared after the
+	// action while recording appear again
+	VerifyOutcome bool `json:"verify_outcome,omitempty"`
+}
+
+// SaveSessionInput is the input for saving a session's storage state
+type SaveSessionInput struct {
+	SessionID  string `json:"session_id"`
+	Name       string `json:"name"`
+	WorkflowID string `json:"workflow_id"`
+	RunID      string `json:"run_id"`
+}
+
+// RunStartedInput is the input for recording that a run started
+type RunStartedInput struct {
+	RunID              string `json:"run_id"`
+	TemporalWorkflowID string `json:"temporal_workflow_id"`
+}
+
+// RunSlotInput is the input for waiting until fewer than Limit runs of a
+// workflow started before a run are active
+type RunSlotInput struct {
+	WorkflowID string `json:"workflow_id"`
+	RunID      string `json:"run_id"`
+	Limit      int    `json:"limit"`
+}
+
+// HealSelectorsInput is the input for writing a run's fallback selectors
+// back to its workflow
+type HealSelectorsInput struct {
+	WorkflowID string `json:"workflow_id"`
+	RunID      string `json:"run_id"`
+	// Selectors are by the ID of their action, which an edit of the
+	// workflow since the run started changed
+	Selectors map[string]string `json:"selectors"`
+}
+
+// ProgressInput is the input for telling the API a run's progress changed
+type ProgressInput struct {
+	RunID string `json:"run_id"`
+}
+
+// publishProgress tells the API the run's progress changed, so it pushes
+// the run to the clients streaming it rather than them polling. Like a
+// failed notification, a failed publish is only logged.
+func publishProgress(ctx workflow.Context, input models.WorkflowInput) {
+	if input.DryRun || input.RunID == "" {
+		return
+	}
+	ctx = workflow.WithLocalActivityOptions(ctx, workflow.LocalActivityOptions{
+		StartToCloseTimeout: 5 * time.Second,
+		RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 1},
+	})
+	err := workflow.ExecuteLocalActivity(ctx, "PublishProgressActivity", ProgressInput{RunID: input.RunID}).Get(ctx, nil)
+	if err != nil {
+		workflow.GetLogger(ctx).Debug("Failed to publish run progress", "error", err.Error())
+	}
+}
+
+// NotifyInput is the input for announcing how a run ended
+type NotifyInput struct {
+	Targets      []models.NotificationTarget `json:"targets"`
+	WorkflowID   string                      `json:"workflow_id"`
+	RunID        string                      `json:"run_id"`
+	Status       models.RunStatus            `json:"status"`
+	ErrorMessage string                      `json:"error_message,omitempty"`
+	// FailedAction is the action the run failed on, and ScreenshotPath the
+	// page when it did
+	FailedAction   *models.SemanticAction `json:"failed_action,omitempty"`
+	ScreenshotPath string                 `json:"screenshot_path,omitempty"`
+}
+
+// RunOutputsInput is the input for recording the values a run produced
+type RunOutputsInput struct {
+	RunID   string              `json:"run_id"`
+	Outputs map[string]string   `json:"outputs"`
+	Dataset []map[string]string `json:"dataset,omitempty"`
+}
+
+// ActionResultsInput is the input for recording the results of a run's
+// actions
+type ActionResultsInput struct {
+	RunID   string                `json:"run_id"`
+	Results []models.ActionResult `json:"results"`
+}
+
+// debugSnapshot captures the page after a step of a debug run. A failed
+// capture is reported in the snapshot.
+func debugSnapshot(ctx, sessionCtx workflow.Context, runID, sessionID string, s step) *models.DebugSnapshot {
+	var snapshot models.DebugSnapshot
+	err := workflow.ExecuteActivity(sessionCtx, "DebugSnapshotActivity", ScreenshotInput{
+		SessionID: sessionID,
+		Filename:  fmt.Sprintf("%s_debug_%d_%d.png", runID, s.Action.SequenceID, s.Iteration),
+	}).Get(ctx, &snapshot)
+	if err != nil {
+		snapshot.ErrorMessage = err.Error()
+	}
+	snapshot.SequenceID = s.Action.SequenceID
+	snapshot.Iteration = s.Iteration
+	return &snapshot
 }
 
 // ScreenshotInput is the input for taking a screenshot
@@ -226,21 +913,122 @@ type ScreenshotInput struct {
 // PreGenerateCodeInput is the input for PreGenerateCodeActivity
 type PreGenerateCodeInput struct {
 	WorkflowID  string                  `json:"workflow_id"`
+	RunID       string                  `json:"run_id"`
 	Actions     []models.SemanticAction `json:"actions"`
 	Parameters  map[string]string       `json:"parameters"`
 	LLMProvider string                  `json:"llm_provider"`
 	LLMAPIKey   string                  `json:"llm_api_key,omitempty"`
+	// CodeTemplates overrides the worker's templates for the fallback generator
+	CodeTemplates map[models.ActionType]string `json:"code_templates,omitempty"`
+	// Inline returns the code in ActionCodes even when the worker could
+	// store it, for runs that only show it
+	Inline bool `json:"inline,omitempty"`
 }
 
-// PreGeneratedCode holds pre-generated code for actions
+// PreGeneratedCode holds pre-generated code for actions. Code is persisted
+// and referenced by CodeRefs when the worker has a database, and carried
+// inline in ActionCodes otherwise; it is never a worker-local path, as the
+// executing activity may run on another worker.
 type PreGeneratedCode struct {
-	ActionCodes map[int]string `json:"action_codes"` // SequenceID -> Generated Code
+	ActionCodes map[int]string  `json:"action_codes"`        // SequenceID -> Generated Code
+	CodeRefs    map[int]CodeRef `json:"code_refs,omitempty"` // SequenceID -> stored code
+}
+
+// CodeRef references pre-generated code stored by run and sequence
+type CodeRef struct {
+	RunID      string `json:"run_id"`
+	SequenceID int    `json:"sequence_id"`
+}
+
+// nonRetryableErrors are the error types no activity is retried after
+var nonRetryableErrors = []string{"FatalBrowserError", "InvalidSelectorError"}
+
+// runRetryPolicy returns the retry policy of a run's activities other than
+// its actions, the run's own policy overriding the defaults
+func runRetryPolicy(input models.WorkflowInput) *temporal.RetryPolicy {
+	policy := &temporal.RetryPolicy{
+		InitialInterval:        time.Second,
+		BackoffCoefficient:     2.0,
+		MaximumInterval:        time.Minute,
+		MaximumAttempts:        int32(input.RetryAttempts),
+		NonRetryableErrorTypes: nonRetryableErrors,
+	}
+	if retry := input.Retry; retry != nil {
+		if retry.MaxAttempts > 0 {
+			policy.MaximumAttempts = int32(retry.MaxAttempts)
+		}
+		applyRetryPolicy(policy, retry)
+	}
+	return policy
+}
+
+// applyRetryPolicy sets a run's backoff and non-retryable errors on policy,
+// raising the maximum interval to the initial one when needed
+func applyRetryPolicy(policy *temporal.RetryPolicy, retry *models.RetryPolicy) {
+	if retry.InitialIntervalMs > 0 {
+		policy.InitialInterval = time.Duration(retry.InitialIntervalMs) * time.Millisecond
+	}
+	if retry.BackoffCoefficient > 0 {
+		policy.BackoffCoefficient = retry.BackoffCoefficient
+	}
+	if policy.MaximumInterval < policy.InitialInterval {
+		policy.MaximumInterval = policy.InitialInterval
+	}
+	policy.NonRetryableErrorTypes = append(append([]string{}, nonRetryableErrors...), retry.NonRetryableErrorTypes...)
+}
+
+// actionHeartbeatTimeout is how long an action may go without the heartbeat
+// it records while it runs. The worker learns of a canceled run from the
+// replies to heartbeats, which the SDK sends at most every 80% of it, so it
+// bounds how long the browser goes on with an action after a cancel.
+const actionHeartbeatTimeout = 10 * time.Second
+
+// actionActivityOptions returns the options of an action's activity, its
+// own timeout and retries overriding the run's retry policy, which in turn
+// overrides the single attempt actions get by default
+func actionActivityOptions(action models.SemanticAction, runTimeout int, retry *models.RetryPolicy) workflow.ActivityOptions {
+	// Options were validated when the actions were stored
+	opts, _ := action.Options()
+
+	timeout := time.Duration(runTimeout) * time.Second
+	if opts.Timeout > 0 {
+		timeout = opts.Timeout
+	}
+
+	policy := &temporal.RetryPolicy{
+		InitialInterval:    time.Second,
+		BackoffCoefficient: 2.0,
+		MaximumInterval:    time.Minute,
+		MaximumAttempts:    int32(opts.MaxRetries + 1),
+	}
+	if retry != nil {
+		if retry.MaxAttempts > 0 && opts.MaxRetries == 0 {
+			policy.MaximumAttempts = int32(retry.MaxAttempts)
+		}
+		applyRetryPolicy(policy, retry)
+	}
+	if opts.RetryBackoff > 0 {
+		policy.InitialInterval = opts.RetryBackoff
+		if policy.MaximumInterval < policy.InitialInterval {
+			policy.MaximumInterval = policy.InitialInterval
+		}
+	}
+
+	return workflow.ActivityOptions{
+		StartToCloseTimeout: timeout,
+		HeartbeatTimeout:    actionHeartbeatTimeout,
+		RetryPolicy:         policy,
+	}
 }
 
-// shouldContinueOnFailure determines if workflow should continue after action failure
+// shouldContinueOnFailure determines if workflow should continue after action failure.
+// Only critical actions stop the run, the others may fail as requested by user.
+// Assertions are critical unless marked optional.
 func shouldContinueOnFailure(action models.SemanticAction) bool {
-	// Always continue on failure as requested by user
-	return true
+	if action.ActionType == models.ActionAssert {
+		return action.Criticality == models.CriticalityOptional
+	}
+	return action.Criticality != models.CriticalityCritical
 }
 
 // ParallelWorkflowInput represents input for parallel workflow execution
@@ -250,12 +1038,39 @@ type ParallelWorkflowInput struct {
 	RunConfigs  []RunConfig             `json:"run_configs"`
 	LLMProvider string                  `json:"llm_provider"`
 	Headless    bool                    `json:"headless"`
+	Delay       models.DelayConfig      `json:"delay"`
+	// Proxies are assigned to the runs round-robin so concurrent runs
+	// originate from distinct IPs
+	Proxies []models.ProxyConfig `json:"proxies,omitempty"`
+	// Parallelism caps how many runs execute at once, all of them when 0
+	Parallelism int `json:"parallelism,omitempty"`
+	// Priority is that of every run, which execute on the batch's task
+	// queue
+	Priority models.RunPriority `json:"priority,omitempty"`
+
+	// Params, LLMAPIKey, Settings and CodeTemplates are passed on to every
+	// run, as a single run gets them
+	Params        []models.WorkflowParameter   `json:"params,omitempty"`
+	LLMAPIKey     string                       `json:"llm_api_key,omitempty"`
+	Settings      models.WorkflowSettings      `json:"settings"`
+	CodeTemplates map[models.ActionType]string `json:"code_templates,omitempty"`
 }
 
 // RunConfig represents a single run configuration
 type RunConfig struct {
-	RunID      string            `json:"run_id"`
-	Parameters map[string]string `json:"parameters"`
+	RunID      string              `json:"run_id"`
+	Parameters map[string]string   `json:"parameters"`
+	Proxy      *models.ProxyConfig `json:"proxy,omitempty"` // overrides the proxy pool
+}
+
+// runProxy returns the proxy of the i-th run: its own, else the pool's
+// next in turn, else none
+func (input ParallelWorkflowInput) runProxy(i int) (*models.ProxyConfig, error) {
+	proxy := input.RunConfigs[i].Proxy
+	if proxy == nil && len(input.Proxies) > 0 {
+		proxy = &input.Proxies[i%len(input.Proxies)]
+	}
+	return proxy.Resolve()
 }
 
 // ParallelWorkflowResult represents the result of parallel execution
@@ -272,32 +1087,83 @@ func ParallelBrowserAutomationWorkflow(ctx workflow.Context, input ParallelWorkf
 		Results: make([]models.WorkflowResult, len(input.RunConfigs)),
 	}
 
-	// Create child workflow options
-	childOptions := workflow.ChildWorkflowOptions{
-		WorkflowID: input.WorkflowID + "-child",
+	// Resolve every run's proxy first so a bad one fails the batch before
+	// any run starts
+	proxies := make([]*models.ProxyConfig, len(input.RunConfigs))
+	for i := range input.RunConfigs {
+		proxy, err := input.runProxy(i)
+		if err != nil {
+			return result, fmt.Errorf("run %s: %w", input.RunConfigs[i].RunID, err)
+		}
+		proxies[i] = proxy
+	}
+
+	// Report each run's result as it completes
+	for i, runConfig := range input.RunConfigs {
+		result.Results[i] = models.WorkflowResult{RunID: runConfig.RunID, Status: models.StatusPending}
+	}
+	err := workflow.SetQueryHandler(ctx, "getProgress", func() (ParallelWorkflowResult, error) {
+		return result, nil
+	})
+	if err != nil {
+		logger.Error("Failed to register query handler", "error", err)
 	}
-	childCtx := workflow.WithChildOptions(ctx, childOptions)
 
-	// Execute child workflows in parallel using selectors
+	// Execute child workflows in parallel using selectors, starting the next
+	// run whenever one completes
 	selector := workflow.NewSelector(ctx)
-	futures := make([]workflow.ChildWorkflowFuture, len(input.RunConfigs))
+	startRun := func(i int) {
+		runConfig := input.RunConfigs[i]
+
+		// Children run concurrently, so each needs its own workflow ID. It
+		// is the one a single run gets, so runs can be watched and signalled
+		// alike.
+		childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
+			WorkflowID: "browser-automation-" + runConfig.RunID,
+		})
 
-	for i, runConfig := range input.RunConfigs {
 		childInput := models.WorkflowInput{
 			WorkflowID:    input.WorkflowID,
 			RunID:         runConfig.RunID,
 			Parameters:    runConfig.Parameters,
+			Params:        input.Params,
 			Actions:       input.Actions,
 			LLMProvider:   input.LLMProvider,
+			LLMAPIKey:     input.LLMAPIKey,
 			Headless:      input.Headless,
+			Dialogs:       input.Settings.Dialogs,
+			Delay:         input.Delay,
+			Proxy:         proxies[i],
 			Timeout:       300,
 			RetryAttempts: 3,
+			CodeTemplates: input.CodeTemplates,
+
+			SkipStabilityChecks: input.Settings.SkipStabilityChecks,
+			DecisionTimeout:     input.Settings.DecisionTimeout,
+
+			Loops: input.Settings.Loops,
+
+			MaxConcurrentRuns: input.Settings.MaxConcurrentRuns,
+			Notifications:     input.Settings.Notifications,
+			Priority:          input.Priority,
 		}
 
-		future := workflow.ExecuteChildWorkflow(childCtx, BrowserAutomationWorkflow, childInput)
-		futures[i] = future
+		// The run is recorded as running only as it starts, so the runs
+		// waiting for a slot count the batch's earlier runs against their
+		// workflow's limit but not the ones it hasn't started yet
+		recordCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+			StartToCloseTimeout: 30 * time.Second,
+		})
+		err := workflow.ExecuteActivity(recordCtx, "RecordRunStartedActivity", RunStartedInput{
+			RunID:              runConfig.RunID,
+			TemporalWorkflowID: "browser-automation-" + runConfig.RunID,
+		}).Get(ctx, nil)
+		if err != nil {
+			logger.Warn("Failed to record run start", "runID", runConfig.RunID, "error", err.Error())
+		}
 
-		idx := i
+		result.Results[i].Status = models.StatusRunning
+		future := workflow.ExecuteChildWorkflow(childCtx, BrowserAutomationWorkflow, childInput)
 		selector.AddFuture(future, func(f workflow.Future) {
 			var childResult models.WorkflowResult
 			if err := f.Get(ctx, &childResult); err != nil {
@@ -307,13 +1173,26 @@ func ParallelBrowserAutomationWorkflow(ctx workflow.Context, input ParallelWorkf
 					ErrorMessage: err.Error(),
 				}
 			}
-			result.Results[idx] = childResult
+			result.Results[i] = childResult
 		})
 	}
 
+	limit := input.Parallelism
+	if limit <= 0 || limit > len(input.RunConfigs) {
+		limit = len(input.RunConfigs)
+	}
+	next := 0
+	for ; next < limit; next++ {
+		startRun(next)
+	}
+
 	// Wait for all child workflows to complete
 	for range input.RunConfigs {
 		selector.Select(ctx)
+		if next < len(input.RunConfigs) {
+			startRun(next)
+			next++
+		}
 	}
 
 	logger.Info("Parallel workflow completed", "totalRuns", len(input.RunConfigs))
@@ -0,0 +1,177 @@
+package workflows
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// newTestEnv returns a test environment whose browser activities succeed,
+// counting the actions executed and the sessions closed
+func newTestEnv(t *testing.T, executed, closed *int) *testsuite.TestWorkflowEnvironment {
+	t.Helper()
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetWorkerOptions(worker.Options{EnableSessionWorker: true})
+
+	register := func(fn interface{}, name string) {
+		env.RegisterActivityWithOptions(fn, activity.RegisterOptions{Name: name})
+	}
+	register(func(ctx context.Context, input PreGenerateCodeInput) (PreGeneratedCode, error) {
+		return PreGeneratedCode{ActionCodes: map[int]string{}}, nil
+	}, "PreGenerateCodeActivity")
+	register(func(ctx context.Context, input BrowserInitInput) (BrowserSession, error) {
+		return BrowserSession{SessionID: "session-1"}, nil
+	}, "InitializeBrowserActivity")
+	register(func(ctx context.Context, input ActionInput) (models.ActionResult, error) {
+		*executed++
+		return models.ActionResult{}, nil
+	}, "ExecuteBrowserActionActivity")
+	register(func(ctx context.Context, sessionID string) error {
+		*closed++
+		return nil
+	}, "CloseBrowserActivity")
+	register(func(ctx context.Context, input ProgressInput) error {
+		return nil
+	}, "PublishProgressActivity")
+	return env
+}
+
+func testActions() []models.SemanticAction {
+	return []models.SemanticAction{
+		{ID: "a1", SequenceID: 1, ActionType: models.ActionNavigate, Value: "https://example.com"},
+		{ID: "a2", SequenceID: 2, ActionType: models.ActionClick},
+		{ID: "a3", SequenceID: 3, ActionType: models.ActionClick},
+	}
+}
+
+func TestBrowserWorkflowCanceledMidRun(t *testing.T) {
+	var executed, closed int
+	env := newTestEnv(t, &executed, &closed)
+
+	// Hold the run before its second step, then cancel it while it waits
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(models.SignalPause, models.PauseRequest{BeforeStep: 2})
+	}, 0)
+	env.RegisterDelayedCallback(env.CancelWorkflow, time.Minute)
+
+	env.ExecuteWorkflow(BrowserAutomationWorkflow, models.WorkflowInput{
+		WorkflowID: "wf-1",
+		RunID:      "run-1",
+		Actions:    testActions(),
+		Timeout:    60,
+	})
+
+	if !env.IsWorkflowCompleted() {
+		t.Fatal("workflow did not complete")
+	}
+	var result models.WorkflowResult
+	if err := env.GetWorkflowResult(&result); err != nil {
+		t.Fatalf("GetWorkflowResult() error = %v", err)
+	}
+	if result.Status != models.StatusCanceled {
+		t.Errorf("Status = %q, want %q", result.Status, models.StatusCanceled)
+	}
+	if executed != 1 {
+		t.Errorf("executed %d actions, want 1", executed)
+	}
+	if closed != 1 {
+		t.Errorf("closed the browser %d times, want 1", closed)
+	}
+	assertCanceledAt(t, result, 2)
+}
+
+func TestBrowserWorkflowCanceledDuringDelay(t *testing.T) {
+	var executed, closed int
+	env := newTestEnv(t, &executed, &closed)
+
+	// Cancel the run while it waits out the delay before its second step
+	env.RegisterDelayedCallback(env.CancelWorkflow, 5*time.Second)
+
+	env.ExecuteWorkflow(BrowserAutomationWorkflow, models.WorkflowInput{
+		WorkflowID: "wf-1",
+		RunID:      "run-1",
+		Actions:    testActions(),
+		Timeout:    60,
+		Delay:      models.DelayConfig{Strategy: models.DelayFixed, FixedMs: 10000},
+	})
+
+	if !env.IsWorkflowCompleted() {
+		t.Fatal("workflow did not complete")
+	}
+	var result models.WorkflowResult
+	if err := env.GetWorkflowResult(&result); err != nil {
+		t.Fatalf("GetWorkflowResult() error = %v", err)
+	}
+	if result.Status != models.StatusCanceled {
+		t.Errorf("Status = %q, want %q", result.Status, models.StatusCanceled)
+	}
+	if executed != 1 {
+		t.Errorf("executed %d actions, want 1", executed)
+	}
+	if closed != 1 {
+		t.Errorf("closed the browser %d times, want 1", closed)
+	}
+	assertCanceledAt(t, result, 2)
+}
+
+// assertCanceledAt checks that the run's last action result is the step it
+// was canceled at
+func assertCanceledAt(t *testing.T, result models.WorkflowResult, sequenceID int) {
+	t.Helper()
+	if len(result.ActionResults) == 0 {
+		t.Fatal("no action results")
+	}
+	last := result.ActionResults[len(result.ActionResults)-1]
+	if last.SequenceID != sequenceID || last.Status != models.StatusCanceled {
+		t.Errorf("last action result = step %d %q, want step %d %q", last.SequenceID, last.Status, sequenceID, models.StatusCanceled)
+	}
+}
+
+func TestNotifyInputCanceled(t *testing.T) {
+	input := models.WorkflowInput{
+		WorkflowID: "wf-1",
+		RunID:      "run-1",
+		Notifications: []models.NotificationTarget{
+			{Type: models.NotifySlack, Channel: "#failures"},
+			{Type: models.NotifySlack, Channel: "#runs", On: []models.RunStatus{models.StatusCanceled}},
+		},
+	}
+	notify := notifyInput(input, models.WorkflowResult{
+		Status:       models.StatusCanceled,
+		ErrorMessage: "Workflow canceled by user",
+	})
+
+	if notify.Status != models.StatusCanceled {
+		t.Errorf("Status = %q, want %q", notify.Status, models.StatusCanceled)
+	}
+	if len(notify.Targets) != 1 || notify.Targets[0].Channel != "#runs" {
+		t.Errorf("Targets = %+v, want only #runs", notify.Targets)
+	}
+	if notify.FailedAction != nil {
+		t.Errorf("FailedAction = %+v, want none for a canceled run", notify.FailedAction)
+	}
+}
+
+func TestNotifyInputCanceledSchedule(t *testing.T) {
+	input := models.WorkflowInput{
+		WorkflowID: "wf-1",
+		RunID:      "run-1",
+		ScheduleID: "schedule-1",
+		Notifications: []models.NotificationTarget{
+			{Type: models.NotifyEmail, To: []string{"ops@example.com"}, Scheduled: true,
+				On: []models.RunStatus{models.StatusFailed, models.StatusCanceled}},
+		},
+	}
+	notify := notifyInput(input, models.WorkflowResult{Status: models.StatusCanceled})
+
+	if notify.Status != models.StatusCanceled || len(notify.Targets) != 1 {
+		t.Errorf("notifyInput() = %q to %d targets, want %q to 1", notify.Status, len(notify.Targets), models.StatusCanceled)
+	}
+}
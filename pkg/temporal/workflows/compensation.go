@@ -0,0 +1,48 @@
+package workflows
+
+import (
+	"go.temporal.io/sdk/workflow"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// compensate undoes the steps that succeeded before the run was aborted,
+// saga-style: the latest step's compensations run first, each step's in
+// the order they were recorded. A failed compensation is recorded and the
+// others still run. They run in the run's browser session even when the
+// run was canceled.
+func compensate(sessionCtx workflow.Context, input models.WorkflowInput, sessionID string, params map[string]string, done []step) []models.ActionResult {
+	logger := workflow.GetLogger(sessionCtx)
+	ctx, _ := workflow.NewDisconnectedContext(sessionCtx)
+
+	var results []models.ActionResult
+	for i := len(done) - 1; i >= 0; i-- {
+		step := done[i]
+		for _, comp := range step.Action.Compensations {
+			logger.Info("Compensating action", "sequence", step.Action.SequenceID, "type", comp.ActionType, "iteration", step.Iteration)
+
+			actionCtx := workflow.WithActivityOptions(ctx, actionActivityOptions(comp, input.Timeout, input.Retry))
+			var result models.ActionResult
+			err := workflow.ExecuteActivity(actionCtx, "ExecuteBrowserActionActivity", ActionInput{
+				SessionID:     sessionID,
+				Action:        comp,
+				Parameters:    withRow(params, step.Row),
+				LLMProvider:   input.LLMProvider,
+				CodeTemplates: input.CodeTemplates,
+			}).Get(ctx, &result)
+
+			result.SequenceID = step.Action.SequenceID
+			result.ActionID = comp.ID
+			result.Iteration = step.Iteration
+			if err != nil {
+				logger.Warn("Compensation failed", "sequence", step.Action.SequenceID, "error", err.Error())
+				result.Status = models.StatusFailed
+				result.ErrorMessage = err.Error()
+			} else {
+				result.Status = models.StatusSuccess
+			}
+			results = append(results, result)
+		}
+	}
+	return results
+}
@@ -0,0 +1,37 @@
+package workflows
+
+import (
+	"strings"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// planSteps lists what each step of a run would execute: its generated code
+// and its value with the run's parameters injected and substituted the way
+// the action activity does. Pasted values are only known once a run copies
+// them, so pastes keep their recorded value.
+func planSteps(input models.WorkflowInput, params map[string]string, code PreGeneratedCode) []models.PlannedAction {
+	steps := expandLoops(input.Actions, input.Loops)
+	plan := make([]models.PlannedAction, 0, len(steps))
+	for _, step := range steps {
+		action, _ := injectParameters(step.Action, input.Params, withRow(input.Parameters, step.Row))
+
+		value := action.Value
+		for name, v := range withRow(params, step.Row) {
+			value = strings.ReplaceAll(value, "{{"+name+"}}", v)
+			if value == name {
+				value = v
+			}
+		}
+
+		plan = append(plan, models.PlannedAction{
+			SequenceID:    action.SequenceID,
+			Iteration:     step.Iteration,
+			ActionType:    action.ActionType,
+			Selector:      action.Target.Selector,
+			Value:         value,
+			GeneratedCode: code.ActionCodes[action.SequenceID],
+		})
+	}
+	return plan
+}
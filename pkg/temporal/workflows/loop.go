@@ -0,0 +1,113 @@
+package workflows
+
+import (
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// step is one execution of an action, either outside loops or in an
+// iteration of a loop with the row of the loop's dataset it binds
+type step struct {
+	Action    models.SemanticAction
+	Iteration int // 1-based, 0 outside loops
+	Row       map[string]string
+}
+
+// expandLoops lists the steps of a run, repeating each loop's block of
+// consecutive actions once for each of its rows
+func expandLoops(actions []models.SemanticAction, loops []models.Loop) []step {
+	steps := make([]step, 0, len(actions))
+	for i := 0; i < len(actions); {
+		loop, ok := loopOf(loops, actions[i].SequenceID)
+		if !ok {
+			steps = append(steps, step{Action: actions[i]})
+			i++
+			continue
+		}
+
+		end := i
+		for end < len(actions) && loop.Contains(actions[end].SequenceID) {
+			end++
+		}
+		for n, row := range loop.Rows {
+			for _, action := range actions[i:end] {
+				steps = append(steps, step{Action: action, Iteration: n + 1, Row: row})
+			}
+		}
+		i = end
+	}
+	return steps
+}
+
+// loopOf returns the loop repeating an action
+func loopOf(loops []models.Loop, sequenceID int) (models.Loop, bool) {
+	for _, loop := range loops {
+		if loop.Contains(sequenceID) {
+			return loop, true
+		}
+	}
+	return models.Loop{}, false
+}
+
+// withRow returns values overlaid with the columns of a loop's row, leaving
+// values itself untouched
+func withRow(values, row map[string]string) map[string]string {
+	if row == nil {
+		return values
+	}
+	merged := make(map[string]string, len(values)+len(row))
+	for name, value := range values {
+		merged[name] = value
+	}
+	for name, value := range row {
+		merged[name] = value
+	}
+	return merged
+}
+
+// dataset collects the values extract actions read into rows: one for each
+// iteration of a loop that extracts values, each row also holding the values
+// read outside loops, or a single row when no loop extracts any
+type dataset struct {
+	shared map[string]string
+	rows   []map[string]string
+	index  map[[2]int]int // row by loop's first step and iteration
+}
+
+// add records a value an extract action read in a step
+func (d *dataset) add(s step, loops []models.Loop, name, value string) {
+	loop, ok := loopOf(loops, s.Action.SequenceID)
+	if !ok || s.Iteration == 0 {
+		if d.shared == nil {
+			d.shared = make(map[string]string)
+		}
+		d.shared[name] = value
+		return
+	}
+
+	key := [2]int{loop.FirstStep, s.Iteration}
+	i, ok := d.index[key]
+	if !ok {
+		if d.index == nil {
+			d.index = make(map[[2]int]int)
+		}
+		i = len(d.rows)
+		d.index[key] = i
+		d.rows = append(d.rows, make(map[string]string))
+	}
+	d.rows[i][name] = value
+}
+
+// Rows returns the dataset's rows, nil when nothing was extracted
+func (d *dataset) Rows() []map[string]string {
+	if len(d.rows) == 0 {
+		if d.shared == nil {
+			return nil
+		}
+		return []map[string]string{d.shared}
+	}
+	rows := make([]map[string]string, len(d.rows))
+	for i, row := range d.rows {
+		rows[i] = withRow(d.shared, row)
+	}
+	return rows
+}
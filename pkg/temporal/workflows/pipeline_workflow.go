@@ -0,0 +1,209 @@
+package workflows
+
+import (
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// PipelineInput is the input for running a pipeline
+type PipelineInput struct {
+	PipelineID string              `json:"pipeline_id"`
+	RunID      string              `json:"run_id"`
+	Steps      []PipelineStepInput `json:"steps"`
+}
+
+// PipelineStepInput is a step of a pipeline run: the input the API built
+// for its workflow run, completed from the steps it depends on
+type PipelineStepInput struct {
+	Run       models.WorkflowInput `json:"run"`
+	DependsOn []int                `json:"depends_on,omitempty"` // indexes of the steps it waits for
+	// Inputs set parameters, by name, to outputs of dependencies
+	Inputs map[string]StepOutput `json:"inputs,omitempty"`
+	// ShareSession starts the step with its only dependency's session
+	ShareSession bool `json:"share_session,omitempty"`
+}
+
+// StepOutput is an output of a pipeline step
+type StepOutput struct {
+	Step   int    `json:"step"`
+	Output string `json:"output"`
+}
+
+// PipelineResult is the result of a pipeline run, with a result for each
+// step in the pipeline's order
+type PipelineResult struct {
+	RunID        string                  `json:"run_id"`
+	Status       models.RunStatus        `json:"status"`
+	Steps        []models.WorkflowResult `json:"steps"`
+	ErrorMessage string                  `json:"error_message,omitempty"`
+}
+
+// PipelineWorkflow runs the workflows of a pipeline as child workflows,
+// each once the steps it depends on have succeeded, in parallel with the
+// other steps that are ready. Once a step fails no more steps start.
+func PipelineWorkflow(ctx workflow.Context, input PipelineInput) (PipelineResult, error) {
+	logger := log.With(workflow.GetLogger(ctx), "pipelineID", input.PipelineID, "runID", input.RunID)
+	logger.Info("Starting pipeline", "steps", len(input.Steps))
+
+	result := PipelineResult{
+		RunID:  input.RunID,
+		Status: models.StatusRunning,
+		Steps:  make([]models.WorkflowResult, len(input.Steps)),
+	}
+	for i, step := range input.Steps {
+		result.Steps[i] = models.WorkflowResult{RunID: step.Run.RunID, Status: models.StatusPending}
+	}
+
+	err := workflow.SetQueryHandler(ctx, "getProgress", func() (PipelineResult, error) {
+		return result, nil
+	})
+	if err != nil {
+		logger.Error("Failed to register query handler", "error", err)
+	}
+
+	// Steps whose session a later step starts from save it under a name
+	// the later step's worker opens. The pipeline removes them once done.
+	shared := make(map[int]bool)
+	for _, step := range input.Steps {
+		if step.ShareSession {
+			shared[step.DependsOn[0]] = true
+		}
+	}
+	defer func() {
+		sessionCtx, _ := workflow.NewDisconnectedContext(ctx)
+		sessionCtx = workflow.WithActivityOptions(sessionCtx, workflow.ActivityOptions{
+			StartToCloseTimeout: 30 * time.Second,
+			RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 3},
+		})
+		for i := range input.Steps {
+			if !shared[i] || !result.Steps[i].Status.Succeeded() {
+				continue
+			}
+			name := pipelineSession(input.RunID, i)
+			if err := workflow.ExecuteActivity(sessionCtx, "DeleteStorageStateActivity", name).Get(sessionCtx, nil); err != nil {
+				logger.Warn("Failed to delete pipeline session", "name", name, "error", err.Error())
+			}
+		}
+	}()
+
+	fail := func(i int, format string, args ...interface{}) {
+		result.Steps[i].Status = models.StatusFailed
+		result.Steps[i].ErrorMessage = fmt.Sprintf(format, args...)
+		if result.ErrorMessage == "" {
+			result.Status = models.StatusFailed
+			result.ErrorMessage = fmt.Sprintf("step %d: %s", i+1, result.Steps[i].ErrorMessage)
+		}
+	}
+
+	// ready reports whether all of a step's dependencies have succeeded
+	ready := func(i int) bool {
+		for _, dep := range input.Steps[i].DependsOn {
+			if !result.Steps[dep].Status.Succeeded() {
+				return false
+			}
+		}
+		return true
+	}
+
+	selector := workflow.NewSelector(ctx)
+	running := 0
+	completed := -1
+	start := func(i int) {
+		step := input.Steps[i]
+		run := step.Run
+		if shared[i] {
+			run.SaveSession = pipelineSession(input.RunID, i)
+		}
+		if step.ShareSession {
+			run.Session = pipelineSession(input.RunID, step.DependsOn[0])
+		}
+		if len(step.Inputs) > 0 {
+			params := make(map[string]string, len(run.Parameters)+len(step.Inputs))
+			for name, value := range run.Parameters {
+				params[name] = value
+			}
+			for name, source := range step.Inputs {
+				value, ok := result.Steps[source.Step].Outputs[source.Output]
+				if !ok {
+					fail(i, "step %d has no output %q for parameter %q", source.Step+1, source.Output, name)
+					return
+				}
+				params[name] = value
+			}
+			run.Parameters = params
+		}
+
+		logger.Info("Running pipeline step", "step", i+1, "workflowID", run.WorkflowID, "runID", run.RunID)
+		result.Steps[i].Status = models.StatusRunning
+
+		// Steps get a single run's workflow ID, so they can be watched and
+		// signalled alike
+		childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
+			WorkflowID: "browser-automation-" + run.RunID,
+		})
+		running++
+		selector.AddFuture(workflow.ExecuteChildWorkflow(childCtx, BrowserAutomationWorkflow, run), func(f workflow.Future) {
+			var stepResult models.WorkflowResult
+			if err := f.Get(ctx, &stepResult); err != nil {
+				stepResult = models.WorkflowResult{
+					RunID:        run.RunID,
+					Status:       models.StatusFailed,
+					ErrorMessage: err.Error(),
+				}
+			}
+			result.Steps[i] = stepResult
+			running--
+			completed = i
+		})
+	}
+
+	for {
+		for i := range input.Steps {
+			if result.ErrorMessage == "" && result.Steps[i].Status == models.StatusPending && ready(i) {
+				start(i)
+			}
+		}
+		if running == 0 {
+			break
+		}
+
+		selector.Select(ctx)
+		step := result.Steps[completed]
+		if !step.Status.Succeeded() && result.ErrorMessage == "" {
+			result.Status = step.Status
+			result.ErrorMessage = fmt.Sprintf("step %d: %s", completed+1, step.ErrorMessage)
+		}
+	}
+
+	// Steps left waiting on a failed step never run
+	for i := range result.Steps {
+		if result.Steps[i].Status == models.StatusPending {
+			result.Steps[i].Status = models.StatusCanceled
+		}
+	}
+	if result.ErrorMessage != "" {
+		logger.Warn("Pipeline failed", "error", result.ErrorMessage)
+		return result, nil
+	}
+
+	result.Status = models.StatusSuccess
+	for _, step := range result.Steps {
+		if step.Status == models.StatusWarning {
+			result.Status = models.StatusWarning
+		}
+	}
+	logger.Info("Pipeline completed")
+	return result, nil
+}
+
+// pipelineSession names the saved session a pipeline run's step hands to
+// the next
+func pipelineSession(runID string, step int) string {
+	return fmt.Sprintf("pipeline-%s-%d", runID, step+1)
+}
@@ -0,0 +1,85 @@
+package workflows
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// ScheduledRunInput is the input a schedule starts each of its runs with
+type ScheduledRunInput struct {
+	ScheduleID string               `json:"schedule_id"`
+	Run        models.WorkflowInput `json:"run"`
+}
+
+// RunStatusInput is the input for recording how a run ended
+type RunStatusInput struct {
+	RunID        string           `json:"run_id"`
+	Status       models.RunStatus `json:"status"`
+	ErrorMessage string           `json:"error_message,omitempty"`
+}
+
+// ScheduledRunWorkflow runs a workflow for a schedule. Each time the
+// schedule fires is a run of its own, recorded like the runs the API starts
+// and executed as a child workflow with a single run's workflow ID.
+func ScheduledRunWorkflow(ctx workflow.Context, input ScheduledRunInput) (models.WorkflowResult, error) {
+	logger := workflow.GetLogger(ctx)
+
+	var runID string
+	err := workflow.SideEffect(ctx, func(ctx workflow.Context) interface{} {
+		return uuid.New().String()
+	}).Get(&runID)
+	if err != nil {
+		return models.WorkflowResult{}, err
+	}
+	logger.Info("Starting scheduled run", "scheduleID", input.ScheduleID, "workflowID", input.Run.WorkflowID, "runID", runID)
+
+	run := input.Run
+	run.RunID = runID
+	run.ScheduleID = input.ScheduleID
+	childID := "browser-automation-" + runID
+
+	// A run the database failed to record still executes
+	recordCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 3},
+	})
+	params, _ := json.Marshal(run.Parameters)
+	err = workflow.ExecuteActivity(recordCtx, "RecordRunActivity", models.WorkflowRun{
+		ID:                 runID,
+		WorkflowID:         run.WorkflowID,
+		TemporalWorkflowID: childID,
+		Status:             models.StatusRunning,
+		ParametersJSON:     string(params),
+		ScheduleID:         input.ScheduleID,
+	}).Get(ctx, nil)
+	if err != nil {
+		logger.Warn("Failed to record scheduled run", "runID", runID, "error", err.Error())
+	}
+
+	childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{WorkflowID: childID})
+	var result models.WorkflowResult
+	if err := workflow.ExecuteChildWorkflow(childCtx, BrowserAutomationWorkflow, run).Get(ctx, &result); err != nil {
+		result = models.WorkflowResult{
+			RunID:        runID,
+			Status:       models.StatusFailed,
+			ErrorMessage: err.Error(),
+		}
+	}
+
+	err = workflow.ExecuteActivity(recordCtx, "UpdateRunStatusActivity", RunStatusInput{
+		RunID:        runID,
+		Status:       result.Status,
+		ErrorMessage: result.ErrorMessage,
+	}).Get(ctx, nil)
+	if err != nil {
+		logger.Warn("Failed to record scheduled run status", "runID", runID, "error", err.Error())
+	}
+
+	return result, nil
+}
@@ -0,0 +1,103 @@
+package workflows
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"dev/bravebird/browser-automation-go/pkg/models"
+)
+
+// TaskQueue is the task queue every worker polls, serving runs without
+// requirements
+const TaskQueue = "browser-automation"
+
+// maxWorkerLabels caps a worker's labels, as it polls a task queue for
+// each combination of them
+const maxWorkerLabels = 5
+
+// labelPattern matches a capability label such as has-display or region=eu
+var labelPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*(=[a-z0-9._-]+)?$`)
+
+// ParseLabels parses a comma-separated list of capability labels, such as
+// "has-display,region=eu", sorted and without duplicates
+func ParseLabels(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return nil, nil
+	}
+	return normalizeLabels(strings.Split(list, ","))
+}
+
+// normalizeLabels checks labels and returns them in lowercase, sorted and
+// without duplicates
+func normalizeLabels(labels []string) ([]string, error) {
+	seen := make(map[string]bool, len(labels))
+	var out []string
+	for _, label := range labels {
+		label = strings.ToLower(strings.TrimSpace(label))
+		if !labelPattern.MatchString(label) {
+			return nil, fmt.Errorf("invalid label %q: want name or name=value in lowercase", label)
+		}
+		if !seen[label] {
+			seen[label] = true
+			out = append(out, label)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// TaskQueueFor returns the task queue of runs of the priority that need a
+// worker with all of the labels
+func TaskQueueFor(requirements []string, priority models.RunPriority) (string, error) {
+	labels, err := normalizeLabels(requirements)
+	if err != nil {
+		return "", err
+	}
+	if err := priority.Validate(); err != nil {
+		return "", err
+	}
+	queue := TaskQueue
+	if len(labels) > 0 {
+		queue += "@" + strings.Join(labels, ",")
+	}
+	return PriorityTaskQueue(queue, priority), nil
+}
+
+// PriorityTaskQueue returns the queue runs of the priority use in place of
+// queue, queue itself for normal runs. Each priority has a queue of its own
+// so urgent runs don't wait behind the tasks of a large batch.
+func PriorityTaskQueue(queue string, priority models.RunPriority) string {
+	if priority == "" || priority == models.PriorityNormal {
+		return queue
+	}
+	return queue + "/" + string(priority)
+}
+
+// WorkerTaskQueues returns the normal priority task queues a worker with the
+// labels polls: the shared one and one for each combination of its labels,
+// so it serves every run whose requirements it meets. It polls the queue of
+// each priority in place of each of them.
+func WorkerTaskQueues(labels []string) ([]string, error) {
+	labels, err := normalizeLabels(labels)
+	if err != nil {
+		return nil, err
+	}
+	if len(labels) > maxWorkerLabels {
+		return nil, fmt.Errorf("a worker can have at most %d labels", maxWorkerLabels)
+	}
+
+	queues := []string{TaskQueue}
+	for mask := 1; mask < 1<<len(labels); mask++ {
+		var subset []string
+		for i, label := range labels {
+			if mask&(1<<i) != 0 {
+				subset = append(subset, label)
+			}
+		}
+		queue, _ := TaskQueueFor(subset, models.PriorityNormal)
+		queues = append(queues, queue)
+	}
+	return queues, nil
+}